@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the profiles file, by default $XDG_CONFIG_HOME/iamctl/config.yaml:
+//
+//	current: prod
+//	profiles:
+//	  prod:
+//	    url: https://iam.example.com
+//	    tokenEnv: IAM_PROD_TOKEN
+//	  local:
+//	    url: http://localhost:8080
+//	    token: eyJraWQi...
+type Config struct {
+	Current  string              `yaml:"current,omitempty"`
+	Profiles map[string]*Profile `yaml:"profiles,omitempty"`
+}
+
+// Profile points iamctl at one deployment. The access token is either stored
+// inline or read from the environment variable named by TokenEnv.
+type Profile struct {
+	URL      string `yaml:"url"`
+	Token    string `yaml:"token,omitempty"`
+	TokenEnv string `yaml:"tokenEnv,omitempty"`
+}
+
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "iamctl.yaml"
+	}
+	return filepath.Join(dir, "iamctl", "config.yaml")
+}
+
+// loadConfig reads the profiles file. A missing file is an empty config.
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: make(map[string]*Profile)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = make(map[string]*Profile)
+	}
+	return cfg, nil
+}
+
+func (c *Config) save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	// Profiles can hold tokens, so only the owner may read the file.
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// selected returns the named profile, or the current one when name is empty.
+func (c *Config) selected(name string) (*Profile, error) {
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return nil, errors.New("no profile selected; run `iamctl profile set` or pass --url")
+	}
+
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %q does not exist", name)
+	}
+	return profile, nil
+}
+
+func (p *Profile) accessToken() (string, error) {
+	if p.TokenEnv == "" {
+		return p.Token, nil
+	}
+
+	token := os.Getenv(p.TokenEnv)
+	if token == "" {
+		return "", fmt.Errorf("environment variable %s is empty", p.TokenEnv)
+	}
+	return token, nil
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/iamBelugaa/iam/pkg/client"
+)
+
+func newGroupCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{Use: "group", Aliases: []string{"groups"}, Short: "Manage groups"}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List all groups",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				groups := make([]*client.Group, 0)
+				for group, err := range c.Groups.All(cmd.Context()) {
+					if err != nil {
+						return err
+					}
+					groups = append(groups, group)
+				}
+
+				rows := make([][]string, len(groups))
+				for i, group := range groups {
+					rows[i] = []string{group.ID, group.Name, group.Type, group.JoinPolicy, group.Description}
+				}
+				return opts.render(cmd.OutOrStdout(), groups,
+					[]string{"ID", "NAME", "TYPE", "JOIN POLICY", "DESCRIPTION"}, rows,
+				)
+			},
+		},
+		&cobra.Command{
+			Use:   "get GROUP_ID",
+			Short: "Show a group",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				group, err := c.Groups.Get(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+
+				return opts.render(cmd.OutOrStdout(), group,
+					[]string{"ID", "NAME", "TYPE", "JOIN POLICY", "CREATED", "DESCRIPTION"},
+					[][]string{{
+						group.ID, group.Name, group.Type, group.JoinPolicy,
+						formatTime(&group.Created), group.Description,
+					}},
+				)
+			},
+		},
+		&cobra.Command{
+			Use:   "members GROUP_ID",
+			Short: "List the members of a group",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				members, err := c.Groups.Members(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+
+				rows := make([][]string, len(members))
+				for i, member := range members {
+					rows[i] = []string{member.ID, member.Login, member.Status, formatTime(member.ExpiresAt)}
+				}
+				return opts.render(cmd.OutOrStdout(), members, []string{"ID", "LOGIN", "STATUS", "EXPIRES"}, rows)
+			},
+		},
+		newAddMemberCommand(opts),
+		&cobra.Command{
+			Use:   "remove-member GROUP_ID USER_ID",
+			Short: "Remove a user from a group",
+			Args:  cobra.ExactArgs(2),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				if err := c.Groups.RemoveMember(cmd.Context(), args[0], args[1]); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s from %s\n", args[1], args[0])
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+func newAddMemberCommand(opts *options) *cobra.Command {
+	var duration time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "add-member GROUP_ID USER_ID",
+		Short: "Add a user to a group",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := opts.client()
+			if err != nil {
+				return err
+			}
+
+			var expiresAt *time.Time
+			if duration > 0 {
+				at := time.Now().Add(duration).UTC()
+				expiresAt = &at
+			}
+
+			if err := c.Groups.AddMember(cmd.Context(), args[0], args[1], expiresAt); err != nil {
+				return err
+			}
+
+			if expiresAt != nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "Added %s to %s until %s\n", args[1], args[0], formatTime(expiresAt))
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Added %s to %s\n", args[1], args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&duration, "for", 0, "make the membership expire after this duration, e.g. 72h")
+	return cmd
+}
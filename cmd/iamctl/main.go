@@ -0,0 +1,97 @@
+// Command iamctl runs common IAM operations against a running server through
+// pkg/client. Server URLs and tokens are kept in named profiles.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/iamBelugaa/iam/pkg/client"
+)
+
+// options holds the global flags.
+type options struct {
+	configPath string
+	profile    string
+	url        string
+	token      string
+	output     string
+}
+
+func main() {
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "iamctl:", err)
+		os.Exit(1)
+	}
+}
+
+func newRootCommand() *cobra.Command {
+	opts := &options{}
+
+	root := &cobra.Command{
+		Use:           "iamctl",
+		Short:         "Manage users and groups through the IAM API",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if opts.output != outputTable && opts.output != outputJSON {
+				return fmt.Errorf("output must be %s or %s", outputTable, outputJSON)
+			}
+			return nil
+		},
+	}
+
+	flags := root.PersistentFlags()
+	flags.StringVar(&opts.configPath, "config", defaultConfigPath(), "path of the profiles file")
+	flags.StringVarP(&opts.profile, "profile", "p", os.Getenv("IAMCTL_PROFILE"), "profile to use instead of the current one")
+	flags.StringVar(&opts.url, "url", "", "server URL, overriding the profile")
+	flags.StringVar(&opts.token, "token", "", "access token, overriding the profile")
+	flags.StringVarP(&opts.output, "output", "o", outputTable, "output format: table or json")
+
+	root.AddCommand(
+		newGroupCommand(opts),
+		newUserCommand(opts),
+		newProfileCommand(opts),
+	)
+	return root
+}
+
+// client builds an API client from the selected profile and the flags.
+func (o *options) client() (*client.Client, error) {
+	cfg, err := loadConfig(o.configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile Profile
+	if o.url == "" || o.token == "" {
+		selected, err := cfg.selected(o.profile)
+		if err != nil && o.url == "" {
+			return nil, err
+		}
+		if selected != nil {
+			profile = *selected
+		}
+	}
+
+	if o.url != "" {
+		profile.URL = o.url
+	}
+	if o.token != "" {
+		profile.Token = o.token
+		profile.TokenEnv = ""
+	}
+
+	token, err := profile.accessToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var clientOpts []client.Option
+	if token != "" {
+		clientOpts = append(clientOpts, client.WithToken(token))
+	}
+	return client.New(profile.URL, clientOpts...)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/cobra"
+)
+
+func newProfileCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{Use: "profile", Aliases: []string{"profiles"}, Short: "Manage connection profiles"}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List profiles",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadConfig(opts.configPath)
+				if err != nil {
+					return err
+				}
+
+				names := make([]string, 0, len(cfg.Profiles))
+				for name := range cfg.Profiles {
+					names = append(names, name)
+				}
+				slices.Sort(names)
+
+				// Tokens are left out of both formats.
+				type profileSummary struct {
+					Name    string `json:"name"`
+					URL     string `json:"url"`
+					Current bool   `json:"current"`
+				}
+
+				summaries := make([]profileSummary, len(names))
+				rows := make([][]string, len(names))
+				for i, name := range names {
+					summaries[i] = profileSummary{Name: name, URL: cfg.Profiles[name].URL, Current: name == cfg.Current}
+
+					current := ""
+					if summaries[i].Current {
+						current = "*"
+					}
+					rows[i] = []string{current, name, summaries[i].URL}
+				}
+				return opts.render(cmd.OutOrStdout(), summaries, []string{"CURRENT", "NAME", "URL"}, rows)
+			},
+		},
+		newProfileSetCommand(opts),
+		&cobra.Command{
+			Use:   "use NAME",
+			Short: "Make a profile the current one",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				cfg, err := loadConfig(opts.configPath)
+				if err != nil {
+					return err
+				}
+				if _, ok := cfg.Profiles[args[0]]; !ok {
+					return fmt.Errorf("profile %q does not exist", args[0])
+				}
+
+				cfg.Current = args[0]
+				if err := cfg.save(opts.configPath); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Switched to profile %s\n", args[0])
+				return nil
+			},
+		},
+	)
+	return cmd
+}
+
+func newProfileSetCommand(opts *options) *cobra.Command {
+	var profile Profile
+
+	cmd := &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadConfig(opts.configPath)
+			if err != nil {
+				return err
+			}
+
+			existing, ok := cfg.Profiles[args[0]]
+			if !ok {
+				if profile.URL == "" {
+					return fmt.Errorf("--url is required for a new profile")
+				}
+				existing = &Profile{}
+				cfg.Profiles[args[0]] = existing
+			}
+
+			flags := cmd.Flags()
+			if flags.Changed("url") {
+				existing.URL = profile.URL
+			}
+			if flags.Changed("token") {
+				existing.Token = profile.Token
+			}
+			if flags.Changed("token-env") {
+				existing.TokenEnv = profile.TokenEnv
+			}
+			if cfg.Current == "" {
+				cfg.Current = args[0]
+			}
+
+			if err := cfg.save(opts.configPath); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Saved profile %s\n", args[0])
+			return nil
+		},
+	}
+
+	// The global --url and --token flags are shadowed here on purpose: they
+	// set the profile rather than override it.
+	cmd.Flags().StringVar(&profile.URL, "url", "", "server URL")
+	cmd.Flags().StringVar(&profile.Token, "token", "", "access token to store in the profile")
+	cmd.Flags().StringVar(&profile.TokenEnv, "token-env", "", "environment variable to read the access token from")
+	return cmd
+}
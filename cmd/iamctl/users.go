@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/iamBelugaa/iam/pkg/client"
+)
+
+func newUserCommand(opts *options) *cobra.Command {
+	cmd := &cobra.Command{Use: "user", Aliases: []string{"users"}, Short: "Manage users"}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "list",
+			Short: "List all users",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				users := make([]*client.User, 0)
+				for user, err := range c.Users.All(cmd.Context()) {
+					if err != nil {
+						return err
+					}
+					users = append(users, user)
+				}
+
+				rows := make([][]string, len(users))
+				for i, user := range users {
+					rows[i] = userRow(user)
+				}
+				return opts.render(cmd.OutOrStdout(), users, userHeader, rows)
+			},
+		},
+		&cobra.Command{
+			Use:   "get USER_ID",
+			Short: "Show a user",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				c, err := opts.client()
+				if err != nil {
+					return err
+				}
+
+				user, err := c.Users.Get(cmd.Context(), args[0])
+				if err != nil {
+					return err
+				}
+				return opts.render(cmd.OutOrStdout(), user, userHeader, [][]string{userRow(user)})
+			},
+		},
+		newImportCommand(opts),
+	)
+	return cmd
+}
+
+var userHeader = []string{"ID", "LOGIN", "EMAIL", "NAME", "STATUS", "LAST LOGIN"}
+
+func userRow(user *client.User) []string {
+	return []string{
+		user.ID, user.Login, user.Email, strings.TrimSpace(user.FirstName + " " + user.LastName),
+		user.Status, formatTime(user.LastLogin),
+	}
+}
+
+// importResult is the outcome of one CSV row.
+type importResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	UserID string `json:"userId,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func newImportCommand(opts *options) *cobra.Command {
+	var (
+		activate bool
+		dryRun   bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import FILE.csv",
+		Short: "Create users from a CSV file",
+		Long: `Create one user per row of a CSV file with a header row.
+
+The email, firstName and lastName columns are required. login defaults to the
+email and password is optional; any other column is set as a profile
+attribute. Rows that fail are reported and the import continues.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			requests, err := readUsersCSV(file)
+			if err != nil {
+				return err
+			}
+			for _, req := range requests {
+				req.Activate = activate
+			}
+
+			var c *client.Client
+			if !dryRun {
+				if c, err = opts.client(); err != nil {
+					return err
+				}
+			}
+
+			results := make([]*importResult, len(requests))
+			failed := 0
+			for i, req := range requests {
+				// Line 1 is the header.
+				results[i] = &importResult{Line: i + 2, Email: req.Email}
+				if dryRun {
+					continue
+				}
+
+				user, err := c.Users.Create(cmd.Context(), req)
+				if err != nil {
+					results[i].Error = err.Error()
+					failed++
+					continue
+				}
+				results[i].UserID = user.ID
+			}
+
+			rows := make([][]string, len(results))
+			for i, result := range results {
+				status := "created"
+				switch {
+				case dryRun:
+					status = "valid"
+				case result.Error != "":
+					status = result.Error
+				}
+				rows[i] = []string{strconv.Itoa(result.Line), result.Email, result.UserID, status}
+			}
+
+			if err := opts.render(cmd.OutOrStdout(), results, []string{"LINE", "EMAIL", "USER ID", "RESULT"}, rows); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d users could not be created", failed, len(results))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&activate, "activate", false, "activate the users and send activation emails")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the file without creating users")
+	return cmd
+}
+
+func readUsersCSV(r io.Reader) ([]*client.CreateUserRequest, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("the CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	for _, required := range []string{"email", "firstName", "lastName"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("the CSV header has no %s column", required)
+		}
+	}
+
+	var requests []*client.CreateUserRequest
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		req := &client.CreateUserRequest{Profile: make(map[string]any)}
+		for name, i := range columns {
+			value := strings.TrimSpace(record[i])
+			switch name {
+			case "email":
+				req.Email = value
+			case "firstName":
+				req.FirstName = value
+			case "lastName":
+				req.LastName = value
+			case "login":
+				req.Login = value
+			case "password":
+				req.Password = value
+			default:
+				if value != "" {
+					req.Profile[name] = value
+				}
+			}
+		}
+
+		if req.Email == "" || req.FirstName == "" || req.LastName == "" {
+			return nil, fmt.Errorf("line %d: email, firstName and lastName are required", line)
+		}
+		if req.Login == "" {
+			req.Login = req.Email
+		}
+		requests = append(requests, req)
+	}
+
+	return requests, nil
+}
@@ -0,0 +1,44 @@
+// Command openapi writes the OpenAPI spec of the HTTP API. The spec is built
+// from the same typed route registrations the server uses, so no services or
+// configuration are needed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/handlers"
+)
+
+func main() {
+	output := flag.String("o", "", "file to write the spec to; defaults to stdout")
+	flag.Parse()
+
+	if err := run(*output); err != nil {
+		fmt.Fprintln(os.Stderr, "openapi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(output string) error {
+	spec := handlers.Setup(&handlers.Config{
+		Router: chi.NewRouter(),
+		Log:    zap.NewNop().Sugar(),
+	})
+
+	data, err := spec.JSON()
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(output, data, 0o644)
+}
@@ -3,26 +3,110 @@ package main
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/joho/godotenv"
+	okta_sdk "github.com/okta/okta-sdk-golang/v5/okta"
 	"go.uber.org/zap"
 
+	"github.com/iamBelugaa/iam/internal/auth"
 	"github.com/iamBelugaa/iam/internal/config"
+	grpc_server "github.com/iamBelugaa/iam/internal/grpc"
 	"github.com/iamBelugaa/iam/internal/handlers"
+	"github.com/iamBelugaa/iam/internal/orgs"
+	"github.com/iamBelugaa/iam/internal/ratelimit"
+	"github.com/iamBelugaa/iam/internal/redaction"
+	"github.com/iamBelugaa/iam/internal/secrets"
+	access_service "github.com/iamBelugaa/iam/internal/services/access"
+	accessrequest_service "github.com/iamBelugaa/iam/internal/services/accessrequest"
+	apikey_service "github.com/iamBelugaa/iam/internal/services/apikey"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	avatar_service "github.com/iamBelugaa/iam/internal/services/avatar"
+	backup_service "github.com/iamBelugaa/iam/internal/services/backup"
+	batch_service "github.com/iamBelugaa/iam/internal/services/batch"
+	catalog_service "github.com/iamBelugaa/iam/internal/services/catalog"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	consent_service "github.com/iamBelugaa/iam/internal/services/consent"
+	dashboard_service "github.com/iamBelugaa/iam/internal/services/dashboard"
+	deactivation_service "github.com/iamBelugaa/iam/internal/services/deactivation"
+	defaultgroup_service "github.com/iamBelugaa/iam/internal/services/defaultgroup"
+	device_service "github.com/iamBelugaa/iam/internal/services/device"
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	drift_service "github.com/iamBelugaa/iam/internal/services/drift"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+	export_service "github.com/iamBelugaa/iam/internal/services/export"
+	expression_service "github.com/iamBelugaa/iam/internal/services/expression"
 	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupclone_service "github.com/iamBelugaa/iam/internal/services/groupclone"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	grouptrash_service "github.com/iamBelugaa/iam/internal/services/grouptrash"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	helpdesk_service "github.com/iamBelugaa/iam/internal/services/helpdesk"
+	history_service "github.com/iamBelugaa/iam/internal/services/history"
+	invitation_service "github.com/iamBelugaa/iam/internal/services/invitation"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	membershipevent_service "github.com/iamBelugaa/iam/internal/services/membershipevent"
+	migration_service "github.com/iamBelugaa/iam/internal/services/migration"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	profiling_service "github.com/iamBelugaa/iam/internal/services/profiling"
+	provisioning_service "github.com/iamBelugaa/iam/internal/services/provisioning"
+	replay_service "github.com/iamBelugaa/iam/internal/services/replay"
+	report_service "github.com/iamBelugaa/iam/internal/services/report"
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
+	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	scaling_service "github.com/iamBelugaa/iam/internal/services/scaling"
+	scheduledchange_service "github.com/iamBelugaa/iam/internal/services/scheduledchange"
+	security_service "github.com/iamBelugaa/iam/internal/services/security"
+	selfservice_service "github.com/iamBelugaa/iam/internal/services/selfservice"
+	serviceaccount_service "github.com/iamBelugaa/iam/internal/services/serviceaccount"
+	session_service "github.com/iamBelugaa/iam/internal/services/session"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	sync_service "github.com/iamBelugaa/iam/internal/services/sync"
+	tokenexchange_service "github.com/iamBelugaa/iam/internal/services/tokenexchange"
+	usage_service "github.com/iamBelugaa/iam/internal/services/usage"
 	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	config_worker "github.com/iamBelugaa/iam/internal/workers/config"
+	dashboard_worker "github.com/iamBelugaa/iam/internal/workers/dashboard"
+	directory_worker "github.com/iamBelugaa/iam/internal/workers/directory"
+	drift_worker "github.com/iamBelugaa/iam/internal/workers/drift"
+	expiry_worker "github.com/iamBelugaa/iam/internal/workers/expiry"
+	guest_worker "github.com/iamBelugaa/iam/internal/workers/guest"
+	inactivity_worker "github.com/iamBelugaa/iam/internal/workers/inactivity"
+	membershipevent_worker "github.com/iamBelugaa/iam/internal/workers/membershipevent"
+	pendingchange_worker "github.com/iamBelugaa/iam/internal/workers/pendingchange"
+	reconciliation_worker "github.com/iamBelugaa/iam/internal/workers/reconciliation"
+	retry_worker "github.com/iamBelugaa/iam/internal/workers/retry"
+	scheduledchange_worker "github.com/iamBelugaa/iam/internal/workers/scheduledchange"
+	secrets_worker "github.com/iamBelugaa/iam/internal/workers/secrets"
+	security_worker "github.com/iamBelugaa/iam/internal/workers/security"
+	serviceaccount_worker "github.com/iamBelugaa/iam/internal/workers/serviceaccount"
+	snapshot_worker "github.com/iamBelugaa/iam/internal/workers/snapshot"
+	"github.com/iamBelugaa/iam/pkg/hooks"
 	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
 	"github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/okta/memory"
+	"github.com/iamBelugaa/iam/pkg/request"
 )
 
 func main() {
-	log := logger.New("flexera-iam")
+	logLevel := zap.NewAtomicLevel()
+	redactor := logger.NewRedactor()
+	log := logger.New("flexera-iam", logLevel, redactor)
 	defer func() {
 		if err := log.Sync(); err != nil {
 			log.Infow("sync error", "error", err)
@@ -35,7 +119,7 @@ func main() {
 
 	log.Infow("Starting Flexera IAM Platform...")
 
-	if err := run(log); err != nil {
+	if err := run(log, logLevel, redactor); err != nil {
 		log.Infow("startup error", "error", err)
 		if err := log.Sync(); err != nil {
 			log.Infow("sync error", "error", err)
@@ -44,14 +128,27 @@ func main() {
 	}
 }
 
-func run(log *zap.SugaredLogger) error {
+func run(log *zap.SugaredLogger, logLevel zap.AtomicLevel, redactor *logger.Redactor) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return err
 	}
-	log.Infow("Configuration loaded successfully")
+	logLevel.SetLevel(cfg.Log.Level)
+	redactor.Configure(cfg.Log.RedactFields, cfg.Log.RedactStrict)
+	log.Infow("Configuration loaded successfully", "file", cfg.File.Path)
 
-	oktaClient, err := okta.NewClient(cfg.Okta)
+	secretsProvider, err := secrets.NewProvider(context.Background(), cfg.Secrets)
+	if err != nil {
+		return err
+	}
+	secretStore := secrets.NewStore(log, secretsProvider)
+
+	standalone := cfg.Okta.Backend == config.OktaBackendMemory
+	if standalone {
+		log.Infow("Running standalone: Okta is served from memory and access tokens are not verified")
+	}
+
+	oktaClient, err := okta.New(context.Background(), cfg.Okta, secretStore, memory.NewOrg)
 	if err != nil {
 		return err
 	}
@@ -61,18 +158,530 @@ func run(log *zap.SugaredLogger) error {
 	}
 	log.Infow("Okta service initialized successfully")
 
+	// Every org gets its own client, so rate limiting and caching are isolated.
+	oktaClients := make(map[string]okta.Backend, len(cfg.Orgs))
+	spokeClients := make(map[string]*okta_sdk.APIClient, len(cfg.Orgs))
+	for name, orgCfg := range cfg.Orgs {
+		spokeClient, err := okta.New(context.Background(), orgCfg, secretStore, memory.NewOrg)
+		if err != nil {
+			return err
+		}
+
+		if err := spokeClient.TestConnection(context.Background()); err != nil {
+			return fmt.Errorf("okta org %s: %w", name, err)
+		}
+
+		oktaClients[name] = spokeClient
+		spokeClients[name] = spokeClient.SDK()
+		log.Infow("Okta org initialized successfully", "org", name)
+	}
+
+	// backgroundCtx scopes goroutines that live as long as the server, such
+	// as signing key refreshes and background workers.
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Workers and jobs call Okta as the sync and batch subsystems, so they are
+	// held to their share of each org's rate limits and never crowd out
+	// requests callers are waiting for.
+	syncCtx := okta.WithSubsystem(backgroundCtx, okta.SubsystemSync)
+	batchCtx := okta.WithSubsystem(backgroundCtx, okta.SubsystemBatch)
+
+	verifier := auth.NewStandaloneVerifier()
+	if !standalone {
+		verifier, err = auth.NewVerifier(backgroundCtx, cfg.Okta)
+		if err != nil {
+			return err
+		}
+	}
+	log.Infow("Access token verifier initialized successfully")
+
+	rateLimiter, err := ratelimit.New(log, cfg.RateLimit, verifier)
+	if err != nil {
+		return err
+	}
+
+	var storagePool *pgxpool.Pool
+	if cfg.Storage.Backend == config.StorageBackendPostgres {
+		storagePool, err = objectstore.OpenPostgres(context.Background(), objectstore.PostgresOptions{
+			URL:             cfg.Storage.PostgresURL,
+			MaxConns:        cfg.Storage.MaxConns,
+			MinConns:        cfg.Storage.MinConns,
+			MaxConnIdleTime: cfg.Storage.MaxConnIdleTime,
+			MaxConnLifetime: cfg.Storage.MaxConnLifetime,
+		})
+		if err != nil {
+			return err
+		}
+		defer storagePool.Close()
+		log.Infow("Postgres storage initialized successfully")
+	}
+
+	// openStore opens the store a feature keeps its state in: its namespace
+	// in Postgres, its own directory, or memory.
+	openStore := func(namespace, dir string) (objectstore.Store, error) {
+		switch cfg.Storage.Backend {
+		case config.StorageBackendPostgres:
+			return objectstore.NewPostgresStore(storagePool, namespace), nil
+		case config.StorageBackendMemory:
+			return objectstore.NewMemoryStore(), nil
+		default:
+			return objectstore.NewFileStore(dir)
+		}
+	}
+
+	auditStore, err := openStore("audit", filepath.Join(cfg.Storage.Dir, "audit"))
+	if err != nil {
+		return err
+	}
+	jobStore, err := openStore("jobs", filepath.Join(cfg.Storage.Dir, "jobs"))
+	if err != nil {
+		return err
+	}
+	groupStore, err := openStore("groups", filepath.Join(cfg.Storage.Dir, "groups"))
+	if err != nil {
+		return err
+	}
+	sagaStore, err := openStore("sagas", filepath.Join(cfg.Storage.Dir, "sagas"))
+	if err != nil {
+		return err
+	}
+	sodStore, err := openStore("sod", filepath.Join(cfg.Storage.Dir, "sod"))
+	if err != nil {
+		return err
+	}
+	guestStore, err := openStore("guests", filepath.Join(cfg.Storage.Dir, "guests"))
+	if err != nil {
+		return err
+	}
+	serviceAccountStore, err := openStore("service-accounts", filepath.Join(cfg.Storage.Dir, "service-accounts"))
+	if err != nil {
+		return err
+	}
+	invitationStore, err := openStore("invitations", filepath.Join(cfg.Storage.Dir, "invitations"))
+	if err != nil {
+		return err
+	}
+	webhookStore, err := openStore("webhooks", filepath.Join(cfg.Storage.Dir, "webhooks"))
+	if err != nil {
+		return err
+	}
+
 	router := chi.NewRouter()
-	usersService := user_service.New(log, oktaClient.SDK())
-	groupsService := group_service.New(log, oktaClient.SDK())
+	auditService := audit_service.New(log, auditStore, redactor)
+	usersService := user_service.New(log, oktaClient.SDK(), hooks.Default)
+	sodService, err := sod_service.New(log, oktaClient.SDK(), sodStore, auditService)
+	if err != nil {
+		return err
+	}
+	guestsService, err := guest_service.New(log, cfg.Guests, guestStore, usersService, auditService)
+	if err != nil {
+		return err
+	}
+	groupPolicyService := grouppolicy_service.New(log, cfg.GroupPolicy)
+	expressionService := expression_service.New(log, oktaClient.SDK(), usersService)
+	groupsService, err := group_service.New(
+		log, oktaClient.SDK(), groupStore, sodService, guestsService, groupPolicyService, hooks.Default,
+	)
+	if err != nil {
+		return err
+	}
+	rolesService := role_service.New(log, oktaClient.SDK())
+	appsService := app_service.New(log, oktaClient.SDK())
+	accessService := access_service.New(log, oktaClient.SDK(), usersService, appsService, rolesService)
+	exportService := export_service.New(log, oktaClient.SDK())
+	reportsService := report_service.New(log, oktaClient.SDK(), cfg.Reports)
+	batchService := batch_service.New(log, usersService, groupsService)
+	webhooksService, err := webhook_service.New(log, webhookStore)
+	if err != nil {
+		return err
+	}
+	invitationsService, err := invitation_service.New(
+		log, cfg.Invitations, invitationStore, usersService, auditService, webhooksService,
+	)
+	if err != nil {
+		return err
+	}
+	deviceService := device_service.New(log, cfg.DeviceAuth, cfg.Okta.Issuer, auditService)
+	sessionService := session_service.New(log, cfg.SessionExchange, oktaClient.SDK(), auditService)
+
+	var tokenExchangeSecret *secrets.Secret
+	if cfg.TokenExchange.ClientID != "" {
+		tokenExchangeSecret, err = secretStore.Load(context.Background(), cfg.TokenExchange.ClientSecretName)
+		if err != nil {
+			return err
+		}
+	}
+	tokenExchangeService := tokenexchange_service.New(
+		log, cfg.TokenExchange, cfg.Okta.Issuer, tokenExchangeSecret, auditService,
+	)
+	accessRequestsService := accessrequest_service.New(log, groupsService, auditService)
+	consentStore, err := openStore("consent", cfg.Consent.StorageDir)
+	if err != nil {
+		return err
+	}
+	consentService := consent_service.New(log, cfg.Consent, consentStore)
+	catalogService := catalog_service.New(
+		log, usersService, groupsService, accessRequestsService, auditService, consentService,
+	)
+	syncService := sync_service.New(log, oktaClient.SDK(), spokeClients, auditService)
+	eventsService := event_service.New(log, cfg.Events)
+	jobsService := job_service.New(batchCtx, log, jobStore, eventsService)
+	sagasService, err := saga_service.New(log, cfg.Reconciliation, sagaStore)
+	if err != nil {
+		return err
+	}
+	provisioningService := provisioning_service.New(log, sagasService, usersService, groupsService)
+	serviceAccountsService, err := serviceaccount_service.New(
+		log, oktaClient.SDK(), cfg.ServiceAccounts, serviceAccountStore, auditService, jobsService,
+	)
+	if err != nil {
+		return err
+	}
+
+	usageService := usage_service.New(log, oktaClient.SDK(), cfg.Reports, appsService, auditService, jobsService)
+
+	orgClients := maps.Clone(spokeClients)
+	orgClients[cfg.Okta.Name] = oktaClient.SDK()
+	migrationService := migration_service.New(log, orgClients, jobsService, auditService)
+	changesService := change_service.New(log, cfg.Changes.MaxChanges)
+	directoryService := directory_service.New(
+		log, usersService, groupsService, changesService, 2*cfg.Workers.DirectoryRefreshInterval,
+	)
+	driftService := drift_service.New(
+		log, oktaClient.SDK(), cfg.Drift, cfg.Okta.ClientID, changesService, webhooksService,
+	)
+	securityService := security_service.New(
+		log, oktaClient.SDK(), cfg.Security, usersService, groupsService, auditService, webhooksService,
+	)
+
+	avatarStore, err := openStore("avatars", cfg.Avatars.StorageDir)
+	if err != nil {
+		return err
+	}
+	avatarsService := avatar_service.New(log, cfg.Avatars, avatarStore, usersService)
+
+	membershipEventStore, err := openStore("membership-events", cfg.MembershipEvents.StorageDir)
+	if err != nil {
+		return err
+	}
+	membershipEventsService := membershipevent_service.New(
+		log, oktaClient.SDK(), cfg.MembershipEvents, membershipEventStore, eventsService,
+	)
+
+	historyStore, err := openStore("history", cfg.History.StorageDir)
+	if err != nil {
+		return err
+	}
+	historyService := history_service.New(
+		log, oktaClient.SDK(), cfg.History, historyStore, groupsService, directoryService, membershipEventsService,
+	)
+
+	groupMetadataStore, err := openStore("group-metadata", cfg.GroupMetadata.StorageDir)
+	if err != nil {
+		return err
+	}
+	groupMetadataService := groupmetadata_service.New(log, groupMetadataStore)
+
+	defaultGroupStore, err := openStore("default-groups", cfg.DefaultGroups.StorageDir)
+	if err != nil {
+		return err
+	}
+	defaultGroupsService := defaultgroup_service.New(
+		log, cfg.DefaultGroups, defaultGroupStore, usersService, groupsService, auditService, jobsService,
+	)
+	hooks.After(hooks.CreateUser, "default-groups", defaultGroupsService.AfterCreateUser)
+
+	profilingStore, err := openStore("profiling", cfg.Profiling.StorageDir)
+	if err != nil {
+		return err
+	}
+	profilingService := profiling_service.New(
+		log, profilingStore, usersService, groupsService, appsService, auditService,
+	)
+
+	helpdeskService := helpdesk_service.New(log, usersService, auditService)
+	selfServiceService := selfservice_service.New(log, cfg.SelfService, usersService, accessService, auditService)
+
+	pendingChangeStore, err := openStore("pending-changes", cfg.PendingChanges.StorageDir)
+	if err != nil {
+		return err
+	}
+	pendingChangesService := pendingchange_service.New(
+		log, cfg.PendingChanges, pendingChangeStore, groupsService, auditService,
+	)
+
+	scheduledChangeStore, err := openStore("scheduled-changes", cfg.ScheduledChanges.StorageDir)
+	if err != nil {
+		return err
+	}
+	scheduledChangeService := scheduledchange_service.New(
+		log, cfg.ScheduledChanges, scheduledChangeStore, groupsService, pendingChangesService, auditService,
+	)
+
+	apiKeyStore, err := openStore("api-keys", cfg.APIKeys.StorageDir)
+	if err != nil {
+		return err
+	}
+	apiKeysService := apikey_service.New(log, cfg.APIKeys, apiKeyStore, auditService)
+
+	var backupStore objectstore.Store
+	if cfg.Backups.Storage == "s3" {
+		backupStore, err = objectstore.NewS3Store(context.Background(), objectstore.S3Options{
+			Bucket:   cfg.Backups.S3Bucket,
+			Prefix:   cfg.Backups.S3Prefix,
+			Region:   cfg.Backups.S3Region,
+			Endpoint: cfg.Backups.S3Endpoint,
+		})
+	} else {
+		backupStore, err = openStore("backups", cfg.Backups.StorageDir)
+	}
+	if err != nil {
+		return err
+	}
+	backupService := backup_service.New(log, oktaClient.SDK(), backupStore, groupsService, sodService, auditService)
+	verifier.AcceptAPIKeys(apiKeysService, handlers.IsRead)
+
+	deactivationService := deactivation_service.New(
+		log, cfg.BulkDeactivation, usersService, groupsService, auditService, jobsService,
+	)
+
+	groupTrashStore, err := openStore("group-trash", cfg.GroupTrash.StorageDir)
+	if err != nil {
+		return err
+	}
+	groupTrashService := grouptrash_service.New(log, cfg.GroupTrash, groupTrashStore, groupsService, groupMetadataService)
+	groupCloneService := groupclone_service.New(log, cfg.GroupClone, groupsService, appsService, jobsService)
+	hooks.Before(hooks.DeleteGroup, "group-trash", groupTrashService.BeforeDeleteGroup)
+	hooks.After(hooks.DeleteGroup, "group-trash", groupTrashService.AfterDeleteGroup)
+
+	retryStore, err := openStore("retry-queue", cfg.RetryQueue.StorageDir)
+	if err != nil {
+		return err
+	}
+	retryQueueService := retry_service.New(log, cfg.RetryQueue, retryStore, router)
+
+	replayStore, err := openStore("replays", cfg.Replay.StorageDir)
+	if err != nil {
+		return err
+	}
+	replayService := replay_service.New(log, cfg.Replay, replayStore, router, standalone)
+
+	backends := []okta.Backend{oktaClient}
+	for _, name := range slices.Sorted(maps.Keys(oktaClients)) {
+		backends = append(backends, oktaClients[name])
+	}
+	scalingService := scaling_service.New(log, jobsService, webhooksService, retryQueueService, backends)
+	dashboardService := dashboard_service.New(log, cfg.Dashboard, changesService, directoryService, scalingService)
+
+	// The other orgs get their own service instances, so their guests, SoD
+	// policies, join policies and membership expirations are kept apart too.
+	// Hooks only run for the primary org; the group naming policy applies to
+	// all.
+	orgServices := make([]*orgs.Services, 0, len(cfg.Orgs))
+	for name, client := range spokeClients {
+		orgUsers := user_service.New(log, client, nil)
+		orgGuestStore, err := openStore("guests:"+name, filepath.Join(cfg.Storage.Dir, "orgs", name, "guests"))
+		if err != nil {
+			return err
+		}
+		orgGuests, err := guest_service.New(log, cfg.Guests, orgGuestStore, orgUsers, auditService)
+		if err != nil {
+			return err
+		}
+		orgSoDStore, err := openStore("sod:"+name, filepath.Join(cfg.Storage.Dir, "orgs", name, "sod"))
+		if err != nil {
+			return err
+		}
+		orgSoD, err := sod_service.New(log, client, orgSoDStore, auditService)
+		if err != nil {
+			return err
+		}
+		orgGroupStore, err := openStore("groups:"+name, filepath.Join(cfg.Storage.Dir, "orgs", name, "groups"))
+		if err != nil {
+			return err
+		}
+		orgGroups, err := group_service.New(log, client, orgGroupStore, orgSoD, orgGuests, groupPolicyService, nil)
+		if err != nil {
+			return err
+		}
+
+		orgServices = append(orgServices, &orgs.Services{
+			Name:   name,
+			Domain: cfg.Orgs[name].Domain,
+			Users:  orgUsers,
+			Groups: orgGroups,
+			Roles:  role_service.New(log, client),
+		})
+
+		orgExpiryWorker := expiry_worker.New(log, cfg.Workers.MembershipExpiryInterval, orgGroups, auditService)
+		go orgExpiryWorker.Run(syncCtx)
+	}
+
+	orgRegistry := orgs.NewRegistry(&orgs.Services{
+		Name:   cfg.Okta.Name,
+		Domain: cfg.Okta.Domain,
+		Users:  usersService,
+		Groups: groupsService,
+		Roles:  rolesService,
+	}, orgServices...)
 
 	handlers.Setup(&handlers.Config{
-		Config:        cfg,
+		Config:                 cfg,
+		Log:                    log,
+		Router:                 router,
+		UsersService:           usersService,
+		GroupsService:          groupsService,
+		RolesService:           rolesService,
+		ReportsService:         reportsService,
+		BatchService:           batchService,
+		WebhooksService:        webhooksService,
+		Verifier:               verifier,
+		AccessRequestsService:  accessRequestsService,
+		AccessService:          accessService,
+		SyncService:            syncService,
+		SoDService:             sodService,
+		AvatarsService:         avatarsService,
+		ExportService:          exportService,
+		ServiceAccountsService: serviceAccountsService,
+		JobsService:            jobsService,
+		GuestsService:          guestsService,
+		AppsService:            appsService,
+		InvitationsService:     invitationsService,
+		DeviceService:          deviceService,
+		SessionService:         sessionService,
+		TokenExchangeService:   tokenExchangeService,
+		CatalogService:         catalogService,
+		ConsentService:         consentService,
+		UsageService:           usageService,
+		DirectoryService:       directoryService,
+		HistoryService:         historyService,
+		MembershipEventService: membershipEventsService,
+		ChangesService:         changesService,
+		DriftService:           driftService,
+		SecurityService:        securityService,
+		RetryQueueService:      retryQueueService,
+		ReplayService:          replayService,
+		ScalingService:         scalingService,
+		DashboardService:       dashboardService,
+		EventsService:          eventsService,
+		EventsHeartbeat:        cfg.Events.HeartbeatInterval,
+		TrustedProxies:         cfg.Server.TrustedProxies,
+		RequestTimeouts: handlers.RequestTimeouts{
+			Read:   cfg.RequestTimeouts.Read,
+			Write:  cfg.RequestTimeouts.Write,
+			Bulk:   cfg.RequestTimeouts.Bulk,
+			Routes: cfg.RequestTimeouts.Routes,
+		},
+		SagasService:           sagasService,
+		ProvisioningService:    provisioningService,
+		GroupPolicyService:     groupPolicyService,
+		ExpressionService:      expressionService,
+		GroupMetadataService:   groupMetadataService,
+		GroupAdminGroups:       cfg.GroupMetadata.AdminGroups,
+		DefaultGroupsService:   defaultGroupsService,
+		ProfilingService:       profilingService,
+		HelpdeskService:        helpdeskService,
+		HelpdeskGroups:         cfg.Helpdesk.Groups,
+		HelpdeskClientIDs:      cfg.Helpdesk.ClientIDs,
+		SelfServiceService:     selfServiceService,
+		APIKeysService:         apiKeysService,
+		PendingChangesService:  pendingChangesService,
+		ScheduledChangeService: scheduledChangeService,
+		MigrationService:       migrationService,
+		BackupService:          backupService,
+		DeactivationService:    deactivationService,
+		GroupTrashService:      groupTrashService,
+		GroupCloneService:      groupCloneService,
+		QueueRetries:           cfg.RetryQueue.Enabled,
+		CaptureFailures:        cfg.Replay.CaptureEnabled,
+		Orgs:                   orgRegistry,
+		RateLimiter:            rateLimiter,
+		Redaction:              redaction.NewPolicy(cfg.Redactions),
+		ValidateRequests:       cfg.Server.ValidateRequests,
+		RequestDecoding:        request.Mode(cfg.Server.RequestDecoding),
+	})
+
+	expiryWorker := expiry_worker.New(log, cfg.Workers.MembershipExpiryInterval, groupsService, auditService)
+	go expiryWorker.Run(syncCtx)
+
+	secretsWorker := secrets_worker.New(log, cfg.Secrets.RefreshInterval, secretStore)
+	go secretsWorker.Run(syncCtx)
+
+	if cfg.File.Path != "" {
+		configWorker := config_worker.New(log, cfg, func(next *config.Config) {
+			logLevel.SetLevel(next.Log.Level)
+			redactor.Configure(next.Log.RedactFields, next.Log.RedactStrict)
+			oktaClient.Tune(next.Okta)
+			for name, orgCfg := range next.Orgs {
+				if client, ok := oktaClients[name]; ok {
+					client.Tune(orgCfg)
+				}
+			}
+		})
+		go configWorker.Run(syncCtx)
+	}
+
+	directoryWorker := directory_worker.New(log, cfg.Workers.DirectoryRefreshInterval, directoryService)
+	go directoryWorker.Run(syncCtx)
+
+	snapshotWorker := snapshot_worker.New(log, cfg.Workers.DirectoryRefreshInterval, historyService)
+	go snapshotWorker.Run(syncCtx)
+
+	driftWorker := drift_worker.New(log, cfg.Drift.CheckInterval, driftService)
+	go driftWorker.Run(syncCtx)
+
+	dashboardWorker := dashboard_worker.New(log, cfg.Dashboard.UpdateInterval, dashboardService)
+	go dashboardWorker.Run(syncCtx)
+
+	securityWorker := security_worker.New(log, cfg.Security.DetectionInterval, securityService)
+	go securityWorker.Run(syncCtx)
+
+	membershipEventWorker := membershipevent_worker.New(log, cfg.MembershipEvents.PollInterval, membershipEventsService)
+	go membershipEventWorker.Run(syncCtx)
+
+	retryWorker := retry_worker.New(log, cfg.RetryQueue.Interval, retryQueueService)
+	go retryWorker.Run(syncCtx)
+
+	reconciliationWorker := reconciliation_worker.New(log, cfg.Reconciliation.Interval, sagasService)
+	go reconciliationWorker.Run(syncCtx)
+
+	guestWorker := guest_worker.New(log, cfg.Workers.GuestInterval, guestsService)
+	go guestWorker.Run(syncCtx)
+
+	pendingChangeWorker := pendingchange_worker.New(log, cfg.Workers.PendingChangeInterval, pendingChangesService)
+	go pendingChangeWorker.Run(syncCtx)
+
+	scheduledChangeWorker := scheduledchange_worker.New(log, cfg.ScheduledChanges.Interval, scheduledChangeService)
+	go scheduledChangeWorker.Run(syncCtx)
+
+	serviceAccountWorker := serviceaccount_worker.New(
+		log, cfg.Workers.ServiceAccountInterval, serviceAccountsService, auditService,
+	)
+	go serviceAccountWorker.Run(syncCtx)
+
+	if cfg.Workers.InactiveUserSuspend {
+		inactivityWorker := inactivity_worker.New(
+			log, cfg.Workers.InactiveUserInterval, reportsService, usersService, auditService,
+		)
+		go inactivityWorker.Run(syncCtx)
+	}
+
+	grpcServer := grpc_server.New(&grpc_server.Config{
 		Log:           log,
-		Router:        router,
+		Verifier:      verifier,
 		UsersService:  usersService,
 		GroupsService: groupsService,
+
+		AdminGroups:           cfg.GroupMetadata.AdminGroups,
+		GroupMetadataService:  groupMetadataService,
+		PendingChangesService: pendingChangesService,
 	})
 
+	if err := grpc_server.MountWeb(router, grpcServer, cfg.Server.GRPCWebOrigins); err != nil {
+		return err
+	}
+
 	server := http.Server{
 		Handler:      router,
 		Addr:         ":" + cfg.Server.Port,
@@ -82,7 +691,7 @@ func run(log *zap.SugaredLogger) error {
 	}
 
 	shutdown := make(chan os.Signal, 1)
-	serverErrors := make(chan error, 1)
+	serverErrors := make(chan error, 2)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
@@ -92,6 +701,18 @@ func run(log *zap.SugaredLogger) error {
 		}
 	}()
 
+	grpcListener, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+	if err != nil {
+		return fmt.Errorf("could not listen for gRPC: %w", err)
+	}
+
+	go func() {
+		log.Infow("gRPC server starting", "address", grpcListener.Addr().String())
+		if err := grpcServer.Serve(grpcListener); err != nil {
+			serverErrors <- fmt.Errorf("grpc: %w", err)
+		}
+	}()
+
 	select {
 	case err := <-serverErrors:
 		return fmt.Errorf("server error: %w", err)
@@ -106,6 +727,18 @@ func run(log *zap.SugaredLogger) error {
 		if err := server.Shutdown(ctx); err != nil {
 			return fmt.Errorf("could not stop server gracefully: %w", err)
 		}
+
+		grpcStopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
 	}
 
 	return nil
@@ -0,0 +1,266 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// APIKeyHeader carries the API key of machine clients that authenticate
+// without an access token.
+const APIKeyHeader = "X-API-Key"
+
+var (
+	ErrMissingToken      = errors.New("missing bearer token")
+	ErrAPIKeysDisabled   = errors.New("API keys are not accepted")
+	ErrInsufficientScope = errors.New("API key lacks the scope for this request")
+)
+
+// APIKeyVerifier returns the current, unrevoked key a client presented.
+type APIKeyVerifier interface {
+	Verify(ctx context.Context, rawKey string) (*models.APIKey, error)
+}
+
+type contextKey struct{}
+
+// Caller is the authenticated principal behind a request, derived from the
+// claims of a validated Okta access token or from an API key.
+type Caller struct {
+	UserID   string   `json:"userId"`
+	Subject  string   `json:"subject"`
+	ClientID string   `json:"clientId,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
+}
+
+// Verifier validates Okta access tokens against the issuer's signing keys.
+type Verifier struct {
+	issuer   string
+	audience string
+	jwksURL  string
+	keys     *jwk.AutoRefresh
+	// standalone accepts any token as the caller it names; see
+	// NewStandaloneVerifier.
+	standalone bool
+	// apiKeys verifies X-API-Key headers, and isRead tells which requests
+	// keys with only the read scope may make; see AcceptAPIKeys.
+	apiKeys APIKeyVerifier
+	isRead  func(r *http.Request) bool
+}
+
+// NewVerifier discovers the JWKS endpoint of the configured issuer and keeps
+// its signing keys refreshed in the background for the lifetime of ctx.
+func NewVerifier(ctx context.Context, cfg *config.OktaConfig) (*Verifier, error) {
+	jwksURL, err := discoverJWKSURL(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := jwk.NewAutoRefresh(ctx)
+	keys.Configure(jwksURL, jwk.WithMinRefreshInterval(15*time.Minute))
+
+	return &Verifier{issuer: cfg.Issuer, audience: cfg.Audience, jwksURL: jwksURL, keys: keys}, nil
+}
+
+// NewStandaloneVerifier returns a verifier for running without an Okta org,
+// which has no issuer to validate tokens against. It checks nothing: the
+// bearer token is taken as the caller's user ID, so any client can act as
+// any user. It must only be used with the in-memory Okta backend.
+func NewStandaloneVerifier() *Verifier {
+	return &Verifier{standalone: true}
+}
+
+// AcceptAPIKeys lets clients authenticate with an API key in the X-API-Key
+// header instead of a bearer token. isRead classifies requests for keys that
+// only hold the read scope.
+func (v *Verifier) AcceptAPIKeys(keys APIKeyVerifier, isRead func(r *http.Request) bool) {
+	v.apiKeys = keys
+	v.isRead = isRead
+}
+
+// VerifyAPIKey validates a raw API key for the request and returns its
+// caller, which has no user ID and is named apikey:<id>.
+func (v *Verifier) VerifyAPIKey(r *http.Request, rawKey string) (*Caller, error) {
+	if v.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	key, err := v.apiKeys.Verify(r.Context(), rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := models.APIKeyScopeWrite
+	if v.isRead(r) {
+		scope = models.APIKeyScopeRead
+	}
+	if !key.HasScope(scope) {
+		return nil, ErrInsufficientScope
+	}
+
+	subject := "apikey:" + key.ID
+	return &Caller{Subject: subject, ClientID: subject, Scopes: key.Scopes}, nil
+}
+
+// Verify parses and validates a raw access token and returns its caller.
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (*Caller, error) {
+	if v.standalone {
+		return &Caller{UserID: rawToken, Subject: rawToken}, nil
+	}
+
+	keySet, err := v.keys.Fetch(ctx, v.jwksURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+
+	token, err := jwt.ParseString(rawToken,
+		jwt.WithKeySet(keySet),
+		jwt.WithValidate(true),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithAcceptableSkew(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access token: %w", err)
+	}
+
+	caller := &Caller{Subject: token.Subject()}
+	if uid, ok := token.Get("uid"); ok {
+		caller.UserID, _ = uid.(string)
+	}
+	if cid, ok := token.Get("cid"); ok {
+		caller.ClientID, _ = cid.(string)
+	}
+	if groups, ok := token.Get("groups"); ok {
+		caller.Groups = toStrings(groups)
+	}
+	if scopes, ok := token.Get("scp"); ok {
+		caller.Scopes = toStrings(scopes)
+	}
+
+	return caller, nil
+}
+
+// Authenticate rejects requests without a valid bearer token or API key and
+// stores the resulting Caller in the request context.
+func Authenticate(log *zap.SugaredLogger, verifier *Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rawKey := r.Header.Get(APIKeyHeader); rawKey != "" {
+				caller, err := verifier.VerifyAPIKey(r, rawKey)
+				switch {
+				case errors.Is(err, ErrInsufficientScope):
+					response.RespondError(w, http.StatusForbidden, "FORBIDDEN", "The API key lacks the scope for this request", nil)
+					return
+				case err != nil:
+					logger.FromContext(r.Context(), log).Infow("Failed to verify API key", zap.Error(err))
+					response.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid, expired or revoked API key", nil)
+					return
+				}
+
+				next.ServeHTTP(w, r.WithContext(WithCaller(r.Context(), caller)))
+				return
+			}
+
+			rawToken, err := bearerToken(r)
+			if err != nil {
+				response.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "A bearer token is required", nil)
+				return
+			}
+
+			caller, err := verifier.Verify(r.Context(), rawToken)
+			if err != nil {
+				logger.FromContext(r.Context(), log).Infow("Failed to verify access token", zap.Error(err))
+				response.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired access token", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithCaller(r.Context(), caller)))
+		})
+	}
+}
+
+func WithCaller(ctx context.Context, caller *Caller) context.Context {
+	return context.WithValue(ctx, contextKey{}, caller)
+}
+
+func CallerFromContext(ctx context.Context) (*Caller, bool) {
+	caller, ok := ctx.Value(contextKey{}).(*Caller)
+	return caller, ok
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", ErrMissingToken
+	}
+
+	return token, nil
+}
+
+func discoverJWKSURL(ctx context.Context, issuer string) (string, error) {
+	discoveryURL := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build discovery request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch issuer discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("issuer discovery returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	var document struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&document); err != nil {
+		return "", fmt.Errorf("failed to decode issuer discovery document: %w", err)
+	}
+
+	if document.JWKSURI == "" {
+		return "", errors.New("issuer discovery document has no jwks_uri")
+	}
+
+	return document.JWKSURI, nil
+}
+
+func toStrings(value any) []string {
+	items, ok := value.([]any)
+	if !ok {
+		if single, ok := value.(string); ok {
+			return []string{single}
+		}
+		return nil
+	}
+
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+
+	return result
+}
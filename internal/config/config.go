@@ -1,27 +1,592 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"net/netip"
 	"os"
+	"strings"
 	"time"
+
+	"go.uber.org/zap/zapcore"
 )
 
 type Config struct {
-	Okta   *OktaConfig
-	Server *ServerConfig
+	Okta    *OktaConfig
+	Server  *ServerConfig
+	Workers *WorkersConfig
+	Reports *ReportsConfig
+	Avatars *AvatarsConfig
+	// ServiceAccounts governs the non-human identity registry.
+	ServiceAccounts *ServiceAccountsConfig
+	Guests          *GuestsConfig
+	Invitations     *InvitationsConfig
+	DeviceAuth      *DeviceAuthConfig
+	SessionExchange *SessionExchangeConfig
+	TokenExchange   *TokenExchangeConfig
+	// GroupPolicy holds the naming and tagging rules groups are held to.
+	GroupPolicy   *GroupPolicyConfig
+	GroupMetadata *GroupMetadataConfig
+	GroupTrash    *GroupTrashConfig
+	GroupClone    *GroupCloneConfig
+	// DefaultGroups lists the groups new users join by user type and
+	// department.
+	DefaultGroups *DefaultGroupsConfig
+	Profiling     *ProfilingConfig
+	Helpdesk      *HelpdeskConfig
+	SelfService   *SelfServiceConfig
+	APIKeys       *APIKeysConfig
+	// PendingChanges governs the two-person rule on protected groups.
+	PendingChanges   *PendingChangesConfig
+	ScheduledChanges *ScheduledChangesConfig
+	Backups          *BackupsConfig
+	// Orgs holds additional named Okta orgs, such as the spokes of a
+	// hub-and-spoke deployment. The primary org is always Okta.
+	Orgs map[string]*OktaConfig
+	// Redactions lists, per role, the response fields callers holding that
+	// role may not see. Roles are Okta groups in the caller's token.
+	Redactions map[string][]string
+	Secrets    *SecretsConfig
+	Storage    *StorageConfig
+	History    *HistoryConfig
+	// MembershipEvents governs the local store of membership changes read
+	// from the System Log.
+	MembershipEvents *MembershipEventsConfig
+	Changes          *ChangesConfig
+	RequestTimeouts  *RequestTimeoutConfig
+	Events           *EventsConfig
+	RateLimit        *RateLimitConfig
+	Drift            *DriftConfig
+	Dashboard        *DashboardConfig
+	Security         *SecurityConfig
+	RetryQueue       *RetryQueueConfig
+	Reconciliation   *ReconciliationConfig
+	Replay           *ReplayConfig
+	Consent          *ConsentConfig
+	BulkDeactivation *BulkDeactivationConfig
+	Log              *LogConfig
+	File             *FileConfig
+
+	// values holds the resolved value of every setting, for comparing a
+	// reloaded configuration with this one.
+	values map[string]string
+}
+
+type LogConfig struct {
+	Level zapcore.Level
+	// RedactFields are the field names whose values are masked in log lines
+	// and audit entry details.
+	RedactFields []string
+	// RedactStrict also masks fields whose names contain one of
+	// RedactFields, and scrubs email addresses and phone numbers from every
+	// logged string, for regulated environments.
+	RedactStrict bool
+}
+
+// FileConfig names the optional YAML file settings are read from. Variables
+// in the environment take precedence over the file.
+type FileConfig struct {
+	Path string
+	// ReloadInterval is how often the file is checked for changes.
+	ReloadInterval time.Duration
 }
 
 type ServerConfig struct {
 	Port         string
+	GRPCPort     string
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+	// GRPCWebOrigins are the browser origins allowed to call the Connect and
+	// gRPC-Web endpoints on Port.
+	GRPCWebOrigins []string
+	// ValidateRequests rejects requests that do not match the OpenAPI spec.
+	ValidateRequests bool
+	// RequestDecoding is strict, lenient, or empty to use each API version's
+	// default.
+	RequestDecoding string
+	// TrustedProxies are the load balancers and proxies in front of the
+	// server, whose forwarding headers name the client.
+	TrustedProxies []netip.Prefix
 }
 
+// RequestTimeoutConfig bounds how long an API request may take, Okta calls
+// included: Read for requests that only read, Write for those that change
+// something, and Bulk for exports, reports, streams and other requests that
+// touch many resources at once.
+type RequestTimeoutConfig struct {
+	Read  time.Duration
+	Write time.Duration
+	Bulk  time.Duration
+	// Routes overrides the timeout of single routes, keyed by method and
+	// route pattern such as "GET /api/v1/reports/inactive-users".
+	Routes map[string]time.Duration
+}
+
+// Okta backends.
+const (
+	// OktaBackendOkta talks to a real Okta org.
+	OktaBackendOkta = "okta"
+	// OktaBackendMemory serves the org from memory, seeded with sample users
+	// and groups, for running the API without an Okta org.
+	OktaBackendMemory = "memory"
+)
+
 type OktaConfig struct {
-	Domain   string
-	APIToken string
-	Issuer   string
-	Audience string
+	// Name addresses the org in /orgs/{org} paths and the X-Okta-Org header.
+	Name string
+	// Backend is OktaBackendOkta or OktaBackendMemory. Every org uses the
+	// primary org's backend.
+	Backend string
+	Domain  string
+	// APITokenSecret names the secret holding the SSWS API token. It is used
+	// unless ClientID is set.
+	APITokenSecret string
+	// ClientID, when set, authenticates as an OAuth service app with the
+	// private key in PrivateKeySecret instead of an API token.
+	ClientID         string
+	PrivateKeySecret string
+	PrivateKeyID     string
+	Scopes           []string
+	Issuer           string
+	Audience         string
+	// CacheTTL enables the Okta client's response cache when non-zero. Every
+	// org has its own client, so caches and rate limit tracking are never
+	// shared between orgs.
+	CacheTTL            time.Duration
+	RateLimitMaxRetries int
+	// BreakerFailures consecutive failures of one endpoint class, such as
+	// users or groups, open its circuit breaker for BreakerOpenTimeout.
+	BreakerFailures    int
+	BreakerOpenTimeout time.Duration
+	// BudgetSyncShare and BudgetBatchShare are the percentages of each rate
+	// limit window that background workers and jobs may use. The rest is kept
+	// for interactive requests, which may also use whatever the others leave.
+	// Background calls over their share wait up to BudgetMaxWait for the
+	// window to reset and fail beyond it.
+	BudgetSyncShare  int
+	BudgetBatchShare int
+	BudgetMaxWait    time.Duration
+}
+
+type WorkersConfig struct {
+	MembershipExpiryInterval time.Duration
+	InactiveUserSuspend      bool
+	InactiveUserInterval     time.Duration
+	ServiceAccountInterval   time.Duration
+	GuestInterval            time.Duration
+	PendingChangeInterval    time.Duration
+	// DirectoryRefreshInterval is how often the local directory index is
+	// rebuilt from Okta.
+	DirectoryRefreshInterval time.Duration
+}
+
+type ReportsConfig struct {
+	InactiveUserDays           int
+	InactiveUserExcludedGroups []string
+	// UnusedAccessDays is the default usage window of unused app access
+	// suggestions. Okta keeps 90 days of System Log.
+	UnusedAccessDays int
+}
+
+type AvatarsConfig struct {
+	StorageDir string
+	// BaseURL prefixes signed avatar URLs, e.g. "https://iam.example.com".
+	BaseURL      string
+	URLSecret    string
+	URLTTL       time.Duration
+	MaxSizeBytes int
+}
+
+type ServiceAccountsConfig struct {
+	// NamePattern is the regular expression every service account name must match.
+	NamePattern string
+	// LoginDomain is appended to the name to build the login of user-backed accounts.
+	LoginDomain          string
+	ReviewInterval       time.Duration
+	RotationIntervalDays int
+	// RotationVerifyTimeout bounds how long a rotation waits to see the new
+	// credential used before giving up and keeping the old one.
+	RotationVerifyTimeout  time.Duration
+	RotationVerifyInterval time.Duration
+}
+
+type GuestsConfig struct {
+	// MaxDuration caps how far in the future a guest's expiry may be set.
+	MaxDuration         time.Duration
+	AttestationInterval time.Duration
+	// EligibleGroups lists the only groups guests may be added to.
+	EligibleGroups []string
+}
+
+type GroupPolicyConfig struct {
+	// TypeAttribute is the group profile attribute that holds a group's type.
+	TypeAttribute string
+	// Default applies to groups without a type.
+	Default *GroupPolicyRule
+	// Types holds the rule of each group type. When it is not empty, groups
+	// with a type must use one of its keys.
+	Types map[string]*GroupPolicyRule
+}
+
+// GroupPolicyRule is what a group of one type must look like. The zero rule
+// allows any group.
+type GroupPolicyRule struct {
+	// NamePattern is a regular expression the name must match.
+	NamePattern string
+	// NamePrefix is a prefix the name must start with.
+	NamePrefix          string
+	DescriptionRequired bool
+	// RequiredTags lists the profile attributes that must be set.
+	RequiredTags []string
+}
+
+// GroupMetadataConfig governs the store of group metadata Okta group
+// profiles do not hold, such as owners and cost centers.
+type GroupMetadataConfig struct {
+	StorageDir string
+	// AdminGroups are the Okta groups whose members may manage the members
+	// and owners of any group. When set, everyone else must own a group to
+	// change its members; when empty, membership changes are not checked.
+	AdminGroups []string
+}
+
+// GroupTrashConfig governs the snapshots of deleted groups kept so they can
+// be restored.
+type GroupTrashConfig struct {
+	StorageDir string
+	// Retention is how long a deleted group can be restored.
+	Retention time.Duration
+}
+
+// GroupCloneConfig governs copying groups.
+type GroupCloneConfig struct {
+	// JobThreshold is the number of members and app assignments beyond which
+	// a clone copies them in a background job instead of before responding.
+	JobThreshold int
+}
+
+// DefaultGroupsConfig holds the default group memberships that come from
+// configuration. More are added through the API and stored in StorageDir.
+type DefaultGroupsConfig struct {
+	StorageDir string
+	// UserTypes maps a profile.userType to the IDs of the groups new users of
+	// that type join.
+	UserTypes map[string][]string
+	// Departments maps a profile.department to the IDs of the groups new
+	// users in that department join.
+	Departments map[string][]string
+}
+
+// ProfilingConfig sets where the profile attributes apps and groups require
+// are kept.
+type ProfilingConfig struct {
+	StorageDir string
+}
+
+// HelpdeskConfig names who may expire and reset users' passwords and unlock
+// them: members of Groups, which are Okta groups in the caller's token, and
+// the OAuth clients in ClientIDs. When both are empty, no one may.
+type HelpdeskConfig struct {
+	Groups    []string
+	ClientIDs []string
+}
+
+// SelfServiceConfig governs what users may change about themselves through
+// the /me routes.
+type SelfServiceConfig struct {
+	// ProfileAttributes are the profile attributes users may edit. When
+	// empty, profiles are read-only.
+	ProfileAttributes []string
+}
+
+// PendingChangesConfig sets where the groups under the two-person rule and
+// their pending membership changes are kept, and how long a change waits for
+// approval before it expires.
+type PendingChangesConfig struct {
+	StorageDir string
+	TTL        time.Duration
+}
+
+// ScheduledChangesConfig governs membership changes scheduled for a future
+// time: where they are kept, how often due ones are applied, and how often
+// and after what wait a change that failed because Okta was unavailable is
+// tried again.
+type ScheduledChangesConfig struct {
+	StorageDir  string
+	Interval    time.Duration
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt, doubled after
+	// every further one.
+	Backoff time.Duration
+}
+
+// BackupsConfig selects where configuration backups are kept: a local
+// directory, or an S3 bucket (or S3-compatible service at S3Endpoint) under
+// S3Prefix.
+type BackupsConfig struct {
+	// Storage is file or s3.
+	Storage    string
+	StorageDir string
+	S3Bucket   string
+	S3Prefix   string
+	// S3Region defaults to the region of the AWS configuration.
+	S3Region   string
+	S3Endpoint string
+}
+
+// APIKeysConfig governs the API keys machine clients use instead of access
+// tokens, and where their hashes are kept.
+type APIKeysConfig struct {
+	StorageDir string
+	// DefaultTTL is the lifetime of keys issued without an expiry; none may
+	// outlive MaxTTL.
+	DefaultTTL time.Duration
+	MaxTTL     time.Duration
+	// RotationGrace is how long the old secret keeps working after a key is
+	// rotated, so pipelines can be updated without failing.
+	RotationGrace time.Duration
+}
+
+type InvitationsConfig struct {
+	// BaseURL is the address of the registration form; the invitation token
+	// is appended as the last path segment.
+	BaseURL string
+	// TTL is how long an invitation stays valid, and the latest expiry a
+	// caller may request.
+	TTL time.Duration
+}
+
+// DeviceAuthConfig holds the Okta app that CLI tools and kiosk apps sign
+// users in through with the device authorization grant. The grant is off
+// while ClientID is empty.
+type DeviceAuthConfig struct {
+	ClientID string
+	// Scopes are requested when the device asks for none; without them,
+	// openid, profile and offline_access are.
+	Scopes []string
+}
+
+// SessionExchangeConfig governs the exchange of embedded login session tokens
+// for session cookie redirects. The exchange is off while AllowedOrigins is
+// empty.
+type SessionExchangeConfig struct {
+	// AllowedOrigins are the origins, such as https://app.example.com, of the
+	// pages allowed to ask for an exchange and to be redirected to.
+	AllowedOrigins []string
+}
+
+// TokenExchangeConfig governs the exchange of users' access tokens for
+// narrowly scoped service tokens at OKTA_ISSUER (RFC 8693). ClientID is the
+// Okta service app that performs the exchanges; the exchange is off while
+// it is empty.
+type TokenExchangeConfig struct {
+	ClientID string
+	// ClientSecretName names the secret holding the app's client secret.
+	ClientSecretName string
+	// Policies holds, by the client ID of the calling service, what it may
+	// ask for. Services without a policy may not exchange tokens.
+	Policies map[string]*TokenExchangePolicy
+}
+
+// TokenExchangePolicy lists the audiences a service may get tokens for and
+// the scopes it may request in them.
+type TokenExchangePolicy struct {
+	Audiences []string
+	Scopes    []string
+}
+
+// HistoryConfig governs the group membership snapshots that past
+// memberships are derived from.
+type HistoryConfig struct {
+	StorageDir       string
+	SnapshotInterval time.Duration
+	// Retention is how long snapshots are kept.
+	Retention time.Duration
+}
+
+// MembershipEventsConfig governs the ingestion of group membership changes
+// from the Okta System Log into a local event store.
+type MembershipEventsConfig struct {
+	StorageDir   string
+	PollInterval time.Duration
+	// Backfill is how far back the first ingestion reads the System Log.
+	Backfill time.Duration
+	// Retention is how long ingested events are kept.
+	Retention time.Duration
+}
+
+// ChangesConfig governs the change feed.
+type ChangesConfig struct {
+	// MaxChanges is how many of the most recent changes the feed keeps.
+	MaxChanges int
+}
+
+// EventsConfig governs the live event stream.
+type EventsConfig struct {
+	// Replay is how many of the latest events are kept for clients resuming
+	// the stream with Last-Event-ID.
+	Replay int
+	// SubscriberBuffer is how many events may wait for a slow client before
+	// it is disconnected.
+	SubscriberBuffer int
+	// HeartbeatInterval is how often an idle stream is sent a comment, so
+	// proxies do not close it.
+	HeartbeatInterval time.Duration
+}
+
+// RateLimitConfig sets how many requests each API client may make. Reads
+// and writes have separate limits; a zero rate leaves that class unlimited.
+type RateLimitConfig struct {
+	ReadsPerMinute  int
+	ReadBurst       int
+	WritesPerMinute int
+	WriteBurst      int
+	// RedisURL, when set, shares the limits between every instance using it.
+	RedisURL string
+}
+
+// DriftConfig governs the detection of changes made in Okta directly, such
+// as in the admin console, rather than through this service.
+type DriftConfig struct {
+	CheckInterval time.Duration
+	// ServiceActors are the Okta user or app IDs this service acts as. The
+	// primary org's OKTA_CLIENT_ID is always one of them.
+	ServiceActors []string
+	// CorrelationWindow is how far apart a logged change and a mutation of
+	// the same resource through the API may be to count as the same change.
+	CorrelationWindow time.Duration
+}
+
+// DashboardConfig governs the ops dashboard, whose figures are brought up to
+// date every UpdateInterval.
+type DashboardConfig struct {
+	UpdateInterval time.Duration
+	// Days is how many days of group creations and deletions are kept.
+	Days int
+	// RecentMutations is how many of the latest API mutations are kept.
+	RecentMutations int
+}
+
+// SecurityConfig governs the detection of brute-force and impossible-travel
+// sign-ins in the System Log.
+type SecurityConfig struct {
+	DetectionInterval time.Duration
+	// BruteForceThreshold is how many failed sign-ins for one user, or from
+	// one IP address, within BruteForceWindow raise an alert.
+	BruteForceThreshold int
+	BruteForceWindow    time.Duration
+	// MaxTravelSpeed, in km/h, is the fastest a user may plausibly travel
+	// between the places of two successful sign-ins.
+	MaxTravelSpeed int
+	// AutoSuspend suspends the user an alert names.
+	AutoSuspend bool
+}
+
+// RetryQueueConfig governs the queue of mutations that failed because Okta
+// was unavailable, and their replay.
+type RetryQueueConfig struct {
+	// Enabled lets clients have such mutations queued instead of failed.
+	Enabled    bool
+	StorageDir string
+	// MaxEntries bounds the queue; once full, failed mutations fail again.
+	MaxEntries int
+	// MaxAttempts is how often a queued mutation is replayed before it is
+	// left for an operator.
+	MaxAttempts int
+	Interval    time.Duration
+	// Backoff is the wait before the first replay, doubled after every
+	// failed one.
+	Backoff time.Duration
+}
+
+// Storage backends.
+const (
+	// StorageBackendFile keeps each feature's state in its own directory.
+	StorageBackendFile = "file"
+	// StorageBackendPostgres keeps all of it in one Postgres database.
+	StorageBackendPostgres = "postgres"
+	// StorageBackendMemory keeps it until the process exits.
+	StorageBackendMemory = "memory"
+)
+
+// StorageConfig selects where local state, such as group metadata,
+// membership snapshots, jobs and the audit trail, is kept. Backend defaults
+// to memory when the org is served from memory, and to file otherwise.
+type StorageConfig struct {
+	Backend string
+	// Dir holds the state of features without a directory of their own,
+	// the audit trail and jobs, with the file backend.
+	Dir         string
+	PostgresURL string
+	// The Postgres connection pool holds between MinConns and MaxConns
+	// connections, closing those idle for MaxConnIdleTime or open for
+	// MaxConnLifetime.
+	MaxConns        int
+	MinConns        int
+	MaxConnIdleTime time.Duration
+	MaxConnLifetime time.Duration
+}
+
+// ReconciliationConfig governs the retrying of the steps of sagas that were
+// left incomplete, or not fully undone.
+type ReconciliationConfig struct {
+	Interval time.Duration
+	// MaxAttempts is how often a saga's steps are retried before the saga is
+	// left stuck for an operator.
+	MaxAttempts int
+}
+
+// ReplayConfig governs the capture of failed requests, with the Okta
+// interactions made while serving them, for replaying offline.
+type ReplayConfig struct {
+	// CaptureEnabled captures requests that fail with a 5xx. Every Okta
+	// response is held in memory until its request completes, so it is meant
+	// to be turned on while a failure is investigated.
+	CaptureEnabled bool
+	StorageDir     string
+	// MaxCaptures bounds the captures kept; the oldest are dropped first.
+	MaxCaptures int
+}
+
+// BulkDeactivationConfig holds the safety limits of bulk deactivations.
+type BulkDeactivationConfig struct {
+	// ExcludedUsers lists the IDs or logins of users never deactivated in
+	// bulk, such as break-glass accounts.
+	ExcludedUsers []string
+	// ExcludedGroups lists groups whose members are never deactivated in
+	// bulk.
+	ExcludedGroups []string
+	// MaxUsers caps the users one deactivation may change.
+	MaxUsers int
+	// Interval is the wait between deactivations, so a large batch does not
+	// eat the org's rate limits.
+	Interval time.Duration
+	// PreviewTTL is how long a dry run's preview can be started.
+	PreviewTTL time.Duration
+}
+
+// ConsentConfig sets the texts users acknowledge before self-service
+// actions, and where their acknowledgements are kept. An action whose text
+// is empty needs no consent.
+type ConsentConfig struct {
+	StorageDir string
+	// GroupJoinText is acknowledged before joining, or requesting to join, a
+	// group from the catalog. GroupJoinVersion names its current wording.
+	GroupJoinText    string
+	GroupJoinVersion string
+}
+
+// SecretsConfig selects where credentials such as the Okta API token are
+// read from, and how often they are reloaded.
+type SecretsConfig struct {
+	// Provider is env, file, aws or vault.
+	Provider        string
+	Dir             string
+	RefreshInterval time.Duration
+	VaultAddr       string
+	VaultToken      string
+	VaultMount      string
 }
 
 type FrontendConfig struct {
@@ -32,38 +597,462 @@ type JWTConfig struct {
 	Secret string
 }
 
+// Load reads the configuration from the environment and the file named by
+// CONFIG_FILE, and validates it. The error lists every invalid setting.
 func Load() (*Config, error) {
+	path := os.Getenv("CONFIG_FILE")
+	src, err := newSource(path)
+	if err != nil {
+		return nil, err
+	}
+
 	config := &Config{
+		File: &FileConfig{
+			Path:           path,
+			ReloadInterval: src.getDurationOrDefault("CONFIG_RELOAD_INTERVAL", "10s"),
+		},
+		Log: &LogConfig{
+			Level: src.getLogLevelOrDefault("LOG_LEVEL", zapcore.InfoLevel),
+			RedactFields: strings.Split(src.getEnvOrDefault("LOG_REDACT_FIELDS",
+				"email,secondEmail,login,firstName,lastName,displayName,mobilePhone,primaryPhone,phone"), ","),
+			RedactStrict: src.getBoolOrDefault("LOG_REDACT_STRICT", false),
+		},
 		Server: &ServerConfig{
-			Port:         getEnvOrDefault("PORT", "8080"),
-			ReadTimeout:  getDurationOrDefault("READ_TIMEOUT", "10s"),
-			WriteTimeout: getDurationOrDefault("WRITE_TIMEOUT", "10s"),
-			IdleTimeout:  getDurationOrDefault("IDLE_TIMEOUT", "120s"),
+			Port:             src.getEnvOrDefault("PORT", "8080"),
+			GRPCPort:         src.getEnvOrDefault("GRPC_PORT", "9090"),
+			ReadTimeout:      src.getDurationOrDefault("READ_TIMEOUT", "10s"),
+			WriteTimeout:     src.getDurationOrDefault("WRITE_TIMEOUT", "10s"),
+			IdleTimeout:      src.getDurationOrDefault("IDLE_TIMEOUT", "120s"),
+			GRPCWebOrigins:   src.getListOrDefault("GRPC_WEB_ALLOWED_ORIGINS"),
+			ValidateRequests: src.getBoolOrDefault("VALIDATE_REQUESTS", false),
+			RequestDecoding:  src.lookup("REQUEST_DECODING"),
+			TrustedProxies:   loadTrustedProxies(src),
+		},
+		RequestTimeouts: &RequestTimeoutConfig{
+			Read:   src.getDurationOrDefault("REQUEST_TIMEOUT_READ", "10s"),
+			Write:  src.getDurationOrDefault("REQUEST_TIMEOUT_WRITE", "30s"),
+			Bulk:   src.getDurationOrDefault("REQUEST_TIMEOUT_BULK", "5m"),
+			Routes: loadRouteTimeouts(src),
 		},
 		Okta: &OktaConfig{
-			Domain:   os.Getenv("OKTA_DOMAIN"),
-			Issuer:   os.Getenv("OKTA_ISSUER"),
-			Audience: os.Getenv("OKTA_AUDIENCE"),
-			APIToken: os.Getenv("OKTA_API_TOKEN"),
+			Name:                src.getEnvOrDefault("OKTA_ORG_NAME", "primary"),
+			Backend:             src.getEnvOrDefault("OKTA_BACKEND", OktaBackendOkta),
+			Domain:              src.lookup("OKTA_DOMAIN"),
+			Issuer:              src.lookup("OKTA_ISSUER"),
+			Audience:            src.lookup("OKTA_AUDIENCE"),
+			APITokenSecret:      src.getEnvOrDefault("OKTA_API_TOKEN_SECRET", "OKTA_API_TOKEN"),
+			ClientID:            src.lookup("OKTA_CLIENT_ID"),
+			PrivateKeySecret:    src.getEnvOrDefault("OKTA_PRIVATE_KEY_SECRET", "OKTA_PRIVATE_KEY"),
+			PrivateKeyID:        src.lookup("OKTA_PRIVATE_KEY_ID"),
+			Scopes:              src.getListOrDefault("OKTA_SCOPES"),
+			CacheTTL:            src.getDurationOrDefault("OKTA_CACHE_TTL", "0s"),
+			RateLimitMaxRetries: src.getIntOrDefault("OKTA_RATE_LIMIT_MAX_RETRIES", 2),
+			BreakerFailures:     src.getIntOrDefault("OKTA_BREAKER_FAILURES", 5),
+			BreakerOpenTimeout:  src.getDurationOrDefault("OKTA_BREAKER_OPEN_TIMEOUT", "30s"),
+			BudgetSyncShare:     src.getIntOrDefault("OKTA_BUDGET_SYNC_SHARE", 25),
+			BudgetBatchShare:    src.getIntOrDefault("OKTA_BUDGET_BATCH_SHARE", 15),
+			BudgetMaxWait:       src.getDurationOrDefault("OKTA_BUDGET_MAX_WAIT", "30s"),
+		},
+		Workers: &WorkersConfig{
+			MembershipExpiryInterval: src.getDurationOrDefault("MEMBERSHIP_EXPIRY_INTERVAL", "1m"),
+			InactiveUserSuspend:      src.getBoolOrDefault("INACTIVE_USER_AUTO_SUSPEND", false),
+			InactiveUserInterval:     src.getDurationOrDefault("INACTIVE_USER_INTERVAL", "24h"),
+			ServiceAccountInterval:   src.getDurationOrDefault("SERVICE_ACCOUNT_REMINDER_INTERVAL", "24h"),
+			GuestInterval:            src.getDurationOrDefault("GUEST_LIFECYCLE_INTERVAL", "1h"),
+			PendingChangeInterval:    src.getDurationOrDefault("PENDING_CHANGE_EXPIRY_INTERVAL", "5m"),
+			DirectoryRefreshInterval: src.getDurationOrDefault("DIRECTORY_REFRESH_INTERVAL", "5m"),
+		},
+		Avatars: &AvatarsConfig{
+			StorageDir:   src.getEnvOrDefault("AVATAR_STORAGE_DIR", "data/avatars"),
+			BaseURL:      src.lookup("AVATAR_BASE_URL"),
+			URLSecret:    src.lookup("AVATAR_URL_SECRET"),
+			URLTTL:       src.getDurationOrDefault("AVATAR_URL_TTL", "15m"),
+			MaxSizeBytes: src.getIntOrDefault("AVATAR_MAX_SIZE_BYTES", 2<<20),
+		},
+		ServiceAccounts: &ServiceAccountsConfig{
+			NamePattern:            src.getEnvOrDefault("SERVICE_ACCOUNT_NAME_PATTERN", `^svc-[a-z0-9][a-z0-9-]{1,48}$`),
+			LoginDomain:            src.getEnvOrDefault("SERVICE_ACCOUNT_LOGIN_DOMAIN", "service.local"),
+			ReviewInterval:         src.getDurationOrDefault("SERVICE_ACCOUNT_REVIEW_INTERVAL", "2160h"),
+			RotationIntervalDays:   src.getIntOrDefault("SERVICE_ACCOUNT_ROTATION_DAYS", 90),
+			RotationVerifyTimeout:  src.getDurationOrDefault("SERVICE_ACCOUNT_ROTATION_VERIFY_TIMEOUT", "24h"),
+			RotationVerifyInterval: src.getDurationOrDefault("SERVICE_ACCOUNT_ROTATION_VERIFY_INTERVAL", "5m"),
+		},
+		Guests: &GuestsConfig{
+			MaxDuration:         src.getDurationOrDefault("GUEST_MAX_DURATION", "2160h"),
+			AttestationInterval: src.getDurationOrDefault("GUEST_ATTESTATION_INTERVAL", "720h"),
+			EligibleGroups:      src.getListOrDefault("GUEST_ELIGIBLE_GROUPS"),
+		},
+		GroupMetadata: &GroupMetadataConfig{
+			StorageDir:  src.getEnvOrDefault("GROUP_METADATA_STORAGE_DIR", "data/group-metadata"),
+			AdminGroups: src.getListOrDefault("GROUP_ADMIN_GROUPS"),
+		},
+		GroupTrash: &GroupTrashConfig{
+			StorageDir: src.getEnvOrDefault("GROUP_TRASH_STORAGE_DIR", "data/group-trash"),
+			Retention:  src.getDurationOrDefault("GROUP_TRASH_RETENTION", "720h"),
+		},
+		GroupClone: &GroupCloneConfig{
+			JobThreshold: src.getIntOrDefault("GROUP_CLONE_JOB_THRESHOLD", 100),
+		},
+		Invitations: &InvitationsConfig{
+			BaseURL: src.getEnvOrDefault("INVITATION_BASE_URL", "http://localhost:8080/api/v1/invite"),
+			TTL:     src.getDurationOrDefault("INVITATION_TTL", "168h"),
+		},
+		SessionExchange: &SessionExchangeConfig{
+			AllowedOrigins: src.getListOrDefault("SESSION_EXCHANGE_ALLOWED_ORIGINS"),
+		},
+		DeviceAuth: &DeviceAuthConfig{
+			ClientID: src.lookup("DEVICE_AUTH_CLIENT_ID"),
+			Scopes:   src.getListOrDefault("DEVICE_AUTH_SCOPES"),
+		},
+		Secrets: &SecretsConfig{
+			Provider:        src.getEnvOrDefault("SECRETS_PROVIDER", "env"),
+			Dir:             src.getEnvOrDefault("SECRETS_DIR", "/run/secrets"),
+			RefreshInterval: src.getDurationOrDefault("SECRETS_REFRESH_INTERVAL", "5m"),
+			VaultAddr:       src.lookup("VAULT_ADDR"),
+			VaultToken:      src.lookup("VAULT_TOKEN"),
+			VaultMount:      src.getEnvOrDefault("VAULT_KV_MOUNT", "secret"),
+		},
+		Storage: &StorageConfig{
+			Backend:         src.lookup("STORAGE_BACKEND"),
+			Dir:             src.getEnvOrDefault("STORAGE_DIR", "data"),
+			PostgresURL:     src.lookup("STORAGE_POSTGRES_URL"),
+			MaxConns:        src.getIntOrDefault("STORAGE_POSTGRES_MAX_CONNS", 10),
+			MinConns:        src.getIntOrDefault("STORAGE_POSTGRES_MIN_CONNS", 0),
+			MaxConnIdleTime: src.getDurationOrDefault("STORAGE_POSTGRES_MAX_CONN_IDLE_TIME", "30m"),
+			MaxConnLifetime: src.getDurationOrDefault("STORAGE_POSTGRES_MAX_CONN_LIFETIME", "1h"),
+		},
+		History: &HistoryConfig{
+			StorageDir:       src.getEnvOrDefault("HISTORY_STORAGE_DIR", "data/history"),
+			SnapshotInterval: src.getDurationOrDefault("MEMBERSHIP_SNAPSHOT_INTERVAL", "24h"),
+			Retention:        src.getDurationOrDefault("MEMBERSHIP_SNAPSHOT_RETENTION", "8760h"),
+		},
+		MembershipEvents: &MembershipEventsConfig{
+			StorageDir:   src.getEnvOrDefault("MEMBERSHIP_EVENTS_STORAGE_DIR", "data/membership-events"),
+			PollInterval: src.getDurationOrDefault("MEMBERSHIP_EVENTS_INTERVAL", "1m"),
+			Backfill:     src.getDurationOrDefault("MEMBERSHIP_EVENTS_BACKFILL", "2160h"),
+			Retention:    src.getDurationOrDefault("MEMBERSHIP_EVENTS_RETENTION", "8760h"),
+		},
+		Changes: &ChangesConfig{
+			MaxChanges: src.getIntOrDefault("CHANGE_FEED_MAX_CHANGES", 100000),
+		},
+		Events: &EventsConfig{
+			Replay:            src.getIntOrDefault("EVENTS_REPLAY", 1000),
+			SubscriberBuffer:  src.getIntOrDefault("EVENTS_SUBSCRIBER_BUFFER", 256),
+			HeartbeatInterval: src.getDurationOrDefault("EVENTS_HEARTBEAT_INTERVAL", "15s"),
+		},
+		Drift: &DriftConfig{
+			CheckInterval:     src.getDurationOrDefault("DRIFT_CHECK_INTERVAL", "5m"),
+			ServiceActors:     src.getListOrDefault("DRIFT_SERVICE_ACTORS"),
+			CorrelationWindow: src.getDurationOrDefault("DRIFT_CORRELATION_WINDOW", "2m"),
+		},
+		Dashboard: &DashboardConfig{
+			UpdateInterval:  src.getDurationOrDefault("DASHBOARD_UPDATE_INTERVAL", "15s"),
+			Days:            src.getIntOrDefault("DASHBOARD_DAYS", 30),
+			RecentMutations: src.getIntOrDefault("DASHBOARD_RECENT_MUTATIONS", 50),
+		},
+		Security: &SecurityConfig{
+			DetectionInterval:   src.getDurationOrDefault("SECURITY_DETECTION_INTERVAL", "1m"),
+			BruteForceThreshold: src.getIntOrDefault("SECURITY_BRUTE_FORCE_THRESHOLD", 10),
+			BruteForceWindow:    src.getDurationOrDefault("SECURITY_BRUTE_FORCE_WINDOW", "10m"),
+			MaxTravelSpeed:      src.getIntOrDefault("SECURITY_MAX_TRAVEL_SPEED", 1000),
+			AutoSuspend:         src.getBoolOrDefault("SECURITY_AUTO_SUSPEND", false),
 		},
+		Reconciliation: &ReconciliationConfig{
+			Interval:    src.getDurationOrDefault("RECONCILIATION_INTERVAL", "1m"),
+			MaxAttempts: src.getIntOrDefault("RECONCILIATION_MAX_ATTEMPTS", 10),
+		},
+		RetryQueue: &RetryQueueConfig{
+			Enabled:     src.getBoolOrDefault("RETRY_QUEUE_ENABLED", false),
+			StorageDir:  src.getEnvOrDefault("RETRY_QUEUE_STORAGE_DIR", "data/retries"),
+			MaxEntries:  src.getIntOrDefault("RETRY_QUEUE_MAX_ENTRIES", 1000),
+			MaxAttempts: src.getIntOrDefault("RETRY_QUEUE_MAX_ATTEMPTS", 10),
+			Interval:    src.getDurationOrDefault("RETRY_QUEUE_INTERVAL", "30s"),
+			Backoff:     src.getDurationOrDefault("RETRY_QUEUE_BACKOFF", "1m"),
+		},
+		Replay: &ReplayConfig{
+			CaptureEnabled: src.getBoolOrDefault("REPLAY_CAPTURE_ENABLED", false),
+			StorageDir:     src.getEnvOrDefault("REPLAY_STORAGE_DIR", "data/replays"),
+			MaxCaptures:    src.getIntOrDefault("REPLAY_MAX_CAPTURES", 100),
+		},
+		BulkDeactivation: &BulkDeactivationConfig{
+			ExcludedUsers:  src.getListOrDefault("BULK_DEACTIVATION_EXCLUDED_USERS"),
+			ExcludedGroups: src.getListOrDefault("BULK_DEACTIVATION_EXCLUDED_GROUPS"),
+			MaxUsers:       src.getIntOrDefault("BULK_DEACTIVATION_MAX_USERS", 1000),
+			Interval:       src.getDurationOrDefault("BULK_DEACTIVATION_INTERVAL", "200ms"),
+			PreviewTTL:     src.getDurationOrDefault("BULK_DEACTIVATION_PREVIEW_TTL", "15m"),
+		},
+		Profiling: &ProfilingConfig{
+			StorageDir: src.getEnvOrDefault("PROFILING_STORAGE_DIR", "data/profiling"),
+		},
+		Helpdesk: &HelpdeskConfig{
+			Groups:    src.getListOrDefault("HELPDESK_GROUPS"),
+			ClientIDs: src.getListOrDefault("HELPDESK_CLIENT_IDS"),
+		},
+		SelfService: &SelfServiceConfig{
+			ProfileAttributes: src.getListOrDefault("SELF_SERVICE_PROFILE_ATTRIBUTES"),
+		},
+		PendingChanges: &PendingChangesConfig{
+			StorageDir: src.getEnvOrDefault("PENDING_CHANGE_STORAGE_DIR", "data/pending-changes"),
+			TTL:        src.getDurationOrDefault("PENDING_CHANGE_TTL", "72h"),
+		},
+		ScheduledChanges: &ScheduledChangesConfig{
+			StorageDir:  src.getEnvOrDefault("SCHEDULED_CHANGE_STORAGE_DIR", "data/scheduled-changes"),
+			Interval:    src.getDurationOrDefault("SCHEDULED_CHANGE_INTERVAL", "1m"),
+			MaxAttempts: src.getIntOrDefault("SCHEDULED_CHANGE_MAX_ATTEMPTS", 5),
+			Backoff:     src.getDurationOrDefault("SCHEDULED_CHANGE_BACKOFF", "1m"),
+		},
+		Backups: &BackupsConfig{
+			Storage:    src.getEnvOrDefault("BACKUP_STORAGE", "file"),
+			StorageDir: src.getEnvOrDefault("BACKUP_STORAGE_DIR", "data/backups"),
+			S3Bucket:   src.lookup("BACKUP_S3_BUCKET"),
+			S3Prefix:   src.lookup("BACKUP_S3_PREFIX"),
+			S3Region:   src.lookup("BACKUP_S3_REGION"),
+			S3Endpoint: src.lookup("BACKUP_S3_ENDPOINT"),
+		},
+		APIKeys: &APIKeysConfig{
+			StorageDir:    src.getEnvOrDefault("API_KEY_STORAGE_DIR", "data/api-keys"),
+			DefaultTTL:    src.getDurationOrDefault("API_KEY_DEFAULT_TTL", "2160h"),
+			MaxTTL:        src.getDurationOrDefault("API_KEY_MAX_TTL", "8760h"),
+			RotationGrace: src.getDurationOrDefault("API_KEY_ROTATION_GRACE", "24h"),
+		},
+		Consent: &ConsentConfig{
+			StorageDir:       src.getEnvOrDefault("CONSENT_STORAGE_DIR", "data/consents"),
+			GroupJoinText:    src.lookup("CONSENT_GROUP_JOIN_TEXT"),
+			GroupJoinVersion: src.lookup("CONSENT_GROUP_JOIN_VERSION"),
+		},
+		RateLimit: &RateLimitConfig{
+			ReadsPerMinute:  src.getIntOrDefault("RATE_LIMIT_READS_PER_MINUTE", 1200),
+			ReadBurst:       src.getIntOrDefault("RATE_LIMIT_READ_BURST", 200),
+			WritesPerMinute: src.getIntOrDefault("RATE_LIMIT_WRITES_PER_MINUTE", 300),
+			WriteBurst:      src.getIntOrDefault("RATE_LIMIT_WRITE_BURST", 50),
+			RedisURL:        src.lookup("RATE_LIMIT_REDIS_URL"),
+		},
+		Reports: &ReportsConfig{
+			InactiveUserDays:           src.getIntOrDefault("INACTIVE_USER_DAYS", 90),
+			InactiveUserExcludedGroups: src.getListOrDefault("INACTIVE_USER_EXCLUDED_GROUPS"),
+			UnusedAccessDays:           src.getIntOrDefault("UNUSED_ACCESS_DAYS", 90),
+		},
+	}
+
+	if config.Storage.Backend == "" {
+		// A standalone server keeps no more than its in-memory org does.
+		config.Storage.Backend = StorageBackendFile
+		if config.Okta.Backend == OktaBackendMemory {
+			config.Storage.Backend = StorageBackendMemory
+		}
+	}
+	config.Orgs = loadOrgs(src, config.Okta)
+	config.Redactions = loadRedactions(src)
+	config.TokenExchange = loadTokenExchange(src)
+	config.GroupPolicy = loadGroupPolicy(src)
+	config.DefaultGroups = loadDefaultGroups(src)
+	config.values = src.values
+
+	errs := append(src.errs, config.validate()...)
+	for _, key := range src.unknown() {
+		errs = append(errs, fmt.Errorf("%s: unknown setting in config file %s", key, path))
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
 	}
 
 	return config, nil
 }
 
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+// loadRedactions reads the roles named in REDACTED_ROLES (comma separated).
+// The fields hidden from role "help-desk" are listed in
+// REDACTED_ROLE_HELP_DESK_FIELDS, e.g. "mobilePhone,profile.employeeNumber".
+func loadRedactions(src *source) map[string][]string {
+	redactions := make(map[string][]string)
+	for _, role := range src.getListOrDefault("REDACTED_ROLES") {
+		key := "REDACTED_ROLE_" + strings.ToUpper(strings.ReplaceAll(role, "-", "_")) + "_FIELDS"
+		if fields := src.getListOrDefault(key); len(fields) > 0 {
+			redactions[role] = fields
+		}
 	}
-	return defaultValue
+	return redactions
 }
 
-func getDurationOrDefault(key, defaultValue string) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
-			return duration
+// loadRouteTimeouts reads REQUEST_TIMEOUT_ROUTES, a comma separated list of
+// routes and their timeouts such as
+// "GET /api/v1/reports/inactive-users=10m,POST /api/v1/users=1m".
+func loadRouteTimeouts(src *source) map[string]time.Duration {
+	timeouts := make(map[string]time.Duration)
+	for _, item := range src.getListOrDefault("REQUEST_TIMEOUT_ROUTES") {
+		route, value, ok := strings.Cut(item, "=")
+		method, pattern, _ := strings.Cut(strings.TrimSpace(route), " ")
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if !ok || err != nil || timeout <= 0 || !strings.HasPrefix(strings.TrimSpace(pattern), "/") {
+			src.invalid("REQUEST_TIMEOUT_ROUTES", item, "route timeout, such as GET /api/v1/users=20s")
+			continue
 		}
+		timeouts[strings.ToUpper(method)+" "+strings.TrimSpace(pattern)] = timeout
 	}
-	duration, _ := time.ParseDuration(defaultValue)
-	return duration
+	return timeouts
+}
+
+// loadTrustedProxies reads TRUSTED_PROXIES, a comma separated list of
+// addresses and CIDR ranges such as "10.0.0.0/8,192.0.2.10".
+func loadTrustedProxies(src *source) []netip.Prefix {
+	var proxies []netip.Prefix
+	for _, item := range src.getListOrDefault("TRUSTED_PROXIES") {
+		prefix, err := netip.ParsePrefix(item)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(item)
+			if addrErr != nil {
+				src.invalid("TRUSTED_PROXIES", item, "address or CIDR range")
+				continue
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies
+}
+
+// loadTokenExchange reads the exchanging app and the services named in
+// TOKEN_EXCHANGE_CLIENTS (comma separated client IDs, or apikey:<id> for
+// API keys). Service "0oa1b2c3" may request the audiences in
+// TOKEN_EXCHANGE_CLIENT_0OA1B2C3_AUDIENCES and the scopes in
+// TOKEN_EXCHANGE_CLIENT_0OA1B2C3_SCOPES.
+func loadTokenExchange(src *source) *TokenExchangeConfig {
+	exchange := &TokenExchangeConfig{
+		ClientID:         src.lookup("TOKEN_EXCHANGE_CLIENT_ID"),
+		ClientSecretName: src.getEnvOrDefault("TOKEN_EXCHANGE_CLIENT_SECRET_NAME", "TOKEN_EXCHANGE_CLIENT_SECRET"),
+		Policies:         make(map[string]*TokenExchangePolicy),
+	}
+	for _, client := range src.getListOrDefault("TOKEN_EXCHANGE_CLIENTS") {
+		prefix := tokenExchangeClientPrefix(client)
+		exchange.Policies[client] = &TokenExchangePolicy{
+			Audiences: src.getListOrDefault(prefix + "AUDIENCES"),
+			Scopes:    src.getListOrDefault(prefix + "SCOPES"),
+		}
+	}
+	return exchange
+}
+
+func tokenExchangeClientPrefix(client string) string {
+	return "TOKEN_EXCHANGE_CLIENT_" + strings.ToUpper(strings.NewReplacer("-", "_", ":", "_").Replace(client)) + "_"
+}
+
+// loadGroupPolicy reads the rule for groups without a type from
+// GROUP_NAME_PATTERN, GROUP_NAME_PREFIX, GROUP_DESCRIPTION_REQUIRED and
+// GROUP_REQUIRED_TAGS, and the group types named in GROUP_TYPES (comma
+// separated). Type "app-access" overrides those settings with
+// GROUP_TYPE_APP_ACCESS_NAME_PATTERN and so on; what it does not override is
+// taken from the untyped rule.
+func loadGroupPolicy(src *source) *GroupPolicyConfig {
+	untyped := &GroupPolicyRule{
+		NamePattern:         src.lookup("GROUP_NAME_PATTERN"),
+		NamePrefix:          src.lookup("GROUP_NAME_PREFIX"),
+		DescriptionRequired: src.getBoolOrDefault("GROUP_DESCRIPTION_REQUIRED", false),
+		RequiredTags:        src.getListOrDefault("GROUP_REQUIRED_TAGS"),
+	}
+
+	policy := &GroupPolicyConfig{
+		TypeAttribute: src.getEnvOrDefault("GROUP_TYPE_ATTRIBUTE", "groupType"),
+		Default:       untyped,
+		Types:         make(map[string]*GroupPolicyRule),
+	}
+
+	for _, groupType := range src.getListOrDefault("GROUP_TYPES") {
+		prefix := groupTypePrefix(groupType)
+		rule := &GroupPolicyRule{
+			NamePattern:         src.getEnvOrDefault(prefix+"NAME_PATTERN", untyped.NamePattern),
+			NamePrefix:          src.getEnvOrDefault(prefix+"NAME_PREFIX", untyped.NamePrefix),
+			DescriptionRequired: src.getBoolOrDefault(prefix+"DESCRIPTION_REQUIRED", untyped.DescriptionRequired),
+			RequiredTags:        untyped.RequiredTags,
+		}
+		if tags := src.getListOrDefault(prefix + "REQUIRED_TAGS"); len(tags) > 0 {
+			rule.RequiredTags = tags
+		}
+		policy.Types[groupType] = rule
+	}
+
+	return policy
+}
+
+func groupTypePrefix(groupType string) string {
+	return "GROUP_TYPE_" + strings.ToUpper(strings.ReplaceAll(groupType, "-", "_")) + "_"
+}
+
+// loadDefaultGroups reads the user types named in DEFAULT_GROUP_USER_TYPES
+// and the departments named in DEFAULT_GROUP_DEPARTMENTS (comma separated).
+// New users of type "Contractor" join the groups listed in
+// DEFAULT_GROUP_USER_TYPE_CONTRACTOR_GROUPS, and those in department
+// "Engineering" the groups in DEFAULT_GROUP_DEPARTMENT_ENGINEERING_GROUPS.
+func loadDefaultGroups(src *source) *DefaultGroupsConfig {
+	defaults := &DefaultGroupsConfig{
+		StorageDir:  src.getEnvOrDefault("DEFAULT_GROUP_STORAGE_DIR", "data/default-groups"),
+		UserTypes:   make(map[string][]string),
+		Departments: make(map[string][]string),
+	}
+	for _, userType := range src.getListOrDefault("DEFAULT_GROUP_USER_TYPES") {
+		defaults.UserTypes[userType] = src.getListOrDefault(defaultGroupsKey("USER_TYPE", userType))
+	}
+	for _, department := range src.getListOrDefault("DEFAULT_GROUP_DEPARTMENTS") {
+		defaults.Departments[department] = src.getListOrDefault(defaultGroupsKey("DEPARTMENT", department))
+	}
+	return defaults
+}
+
+func defaultGroupsKey(kind, value string) string {
+	value = strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(value))
+	return "DEFAULT_GROUP_" + kind + "_" + value + "_GROUPS"
+}
+
+// loadOrgs reads the orgs named in OKTA_ORGS (comma separated). Each org
+// "brand-a" is configured through OKTA_ORG_BRAND_A_DOMAIN and authenticates
+// with the secret named by OKTA_ORG_BRAND_A_API_TOKEN_SECRET, which defaults
+// to OKTA_ORG_BRAND_A_API_TOKEN, or as a service app with
+// OKTA_ORG_BRAND_A_CLIENT_ID and OKTA_ORG_BRAND_A_PRIVATE_KEY_SECRET. It may
+// override the primary org's cache and rate limit settings with
+// OKTA_ORG_BRAND_A_CACHE_TTL and OKTA_ORG_BRAND_A_RATE_LIMIT_MAX_RETRIES.
+func loadOrgs(src *source, primary *OktaConfig) map[string]*OktaConfig {
+	orgs := make(map[string]*OktaConfig)
+
+	for _, name := range strings.Split(src.lookup("OKTA_ORGS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if name == primary.Name {
+			src.errs = append(src.errs, fmt.Errorf("OKTA_ORGS: org %q has the same name as the primary org (OKTA_ORG_NAME)", name))
+			continue
+		}
+
+		prefix := orgPrefix(name)
+		org := &OktaConfig{
+			Name:                name,
+			Backend:             primary.Backend,
+			Domain:              src.lookup(prefix + "DOMAIN"),
+			APITokenSecret:      src.getEnvOrDefault(prefix+"API_TOKEN_SECRET", prefix+"API_TOKEN"),
+			ClientID:            src.lookup(prefix + "CLIENT_ID"),
+			PrivateKeySecret:    src.getEnvOrDefault(prefix+"PRIVATE_KEY_SECRET", prefix+"PRIVATE_KEY"),
+			PrivateKeyID:        src.lookup(prefix + "PRIVATE_KEY_ID"),
+			Scopes:              primary.Scopes,
+			CacheTTL:            src.getDurationOrDefault(prefix+"CACHE_TTL", primary.CacheTTL.String()),
+			RateLimitMaxRetries: src.getIntOrDefault(prefix+"RATE_LIMIT_MAX_RETRIES", primary.RateLimitMaxRetries),
+			BreakerFailures:     src.getIntOrDefault(prefix+"BREAKER_FAILURES", primary.BreakerFailures),
+			BreakerOpenTimeout:  src.getDurationOrDefault(prefix+"BREAKER_OPEN_TIMEOUT", primary.BreakerOpenTimeout.String()),
+			BudgetSyncShare:     src.getIntOrDefault(prefix+"BUDGET_SYNC_SHARE", primary.BudgetSyncShare),
+			BudgetBatchShare:    src.getIntOrDefault(prefix+"BUDGET_BATCH_SHARE", primary.BudgetBatchShare),
+			BudgetMaxWait:       src.getDurationOrDefault(prefix+"BUDGET_MAX_WAIT", primary.BudgetMaxWait.String()),
+		}
+
+		if scopes := src.getListOrDefault(prefix + "SCOPES"); len(scopes) > 0 {
+			org.Scopes = scopes
+		}
+
+		orgs[name] = org
+	}
+
+	return orgs
+}
+
+// orgPrefix is the prefix of the variables configuring the named org.
+func orgPrefix(name string) string {
+	return "OKTA_ORG_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
 }
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/yaml.v3"
+)
+
+// source resolves settings from the environment, falling back to the YAML
+// file named by CONFIG_FILE. It records the value of every setting it is
+// asked for, and every value that fails to parse, so Load can report all
+// problems at once instead of silently using defaults.
+type source struct {
+	file   map[string]string
+	values map[string]string
+	errs   []error
+}
+
+// newSource reads the YAML file at path, if any. Nested keys are joined with
+// underscores and upper-cased, so
+//
+//	okta:
+//	  cache_ttl: 30s
+//
+// sets OKTA_CACHE_TTL. Lists become comma separated values.
+func newSource(path string) (*source, error) {
+	src := &source{file: make(map[string]string), values: make(map[string]string)}
+	if path == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]any
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	flatten("", document, src.file)
+	return src, nil
+}
+
+func flatten(prefix string, node map[string]any, into map[string]string) {
+	for key, value := range node {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+
+		switch value := value.(type) {
+		case map[string]any:
+			flatten(name, value, into)
+		case []any:
+			items := make([]string, 0, len(value))
+			for _, item := range value {
+				items = append(items, fmt.Sprint(item))
+			}
+			into[name] = strings.Join(items, ",")
+		case nil:
+			into[name] = ""
+		default:
+			into[name] = fmt.Sprint(value)
+		}
+	}
+}
+
+func (s *source) lookup(key string) string {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		value = s.file[key]
+	}
+	s.values[key] = value
+	return value
+}
+
+func (s *source) invalid(key, value, kind string) {
+	s.errs = append(s.errs, fmt.Errorf("%s: %q is not a valid %s", key, value, kind))
+}
+
+// unknown lists the keys of the config file that no setting read, which are
+// almost always typos.
+func (s *source) unknown() []string {
+	var keys []string
+	for key := range s.file {
+		if _, read := s.values[key]; !read {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *source) getEnvOrDefault(key, defaultValue string) string {
+	if value := s.lookup(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func (s *source) getDurationOrDefault(key, defaultValue string) time.Duration {
+	if value := s.lookup(key); value != "" {
+		duration, err := time.ParseDuration(value)
+		if err == nil {
+			return duration
+		}
+		s.invalid(key, value, "duration")
+	}
+	duration, _ := time.ParseDuration(defaultValue)
+	return duration
+}
+
+func (s *source) getIntOrDefault(key string, defaultValue int) int {
+	if value := s.lookup(key); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err == nil {
+			return parsed
+		}
+		s.invalid(key, value, "integer")
+	}
+	return defaultValue
+}
+
+func (s *source) getBoolOrDefault(key string, defaultValue bool) bool {
+	if value := s.lookup(key); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err == nil {
+			return parsed
+		}
+		s.invalid(key, value, "boolean")
+	}
+	return defaultValue
+}
+
+func (s *source) getLogLevelOrDefault(key string, defaultValue zapcore.Level) zapcore.Level {
+	if value := s.lookup(key); value != "" {
+		level, err := zapcore.ParseLevel(value)
+		if err == nil {
+			return level
+		}
+		s.invalid(key, value, "log level")
+	}
+	return defaultValue
+}
+
+// getListOrDefault splits a comma separated variable, dropping empty items.
+func (s *source) getListOrDefault(key string) []string {
+	var values []string
+	for _, value := range strings.Split(s.lookup(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamBelugaa/iam/pkg/request"
+)
+
+// validate checks the settings that parse but cannot work, such as missing
+// required values or intervals the workers cannot tick at.
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+	positive := func(key string, value time.Duration) {
+		check(value > 0, key, "must be greater than zero, got %s", value)
+	}
+
+	for key, port := range map[string]string{"PORT": c.Server.Port, "GRPC_PORT": c.Server.GRPCPort} {
+		number, err := strconv.Atoi(port)
+		check(err == nil && number > 0 && number < 65536, key, "%q is not a valid port", port)
+	}
+	positive("READ_TIMEOUT", c.Server.ReadTimeout)
+	positive("WRITE_TIMEOUT", c.Server.WriteTimeout)
+	positive("REQUEST_TIMEOUT_READ", c.RequestTimeouts.Read)
+	positive("REQUEST_TIMEOUT_WRITE", c.RequestTimeouts.Write)
+	positive("REQUEST_TIMEOUT_BULK", c.RequestTimeouts.Bulk)
+	positive("IDLE_TIMEOUT", c.Server.IdleTimeout)
+
+	check(c.Okta.Backend == OktaBackendOkta || c.Okta.Backend == OktaBackendMemory,
+		"OKTA_BACKEND", "must be %q or %q, got %q", OktaBackendOkta, OktaBackendMemory, c.Okta.Backend,
+	)
+	standalone := c.Okta.Backend == OktaBackendMemory
+	check(standalone || c.Okta.Domain != "", "OKTA_DOMAIN", "is required")
+	check(standalone || c.Okta.Issuer != "", "OKTA_ISSUER", "is required")
+	errs = append(errs, c.Okta.validate("OKTA_")...)
+	for name, org := range c.Orgs {
+		prefix := orgPrefix(name)
+		check(standalone || org.Domain != "", prefix+"DOMAIN", "is required for org %q", name)
+		errs = append(errs, org.validate(prefix)...)
+	}
+
+	switch c.Storage.Backend {
+	case StorageBackendFile, StorageBackendMemory:
+	case StorageBackendPostgres:
+		check(c.Storage.PostgresURL != "", "STORAGE_POSTGRES_URL", "is required when STORAGE_BACKEND is %q", StorageBackendPostgres)
+		check(c.Storage.MaxConns > 0, "STORAGE_POSTGRES_MAX_CONNS", "must be greater than zero")
+		check(c.Storage.MinConns >= 0 && c.Storage.MinConns <= c.Storage.MaxConns,
+			"STORAGE_POSTGRES_MIN_CONNS", "must be between 0 and STORAGE_POSTGRES_MAX_CONNS",
+		)
+		positive("STORAGE_POSTGRES_MAX_CONN_IDLE_TIME", c.Storage.MaxConnIdleTime)
+		positive("STORAGE_POSTGRES_MAX_CONN_LIFETIME", c.Storage.MaxConnLifetime)
+	default:
+		check(false, "STORAGE_BACKEND", "must be %q, %q or %q, got %q",
+			StorageBackendFile, StorageBackendPostgres, StorageBackendMemory, c.Storage.Backend,
+		)
+	}
+
+	positive("MEMBERSHIP_EXPIRY_INTERVAL", c.Workers.MembershipExpiryInterval)
+	positive("INACTIVE_USER_INTERVAL", c.Workers.InactiveUserInterval)
+	positive("SERVICE_ACCOUNT_REMINDER_INTERVAL", c.Workers.ServiceAccountInterval)
+	positive("GUEST_LIFECYCLE_INTERVAL", c.Workers.GuestInterval)
+	positive("PENDING_CHANGE_EXPIRY_INTERVAL", c.Workers.PendingChangeInterval)
+	positive("DIRECTORY_REFRESH_INTERVAL", c.Workers.DirectoryRefreshInterval)
+	positive("SECRETS_REFRESH_INTERVAL", c.Secrets.RefreshInterval)
+	positive("CONFIG_RELOAD_INTERVAL", c.File.ReloadInterval)
+
+	check(c.Reports.InactiveUserDays > 0, "INACTIVE_USER_DAYS", "must be greater than zero")
+	check(c.Reports.UnusedAccessDays > 0, "UNUSED_ACCESS_DAYS", "must be greater than zero")
+
+	check(c.Avatars.URLSecret != "", "AVATAR_URL_SECRET", "is required to sign avatar URLs")
+	positive("AVATAR_URL_TTL", c.Avatars.URLTTL)
+	check(c.Avatars.MaxSizeBytes > 0, "AVATAR_MAX_SIZE_BYTES", "must be greater than zero")
+
+	if _, err := regexp.Compile(c.ServiceAccounts.NamePattern); err != nil {
+		check(false, "SERVICE_ACCOUNT_NAME_PATTERN", "is not a valid regular expression: %v", err)
+	}
+	positive("SERVICE_ACCOUNT_REVIEW_INTERVAL", c.ServiceAccounts.ReviewInterval)
+	check(c.ServiceAccounts.RotationIntervalDays > 0, "SERVICE_ACCOUNT_ROTATION_DAYS", "must be greater than zero")
+	positive("SERVICE_ACCOUNT_ROTATION_VERIFY_TIMEOUT", c.ServiceAccounts.RotationVerifyTimeout)
+	positive("SERVICE_ACCOUNT_ROTATION_VERIFY_INTERVAL", c.ServiceAccounts.RotationVerifyInterval)
+
+	check(c.Server.RequestDecoding == "" || request.IsMode(c.Server.RequestDecoding),
+		"REQUEST_DECODING", "must be strict, lenient or empty, got %q", c.Server.RequestDecoding,
+	)
+	check(c.GroupPolicy.TypeAttribute != "", "GROUP_TYPE_ATTRIBUTE", "must not be empty")
+	if _, err := regexp.Compile(c.GroupPolicy.Default.NamePattern); err != nil {
+		check(false, "GROUP_NAME_PATTERN", "is not a valid regular expression: %v", err)
+	}
+	for groupType, rule := range c.GroupPolicy.Types {
+		if _, err := regexp.Compile(rule.NamePattern); err != nil {
+			check(false, groupTypePrefix(groupType)+"NAME_PATTERN", "is not a valid regular expression: %v", err)
+		}
+	}
+
+	for userType, groups := range c.DefaultGroups.UserTypes {
+		check(len(groups) > 0, defaultGroupsKey("USER_TYPE", userType), "must list at least one group ID")
+	}
+	for department, groups := range c.DefaultGroups.Departments {
+		check(len(groups) > 0, defaultGroupsKey("DEPARTMENT", department), "must list at least one group ID")
+	}
+
+	positive("GUEST_MAX_DURATION", c.Guests.MaxDuration)
+	positive("GUEST_ATTESTATION_INTERVAL", c.Guests.AttestationInterval)
+	positive("INVITATION_TTL", c.Invitations.TTL)
+	check(c.DeviceAuth.ClientID == "" || c.Okta.Issuer != "", "DEVICE_AUTH_CLIENT_ID", "requires OKTA_ISSUER")
+	check(c.TokenExchange.ClientID == "" || c.Okta.Issuer != "", "TOKEN_EXCHANGE_CLIENT_ID", "requires OKTA_ISSUER")
+	for client, policy := range c.TokenExchange.Policies {
+		prefix := tokenExchangeClientPrefix(client)
+		check(len(policy.Audiences) > 0, prefix+"AUDIENCES", "must list at least one audience")
+		check(len(policy.Scopes) > 0, prefix+"SCOPES", "must list at least one scope")
+	}
+	for _, origin := range c.SessionExchange.AllowedOrigins {
+		parsed, err := url.Parse(origin)
+		check(err == nil && (parsed.Scheme == "https" || parsed.Scheme == "http") && parsed.Host != "" &&
+			parsed.User == nil && parsed.Path == "" && parsed.RawQuery == "" && parsed.Fragment == "",
+			"SESSION_EXCHANGE_ALLOWED_ORIGINS", "%q is not an origin such as https://app.example.com", origin,
+		)
+	}
+	positive("GROUP_TRASH_RETENTION", c.GroupTrash.Retention)
+	check(c.GroupClone.JobThreshold >= 0, "GROUP_CLONE_JOB_THRESHOLD", "must not be negative")
+	positive("MEMBERSHIP_SNAPSHOT_INTERVAL", c.History.SnapshotInterval)
+	positive("MEMBERSHIP_SNAPSHOT_RETENTION", c.History.Retention)
+	positive("MEMBERSHIP_EVENTS_INTERVAL", c.MembershipEvents.PollInterval)
+	positive("MEMBERSHIP_EVENTS_BACKFILL", c.MembershipEvents.Backfill)
+	check(c.MembershipEvents.Backfill <= 90*24*time.Hour, "MEMBERSHIP_EVENTS_BACKFILL",
+		"must not exceed the System Log retention of 90 days, got %s", c.MembershipEvents.Backfill,
+	)
+	positive("MEMBERSHIP_EVENTS_RETENTION", c.MembershipEvents.Retention)
+	check(c.Changes.MaxChanges > 0, "CHANGE_FEED_MAX_CHANGES", "must be greater than zero")
+	check(c.Events.Replay > 0, "EVENTS_REPLAY", "must be greater than zero")
+	check(c.Events.SubscriberBuffer > 0, "EVENTS_SUBSCRIBER_BUFFER", "must be greater than zero")
+	positive("EVENTS_HEARTBEAT_INTERVAL", c.Events.HeartbeatInterval)
+	positive("DRIFT_CHECK_INTERVAL", c.Drift.CheckInterval)
+	positive("DRIFT_CORRELATION_WINDOW", c.Drift.CorrelationWindow)
+	positive("DASHBOARD_UPDATE_INTERVAL", c.Dashboard.UpdateInterval)
+	check(c.Dashboard.Days > 0, "DASHBOARD_DAYS", "must be greater than zero")
+	check(c.Dashboard.RecentMutations > 0, "DASHBOARD_RECENT_MUTATIONS", "must be greater than zero")
+	positive("SECURITY_DETECTION_INTERVAL", c.Security.DetectionInterval)
+	check(c.Security.BruteForceThreshold > 0, "SECURITY_BRUTE_FORCE_THRESHOLD", "must be greater than zero")
+	positive("SECURITY_BRUTE_FORCE_WINDOW", c.Security.BruteForceWindow)
+	check(c.Security.MaxTravelSpeed > 0, "SECURITY_MAX_TRAVEL_SPEED", "must be greater than zero")
+	check(c.RetryQueue.MaxEntries > 0, "RETRY_QUEUE_MAX_ENTRIES", "must be greater than zero")
+	check(c.RetryQueue.MaxAttempts > 0, "RETRY_QUEUE_MAX_ATTEMPTS", "must be greater than zero")
+	positive("RETRY_QUEUE_INTERVAL", c.RetryQueue.Interval)
+	positive("RETRY_QUEUE_BACKOFF", c.RetryQueue.Backoff)
+	positive("RECONCILIATION_INTERVAL", c.Reconciliation.Interval)
+	check(c.Reconciliation.MaxAttempts > 0, "RECONCILIATION_MAX_ATTEMPTS", "must be greater than zero")
+	check(c.Replay.MaxCaptures > 0, "REPLAY_MAX_CAPTURES", "must be greater than zero")
+	check(c.BulkDeactivation.MaxUsers > 0, "BULK_DEACTIVATION_MAX_USERS", "must be greater than zero")
+	check(c.BulkDeactivation.Interval >= 0, "BULK_DEACTIVATION_INTERVAL", "must not be negative")
+	positive("BULK_DEACTIVATION_PREVIEW_TTL", c.BulkDeactivation.PreviewTTL)
+	check((c.Consent.GroupJoinText == "") == (c.Consent.GroupJoinVersion == ""), "CONSENT_GROUP_JOIN_VERSION",
+		"must be set together with CONSENT_GROUP_JOIN_TEXT",
+	)
+	for _, attribute := range []string{"login", "email"} {
+		check(!slices.Contains(c.SelfService.ProfileAttributes, attribute), "SELF_SERVICE_PROFILE_ATTRIBUTES",
+			"must not include %s, which identifies the user", attribute,
+		)
+	}
+	positive("PENDING_CHANGE_TTL", c.PendingChanges.TTL)
+	positive("SCHEDULED_CHANGE_INTERVAL", c.ScheduledChanges.Interval)
+	check(c.ScheduledChanges.MaxAttempts > 0, "SCHEDULED_CHANGE_MAX_ATTEMPTS", "must be greater than zero")
+	positive("SCHEDULED_CHANGE_BACKOFF", c.ScheduledChanges.Backoff)
+	check(c.Backups.Storage == "file" || c.Backups.Storage == "s3", "BACKUP_STORAGE",
+		"must be \"file\" or \"s3\", got %q", c.Backups.Storage,
+	)
+	check(c.Backups.Storage != "s3" || c.Backups.S3Bucket != "", "BACKUP_S3_BUCKET", "is required when BACKUP_STORAGE is s3")
+	if c.Backups.S3Endpoint != "" {
+		endpoint, err := url.Parse(c.Backups.S3Endpoint)
+		check(err == nil && endpoint.Scheme != "" && endpoint.Host != "", "BACKUP_S3_ENDPOINT", "%q is not a valid URL", c.Backups.S3Endpoint)
+	}
+	positive("API_KEY_DEFAULT_TTL", c.APIKeys.DefaultTTL)
+	check(c.APIKeys.MaxTTL >= c.APIKeys.DefaultTTL, "API_KEY_MAX_TTL",
+		"must not be shorter than API_KEY_DEFAULT_TTL, got %s", c.APIKeys.MaxTTL,
+	)
+	check(c.APIKeys.RotationGrace >= 0, "API_KEY_ROTATION_GRACE", "must not be negative")
+
+	check(c.RateLimit.ReadsPerMinute >= 0, "RATE_LIMIT_READS_PER_MINUTE", "must not be negative")
+	check(c.RateLimit.WritesPerMinute >= 0, "RATE_LIMIT_WRITES_PER_MINUTE", "must not be negative")
+	check(c.RateLimit.ReadsPerMinute == 0 || c.RateLimit.ReadBurst > 0, "RATE_LIMIT_READ_BURST", "must be greater than zero")
+	check(c.RateLimit.WritesPerMinute == 0 || c.RateLimit.WriteBurst > 0, "RATE_LIMIT_WRITE_BURST", "must be greater than zero")
+
+	// Ranging over maps makes the order vary between runs.
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+func (o *OktaConfig) validate(prefix string) []error {
+	var errs []error
+	if o.ClientID != "" && len(o.Scopes) == 0 {
+		errs = append(errs, fmt.Errorf("%sSCOPES: scopes are required to authenticate as a service app", prefix))
+	}
+	if o.CacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("%sCACHE_TTL: must not be negative", prefix))
+	}
+	if o.RateLimitMaxRetries < 0 {
+		errs = append(errs, fmt.Errorf("%sRATE_LIMIT_MAX_RETRIES: must not be negative", prefix))
+	}
+	if o.BreakerFailures <= 0 {
+		errs = append(errs, fmt.Errorf("%sBREAKER_FAILURES: must be greater than zero", prefix))
+	}
+	if o.BreakerOpenTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%sBREAKER_OPEN_TIMEOUT: must be greater than zero, got %s", prefix, o.BreakerOpenTimeout))
+	}
+	if o.BudgetSyncShare <= 0 || o.BudgetSyncShare >= 100 {
+		errs = append(errs, fmt.Errorf("%sBUDGET_SYNC_SHARE: must be between 1 and 99, got %d", prefix, o.BudgetSyncShare))
+	}
+	if o.BudgetBatchShare <= 0 || o.BudgetBatchShare >= 100 {
+		errs = append(errs, fmt.Errorf("%sBUDGET_BATCH_SHARE: must be between 1 and 99, got %d", prefix, o.BudgetBatchShare))
+	}
+	if o.BudgetSyncShare+o.BudgetBatchShare >= 100 {
+		errs = append(errs, fmt.Errorf("%sBUDGET_SYNC_SHARE, %sBUDGET_BATCH_SHARE: must add up to less than 100", prefix, prefix))
+	}
+	if o.BudgetMaxWait < 0 {
+		errs = append(errs, fmt.Errorf("%sBUDGET_MAX_WAIT: must not be negative", prefix))
+	}
+	return errs
+}
+
+// Reloadable reports whether a change to the setting takes effect without a
+// restart: the log level and redaction, and each org's cache TTL, rate limit retries and
+// rate limit budget.
+func Reloadable(key string) bool {
+	if strings.HasPrefix(key, "LOG_") {
+		return true
+	}
+	return strings.HasPrefix(key, "OKTA_") &&
+		(strings.HasSuffix(key, "_CACHE_TTL") || strings.HasSuffix(key, "_RATE_LIMIT_MAX_RETRIES") ||
+			strings.Contains(key, "_BUDGET_"))
+}
+
+// Changed lists the settings whose values differ between c and next.
+func (c *Config) Changed(next *Config) []string {
+	var keys []string
+	for key, value := range next.values {
+		if previous, ok := c.values[key]; !ok || previous != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range c.values {
+		if _, ok := next.values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,172 @@
+package grpc_server
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	iamv1 "github.com/iamBelugaa/iam/api/iam/v1"
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+)
+
+type groupServer struct {
+	iamv1.UnimplementedGroupServiceServer
+
+	log       *zap.SugaredLogger
+	groupsSvc *group_service.Service
+	// adminGroups may change the members of any group; with none, membership
+	// changes are not restricted.
+	adminGroups []string
+	metadataSvc *groupmetadata_service.Service
+	changesSvc  *pendingchange_service.Service
+}
+
+func (s *groupServer) CreateGroup(ctx context.Context, req *iamv1.CreateGroupRequest) (*iamv1.Group, error) {
+	group, err := s.groupsSvc.CreateGroup(ctx, &models.CreateGroupRequest{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Profile:     req.GetProfile().AsMap(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to create group")
+	}
+	return toGroup(group), nil
+}
+
+func (s *groupServer) GetGroup(ctx context.Context, req *iamv1.GetGroupRequest) (*iamv1.Group, error) {
+	if req.GetGroupId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+
+	group, err := s.groupsSvc.GetGroup(ctx, req.GetGroupId())
+	if err != nil {
+		return nil, toStatus(err, "failed to retrieve group")
+	}
+	return toGroup(group), nil
+}
+
+func (s *groupServer) ListGroups(req *iamv1.ListGroupsRequest, stream grpc.ServerStreamingServer[iamv1.Group]) error {
+	err := s.groupsSvc.StreamGroups(stream.Context(), func(group *models.Group) error {
+		return stream.Send(toGroup(group))
+	})
+	if err != nil {
+		return toStatus(err, "failed to retrieve groups")
+	}
+	return nil
+}
+
+func (s *groupServer) UpdateGroup(ctx context.Context, req *iamv1.UpdateGroupRequest) (*iamv1.Group, error) {
+	if req.GetGroupId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+
+	group, err := s.groupsSvc.UpdateGroup(ctx, req.GetGroupId(), &models.UpdateGroupRequest{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Profile:     req.GetProfile().AsMap(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to update group")
+	}
+	return toGroup(group), nil
+}
+
+func (s *groupServer) DeleteGroup(ctx context.Context, req *iamv1.GetGroupRequest) (*emptypb.Empty, error) {
+	if req.GetGroupId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id is required")
+	}
+
+	if err := s.groupsSvc.DeleteGroup(ctx, req.GetGroupId()); err != nil {
+		return nil, toStatus(err, "failed to delete group")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *groupServer) ListGroupMembers(
+	req *iamv1.ListGroupMembersRequest, stream grpc.ServerStreamingServer[iamv1.GroupMember],
+) error {
+	if req.GetGroupId() == "" {
+		return status.Error(codes.InvalidArgument, "group_id is required")
+	}
+
+	var expirations map[string]time.Time
+	if req.GetIncludeExpiry() {
+		expirations = s.groupsSvc.GetMembershipExpirations(req.GetGroupId())
+	}
+
+	err := s.groupsSvc.StreamGroupMembers(stream.Context(), req.GetGroupId(), func(user *models.User) error {
+		member := &iamv1.GroupMember{User: toUser(user)}
+		if expiresAt, ok := expirations[user.ID]; ok {
+			member.ExpiresAt = timestamppb.New(expiresAt)
+		}
+		return stream.Send(member)
+	})
+	if err != nil {
+		return toStatus(err, "failed to retrieve group members")
+	}
+	return nil
+}
+
+func (s *groupServer) AddGroupMember(ctx context.Context, req *iamv1.AddGroupMemberRequest) (*emptypb.Empty, error) {
+	if req.GetGroupId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id and user_id are required")
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		value := req.GetExpiresAt().AsTime()
+		if !value.After(time.Now()) {
+			return nil, status.Error(codes.InvalidArgument, "expires_at must be in the future")
+		}
+		expiresAt = &value
+	}
+
+	if err := s.authorizeMembership(ctx, req.GetGroupId()); err != nil {
+		return nil, err
+	}
+	if err := s.hold(ctx, req.GetGroupId(), req.GetUserId(), models.PendingChangeOperationAdd, expiresAt); err != nil {
+		return nil, err
+	}
+	if err := s.groupsSvc.AddUserToGroup(ctx, req.GetGroupId(), req.GetUserId(), expiresAt); err != nil {
+		return nil, toStatus(err, "failed to add user to group")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *groupServer) RemoveGroupMember(ctx context.Context, req *iamv1.RemoveGroupMemberRequest) (*emptypb.Empty, error) {
+	if req.GetGroupId() == "" || req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "group_id and user_id are required")
+	}
+
+	if err := s.authorizeMembership(ctx, req.GetGroupId()); err != nil {
+		return nil, err
+	}
+	if err := s.hold(ctx, req.GetGroupId(), req.GetUserId(), models.PendingChangeOperationRemove, nil); err != nil {
+		return nil, err
+	}
+	if err := s.groupsSvc.RemoveUserFromGroup(ctx, req.GetGroupId(), req.GetUserId()); err != nil {
+		return nil, toStatus(err, "failed to remove user from group")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toGroup(group *models.Group) *iamv1.Group {
+	return &iamv1.Group{
+		Id:          group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		Type:        group.Type,
+		Created:     timestamppb.New(group.Created),
+		LastUpdated: timestamppb.New(group.LastUpdated),
+		Profile:     toStruct(group.Profile),
+	}
+}
@@ -0,0 +1,71 @@
+package grpc_server
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	iamv1 "github.com/iamBelugaa/iam/api/iam/v1"
+	"github.com/iamBelugaa/iam/internal/auth"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+func TestAuthorizeMembership(t *testing.T) {
+	log := zap.NewNop().Sugar()
+	metadataSvc := groupmetadata_service.New(log, objectstore.NewMemoryStore())
+	if _, err := metadataSvc.AddOwner(context.Background(), "00gteam", "00uowner"); err != nil {
+		t.Fatalf("AddOwner() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		adminGroups []string
+		caller      *auth.Caller
+		want        codes.Code
+	}{
+		{name: "unrestricted without admin groups", caller: &auth.Caller{UserID: "00uother"}, want: codes.OK},
+		{name: "admin", adminGroups: []string{"admins"}, caller: &auth.Caller{UserID: "00uadmin", Groups: []string{"admins"}}, want: codes.OK},
+		{name: "owner", adminGroups: []string{"admins"}, caller: &auth.Caller{UserID: "00uowner"}, want: codes.OK},
+		{name: "non-owner", adminGroups: []string{"admins"}, caller: &auth.Caller{UserID: "00uother", Groups: []string{"eng"}}, want: codes.PermissionDenied},
+		{name: "no caller", adminGroups: []string{"admins"}, want: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &groupServer{log: log, adminGroups: tt.adminGroups, metadataSvc: metadataSvc}
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = auth.WithCaller(ctx, tt.caller)
+			}
+
+			err := server.authorizeMembership(ctx, "00gteam")
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("authorizeMembership() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMembershipChangesRejectNonOwners(t *testing.T) {
+	log := zap.NewNop().Sugar()
+	// groupsSvc and changesSvc are nil: a rejected call must not reach them.
+	server := &groupServer{
+		log:         log,
+		adminGroups: []string{"admins"},
+		metadataSvc: groupmetadata_service.New(log, objectstore.NewMemoryStore()),
+	}
+	ctx := auth.WithCaller(context.Background(), &auth.Caller{UserID: "00uother"})
+
+	_, err := server.AddGroupMember(ctx, &iamv1.AddGroupMemberRequest{GroupId: "00gteam", UserId: "00uother"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("AddGroupMember() error = %v, want PermissionDenied", err)
+	}
+	_, err = server.RemoveGroupMember(ctx, &iamv1.RemoveGroupMemberRequest{GroupId: "00gteam", UserId: "00uowner"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("RemoveGroupMember() error = %v, want PermissionDenied", err)
+	}
+}
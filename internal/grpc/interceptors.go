@@ -0,0 +1,136 @@
+package grpc_server
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/okta"
+)
+
+func unaryLogging(log *zap.SugaredLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx, id := withRequestID(ctx, log)
+		if err := grpc.SetHeader(ctx, metadata.Pairs(logger.RequestIDHeader, id)); err != nil {
+			log.Infow("Failed to set request ID header", zap.Error(err))
+		}
+		ctx = okta.TrackUnavailable(ctx)
+
+		resp, err := handler(ctx, req)
+		err = unavailable(ctx, err)
+		logger.FromContext(ctx, log).Infow("gRPC request completed",
+			"method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start),
+		)
+		return resp, err
+	}
+}
+
+func streamLogging(log *zap.SugaredLogger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, id := withRequestID(ss.Context(), log)
+		if err := ss.SetHeader(metadata.Pairs(logger.RequestIDHeader, id)); err != nil {
+			log.Infow("Failed to set request ID header", zap.Error(err))
+		}
+		ctx = okta.TrackUnavailable(ctx)
+
+		err := unavailable(ctx, handler(srv, &contextStream{ServerStream: ss, ctx: ctx}))
+		logger.FromContext(ctx, log).Infow("gRPC stream completed",
+			"method", info.FullMethod, "code", status.Code(err).String(), "duration", time.Since(start),
+		)
+		return err
+	}
+}
+
+// withRequestID gives the call a correlation ID, the client's x-request-id
+// metadata when it sends a usable one, like requests to the HTTP API get.
+func withRequestID(ctx context.Context, log *zap.SugaredLogger) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var clientID string
+	if values := md.Get(logger.RequestIDHeader); len(values) > 0 {
+		clientID = values[0]
+	}
+
+	id := logger.NewRequestID(clientID)
+	return logger.WithRequestID(ctx, log, id), id
+}
+
+// unavailable reports an Internal error as Unavailable when the call failed
+// because an Okta circuit breaker was open, as the HTTP API answers 503.
+func unavailable(ctx context.Context, err error) error {
+	if status.Code(err) == codes.Internal {
+		if _, ok := okta.Unavailable(ctx); ok {
+			return status.Error(codes.Unavailable, "Okta is unavailable, retry later")
+		}
+	}
+	return err
+}
+
+func unaryAuth(log *zap.SugaredLogger, verifier *auth.Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, log, verifier)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(log *zap.SugaredLogger, verifier *auth.Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), log, verifier)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate verifies the bearer token in the authorization metadata and
+// stores the resulting Caller in the context, like auth.Authenticate does
+// for HTTP requests.
+func authenticate(ctx context.Context, log *zap.SugaredLogger, verifier *auth.Verifier) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var rawToken string
+	for _, value := range md.Get("authorization") {
+		scheme, token, found := strings.Cut(value, " ")
+		if found && strings.EqualFold(scheme, "Bearer") && token != "" {
+			rawToken = token
+			break
+		}
+	}
+
+	if rawToken == "" {
+		return nil, status.Error(codes.Unauthenticated, "a bearer token is required")
+	}
+
+	caller, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		logger.FromContext(ctx, log).Infow("Failed to verify access token", zap.Error(err))
+		return nil, status.Error(codes.Unauthenticated, "invalid or expired access token")
+	}
+
+	return auth.WithCaller(ctx, caller), nil
+}
+
+// contextStream replaces the context of a stream, which interceptors cannot
+// otherwise pass on to the handler.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,81 @@
+package grpc_server
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// authorizeMembership lets the caller change the group's members as the
+// HTTP membership routes do: with admin groups configured, only admins and
+// the group's owners may.
+func (s *groupServer) authorizeMembership(ctx context.Context, groupID string) error {
+	if len(s.adminGroups) == 0 {
+		return nil
+	}
+
+	caller, ok := auth.CallerFromContext(ctx)
+	if !ok {
+		return status.Error(codes.PermissionDenied, "only the group's owners and admins may change its members")
+	}
+	for _, group := range caller.Groups {
+		if slices.Contains(s.adminGroups, group) {
+			return nil
+		}
+	}
+
+	owner, err := s.metadataSvc.IsOwner(ctx, groupID, caller.UserID)
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to check group ownership",
+			zap.Error(err), "groupId", groupID, "userId", caller.UserID,
+		)
+		return status.Error(codes.Internal, "failed to check group ownership")
+	}
+	if !owner {
+		logger.FromContext(ctx, s.log).Infow("Membership change rejected for non-owner",
+			"groupId", groupID, "userId", caller.UserID,
+		)
+		return status.Error(codes.PermissionDenied, "only the group's owners and admins may change its members")
+	}
+	return nil
+}
+
+// hold holds a membership change to a group under the two-person rule as a
+// pending change, as the HTTP membership routes do. It returns nil for other
+// groups, and FailedPrecondition naming the pending change once one is held,
+// as the membership has not changed yet.
+func (s *groupServer) hold(
+	ctx context.Context, groupID, userID, operation string, expiresAt *time.Time,
+) error {
+	protected, err := s.changesSvc.IsProtected(ctx, groupID)
+	if err != nil {
+		return toStatus(err, "failed to check group protection")
+	}
+	if !protected {
+		return nil
+	}
+
+	var requester string
+	if caller, ok := auth.CallerFromContext(ctx); ok {
+		requester = caller.UserID
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Holding membership change to protected group",
+		"groupId", groupID, "userId", userID, "operation", operation, "requestedBy", requester,
+	)
+
+	change, err := s.changesSvc.Propose(ctx, requester, groupID, userID, operation, expiresAt)
+	if err != nil {
+		return toStatus(err, "failed to hold membership change")
+	}
+	return status.Errorf(codes.FailedPrecondition,
+		"the group is under the two-person rule: the change is pending approval by a second admin as %s", change.ID,
+	)
+}
@@ -0,0 +1,74 @@
+package grpc_server
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	iamv1 "github.com/iamBelugaa/iam/api/iam/v1"
+	"github.com/iamBelugaa/iam/internal/auth"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+)
+
+type Config struct {
+	Log           *zap.SugaredLogger
+	Verifier      *auth.Verifier
+	UsersService  *user_service.Service
+	GroupsService *group_service.Service
+	// AdminGroups, GroupMetadataService and PendingChangesService decide who
+	// may change a group's members and which changes wait for approval, as
+	// they do for the HTTP API.
+	AdminGroups           []string
+	GroupMetadataService  *groupmetadata_service.Service
+	PendingChangesService *pendingchange_service.Service
+}
+
+// New builds a gRPC server exposing the user and group services. Every call
+// is logged and must carry a valid Okta access token in the authorization
+// metadata.
+func New(cfg *Config) *grpc.Server {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryLogging(cfg.Log), unaryAuth(cfg.Log, cfg.Verifier)),
+		grpc.ChainStreamInterceptor(streamLogging(cfg.Log), streamAuth(cfg.Log, cfg.Verifier)),
+	)
+
+	iamv1.RegisterUserServiceServer(server, &userServer{usersSvc: cfg.UsersService})
+	iamv1.RegisterGroupServiceServer(server, &groupServer{
+		log:         cfg.Log,
+		groupsSvc:   cfg.GroupsService,
+		adminGroups: cfg.AdminGroups,
+		metadataSvc: cfg.GroupMetadataService,
+		changesSvc:  cfg.PendingChangesService,
+	})
+
+	return server
+}
+
+// toStatus maps service errors onto gRPC status codes, mirroring the HTTP
+// handlers. Unrecognised errors are reported as Internal with message.
+func toStatus(err error, message string) error {
+	var violationErr *sod_service.ViolationError
+	if errors.As(err, &violationErr) {
+		return status.Error(codes.FailedPrecondition, violationErr.Error())
+	}
+
+	switch {
+	case errors.Is(err, guest_service.ErrGroupNotEligible),
+		errors.Is(err, pendingchange_service.ErrRequesterRequired):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, pendingchange_service.ErrDuplicateChange):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, pendingchange_service.ErrMembershipExpiry):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, message)
+}
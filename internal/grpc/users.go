@@ -0,0 +1,164 @@
+package grpc_server
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	iamv1 "github.com/iamBelugaa/iam/api/iam/v1"
+	"github.com/iamBelugaa/iam/internal/models"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+)
+
+type userServer struct {
+	iamv1.UnimplementedUserServiceServer
+
+	usersSvc *user_service.Service
+}
+
+func (s *userServer) CreateUser(ctx context.Context, req *iamv1.CreateUserRequest) (*iamv1.User, error) {
+	user, err := s.usersSvc.CreateUser(ctx, &models.CreateUserRequest{
+		Email:     req.GetEmail(),
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Login:     req.GetLogin(),
+		Password:  req.GetPassword(),
+		Profile:   req.GetProfile().AsMap(),
+		Activate:  req.GetActivate(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to create user")
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *iamv1.GetUserRequest) (*iamv1.User, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := s.usersSvc.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err, "failed to retrieve user")
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) ListUsers(req *iamv1.ListUsersRequest, stream grpc.ServerStreamingServer[iamv1.User]) error {
+	err := s.usersSvc.StreamUsers(stream.Context(), func(user *models.User) error {
+		return stream.Send(toUser(user))
+	})
+	if err != nil {
+		return toStatus(err, "failed to retrieve users")
+	}
+	return nil
+}
+
+func (s *userServer) UpdateUser(ctx context.Context, req *iamv1.UpdateUserRequest) (*iamv1.User, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := s.usersSvc.UpdateUser(ctx, req.GetUserId(), &models.UpdateUserRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Profile:   req.GetProfile().AsMap(),
+	})
+	if err != nil {
+		return nil, toStatus(err, "failed to update user")
+	}
+	return toUser(user), nil
+}
+
+func (s *userServer) DeleteUser(ctx context.Context, req *iamv1.UserIdRequest) (*emptypb.Empty, error) {
+	return s.lifecycle(ctx, req, s.usersSvc.DeleteUser, "failed to delete user")
+}
+
+func (s *userServer) ActivateUser(ctx context.Context, req *iamv1.UserIdRequest) (*emptypb.Empty, error) {
+	return s.lifecycle(ctx, req, s.usersSvc.ActivateUser, "failed to activate user")
+}
+
+func (s *userServer) DeactivateUser(ctx context.Context, req *iamv1.UserIdRequest) (*emptypb.Empty, error) {
+	return s.lifecycle(ctx, req, s.usersSvc.DeactivateUser, "failed to deactivate user")
+}
+
+func (s *userServer) SuspendUser(ctx context.Context, req *iamv1.UserIdRequest) (*emptypb.Empty, error) {
+	return s.lifecycle(ctx, req, s.usersSvc.SuspendUser, "failed to suspend user")
+}
+
+func (s *userServer) UnsuspendUser(ctx context.Context, req *iamv1.UserIdRequest) (*emptypb.Empty, error) {
+	return s.lifecycle(ctx, req, s.usersSvc.UnsuspendUser, "failed to unsuspend user")
+}
+
+func (s *userServer) ListUserGroups(ctx context.Context, req *iamv1.UserIdRequest) (*iamv1.ListGroupsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	groups, err := s.usersSvc.GetUserGroups(ctx, req.GetUserId())
+	if err != nil {
+		return nil, toStatus(err, "failed to retrieve user groups")
+	}
+
+	result := &iamv1.ListGroupsResponse{Groups: make([]*iamv1.Group, len(groups))}
+	for i, group := range groups {
+		result.Groups[i] = toGroup(group)
+	}
+	return result, nil
+}
+
+func (s *userServer) lifecycle(
+	ctx context.Context, req *iamv1.UserIdRequest, action func(context.Context, string) error, message string,
+) (*emptypb.Empty, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	if err := action(ctx, req.GetUserId()); err != nil {
+		return nil, toStatus(err, message)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func toUser(user *models.User) *iamv1.User {
+	return &iamv1.User{
+		Id:          user.ID,
+		Email:       user.Email,
+		FirstName:   user.FirstName,
+		LastName:    user.LastName,
+		Login:       user.Login,
+		Status:      user.Status,
+		Created:     timestamppb.New(user.Created),
+		Activated:   toTimestamp(user.Activated),
+		LastLogin:   toTimestamp(user.LastLogin),
+		LastUpdated: toTimestamp(user.LastUpdated),
+		Profile:     toStruct(user.Profile),
+	}
+}
+
+func toTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+// toStruct converts a profile map, dropping it if it holds values that
+// cannot be represented as a protobuf Struct.
+func toStruct(values map[string]any) *structpb.Struct {
+	if len(values) == 0 {
+		return nil
+	}
+
+	result, err := structpb.NewStruct(values)
+	if err != nil {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,74 @@
+package grpc_server
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"connectrpc.com/vanguard/vanguardgrpc"
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+)
+
+var (
+	webAllowedHeaders = []string{
+		"Authorization", "Content-Type", "Connect-Protocol-Version", "Connect-Timeout-Ms",
+		"Grpc-Timeout", "X-Grpc-Web", "X-User-Agent",
+	}
+	webExposedHeaders = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+)
+
+// MountWeb serves every service registered on server over the Connect and
+// gRPC-Web protocols at /<package>.<Service>/<Method> on router, so browsers
+// and plain HTTP callers can use the typed API without a gRPC proxy. Requests
+// are transcoded to gRPC and run through the same interceptors, including
+// authentication from the Authorization header. allowedOrigins lists the
+// browser origins allowed to make cross-origin calls.
+func MountWeb(router chi.Router, server *grpc.Server, allowedOrigins []string) error {
+	transcoder, err := vanguardgrpc.NewTranscoder(server)
+	if err != nil {
+		return fmt.Errorf("failed to build Connect transcoder: %w", err)
+	}
+
+	handler := webCORS(allowedOrigins, withoutWriteDeadline(transcoder))
+	for name := range server.GetServiceInfo() {
+		router.Handle("/"+name+"/*", handler)
+	}
+	return nil
+}
+
+// withoutWriteDeadline lifts the HTTP server's write timeout, which would
+// otherwise cut off long server streams such as ListUsers.
+func withoutWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		next.ServeHTTP(w, r)
+	})
+}
+
+// webCORS answers preflight requests and sets the CORS headers browsers need
+// to read Connect and gRPC-Web responses from an allowed origin.
+func webCORS(allowedOrigins []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || !slices.Contains(allowedOrigins, origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", strings.Join(webExposedHeaders, ", "))
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", strings.Join(webAllowedHeaders, ", "))
+			w.Header().Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
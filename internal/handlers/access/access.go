@@ -0,0 +1,77 @@
+package access_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	access_service "github.com/iamBelugaa/iam/internal/services/access"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	accessSvc *access_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *access_service.Service) *Handler {
+	return &Handler{log: log, accessSvc: svc}
+}
+
+func (h *Handler) GetUserAccess(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get user access request received", "userId", userID)
+
+	access, err := h.accessSvc.GetUserAccess(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, user_service.ErrUserNotFound) {
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user access", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Failed to retrieve user access", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", access)
+}
+
+func (h *Handler) CompareUsers(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	otherUserID := chi.URLParam(r, "otherUserID")
+	if userID == "" || otherUserID == "" {
+		h.respondWithError(w, "Both user IDs are required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Compare users request received", "userId", userID, "otherUserId", otherUserID)
+
+	comparison, err := h.accessSvc.CompareUsers(r.Context(), userID, otherUserID)
+	if err != nil {
+		if errors.Is(err, user_service.ErrUserNotFound) {
+			h.respondWithError(w, "User not found", http.StatusNotFound)
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to compare users", zap.Error(err),
+			"userId", userID,
+			"otherUserId", otherUserID,
+		)
+		h.respondWithError(w, "Failed to compare users", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", comparison)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,249 @@
+package accessrequest_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	accessrequest_service "github.com/iamBelugaa/iam/internal/services/accessrequest"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log               *zap.SugaredLogger
+	accessRequestsSvc *accessrequest_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *accessrequest_service.Service) *Handler {
+	return &Handler{log: log, accessRequestsSvc: svc}
+}
+
+func (h *Handler) GetProtectedGroups(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get protected groups request received")
+
+	groups := h.accessRequestsSvc.GetProtectedGroups(r.Context())
+	response.RespondSuccess(w, http.StatusOK, "Success", groups)
+}
+
+func (h *Handler) ProtectGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Protect group request received", "groupId", groupID)
+
+	var req models.UpdateProtectedGroupRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode protect group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.ApproverIDs) == 0 {
+		h.respondWithError(w, "At least one approver is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.MaxDurationHours < 0 {
+		h.respondWithError(w, "maxDurationHours cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	protected, err := h.accessRequestsSvc.ProtectGroup(r.Context(), groupID, &req)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to protect group", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to protect group", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group protected successfully", protected)
+}
+
+func (h *Handler) UnprotectGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Unprotect group request received", "groupId", groupID)
+
+	if err := h.accessRequestsSvc.UnprotectGroup(r.Context(), groupID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to unprotect group")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group protection removed successfully", nil)
+}
+
+func (h *Handler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Create access request received", "requesterId", caller.UserID)
+
+	var req models.CreateAccessRequestRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create access request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(req.Justification) == "" {
+		h.respondWithError(w, "A justification is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.DurationHours < 0 {
+		h.respondWithError(w, "durationHours cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	accessRequest, err := h.accessRequestsSvc.CreateRequest(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create access request")
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Access request created successfully", "accessRequestId", accessRequest.ID)
+	response.RespondSuccess(w, http.StatusCreated, "Access request created successfully", accessRequest)
+}
+
+func (h *Handler) GetRequests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.AccessRequestFilter{
+		Status:      strings.ToUpper(query.Get("status")),
+		GroupID:     query.Get("groupId"),
+		RequesterID: query.Get("requesterId"),
+		ApproverID:  query.Get("approverId"),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get access requests request received", "status", filter.Status, "groupId", filter.GroupID)
+
+	requests := h.accessRequestsSvc.GetRequests(r.Context(), &filter)
+
+	logger.FromContext(r.Context(), h.log).Infow("Access requests retrieved successfully", "count", len(requests))
+	response.RespondSuccess(w, http.StatusOK, "Success", requests)
+}
+
+func (h *Handler) GetRequest(w http.ResponseWriter, r *http.Request) {
+	requestID := chi.URLParam(r, "requestID")
+	if requestID == "" {
+		h.respondWithError(w, "Access request ID is required", http.StatusBadRequest)
+		return
+	}
+
+	accessRequest, err := h.accessRequestsSvc.GetRequest(r.Context(), requestID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve access request")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", accessRequest)
+}
+
+func (h *Handler) ApproveRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideRequest(w, r, true)
+}
+
+func (h *Handler) DenyRequest(w http.ResponseWriter, r *http.Request) {
+	h.decideRequest(w, r, false)
+}
+
+func (h *Handler) decideRequest(w http.ResponseWriter, r *http.Request, approve bool) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	requestID := chi.URLParam(r, "requestID")
+	if requestID == "" {
+		h.respondWithError(w, "Access request ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Access request decision received", "accessRequestId", requestID, "approverId", caller.UserID, "approve", approve)
+
+	var decision models.AccessRequestDecision
+	if err := request.Decode(r, &decision); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode access request decision", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		accessRequest *models.AccessRequest
+		err           error
+	)
+	if approve {
+		accessRequest, err = h.accessRequestsSvc.Approve(r.Context(), requestID, caller.UserID, &decision)
+	} else {
+		accessRequest, err = h.accessRequestsSvc.Deny(r.Context(), requestID, caller.UserID, &decision)
+	}
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to decide on access request")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Access request "+strings.ToLower(accessRequest.Status), accessRequest)
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var violationErr *sod_service.ViolationError
+	if errors.As(err, &violationErr) {
+		response.RespondError(w, http.StatusConflict, "SOD_VIOLATION", violationErr.Error(), violationErr.Violations)
+		return
+	}
+
+	switch {
+	case errors.Is(err, accessrequest_service.ErrAccessRequestNotFound):
+		h.respondWithError(w, "Access request not found", http.StatusNotFound)
+	case errors.Is(err, accessrequest_service.ErrGroupNotProtected):
+		h.respondWithError(w, "Group is not protected by access requests", http.StatusNotFound)
+	case errors.Is(err, accessrequest_service.ErrNotApprover),
+		errors.Is(err, accessrequest_service.ErrSelfApproval),
+		errors.Is(err, accessrequest_service.ErrApprovalNotRequired),
+		errors.Is(err, guest_service.ErrGroupNotEligible):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, accessrequest_service.ErrRequestNotPending),
+		errors.Is(err, accessrequest_service.ErrDuplicateRequest):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, accessrequest_service.ErrDurationExceeded):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,139 @@
+package apikey_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	apikey_service "github.com/iamBelugaa/iam/internal/services/apikey"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	apiKeySvc *apikey_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *apikey_service.Service) *Handler {
+	return &Handler{log: log, apiKeySvc: svc}
+}
+
+func (h *Handler) GetKeys(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get API keys request received", "userId", caller.UserID)
+
+	keys, err := h.apiKeySvc.Keys(r.Context(), caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve API keys")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", keys)
+}
+
+func (h *Handler) IssueKey(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode issue API key request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Issue API key request received",
+		"userId", caller.UserID, "name", req.Name, "scopes", req.Scopes,
+	)
+
+	key, err := h.apiKeySvc.Issue(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to issue API key")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "API key issued successfully", key)
+}
+
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := chi.URLParam(r, "keyID")
+	logger.FromContext(r.Context(), h.log).Infow("Rotate API key request received", "userId", caller.UserID, "keyId", keyID)
+
+	key, err := h.apiKeySvc.Rotate(r.Context(), caller.UserID, keyID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to rotate API key")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "API key rotated successfully", key)
+}
+
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	keyID := chi.URLParam(r, "keyID")
+	logger.FromContext(r.Context(), h.log).Infow("Revoke API key request received", "userId", caller.UserID, "keyId", keyID)
+
+	key, err := h.apiKeySvc.Revoke(r.Context(), caller.UserID, keyID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to revoke API key")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "API key revoked successfully", key)
+}
+
+// caller requires a user's access token: API keys cannot manage API keys.
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, apikey_service.ErrKeyNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, apikey_service.ErrKeyRevoked),
+		errors.Is(err, apikey_service.ErrKeyExpired):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, apikey_service.ErrUserKeyManager):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, apikey_service.ErrNameRequired),
+		errors.Is(err, apikey_service.ErrNoScopes),
+		errors.Is(err, apikey_service.ErrInvalidScope),
+		errors.Is(err, apikey_service.ErrExpiryInPast),
+		errors.Is(err, apikey_service.ErrExpiryTooLate):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,145 @@
+package avatar_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	avatar_service "github.com/iamBelugaa/iam/internal/services/avatar"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	avatarsSvc *avatar_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *avatar_service.Service) *Handler {
+	return &Handler{log: log, avatarsSvc: svc}
+}
+
+// UploadAvatar accepts the image either as the raw request body or as the
+// "file" field of a multipart form.
+func (h *Handler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Upload avatar request received", "userId", userID)
+
+	// Allow some headroom for multipart framing on top of the image itself.
+	body := http.MaxBytesReader(w, r.Body, int64(h.avatarsSvc.MaxSizeBytes())+64<<10)
+
+	var source io.Reader = body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		r.Body = body
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			logger.FromContext(r.Context(), h.log).Infow("Failed to read avatar form file", zap.Error(err), "userId", userID)
+			h.respondWithError(w, "A 'file' form field with the image is required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		source = file
+	}
+
+	data, err := io.ReadAll(source)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to read avatar upload", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Avatar is too large or could not be read", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if len(data) == 0 {
+		h.respondWithError(w, "Avatar image is required", http.StatusBadRequest)
+		return
+	}
+
+	avatarURL, err := h.avatarsSvc.Upload(r.Context(), userID, data)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to upload avatar", userID)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Avatar uploaded successfully", "userId", userID)
+	response.RespondSuccess(w, http.StatusOK, "Avatar uploaded successfully", avatarURL)
+}
+
+func (h *Handler) GetAvatarURL(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	avatarURL, err := h.avatarsSvc.GetURL(r.Context(), userID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve avatar", userID)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", avatarURL)
+}
+
+func (h *Handler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Delete avatar request received", "userId", userID)
+
+	if err := h.avatarsSvc.Delete(r.Context(), userID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete avatar", userID)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Avatar deleted successfully", nil)
+}
+
+// ServeAvatar serves the image behind a signed avatar URL.
+func (h *Handler) ServeAvatar(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	query := r.URL.Query()
+
+	object, err := h.avatarsSvc.Open(r.Context(), userID, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to serve avatar", userID)
+		return
+	}
+
+	w.Header().Set("Content-Type", object.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(object.Data)))
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(object.Data)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message, userID string) {
+	switch {
+	case errors.Is(err, avatar_service.ErrAvatarNotFound):
+		h.respondWithError(w, "Avatar not found", http.StatusNotFound)
+	case errors.Is(err, avatar_service.ErrInvalidSignature):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, avatar_service.ErrUnsupportedContent):
+		h.respondWithError(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, avatar_service.ErrAvatarTooLarge):
+		h.respondWithError(w, err.Error(), http.StatusRequestEntityTooLarge)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err), "userId", userID)
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
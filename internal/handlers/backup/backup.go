@@ -0,0 +1,133 @@
+package backup_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	backup_service "github.com/iamBelugaa/iam/internal/services/backup"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	backupSvc *backup_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *backup_service.Service) *Handler {
+	return &Handler{log: log, backupSvc: svc}
+}
+
+func (h *Handler) CreateBackup(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateBackupRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create backup request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Create backup request received", "label", req.Label, "actor", actor)
+
+	backup, err := h.backupSvc.Create(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create backup")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Backup created", backup)
+}
+
+func (h *Handler) GetBackups(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get backups request received")
+
+	backups, err := h.backupSvc.Backups(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve backups")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", backups)
+}
+
+func (h *Handler) GetBackup(w http.ResponseWriter, r *http.Request) {
+	backupID := chi.URLParam(r, "backupID")
+	logger.FromContext(r.Context(), h.log).Infow("Get backup request received", "backupId", backupID)
+
+	backup, err := h.backupSvc.Backup(r.Context(), backupID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve backup")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", backup)
+}
+
+func (h *Handler) RestoreBackup(w http.ResponseWriter, r *http.Request) {
+	var req models.RestoreRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode restore request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	for i, kind := range req.Include {
+		req.Include[i] = strings.ToUpper(kind)
+	}
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Restore request received",
+		"backupId", req.BackupID, "dryRun", req.DryRun, "actor", actor,
+	)
+
+	restore, err := h.backupSvc.Restore(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to restore backup")
+		return
+	}
+
+	message := "Backup restored"
+	if req.DryRun {
+		message = "Restore preview"
+	}
+	response.RespondSuccess(w, http.StatusOK, message, restore)
+}
+
+// actorFromRequest is the user who made the request, or the client when the
+// access token does not identify a user.
+func actorFromRequest(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if caller.UserID != "" {
+		return caller.UserID
+	}
+	return caller.ClientID
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, backup_service.ErrBackupNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, backup_service.ErrBackupIDRequired),
+		errors.Is(err, backup_service.ErrInvalidInclude):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, backup_service.ErrNewerFormat):
+		h.respondWithError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
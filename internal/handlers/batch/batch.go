@@ -0,0 +1,105 @@
+package batch_handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	batch_service "github.com/iamBelugaa/iam/internal/services/batch"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	batchSvc *batch_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *batch_service.Service) *Handler {
+	return &Handler{log: log, batchSvc: svc}
+}
+
+func (h *Handler) BatchGet(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Batch get request received")
+
+	var req models.BatchGetRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode batch get request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Resources) == 0 {
+		h.respondWithError(w, "At least one resource is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Resources) > models.MaxBatchGetResources {
+		h.respondWithError(
+			w, fmt.Sprintf("At most %d resources can be fetched at once", models.MaxBatchGetResources),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	for _, ref := range req.Resources {
+		if ref.ID == "" {
+			h.respondWithError(w, "Every resource must have an ID", http.StatusBadRequest)
+			return
+		}
+		if ref.Type != models.ResourceTypeUser && ref.Type != models.ResourceTypeGroup {
+			h.respondWithError(w, fmt.Sprintf("Unsupported resource type '%s'", ref.Type), http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := h.batchSvc.BatchGet(r.Context(), req.Resources)
+
+	logger.FromContext(r.Context(), h.log).Infow("Batch get completed", "count", len(results))
+	response.RespondSuccess(w, http.StatusOK, "Success", results)
+}
+
+func (h *Handler) LookupUsers(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("User lookup request received")
+
+	var req models.UserLookupRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode user lookup request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Identifiers) == 0 {
+		h.respondWithError(w, "At least one identifier is required", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.Identifiers) > models.MaxUserLookups {
+		h.respondWithError(
+			w, fmt.Sprintf("At most %d users can be looked up at once", models.MaxUserLookups),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	for i, identifier := range req.Identifiers {
+		req.Identifiers[i] = strings.TrimSpace(identifier)
+		if req.Identifiers[i] == "" {
+			h.respondWithError(w, "Identifiers must not be empty", http.StatusBadRequest)
+			return
+		}
+	}
+
+	results := h.batchSvc.LookupUsers(r.Context(), req.Identifiers)
+
+	logger.FromContext(r.Context(), h.log).Infow("User lookup completed", "count", len(results))
+	response.RespondSuccess(w, http.StatusOK, "Success", results)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
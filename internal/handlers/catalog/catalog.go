@@ -0,0 +1,127 @@
+package catalog_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	accessrequest_service "github.com/iamBelugaa/iam/internal/services/accessrequest"
+	catalog_service "github.com/iamBelugaa/iam/internal/services/catalog"
+	consent_service "github.com/iamBelugaa/iam/internal/services/consent"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	catalogSvc *catalog_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *catalog_service.Service) *Handler {
+	return &Handler{log: log, catalogSvc: svc}
+}
+
+func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get catalog groups request received", "userId", caller.UserID)
+
+	groups, err := h.catalogSvc.GetGroups(r.Context(), caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve catalog")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", groups)
+}
+
+func (h *Handler) JoinGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Join group request received", "groupId", groupID, "userId", caller.UserID)
+
+	// The request body is optional; OPEN groups need no justification.
+	var req models.JoinGroupRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode join group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.DurationHours < 0 {
+		h.respondWithError(w, "durationHours cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.catalogSvc.JoinGroup(r.Context(), caller.UserID, groupID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to join group")
+		return
+	}
+
+	if result.Status == models.JoinStatusRequested {
+		response.RespondSuccess(w, http.StatusAccepted, "Access request created", result)
+		return
+	}
+	response.RespondSuccess(w, http.StatusOK, "Joined group successfully", result)
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var violationErr *sod_service.ViolationError
+	if errors.As(err, &violationErr) {
+		response.RespondError(w, http.StatusConflict, "SOD_VIOLATION", violationErr.Error(), violationErr.Violations)
+		return
+	}
+
+	switch {
+	case errors.Is(err, catalog_service.ErrJoinNotAllowed),
+		errors.Is(err, guest_service.ErrGroupNotEligible):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, catalog_service.ErrAlreadyMember),
+		errors.Is(err, accessrequest_service.ErrDuplicateRequest):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, accessrequest_service.ErrGroupNotProtected):
+		h.respondWithError(w, "Group has no approvers configured", http.StatusConflict)
+	case errors.Is(err, accessrequest_service.ErrDurationExceeded),
+		errors.Is(err, consent_service.ErrConsentRequired):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, consent_service.ErrConsentOutdated):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
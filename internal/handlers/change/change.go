@@ -0,0 +1,62 @@
+package change_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	changesSvc *change_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *change_service.Service) *Handler {
+	return &Handler{log: log, changesSvc: svc}
+}
+
+func (h *Handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	page := &models.ChangeFeedPage{Limit: defaultLimit, Cursor: r.URL.Query().Get("cursor")}
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed <= 0 || parsed > maxLimit {
+			h.respondWithError(w, "Limit must be between 1 and "+strconv.Itoa(maxLimit), http.StatusBadRequest)
+			return
+		}
+		page.Limit = parsed
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get changes request received", "cursor", page.Cursor, "limit", page.Limit)
+
+	feed, err := h.changesSvc.GetChanges(r.Context(), page)
+	if err != nil {
+		switch {
+		case errors.Is(err, change_service.ErrInvalidCursor):
+			h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, change_service.ErrCursorExpired):
+			h.respondWithError(w, err.Error(), http.StatusGone)
+		default:
+			logger.FromContext(r.Context(), h.log).Infow("Failed to get changes", zap.Error(err))
+			h.respondWithError(w, "Failed to get changes", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", feed)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+)
+
+// readOnlyPosts are the routes that take a POST without changing anything.
+var readOnlyPosts = map[string]bool{
+	APIVersion1URL + "/batch:get":                                true,
+	APIVersion1URL + "/graphql":                                  true,
+	APIVersion1URL + "/users/lookup":                             true,
+	APIVersion1URL + "/users/bulk-deactivate/dry-run":            true,
+	APIVersion1URL + "/webhooks/subscribers/{subscriberID}/test": true,
+	APIVersion1URL + "/groups/{groupID}/members:check":           true,
+	APIVersion1URL + "/group-policy/check":                       true,
+	APIVersion1URL + "/device/authorize":                         true,
+	APIVersion1URL + "/device/token":                             true,
+	APIVersion1URL + "/sessions/cookie-redirect":                 true,
+}
+
+// changeResourceTypes names the resource behind the first path segment of
+// the routes whose segment is not already a resource type.
+var changeResourceTypes = map[string]string{
+	"users":             models.ResourceTypeUser,
+	"groups":            models.ResourceTypeGroup,
+	"api-keys":          models.ResourceTypeAPIKey,
+	"catalog":           models.ResourceTypeGroup,
+	"guests":            models.ResourceTypeGuest,
+	"invitations":       models.ResourceTypeInvitation,
+	"invite":            models.ResourceTypeInvitation,
+	"me":                models.ResourceTypeUser,
+	"pending-changes":   models.ResourceTypeGroup,
+	"scheduled-changes": models.ResourceTypeGroup,
+	"service-accounts":  models.ResourceTypeServiceAccount,
+	"unused-access":     models.ResourceTypeApp,
+}
+
+// recordChanges adds every successful mutating request to the change feed,
+// under the route it matched and the path parameters it was called with.
+func recordChanges(changesSvc *change_service.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			// The route is only known once the router has matched it.
+			rctx := chi.RouteContext(r.Context())
+			route := strings.TrimSuffix(rctx.RoutePattern(), "/")
+			if ww.Status() < 200 || ww.Status() >= 300 || route == "" || readOnlyPosts[route] {
+				return
+			}
+
+			params := make(map[string]string)
+			for i, key := range rctx.URLParams.Keys {
+				if key != "*" {
+					params[key] = rctx.URLParams.Values[i]
+				}
+			}
+
+			change := &models.Change{
+				Source: models.ChangeSourceAPI,
+				Type:   models.ChangeTypeAPIRequest,
+				Details: map[string]any{
+					"method": r.Method,
+					"route":  route,
+					"status": ww.Status(),
+				},
+			}
+			if len(params) > 0 {
+				change.Details["params"] = params
+			}
+
+			// /orgs/{org}/users/... changes a user of another org.
+			segments := strings.Split(strings.TrimPrefix(route, APIVersion1URL+"/"), "/")
+			if len(segments) > 2 && segments[0] == "orgs" {
+				change.Details["org"] = params["org"]
+				segments = segments[2:]
+			}
+			change.ResourceType = segments[0]
+			if resourceType, ok := changeResourceTypes[segments[0]]; ok {
+				change.ResourceType = resourceType
+			}
+			if len(segments) > 1 && strings.HasPrefix(segments[1], "{") {
+				change.ResourceID = params[strings.Trim(segments[1], "{}")]
+			}
+
+			changesSvc.Record(r.Context(), change)
+		})
+	}
+}
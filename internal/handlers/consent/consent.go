@@ -0,0 +1,48 @@
+package consent_handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	consent_service "github.com/iamBelugaa/iam/internal/services/consent"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	consentSvc *consent_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *consent_service.Service) *Handler {
+	return &Handler{log: log, consentSvc: svc}
+}
+
+func (h *Handler) GetTexts(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.consentSvc.GetTexts(r.Context()))
+}
+
+func (h *Handler) GetUserConsents(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get user consents request received", "userId", userID)
+
+	records, err := h.consentSvc.GetRecords(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user consents", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Failed to retrieve consent history", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", records)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,39 @@
+package dashboard_handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	dashboard_service "github.com/iamBelugaa/iam/internal/services/dashboard"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log          *zap.SugaredLogger
+	dashboardSvc *dashboard_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *dashboard_service.Service) *Handler {
+	return &Handler{log: log, dashboardSvc: svc}
+}
+
+func (h *Handler) GetDashboard(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.dashboardSvc.GetDashboard(r.Context()))
+}
+
+func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.dashboardSvc.GetUsers(r.Context()))
+}
+
+func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.dashboardSvc.GetGroups(r.Context()))
+}
+
+func (h *Handler) GetMutations(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.dashboardSvc.GetMutations(r.Context()))
+}
+
+func (h *Handler) GetOkta(w http.ResponseWriter, r *http.Request) {
+	response.RespondSuccess(w, http.StatusOK, "Success", h.dashboardSvc.GetOkta(r.Context()))
+}
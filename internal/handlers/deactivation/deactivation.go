@@ -0,0 +1,147 @@
+package deactivation_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	deactivation_service "github.com/iamBelugaa/iam/internal/services/deactivation"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log             *zap.SugaredLogger
+	deactivationSvc *deactivation_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *deactivation_service.Service) *Handler {
+	return &Handler{log: log, deactivationSvc: svc}
+}
+
+// Preview is the mandatory dry run of a bulk deactivation. It changes
+// nothing.
+func (h *Handler) Preview(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.BulkDeactivationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode bulk deactivation dry run request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Bulk deactivation dry run request received",
+		"userCount", len(req.UserIDs), "filter", req.Filter, "callerId", caller.UserID,
+	)
+
+	preview, err := h.deactivationSvc.Preview(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to preview bulk deactivation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Dry run completed", preview)
+}
+
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.StartBulkDeactivationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode bulk deactivation request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Bulk deactivation request received",
+		"previewId", req.PreviewID, "callerId", caller.UserID,
+	)
+
+	job, err := h.deactivationSvc.Start(r.Context(), caller.UserID, req.PreviewID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to start bulk deactivation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusAccepted, "Bulk deactivation started", job)
+}
+
+func (h *Handler) GetResults(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobID")
+	format := r.URL.Query().Get("format")
+
+	if format != "" && format != "json" && format != "csv" {
+		h.respondWithError(w, "Format must be one of json or csv", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get bulk deactivation results request received", "jobId", jobID, "format", format)
+
+	results, err := h.deactivationSvc.Results(r.Context(), jobID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve bulk deactivation results")
+		return
+	}
+
+	if format == "csv" {
+		header, rows := resultsToCSV(results)
+		response.RespondCSV(w, "bulk-deactivation-"+jobID+".csv", header, rows)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", results)
+}
+
+func resultsToCSV(results []*models.DeactivationResult) ([]string, [][]string) {
+	header := []string{"userId", "login", "outcome", "error"}
+	rows := make([][]string, len(results))
+	for i, result := range results {
+		rows[i] = []string{result.UserID, result.Login, result.Outcome, result.Error}
+	}
+	return header, rows
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, deactivation_service.ErrPreviewNotFound),
+		errors.Is(err, deactivation_service.ErrDeactivationNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, deactivation_service.ErrPreviewExpired):
+		h.respondWithError(w, err.Error(), http.StatusGone)
+	case errors.Is(err, deactivation_service.ErrPreviewRequired),
+		errors.Is(err, deactivation_service.ErrSelectionRequired),
+		errors.Is(err, deactivation_service.ErrSelectionConflict),
+		errors.Is(err, deactivation_service.ErrTooManyUsers),
+		errors.Is(err, user_service.ErrInvalidSearch):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,130 @@
+package defaultgroup_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	defaultgroup_service "github.com/iamBelugaa/iam/internal/services/defaultgroup"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log             *zap.SugaredLogger
+	defaultGroupSvc *defaultgroup_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *defaultgroup_service.Service) *Handler {
+	return &Handler{log: log, defaultGroupSvc: svc}
+}
+
+func (h *Handler) GetRules(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get default group rules request received")
+
+	rules, err := h.defaultGroupSvc.Rules(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve default group rules")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", rules)
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Create default group rule request received")
+
+	var req models.CreateDefaultGroupRuleRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create default group rule request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rule, err := h.defaultGroupSvc.CreateRule(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create default group rule")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Default group rule created successfully", rule)
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	ruleID := chi.URLParam(r, "ruleID")
+	logger.FromContext(r.Context(), h.log).Infow("Delete default group rule request received", "ruleId", ruleID)
+
+	if err := h.defaultGroupSvc.DeleteRule(r.Context(), ruleID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete default group rule")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Default group rule deleted successfully", nil)
+}
+
+func (h *Handler) GetMissingDefaults(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get users missing default groups request received")
+
+	missing, err := h.defaultGroupSvc.MissingDefaults(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to find users missing default groups")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", missing)
+}
+
+// Remediate starts a job that adds users to the default groups they are
+// missing. An empty body remediates every user in the report.
+func (h *Handler) Remediate(w http.ResponseWriter, r *http.Request) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return
+	}
+
+	var req models.RemediateDefaultGroupsRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode remediate default groups request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Remediate default groups request received",
+		"userCount", len(req.UserIDs), "callerId", caller.UserID,
+	)
+
+	job, err := h.defaultGroupSvc.Remediate(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to start remediation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusAccepted, "Remediation started", job)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, defaultgroup_service.ErrRuleNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, defaultgroup_service.ErrConfiguredRule):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, defaultgroup_service.ErrInvalidAttribute),
+		errors.Is(err, defaultgroup_service.ErrValueRequired),
+		errors.Is(err, defaultgroup_service.ErrNoGroups):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,86 @@
+package device_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	device_service "github.com/iamBelugaa/iam/internal/services/device"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	deviceSvc *device_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *device_service.Service) *Handler {
+	return &Handler{log: log, deviceSvc: svc}
+}
+
+// StartAuthorization starts a device flow. An empty body requests the
+// configured scopes.
+func (h *Handler) StartAuthorization(w http.ResponseWriter, r *http.Request) {
+	var req models.StartDeviceAuthorizationRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode start device authorization request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Start device authorization request received", "clientName", req.ClientName)
+
+	authorization, err := h.deviceSvc.Start(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to start device authorization")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Device authorization started", authorization)
+}
+
+// PollAuthorization reports whether the user has approved the device, with
+// the tokens once they have.
+func (h *Handler) PollAuthorization(w http.ResponseWriter, r *http.Request) {
+	var req models.PollDeviceAuthorizationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode poll device authorization request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status, err := h.deviceSvc.Poll(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to poll device authorization")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.RespondSuccess(w, http.StatusOK, "Success", status)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, device_service.ErrNotConfigured):
+		h.respondWithError(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, device_service.ErrDeviceCodeRequired):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, device_service.ErrDeviceCodeNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, device_service.ErrAuthorizationFailure):
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
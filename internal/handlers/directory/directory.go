@@ -0,0 +1,163 @@
+package directory_handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// Handler serves the read-only directory. Nothing here reaches Okta, except
+// membership checks the index cannot answer: every response comes from the
+// local index and carries its freshness, in the body and in the Age and
+// X-Index-Refreshed-At headers. Requests are not logged individually since
+// these endpoints exist for high-volume consumers.
+type Handler struct {
+	log          *zap.SugaredLogger
+	directorySvc *directory_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *directory_service.Service) *Handler {
+	return &Handler{log: log, directorySvc: svc}
+}
+
+func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
+	result, err := h.directorySvc.GetUsers(r.URL.Query().Get("q"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.respond(w, result.Index, result)
+}
+
+func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
+	result, err := h.directorySvc.GetUser(chi.URLParam(r, "userID"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.respond(w, result.Index, result)
+}
+
+func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	result, err := h.directorySvc.GetGroups(r.URL.Query().Get("q"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.respond(w, result.Index, result)
+}
+
+func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	result, err := h.directorySvc.GetGroup(chi.URLParam(r, "groupID"))
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.respond(w, result.Index, result)
+}
+
+// CheckGroupMembers answers, for a batch of user IDs, whether each is a member
+// of the group, so callers such as service meshes can authorize many requests
+// at once. consistent=true skips the index and asks Okta.
+func (h *Handler) CheckGroupMembers(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CheckGroupMembersRequest
+	if err := request.Decode(r, &req); err != nil {
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(req.UserIDs) == 0 {
+		h.respondWithError(w, "At least one user ID is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.UserIDs) > models.MaxMembershipCheckUsers {
+		h.respondWithError(
+			w, fmt.Sprintf("At most %d users can be checked at once", models.MaxMembershipCheckUsers),
+			http.StatusBadRequest,
+		)
+		return
+	}
+
+	consistent := r.URL.Query().Get("consistent") == "true"
+	result, err := h.directorySvc.CheckMembers(r.Context(), groupID, req.UserIDs, consistent)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to check group members", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to check group members", http.StatusInternalServerError)
+		return
+	}
+
+	if result.Index != nil {
+		h.respond(w, result.Index, result)
+		return
+	}
+	response.RespondSuccess(w, http.StatusOK, "Success", result)
+}
+
+// GetGroupStats counts groups by type and size, so dashboards need not page
+// through every group and its members. consistent=true skips the index and
+// asks Okta.
+func (h *Handler) GetGroupStats(w http.ResponseWriter, r *http.Request) {
+	consistent := r.URL.Query().Get("consistent") == "true"
+	result, err := h.directorySvc.GroupStats(r.Context(), consistent)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to compute group stats", zap.Error(err))
+		h.respondWithError(w, "Failed to compute group stats", http.StatusInternalServerError)
+		return
+	}
+
+	if result.Index != nil {
+		h.respond(w, result.Index, result)
+		return
+	}
+	response.RespondSuccess(w, http.StatusOK, "Success", result)
+}
+
+func (h *Handler) GetIndex(w http.ResponseWriter, r *http.Request) {
+	index, err := h.directorySvc.Freshness()
+	if err != nil {
+		h.handleServiceError(w, r, err)
+		return
+	}
+	h.respond(w, index, index)
+}
+
+func (h *Handler) respond(w http.ResponseWriter, index *models.DirectoryIndex, data any) {
+	w.Header().Set("Age", strconv.FormatInt(index.AgeSeconds, 10))
+	w.Header().Set("X-Index-Refreshed-At", index.RefreshedAt.Format(time.RFC3339))
+	response.RespondSuccess(w, http.StatusOK, "Success", data)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, directory_service.ErrIndexNotReady):
+		w.Header().Set("Retry-After", "30")
+		h.respondWithError(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, directory_service.ErrUserNotFound),
+		errors.Is(err, directory_service.ErrGroupNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow("Failed to read the directory index", zap.Error(err))
+		h.respondWithError(w, "Failed to read the directory index", http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
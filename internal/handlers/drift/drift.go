@@ -0,0 +1,53 @@
+package drift_handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	drift_service "github.com/iamBelugaa/iam/internal/services/drift"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	driftSvc *drift_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *drift_service.Service) *Handler {
+	return &Handler{log: log, driftSvc: svc}
+}
+
+func (h *Handler) GetReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &models.DriftFilter{EventType: query.Get("eventType"), ActorID: query.Get("actorId")}
+
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.respondWithError(w, param.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*param.value = parsed
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get drift report request received",
+		"since", filter.Since, "until", filter.Until, "eventType", filter.EventType, "actorId", filter.ActorID,
+	)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.driftSvc.GetReport(r.Context(), filter))
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
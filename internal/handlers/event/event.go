@@ -0,0 +1,118 @@
+package event_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// streamEventTypes are the event types clients may subscribe to.
+var streamEventTypes = []string{
+	models.StreamEventMembershipAdded,
+	models.StreamEventMembershipRemoved,
+	models.StreamEventUserLifecycle,
+	models.StreamEventJobProgress,
+}
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	eventsSvc *event_service.Service
+	heartbeat time.Duration
+}
+
+func New(log *zap.SugaredLogger, svc *event_service.Service, heartbeat time.Duration) *Handler {
+	return &Handler{log: log, eventsSvc: svc, heartbeat: heartbeat}
+}
+
+// Stream sends live events as Server-Sent Events until the client goes away
+// or falls too far behind, when it should reconnect with Last-Event-ID.
+func (h *Handler) Stream(w http.ResponseWriter, r *http.Request) {
+	var types []string
+	if value := r.URL.Query().Get("types"); value != "" {
+		for _, eventType := range strings.Split(value, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !slices.Contains(streamEventTypes, eventType) {
+				h.respondWithError(w, fmt.Sprintf(
+					"Invalid event type %q, expected one of %s", eventType, strings.Join(streamEventTypes, ", "),
+				), http.StatusBadRequest)
+				return
+			}
+			types = append(types, eventType)
+		}
+	}
+
+	log := logger.FromContext(r.Context(), h.log)
+	subscription, replay, missed := h.eventsSvc.Subscribe(types, r.Header.Get("Last-Event-ID"))
+	defer h.eventsSvc.Unsubscribe(subscription)
+	log.Infow("Live event stream opened", "types", types, "replayed", len(replay), "missed", missed)
+
+	controller := http.NewResponseController(w)
+	// The stream stays open far longer than the server's write timeout.
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keeps reverse proxies such as nginx from buffering the stream.
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if missed {
+		// Sent without an ID, so the client resumes from the last one it saw.
+		writeEvent(w, &models.StreamEvent{Type: models.StreamEventMissed, OccurredAt: time.Now().UTC()})
+	}
+	for _, event := range replay {
+		writeEvent(w, event)
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			log.Infow("Live event stream closed by the client")
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-subscription.Events:
+			if !ok {
+				log.Infow("Live event stream closed; the client fell behind")
+				return
+			}
+			writeEvent(w, event)
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeEvent writes one event in the text/event-stream format, with the whole
+// event as its JSON data.
+func writeEvent(w http.ResponseWriter, event *models.StreamEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if event.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", event.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+)
+
+// userLifecycleActions maps the user routes, below /users or
+// /orgs/{org}/users, to the lifecycle action they take.
+var userLifecycleActions = map[string]string{
+	http.MethodDelete + " /users/{userID}":          models.UserLifecycleDeleted,
+	http.MethodPost + " /users/{userID}/activate":   models.UserLifecycleActivated,
+	http.MethodPost + " /users/{userID}/deactivate": models.UserLifecycleDeactivated,
+	http.MethodPost + " /users/{userID}/suspend":    models.UserLifecycleSuspended,
+	http.MethodPost + " /users/{userID}/unsuspend":  models.UserLifecycleUnsuspended,
+}
+
+// membershipEventTypes maps the membership routes, below /groups or
+// /orgs/{org}/groups, to the event they publish.
+var membershipEventTypes = map[string]string{
+	http.MethodPut + " /groups/{groupID}/members/{userID}":    models.StreamEventMembershipAdded,
+	http.MethodDelete + " /groups/{groupID}/members/{userID}": models.StreamEventMembershipRemoved,
+}
+
+// publishEvents publishes a live event for every membership change and user
+// lifecycle action made through the API. Membership changes held for
+// approval, answered with 202, are left out; they reach the stream from the
+// System Log once applied.
+func publishEvents(eventsSvc *event_service.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost && r.Method != http.MethodPut && r.Method != http.MethodDelete {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			if ww.Status() < 200 || ww.Status() >= 300 || ww.Status() == http.StatusAccepted {
+				return
+			}
+
+			rctx := chi.RouteContext(r.Context())
+			route := strings.TrimSuffix(strings.TrimPrefix(rctx.RoutePattern(), APIVersion1URL), "/")
+			org := ""
+			if strings.HasPrefix(route, "/orgs/{org}/") {
+				org = rctx.URLParam("org")
+				route = strings.TrimPrefix(route, "/orgs/{org}")
+			}
+
+			key := r.Method + " " + route
+			if action, ok := userLifecycleActions[key]; ok {
+				eventsSvc.Publish(r.Context(), models.StreamEventUserLifecycle, models.ChangeSourceAPI, &models.StreamUserLifecycle{
+					Org:    org,
+					UserID: rctx.URLParam("userID"),
+					Action: action,
+				})
+			}
+			if eventType, ok := membershipEventTypes[key]; ok {
+				eventsSvc.Publish(r.Context(), eventType, models.ChangeSourceAPI, &models.StreamMembershipChange{
+					Org:     org,
+					GroupID: rctx.URLParam("groupID"),
+					UserID:  rctx.URLParam("userID"),
+				})
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package export_handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	export_service "github.com/iamBelugaa/iam/internal/services/export"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+var memberColumns = []string{"groupId", "groupName", "userId", "login", "email", "firstName", "lastName", "status"}
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	exportSvc *export_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *export_service.Service) *Handler {
+	return &Handler{log: log, exportSvc: svc}
+}
+
+func (h *Handler) ExportGroupMembers(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	h.export(w, r, "group-"+groupID+"-members", func(attributes []string, emit func(*models.MemberExportRecord) error) error {
+		return h.exportSvc.ExportGroupMembers(r.Context(), groupID, attributes, emit)
+	})
+}
+
+func (h *Handler) ExportAllGroupMembers(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, "group-members", func(attributes []string, emit func(*models.MemberExportRecord) error) error {
+		return h.exportSvc.ExportAllGroupMembers(r.Context(), attributes, emit)
+	})
+}
+
+// export streams records as CSV or JSON lines, flushing after every record
+// so the client receives data while later pages are still being fetched.
+func (h *Handler) export(
+	w http.ResponseWriter, r *http.Request, name string,
+	run func(attributes []string, emit func(*models.MemberExportRecord) error) error,
+) {
+	format := negotiateFormat(r)
+	if format == "" {
+		h.respondWithError(w, "Format must be one of csv or jsonl", http.StatusBadRequest)
+		return
+	}
+
+	var attributes []string
+	if value := r.URL.Query().Get("attributes"); value != "" {
+		attributes = strings.Split(value, ",")
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Export group members request received", "name", name, "format", format, "attributes", attributes)
+
+	controller := http.NewResponseController(w)
+	started := false
+
+	var emit func(*models.MemberExportRecord) error
+	switch format {
+	case models.ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		emit = func(record *models.MemberExportRecord) error {
+			if !started {
+				started = true
+				startStream(w, "text/csv", name+".csv")
+				if err := writer.Write(append(memberColumns, attributes...)); err != nil {
+					return err
+				}
+			}
+
+			row := []string{
+				record.GroupID, record.GroupName, record.UserID, record.Login,
+				record.Email, record.FirstName, record.LastName, record.Status,
+			}
+			for _, attribute := range attributes {
+				if value := record.Attributes[attribute]; value != nil {
+					row = append(row, fmt.Sprint(value))
+				} else {
+					row = append(row, "")
+				}
+			}
+
+			if err := writer.Write(row); err != nil {
+				return err
+			}
+			writer.Flush()
+			_ = controller.Flush()
+			return writer.Error()
+		}
+	default:
+		encoder := json.NewEncoder(w)
+		emit = func(record *models.MemberExportRecord) error {
+			if !started {
+				started = true
+				startStream(w, "application/x-ndjson", name+".jsonl")
+			}
+
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+			_ = controller.Flush()
+			return nil
+		}
+	}
+
+	if err := run(attributes, emit); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to export group members", zap.Error(err), "name", name)
+		// Once streaming has begun the status is already sent; just stop.
+		if !started {
+			h.respondWithError(w, "Failed to export group members", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if !started {
+		// Nothing was emitted: still return a well-formed, empty export.
+		if format == models.ExportFormatCSV {
+			startStream(w, "text/csv", name+".csv")
+			writer := csv.NewWriter(w)
+			_ = writer.Write(append(memberColumns, attributes...))
+			writer.Flush()
+		} else {
+			startStream(w, "application/x-ndjson", name+".jsonl")
+		}
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Group members exported successfully", "name", name, "format", format)
+}
+
+func startStream(w http.ResponseWriter, contentType, filename string) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+// negotiateFormat prefers the format query parameter and falls back to the
+// Accept header, defaulting to CSV. It returns "" for unsupported formats.
+func negotiateFormat(r *http.Request) string {
+	switch format := r.URL.Query().Get("format"); format {
+	case models.ExportFormatCSV, models.ExportFormatJSONL:
+		return format
+	case "":
+	default:
+		return ""
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/x-ndjson") || strings.Contains(accept, "application/jsonl") {
+		return models.ExportFormatJSONL
+	}
+
+	return models.ExportFormatCSV
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
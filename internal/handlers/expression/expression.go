@@ -0,0 +1,76 @@
+package expression_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	expression_service "github.com/iamBelugaa/iam/internal/services/expression"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// maxRequestSize bounds the request body, which holds the expression and
+// the profile and groups it is evaluated against.
+const maxRequestSize = 1 << 20
+
+type Handler struct {
+	log           *zap.SugaredLogger
+	expressionSvc *expression_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *expression_service.Service) *Handler {
+	return &Handler{log: log, expressionSvc: svc}
+}
+
+// EvaluateExpression evaluates an Okta Expression Language expression
+// against a user. Expressions that do not parse or evaluate are answered with
+// 200 and the error, like any other result.
+func (h *Handler) EvaluateExpression(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxRequestSize)
+
+	var req models.EvaluateExpressionRequest
+	if err := request.Decode(r, &req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.respondWithError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode evaluate expression request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Evaluate expression request received",
+		"groupRuleId", req.GroupRuleID, "userId", req.UserID,
+	)
+
+	evaluation, err := h.expressionSvc.Evaluate(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to evaluate expression")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", evaluation)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, expression_service.ErrNoExpression), errors.Is(err, expression_service.ErrTwoExpressions),
+		errors.Is(err, expression_service.ErrTwoUsers):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, user_service.ErrUserNotFound), errors.Is(err, expression_service.ErrGroupRuleNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,95 @@
+package graphql_handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const (
+	// maxQueryDepth stops clients from walking user -> groups -> members
+	// -> groups ... without bound.
+	maxQueryDepth = 6
+
+	// maxParallelism bounds the number of resolvers run concurrently per query.
+	maxParallelism = 20
+)
+
+var errTooManyIDs = fmt.Errorf("at most %d ids can be fetched at once", models.MaxBatchGetResources)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	schema    *graphql.Schema
+	usersSvc  *user_service.Service
+	groupsSvc *group_service.Service
+	appsSvc   *app_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger,
+	usersSvc *user_service.Service,
+	groupsSvc *group_service.Service,
+	appsSvc *app_service.Service,
+) *Handler {
+	return &Handler{
+		log:       log,
+		usersSvc:  usersSvc,
+		groupsSvc: groupsSvc,
+		appsSvc:   appsSvc,
+		schema: graphql.MustParseSchema(
+			schema, &rootResolver{},
+			graphql.MaxDepth(maxQueryDepth),
+			graphql.MaxParallelism(maxParallelism),
+		),
+	}
+}
+
+// QueryRequest is a GraphQL request as sent by standard clients.
+type QueryRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// ServeGraphQL executes a GraphQL query. Loaders are created per request so
+// cached results never leak between callers.
+func (h *Handler) ServeGraphQL(w http.ResponseWriter, r *http.Request) {
+	var req QueryRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode GraphQL request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Query == "" {
+		h.respondWithError(w, "Query is required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := withLoaders(r.Context(), newLoaders(h.usersSvc, h.groupsSvc, h.appsSvc))
+	result := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	if len(result.Errors) > 0 {
+		logger.FromContext(r.Context(), h.log).Infow("GraphQL query completed with errors", "errorCount", len(result.Errors))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to write GraphQL response", zap.Error(err))
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
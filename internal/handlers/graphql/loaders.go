@@ -0,0 +1,98 @@
+package graphql_handlers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/dataloader"
+)
+
+const (
+	// batchWait is how long a loader collects keys before fetching them.
+	batchWait = 2 * time.Millisecond
+
+	// maxConcurrentFetches bounds the number of in-flight Okta calls per batch.
+	maxConcurrentFetches = 10
+)
+
+type loadersKey struct{}
+
+// loaders holds the per-request dataloaders. Every field of every object is
+// resolved through them, so a user or group referenced many times in one
+// query is only fetched from Okta once.
+type loaders struct {
+	users        *dataloader.Loader[string, *models.User]
+	userGroups   *dataloader.Loader[string, []*models.Group]
+	userApps     *dataloader.Loader[string, []*models.App]
+	groups       *dataloader.Loader[string, *models.Group]
+	groupMembers *dataloader.Loader[string, []*models.User]
+	groupApps    *dataloader.Loader[string, []*models.App]
+}
+
+func newLoaders(
+	usersSvc *user_service.Service, groupsSvc *group_service.Service, appsSvc *app_service.Service,
+) *loaders {
+	l := &loaders{}
+
+	l.users = dataloader.New(fanOut(usersSvc.GetUser), batchWait)
+	l.groups = dataloader.New(fanOut(groupsSvc.GetGroup), batchWait)
+	l.userApps = dataloader.New(fanOut(appsSvc.GetUserApps), batchWait)
+	l.groupApps = dataloader.New(fanOut(appsSvc.GetGroupApps), batchWait)
+
+	l.userGroups = dataloader.New(fanOut(func(ctx context.Context, userID string) ([]*models.Group, error) {
+		groups, err := usersSvc.GetUserGroups(ctx, userID)
+		for _, group := range groups {
+			l.groups.Prime(group.ID, group)
+		}
+		return groups, err
+	}), batchWait)
+
+	l.groupMembers = dataloader.New(fanOut(func(ctx context.Context, groupID string) ([]*models.User, error) {
+		members, err := groupsSvc.GetGroupMembers(ctx, groupID)
+		for _, member := range members {
+			l.users.Prime(member.ID, member)
+		}
+		return members, err
+	}), batchWait)
+
+	return l
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFromContext(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// fanOut turns a single-key service call into a batch function that fetches
+// every key of the batch concurrently.
+func fanOut[V any](fetch func(ctx context.Context, key string) (V, error)) dataloader.BatchFunc[string, V] {
+	return func(ctx context.Context, keys []string) ([]V, []error) {
+		var wg sync.WaitGroup
+		values := make([]V, len(keys))
+		errs := make([]error, len(keys))
+		semaphore := make(chan struct{}, maxConcurrentFetches)
+
+		for i, key := range keys {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				semaphore <- struct{}{}
+				defer func() { <-semaphore }()
+
+				values[i], errs[i] = fetch(ctx, key)
+			}()
+		}
+
+		wg.Wait()
+		return values, errs
+	}
+}
@@ -0,0 +1,147 @@
+package graphql_handlers
+
+import (
+	"context"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+type rootResolver struct{}
+
+func (r *rootResolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	user, err := loadersFromContext(ctx).users.Load(ctx, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{user}, nil
+}
+
+func (r *rootResolver) Users(ctx context.Context, args struct{ IDs []graphql.ID }) ([]*userResolver, error) {
+	if len(args.IDs) > models.MaxBatchGetResources {
+		return nil, errTooManyIDs
+	}
+
+	// Loads are issued concurrently so they land in the same batch. A user
+	// that cannot be fetched resolves to null rather than failing the list.
+	l := loadersFromContext(ctx)
+	var wg sync.WaitGroup
+	users := make([]*userResolver, len(args.IDs))
+
+	for i, id := range args.IDs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if user, err := l.users.Load(ctx, string(id)); err == nil {
+				users[i] = &userResolver{user}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return users, nil
+}
+
+func (r *rootResolver) Group(ctx context.Context, args struct{ ID graphql.ID }) (*groupResolver, error) {
+	group, err := loadersFromContext(ctx).groups.Load(ctx, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &groupResolver{group}, nil
+}
+
+type userResolver struct {
+	user *models.User
+}
+
+func (r *userResolver) ID() graphql.ID    { return graphql.ID(r.user.ID) }
+func (r *userResolver) Email() string     { return r.user.Email }
+func (r *userResolver) FirstName() string { return r.user.FirstName }
+func (r *userResolver) LastName() string  { return r.user.LastName }
+func (r *userResolver) Login() string     { return r.user.Login }
+func (r *userResolver) Status() string    { return r.user.Status }
+
+func (r *userResolver) Created() graphql.Time {
+	return graphql.Time{Time: r.user.Created}
+}
+
+func (r *userResolver) LastLogin() *graphql.Time {
+	if r.user.LastLogin == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *r.user.LastLogin}
+}
+
+func (r *userResolver) Groups(ctx context.Context) ([]*groupResolver, error) {
+	groups, err := loadersFromContext(ctx).userGroups.Load(ctx, r.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toGroupResolvers(groups), nil
+}
+
+func (r *userResolver) Apps(ctx context.Context) ([]*appResolver, error) {
+	apps, err := loadersFromContext(ctx).userApps.Load(ctx, r.user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toAppResolvers(apps), nil
+}
+
+type groupResolver struct {
+	group *models.Group
+}
+
+func (r *groupResolver) ID() graphql.ID      { return graphql.ID(r.group.ID) }
+func (r *groupResolver) Name() string        { return r.group.Name }
+func (r *groupResolver) Description() string { return r.group.Description }
+func (r *groupResolver) Type() string        { return r.group.Type }
+func (r *groupResolver) JoinPolicy() string  { return r.group.JoinPolicy }
+
+func (r *groupResolver) Members(ctx context.Context) ([]*userResolver, error) {
+	members, err := loadersFromContext(ctx).groupMembers.Load(ctx, r.group.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*userResolver, len(members))
+	for i, member := range members {
+		result[i] = &userResolver{member}
+	}
+	return result, nil
+}
+
+func (r *groupResolver) Apps(ctx context.Context) ([]*appResolver, error) {
+	apps, err := loadersFromContext(ctx).groupApps.Load(ctx, r.group.ID)
+	if err != nil {
+		return nil, err
+	}
+	return toAppResolvers(apps), nil
+}
+
+type appResolver struct {
+	app *models.App
+}
+
+func (r *appResolver) ID() graphql.ID     { return graphql.ID(r.app.ID) }
+func (r *appResolver) Label() string      { return r.app.Label }
+func (r *appResolver) Status() string     { return r.app.Status }
+func (r *appResolver) SignOnMode() string { return r.app.SignOnMode }
+
+func toGroupResolvers(groups []*models.Group) []*groupResolver {
+	result := make([]*groupResolver, len(groups))
+	for i, group := range groups {
+		result[i] = &groupResolver{group}
+	}
+	return result
+}
+
+func toAppResolvers(apps []*models.App) []*appResolver {
+	result := make([]*appResolver, len(apps))
+	for i, app := range apps {
+		result[i] = &appResolver{app}
+	}
+	return result
+}
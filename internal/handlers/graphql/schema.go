@@ -0,0 +1,47 @@
+package graphql_handlers
+
+// schema is the GraphQL schema served at /graphql. It is read-only and
+// resolves users, groups and apps through the existing services.
+const schema = `
+schema {
+	query: Query
+}
+
+type Query {
+	user(id: ID!): User
+	users(ids: [ID!]!): [User]!
+	group(id: ID!): Group
+}
+
+type User {
+	id: ID!
+	email: String!
+	firstName: String!
+	lastName: String!
+	login: String!
+	status: String!
+	created: Time!
+	lastLogin: Time
+	groups: [Group!]!
+	apps: [App!]!
+}
+
+type Group {
+	id: ID!
+	name: String!
+	description: String!
+	type: String!
+	joinPolicy: String!
+	members: [User!]!
+	apps: [App!]!
+}
+
+type App {
+	id: ID!
+	label: String!
+	status: String!
+	signOnMode: String!
+}
+
+scalar Time
+`
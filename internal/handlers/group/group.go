@@ -1,61 +1,142 @@
 package group_handlers
 
 import (
-	"encoding/json"
+	"cmp"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
 	"github.com/iamBelugaa/iam/internal/models"
 	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	history_service "github.com/iamBelugaa/iam/internal/services/history"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/patch"
+	"github.com/iamBelugaa/iam/pkg/request"
 	"github.com/iamBelugaa/iam/pkg/response"
 )
 
 type Handler struct {
 	log       *zap.SugaredLogger
 	groupsSvc *group_service.Service
+	// historySvc answers asOf queries. It is nil for orgs whose membership
+	// history is not kept.
+	historySvc *history_service.Service
+	// metadataSvc holds owners, cost centers and tags. It is nil for orgs
+	// whose group metadata is not kept.
+	metadataSvc *groupmetadata_service.Service
 }
 
-func New(log *zap.SugaredLogger, svc *group_service.Service) *Handler {
-	return &Handler{log: log, groupsSvc: svc}
+func New(
+	log *zap.SugaredLogger, svc *group_service.Service, historySvc *history_service.Service,
+	metadataSvc *groupmetadata_service.Service,
+) *Handler {
+	return &Handler{log: log, groupsSvc: svc, historySvc: historySvc, metadataSvc: metadataSvc}
 }
 
 func (h *Handler) CreateGroup(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Create group request received")
+	logger.FromContext(r.Context(), h.log).Infow("Create group request received")
 
 	var req models.CreateGroupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode create group request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	group, err := h.groupsSvc.CreateGroup(r.Context(), &req)
+	if errors.Is(err, group_service.ErrInvalidJoinPolicy) {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		h.log.Infow("Failed to create group", zap.Error(err), "name", req.Name)
+		if h.respondIfRejected(w, err) || h.respondIfPolicyViolation(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create group", zap.Error(err), "name", req.Name)
 		h.respondWithError(w, "Failed to create group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Group created successfully", "groupId", group.ID, "name", group.Name)
+	logger.FromContext(r.Context(), h.log).Infow("Group created successfully", "groupId", group.ID, "name", group.Name)
 	response.RespondSuccess(
 		w, http.StatusCreated, fmt.Sprintf("Group '%s' created successfully", group.Name), group,
 	)
 }
 
 func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Get groups request received")
+	logger.FromContext(r.Context(), h.log).Infow("Get groups request received")
+
+	filters, err := groupmetadata_service.ParseTagFilters(r.URL.Query()["tag"])
+	if err != nil {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(filters) > 0 && h.metadataSvc == nil {
+		h.respondWithError(w, "tag filters are only supported for the primary org", http.StatusBadRequest)
+		return
+	}
+
+	compare, err := request.Sort(r, groupSorts)
+	if err == nil && compare != nil && response.WantsStream(r) {
+		err = errSortedStream
+	}
+	if err != nil {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if response.WantsStream(r) {
+		stream := response.NewStream(w)
+		err := h.groupsSvc.StreamGroups(r.Context(), func(group *models.Group) error {
+			if err := h.attachMetadata(r, group); err != nil {
+				return err
+			}
+			if !groupmetadata_service.Matches(group, filters) {
+				return nil
+			}
+			return stream.Write(group)
+		})
+		h.finishStream(w, r, stream, err, "Failed to retrieve groups")
+		return
+	}
 
 	groups, err := h.groupsSvc.GetGroups(r.Context())
+	if err == nil {
+		err = h.attachMetadata(r, groups...)
+	}
 	if err != nil {
-		h.log.Infow("Failed to get groups", zap.Error(err))
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get groups", zap.Error(err))
 		h.respondWithError(w, "Failed to retrieve groups", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Groups retrieved successfully", zap.Int("count", len(groups)))
+	if len(filters) > 0 {
+		matching := make([]*models.Group, 0, len(groups))
+		for _, group := range groups {
+			if groupmetadata_service.Matches(group, filters) {
+				matching = append(matching, group)
+			}
+		}
+		groups = matching
+	}
+	if compare != nil {
+		slices.SortStableFunc(groups, byID(compare, func(group *models.Group) string { return group.ID }))
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Groups retrieved successfully", zap.Int("count", len(groups)))
 	response.RespondSuccess(w, http.StatusOK, "Success", groups)
 }
 
@@ -66,16 +147,20 @@ func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get group request received", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Get group request received", "groupId", groupID)
 
 	group, err := h.groupsSvc.GetGroup(r.Context(), groupID)
+	if err == nil {
+		err = h.attachMetadata(r, group)
+	}
 	if err != nil {
-		h.log.Infow("Failed to get group", zap.Error(err), "groupId", groupID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group", zap.Error(err), "groupId", groupID)
 		h.respondWithError(w, "Failed to retrieve group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Group retrieved successfully", zap.String("groupId", groupID))
+	logger.FromContext(r.Context(), h.log).Infow("Group retrieved successfully", zap.String("groupId", groupID))
+	w.Header().Set("ETag", groupETag(group))
 	response.RespondSuccess(w, http.StatusOK, "Success", group)
 }
 
@@ -86,26 +171,123 @@ func (h *Handler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Update group request received", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Update group request received", "groupId", groupID)
 
 	var req models.UpdateGroupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode update group request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode update group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkPrecondition(w, r, groupID) {
 		return
 	}
 
 	group, err := h.groupsSvc.UpdateGroup(r.Context(), groupID, &req)
+	if errors.Is(err, group_service.ErrInvalidJoinPolicy) {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		h.log.Infow("Failed to update group", zap.Error(err), "groupId", groupID)
+		if h.respondIfRejected(w, err) || h.respondIfPolicyViolation(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to update group", zap.Error(err), "groupId", groupID)
 		h.respondWithError(w, "Failed to update group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Group updated successfully", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Group updated successfully", "groupId", groupID)
+	w.Header().Set("ETag", groupETag(group))
 	response.RespondSuccess(w, http.StatusOK, "Group updated successfully", group)
 }
 
+// PatchGroup applies a JSON Merge Patch or JSON Patch to the fields PUT
+// accepts, so a client can change one of them without sending the others.
+func (h *Handler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Patch group request received", "groupId", groupID)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, patch.MaxBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.respondWithError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		h.respondWithError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	group, err := h.groupsSvc.GetGroup(r.Context(), groupID)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group", http.StatusInternalServerError)
+		return
+	}
+	if !h.preconditionMet(w, r, group) {
+		return
+	}
+
+	profile := group.Profile
+	if profile == nil {
+		profile = make(map[string]any)
+	}
+	current := map[string]any{
+		"name":        group.Name,
+		"description": group.Description,
+		"joinPolicy":  group.JoinPolicy,
+		"profile":     profile,
+	}
+
+	var patched models.UpdateGroupRequest
+	if err := patch.Apply(r.Header.Get("Content-Type"), current, body, &patched); err != nil {
+		h.respondPatchError(w, r, err)
+		return
+	}
+	if patched.Name == "" || patched.JoinPolicy == "" {
+		h.respondWithError(w, "The group's name and joinPolicy cannot be removed", http.StatusUnprocessableEntity)
+		return
+	}
+
+	req := &models.UpdateGroupRequest{}
+	if patched.JoinPolicy != group.JoinPolicy {
+		req.JoinPolicy = patched.JoinPolicy
+	}
+	// Okta replaces the whole group profile, so the fields the patch left
+	// alone are sent along with those it changed.
+	if patched.Name != group.Name || patched.Description != group.Description ||
+		!sameProfile(patched.Profile, group.Profile) {
+		req.Name = patched.Name
+		req.Description = patched.Description
+		req.Profile = patched.Profile
+	}
+
+	updated, err := h.groupsSvc.UpdateGroup(r.Context(), groupID, req)
+	if errors.Is(err, group_service.ErrInvalidJoinPolicy) {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		if h.respondIfRejected(w, err) || h.respondIfPolicyViolation(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to patch group", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to update group", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Group patched successfully", "groupId", groupID)
+	w.Header().Set("ETag", groupETag(updated))
+	response.RespondSuccess(w, http.StatusOK, "Group updated successfully", updated)
+}
+
 func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 	groupID := chi.URLParam(r, "groupID")
 	if groupID == "" {
@@ -113,18 +295,219 @@ func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Delete group request received", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Delete group request received", "groupId", groupID)
+
+	if !h.checkPrecondition(w, r, groupID) {
+		return
+	}
 
 	if err := h.groupsSvc.DeleteGroup(r.Context(), groupID); err != nil {
-		h.log.Infow("Failed to delete group", zap.Error(err), "groupId", groupID)
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to delete group", zap.Error(err), "groupId", groupID)
 		h.respondWithError(w, "Failed to delete group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Group deleted successfully", "groupId", groupID)
+	if h.metadataSvc != nil {
+		err := h.metadataSvc.DeleteMetadata(r.Context(), groupID)
+		if err != nil && !errors.Is(err, groupmetadata_service.ErrMetadataNotFound) {
+			logger.FromContext(r.Context(), h.log).Infow("Failed to delete metadata of deleted group", zap.Error(err), "groupId", groupID)
+		}
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Group deleted successfully", "groupId", groupID)
 	response.RespondSuccess(w, http.StatusOK, "Group deleted successfully", nil)
 }
 
+// attachMetadata sets the stored metadata of the groups, when this org keeps
+// any.
+func (h *Handler) attachMetadata(r *http.Request, groups ...*models.Group) error {
+	if h.metadataSvc == nil {
+		return nil
+	}
+	return h.metadataSvc.Attach(r.Context(), groups...)
+}
+
+func (h *Handler) GetGroupMetadata(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get group metadata request received", "groupId", groupID)
+
+	metadata, err := h.metadataSvc.GetMetadata(r.Context(), groupID)
+	if errors.Is(err, groupmetadata_service.ErrMetadataNotFound) {
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group metadata", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group metadata", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", metadata)
+}
+
+// SetGroupMetadata replaces the metadata of an existing group.
+func (h *Handler) SetGroupMetadata(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Set group metadata request received", "groupId", groupID)
+
+	var req models.SetGroupMetadataRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode set group metadata request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.groupsSvc.GetGroup(r.Context(), groupID); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group for metadata", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group", http.StatusInternalServerError)
+		return
+	}
+
+	metadata, err := h.metadataSvc.SetMetadata(r.Context(), groupID, &req)
+	if errors.Is(err, groupmetadata_service.ErrInvalidClassification) {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to set group metadata", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to update group metadata", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group metadata updated successfully", metadata)
+}
+
+func (h *Handler) DeleteGroupMetadata(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Delete group metadata request received", "groupId", groupID)
+
+	err := h.metadataSvc.DeleteMetadata(r.Context(), groupID)
+	if errors.Is(err, groupmetadata_service.ErrMetadataNotFound) {
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to delete group metadata", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to delete group metadata", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group metadata deleted successfully", nil)
+}
+
+func (h *Handler) GetGroupOwners(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get group owners request received", "groupId", groupID)
+
+	owners := []string{}
+	metadata, err := h.metadataSvc.GetMetadata(r.Context(), groupID)
+	if err != nil && !errors.Is(err, groupmetadata_service.ErrMetadataNotFound) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group owners", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group owners", http.StatusInternalServerError)
+		return
+	}
+	if metadata != nil && metadata.Owners != nil {
+		owners = metadata.Owners
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", owners)
+}
+
+// AddGroupOwner makes a user an owner of an existing group, which lets them
+// manage its members.
+func (h *Handler) AddGroupOwner(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	userID := chi.URLParam(r, "userID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Add group owner request received", "groupId", groupID, "userId", userID)
+
+	if _, err := h.groupsSvc.GetGroup(r.Context(), groupID); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group for owner", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group", http.StatusInternalServerError)
+		return
+	}
+
+	metadata, err := h.metadataSvc.AddOwner(r.Context(), groupID, userID)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to add group owner", zap.Error(err), "groupId", groupID, "userId", userID)
+		h.respondWithError(w, "Failed to add group owner", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group owner added successfully", metadata.Owners)
+}
+
+func (h *Handler) RemoveGroupOwner(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	userID := chi.URLParam(r, "userID")
+	if !h.requireMetadata(w, groupID) {
+		return
+	}
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Remove group owner request received", "groupId", groupID, "userId", userID)
+
+	metadata, err := h.metadataSvc.RemoveOwner(r.Context(), groupID, userID)
+	if errors.Is(err, groupmetadata_service.ErrOwnerNotFound) {
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to remove group owner", zap.Error(err), "groupId", groupID, "userId", userID)
+		h.respondWithError(w, "Failed to remove group owner", http.StatusInternalServerError)
+		return
+	}
+
+	owners := metadata.Owners
+	if owners == nil {
+		owners = []string{}
+	}
+	response.RespondSuccess(w, http.StatusOK, "Group owner removed successfully", owners)
+}
+
+// requireMetadata answers 400 when the group ID is missing or this org keeps
+// no group metadata.
+func (h *Handler) requireMetadata(w http.ResponseWriter, groupID string) bool {
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return false
+	}
+	if h.metadataSvc == nil {
+		h.respondWithError(w, "Group metadata is only supported for the primary org", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
 func (h *Handler) GetGroupMembers(w http.ResponseWriter, r *http.Request) {
 	groupID := chi.URLParam(r, "groupID")
 	if groupID == "" {
@@ -132,19 +515,301 @@ func (h *Handler) GetGroupMembers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get group members request received", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Get group members request received", "groupId", groupID)
+
+	compare, err := request.Sort(r, memberSorts)
+	if err == nil && compare != nil {
+		if response.WantsStream(r) {
+			err = errSortedStream
+		} else if r.URL.Query().Get("asOf") != "" {
+			err = errSortedAsOf
+		}
+	}
+	var expand []string
+	if err == nil {
+		expand, err = group_service.ParseMemberExpand(r.URL.Query()["expand"])
+	}
+	if err == nil && len(expand) > 0 {
+		if response.WantsStream(r) {
+			err = errExpandedStream
+		} else if r.URL.Query().Get("asOf") != "" {
+			err = errExpandedAsOf
+		}
+	}
+	if err != nil {
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+		h.getGroupMembersAsOf(w, r, groupID, "asOf", asOf)
+		return
+	}
+
+	if response.WantsStream(r) {
+		h.streamGroupMembers(w, r, groupID)
+		return
+	}
 
 	members, err := h.groupsSvc.GetGroupMembers(r.Context(), groupID)
 	if err != nil {
-		h.log.Infow("Failed to get group members", zap.Error(err), "groupId", groupID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group members", zap.Error(err), "groupId", groupID)
 		h.respondWithError(w, "Failed to retrieve group members", http.StatusInternalServerError)
 		return
 	}
+	if compare != nil {
+		slices.SortStableFunc(members, byID(compare, func(user *models.User) string { return user.ID }))
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Group members retrieved successfully", "groupId", groupID, "memberCount", len(members))
+
+	includeExpiry := r.URL.Query().Get("includeExpiry") == "true"
+	if !includeExpiry && len(expand) == 0 {
+		response.RespondSuccess(w, http.StatusOK, "Success", members)
+		return
+	}
+
+	now := time.Now()
+	var expirations map[string]time.Time
+	if includeExpiry {
+		expirations = h.groupsSvc.GetMembershipExpirations(groupID)
+	}
+
+	result := make([]*models.GroupMember, len(members))
+	for i, member := range members {
+		result[i] = &models.GroupMember{User: member}
+
+		if expiresAt, ok := expirations[member.ID]; ok {
+			remaining := int64(max(expiresAt.Sub(now), 0).Seconds())
+			result[i].ExpiresAt = &expiresAt
+			result[i].RemainingSeconds = &remaining
+		}
+	}
+
+	if len(expand) > 0 {
+		h.groupsSvc.ExpandGroupMembers(r.Context(), result, expand)
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", result)
+}
+
+// getGroupMembersAsOf answers GetGroupMembers for a past time. Expiry details
+// and streaming do not apply to past memberships.
+// param names the query parameter rawAsOf came from.
+func (h *Handler) getGroupMembersAsOf(w http.ResponseWriter, r *http.Request, groupID, param, rawAsOf string) {
+	if !h.requireHistory(w) {
+		return
+	}
+
+	asOf, err := parseHistoryTime(rawAsOf)
+	if err != nil {
+		h.respondWithError(w, param+" "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	members, err := h.historySvc.GetGroupMembersAsOf(r.Context(), groupID, asOf)
+	if err != nil {
+		h.handleHistoryError(w, r, err, groupID)
+		return
+	}
 
-	h.log.Infow("Group members retrieved successfully", "groupId", groupID, "memberCount", len(members))
 	response.RespondSuccess(w, http.StatusOK, "Success", members)
 }
 
+// GetGroupMembersHistory returns the members of a group at the past time in
+// the at parameter.
+func (h *Handler) GetGroupMembersHistory(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	at := r.URL.Query().Get("at")
+	logger.FromContext(r.Context(), h.log).Infow("Get group members history request received", "groupId", groupID, "at", at)
+
+	if at == "" {
+		h.respondWithError(w, "at is required", http.StatusBadRequest)
+		return
+	}
+	h.getGroupMembersAsOf(w, r, groupID, "at", at)
+}
+
+// GetGroupMembersDiff returns who joined and left a group between from and
+// to, which defaults to now.
+func (h *Handler) GetGroupMembersDiff(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+	if groupID == "" {
+		h.respondWithError(w, "Group ID is required", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	logger.FromContext(r.Context(), h.log).Infow("Get group members diff request received",
+		"groupId", groupID, "from", query.Get("from"), "to", query.Get("to"),
+	)
+
+	if !h.requireHistory(w) {
+		return
+	}
+
+	if query.Get("from") == "" {
+		h.respondWithError(w, "from is required", http.StatusBadRequest)
+		return
+	}
+	from, err := parseHistoryTime(query.Get("from"))
+	if err != nil {
+		h.respondWithError(w, "from "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	to := time.Now().UTC()
+	if rawTo := query.Get("to"); rawTo != "" {
+		if to, err = parseHistoryTime(rawTo); err != nil {
+			h.respondWithError(w, "to "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	diff, err := h.historySvc.DiffGroupMembers(r.Context(), groupID, from, to)
+	if err != nil {
+		h.handleHistoryError(w, r, err, groupID)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", diff)
+}
+
+var (
+	errSortedStream = errors.New("sortBy cannot be combined with stream")
+	errSortedAsOf   = errors.New("sortBy cannot be combined with asOf")
+
+	errExpandedStream = errors.New("expand cannot be combined with stream")
+	errExpandedAsOf   = errors.New("expand cannot be combined with asOf")
+)
+
+// groupSorts and memberSorts are the fields group and member listings can be
+// sorted by.
+var (
+	groupSorts = map[string]func(a, b *models.Group) int{
+		"name": func(a, b *models.Group) int {
+			return cmp.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		},
+		"type":        func(a, b *models.Group) int { return cmp.Compare(a.Type, b.Type) },
+		"created":     func(a, b *models.Group) int { return a.Created.Compare(b.Created) },
+		"lastUpdated": func(a, b *models.Group) int { return a.LastUpdated.Compare(b.LastUpdated) },
+	}
+	memberSorts = map[string]func(a, b *models.User) int{
+		"login": func(a, b *models.User) int {
+			return cmp.Compare(strings.ToLower(a.Login), strings.ToLower(b.Login))
+		},
+		"email": func(a, b *models.User) int {
+			return cmp.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+		},
+		"firstName": func(a, b *models.User) int {
+			return cmp.Compare(strings.ToLower(a.FirstName), strings.ToLower(b.FirstName))
+		},
+		"lastName": func(a, b *models.User) int {
+			return cmp.Compare(strings.ToLower(a.LastName), strings.ToLower(b.LastName))
+		},
+		"status":  func(a, b *models.User) int { return cmp.Compare(a.Status, b.Status) },
+		"created": func(a, b *models.User) int { return a.Created.Compare(b.Created) },
+		// Users who never logged in sort first.
+		"lastLogin": func(a, b *models.User) int {
+			var aLogin, bLogin time.Time
+			if a.LastLogin != nil {
+				aLogin = *a.LastLogin
+			}
+			if b.LastLogin != nil {
+				bLogin = *b.LastLogin
+			}
+			return aLogin.Compare(bLogin)
+		},
+	}
+)
+
+// byID breaks the ties of compare by ID, so equal values keep a stable order
+// from one request to the next.
+func byID[T any](compare func(a, b T) int, id func(T) string) func(a, b T) int {
+	return func(a, b T) int {
+		return cmp.Or(compare(a, b), cmp.Compare(id(a), id(b)))
+	}
+}
+
+// requireHistory answers 400 when this org keeps no membership history.
+func (h *Handler) requireHistory(w http.ResponseWriter) bool {
+	if h.historySvc == nil {
+		h.respondWithError(w, "Membership history is only kept for the primary org", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) handleHistoryError(w http.ResponseWriter, r *http.Request, err error, groupID string) {
+	switch {
+	case errors.Is(err, history_service.ErrAsOfInFuture),
+		errors.Is(err, history_service.ErrHistoryUnavailable),
+		errors.Is(err, history_service.ErrInvalidRange):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get historical group members", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group members", http.StatusInternalServerError)
+	}
+}
+
+// parseHistoryTime reads an RFC 3339 time, or a date, which stands for its
+// start in UTC.
+func parseHistoryTime(raw string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339, raw); err == nil {
+		return at, nil
+	}
+	if at, err := time.Parse(time.DateOnly, raw); err == nil {
+		return at, nil
+	}
+	return time.Time{}, errors.New("must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// streamGroupMembers writes members as NDJSON while pages are fetched,
+// attaching expiry details when includeExpiry is set.
+func (h *Handler) streamGroupMembers(w http.ResponseWriter, r *http.Request, groupID string) {
+	var expirations map[string]time.Time
+	if r.URL.Query().Get("includeExpiry") == "true" {
+		expirations = h.groupsSvc.GetMembershipExpirations(groupID)
+	}
+
+	stream := response.NewStream(w)
+	err := h.groupsSvc.StreamGroupMembers(r.Context(), groupID, func(member *models.User) error {
+		if expirations == nil {
+			return stream.Write(member)
+		}
+
+		result := &models.GroupMember{User: member}
+		if expiresAt, ok := expirations[member.ID]; ok {
+			remaining := int64(max(time.Until(expiresAt), 0).Seconds())
+			result.ExpiresAt = &expiresAt
+			result.RemainingSeconds = &remaining
+		}
+		return stream.Write(result)
+	})
+
+	h.finishStream(w, r, stream, err, "Failed to retrieve group members")
+}
+
+// finishStream reports err as a normal error response if nothing was
+// streamed yet. Once records have been sent the status cannot change, so
+// the error is only logged and the truncated stream ends.
+func (h *Handler) finishStream(w http.ResponseWriter, r *http.Request, stream *response.Stream, err error, message string) {
+	if err == nil {
+		stream.Close()
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err), "streamStarted", stream.Started())
+	if !stream.Started() {
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
 func (h *Handler) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 	groupID := chi.URLParam(r, "groupID")
 	userID := chi.URLParam(r, "userID")
@@ -154,15 +819,46 @@ func (h *Handler) AddUserToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Add user to group request received", "groupId", groupID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Add user to group request received", "groupId", groupID, "userId", userID)
 
-	if err := h.groupsSvc.AddUserToGroup(r.Context(), groupID, userID); err != nil {
-		h.log.Infow("Failed to add user to group", zap.Error(err), "groupId", groupID, "userId", userID)
+	// The request body is optional; an empty body adds a permanent membership.
+	var req models.AddGroupMemberRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode add user to group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.respondWithError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.groupsSvc.AddUserToGroup(r.Context(), groupID, userID, req.ExpiresAt); err != nil {
+		var violationErr *sod_service.ViolationError
+		if errors.As(err, &violationErr) {
+			logger.FromContext(r.Context(), h.log).Infow("User addition rejected by SoD policy", "groupId", groupID, "userId", userID)
+			response.RespondError(
+				w, http.StatusConflict, "SOD_VIOLATION", violationErr.Error(), violationErr.Violations,
+			)
+			return
+		}
+
+		if errors.Is(err, guest_service.ErrGroupNotEligible) {
+			h.respondWithError(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		if h.respondIfRejected(w, err) {
+			return
+		}
+
+		logger.FromContext(r.Context(), h.log).Infow("Failed to add user to group", zap.Error(err), "groupId", groupID, "userId", userID)
 		h.respondWithError(w, "Failed to add user to group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User added to group successfully", "groupId", groupID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User added to group successfully", "groupId", groupID, "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User added to group successfully", nil)
 }
 
@@ -175,18 +871,115 @@ func (h *Handler) RemoveUserFromGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Remove user from group request received", "groupId", groupID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Remove user from group request received", "groupId", groupID, "userId", userID)
 
 	if err := h.groupsSvc.RemoveUserFromGroup(r.Context(), groupID, userID); err != nil {
-		h.log.Infow("Failed to remove user from group", zap.Error(err), "groupId", groupID, "userId", userID)
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to remove user from group", zap.Error(err), "groupId", groupID, "userId", userID)
 		h.respondWithError(w, "Failed to remove user from group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User removed from group successfully", "groupId", groupID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User removed from group successfully", "groupId", groupID, "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User removed from group successfully", nil)
 }
 
+// checkPrecondition answers 412 and returns false when the request has an
+// If-Match header that does not name the group's current entity tag, so a
+// client does not overwrite or delete a change made since it read the group.
+// A group that no longer exists has no tag to match, so it fails too.
+func (h *Handler) checkPrecondition(w http.ResponseWriter, r *http.Request, groupID string) bool {
+	if r.Header.Get("If-Match") == "" {
+		return true
+	}
+
+	group, err := h.groupsSvc.GetGroup(r.Context(), groupID)
+	if errors.Is(err, group_service.ErrGroupNotFound) {
+		logger.FromContext(r.Context(), h.log).Infow("Group for If-Match not found", "groupId", groupID)
+		response.RespondError(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+			"The group no longer exists", nil,
+		)
+		return false
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group for If-Match", zap.Error(err), "groupId", groupID)
+		h.respondWithError(w, "Failed to retrieve group", http.StatusInternalServerError)
+		return false
+	}
+
+	return h.preconditionMet(w, r, group)
+}
+
+// preconditionMet is checkPrecondition for a group already read.
+func (h *Handler) preconditionMet(w http.ResponseWriter, r *http.Request, group *models.Group) bool {
+	etag := groupETag(group)
+	if !request.IfMatch(r, etag) {
+		logger.FromContext(r.Context(), h.log).Infow("Group changed since it was read", "groupId", group.ID)
+		w.Header().Set("ETag", etag)
+		response.RespondError(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+			"The group has changed since it was read", nil,
+		)
+		return false
+	}
+	return true
+}
+
+// respondPatchError answers a patch that could not be applied: 415 for other
+// media types, 400 for malformed patches, 409 for patches that do not fit the
+// resource as it is, and 422 for patches that would make it invalid.
+func (h *Handler) respondPatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, patch.ErrUnsupportedMediaType):
+		h.respondWithError(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, patch.ErrInvalidPatch):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, patch.ErrConflict):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, patch.ErrInvalidResult):
+		h.respondWithError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow("Failed to apply patch", zap.Error(err))
+		h.respondWithError(w, "Failed to apply patch", http.StatusInternalServerError)
+	}
+}
+
+// sameProfile reports whether two custom profiles hold the same attributes.
+func sameProfile(a, b map[string]any) bool {
+	return (len(a) == 0 && len(b) == 0) || reflect.DeepEqual(a, b)
+}
+
+// groupETag changes whenever the group's profile in Okta or its join policy
+// does. Metadata and members have their own routes and do not affect it.
+func groupETag(group *models.Group) string {
+	return response.ETag(group.ID, group.LastUpdated.Format(time.RFC3339Nano), group.JoinPolicy)
+}
+
 func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
 	response.RespondError(w, statusCode, "API_ERROR", message, nil)
 }
+
+// respondIfPolicyViolation answers 400 with every rule broken when the group
+// does not follow the naming policy.
+func (h *Handler) respondIfPolicyViolation(w http.ResponseWriter, err error) bool {
+	var violationErr *grouppolicy_service.ViolationError
+	if !errors.As(err, &violationErr) {
+		return false
+	}
+	response.RespondError(
+		w, http.StatusBadRequest, "GROUP_POLICY_VIOLATION", violationErr.Error(), violationErr.Violations,
+	)
+	return true
+}
+
+// respondIfRejected answers 422 with the hook's message when a before hook
+// rejected the operation.
+func (h *Handler) respondIfRejected(w http.ResponseWriter, err error) bool {
+	var rejectedErr *hooks.RejectedError
+	if !errors.As(err, &rejectedErr) {
+		return false
+	}
+	response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+	return true
+}
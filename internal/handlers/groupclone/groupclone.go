@@ -0,0 +1,85 @@
+package groupclone_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupclone_service "github.com/iamBelugaa/iam/internal/services/groupclone"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	cloneSvc *groupclone_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *groupclone_service.Service) *Handler {
+	return &Handler{log: log, cloneSvc: svc}
+}
+
+// CloneGroup creates a copy of the group under a new name. Large groups have
+// their members and apps copied by a job and are answered with 202.
+func (h *Handler) CloneGroup(w http.ResponseWriter, r *http.Request) {
+	groupID := chi.URLParam(r, "groupID")
+
+	var req models.CloneGroupRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode clone group request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		h.respondWithError(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Clone group request received",
+		"groupId", groupID, "name", req.Name, "copyMembers", req.CopyMembers, "copyApps", req.CopyApps,
+	)
+
+	clone, err := h.cloneSvc.Clone(r.Context(), groupID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to clone group")
+		return
+	}
+
+	if clone.Job != nil {
+		response.RespondSuccess(w, http.StatusAccepted, "Group cloned, copying members and apps", clone)
+		return
+	}
+	response.RespondSuccess(w, http.StatusCreated, "Group cloned successfully", clone)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var violationErr *grouppolicy_service.ViolationError
+	var rejectedErr *hooks.RejectedError
+
+	switch {
+	case errors.Is(err, group_service.ErrGroupNotFound), errors.Is(err, app_service.ErrGroupNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &violationErr):
+		response.RespondError(
+			w, http.StatusBadRequest, "GROUP_POLICY_VIOLATION", violationErr.Error(), violationErr.Violations,
+		)
+	case errors.As(err, &rejectedErr):
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
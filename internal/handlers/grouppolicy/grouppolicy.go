@@ -0,0 +1,51 @@
+package grouppolicy_handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	policySvc *grouppolicy_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *grouppolicy_service.Service) *Handler {
+	return &Handler{log: log, policySvc: svc}
+}
+
+func (h *Handler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get group naming policy request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.policySvc.Policy())
+}
+
+// CheckGroup tests a group's name, description and tags against the policy
+// without creating it. Violations are reported in the result, not as an error.
+func (h *Handler) CheckGroup(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Check group naming policy request received")
+
+	var req models.CheckGroupPolicyRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode check group naming policy request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" {
+		h.respondWithError(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", h.policySvc.Check(&req))
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,87 @@
+package grouptrash_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	grouptrash_service "github.com/iamBelugaa/iam/internal/services/grouptrash"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	trashSvc *grouptrash_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *grouptrash_service.Service) *Handler {
+	return &Handler{log: log, trashSvc: svc}
+}
+
+func (h *Handler) GetDeletedGroups(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get deleted groups request received")
+
+	deleted, err := h.trashSvc.GetDeletedGroups(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve deleted groups")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", deleted)
+}
+
+func (h *Handler) GetDeletedGroup(w http.ResponseWriter, r *http.Request) {
+	trashID := chi.URLParam(r, "trashID")
+	logger.FromContext(r.Context(), h.log).Infow("Get deleted group request received", "trashId", trashID)
+
+	deleted, err := h.trashSvc.GetDeletedGroup(r.Context(), trashID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve deleted group")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", deleted)
+}
+
+// RestoreGroup recreates a deleted group with its members and metadata. The
+// group gets a new ID.
+func (h *Handler) RestoreGroup(w http.ResponseWriter, r *http.Request) {
+	trashID := chi.URLParam(r, "trashID")
+	logger.FromContext(r.Context(), h.log).Infow("Restore deleted group request received", "trashId", trashID)
+
+	restore, err := h.trashSvc.Restore(r.Context(), trashID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to restore deleted group")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Group restored successfully", restore)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var violationErr *grouppolicy_service.ViolationError
+	var rejectedErr *hooks.RejectedError
+
+	switch {
+	case errors.Is(err, grouptrash_service.ErrDeletedGroupNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &violationErr):
+		response.RespondError(
+			w, http.StatusBadRequest, "GROUP_POLICY_VIOLATION", violationErr.Error(), violationErr.Violations,
+		)
+	case errors.As(err, &rejectedErr):
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,182 @@
+package guest_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	guestsSvc *guest_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *guest_service.Service) *Handler {
+	return &Handler{log: log, guestsSvc: svc}
+}
+
+func (h *Handler) CreateGuest(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateGuestRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create guest request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Create guest request received", "email", req.Email, "sponsorId", caller.UserID)
+
+	if req.Email == "" || req.FirstName == "" || req.LastName == "" {
+		h.respondWithError(w, "Email, firstName and lastName are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt.IsZero() {
+		h.respondWithError(w, "expiresAt is required for guests", http.StatusBadRequest)
+		return
+	}
+
+	if !req.ExpiresAt.After(time.Now()) {
+		h.respondWithError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	guest, err := h.guestsSvc.CreateGuest(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create guest")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Guest created successfully", guest)
+}
+
+func (h *Handler) GetGuests(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.GuestFilter{
+		SponsorID: query.Get("sponsorId"),
+		Status:    strings.ToUpper(query.Get("status")),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get guests request received", "sponsorId", filter.SponsorID, "status", filter.Status)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.guestsSvc.GetGuests(r.Context(), &filter))
+}
+
+func (h *Handler) GetGuest(w http.ResponseWriter, r *http.Request) {
+	guestID := chi.URLParam(r, "guestID")
+	if guestID == "" {
+		h.respondWithError(w, "Guest ID is required", http.StatusBadRequest)
+		return
+	}
+
+	guest, err := h.guestsSvc.GetGuest(r.Context(), guestID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve guest")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", guest)
+}
+
+func (h *Handler) AttestGuest(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	guestID := chi.URLParam(r, "guestID")
+	if guestID == "" {
+		h.respondWithError(w, "Guest ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Attest guest request received", "guestId", guestID, "sponsorId", caller.UserID)
+
+	// The request body is optional; an empty body keeps the current expiry.
+	var req models.AttestGuestRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode attest guest request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.respondWithError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	guest, err := h.guestsSvc.AttestGuest(r.Context(), guestID, caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to attest guest")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Guest attested successfully", guest)
+}
+
+func (h *Handler) OffboardGuest(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	guestID := chi.URLParam(r, "guestID")
+	if guestID == "" {
+		h.respondWithError(w, "Guest ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Offboard guest request received", "guestId", guestID, "sponsorId", caller.UserID)
+
+	if err := h.guestsSvc.OffboardGuest(r.Context(), guestID, caller.UserID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to offboard guest")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Guest offboarded successfully", nil)
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, guest_service.ErrGuestNotFound):
+		h.respondWithError(w, "Guest not found", http.StatusNotFound)
+	case errors.Is(err, guest_service.ErrNotSponsor),
+		errors.Is(err, guest_service.ErrInvalidSponsor):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, guest_service.ErrExpiryTooFar):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, guest_service.ErrGuestDeactivated):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -1,105 +1,1793 @@
 package handlers
 
 import (
+	"net/http"
+	"net/netip"
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
+	"github.com/iamBelugaa/iam/internal/auth"
 	"github.com/iamBelugaa/iam/internal/config"
+	access_handlers "github.com/iamBelugaa/iam/internal/handlers/access"
+	accessrequest_handlers "github.com/iamBelugaa/iam/internal/handlers/accessrequest"
+	apikey_handlers "github.com/iamBelugaa/iam/internal/handlers/apikey"
+	app_handlers "github.com/iamBelugaa/iam/internal/handlers/app"
+	avatar_handlers "github.com/iamBelugaa/iam/internal/handlers/avatar"
+	backup_handlers "github.com/iamBelugaa/iam/internal/handlers/backup"
+	batch_handlers "github.com/iamBelugaa/iam/internal/handlers/batch"
+	catalog_handlers "github.com/iamBelugaa/iam/internal/handlers/catalog"
+	change_handlers "github.com/iamBelugaa/iam/internal/handlers/change"
+	consent_handlers "github.com/iamBelugaa/iam/internal/handlers/consent"
+	dashboard_handlers "github.com/iamBelugaa/iam/internal/handlers/dashboard"
+	deactivation_handlers "github.com/iamBelugaa/iam/internal/handlers/deactivation"
+	defaultgroup_handlers "github.com/iamBelugaa/iam/internal/handlers/defaultgroup"
+	device_handlers "github.com/iamBelugaa/iam/internal/handlers/device"
+	directory_handlers "github.com/iamBelugaa/iam/internal/handlers/directory"
+	drift_handlers "github.com/iamBelugaa/iam/internal/handlers/drift"
+	event_handlers "github.com/iamBelugaa/iam/internal/handlers/event"
+	export_handlers "github.com/iamBelugaa/iam/internal/handlers/export"
+	expression_handlers "github.com/iamBelugaa/iam/internal/handlers/expression"
+	graphql_handlers "github.com/iamBelugaa/iam/internal/handlers/graphql"
 	group_handlers "github.com/iamBelugaa/iam/internal/handlers/group"
+	groupclone_handlers "github.com/iamBelugaa/iam/internal/handlers/groupclone"
+	grouppolicy_handlers "github.com/iamBelugaa/iam/internal/handlers/grouppolicy"
+	grouptrash_handlers "github.com/iamBelugaa/iam/internal/handlers/grouptrash"
+	guest_handlers "github.com/iamBelugaa/iam/internal/handlers/guest"
+	helpdesk_handlers "github.com/iamBelugaa/iam/internal/handlers/helpdesk"
+	invitation_handlers "github.com/iamBelugaa/iam/internal/handlers/invitation"
+	job_handlers "github.com/iamBelugaa/iam/internal/handlers/job"
+	membershipevent_handlers "github.com/iamBelugaa/iam/internal/handlers/membershipevent"
+	migration_handlers "github.com/iamBelugaa/iam/internal/handlers/migration"
+	pendingchange_handlers "github.com/iamBelugaa/iam/internal/handlers/pendingchange"
+	profiling_handlers "github.com/iamBelugaa/iam/internal/handlers/profiling"
+	provisioning_handlers "github.com/iamBelugaa/iam/internal/handlers/provisioning"
+	replay_handlers "github.com/iamBelugaa/iam/internal/handlers/replay"
+	report_handlers "github.com/iamBelugaa/iam/internal/handlers/report"
+	retry_handlers "github.com/iamBelugaa/iam/internal/handlers/retry"
 	role_handlers "github.com/iamBelugaa/iam/internal/handlers/role"
+	saga_handlers "github.com/iamBelugaa/iam/internal/handlers/saga"
+	scaling_handlers "github.com/iamBelugaa/iam/internal/handlers/scaling"
+	scheduledchange_handlers "github.com/iamBelugaa/iam/internal/handlers/scheduledchange"
+	security_handlers "github.com/iamBelugaa/iam/internal/handlers/security"
+	selfservice_handlers "github.com/iamBelugaa/iam/internal/handlers/selfservice"
+	serviceaccount_handlers "github.com/iamBelugaa/iam/internal/handlers/serviceaccount"
+	session_handlers "github.com/iamBelugaa/iam/internal/handlers/session"
+	sod_handlers "github.com/iamBelugaa/iam/internal/handlers/sod"
+	sync_handlers "github.com/iamBelugaa/iam/internal/handlers/sync"
+	tokenexchange_handlers "github.com/iamBelugaa/iam/internal/handlers/tokenexchange"
+	usage_handlers "github.com/iamBelugaa/iam/internal/handlers/usage"
 	user_handlers "github.com/iamBelugaa/iam/internal/handlers/user"
+	webhook_handlers "github.com/iamBelugaa/iam/internal/handlers/webhook"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/internal/orgs"
+	"github.com/iamBelugaa/iam/internal/projection"
+	"github.com/iamBelugaa/iam/internal/ratelimit"
+	"github.com/iamBelugaa/iam/internal/redaction"
+	access_service "github.com/iamBelugaa/iam/internal/services/access"
+	accessrequest_service "github.com/iamBelugaa/iam/internal/services/accessrequest"
+	apikey_service "github.com/iamBelugaa/iam/internal/services/apikey"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	avatar_service "github.com/iamBelugaa/iam/internal/services/avatar"
+	backup_service "github.com/iamBelugaa/iam/internal/services/backup"
+	batch_service "github.com/iamBelugaa/iam/internal/services/batch"
+	catalog_service "github.com/iamBelugaa/iam/internal/services/catalog"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	consent_service "github.com/iamBelugaa/iam/internal/services/consent"
+	dashboard_service "github.com/iamBelugaa/iam/internal/services/dashboard"
+	deactivation_service "github.com/iamBelugaa/iam/internal/services/deactivation"
+	defaultgroup_service "github.com/iamBelugaa/iam/internal/services/defaultgroup"
+	device_service "github.com/iamBelugaa/iam/internal/services/device"
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	drift_service "github.com/iamBelugaa/iam/internal/services/drift"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+	export_service "github.com/iamBelugaa/iam/internal/services/export"
+	expression_service "github.com/iamBelugaa/iam/internal/services/expression"
 	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupclone_service "github.com/iamBelugaa/iam/internal/services/groupclone"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	grouptrash_service "github.com/iamBelugaa/iam/internal/services/grouptrash"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	helpdesk_service "github.com/iamBelugaa/iam/internal/services/helpdesk"
+	history_service "github.com/iamBelugaa/iam/internal/services/history"
+	invitation_service "github.com/iamBelugaa/iam/internal/services/invitation"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	membershipevent_service "github.com/iamBelugaa/iam/internal/services/membershipevent"
+	migration_service "github.com/iamBelugaa/iam/internal/services/migration"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	profiling_service "github.com/iamBelugaa/iam/internal/services/profiling"
+	provisioning_service "github.com/iamBelugaa/iam/internal/services/provisioning"
+	replay_service "github.com/iamBelugaa/iam/internal/services/replay"
+	report_service "github.com/iamBelugaa/iam/internal/services/report"
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
 	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	scaling_service "github.com/iamBelugaa/iam/internal/services/scaling"
+	scheduledchange_service "github.com/iamBelugaa/iam/internal/services/scheduledchange"
+	security_service "github.com/iamBelugaa/iam/internal/services/security"
+	selfservice_service "github.com/iamBelugaa/iam/internal/services/selfservice"
+	serviceaccount_service "github.com/iamBelugaa/iam/internal/services/serviceaccount"
+	session_service "github.com/iamBelugaa/iam/internal/services/session"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	sync_service "github.com/iamBelugaa/iam/internal/services/sync"
+	tokenexchange_service "github.com/iamBelugaa/iam/internal/services/tokenexchange"
+	usage_service "github.com/iamBelugaa/iam/internal/services/usage"
 	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/openapi"
+	"github.com/iamBelugaa/iam/pkg/patch"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
 )
 
+//go:generate go run ../../cmd/openapi -o ../../api/openapi.json
+
 const (
 	APIVersion1URL = "/api/v1"
+	// apiVersion1Decoding is how v1 decodes request bodies unless
+	// Config.RequestDecoding says otherwise. v1 clients rely on unknown fields
+	// being ignored, so it stays lenient; later versions default to strict.
+	apiVersion1Decoding = request.Lenient
+
+	ndjson = "application/x-ndjson"
+)
+
+var (
+	streamParam = openapi.Param{Name: "stream", Description: "Stream newline-delimited JSON"}
+	fieldsParam = openapi.Param{
+		Name:        projection.QueryParam,
+		Description: "Comma-separated fields to return, with dots for nested fields such as profile.costCenter",
+	}
+	formatParam = openapi.Param{Name: "format", Description: "csv to export"}
+	// sortOrderParam goes with a sortBy parameter naming the fields of the
+	// listing it sorts.
+	sortOrderParam  = openapi.Param{Name: request.SortOrderParam, Description: "asc, the default, or desc"}
+	groupSortParams = []openapi.Param{
+		{Name: request.SortByParam, Description: "name, type, created or lastUpdated; not with stream"},
+		sortOrderParam,
+	}
+	memberSortParams = []openapi.Param{
+		{
+			Name:        request.SortByParam,
+			Description: "login, email, firstName, lastName, status, created or lastLogin; not with stream or asOf",
+		},
+		sortOrderParam,
+	}
+
+	exportParams = []openapi.Param{
+		{Name: "format", Description: "csv or jsonl"},
+		{Name: "attributes", Description: "Comma separated extra profile attributes"},
+	}
 )
 
 type Config struct {
-	Router        *chi.Mux
-	Config        *config.Config
-	Log           *zap.SugaredLogger
-	UsersService  *user_service.Service
-	GroupsService *group_service.Service
-	RolesService  *role_service.Service
+	Router                 *chi.Mux
+	Config                 *config.Config
+	Log                    *zap.SugaredLogger
+	UsersService           *user_service.Service
+	GroupsService          *group_service.Service
+	RolesService           *role_service.Service
+	ReportsService         *report_service.Service
+	BatchService           *batch_service.Service
+	WebhooksService        *webhook_service.Service
+	Verifier               *auth.Verifier
+	AccessRequestsService  *accessrequest_service.Service
+	AccessService          *access_service.Service
+	SyncService            *sync_service.Service
+	SoDService             *sod_service.Service
+	AvatarsService         *avatar_service.Service
+	ExportService          *export_service.Service
+	ServiceAccountsService *serviceaccount_service.Service
+	JobsService            *job_service.Service
+	GuestsService          *guest_service.Service
+	AppsService            *app_service.Service
+	InvitationsService     *invitation_service.Service
+	DeviceService          *device_service.Service
+	SessionService         *session_service.Service
+	TokenExchangeService   *tokenexchange_service.Service
+	CatalogService         *catalog_service.Service
+	ConsentService         *consent_service.Service
+	UsageService           *usage_service.Service
+	DirectoryService       *directory_service.Service
+	HistoryService         *history_service.Service
+	MembershipEventService *membershipevent_service.Service
+	ChangesService         *change_service.Service
+	DriftService           *drift_service.Service
+	SecurityService        *security_service.Service
+	RetryQueueService      *retry_service.Service
+	ReplayService          *replay_service.Service
+	ScalingService         *scaling_service.Service
+	DashboardService       *dashboard_service.Service
+	EventsService          *event_service.Service
+	ProfilingService       *profiling_service.Service
+	HelpdeskService        *helpdesk_service.Service
+	SelfServiceService     *selfservice_service.Service
+	APIKeysService         *apikey_service.Service
+	PendingChangesService  *pendingchange_service.Service
+	ScheduledChangeService *scheduledchange_service.Service
+	MigrationService       *migration_service.Service
+	BackupService          *backup_service.Service
+	SagasService           *saga_service.Service
+	ProvisioningService    *provisioning_service.Service
+	GroupPolicyService     *grouppolicy_service.Service
+	ExpressionService      *expression_service.Service
+	GroupMetadataService   *groupmetadata_service.Service
+	DefaultGroupsService   *defaultgroup_service.Service
+	DeactivationService    *deactivation_service.Service
+	GroupTrashService      *grouptrash_service.Service
+	GroupCloneService      *groupclone_service.Service
+	// Orgs serves the user, group and role endpoints of every configured Okta
+	// org under /orgs/{org}; the unprefixed endpoints serve the primary org.
+	Orgs *orgs.Registry
+	// RateLimiter throttles each API client, when set.
+	RateLimiter *ratelimit.Limiter
+	// TrustedProxies are the addresses of the proxies whose X-Forwarded-For
+	// and X-Real-IP headers name the client. Other requests are identified by
+	// their peer address.
+	TrustedProxies []netip.Prefix
+	// Redaction hides response fields from callers by role.
+	Redaction *redaction.Policy
+	// QueueRetries lets clients have mutations that failed because Okta was
+	// unavailable queued in RetryQueueService instead.
+	QueueRetries bool
+	// CaptureFailures keeps requests that fail with a 5xx in ReplayService,
+	// with the Okta interactions made while serving them.
+	CaptureFailures bool
+	// ValidateRequests checks every documented request against the OpenAPI
+	// spec before it reaches its handler.
+	ValidateRequests bool
+	// RequestDecoding decodes the request bodies of every API version in
+	// this mode. Empty leaves each version to its default.
+	RequestDecoding request.Mode
+	// GroupAdminGroups are the Okta groups whose members may change the
+	// members and owners of any group. When set, other callers must own a
+	// group to change its members.
+	GroupAdminGroups []string
+	// HelpdeskGroups and HelpdeskClientIDs are the Okta groups of the users,
+	// and the OAuth clients, that may run helpdesk password operations.
+	HelpdeskGroups    []string
+	HelpdeskClientIDs []string
+	// RequestTimeouts bound how long each API request may take.
+	RequestTimeouts RequestTimeouts
+	// EventsHeartbeat is how often an idle live event stream is sent a
+	// comment to keep it open.
+	EventsHeartbeat time.Duration
 }
 
-func Setup(cfg *Config) {
-	// Standard middleware for RealIP, RequestID, Logger, Recoverer etc.
-	cfg.Router.Use(middleware.RealIP)
-	cfg.Router.Use(middleware.RequestID)
-	cfg.Router.Use(middleware.Logger)
+// Setup registers every route on cfg.Router and returns the OpenAPI spec
+// built from the registrations, which is also served at /openapi.json.
+func Setup(cfg *Config) *openapi.Spec {
+	spec := openapi.New(openapi.Info{
+		Title:       "Flexera IAM Platform",
+		Description: "User, group, role and permission management through Okta.",
+		Version:     "v1",
+	})
+
+	// Standard middleware for the client address, request IDs and logging,
+	// Recoverer etc.
+	cfg.Router.Use(realIP(cfg.TrustedProxies))
+	cfg.Router.Use(requestLogging(cfg.Log))
 	cfg.Router.Use(middleware.Recoverer)
+	// YAML bodies are turned into JSON, and JSON responses into YAML for
+	// clients asking for it, around everything that reads or writes JSON.
+	cfg.Router.Use(response.EncodeYAML, request.DecodeYAML)
+	if cfg.Orgs != nil {
+		cfg.Router.Use(selectOrg(cfg.Orgs.Primary()))
+	}
+	if cfg.ValidateRequests {
+		cfg.Router.Use(spec.ValidateRequests)
+	}
 
 	userHandlers := user_handlers.New(cfg.Log, cfg.UsersService)
-	groupHandlers := group_handlers.New(cfg.Log, cfg.GroupsService)
+	accessHandlers := access_handlers.New(cfg.Log, cfg.AccessService)
+	groupHandlers := group_handlers.New(cfg.Log, cfg.GroupsService, cfg.HistoryService, cfg.GroupMetadataService)
 	roleHandlers := role_handlers.New(cfg.Log, cfg.RolesService)
+	reportHandlers := report_handlers.New(cfg.Log, cfg.ReportsService)
+	batchHandlers := batch_handlers.New(cfg.Log, cfg.BatchService)
+	webhookHandlers := webhook_handlers.New(cfg.Log, cfg.WebhooksService)
+	accessRequestHandlers := accessrequest_handlers.New(cfg.Log, cfg.AccessRequestsService)
+	syncHandlers := sync_handlers.New(cfg.Log, cfg.SyncService)
+	sodHandlers := sod_handlers.New(cfg.Log, cfg.SoDService)
+	avatarHandlers := avatar_handlers.New(cfg.Log, cfg.AvatarsService)
+	exportHandlers := export_handlers.New(cfg.Log, cfg.ExportService)
+	serviceAccountHandlers := serviceaccount_handlers.New(cfg.Log, cfg.ServiceAccountsService)
+	jobHandlers := job_handlers.New(cfg.Log, cfg.JobsService)
+	guestHandlers := guest_handlers.New(cfg.Log, cfg.GuestsService)
+	invitationHandlers := invitation_handlers.New(cfg.Log, cfg.InvitationsService)
+	deviceHandlers := device_handlers.New(cfg.Log, cfg.DeviceService)
+	sessionHandlers := session_handlers.New(cfg.Log, cfg.SessionService)
+	tokenExchangeHandlers := tokenexchange_handlers.New(cfg.Log, cfg.TokenExchangeService)
+	catalogHandlers := catalog_handlers.New(cfg.Log, cfg.CatalogService)
+	consentHandlers := consent_handlers.New(cfg.Log, cfg.ConsentService)
+	usageHandlers := usage_handlers.New(cfg.Log, cfg.UsageService)
+	appHandlers := app_handlers.New(cfg.Log, cfg.AppsService)
+	directoryHandlers := directory_handlers.New(cfg.Log, cfg.DirectoryService)
+	changeHandlers := change_handlers.New(cfg.Log, cfg.ChangesService)
+	driftHandlers := drift_handlers.New(cfg.Log, cfg.DriftService)
+	securityHandlers := security_handlers.New(cfg.Log, cfg.SecurityService)
+	membershipEventHandlers := membershipevent_handlers.New(cfg.Log, cfg.MembershipEventService)
+	retryHandlers := retry_handlers.New(cfg.Log, cfg.RetryQueueService)
+	replayHandlers := replay_handlers.New(cfg.Log, cfg.ReplayService)
+	scalingHandlers := scaling_handlers.New(cfg.Log, cfg.ScalingService)
+	dashboardHandlers := dashboard_handlers.New(cfg.Log, cfg.DashboardService)
+	eventHandlers := event_handlers.New(cfg.Log, cfg.EventsService, cfg.EventsHeartbeat)
+	sagaHandlers := saga_handlers.New(cfg.Log, cfg.SagasService)
+	groupPolicyHandlers := grouppolicy_handlers.New(cfg.Log, cfg.GroupPolicyService)
+	expressionHandlers := expression_handlers.New(cfg.Log, cfg.ExpressionService)
+	provisioningHandlers := provisioning_handlers.New(cfg.Log, cfg.ProvisioningService)
+	defaultGroupHandlers := defaultgroup_handlers.New(cfg.Log, cfg.DefaultGroupsService)
+	deactivationHandlers := deactivation_handlers.New(cfg.Log, cfg.DeactivationService)
+	profilingHandlers := profiling_handlers.New(cfg.Log, cfg.ProfilingService)
+	helpdeskHandlers := helpdesk_handlers.New(cfg.Log, cfg.HelpdeskService)
+	selfServiceHandlers := selfservice_handlers.New(cfg.Log, cfg.SelfServiceService)
+	apiKeyHandlers := apikey_handlers.New(cfg.Log, cfg.APIKeysService)
+	pendingChangeHandlers := pendingchange_handlers.New(cfg.Log, cfg.PendingChangesService)
+	scheduledChangeHandlers := scheduledchange_handlers.New(cfg.Log, cfg.ScheduledChangeService)
+	migrationHandlers := migration_handlers.New(cfg.Log, cfg.MigrationService)
+	backupHandlers := backup_handlers.New(cfg.Log, cfg.BackupService)
+	groupTrashHandlers := grouptrash_handlers.New(cfg.Log, cfg.GroupTrashService)
+	groupCloneHandlers := groupclone_handlers.New(cfg.Log, cfg.GroupCloneService)
+	graphqlHandlers := graphql_handlers.New(cfg.Log, cfg.UsersService, cfg.GroupsService, cfg.AppsService)
+	admins := newGroupAdmins(cfg.Log, cfg.GroupAdminGroups, cfg.GroupMetadataService)
+	helpdesk := newHelpdeskAgents(cfg.Log, cfg.HelpdeskGroups, cfg.HelpdeskClientIDs)
+
+	router := openapi.NewRouter(cfg.Router, spec)
+
+	// Prometheus metrics, such as the state of the Okta circuit breakers.
+	cfg.Router.Handle("/metrics", promhttp.Handler())
+
+	// API documentation.
+	cfg.Router.Get("/openapi.json", spec.ServeHTTP)
+	cfg.Router.Get("/docs", http.RedirectHandler("/docs/", http.StatusMovedPermanently).ServeHTTP)
+	cfg.Router.Handle("/docs/*", http.StripPrefix("/docs", openapi.UI("Flexera IAM Platform", "/openapi.json")))
+
+	// Workload signals for autoscaling worker replicas, such as with KEDA's
+	// metrics API scaler. Like /metrics, they describe this replica.
+	router.Get("/scale-metrics", scalingHandlers.GetMetrics, openapi.Doc{
+		Summary:     "Get this replica's workload for autoscaling",
+		Description: "Reports unfinished jobs and retries, webhook deliveries in flight, Okta rate limit pressure and how each org's rate limits are shared between subsystems.",
+		Response:    models.ScaleMetrics{},
+	})
+
+	// Signed avatar links are shared with browsers, so they live outside the API prefix.
+	router.Get("/avatars/{userID}", avatarHandlers.ServeAvatar, openapi.Doc{
+		Summary:  "Serve an avatar through a signed URL",
+		Query:    []openapi.Param{{Name: "expires"}, {Name: "signature"}},
+		Produces: []string{"image/png", "image/jpeg", "image/gif", "image/webp"},
+	})
+
+	router.Route(APIVersion1URL, func(r *openapi.Router) {
+		if cfg.RateLimiter != nil {
+			r.Use(cfg.RateLimiter.Middleware(routeClass))
+		}
+		if cfg.CaptureFailures {
+			r.Use(captureFailures(cfg.Log, cfg.ReplayService, spec))
+		}
+		r.Use(oktaUnavailable)
+		r.Use(requestDeadlines(cfg.Router, cfg.RequestTimeouts))
+		r.Use(request.WithMode(decodingMode(cfg.RequestDecoding, apiVersion1Decoding)))
+		if cfg.QueueRetries {
+			r.Use(queueRetries(cfg.Log, cfg.RetryQueueService, spec))
+		}
+		if cfg.Redaction != nil {
+			r.Use(cfg.Redaction.Middleware(cfg.Log, cfg.Verifier))
+		}
+		r.Use(projection.Middleware)
+		r.Use(recordChanges(cfg.ChangesService))
+		r.Use(publishEvents(cfg.EventsService))
 
-	cfg.Router.Route(APIVersion1URL, func(r chi.Router) {
 		// User management endpoints.
-		r.Route("/users", func(r chi.Router) {
-			r.Get("/", userHandlers.GetUsers)
-			r.Post("/", userHandlers.CreateUser)
+		r.Route("/users", func(r *openapi.Router) {
+			r.Get("/", userHandlers.GetUsers, openapi.Doc{
+				Summary:  "List all users",
+				Query:    []openapi.Param{streamParam, fieldsParam},
+				Response: []models.User{},
+				Produces: []string{ndjson},
+			})
+			r.Post("/", userHandlers.CreateUser, openapi.Doc{
+				Summary:  "Create new user",
+				Request:  models.CreateUserRequest{},
+				Response: models.User{},
+				Status:   http.StatusCreated,
+			})
+			r.Route("/bulk-deactivate", func(r *openapi.Router) {
+				if admins != nil {
+					r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+				} else {
+					r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+				}
 
-			r.Route("/{userID}", func(r chi.Router) {
-				r.Get("/", userHandlers.GetUser)
-				r.Put("/", userHandlers.UpdateUser)
-				r.Delete("/", userHandlers.DeleteUser)
+				r.Post("/", deactivationHandlers.Start, openapi.Doc{
+					Summary:     "Start a job that deactivates the users a dry run previewed",
+					Description: "A preview can be started once, by the caller who made it, until it expires.",
+					Request:     models.StartBulkDeactivationRequest{},
+					Response:    models.Job{},
+					Status:      http.StatusAccepted,
+				})
+				r.Post("/dry-run", deactivationHandlers.Preview, openapi.Doc{
+					Summary: "Preview which users a bulk deactivation would change and which it leaves out",
+					Description: "Users are selected by ID or with an Okta search filter. Break-glass accounts, " +
+						"members of excluded groups and the caller are never deactivated.",
+					Request:  models.BulkDeactivationRequest{},
+					Response: models.BulkDeactivationPreview{},
+				})
+				r.Get("/{jobID}/results", deactivationHandlers.GetResults, openapi.Doc{
+					Summary:  "Get what a bulk deactivation did to each user",
+					Query:    []openapi.Param{formatParam},
+					Response: []models.DeactivationResult{},
+					Produces: []string{"text/csv"},
+				})
+			})
+			r.Post("/lookup", batchHandlers.LookupUsers, openapi.Doc{
+				Summary:     "Resolve up to 100 logins or emails to users concurrently",
+				Description: "Identifiers that match no user, or several by email, get an error instead of a user.",
+				Request:     models.UserLookupRequest{},
+				Response:    []models.UserLookupResult{},
+			})
+
+			r.Route("/{userID}", func(r *openapi.Router) {
+				r.Get("/", userHandlers.GetUser, openapi.Doc{
+					Summary:     "Get user by ID",
+					Description: "The ETag header can be sent back in If-Match to update or delete the user.",
+					Query:       []openapi.Param{fieldsParam},
+					Response:    models.User{},
+				})
+				r.Put("/", userHandlers.UpdateUser, openapi.Doc{
+					Summary:     "Update user",
+					Description: "With If-Match, answers 412 if the user changed since the ETag was read.",
+					Request:     models.UpdateUserRequest{},
+					Response:    models.User{},
+				})
+				r.Patch("/", userHandlers.PatchUser, openapi.Doc{
+					Summary: "Change some of the user's fields",
+					Description: "Takes a JSON Merge Patch or JSON Patch of the fields PUT accepts. " +
+						"With If-Match, answers 412 if the user changed since the ETag was read.",
+					Response: models.User{},
+					Consumes: []string{patch.MergePatch, patch.JSONPatch},
+				})
+				r.Delete("/", userHandlers.DeleteUser, openapi.Doc{
+					Summary:     "Delete user",
+					Description: "With If-Match, answers 412 if the user changed since the ETag was read.",
+				})
+				r.Get("/access", accessHandlers.GetUserAccess, openapi.Doc{
+					Summary:     "Get the user's groups, apps, admin roles and factors in one view",
+					Description: "Apps include those assigned through a group.",
+					Response:    models.UserAccess{},
+				})
+				r.Get("/consents", consentHandlers.GetUserConsents, openapi.Doc{
+					Summary:  "Get the consent the user gave before self-service actions, newest first",
+					Response: []models.ConsentRecord{},
+				})
+				r.Get("/missing-profile-attributes", profilingHandlers.GetUserMissing, openapi.Doc{
+					Summary:  "List the profile attributes the user's apps and groups require that they have not filled in",
+					Response: []models.MissingProfileAttribute{},
+				})
+				r.Get("/compare/{otherUserID}", accessHandlers.CompareUsers, openapi.Doc{
+					Summary:     "Compare the group memberships and app assignments of two users",
+					Description: "Lists the groups and apps both users have, and those only one has.",
+					Response:    models.UserAccessComparison{},
+				})
 
 				// User lifecycle actions.
-				r.Post("/activate", userHandlers.ActivateUser)
-				r.Post("/deactivate", userHandlers.DeactivateUser)
-				r.Post("/suspend", userHandlers.SuspendUser)
-				r.Post("/unsuspend", userHandlers.UnSuspendUser)
+				r.Post("/activate", userHandlers.ActivateUser, openapi.Doc{Summary: "Activate user"})
+				r.Post("/deactivate", userHandlers.DeactivateUser, openapi.Doc{Summary: "Deactivate user"})
+				r.Post("/suspend", userHandlers.SuspendUser, openapi.Doc{Summary: "Suspend user"})
+				r.Post("/unsuspend", userHandlers.UnSuspendUser, openapi.Doc{Summary: "Unsuspend user"})
+
+				// Helpdesk operations, for helpdesk agents only.
+				r.Route("/helpdesk", func(r *openapi.Router) {
+					r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), helpdesk.requireAgent)
+
+					r.Post("/expire-password", helpdeskHandlers.ExpirePassword, openapi.Doc{
+						Summary: "Make the user choose a new password at their next sign-in",
+					})
+					r.Post("/reset-password", helpdeskHandlers.ResetPassword, openapi.Doc{
+						Summary: "Email the user a link to reset their password",
+					})
+					r.Post("/temporary-password", helpdeskHandlers.IssueTemporaryPassword, openapi.Doc{
+						Summary:     "Expire the user's password and generate a one-time temporary password",
+						Description: "The user must change the temporary password at their next sign-in.",
+						Response:    models.TemporaryPassword{},
+					})
+					r.Post("/unlock", helpdeskHandlers.UnlockUser, openapi.Doc{
+						Summary: "Unlock a user locked out after too many failed sign-ins",
+					})
+				})
+
+				// User avatar sub-resource.
+				r.Get("/avatar", avatarHandlers.GetAvatarURL, openapi.Doc{
+					Summary:  "Get a signed URL for the user's avatar",
+					Response: models.AvatarURL{},
+				})
+				r.Put("/avatar", avatarHandlers.UploadAvatar, openapi.Doc{
+					Summary:  "Upload a PNG, JPEG, GIF or WebP avatar",
+					Consumes: []string{"image/*", "multipart/form-data"},
+					Response: models.AvatarURL{},
+				})
+				r.Delete("/avatar", avatarHandlers.DeleteAvatar, openapi.Doc{Summary: "Delete the user's avatar"})
 
 				// User roles sub-resource.
-				r.Route("/roles", func(r chi.Router) {
-					r.Get("/", roleHandlers.GetUserRoles)
-					r.Put("/{roleID}", roleHandlers.AssignRoleToUser)
-					r.Delete("/{roleID}", roleHandlers.UnassignRoleFromUser)
+				r.Route("/roles", func(r *openapi.Router) {
+					r.Get("/", roleHandlers.GetUserRoles, openapi.Doc{
+						Summary:  "Get roles of a user",
+						Response: []models.Role{},
+					})
+					r.Put("/{roleID}", roleHandlers.AssignRoleToUser, openapi.Doc{Summary: "Assign a role to a user"})
+					r.Delete("/{roleID}", roleHandlers.UnassignRoleFromUser, openapi.Doc{
+						Summary: "Unassign a role from a user",
+					})
 				})
 			})
 		})
 
 		// Group management endpoints.
-		r.Route("/groups", func(r chi.Router) {
-			r.Get("/", groupHandlers.GetGroups)
-			r.Post("/", groupHandlers.CreateGroup)
+		r.Route("/groups", func(r *openapi.Router) {
+			r.Get("/", groupHandlers.GetGroups, openapi.Doc{
+				Summary: "List all groups",
+				Query: append([]openapi.Param{streamParam, fieldsParam, {
+					Name:        "tag",
+					Description: "Only groups whose metadata has this key, or key:value; repeat to require several",
+				}}, groupSortParams...),
+				Response: []models.Group{},
+				Produces: []string{ndjson},
+			})
+			r.Post("/", groupHandlers.CreateGroup, openapi.Doc{
+				Summary:  "Create new group",
+				Request:  models.CreateGroupRequest{},
+				Response: models.Group{},
+				Status:   http.StatusCreated,
+			})
+			r.Get("/stats", directoryHandlers.GetGroupStats, openapi.Doc{
+				Summary: "Count groups by type and size, and the groups without members",
+				Description: "Computed from the directory index while it is fresh, and from Okta otherwise " +
+					"or with consistent=true.",
+				Query:    []openapi.Param{{Name: "consistent", Description: "true to ask Okta instead of the index"}},
+				Response: models.GroupStats{},
+			})
+			r.Get("/export", exportHandlers.ExportAllGroupMembers, openapi.Doc{
+				Summary:  "Stream the members of every group as CSV or JSON lines",
+				Query:    exportParams,
+				Produces: []string{"text/csv", ndjson},
+			})
+
+			// Deleted groups kept for the retention period, and restoring them.
+			r.Route("/trash", func(r *openapi.Router) {
+				r.Get("/", groupTrashHandlers.GetDeletedGroups, openapi.Doc{
+					Summary:  "List the deleted groups that can still be restored, without their members",
+					Response: []models.DeletedGroup{},
+				})
+				r.Get("/{trashID}", groupTrashHandlers.GetDeletedGroup, openapi.Doc{
+					Summary:  "Get a deleted group with the members it had",
+					Response: models.DeletedGroup{},
+				})
+				r.Post("/{trashID}/restore", groupTrashHandlers.RestoreGroup, openapi.Doc{
+					Summary: "Recreate a deleted group with its members and metadata",
+					Description: "The group gets a new ID. Members whose time-bound membership ended while " +
+						"the group was in the trash are skipped, and members that cannot be added are reported.",
+					Response: models.GroupRestore{},
+					Status:   http.StatusCreated,
+				})
+			})
+
+			r.Route("/{groupID}", func(r *openapi.Router) {
+				r.Get("/", groupHandlers.GetGroup, openapi.Doc{
+					Summary:     "Get group by ID",
+					Description: "The ETag header can be sent back in If-Match to update or delete the group.",
+					Query:       []openapi.Param{fieldsParam},
+					Response:    models.Group{},
+				})
+				r.Put("/", groupHandlers.UpdateGroup, openapi.Doc{
+					Summary:     "Update group",
+					Description: "With If-Match, answers 412 if the group changed since the ETag was read.",
+					Request:     models.UpdateGroupRequest{},
+					Response:    models.Group{},
+				})
+				r.Patch("/", groupHandlers.PatchGroup, openapi.Doc{
+					Summary: "Change some of the group's fields",
+					Description: "Takes a JSON Merge Patch or JSON Patch of the fields PUT accepts. " +
+						"With If-Match, answers 412 if the group changed since the ETag was read.",
+					Response: models.Group{},
+					Consumes: []string{patch.MergePatch, patch.JSONPatch},
+				})
+				r.Delete("/", groupHandlers.DeleteGroup, openapi.Doc{
+					Summary:     "Delete group",
+					Description: "With If-Match, answers 412 if the group changed since the ETag was read.",
+				})
+				r.Post("/members:check", directoryHandlers.CheckGroupMembers, openapi.Doc{
+					Summary: "Check whether each of a batch of users is a member of the group",
+					Description: "Takes up to 1000 user IDs. Answered from the directory index while it is fresh " +
+						"and has the group, and from Okta otherwise or with consistent=true.",
+					Query:    []openapi.Param{{Name: "consistent", Description: "true to ask Okta instead of the index"}},
+					Request:  models.CheckGroupMembersRequest{},
+					Response: models.GroupMembershipCheck{},
+				})
+				r.Get("/apps", appHandlers.GetGroupApps, openapi.Doc{
+					Summary:     "List the apps the group is assigned to",
+					Description: "Each app comes with the priority and app profile its assignment gives the group's members.",
+					Response:    []models.GroupApp{},
+				})
+
+				// Copying a group, which with admin groups configured only
+				// admins may do.
+				r.Route("/clone", func(r *openapi.Router) {
+					if admins != nil {
+						r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+					}
+
+					r.Post("/", groupCloneHandlers.CloneGroup, openapi.Doc{
+						Summary: "Create a copy of the group under a new name, optionally with its members and apps",
+						Description: "The copy gets the group's description, profile and join policy. Members keep " +
+							"the expiry of time-bound memberships, and apps the priority and app profile of their " +
+							"assignment. When there are more members and apps than GROUP_CLONE_JOB_THRESHOLD they " +
+							"are copied by a job and the clone is returned with 202 and the job.",
+						Request:  models.CloneGroupRequest{},
+						Response: models.GroupClone{},
+						Status:   http.StatusCreated,
+					})
+				})
+
+				// Group metadata kept alongside the Okta group.
+				r.Route("/metadata", func(r *openapi.Router) {
+					r.Get("/", groupHandlers.GetGroupMetadata, openapi.Doc{
+						Summary:  "Get the group's owners, cost center, classification and tags",
+						Response: models.GroupMetadata{},
+					})
+					r.Put("/", groupHandlers.SetGroupMetadata, openapi.Doc{
+						Summary:  "Replace the group's metadata",
+						Request:  models.SetGroupMetadataRequest{},
+						Response: models.GroupMetadata{},
+					})
+					r.Delete("/", groupHandlers.DeleteGroupMetadata, openapi.Doc{Summary: "Delete the group's metadata"})
+				})
+
+				// Group owners, who may change the group's members without
+				// being admins.
+				r.Route("/owners", func(r *openapi.Router) {
+					r.Get("/", groupHandlers.GetGroupOwners, openapi.Doc{
+						Summary:  "List the users who own the group",
+						Response: []string{},
+					})
+
+					r.Route("/{userID}", func(r *openapi.Router) {
+						if admins != nil {
+							r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+						}
 
-			r.Route("/{groupID}", func(r chi.Router) {
-				r.Get("/", groupHandlers.GetGroup)
-				r.Put("/", groupHandlers.UpdateGroup)
-				r.Delete("/", groupHandlers.DeleteGroup)
+						r.Put("/", groupHandlers.AddGroupOwner, openapi.Doc{
+							Summary:  "Make a user an owner of the group",
+							Response: []string{},
+						})
+						r.Delete("/", groupHandlers.RemoveGroupOwner, openapi.Doc{
+							Summary:  "Remove an owner from the group",
+							Response: []string{},
+						})
+					})
+				})
 
 				// Group members sub-resource.
-				r.Route("/members", func(r chi.Router) {
-					r.Get("/", groupHandlers.GetGroupMembers)
-					r.Put("/{userID}", groupHandlers.AddUserToGroup)
-					r.Delete("/{userID}", groupHandlers.RemoveUserFromGroup)
+				r.Route("/members", func(r *openapi.Router) {
+					r.Get("/", groupHandlers.GetGroupMembers, openapi.Doc{
+						Summary: "Get group members",
+						Description: "With asOf, the data is a GroupMembersAsOf derived from membership " +
+							"snapshots and the membership changes in the Okta System Log.",
+						Query: append([]openapi.Param{
+							{Name: "includeExpiry", Description: "Add the expiry of time-bound memberships"},
+							{Name: "expand", Description: "Add each member's factors and/or groups, comma separated"},
+							{Name: "asOf", Description: "Return the membership at this past RFC 3339 time or date"},
+							streamParam,
+							fieldsParam,
+						}, memberSortParams...),
+						Response: []models.GroupMember{},
+						Produces: []string{ndjson},
+					})
+					r.Get("/export", exportHandlers.ExportGroupMembers, openapi.Doc{
+						Summary:  "Stream the group's members as CSV or JSON lines",
+						Query:    exportParams,
+						Produces: []string{"text/csv", ndjson},
+					})
+					r.Get("/history", groupHandlers.GetGroupMembersHistory, openapi.Doc{
+						Summary: "Get the group's members at a past time",
+						Description: "Derived from membership snapshots and the membership changes in the " +
+							"Okta System Log.",
+						Query:    []openapi.Param{{Name: "at", Description: "Past RFC 3339 time, or a date for its start in UTC"}},
+						Response: models.GroupMembersAsOf{},
+					})
+					r.Get("/history/diff", groupHandlers.GetGroupMembersDiff, openapi.Doc{
+						Summary: "Get who joined and left the group between two times",
+						Query: []openapi.Param{
+							{Name: "from", Description: "RFC 3339 time or date"},
+							{Name: "to", Description: "RFC 3339 time or date; defaults to now"},
+						},
+						Response: models.GroupMembershipDiff{},
+					})
+
+					// With admin groups configured, only admins and the
+					// group's owners may change its members. Changes to
+					// groups under the two-person rule wait for approval.
+					r.Route("/{userID}", func(r *openapi.Router) {
+						if admins != nil {
+							r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireOwner)
+						}
+						r.Use(pendingChangeHandlers.Hold)
+
+						r.Put("/", groupHandlers.AddUserToGroup, openapi.Doc{
+							Summary: "Add user to group, optionally until expiresAt",
+							Description: "For a protected group, the change is held for a second admin's approval " +
+								"and a PendingMembershipChange is returned with 202.",
+							Request: models.AddGroupMemberRequest{},
+						})
+						r.Delete("/", groupHandlers.RemoveUserFromGroup, openapi.Doc{
+							Summary: "Remove user from group",
+							Description: "For a protected group, the change is held for a second admin's approval " +
+								"and a PendingMembershipChange is returned with 202.",
+						})
+					})
 				})
 
 				// Group roles sub-resource.
-				r.Route("/roles", func(r chi.Router) {
-					r.Get("/", roleHandlers.GetGroupRoles)
-					r.Put("/{roleID}", roleHandlers.AssignRoleToGroup)
-					r.Delete("/{roleID}", roleHandlers.UnassignRoleFromGroup)
+				r.Route("/roles", func(r *openapi.Router) {
+					r.Get("/", roleHandlers.GetGroupRoles, openapi.Doc{
+						Summary:  "Get roles of a group",
+						Response: []models.Role{},
+					})
+					r.Put("/{roleID}", roleHandlers.AssignRoleToGroup, openapi.Doc{Summary: "Assign a role to a group"})
+					r.Delete("/{roleID}", roleHandlers.UnassignRoleFromGroup, openapi.Doc{
+						Summary: "Unassign a role from a group",
+					})
 				})
 			})
 		})
 
+		// Tools for testing configuration before it is deployed to Okta.
+		r.Route("/tools", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Post("/evaluate-expression", expressionHandlers.EvaluateExpression, openapi.Doc{
+				Summary: "Evaluate an Okta Expression Language expression against a user",
+				Description: "Takes an expression, or the groupRuleId of a group rule to test, and the userId of a " +
+					"user read from Okta with their groups, or a profile and groups. Nothing is changed. " +
+					"Expressions that do not parse or evaluate are answered with the error and its position.",
+				Request:  models.EvaluateExpressionRequest{},
+				Response: models.ExpressionEvaluation{},
+			})
+		})
+
+		// Group naming and tagging policy endpoints.
+		r.Route("/group-policy", func(r *openapi.Router) {
+			r.Get("/", groupPolicyHandlers.GetPolicy, openapi.Doc{
+				Summary:  "Get the naming and tagging rules groups must follow",
+				Response: models.GroupPolicy{},
+			})
+			r.Post("/check", groupPolicyHandlers.CheckGroup, openapi.Doc{
+				Summary:     "Test a group name, description and tags against the rules",
+				Description: "Nothing is created. Every rule the group breaks is listed with what to change.",
+				Request:     models.CheckGroupPolicyRequest{},
+				Response:    models.GroupPolicyCheck{},
+			})
+		})
+
+		// Composite provisioning endpoints. Each runs as a saga: when a step
+		// fails, the steps before it are undone and the saga is returned as
+		// the error's details.
+		r.Post("/onboarding", provisioningHandlers.OnboardUser, openapi.Doc{
+			Summary: "Create a user and add them to groups",
+			Description: "If adding the user to a group fails, the user is deleted again. With reconcile, the " +
+				"addition is retried by the reconciliation worker instead, and 202 is returned.",
+			Request:  models.OnboardUserRequest{},
+			Response: models.Saga{},
+			Status:   http.StatusCreated,
+		})
+		r.Post("/teams", provisioningHandlers.CreateTeam, openapi.Doc{
+			Summary: "Create a team's group and add its members",
+			Description: "If adding a member fails, the group is deleted again. With reconcile, the addition " +
+				"is retried by the reconciliation worker instead, and 202 is returned.",
+			Request:  models.CreateTeamRequest{},
+			Response: models.Saga{},
+			Status:   http.StatusCreated,
+		})
+		r.Route("/sagas", func(r *openapi.Router) {
+			r.Get("/", sagaHandlers.GetSagas, openapi.Doc{
+				Summary:  "List sagas, newest first",
+				Query:    []openapi.Param{{Name: "type"}, {Name: "status"}, {Name: "resourceId"}},
+				Response: []models.Saga{},
+			})
+			r.Get("/{sagaID}", sagaHandlers.GetSaga, openapi.Doc{
+				Summary:  "Get a saga and the status of each of its steps",
+				Response: models.Saga{},
+			})
+		})
+
+		// Sagas left short of their intended state, whose remaining steps the
+		// reconciliation worker retries.
+		r.Route("/reconciliation", func(r *openapi.Router) {
+			r.Get("/pending", sagaHandlers.GetPendingReconciliations, openapi.Doc{
+				Summary: "List sagas being reconciled, oldest first",
+				Description: "FORWARD sagas were started with reconcile and have a failed step left to retry; UNDO " +
+					"sagas failed to undo a step. STUCK ones failed every attempt and are only retried when forced.",
+				Response: []models.Reconciliation{},
+			})
+			r.Post("/{sagaID}/retry", sagaHandlers.RetryReconciliation, openapi.Doc{
+				Summary:     "Retry the remaining steps of a saga now",
+				Description: "Stuck sagas are retried too. A saga that reaches its intended state is no longer pending.",
+				Response:    models.Reconciliation{},
+			})
+		})
+
 		// Role management endpoints.
-		r.Route("/roles", func(r chi.Router) {
-			r.Get("/", roleHandlers.GetRoles)
-			r.Post("/", roleHandlers.CreateRole)
+		r.Route("/roles", func(r *openapi.Router) {
+			r.Get("/", roleHandlers.GetRoles, openapi.Doc{Summary: "List all roles", Response: []models.Role{}})
+			r.Post("/", roleHandlers.CreateRole, openapi.Doc{
+				Summary:  "Create new role",
+				Request:  models.CreateRoleRequest{},
+				Response: models.Role{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/{roleID}", func(r *openapi.Router) {
+				r.Get("/", roleHandlers.GetRole, openapi.Doc{Summary: "Get role by ID", Response: models.Role{}})
+				r.Put("/", roleHandlers.UpdateRole, openapi.Doc{
+					Summary:  "Update role",
+					Request:  models.UpdateRoleRequest{},
+					Response: models.Role{},
+				})
+				r.Delete("/", roleHandlers.DeleteRole, openapi.Doc{Summary: "Delete role"})
+			})
+		})
+
+		// App endpoints.
+		r.Get("/apps/{appID}/access", appHandlers.GetAppAccess, openapi.Doc{
+			Summary:     "Who has access to the app",
+			Description: "Every user assigned to the app directly or through a group, with each path, ordered by login.",
+			Query: []openapi.Param{
+				{Name: "limit", Description: "Users per page, at most 1000; defaults to 100"},
+				{Name: "after", Description: "The next cursor of the previous page"},
+			},
+			Response: models.AppAccess{},
+		})
+		r.Get("/apps/{appID}/users", appHandlers.GetAppUsers, openapi.Doc{
+			Summary: "Users assigned to the app, directly or through a group",
+			Description: "The access listing narrowed to direct or group assignments. Counts cover every user " +
+				"with access; next pages through the matching users.",
+			Query: []openapi.Param{
+				{Name: "assignment", Description: "direct or group; omit for both"},
+				{Name: "limit", Description: "Users per page, at most 1000; defaults to 100"},
+				{Name: "after", Description: "The next cursor of the previous page"},
+			},
+			Response: models.AppAccess{},
+		})
+		r.Get("/apps/{appID}/groups", appHandlers.GetAppGroups, openapi.Doc{
+			Summary:     "Groups assigned to the app",
+			Description: "Ordered by name, each with the priority and app profile its assignment gives the group's members.",
+			Response:    []models.AppGroup{},
+		})
+
+		// Reporting endpoints.
+		r.Route("/reports", func(r *openapi.Router) {
+			r.Get("/group-app-matrix", reportHandlers.GetGroupAppMatrix, openapi.Doc{
+				Summary: "Matrix of groups vs. the apps they grant",
+				Query: []openapi.Param{
+					{Name: "groupQuery"}, {Name: "appQuery"}, {Name: "onlyAssigned"}, formatParam,
+				},
+				Response: models.GroupAppMatrix{},
+				Produces: []string{"text/csv"},
+			})
+			r.Get("/inactive-users", reportHandlers.GetInactiveUsers, openapi.Doc{
+				Summary: "Users with no sign-in for the given number of days",
+				Query: []openapi.Param{
+					{Name: "days"},
+					{Name: "excludeGroupIds", Description: "Comma separated group IDs"},
+					formatParam,
+				},
+				Response: []models.InactiveUser{},
+				Produces: []string{"text/csv"},
+			})
+			r.Get("/service-accounts-past-review", serviceAccountHandlers.GetPastReview, openapi.Doc{
+				Summary:  "Service accounts whose review date has passed, most overdue first",
+				Query:    []openapi.Param{formatParam},
+				Response: []models.ServiceAccountPastReview{},
+				Produces: []string{"text/csv"},
+			})
+		})
+
+		// Batch endpoints.
+		r.Post("/batch:get", batchHandlers.BatchGet, openapi.Doc{
+			Summary:  "Fetch up to 100 users and groups concurrently in one request",
+			Request:  models.BatchGetRequest{},
+			Response: []models.BatchGetResult{},
+		})
+
+		// GraphQL endpoint for fetching users, groups and apps in one query.
+		r.Post("/graphql", graphqlHandlers.ServeGraphQL, openapi.Doc{
+			Summary:  "Run a read-only GraphQL query over users, groups and their app assignments",
+			Request:  graphql_handlers.QueryRequest{},
+			Produces: []string{"application/json"},
+		})
+
+		// Webhook subscriber endpoints. Subscribers choose where the server
+		// sends requests, so only admins may manage them when admin groups
+		// are configured.
+		r.Route("/webhooks/subscribers", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/", webhookHandlers.GetSubscribers, openapi.Doc{
+				Summary:  "List webhook subscribers",
+				Response: []models.WebhookSubscriber{},
+			})
+			r.Post("/", webhookHandlers.CreateSubscriber, openapi.Doc{
+				Summary:  "Register a webhook subscriber; the signing secret is only returned here",
+				Request:  models.CreateWebhookSubscriberRequest{},
+				Response: models.WebhookSubscriber{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/{subscriberID}", func(r *openapi.Router) {
+				r.Get("/", webhookHandlers.GetSubscriber, openapi.Doc{
+					Summary:  "Get subscriber by ID",
+					Response: models.WebhookSubscriber{},
+				})
+				r.Delete("/", webhookHandlers.DeleteSubscriber, openapi.Doc{Summary: "Delete subscriber"})
+				r.Post("/test", webhookHandlers.TestSubscriber, openapi.Doc{
+					Summary:  "Send a signed sample event and report the subscriber's response",
+					Response: models.WebhookDeliveryResult{},
+				})
+			})
+		})
+
+		// Just-in-time access request endpoints. These act on behalf of the
+		// caller, so they require a valid Okta access token.
+		r.Route("/access-requests", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", accessRequestHandlers.GetRequests, openapi.Doc{
+				Summary: "List access requests",
+				Query: []openapi.Param{
+					{Name: "status"}, {Name: "groupId"}, {Name: "requesterId"}, {Name: "approverId"},
+				},
+				Response: []models.AccessRequest{},
+			})
+			r.Post("/", accessRequestHandlers.CreateRequest, openapi.Doc{
+				Summary:  "Request membership in a protected group",
+				Request:  models.CreateAccessRequestRequest{},
+				Response: models.AccessRequest{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/protected-groups", func(r *openapi.Router) {
+				r.Get("/", accessRequestHandlers.GetProtectedGroups, openapi.Doc{
+					Summary:  "List protected groups",
+					Response: []models.ProtectedGroup{},
+				})
+
+				// Approvers of a protected group can grant its membership, so
+				// only admins may name them.
+				r.Group(func(r *openapi.Router) {
+					r.Secure(requireChangeAdmin(admins))
+
+					r.Put("/{groupID}", accessRequestHandlers.ProtectGroup, openapi.Doc{
+						Summary:  "Protect a group and set its approvers and maximum duration",
+						Request:  models.UpdateProtectedGroupRequest{},
+						Response: models.ProtectedGroup{},
+					})
+					r.Delete("/{groupID}", accessRequestHandlers.UnprotectGroup, openapi.Doc{
+						Summary: "Remove protection from a group",
+					})
+				})
+			})
+
+			r.Route("/{requestID}", func(r *openapi.Router) {
+				r.Get("/", accessRequestHandlers.GetRequest, openapi.Doc{
+					Summary:  "Get access request by ID",
+					Response: models.AccessRequest{},
+				})
+				r.Post("/approve", accessRequestHandlers.ApproveRequest, openapi.Doc{
+					Summary:  "Approve and grant the membership",
+					Request:  models.AccessRequestDecision{},
+					Response: models.AccessRequest{},
+				})
+				r.Post("/deny", accessRequestHandlers.DenyRequest, openapi.Doc{
+					Summary:  "Deny the request",
+					Request:  models.AccessRequestDecision{},
+					Response: models.AccessRequest{},
+				})
+			})
+		})
+
+		// The caller's own account. The user is taken from the access token,
+		// so portals need no admin token to serve their users.
+		r.Route("/me", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", selfServiceHandlers.GetProfile, openapi.Doc{
+				Summary:  "Get the caller's profile",
+				Response: models.User{},
+			})
+			r.Put("/", selfServiceHandlers.UpdateProfile, openapi.Doc{
+				Summary:     "Update the caller's profile",
+				Description: "Only the attributes in SELF_SERVICE_PROFILE_ATTRIBUTES can be changed.",
+				Request:     models.UpdateMyProfileRequest{},
+				Response:    models.User{},
+			})
+			r.Get("/groups", selfServiceHandlers.GetGroups, openapi.Doc{
+				Summary:  "List the caller's groups",
+				Response: []models.Group{},
+			})
+			r.Get("/factors", selfServiceHandlers.GetFactors, openapi.Doc{
+				Summary:  "List the sign-in factors the caller has enrolled",
+				Response: models.FactorEnrollment{},
+			})
+			r.Delete("/sessions", selfServiceHandlers.RevokeSessions, openapi.Doc{
+				Summary:     "Sign the caller out of every Okta session",
+				Description: "With oauthTokens, the tokens Okta issued the caller are revoked too.",
+				Request:     models.RevokeMySessionsRequest{},
+			})
+		})
+
+		// Membership changes to groups under the two-person rule, held until
+		// a second group admin approves them.
+		r.Route("/pending-changes", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), requireChangeAdmin(admins))
+
+			r.Get("/", pendingChangeHandlers.GetChanges, openapi.Doc{
+				Summary:  "List membership changes held for approval, newest first",
+				Query:    []openapi.Param{{Name: "status"}, {Name: "groupId"}},
+				Response: []models.PendingMembershipChange{},
+			})
+
+			r.Route("/protected-groups", func(r *openapi.Router) {
+				r.Get("/", pendingChangeHandlers.GetProtectedGroups, openapi.Doc{
+					Summary:  "List the groups under the two-person rule",
+					Response: []models.ChangeProtectedGroup{},
+				})
+				r.Put("/{groupID}", pendingChangeHandlers.ProtectGroup, openapi.Doc{
+					Summary:  "Hold the group's membership changes for a second admin's approval",
+					Response: models.ChangeProtectedGroup{},
+				})
+				r.Delete("/{groupID}", pendingChangeHandlers.UnprotectGroup, openapi.Doc{
+					Summary:     "Stop holding the group's membership changes",
+					Description: "Refused while the group has changes pending.",
+				})
+			})
+
+			r.Route("/{changeID}", func(r *openapi.Router) {
+				r.Get("/", pendingChangeHandlers.GetChange, openapi.Doc{
+					Summary:  "Get a held membership change",
+					Response: models.PendingMembershipChange{},
+				})
+				r.Post("/approve", pendingChangeHandlers.ApproveChange, openapi.Doc{
+					Summary:     "Approve and apply a held membership change",
+					Description: "The approver must be an admin other than the one who made the change.",
+					Request:     models.PendingChangeDecision{},
+					Response:    models.PendingMembershipChange{},
+				})
+				r.Post("/reject", pendingChangeHandlers.RejectChange, openapi.Doc{
+					Summary:     "Reject a held membership change",
+					Description: "The admin who made the change may reject it to withdraw it.",
+					Request:     models.PendingChangeDecision{},
+					Response:    models.PendingMembershipChange{},
+				})
+			})
+		})
+
+		// Live membership changes, user lifecycle actions and job progress.
+		r.Route("/events", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/stream", eventHandlers.Stream, openapi.Doc{
+				Summary: "Stream live IAM events as Server-Sent Events",
+				Description: "Each event's data is a StreamEvent. Membership changes come from the API as they are made " +
+					"and from the System Log as they are ingested, so a change made through the API is sent twice, " +
+					"once from each source. Send Last-Event-ID to resume after a disconnect; a stream.missed event " +
+					"says some events were lost. A client that falls behind is disconnected and should resume.",
+				Query: []openapi.Param{
+					{Name: "types", Description: "Comma separated: membership.added, membership.removed, user.lifecycle or job.progress"},
+				},
+				Response: models.StreamEvent{},
+				Produces: []string{"text/event-stream"},
+			})
+		})
+
+		// Figures for the ops dashboard, kept up to date in the background.
+		r.Route("/dashboard", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/", dashboardHandlers.GetDashboard, openapi.Doc{
+				Summary:  "Get every section of the ops dashboard",
+				Response: models.Dashboard{},
+			})
+			r.Get("/users", dashboardHandlers.GetUsers, openapi.Doc{
+				Summary:     "Count users by status",
+				Description: "Counted when the directory index is refreshed; empty until it is first built.",
+				Response:    models.DashboardUsers{},
+			})
+			r.Get("/groups", dashboardHandlers.GetGroups, openapi.Doc{
+				Summary:     "Count groups created and deleted in Okta by day",
+				Description: "Counted from the change feed since the server started, for the last DASHBOARD_DAYS days.",
+				Response:    models.DashboardGroups{},
+			})
+			r.Get("/mutations", dashboardHandlers.GetMutations, openapi.Doc{
+				Summary:     "Get the latest mutations made through this service",
+				Description: "Counts the mutations since the server started and lists the latest DASHBOARD_RECENT_MUTATIONS, newest first.",
+				Response:    models.DashboardMutations{},
+			})
+			r.Get("/okta", dashboardHandlers.GetOkta, openapi.Doc{
+				Summary:     "Get the Okta rate limit headroom and the depth of the work queues",
+				Description: "As of the last dashboard update, every DASHBOARD_UPDATE_INTERVAL.",
+				Response:    models.DashboardOkta{},
+			})
+		})
+
+		// Membership changes scheduled for a future time, such as a
+		// contractor's start or end date, applied by a background worker.
+		r.Route("/scheduled-changes", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/", scheduledChangeHandlers.GetChanges, openapi.Doc{
+				Summary: "List scheduled membership changes, the soonest to run first",
+				Query: []openapi.Param{
+					{Name: "status", Description: "SCHEDULED, RUNNING, SUCCEEDED, FAILED or CANCELLED"},
+					{Name: "groupId"},
+					{Name: "userId"},
+				},
+				Response: []models.ScheduledMembershipChange{},
+			})
+			r.Post("/", scheduledChangeHandlers.ScheduleChange, openapi.Doc{
+				Summary: "Schedule adding a user to or removing a user from a group",
+				Description: "A change that fails because Okta is unavailable is tried again with a growing wait. " +
+					"Groups under the two-person rule cannot have changes scheduled.",
+				Request:  models.ScheduleMembershipChangeRequest{},
+				Response: models.ScheduledMembershipChange{},
+			})
 
-			r.Route("/{roleID}", func(r chi.Router) {
-				r.Get("/", roleHandlers.GetRole)
-				r.Put("/", roleHandlers.UpdateRole)
-				r.Delete("/", roleHandlers.DeleteRole)
+			r.Route("/{changeID}", func(r *openapi.Router) {
+				r.Get("/", scheduledChangeHandlers.GetChange, openapi.Doc{
+					Summary:  "Get a scheduled membership change",
+					Response: models.ScheduledMembershipChange{},
+				})
+				r.Delete("/", scheduledChangeHandlers.CancelChange, openapi.Doc{
+					Summary:     "Cancel a scheduled membership change",
+					Description: "Only changes that have not run yet can be cancelled; they stay listed as CANCELLED.",
+					Response:    models.ScheduledMembershipChange{},
+				})
+			})
+		})
+
+		// Copying users, groups and memberships from one configured org to
+		// another, run as background jobs.
+		r.Route("/migrations", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/", migrationHandlers.GetMigrations, openapi.Doc{
+				Summary:  "List org migrations, newest first, without their plans",
+				Response: []models.Migration{},
+			})
+			r.Post("/", migrationHandlers.StartMigration, openapi.Doc{
+				Summary: "Start copying users, groups and memberships from one org to another",
+				Description: "Runs as a job with export, plan and import steps. Users are matched by login and " +
+					"groups by name. With dryRun the job stops once the plan is made; with the FAIL conflict " +
+					"strategy it stops there if anything already exists in the target org.",
+				Request:  models.MigrationRequest{},
+				Response: models.Migration{},
+				Status:   http.StatusAccepted,
+			})
+			r.Get("/{migrationID}", migrationHandlers.GetMigration, openapi.Doc{
+				Summary:  "Get an org migration with its plan and the outcome of every item",
+				Response: models.Migration{},
+			})
+		})
+
+		// Backups of groups, group rules, SoD policies and memberships, and
+		// restoring them after a misconfiguration.
+		r.Group(func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Post("/backup", backupHandlers.CreateBackup, openapi.Doc{
+				Summary: "Back up groups, join policies, memberships, group rules and SoD policies",
+				Description: "Writes a versioned, gzipped JSON archive to the configured storage (local disk or S3). " +
+					"Only Okta-mastered groups are backed up; users are referred to by ID.",
+				Request:  models.CreateBackupRequest{},
+				Response: models.Backup{},
+				Status:   http.StatusCreated,
+			})
+			r.Get("/backups", backupHandlers.GetBackups, openapi.Doc{
+				Summary:  "List backups, newest first",
+				Response: []models.Backup{},
+			})
+			r.Get("/backups/{backupID}", backupHandlers.GetBackup, openapi.Doc{
+				Summary:  "Get a backup's description and counts",
+				Response: models.Backup{},
+			})
+			r.Post("/restore", backupHandlers.RestoreBackup, openapi.Doc{
+				Summary: "Restore a backup, or preview the restore with dryRun",
+				Description: "Compares the backup with the org and applies the differences: missing groups, rules " +
+					"and policies are recreated and changed ones put back. Groups are matched by ID, then by name. " +
+					"Members who joined since the backup are only removed with removeExtraMembers. include limits " +
+					"the restore to GROUP, JOIN_POLICY, GROUP_RULE, SOD_POLICY or MEMBERSHIP changes.",
+				Request:  models.RestoreRequest{},
+				Response: models.Restore{},
+			})
+		})
+
+		// API keys for machine clients such as CI pipelines, which send them in
+		// X-API-Key instead of an access token. Users manage the keys they
+		// issued, with their own access token.
+		r.Route("/api-keys", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", apiKeyHandlers.GetKeys, openapi.Doc{
+				Summary:  "List the API keys the caller issued, revoked ones included",
+				Response: []models.APIKey{},
+			})
+			r.Post("/", apiKeyHandlers.IssueKey, openapi.Doc{
+				Summary: "Issue an API key with read or write scope",
+				Description: "The key is only returned in this response. Without expiresAt it lasts " +
+					"API_KEY_DEFAULT_TTL.",
+				Request:  models.CreateAPIKeyRequest{},
+				Response: models.IssuedAPIKey{},
+				Status:   http.StatusCreated,
+			})
+			r.Post("/{keyID}/rotate", apiKeyHandlers.RotateKey, openapi.Doc{
+				Summary:     "Replace the secret of an API key",
+				Description: "The old key keeps working for API_KEY_ROTATION_GRACE.",
+				Response:    models.IssuedAPIKey{},
+			})
+			r.Delete("/{keyID}", apiKeyHandlers.RevokeKey, openapi.Doc{
+				Summary:  "Revoke an API key",
+				Response: models.APIKey{},
+			})
+		})
+
+		// Self-service group catalog. Listings and joins are for the caller,
+		// so they require a valid Okta access token.
+		r.Route("/catalog/groups", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", catalogHandlers.GetGroups, openapi.Doc{
+				Summary:  "List the groups the caller can see, with the action available to them",
+				Response: []models.CatalogGroup{},
+			})
+			r.Post("/{groupID}/join", catalogHandlers.JoinGroup, openapi.Doc{
+				Summary:     "Join an OPEN group, or request access to an APPROVAL group",
+				Description: "While a group.join consent text is configured, consent must acknowledge its current version.",
+				Request:     models.JoinGroupRequest{},
+				Response:    models.JoinGroupResult{},
+			})
+		})
+
+		// Consent texts users acknowledge before self-service actions.
+		r.Route("/consents", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/texts", consentHandlers.GetTexts, openapi.Doc{
+				Summary:  "List the current consent texts of self-service actions",
+				Response: []models.ConsentText{},
+			})
+		})
+
+		// Hub-and-spoke org sync endpoints. Pushes are audited as made by the
+		// caller.
+		r.Route("/sync", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/spokes", syncHandlers.GetSpokes, openapi.Doc{
+				Summary:  "List configured spoke orgs",
+				Response: []string{},
+			})
+			r.Post("/push", syncHandlers.Push, openapi.Doc{
+				Summary:  "Push selected users and groups between the hub and a spoke",
+				Request:  models.SyncPushRequest{},
+				Response: models.SyncPushResult{},
+			})
+		})
+
+		// Separation-of-duties endpoints.
+		r.Route("/sod", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			}
+
+			r.Get("/violations", sodHandlers.GetViolations, openapi.Doc{
+				Summary:  "List users currently violating a policy",
+				Response: []models.SoDViolation{},
+			})
+
+			r.Route("/policies", func(r *openapi.Router) {
+				r.Get("/", sodHandlers.GetPolicies, openapi.Doc{
+					Summary:  "List SoD policies",
+					Response: []models.SoDPolicy{},
+				})
+				r.Post("/", sodHandlers.CreatePolicy, openapi.Doc{
+					Summary:  "Create a policy for an incompatible group pair",
+					Request:  models.CreateSoDPolicyRequest{},
+					Response: models.SoDPolicy{},
+					Status:   http.StatusCreated,
+				})
+				r.Get("/{policyID}", sodHandlers.GetPolicy, openapi.Doc{
+					Summary:  "Get policy by ID",
+					Response: models.SoDPolicy{},
+				})
+				r.Delete("/{policyID}", sodHandlers.DeletePolicy, openapi.Doc{Summary: "Delete policy"})
+			})
+		})
+
+		// Service account registry endpoints. Changes are attributed to the
+		// caller, so they require a valid Okta access token.
+		r.Route("/service-accounts", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", serviceAccountHandlers.GetServiceAccounts, openapi.Doc{
+				Summary:  "List service accounts",
+				Response: []models.ServiceAccount{},
+			})
+			r.Post("/", serviceAccountHandlers.CreateServiceAccount, openapi.Doc{
+				Summary:  "Create a service account; an app client's secret is only returned here",
+				Request:  models.CreateServiceAccountRequest{},
+				Response: models.ServiceAccount{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/{serviceAccountID}", func(r *openapi.Router) {
+				r.Get("/", serviceAccountHandlers.GetServiceAccount, openapi.Doc{
+					Summary:  "Get service account by ID",
+					Response: models.ServiceAccount{},
+				})
+				r.Delete("/", serviceAccountHandlers.DeleteServiceAccount, openapi.Doc{
+					Summary: "Delete the account and its Okta identity",
+				})
+				r.Post("/review", serviceAccountHandlers.ReviewServiceAccount, openapi.Doc{
+					Summary:  "Owner attests the account is still needed",
+					Response: models.ServiceAccount{},
+				})
+				r.Post("/rotate", serviceAccountHandlers.RotateCredential, openapi.Doc{
+					Summary:  "Owner starts a credential rotation; the new credential is only returned here",
+					Response: models.CredentialRotation{},
+					Status:   http.StatusAccepted,
+				})
+			})
+		})
+
+		// Guest lifecycle endpoints. The caller is the guest's sponsor, so
+		// these require a valid Okta access token.
+		r.Route("/guests", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", guestHandlers.GetGuests, openapi.Doc{
+				Summary:  "List guests",
+				Query:    []openapi.Param{{Name: "sponsorId"}, {Name: "status"}},
+				Response: []models.Guest{},
+			})
+			r.Post("/", guestHandlers.CreateGuest, openapi.Doc{
+				Summary:  "Create a guest sponsored by the caller",
+				Request:  models.CreateGuestRequest{},
+				Response: models.Guest{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/{guestID}", func(r *openapi.Router) {
+				r.Get("/", guestHandlers.GetGuest, openapi.Doc{Summary: "Get guest by ID", Response: models.Guest{}})
+				r.Delete("/", guestHandlers.OffboardGuest, openapi.Doc{
+					Summary: "Sponsor deactivates the guest early",
+				})
+				r.Post("/attest", guestHandlers.AttestGuest, openapi.Doc{
+					Summary:  "Sponsor re-attests, optionally moving expiresAt",
+					Request:  models.AttestGuestRequest{},
+					Response: models.Guest{},
+				})
+			})
+		})
+
+		// Invitation endpoints for admins. Changes are attributed to the
+		// caller, so they require a valid Okta access token.
+		r.Route("/invitations", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", invitationHandlers.GetInvitations, openapi.Doc{
+				Summary:  "List invitations",
+				Query:    []openapi.Param{{Name: "sponsorId"}, {Name: "status"}},
+				Response: []models.Invitation{},
+			})
+			r.Post("/", invitationHandlers.CreateInvitation, openapi.Doc{
+				Summary:  "Create an invitation; the link is only returned here",
+				Request:  models.CreateInvitationRequest{},
+				Response: models.Invitation{},
+				Status:   http.StatusCreated,
+			})
+
+			r.Route("/{invitationID}", func(r *openapi.Router) {
+				r.Get("/", invitationHandlers.GetInvitation, openapi.Doc{
+					Summary:  "Get invitation by ID",
+					Response: models.Invitation{},
+				})
+				r.Delete("/", invitationHandlers.RevokeInvitation, openapi.Doc{
+					Summary: "Revoke a pending invitation",
+				})
+			})
+		})
+
+		// Invitee pre-registration. The token in the invitation link is the
+		// only credential.
+		r.Route("/invite/{token}", func(r *openapi.Router) {
+			r.Get("/", invitationHandlers.PreviewInvitation, openapi.Doc{
+				Summary:  "Prefill the registration form",
+				Response: models.InvitationPreview{},
+			})
+			r.Post("/", invitationHandlers.AcceptInvitation, openapi.Doc{
+				Summary:  "Complete registration",
+				Request:  models.AcceptInvitationRequest{},
+				Response: models.InvitationPreview{},
+				Status:   http.StatusCreated,
+			})
+		})
+
+		// Device authorization grant for CLI tools and kiosk apps, which have
+		// no token yet; the device code is the only credential.
+		r.Route("/device", func(r *openapi.Router) {
+			r.Post("/authorize", deviceHandlers.StartAuthorization, openapi.Doc{
+				Summary:     "Start a device flow and get the code for the user to enter",
+				Description: "Poll /device/token with the device code every interval seconds until it ends.",
+				Request:     models.StartDeviceAuthorizationRequest{},
+				Response:    models.DeviceAuthorization{},
+			})
+			r.Post("/token", deviceHandlers.PollAuthorization, openapi.Doc{
+				Summary: "Poll a device flow for its status, and the tokens once the user approves",
+				Description: "The status is PENDING or SLOW_DOWN until the flow ends as APPROVED, DENIED " +
+					"or EXPIRED; wait interval seconds before polling again.",
+				Request:  models.PollDeviceAuthorizationRequest{},
+				Response: models.DeviceAuthorizationStatus{},
+			})
+		})
+
+		// Session token exchange for legacy apps using embedded login. The
+		// caller has no token yet; only allowed origins may call it.
+		r.Route("/sessions", func(r *openapi.Router) {
+			r.Use(sessionHandlers.CORS)
+			r.Post("/cookie-redirect", sessionHandlers.CookieRedirect, openapi.Doc{
+				Summary: "Exchange a session token for the URL that sets the Okta session cookie",
+				Description: "The Origin header and the redirect URL must both be on an allowed origin. " +
+					"Send the browser to the returned URL; Okta sets the cookie and redirects to redirectUrl.",
+				Request:  models.SessionCookieRedirectRequest{},
+				Response: models.SessionCookieRedirect{},
+			})
+		})
+
+		// Token exchange for internal services acting for a user. The caller
+		// authenticates as itself; the user's token is in the body.
+		r.Route("/oauth", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Post("/exchange", tokenExchangeHandlers.Exchange, openapi.Doc{
+				Summary: "Exchange a user's access token for a narrowly scoped token for another audience",
+				Description: "RFC 8693 token exchange at the Okta issuer. The calling service may only request " +
+					"the audiences and scopes of its policy; without scope, all of its scopes are requested.",
+				Request:  models.TokenExchangeRequest{},
+				Response: models.TokenExchangeResponse{},
+			})
+		})
+
+		// Read-only directory served from the local index, never from Okta.
+		r.Route("/directory", func(r *openapi.Router) {
+			const readOnly = "Read-only and served from the local index only, never from Okta. " +
+				"The index block and the Age header tell how old the data is."
+
+			queryParam := openapi.Param{Name: "q", Description: "Case-insensitive prefix to match"}
+			r.Get("/", directoryHandlers.GetIndex, openapi.Doc{
+				Summary:     "Freshness of the local directory index",
+				Description: readOnly,
+				Response:    models.DirectoryIndex{},
+			})
+			r.Get("/users", directoryHandlers.GetUsers, openapi.Doc{
+				Summary:     "List indexed users by login, email or name prefix",
+				Description: readOnly,
+				Query:       []openapi.Param{queryParam},
+				Response:    models.DirectoryUsers{},
+			})
+			r.Get("/users/{userID}", directoryHandlers.GetUser, openapi.Doc{
+				Summary:     "Get an indexed user by ID or login, with their groups",
+				Description: readOnly,
+				Response:    models.DirectoryUser{},
+			})
+			r.Get("/groups", directoryHandlers.GetGroups, openapi.Doc{
+				Summary:     "List indexed groups by name prefix",
+				Description: readOnly,
+				Query:       []openapi.Param{queryParam},
+				Response:    models.DirectoryGroups{},
+			})
+			r.Get("/groups/{groupID}", directoryHandlers.GetGroup, openapi.Doc{
+				Summary:     "Get an indexed group with its members",
+				Description: readOnly,
+				Response:    models.DirectoryGroup{},
+			})
+		})
+
+		// The user, group and role endpoints of every configured org.
+		registerOrgRoutes(r, cfg.Orgs, cfg, admins, pendingChangeHandlers.Hold)
+
+		// Default group memberships of new users, and the users missing them.
+		// Rules and remediations add users to groups, so with admin groups
+		// configured only admins may use them.
+		r.Route("/default-groups", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Route("/rules", func(r *openapi.Router) {
+				r.Get("/", defaultGroupHandlers.GetRules, openapi.Doc{
+					Summary:  "List the rules giving new users default groups by user type or department",
+					Response: []models.DefaultGroupRule{},
+				})
+				r.Post("/", defaultGroupHandlers.CreateRule, openapi.Doc{
+					Summary:  "Add a default group rule",
+					Request:  models.CreateDefaultGroupRuleRequest{},
+					Response: models.DefaultGroupRule{},
+					Status:   http.StatusCreated,
+				})
+				r.Delete("/{ruleID}", defaultGroupHandlers.DeleteRule, openapi.Doc{
+					Summary: "Delete a default group rule added through the API",
+				})
+			})
+			r.Get("/missing", defaultGroupHandlers.GetMissingDefaults, openapi.Doc{
+				Summary:  "Users who are not members of every default group the rules give them",
+				Response: []models.MissingDefaultGroups{},
+			})
+			r.Post("/remediations", defaultGroupHandlers.Remediate, openapi.Doc{
+				Summary:  "Start a job that adds users to the default groups they are missing",
+				Request:  models.RemediateDefaultGroupsRequest{},
+				Response: models.Job{},
+				Status:   http.StatusAccepted,
+			})
+		})
+
+		// Progressive profiling. Apps and groups require profile attributes,
+		// which the portal asks the caller for and writes to their profile.
+		r.Route("/profiling", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Route("/requirements", func(r *openapi.Router) {
+				r.Get("/", profilingHandlers.GetRequirements, openapi.Doc{
+					Summary:  "List the profile attributes apps and groups require of their users",
+					Response: []models.ProfileRequirement{},
+				})
+				r.Post("/", profilingHandlers.CreateRequirement, openapi.Doc{
+					Summary:  "Require a profile attribute of the users of apps or groups",
+					Request:  models.CreateProfileRequirementRequest{},
+					Response: models.ProfileRequirement{},
+					Status:   http.StatusCreated,
+				})
+				r.Delete("/{requirementID}", profilingHandlers.DeleteRequirement, openapi.Doc{
+					Summary: "Stop requiring a profile attribute",
+				})
+			})
+			r.Get("/missing", profilingHandlers.GetMissing, openapi.Doc{
+				Summary:  "List the profile attributes the caller is asked for",
+				Response: []models.MissingProfileAttribute{},
+			})
+			r.Post("/submissions", profilingHandlers.Submit, openapi.Doc{
+				Summary:     "Fill in profile attributes the caller is asked for",
+				Description: "Every attribute is validated before any is written; refused ones are listed in details.",
+				Request:     models.SubmitProfileAttributesRequest{},
+				Response:    models.ProfileSubmission{},
+			})
+		})
+
+		// Unused app access suggestions and their revocation.
+		r.Route("/unused-access", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			daysParam := openapi.Param{Name: "days", Description: "Usage window in days, at most 90"}
+			r.Get("/apps/{appID}", usageHandlers.GetAppUnusedAccess, openapi.Doc{
+				Summary:  "Users assigned to the app who have not signed in to it during the window",
+				Query:    []openapi.Param{daysParam},
+				Response: []models.UnusedAccess{},
+			})
+			r.Get("/users/{userID}", usageHandlers.GetUserUnusedAccess, openapi.Doc{
+				Summary:  "Apps assigned to the user that they have not signed in to during the window",
+				Query:    []openapi.Param{daysParam},
+				Response: []models.UnusedAccess{},
+			})
+			r.Post("/revocations", usageHandlers.RevokeUnusedAccess, openapi.Doc{
+				Summary:  "Start a job that unassigns unused app access",
+				Request:  models.RevokeUnusedAccessRequest{},
+				Response: models.Job{},
+				Status:   http.StatusAccepted,
+			})
+		})
+
+		// Ordered feed of every change for downstream sync consumers.
+		r.Get("/changes", changeHandlers.GetChanges, openapi.Doc{
+			Summary: "Changes made through this API or found in Okta, oldest first",
+			Description: "Successful mutations through this API and the user, group and membership changes " +
+				"the directory index refresh finds in Okta. Resume with the next cursor of the previous page; " +
+				"410 means changes after the cursor are no longer kept.",
+			Query: []openapi.Param{
+				{Name: "cursor", Description: "The next cursor of the previous page; omit to start at the oldest change kept"},
+				{Name: "limit", Description: "Changes per page, at most 1000; defaults to 100"},
+			},
+			Response: models.ChangeFeed{},
+		})
+
+		// Changes made in Okta directly rather than through this service.
+		r.Get("/drift", driftHandlers.GetReport, openapi.Doc{
+			Summary: "Out-of-band changes found in the Okta System Log, newest first",
+			Description: "Changes to users, groups, memberships, admin roles and app assignments that were " +
+				"neither made by an actor this service acts as nor matched by a mutation through this API.",
+			Query: []openapi.Param{
+				{Name: "since", Description: "RFC 3339 time the change was logged at or after"},
+				{Name: "until", Description: "RFC 3339 time the change was logged before"},
+				{Name: "eventType", Description: "System Log event type, such as group.user_membership.add"},
+				{Name: "actorId", Description: "Okta ID of the user or app that made the change"},
+			},
+			Response: models.DriftReport{},
+		})
+
+		// Brute-force and impossible-travel sign-ins found in the System Log,
+		// and locking down the accounts involved.
+		r.Route("/security", func(r *openapi.Router) {
+			if admins != nil {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireAdmin)
+			} else {
+				r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+			}
+
+			r.Get("/alerts", securityHandlers.GetAlerts, openapi.Doc{
+				Summary: "Brute-force and impossible-travel sign-in alerts, newest first",
+				Description: "BRUTE_FORCE alerts count failed sign-ins for one user or from one IP address within " +
+					"the configured window; IMPOSSIBLE_TRAVEL alerts compare the places and times of a user's " +
+					"successful sign-ins. With SECURITY_AUTO_SUSPEND, the user an alert names is suspended.",
+				Query: []openapi.Param{
+					{Name: "type", Description: "BRUTE_FORCE or IMPOSSIBLE_TRAVEL"},
+					{Name: "userId"},
+					{Name: "ipAddress"},
+					{Name: "since", Description: "RFC 3339 time the alert was raised at or after"},
+					{Name: "until", Description: "RFC 3339 time the alert was raised before"},
+				},
+				Response: models.SecurityAlertList{},
+			})
+
+			// Break-glass containment of compromised accounts.
+			r.Post("/lockdown", securityHandlers.Lockdown, openapi.Doc{
+				Summary: "Suspend users or a group's members, revoke their sessions and optionally reset their factors",
+				Description: "Users who fail are reported without stopping the others. The lockdown and the outcome " +
+					"for each user are audited under the incident ID; 409 means the incident is already locked down.",
+				Request:  models.LockdownRequest{},
+				Response: models.Lockdown{},
+			})
+			r.Post("/unlock", securityHandlers.Unlock, openapi.Doc{
+				Summary: "Unsuspend the users an incident's lockdown suspended",
+				Description: "Revoked sessions and reset factors are not restored. The lockdown stays LOCKED while " +
+					"any user fails to unsuspend; call again to retry them.",
+				Request:  models.UnlockRequest{},
+				Response: models.Lockdown{},
+			})
+		})
+
+		r.Get("/membership-events", membershipEventHandlers.GetEvents, openapi.Doc{
+			Summary: "Group membership changes copied from the Okta System Log, newest first",
+			Description: "Includes changes made outside this service, and is kept longer than the System Log. " +
+				"With userId, groupId and action=ADDED, the actor answers who added the user to the group.",
+			Query: []openapi.Param{
+				{Name: "groupId"},
+				{Name: "userId"},
+				{Name: "actorId", Description: "Okta ID of the user or app that made the change"},
+				{Name: "action", Description: "ADDED or REMOVED"},
+				{Name: "since", Description: "RFC 3339 time the change was logged at or after"},
+				{Name: "until", Description: "RFC 3339 time the change was logged before"},
+			},
+			Response: models.MembershipEventList{},
+		})
+
+		// Mutations queued while Okta was unavailable.
+		r.Route("/retry-queue", func(r *openapi.Router) {
+			r.Get("/", retryHandlers.GetEntries, openapi.Doc{
+				Summary:  "List queued mutations, oldest first",
+				Query:    []openapi.Param{{Name: "status"}, {Name: "route"}},
+				Response: []models.RetryEntry{},
+			})
+
+			r.Route("/{entryID}", func(r *openapi.Router) {
+				r.Get("/", retryHandlers.GetEntry, openapi.Doc{
+					Summary:  "Get a queued mutation",
+					Response: models.RetryEntry{},
+				})
+				r.Post("/retry", retryHandlers.RetryEntry, openapi.Doc{
+					Summary:     "Replay a queued mutation now",
+					Description: "Its attempts are reset. A mutation that succeeds is no longer queued.",
+					Response:    models.RetryEntry{},
+				})
+				r.Delete("/", retryHandlers.DiscardEntry, openapi.Doc{Summary: "Discard a queued mutation"})
+			})
+		})
+
+		// Failed requests captured with their Okta interactions, for
+		// debugging offline.
+		r.Route("/replays", func(r *openapi.Router) {
+			r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier))
+
+			r.Get("/", replayHandlers.GetCaptures, openapi.Doc{
+				Summary:  "List captured failed requests, newest first",
+				Response: []models.ReplayCaptureSummary{},
+			})
+			r.Post("/", replayHandlers.ImportFixture, openapi.Doc{
+				Summary:     "Import a fixture exported from another server as a capture",
+				Description: "Import production captures into a standalone server to replay them there.",
+				Request:     models.ReplayFixture{},
+				Response:    models.ReplayCapture{},
+				Status:      http.StatusCreated,
+			})
+
+			r.Route("/{captureID}", func(r *openapi.Router) {
+				r.Get("/", replayHandlers.GetCapture, openapi.Doc{
+					Summary:  "Get a capture with its request, response and Okta interactions",
+					Response: models.ReplayCapture{},
+				})
+				r.Get("/fixture", replayHandlers.ExportFixture, openapi.Doc{
+					Summary:     "Download the capture as a fixture file",
+					Description: "The import endpoint and oktamock.NewReplay take the file as is.",
+				})
+				r.Post("/replay", replayHandlers.RunCapture, openapi.Doc{
+					Summary: "Replay the captured request with Okta answered from its recorded interactions",
+					Description: "Only a standalone server, running with OKTA_BACKEND=memory, replays captures, " +
+						"so nothing the replay changes leaves the process.",
+					Response: models.ReplayResult{},
+				})
+				r.Delete("/", replayHandlers.DeleteCapture, openapi.Doc{Summary: "Delete a capture"})
+			})
+		})
+
+		// Background job endpoints.
+		r.Route("/jobs", func(r *openapi.Router) {
+			r.Get("/", jobHandlers.GetJobs, openapi.Doc{
+				Summary:  "List background jobs, newest first",
+				Query:    []openapi.Param{{Name: "type"}, {Name: "status"}, {Name: "resourceId"}},
+				Response: []models.Job{},
+			})
+			r.Get("/{jobID}", jobHandlers.GetJob, openapi.Doc{
+				Summary:  "Get a job and the status of each of its steps",
+				Response: models.Job{},
 			})
 		})
 	})
+
+	return spec
+}
+
+// decodingMode returns the configured mode, or the version's default when
+// none is configured.
+func decodingMode(configured, versionDefault request.Mode) request.Mode {
+	if configured != "" {
+		return configured
+	}
+	return versionDefault
 }
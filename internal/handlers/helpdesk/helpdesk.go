@@ -0,0 +1,108 @@
+package helpdesk_handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	helpdesk_service "github.com/iamBelugaa/iam/internal/services/helpdesk"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log         *zap.SugaredLogger
+	helpdeskSvc *helpdesk_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *helpdesk_service.Service) *Handler {
+	return &Handler{log: log, helpdeskSvc: svc}
+}
+
+func (h *Handler) ExpirePassword(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, "Expire password", h.helpdeskSvc.ExpirePassword, "Password expired successfully", "Failed to expire password")
+}
+
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, "Reset password", h.helpdeskSvc.ResetPassword, "Password reset email sent successfully", "Failed to reset password")
+}
+
+func (h *Handler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	h.run(w, r, "Unlock user", h.helpdeskSvc.Unlock, "User unlocked successfully", "Failed to unlock user")
+}
+
+func (h *Handler) IssueTemporaryPassword(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	actor := actor(r)
+	logger.FromContext(r.Context(), h.log).Infow("Issue temporary password request received", "userId", userID, "actor", actor)
+
+	tempPassword, err := h.helpdeskSvc.IssueTemporaryPassword(r.Context(), actor, userID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to issue temporary password")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Temporary password issued successfully", tempPassword)
+}
+
+// run serves an operation on the user in the path that returns nothing.
+func (h *Handler) run(
+	w http.ResponseWriter, r *http.Request, operation string,
+	fn func(ctx context.Context, actor, userID string) error, success, failure string,
+) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	actor := actor(r)
+	logger.FromContext(r.Context(), h.log).Infow(operation+" request received", "userId", userID, "actor", actor)
+
+	if err := fn(r.Context(), actor, userID); err != nil {
+		h.handleServiceError(w, r, err, failure)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, success, nil)
+}
+
+// actor names the agent in the audit log: the user of the token, or the
+// client when it acts as itself.
+func actor(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	switch {
+	case !ok:
+		return ""
+	case caller.UserID == "" && caller.ClientID != "":
+		return "client:" + caller.ClientID
+	default:
+		return caller.UserID
+	}
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, user_service.ErrUserNotFound):
+		h.respondWithError(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, user_service.ErrStatusNotAllowed):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,177 @@
+package invitation_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	invitation_service "github.com/iamBelugaa/iam/internal/services/invitation"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log            *zap.SugaredLogger
+	invitationsSvc *invitation_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *invitation_service.Service) *Handler {
+	return &Handler{log: log, invitationsSvc: svc}
+}
+
+func (h *Handler) CreateInvitation(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateInvitationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create invitation request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Create invitation request received", "email", req.Email, "actor", caller.UserID)
+
+	if req.Email == "" {
+		h.respondWithError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		h.respondWithError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.invitationsSvc.CreateInvitation(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create invitation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Invitation created successfully", invitation)
+}
+
+func (h *Handler) GetInvitations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.InvitationFilter{
+		SponsorID: query.Get("sponsorId"),
+		Status:    strings.ToUpper(query.Get("status")),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get invitations request received", "sponsorId", filter.SponsorID, "status", filter.Status)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.invitationsSvc.GetInvitations(r.Context(), &filter))
+}
+
+func (h *Handler) GetInvitation(w http.ResponseWriter, r *http.Request) {
+	invitationID := chi.URLParam(r, "invitationID")
+	if invitationID == "" {
+		h.respondWithError(w, "Invitation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.invitationsSvc.GetInvitation(r.Context(), invitationID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve invitation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", invitation)
+}
+
+func (h *Handler) RevokeInvitation(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	invitationID := chi.URLParam(r, "invitationID")
+	if invitationID == "" {
+		h.respondWithError(w, "Invitation ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Revoke invitation request received", "invitationId", invitationID, "actor", caller.UserID)
+
+	if err := h.invitationsSvc.RevokeInvitation(r.Context(), caller.UserID, invitationID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to revoke invitation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Invitation revoked successfully", nil)
+}
+
+// PreviewInvitation is called by the registration form to prefill it. The
+// token in the link is the only credential, so this route is public.
+func (h *Handler) PreviewInvitation(w http.ResponseWriter, r *http.Request) {
+	preview, err := h.invitationsSvc.PreviewInvitation(r.Context(), chi.URLParam(r, "token"))
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve invitation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", preview)
+}
+
+// AcceptInvitation completes pre-registration from the invitee's form.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req models.AcceptInvitationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode accept invitation request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invitation, err := h.invitationsSvc.AcceptInvitation(r.Context(), chi.URLParam(r, "token"), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to accept invitation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Registration completed successfully", &models.InvitationPreview{
+		Email:     invitation.Email,
+		FirstName: invitation.FirstName,
+		LastName:  invitation.LastName,
+		ExpiresAt: invitation.ExpiresAt,
+	})
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, invitation_service.ErrInvitationNotFound):
+		h.respondWithError(w, "Invitation not found", http.StatusNotFound)
+	case errors.Is(err, invitation_service.ErrInvitationExpired):
+		h.respondWithError(w, err.Error(), http.StatusGone)
+	case errors.Is(err, invitation_service.ErrInvitationUsed):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, invitation_service.ErrExpiryTooFar),
+		errors.Is(err, invitation_service.ErrNameRequired):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, invitation_service.ErrInvalidSponsor):
+		h.respondWithError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,61 @@
+package job_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log     *zap.SugaredLogger
+	jobsSvc *job_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *job_service.Service) *Handler {
+	return &Handler{log: log, jobsSvc: svc}
+}
+
+func (h *Handler) GetJobs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.JobFilter{
+		Type:       query.Get("type"),
+		Status:     query.Get("status"),
+		ResourceID: query.Get("resourceId"),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get jobs request received", "type", filter.Type, "status", filter.Status)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.jobsSvc.GetJobs(r.Context(), &filter))
+}
+
+func (h *Handler) GetJob(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "jobID")
+	if jobID == "" {
+		h.respondWithError(w, "Job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	job, err := h.jobsSvc.GetJob(r.Context(), jobID)
+	if err != nil {
+		if errors.Is(err, job_service.ErrJobNotFound) {
+			h.respondWithError(w, "Job not found", http.StatusNotFound)
+			return
+		}
+
+		logger.FromContext(r.Context(), h.log).Infow("Failed to retrieve job", zap.Error(err), "jobId", jobID)
+		h.respondWithError(w, "Failed to retrieve job", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", job)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,73 @@
+package membershipevent_handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	membershipevent_service "github.com/iamBelugaa/iam/internal/services/membershipevent"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	eventsSvc *membershipevent_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *membershipevent_service.Service) *Handler {
+	return &Handler{log: log, eventsSvc: svc}
+}
+
+func (h *Handler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &models.MembershipEventFilter{
+		GroupID: query.Get("groupId"),
+		UserID:  query.Get("userId"),
+		ActorID: query.Get("actorId"),
+		Action:  query.Get("action"),
+	}
+
+	if filter.Action != "" && filter.Action != models.MembershipEventAdded &&
+		filter.Action != models.MembershipEventRemoved {
+		h.respondWithError(w, "action must be ADDED or REMOVED", http.StatusBadRequest)
+		return
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.respondWithError(w, param.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*param.value = parsed
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get membership events request received",
+		"groupId", filter.GroupID, "userId", filter.UserID, "actorId", filter.ActorID, "action", filter.Action,
+		"since", filter.Since, "until", filter.Until,
+	)
+
+	events, err := h.eventsSvc.GetEvents(r.Context(), filter)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to list membership events", zap.Error(err))
+		h.respondWithError(w, "Failed to retrieve membership events", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", events)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,103 @@
+package migration_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	migration_service "github.com/iamBelugaa/iam/internal/services/migration"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log          *zap.SugaredLogger
+	migrationSvc *migration_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *migration_service.Service) *Handler {
+	return &Handler{log: log, migrationSvc: svc}
+}
+
+func (h *Handler) GetMigrations(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get migrations request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.migrationSvc.Migrations(r.Context()))
+}
+
+func (h *Handler) StartMigration(w http.ResponseWriter, r *http.Request) {
+	var req models.MigrationRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode migration request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.ConflictStrategy = strings.ToUpper(req.ConflictStrategy)
+
+	if req.SourceOrg == "" || req.TargetOrg == "" {
+		h.respondWithError(w, "Source and target org are required", http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Start migration request received",
+		"sourceOrg", req.SourceOrg, "targetOrg", req.TargetOrg, "dryRun", req.DryRun, "actor", actor,
+	)
+
+	migration, err := h.migrationSvc.Start(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to start migration")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusAccepted, "Migration started", migration)
+}
+
+func (h *Handler) GetMigration(w http.ResponseWriter, r *http.Request) {
+	migrationID := chi.URLParam(r, "migrationID")
+	logger.FromContext(r.Context(), h.log).Infow("Get migration request received", "migrationId", migrationID)
+
+	migration, err := h.migrationSvc.Migration(r.Context(), migrationID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve migration")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", migration)
+}
+
+// actorFromRequest is the user who made the request, or the client when the
+// access token does not identify a user.
+func actorFromRequest(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if caller.UserID != "" {
+		return caller.UserID
+	}
+	return caller.ClientID
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, migration_service.ErrMigrationNotFound),
+		errors.Is(err, migration_service.ErrUnknownOrg):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, migration_service.ErrSameOrg),
+		errors.Is(err, migration_service.ErrInvalidConflict):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
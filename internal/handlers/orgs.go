@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	group_handlers "github.com/iamBelugaa/iam/internal/handlers/group"
+	role_handlers "github.com/iamBelugaa/iam/internal/handlers/role"
+	user_handlers "github.com/iamBelugaa/iam/internal/handlers/user"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/internal/orgs"
+	"github.com/iamBelugaa/iam/pkg/openapi"
+	"github.com/iamBelugaa/iam/pkg/patch"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// orgScopedPrefixes are the API paths that can be served for any org.
+var orgScopedPrefixes = []string{"/users", "/groups", "/roles"}
+
+// orgHandlers are the handlers of one org.
+type orgHandlers struct {
+	users  *user_handlers.Handler
+	groups *group_handlers.Handler
+	roles  *role_handlers.Handler
+}
+
+// selectOrg routes requests that name a non-primary org in the X-Okta-Org
+// header to the same endpoint under /orgs/{org}. Endpoints that are not
+// org-scoped reject the header rather than silently serving the primary org.
+func selectOrg(primary string) func(http.Handler) http.Handler {
+	orgsPrefix := APIVersion1URL + "/orgs/"
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			org := r.Header.Get(orgs.Header)
+			if org == "" || org == primary || strings.HasPrefix(r.URL.Path, orgsPrefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			path, ok := strings.CutPrefix(r.URL.Path, APIVersion1URL)
+			if !ok || !isOrgScoped(path) {
+				response.RespondError(w, http.StatusBadRequest, "API_ERROR",
+					orgs.Header+" is only supported on user, group and role endpoints", nil,
+				)
+				return
+			}
+
+			r.URL.Path = orgsPrefix + url.PathEscape(org) + path
+			r.URL.RawPath = ""
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isOrgScoped(path string) bool {
+	for _, prefix := range orgScopedPrefixes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// registerOrgRoutes serves the user, group and role endpoints of every
+// configured org under /orgs/{org}.
+func registerOrgRoutes(
+	r *openapi.Router, registry *orgs.Registry, cfg *Config, admins *groupAdmins, hold func(http.Handler) http.Handler,
+) {
+	handlersByOrg := make(map[string]*orgHandlers)
+	orgList := make([]*models.Org, 0)
+
+	if registry != nil {
+		for _, org := range registry.All() {
+			handlersByOrg[org.Name] = &orgHandlers{
+				users:  user_handlers.New(cfg.Log, org.Users),
+				groups: group_handlers.New(cfg.Log, org.Groups, nil, nil),
+				roles:  role_handlers.New(cfg.Log, org.Roles),
+			}
+			orgList = append(orgList, &models.Org{
+				Name:    org.Name,
+				Domain:  org.Domain,
+				Primary: org.Name == registry.Primary(),
+			})
+		}
+	}
+
+	byOrg := func(serve func(h *orgHandlers, w http.ResponseWriter, r *http.Request)) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			h, ok := handlersByOrg[chi.URLParam(r, "org")]
+			if !ok {
+				response.RespondError(w, http.StatusNotFound, "API_ERROR", "Org not found", nil)
+				return
+			}
+			serve(h, w, r)
+		}
+	}
+	users := func(fn func(*user_handlers.Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+		return byOrg(func(h *orgHandlers, w http.ResponseWriter, r *http.Request) { fn(h.users, w, r) })
+	}
+	groups := func(fn func(*group_handlers.Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+		return byOrg(func(h *orgHandlers, w http.ResponseWriter, r *http.Request) { fn(h.groups, w, r) })
+	}
+	roles := func(fn func(*role_handlers.Handler, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+		return byOrg(func(h *orgHandlers, w http.ResponseWriter, r *http.Request) { fn(h.roles, w, r) })
+	}
+
+	r.Route("/orgs", func(r *openapi.Router) {
+		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+			response.RespondSuccess(w, http.StatusOK, "Success", orgList)
+		}, openapi.Doc{Summary: "List configured Okta orgs", Response: []models.Org{}})
+
+		r.Route("/{org}", func(r *openapi.Router) {
+			r.Route("/users", func(r *openapi.Router) {
+				r.Get("/", users((*user_handlers.Handler).GetUsers), openapi.Doc{
+					Summary:  "List all users of an org",
+					Query:    []openapi.Param{streamParam, fieldsParam},
+					Response: []models.User{},
+					Produces: []string{ndjson},
+				})
+				r.Post("/", users((*user_handlers.Handler).CreateUser), openapi.Doc{
+					Summary:  "Create new user in an org",
+					Request:  models.CreateUserRequest{},
+					Response: models.User{},
+					Status:   http.StatusCreated,
+				})
+
+				r.Route("/{userID}", func(r *openapi.Router) {
+					r.Get("/", users((*user_handlers.Handler).GetUser), openapi.Doc{
+						Summary:  "Get user of an org by ID",
+						Response: models.User{},
+					})
+					r.Put("/", users((*user_handlers.Handler).UpdateUser), openapi.Doc{
+						Summary:  "Update user of an org",
+						Request:  models.UpdateUserRequest{},
+						Response: models.User{},
+					})
+					r.Patch("/", users((*user_handlers.Handler).PatchUser), openapi.Doc{
+						Summary:  "Change some of the fields of a user of an org",
+						Response: models.User{},
+						Consumes: []string{patch.MergePatch, patch.JSONPatch},
+					})
+					r.Delete("/", users((*user_handlers.Handler).DeleteUser), openapi.Doc{Summary: "Delete user of an org"})
+
+					r.Post("/activate", users((*user_handlers.Handler).ActivateUser), openapi.Doc{
+						Summary: "Activate user of an org",
+					})
+					r.Post("/deactivate", users((*user_handlers.Handler).DeactivateUser), openapi.Doc{
+						Summary: "Deactivate user of an org",
+					})
+					r.Post("/suspend", users((*user_handlers.Handler).SuspendUser), openapi.Doc{
+						Summary: "Suspend user of an org",
+					})
+					r.Post("/unsuspend", users((*user_handlers.Handler).UnSuspendUser), openapi.Doc{
+						Summary: "Unsuspend user of an org",
+					})
+
+					r.Route("/roles", func(r *openapi.Router) {
+						r.Get("/", roles((*role_handlers.Handler).GetUserRoles), openapi.Doc{
+							Summary:  "Get roles of a user of an org",
+							Response: []models.Role{},
+						})
+						r.Put("/{roleID}", roles((*role_handlers.Handler).AssignRoleToUser), openapi.Doc{
+							Summary: "Assign a role to a user of an org",
+						})
+						r.Delete("/{roleID}", roles((*role_handlers.Handler).UnassignRoleFromUser), openapi.Doc{
+							Summary: "Unassign a role from a user of an org",
+						})
+					})
+				})
+			})
+
+			r.Route("/groups", func(r *openapi.Router) {
+				r.Get("/", groups((*group_handlers.Handler).GetGroups), openapi.Doc{
+					Summary:  "List all groups of an org",
+					Query:    append([]openapi.Param{streamParam, fieldsParam}, groupSortParams...),
+					Response: []models.Group{},
+					Produces: []string{ndjson},
+				})
+				r.Post("/", groups((*group_handlers.Handler).CreateGroup), openapi.Doc{
+					Summary:  "Create new group in an org",
+					Request:  models.CreateGroupRequest{},
+					Response: models.Group{},
+					Status:   http.StatusCreated,
+				})
+
+				r.Route("/{groupID}", func(r *openapi.Router) {
+					r.Get("/", groups((*group_handlers.Handler).GetGroup), openapi.Doc{
+						Summary:  "Get group of an org by ID",
+						Response: models.Group{},
+					})
+					r.Put("/", groups((*group_handlers.Handler).UpdateGroup), openapi.Doc{
+						Summary:  "Update group of an org",
+						Request:  models.UpdateGroupRequest{},
+						Response: models.Group{},
+					})
+					r.Patch("/", groups((*group_handlers.Handler).PatchGroup), openapi.Doc{
+						Summary:  "Change some of the fields of a group of an org",
+						Response: models.Group{},
+						Consumes: []string{patch.MergePatch, patch.JSONPatch},
+					})
+					r.Delete("/", groups((*group_handlers.Handler).DeleteGroup), openapi.Doc{
+						Summary: "Delete group of an org",
+					})
+
+					r.Route("/members", func(r *openapi.Router) {
+						r.Get("/", groups((*group_handlers.Handler).GetGroupMembers), openapi.Doc{
+							Summary: "Get group members in an org",
+							Query: append([]openapi.Param{
+								{Name: "includeExpiry", Description: "Add the expiry of time-bound memberships"},
+								streamParam,
+							}, memberSortParams...),
+							Response: []models.GroupMember{},
+							Produces: []string{ndjson},
+						})
+
+						// Groups of other orgs have no owners, so with admin
+						// groups configured only admins may change their members.
+						// The primary org is served here too, so changes to its
+						// protected groups wait for approval as they do under
+						// /groups.
+						r.Route("/{userID}", func(r *openapi.Router) {
+							if admins != nil {
+								r.Secure(auth.Authenticate(cfg.Log, cfg.Verifier), admins.requireOwner)
+							}
+							r.Use(hold)
+
+							r.Put("/", groups((*group_handlers.Handler).AddUserToGroup), openapi.Doc{
+								Summary: "Add user to group in an org, optionally until expiresAt",
+								Description: "For a protected group, the change is held for a second admin's approval " +
+									"and a PendingMembershipChange is returned with 202.",
+								Request: models.AddGroupMemberRequest{},
+							})
+							r.Delete("/", groups((*group_handlers.Handler).RemoveUserFromGroup), openapi.Doc{
+								Summary: "Remove user from group in an org",
+								Description: "For a protected group, the change is held for a second admin's approval " +
+									"and a PendingMembershipChange is returned with 202.",
+							})
+						})
+					})
+
+					r.Route("/roles", func(r *openapi.Router) {
+						r.Get("/", roles((*role_handlers.Handler).GetGroupRoles), openapi.Doc{
+							Summary:  "Get roles of a group of an org",
+							Response: []models.Role{},
+						})
+						r.Put("/{roleID}", roles((*role_handlers.Handler).AssignRoleToGroup), openapi.Doc{
+							Summary: "Assign a role to a group of an org",
+						})
+						r.Delete("/{roleID}", roles((*role_handlers.Handler).UnassignRoleFromGroup), openapi.Doc{
+							Summary: "Unassign a role from a group of an org",
+						})
+					})
+				})
+			})
+
+			r.Route("/roles", func(r *openapi.Router) {
+				r.Get("/", roles((*role_handlers.Handler).GetRoles), openapi.Doc{
+					Summary:  "List all roles of an org",
+					Response: []models.Role{},
+				})
+				r.Post("/", roles((*role_handlers.Handler).CreateRole), openapi.Doc{
+					Summary:  "Create new role in an org",
+					Request:  models.CreateRoleRequest{},
+					Response: models.Role{},
+					Status:   http.StatusCreated,
+				})
+
+				r.Route("/{roleID}", func(r *openapi.Router) {
+					r.Get("/", roles((*role_handlers.Handler).GetRole), openapi.Doc{
+						Summary:  "Get role of an org by ID",
+						Response: models.Role{},
+					})
+					r.Put("/", roles((*role_handlers.Handler).UpdateRole), openapi.Doc{
+						Summary:  "Update role of an org",
+						Request:  models.UpdateRoleRequest{},
+						Response: models.Role{},
+					})
+					r.Delete("/", roles((*role_handlers.Handler).DeleteRole), openapi.Doc{
+						Summary: "Delete role of an org",
+					})
+				})
+			})
+		})
+	})
+}
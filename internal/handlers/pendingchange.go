@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// requireChangeAdmin lets only group admins protect groups and decide on
+// their pending membership changes. Unlike the membership routes, it lets no
+// one through when no admin groups are configured, as the two-person rule
+// needs admins to approve. It runs after auth.Authenticate.
+func requireChangeAdmin(admins *groupAdmins) func(http.Handler) http.Handler {
+	if admins != nil {
+		return admins.requireAdmin
+	}
+	return func(http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.RespondError(w, http.StatusForbidden, "FORBIDDEN", "Only group admins may do this", nil)
+		})
+	}
+}
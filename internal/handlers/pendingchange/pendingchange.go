@@ -0,0 +1,252 @@
+package pendingchange_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	changesSvc *pendingchange_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *pendingchange_service.Service) *Handler {
+	return &Handler{log: log, changesSvc: svc}
+}
+
+func (h *Handler) GetProtectedGroups(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get change protected groups request received")
+
+	groups, err := h.changesSvc.ProtectedGroups(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve protected groups")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", groups)
+}
+
+func (h *Handler) ProtectGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID := chi.URLParam(r, "groupID")
+	logger.FromContext(r.Context(), h.log).Infow("Protect group changes request received", "groupId", groupID, "actor", caller.UserID)
+
+	group, err := h.changesSvc.Protect(r.Context(), caller.UserID, groupID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to protect group")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group protected successfully", group)
+}
+
+func (h *Handler) UnprotectGroup(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	groupID := chi.URLParam(r, "groupID")
+	logger.FromContext(r.Context(), h.log).Infow("Unprotect group changes request received", "groupId", groupID, "actor", caller.UserID)
+
+	if err := h.changesSvc.Unprotect(r.Context(), caller.UserID, groupID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to unprotect group")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Group protection removed successfully", nil)
+}
+
+func (h *Handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.PendingChangeFilter{
+		Status:  strings.ToUpper(query.Get("status")),
+		GroupID: query.Get("groupId"),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get pending changes request received", "status", filter.Status, "groupId", filter.GroupID)
+
+	changes, err := h.changesSvc.Changes(r.Context(), &filter)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve pending changes")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", changes)
+}
+
+func (h *Handler) GetChange(w http.ResponseWriter, r *http.Request) {
+	changeID := chi.URLParam(r, "changeID")
+	logger.FromContext(r.Context(), h.log).Infow("Get pending change request received", "pendingChangeId", changeID)
+
+	change, err := h.changesSvc.Change(r.Context(), changeID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve pending change")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", change)
+}
+
+func (h *Handler) ApproveChange(w http.ResponseWriter, r *http.Request) {
+	h.decideChange(w, r, true)
+}
+
+func (h *Handler) RejectChange(w http.ResponseWriter, r *http.Request) {
+	h.decideChange(w, r, false)
+}
+
+func (h *Handler) decideChange(w http.ResponseWriter, r *http.Request, approve bool) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	changeID := chi.URLParam(r, "changeID")
+	logger.FromContext(r.Context(), h.log).Infow("Pending change decision received",
+		"pendingChangeId", changeID, "approverId", caller.UserID, "approve", approve,
+	)
+
+	var decision models.PendingChangeDecision
+	if err := request.Decode(r, &decision); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode pending change decision", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		change *models.PendingMembershipChange
+		err    error
+	)
+	if approve {
+		change, err = h.changesSvc.Approve(r.Context(), caller.UserID, changeID, &decision)
+	} else {
+		change, err = h.changesSvc.Reject(r.Context(), caller.UserID, changeID, &decision)
+	}
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to decide on pending change")
+		return
+	}
+
+	message := "Pending change rejected"
+	if approve {
+		message = "Pending change approved and applied"
+	}
+	response.RespondSuccess(w, http.StatusOK, message, change)
+}
+
+// Hold answers membership changes to protected groups with a pending change
+// instead of making them, and passes changes to other groups through. It
+// wraps the routes that add a user to and remove a user from a group.
+func (h *Handler) Hold(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		groupID := chi.URLParam(r, "groupID")
+		userID := chi.URLParam(r, "userID")
+
+		protected, err := h.changesSvc.IsProtected(r.Context(), groupID)
+		if err != nil {
+			h.handleServiceError(w, r, err, "Failed to check group protection")
+			return
+		}
+		if !protected {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		operation := models.PendingChangeOperationRemove
+		var req models.AddGroupMemberRequest
+		if r.Method == http.MethodPut {
+			operation = models.PendingChangeOperationAdd
+			if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+				logger.FromContext(r.Context(), h.log).Infow("Failed to decode add user to group request", zap.Error(err))
+				h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var requester string
+		if caller, ok := auth.CallerFromContext(r.Context()); ok {
+			requester = caller.UserID
+		}
+
+		logger.FromContext(r.Context(), h.log).Infow("Holding membership change to protected group",
+			"groupId", groupID, "userId", userID, "operation", operation, "requestedBy", requester,
+		)
+
+		change, err := h.changesSvc.Propose(r.Context(), requester, groupID, userID, operation, req.ExpiresAt)
+		if err != nil {
+			h.handleServiceError(w, r, err, "Failed to hold membership change")
+			return
+		}
+
+		response.RespondSuccess(w, http.StatusAccepted, "Membership change is pending approval by a second admin", change)
+	})
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var violationErr *sod_service.ViolationError
+	if errors.As(err, &violationErr) {
+		response.RespondError(w, http.StatusConflict, "SOD_VIOLATION", violationErr.Error(), violationErr.Violations)
+		return
+	}
+
+	var rejectedErr *hooks.RejectedError
+	if errors.As(err, &rejectedErr) {
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+		return
+	}
+
+	switch {
+	case errors.Is(err, pendingchange_service.ErrChangeNotFound),
+		errors.Is(err, pendingchange_service.ErrGroupNotProtected):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, pendingchange_service.ErrSelfApproval),
+		errors.Is(err, pendingchange_service.ErrRequesterRequired),
+		errors.Is(err, guest_service.ErrGroupNotEligible):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, pendingchange_service.ErrGroupProtected),
+		errors.Is(err, pendingchange_service.ErrChangeNotPending),
+		errors.Is(err, pendingchange_service.ErrChangeStale),
+		errors.Is(err, pendingchange_service.ErrDuplicateChange),
+		errors.Is(err, pendingchange_service.ErrChangesPending):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, pendingchange_service.ErrMembershipExpiry),
+		errors.Is(err, pendingchange_service.ErrInvalidStatus):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,181 @@
+package profiling_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	profiling_service "github.com/iamBelugaa/iam/internal/services/profiling"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log          *zap.SugaredLogger
+	profilingSvc *profiling_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *profiling_service.Service) *Handler {
+	return &Handler{log: log, profilingSvc: svc}
+}
+
+func (h *Handler) GetRequirements(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get profile requirements request received")
+
+	requirements, err := h.profilingSvc.Requirements(r.Context())
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve profile requirements")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", requirements)
+}
+
+func (h *Handler) CreateRequirement(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Create profile requirement request received")
+
+	var req models.CreateProfileRequirementRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create profile requirement request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	requirement, err := h.profilingSvc.CreateRequirement(r.Context(), &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create profile requirement")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Profile requirement created successfully", requirement)
+}
+
+func (h *Handler) DeleteRequirement(w http.ResponseWriter, r *http.Request) {
+	requirementID := chi.URLParam(r, "requirementID")
+	logger.FromContext(r.Context(), h.log).Infow("Delete profile requirement request received", "requirementId", requirementID)
+
+	if err := h.profilingSvc.DeleteRequirement(r.Context(), requirementID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete profile requirement")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Profile requirement deleted successfully", nil)
+}
+
+// GetMissing lists the attributes the portal asks the caller for.
+func (h *Handler) GetMissing(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get missing profile attributes request received", "userId", caller.UserID)
+	h.respondWithMissing(w, r, caller.UserID)
+}
+
+// GetUserMissing lists the attributes the portal asks the given user for.
+func (h *Handler) GetUserMissing(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get user missing profile attributes request received", "userId", userID)
+	h.respondWithMissing(w, r, userID)
+}
+
+func (h *Handler) respondWithMissing(w http.ResponseWriter, r *http.Request, userID string) {
+	missing, err := h.profilingSvc.Missing(r.Context(), userID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to find missing profile attributes")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", missing)
+}
+
+// Submit writes the attributes the caller filled in to their profile.
+func (h *Handler) Submit(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SubmitProfileAttributesRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode submit profile attributes request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Submit profile attributes request received",
+		"userId", caller.UserID, "count", len(req.Attributes),
+	)
+
+	submission, err := h.profilingSvc.Submit(r.Context(), caller.UserID, caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to submit profile attributes")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Profile updated successfully", submission)
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var validationErr *profiling_service.ValidationError
+	if errors.As(err, &validationErr) {
+		response.RespondError(
+			w, http.StatusBadRequest, "PROFILE_VALIDATION_FAILED", validationErr.Error(), validationErr.Errors,
+		)
+		return
+	}
+
+	var rejectedErr *hooks.RejectedError
+	if errors.As(err, &rejectedErr) {
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+		return
+	}
+
+	switch {
+	case errors.Is(err, profiling_service.ErrRequirementNotFound),
+		errors.Is(err, user_service.ErrUserNotFound),
+		errors.Is(err, app_service.ErrAppNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, profiling_service.ErrAttributeDefined):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, profiling_service.ErrAttributeRequired),
+		errors.Is(err, profiling_service.ErrAttributeProtected),
+		errors.Is(err, profiling_service.ErrLabelRequired),
+		errors.Is(err, profiling_service.ErrInvalidType),
+		errors.Is(err, profiling_service.ErrInvalidPattern),
+		errors.Is(err, profiling_service.ErrStringOnly),
+		errors.Is(err, profiling_service.ErrNoScope),
+		errors.Is(err, profiling_service.ErrNoAttributes):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
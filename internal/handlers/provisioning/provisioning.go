@@ -0,0 +1,119 @@
+package provisioning_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	provisioning_service "github.com/iamBelugaa/iam/internal/services/provisioning"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log             *zap.SugaredLogger
+	provisioningSvc *provisioning_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *provisioning_service.Service) *Handler {
+	return &Handler{log: log, provisioningSvc: svc}
+}
+
+func (h *Handler) OnboardUser(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Onboard user request received")
+
+	var req models.OnboardUserRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode onboard user request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saga, err := h.provisioningSvc.OnboardUser(r.Context(), &req)
+	if errors.Is(err, saga_service.ErrReconciling) {
+		logger.FromContext(r.Context(), h.log).Infow("User onboarding left for reconciliation", zap.Error(err),
+			"userId", saga.ResourceID, "sagaId", saga.ID,
+		)
+		response.RespondSuccess(w, http.StatusAccepted, "User created; the remaining steps will be retried", saga)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to onboard user", zap.Error(err), "email", req.User.Email)
+		h.handleServiceError(w, err, saga, "Failed to onboard user")
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("User onboarded successfully", "userId", saga.ResourceID, "sagaId", saga.ID)
+	response.RespondSuccess(w, http.StatusCreated, "User onboarded successfully", saga)
+}
+
+func (h *Handler) CreateTeam(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Create team request received")
+
+	var req models.CreateTeamRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create team request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	saga, err := h.provisioningSvc.CreateTeam(r.Context(), &req)
+	if errors.Is(err, saga_service.ErrReconciling) {
+		logger.FromContext(r.Context(), h.log).Infow("Team creation left for reconciliation", zap.Error(err),
+			"groupId", saga.ResourceID, "sagaId", saga.ID,
+		)
+		response.RespondSuccess(w, http.StatusAccepted, "Team created; the remaining steps will be retried", saga)
+		return
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create team", zap.Error(err), "name", req.Name)
+		h.handleServiceError(w, err, saga, "Failed to create team")
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Team created successfully", "groupId", saga.ResourceID, "sagaId", saga.ID)
+	response.RespondSuccess(w, http.StatusCreated, "Team created successfully", saga)
+}
+
+// handleServiceError answers with the status the failure calls for. When the
+// saga ran, it is returned as the error's details, so the client can see
+// which step failed and what was undone.
+func (h *Handler) handleServiceError(w http.ResponseWriter, err error, saga *models.Saga, message string) {
+	var details any
+	if saga != nil {
+		details = saga
+	}
+
+	var violationErr *sod_service.ViolationError
+	var rejectedErr *hooks.RejectedError
+	var policyErr *grouppolicy_service.ViolationError
+	switch {
+	case errors.Is(err, provisioning_service.ErrNameRequired),
+		errors.Is(err, provisioning_service.ErrNoGroups),
+		errors.Is(err, group_service.ErrInvalidJoinPolicy):
+		response.RespondError(w, http.StatusBadRequest, "API_ERROR", err.Error(), details)
+	case errors.As(err, &policyErr):
+		response.RespondError(w, http.StatusBadRequest, "GROUP_POLICY_VIOLATION", policyErr.Error(), details)
+	case errors.As(err, &violationErr):
+		response.RespondError(w, http.StatusConflict, "SOD_VIOLATION", violationErr.Error(), details)
+	case errors.As(err, &rejectedErr):
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), details)
+	case errors.Is(err, guest_service.ErrGroupNotEligible):
+		response.RespondError(w, http.StatusForbidden, "API_ERROR", err.Error(), details)
+	default:
+		response.RespondError(w, http.StatusInternalServerError, "API_ERROR", message, details)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
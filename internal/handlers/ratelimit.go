@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/iamBelugaa/iam/internal/ratelimit"
+)
+
+// routeClass counts reads, including the read-only batch, GraphQL, group
+// policy, membership check and expression evaluation queries sent as POST,
+// against the read limit and the rest as writes.
+func routeClass(r *http.Request) ratelimit.Class {
+	switch {
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions:
+		return ratelimit.Read
+	case r.URL.Path == APIVersion1URL+"/batch:get" || r.URL.Path == APIVersion1URL+"/graphql",
+		r.URL.Path == APIVersion1URL+"/users/lookup" || r.URL.Path == APIVersion1URL+"/users/bulk-deactivate/dry-run",
+		r.URL.Path == APIVersion1URL+"/group-policy/check", r.URL.Path == APIVersion1URL+"/tools/evaluate-expression",
+		strings.HasSuffix(r.URL.Path, "/members:check"):
+		return ratelimit.Read
+	default:
+		return ratelimit.Write
+	}
+}
+
+// IsRead reports whether a request only reads, by the same classification
+// as the rate limits. API keys holding only the read scope may make it.
+func IsRead(r *http.Request) bool {
+	return routeClass(r) == ratelimit.Read
+}
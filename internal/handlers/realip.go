@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// realIP replaces the RemoteAddr of requests made through one of the trusted
+// proxies with the client address they forwarded, so logs and the rate
+// limiter see the client rather than the proxy. X-Forwarded-For is read from
+// the right, skipping the trusted proxies, as the addresses left of them are
+// whatever the client sent. Requests from anywhere else keep their peer
+// address: their forwarding headers are the client's own and could be
+// rotated to dodge rate limits.
+func realIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(addr netip.Addr) bool {
+		for _, prefix := range trusted {
+			if prefix.Contains(addr.Unmap()) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !isTrusted(peer.Addr()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := ""
+			forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(forwarded) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+				if err != nil {
+					break
+				}
+				client = addr.String()
+				if !isTrusted(addr) {
+					break
+				}
+			}
+			if client == "" {
+				if addr, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+					client = addr.String()
+				}
+			}
+
+			if client != "" {
+				r.RemoteAddr = net.JoinHostPort(client, "0")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
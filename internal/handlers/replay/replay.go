@@ -0,0 +1,149 @@
+package replay_handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	replay_service "github.com/iamBelugaa/iam/internal/services/replay"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log       *zap.SugaredLogger
+	replaySvc *replay_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *replay_service.Service) *Handler {
+	return &Handler{log: log, replaySvc: svc}
+}
+
+func (h *Handler) GetCaptures(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get replay captures request received")
+
+	captures, err := h.replaySvc.GetCaptures(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to retrieve replay captures", zap.Error(err))
+		h.respondWithError(w, "Failed to retrieve replay captures", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", captures)
+}
+
+// ImportFixture keeps a fixture exported from another server, typically a
+// production one, so it can be replayed here.
+func (h *Handler) ImportFixture(w http.ResponseWriter, r *http.Request) {
+	var fixture models.ReplayFixture
+	if err := request.Decode(r, &fixture); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode replay fixture", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	capture, err := h.replaySvc.Import(r.Context(), &fixture)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to import replay fixture")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Fixture imported", capture)
+}
+
+func (h *Handler) GetCapture(w http.ResponseWriter, r *http.Request) {
+	captureID := chi.URLParam(r, "captureID")
+	if captureID == "" {
+		h.respondWithError(w, "Capture ID is required", http.StatusBadRequest)
+		return
+	}
+
+	capture, err := h.replaySvc.GetCapture(r.Context(), captureID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve replay capture")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", capture)
+}
+
+// ExportFixture downloads the capture as a fixture file, which the import
+// endpoint and oktamock.NewReplay take as is.
+func (h *Handler) ExportFixture(w http.ResponseWriter, r *http.Request) {
+	captureID := chi.URLParam(r, "captureID")
+	if captureID == "" {
+		h.respondWithError(w, "Capture ID is required", http.StatusBadRequest)
+		return
+	}
+
+	capture, err := h.replaySvc.GetCapture(r.Context(), captureID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to export replay capture")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "replay-"+capture.ID+".json"))
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(capture.ReplayFixture)
+}
+
+func (h *Handler) RunCapture(w http.ResponseWriter, r *http.Request) {
+	captureID := chi.URLParam(r, "captureID")
+	if captureID == "" {
+		h.respondWithError(w, "Capture ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Replay of captured request requested", "captureId", captureID)
+
+	result, err := h.replaySvc.Run(r.Context(), captureID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to replay captured request")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Capture replayed", result)
+}
+
+func (h *Handler) DeleteCapture(w http.ResponseWriter, r *http.Request) {
+	captureID := chi.URLParam(r, "captureID")
+	if captureID == "" {
+		h.respondWithError(w, "Capture ID is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.replaySvc.DeleteCapture(r.Context(), captureID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete replay capture")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Capture deleted", nil)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, replay_service.ErrCaptureNotFound):
+		h.respondWithError(w, "Replay capture not found", http.StatusNotFound)
+	case errors.Is(err, replay_service.ErrInvalidFixture):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, replay_service.ErrNotStandalone), errors.Is(err, replay_service.ErrNestedReplay):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(msg, zap.Error(err))
+		h.respondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
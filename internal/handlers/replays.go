@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	replay_service "github.com/iamBelugaa/iam/internal/services/replay"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/openapi"
+)
+
+// maxCapturedBody is the largest request or response body a capture keeps.
+const maxCapturedBody = 1 << 20
+
+// capturedHeaders are the request headers a capture keeps; the rest, such as
+// Authorization, are left out.
+var capturedHeaders = []string{"Accept", "Content-Type", "Prefer"}
+
+// captureFailures captures requests that fail with a 5xx, with the Okta
+// interactions made while serving them, for replaying offline. Like queued
+// retries, only requests with JSON or empty bodies are captured, and only on
+// routes that do not act for a caller, since replays carry no credentials.
+// Responses are teed rather than buffered, so streams keep flowing.
+func captureFailures(
+	log *zap.SugaredLogger,
+	replaySvc *replay_service.Service,
+	spec *openapi.Spec,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions || replay_service.Replaying(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxCapturedBody+1))
+			if err != nil || len(body) > maxCapturedBody || (len(body) > 0 && !json.Valid(body)) {
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := okta.TrackInteractions(r.Context())
+			r = r.WithContext(ctx)
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			responseBody := &limitedBuffer{limit: maxCapturedBody}
+			ww.Tee(responseBody)
+			next.ServeHTTP(ww, r)
+
+			route := strings.TrimSuffix(chi.RouteContext(ctx).RoutePattern(), "/")
+			if ww.Status() < http.StatusInternalServerError || route == "" || spec.Secured(r.Method, route) {
+				return
+			}
+
+			interactions, truncated := okta.Interactions(ctx)
+			capture := &models.ReplayCapture{
+				Route:     route,
+				RequestID: logger.RequestID(ctx),
+				ReplayFixture: models.ReplayFixture{
+					Request: &models.ReplayRequest{
+						Method:  r.Method,
+						Path:    r.URL.RequestURI(),
+						Headers: make(map[string]string),
+						Body:    body,
+					},
+					Response:     &models.ReplayResponse{Status: ww.Status()},
+					Interactions: interactions,
+					Truncated:    truncated,
+				},
+			}
+			for _, name := range capturedHeaders {
+				if value := r.Header.Get(name); value != "" {
+					capture.Request.Headers[name] = value
+				}
+			}
+			if !responseBody.overflowed && json.Valid(responseBody.Bytes()) {
+				capture.Response.Body = responseBody.Bytes()
+			}
+
+			if _, err := replaySvc.Capture(ctx, capture); err != nil {
+				logger.FromContext(ctx, log).Infow("Failed to capture failed request", zap.Error(err))
+			}
+		})
+	}
+}
+
+// limitedBuffer keeps what is written to it up to limit bytes, and notes
+// whether more was written.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit      int
+	overflowed bool
+}
+
+func (b *limitedBuffer) Write(data []byte) (int, error) {
+	if b.overflowed || b.Len()+len(data) > b.limit {
+		b.overflowed = true
+		b.Reset()
+		return len(data), nil
+	}
+	return b.Buffer.Write(data)
+}
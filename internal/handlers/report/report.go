@@ -0,0 +1,161 @@
+package report_handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	report_service "github.com/iamBelugaa/iam/internal/services/report"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	reportsSvc *report_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *report_service.Service) *Handler {
+	return &Handler{log: log, reportsSvc: svc}
+}
+
+func (h *Handler) GetGroupAppMatrix(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+
+	if format != "" && format != "json" && format != "csv" {
+		h.respondWithError(w, "Format must be one of json or csv", http.StatusBadRequest)
+		return
+	}
+
+	filter := models.GroupAppMatrixFilter{
+		GroupQuery:   query.Get("groupQuery"),
+		AppQuery:     query.Get("appQuery"),
+		OnlyAssigned: query.Get("onlyAssigned") == "true",
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get group app matrix request received",
+		"groupQuery", filter.GroupQuery,
+		"appQuery", filter.AppQuery,
+		"format", format,
+	)
+
+	matrix, err := h.reportsSvc.GetGroupAppMatrix(r.Context(), &filter)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to build group app matrix", zap.Error(err))
+		h.respondWithError(w, "Failed to build group app matrix", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Group app matrix built successfully", "groupCount", len(matrix.Rows), "appCount", len(matrix.Apps))
+
+	if format == "csv" {
+		header, rows := groupAppMatrixToCSV(matrix)
+		response.RespondCSV(w, "group-app-matrix.csv", header, rows)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", matrix)
+}
+
+func (h *Handler) GetInactiveUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+
+	if format != "" && format != "json" && format != "csv" {
+		h.respondWithError(w, "Format must be one of json or csv", http.StatusBadRequest)
+		return
+	}
+
+	var filter models.InactiveUsersFilter
+	if days := query.Get("days"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed <= 0 {
+			h.respondWithError(w, "Days must be a positive number", http.StatusBadRequest)
+			return
+		}
+		filter.Days = parsed
+	}
+
+	if excluded := query.Get("excludeGroupIds"); excluded != "" {
+		filter.ExcludeGroupIDs = strings.Split(excluded, ",")
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get inactive users request received", "days", filter.Days, "format", format)
+
+	users, err := h.reportsSvc.GetInactiveUsers(r.Context(), &filter)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to build inactive users report", zap.Error(err))
+		h.respondWithError(w, "Failed to build inactive users report", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Inactive users report built successfully", "count", len(users))
+
+	if format == "csv" {
+		header, rows := inactiveUsersToCSV(users)
+		response.RespondCSV(w, "inactive-users.csv", header, rows)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", users)
+}
+
+func inactiveUsersToCSV(users []*models.InactiveUser) ([]string, [][]string) {
+	header := []string{
+		"id", "login", "email", "firstName", "lastName", "status", "lastLogin", "daysInactive", "reason",
+	}
+
+	rows := make([][]string, len(users))
+	for i, user := range users {
+		var lastLogin string
+		if user.LastLogin != nil {
+			lastLogin = user.LastLogin.Format(time.RFC3339)
+		}
+
+		rows[i] = []string{
+			user.ID, user.Login, user.Email, user.FirstName, user.LastName, user.Status,
+			lastLogin, strconv.Itoa(user.DaysInactive), user.Reason,
+		}
+	}
+
+	return header, rows
+}
+
+// groupAppMatrixToCSV flattens the matrix into one row per group and one
+// column per app, marking assigned cells with "X".
+func groupAppMatrixToCSV(matrix *models.GroupAppMatrix) ([]string, [][]string) {
+	header := make([]string, 0, len(matrix.Apps)+2)
+	header = append(header, "groupId", "groupName")
+
+	columns := make(map[string]int, len(matrix.Apps))
+	for i, app := range matrix.Apps {
+		columns[app.ID] = i + 2
+		header = append(header, app.Label)
+	}
+
+	rows := make([][]string, len(matrix.Rows))
+	for i, row := range matrix.Rows {
+		record := make([]string, len(header))
+		record[0] = row.GroupID
+		record[1] = row.GroupName
+
+		for _, appID := range row.AppIDs {
+			if column, ok := columns[appID]; ok {
+				record[column] = "X"
+			}
+		}
+
+		rows[i] = record
+	}
+
+	return header, rows
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
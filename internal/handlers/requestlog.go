@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	replay_service "github.com/iamBelugaa/iam/internal/services/replay"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// requestLogging gives every request a correlation ID, the client's
+// X-Request-ID when it sends a usable one, and echoes it in the response.
+// The request-scoped logger in the context adds the ID to every line logged
+// while serving the request, and one line is logged when it completes, with
+// secrets in the path such as invitation tokens redacted.
+func requestLogging(log *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			// Later middleware may rewrite the path, as selectOrg does.
+			path := r.URL.Path
+
+			id := logger.NewRequestID(r.Header.Get(logger.RequestIDHeader))
+			w.Header().Set(logger.RequestIDHeader, id)
+			// Handlers that proxy the request, like the gRPC-Web transcoder,
+			// forward the header, so the proxied call keeps the same ID.
+			r.Header.Set(logger.RequestIDHeader, id)
+			ctx := logger.WithRequestID(r.Context(), log, id)
+
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r.WithContext(ctx))
+
+			route := chi.RouteContext(ctx).RoutePattern()
+			if r.URL.Path != path {
+				// selectOrg served the path under /orgs/{org}.
+				if rest, ok := strings.CutPrefix(route, APIVersion1URL+"/orgs/{org}"); ok {
+					route = APIVersion1URL + rest
+				}
+			}
+
+			logger.FromContext(ctx, log).Infow("HTTP request completed",
+				"method", r.Method, "path", replay_service.SanitizePath(path, route), "status", ww.Status(), "bytes", ww.BytesWritten(),
+				"duration", time.Since(start), "remoteAddr", r.RemoteAddr,
+			)
+		})
+	}
+}
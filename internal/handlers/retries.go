@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/openapi"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// maxQueuedBody is the largest request body a queued mutation may have.
+const maxQueuedBody = 1 << 20
+
+// queueRetries queues mutations that failed because Okta was unavailable,
+// or kept failing after the in-request retries, and answers 202 with the
+// queue entry instead of the error. Clients opt in per request with
+// Prefer: respond-async, as they must be able to tell a queued mutation from
+// one that was made. Only JSON or empty bodies are queued, and only on
+// routes that do not act for a caller, since replays carry no credentials.
+func queueRetries(
+	log *zap.SugaredLogger,
+	retrySvc *retry_service.Service,
+	spec *openapi.Spec,
+) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions ||
+				!prefersAsync(r) || retry_service.Replaying(r.Context()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxQueuedBody+1))
+			if err != nil {
+				response.RespondError(w, http.StatusBadRequest, "API_ERROR", "Failed to read request body", nil)
+				return
+			}
+			if len(body) > maxQueuedBody || (len(body) > 0 && !json.Valid(body)) {
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(body), r.Body), Closer: r.Body}
+				next.ServeHTTP(w, r)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			ctx := okta.TrackRetryable(r.Context())
+			r = r.WithContext(ctx)
+			buffered := &bufferedWriter{header: w.Header().Clone()}
+			next.ServeHTTP(buffered, r)
+
+			route := strings.TrimSuffix(chi.RouteContext(ctx).RoutePattern(), "/")
+			failed := buffered.status >= http.StatusInternalServerError || buffered.status == http.StatusTooManyRequests
+			if failed && okta.Retryable(ctx) && route != "" && !readOnlyPosts[route] && !spec.Secured(r.Method, route) {
+				entry, err := retrySvc.Enqueue(ctx, &models.RetryEntry{
+					Method:     r.Method,
+					Path:       r.URL.RequestURI(),
+					Route:      route,
+					Body:       body,
+					RequestID:  logger.RequestID(ctx),
+					LastStatus: buffered.status,
+				})
+				if err == nil {
+					w.Header().Set("Preference-Applied", "respond-async")
+					w.Header().Set("Location", APIVersion1URL+"/retry-queue/"+entry.ID)
+					response.RespondSuccess(w, http.StatusAccepted,
+						"Okta is unavailable; the request was queued and will be retried", entry,
+					)
+					return
+				}
+				logger.FromContext(ctx, log).Infow("Failed to queue mutation for retry", zap.Error(err))
+			}
+
+			buffered.flush(w)
+		})
+	}
+}
+
+// prefersAsync reports whether the client sent Prefer: respond-async.
+func prefersAsync(r *http.Request) bool {
+	for _, header := range r.Header.Values("Prefer") {
+		for _, preference := range strings.Split(header, ",") {
+			name, _, _ := strings.Cut(preference, ";")
+			if strings.EqualFold(strings.TrimSpace(name), "respond-async") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bufferedWriter holds a response back until it is known whether the
+// mutation is queued instead.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *bufferedWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) flush(to http.ResponseWriter) {
+	for key, values := range w.header {
+		to.Header()[key] = values
+	}
+	to.WriteHeader(max(w.status, http.StatusOK))
+	_, _ = to.Write(w.body.Bytes())
+}
@@ -0,0 +1,106 @@
+package retry_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	retrySvc *retry_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *retry_service.Service) *Handler {
+	return &Handler{log: log, retrySvc: svc}
+}
+
+func (h *Handler) GetEntries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.RetryFilter{Status: query.Get("status"), Route: query.Get("route")}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get retry queue request received", "status", filter.Status, "route", filter.Route)
+
+	entries, err := h.retrySvc.GetEntries(r.Context(), &filter)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to retrieve retry queue", zap.Error(err))
+		h.respondWithError(w, "Failed to retrieve retry queue", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", entries)
+}
+
+func (h *Handler) GetEntry(w http.ResponseWriter, r *http.Request) {
+	entryID := chi.URLParam(r, "entryID")
+	if entryID == "" {
+		h.respondWithError(w, "Entry ID is required", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.retrySvc.GetEntry(r.Context(), entryID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve retry queue entry")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", entry)
+}
+
+func (h *Handler) RetryEntry(w http.ResponseWriter, r *http.Request) {
+	entryID := chi.URLParam(r, "entryID")
+	if entryID == "" {
+		h.respondWithError(w, "Entry ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Retry queue entry retry requested", "entryId", entryID)
+
+	entry, err := h.retrySvc.Retry(r.Context(), entryID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retry queue entry")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Entry replayed", entry)
+}
+
+func (h *Handler) DiscardEntry(w http.ResponseWriter, r *http.Request) {
+	entryID := chi.URLParam(r, "entryID")
+	if entryID == "" {
+		h.respondWithError(w, "Entry ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Retry queue entry discard requested", "entryId", entryID)
+
+	if err := h.retrySvc.Discard(r.Context(), entryID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to discard retry queue entry")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Entry discarded", nil)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	switch {
+	case errors.Is(err, retry_service.ErrEntryNotFound):
+		h.respondWithError(w, "Retry queue entry not found", http.StatusNotFound)
+	case errors.Is(err, retry_service.ErrEntryRunning):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(msg, zap.Error(err))
+		h.respondWithError(w, msg, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -1,7 +1,6 @@
 package role_handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 
@@ -10,6 +9,8 @@ import (
 
 	"github.com/iamBelugaa/iam/internal/models"
 	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
 	"github.com/iamBelugaa/iam/pkg/response"
 )
 
@@ -23,39 +24,39 @@ func New(log *zap.SugaredLogger, svc *role_service.Service) *Handler {
 }
 
 func (h *Handler) CreateRole(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Create role request received")
+	logger.FromContext(r.Context(), h.log).Infow("Create role request received")
 
 	var req models.CreateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode create role request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body - please check your JSON format", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create role request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	role, err := h.rolesSvc.CreateRole(r.Context(), &req)
 	if err != nil {
-		h.log.Infow("Failed to create role", zap.Error(err), "name", req.Name)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create role", zap.Error(err), "name", req.Name)
 		h.respondWithError(w, "Failed to create role - please try again", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role created successfully", "roleId", role.ID, "name", role.Name)
+	logger.FromContext(r.Context(), h.log).Infow("Role created successfully", "roleId", role.ID, "name", role.Name)
 	response.RespondSuccess(
 		w, http.StatusCreated, fmt.Sprintf("Role '%s' created successfully", role.Name), role,
 	)
 }
 
 func (h *Handler) GetRoles(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Get roles request received")
+	logger.FromContext(r.Context(), h.log).Infow("Get roles request received")
 
 	roles, err := h.rolesSvc.GetRoles(r.Context())
 	if err != nil {
-		h.log.Infow("Failed to get roles", zap.Error(err))
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get roles", zap.Error(err))
 		h.respondWithError(w, "Failed to retrieve roles", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Roles retrieved successfully", "count", len(roles))
+	logger.FromContext(r.Context(), h.log).Infow("Roles retrieved successfully", "count", len(roles))
 	response.RespondSuccess(w, http.StatusOK, "Success", roles)
 }
 
@@ -66,16 +67,16 @@ func (h *Handler) GetRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get role request received", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Get role request received", "roleId", roleID)
 
 	role, err := h.rolesSvc.GetRole(r.Context(), roleID)
 	if err != nil {
-		h.log.Infow("Failed to get role", zap.Error(err), "roleId", roleID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get role", zap.Error(err), "roleId", roleID)
 		h.respondWithError(w, "Failed to retrieve role", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role retrieved successfully", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Role retrieved successfully", "roleId", roleID)
 	response.RespondSuccess(w, http.StatusOK, "Success", role)
 }
 
@@ -86,23 +87,23 @@ func (h *Handler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Update role request received", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Update role request received", "roleId", roleID)
 
 	var req models.UpdateRoleRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode update role request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode update role request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	role, err := h.rolesSvc.UpdateRole(r.Context(), roleID, &req)
 	if err != nil {
-		h.log.Infow("Failed to update role", zap.Error(err), "roleId", roleID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to update role", zap.Error(err), "roleId", roleID)
 		h.respondWithError(w, "Failed to update role", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role updated successfully", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Role updated successfully", "roleId", roleID)
 	response.RespondSuccess(w, http.StatusOK, "Role updated successfully", role)
 }
 
@@ -113,15 +114,15 @@ func (h *Handler) DeleteRole(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Delete role request received", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Delete role request received", "roleId", roleID)
 
 	if err := h.rolesSvc.DeleteRole(r.Context(), roleID); err != nil {
-		h.log.Infow("Failed to delete role", zap.Error(err), "roleId", roleID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to delete role", zap.Error(err), "roleId", roleID)
 		h.respondWithError(w, "Failed to delete role", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role deleted successfully", "roleId", roleID)
+	logger.FromContext(r.Context(), h.log).Infow("Role deleted successfully", "roleId", roleID)
 	response.RespondSuccess(w, http.StatusOK, "Role deleted successfully", nil)
 }
 
@@ -134,15 +135,15 @@ func (h *Handler) AssignRoleToUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Assign role to user request received", "roleId", roleID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Assign role to user request received", "roleId", roleID, "userId", userID)
 
 	if err := h.rolesSvc.AssignRoleToUser(r.Context(), userID, roleID); err != nil {
-		h.log.Infow("Failed to assign role to user", zap.Error(err), "roleId", roleID, "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to assign role to user", zap.Error(err), "roleId", roleID, "userId", userID)
 		h.respondWithError(w, "Failed to assign role to user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role assigned to user successfully", "roleId", roleID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Role assigned to user successfully", "roleId", roleID, "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "Role assigned to user successfully", nil)
 }
 
@@ -155,15 +156,15 @@ func (h *Handler) UnassignRoleFromUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Unassign role from user request received", "roleId", roleID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Unassign role from user request received", "roleId", roleID, "userId", userID)
 
 	if err := h.rolesSvc.UnassignRoleFromUser(r.Context(), userID, roleID); err != nil {
-		h.log.Infow("Failed to unassign role from user", zap.Error(err), "roleId", roleID, "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to unassign role from user", zap.Error(err), "roleId", roleID, "userId", userID)
 		h.respondWithError(w, "Failed to unassign role from user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role unassigned from user successfully", "roleId", roleID, "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Role unassigned from user successfully", "roleId", roleID, "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "Role unassigned from user successfully", nil)
 }
 
@@ -176,15 +177,15 @@ func (h *Handler) AssignRoleToGroup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Assign role to group request received", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Assign role to group request received", "roleId", roleID, "groupId", groupID)
 
 	if err := h.rolesSvc.AssignRoleToGroup(r.Context(), groupID, roleID); err != nil {
-		h.log.Infow("Failed to assign role to group", zap.Error(err), "roleId", roleID, "groupId", groupID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to assign role to group", zap.Error(err), "roleId", roleID, "groupId", groupID)
 		h.respondWithError(w, "Failed to assign role to group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role assigned to group successfully", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Role assigned to group successfully", "roleId", roleID, "groupId", groupID)
 	response.RespondSuccess(w, http.StatusOK, "Role assigned to group successfully", nil)
 }
 
@@ -197,15 +198,15 @@ func (h *Handler) UnassignRoleFromGroup(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.log.Infow("Unassign role from group request received", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Unassign role from group request received", "roleId", roleID, "groupId", groupID)
 
 	if err := h.rolesSvc.UnassignRoleFromGroup(r.Context(), groupID, roleID); err != nil {
-		h.log.Infow("Failed to unassign role from group", zap.Error(err), "roleId", roleID, "groupId", groupID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to unassign role from group", zap.Error(err), "roleId", roleID, "groupId", groupID)
 		h.respondWithError(w, "Failed to unassign role from group", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Role unassigned from group successfully", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Role unassigned from group successfully", "roleId", roleID, "groupId", groupID)
 	response.RespondSuccess(w, http.StatusOK, "Role unassigned from group successfully", nil)
 }
 
@@ -216,16 +217,16 @@ func (h *Handler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get user roles request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Get user roles request received", "userId", userID)
 
 	roles, err := h.rolesSvc.GetUserRoles(r.Context(), userID)
 	if err != nil {
-		h.log.Infow("Failed to get user roles", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user roles", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to retrieve user roles", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User roles retrieved successfully", "userId", userID, "roleCount", len(roles))
+	logger.FromContext(r.Context(), h.log).Infow("User roles retrieved successfully", "userId", userID, "roleCount", len(roles))
 	response.RespondSuccess(w, http.StatusOK, "Success", roles)
 }
 
@@ -236,16 +237,16 @@ func (h *Handler) GetGroupRoles(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get group roles request received", "groupId", groupID)
+	logger.FromContext(r.Context(), h.log).Infow("Get group roles request received", "groupId", groupID)
 
 	roles, err := h.rolesSvc.GetGroupRoles(r.Context(), groupID)
 	if err != nil {
-		h.log.Infow("Failed to get group roles", zap.Error(err), "groupId", groupID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get group roles", zap.Error(err), "groupId", groupID)
 		h.respondWithError(w, "Failed to retrieve group roles", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Group roles retrieved successfully", "groupId", groupID, "roleCount", len(roles))
+	logger.FromContext(r.Context(), h.log).Infow("Group roles retrieved successfully", "groupId", groupID, "roleCount", len(roles))
 	response.RespondSuccess(w, http.StatusOK, "Success", roles)
 }
 
@@ -0,0 +1,91 @@
+package saga_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	sagasSvc *saga_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *saga_service.Service) *Handler {
+	return &Handler{log: log, sagasSvc: svc}
+}
+
+func (h *Handler) GetSagas(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.SagaFilter{
+		Type:       query.Get("type"),
+		Status:     query.Get("status"),
+		ResourceID: query.Get("resourceId"),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get sagas request received", "type", filter.Type, "status", filter.Status)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.sagasSvc.GetSagas(r.Context(), &filter))
+}
+
+func (h *Handler) GetSaga(w http.ResponseWriter, r *http.Request) {
+	sagaID := chi.URLParam(r, "sagaID")
+	if sagaID == "" {
+		h.respondWithError(w, "Saga ID is required", http.StatusBadRequest)
+		return
+	}
+
+	saga, err := h.sagasSvc.GetSaga(r.Context(), sagaID)
+	if err != nil {
+		if errors.Is(err, saga_service.ErrSagaNotFound) {
+			h.respondWithError(w, "Saga not found", http.StatusNotFound)
+			return
+		}
+
+		logger.FromContext(r.Context(), h.log).Infow("Failed to retrieve saga", zap.Error(err), "sagaId", sagaID)
+		h.respondWithError(w, "Failed to retrieve saga", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", saga)
+}
+
+func (h *Handler) GetPendingReconciliations(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get pending reconciliations request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.sagasSvc.Pending(r.Context()))
+}
+
+func (h *Handler) RetryReconciliation(w http.ResponseWriter, r *http.Request) {
+	sagaID := chi.URLParam(r, "sagaID")
+	logger.FromContext(r.Context(), h.log).Infow("Retry reconciliation request received", "sagaId", sagaID)
+
+	reconciliation, err := h.sagasSvc.Retry(r.Context(), sagaID)
+	if err != nil {
+		switch {
+		case errors.Is(err, saga_service.ErrNotReconciling):
+			h.respondWithError(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, saga_service.ErrReconcileActive):
+			h.respondWithError(w, err.Error(), http.StatusConflict)
+		default:
+			logger.FromContext(r.Context(), h.log).Infow("Failed to retry reconciliation", zap.Error(err), "sagaId", sagaID)
+			h.respondWithError(w, "Failed to retry reconciliation", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	message := "Saga reconciled"
+	if reconciliation.Status != models.ReconciliationStatusResolved {
+		message = "Retry failed: " + reconciliation.LastError
+	}
+	response.RespondSuccess(w, http.StatusOK, message, reconciliation)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
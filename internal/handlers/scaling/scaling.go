@@ -0,0 +1,31 @@
+package scaling_handlers
+
+import (
+	"net/http"
+
+	"go.uber.org/zap"
+
+	scaling_service "github.com/iamBelugaa/iam/internal/services/scaling"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	scalingSvc *scaling_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *scaling_service.Service) *Handler {
+	return &Handler{log: log, scalingSvc: svc}
+}
+
+func (h *Handler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	metrics, err := h.scalingSvc.GetMetrics(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get scale metrics", zap.Error(err))
+		response.RespondError(w, http.StatusInternalServerError, "API_ERROR", "Failed to retrieve scale metrics", nil)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", metrics)
+}
@@ -0,0 +1,135 @@
+package scheduledchange_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	scheduledchange_service "github.com/iamBelugaa/iam/internal/services/scheduledchange"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log          *zap.SugaredLogger
+	scheduledSvc *scheduledchange_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *scheduledchange_service.Service) *Handler {
+	return &Handler{log: log, scheduledSvc: svc}
+}
+
+func (h *Handler) GetChanges(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.ScheduledChangeFilter{
+		Status:  strings.ToUpper(query.Get("status")),
+		GroupID: query.Get("groupId"),
+		UserID:  query.Get("userId"),
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get scheduled changes request received",
+		"status", filter.Status, "groupId", filter.GroupID, "userId", filter.UserID,
+	)
+
+	changes, err := h.scheduledSvc.Changes(r.Context(), &filter)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve scheduled changes")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", changes)
+}
+
+func (h *Handler) ScheduleChange(w http.ResponseWriter, r *http.Request) {
+	var req models.ScheduleMembershipChangeRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode schedule change request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Operation = strings.ToUpper(req.Operation)
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Schedule membership change request received",
+		"groupId", req.GroupID, "userId", req.UserID, "operation", req.Operation, "runAt", req.RunAt, "actor", actor,
+	)
+
+	change, err := h.scheduledSvc.Schedule(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to schedule membership change")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Membership change scheduled successfully", change)
+}
+
+func (h *Handler) GetChange(w http.ResponseWriter, r *http.Request) {
+	changeID := chi.URLParam(r, "changeID")
+	logger.FromContext(r.Context(), h.log).Infow("Get scheduled change request received", "scheduledChangeId", changeID)
+
+	change, err := h.scheduledSvc.Change(r.Context(), changeID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve scheduled change")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", change)
+}
+
+func (h *Handler) CancelChange(w http.ResponseWriter, r *http.Request) {
+	changeID := chi.URLParam(r, "changeID")
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Cancel scheduled change request received", "scheduledChangeId", changeID, "actor", actor)
+
+	change, err := h.scheduledSvc.Cancel(r.Context(), actor, changeID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to cancel scheduled change")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Scheduled change cancelled successfully", change)
+}
+
+// actorFromRequest is the user who made the request, or the client when the
+// access token does not identify a user.
+func actorFromRequest(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if caller.UserID != "" {
+		return caller.UserID
+	}
+	return caller.ClientID
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, scheduledchange_service.ErrChangeNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, scheduledchange_service.ErrChangeNotScheduled),
+		errors.Is(err, scheduledchange_service.ErrGroupProtected):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, scheduledchange_service.ErrGroupIDRequired),
+		errors.Is(err, scheduledchange_service.ErrUserIDRequired),
+		errors.Is(err, scheduledchange_service.ErrInvalidOperation),
+		errors.Is(err, scheduledchange_service.ErrRunAtInPast),
+		errors.Is(err, scheduledchange_service.ErrMembershipExpiry),
+		errors.Is(err, scheduledchange_service.ErrExpiryOnRemove),
+		errors.Is(err, scheduledchange_service.ErrInvalidStatusFilter):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
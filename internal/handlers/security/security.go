@@ -0,0 +1,143 @@
+package security_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	security_service "github.com/iamBelugaa/iam/internal/services/security"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log         *zap.SugaredLogger
+	securitySvc *security_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *security_service.Service) *Handler {
+	return &Handler{log: log, securitySvc: svc}
+}
+
+func (h *Handler) GetAlerts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := &models.SecurityAlertFilter{
+		Type:      strings.ToUpper(query.Get("type")),
+		UserID:    query.Get("userId"),
+		IPAddress: query.Get("ipAddress"),
+	}
+	if filter.Type != "" && filter.Type != models.SecurityAlertBruteForce &&
+		filter.Type != models.SecurityAlertImpossibleTravel {
+		h.respondWithError(w, "type must be BRUTE_FORCE or IMPOSSIBLE_TRAVEL", http.StatusBadRequest)
+		return
+	}
+
+	for _, param := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw := query.Get(param.name)
+		if raw == "" {
+			continue
+		}
+
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.respondWithError(w, param.name+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		*param.value = parsed
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get security alerts request received",
+		"type", filter.Type, "userId", filter.UserID, "ipAddress", filter.IPAddress,
+		"since", filter.Since, "until", filter.Until,
+	)
+	response.RespondSuccess(w, http.StatusOK, "Success", h.securitySvc.Alerts(r.Context(), filter))
+}
+
+func (h *Handler) Lockdown(w http.ResponseWriter, r *http.Request) {
+	var req models.LockdownRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode lockdown request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Lockdown request received",
+		"incidentId", req.IncidentID, "userCount", len(req.UserIDs), "groupId", req.GroupID,
+		"clearFactors", req.ClearFactors, "actor", actor,
+	)
+
+	lockdown, err := h.securitySvc.Lockdown(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to lock down users")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Lockdown in place", lockdown)
+}
+
+func (h *Handler) Unlock(w http.ResponseWriter, r *http.Request) {
+	var req models.UnlockRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode unlock request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor := actorFromRequest(r)
+	logger.FromContext(r.Context(), h.log).Infow("Unlock request received", "incidentId", req.IncidentID, "actor", actor)
+
+	lockdown, err := h.securitySvc.Unlock(r.Context(), actor, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to lift lockdown")
+		return
+	}
+
+	message := "Lockdown lifted"
+	if lockdown.Status == models.LockdownStatusLocked {
+		message = "Lockdown partially lifted; retry to unsuspend the remaining users"
+	}
+	response.RespondSuccess(w, http.StatusOK, message, lockdown)
+}
+
+// actorFromRequest is the user who made the request, or the client when the
+// access token does not identify a user.
+func actorFromRequest(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if caller.UserID != "" {
+		return caller.UserID
+	}
+	return caller.ClientID
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, security_service.ErrLockdownNotFound):
+		h.respondWithError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, security_service.ErrIncidentIDRequired),
+		errors.Is(err, security_service.ErrLockdownNoTargets):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, security_service.ErrIncidentLocked),
+		errors.Is(err, security_service.ErrIncidentUnlocked):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
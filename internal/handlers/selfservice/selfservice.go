@@ -0,0 +1,161 @@
+package selfservice_handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	selfservice_service "github.com/iamBelugaa/iam/internal/services/selfservice"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log            *zap.SugaredLogger
+	selfServiceSvc *selfservice_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *selfservice_service.Service) *Handler {
+	return &Handler{log: log, selfServiceSvc: svc}
+}
+
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get own profile request received", "userId", caller.UserID)
+
+	user, err := h.selfServiceSvc.GetProfile(r.Context(), caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve profile")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", user)
+}
+
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateMyProfileRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode update own profile request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Update own profile request received", "userId", caller.UserID)
+
+	user, err := h.selfServiceSvc.UpdateProfile(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to update profile")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Profile updated successfully", user)
+}
+
+func (h *Handler) GetGroups(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get own groups request received", "userId", caller.UserID)
+
+	groups, err := h.selfServiceSvc.GetGroups(r.Context(), caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve groups")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", groups)
+}
+
+func (h *Handler) GetFactors(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get own factors request received", "userId", caller.UserID)
+
+	factors, err := h.selfServiceSvc.GetFactors(r.Context(), caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve factors")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", factors)
+}
+
+// RevokeSessions signs the caller out everywhere. The body is optional.
+func (h *Handler) RevokeSessions(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RevokeMySessionsRequest
+	if err := request.Decode(r, &req); err != nil && !errors.Is(err, io.EOF) {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode revoke own sessions request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Revoke own sessions request received",
+		"userId", caller.UserID, "oauthTokens", req.OAuthTokens,
+	)
+
+	if err := h.selfServiceSvc.RevokeSessions(r.Context(), caller.UserID, &req); err != nil {
+		h.handleServiceError(w, r, err, "Failed to revoke sessions")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Sessions revoked successfully", nil)
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	var rejectedErr *hooks.RejectedError
+	if errors.As(err, &rejectedErr) {
+		response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+		return
+	}
+
+	switch {
+	case errors.Is(err, user_service.ErrUserNotFound):
+		h.respondWithError(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, selfservice_service.ErrNoAttributes):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, selfservice_service.ErrAttributeNotAllowed):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
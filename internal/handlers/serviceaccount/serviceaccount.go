@@ -0,0 +1,241 @@
+package serviceaccount_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	serviceaccount_service "github.com/iamBelugaa/iam/internal/services/serviceaccount"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log                *zap.SugaredLogger
+	serviceAccountsSvc *serviceaccount_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *serviceaccount_service.Service) *Handler {
+	return &Handler{log: log, serviceAccountsSvc: svc}
+}
+
+func (h *Handler) CreateServiceAccount(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateServiceAccountRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create service account request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Create service account request received", "name", req.Name, "kind", req.Kind, "callerId", caller.UserID)
+
+	if req.Name == "" || req.OwnerID == "" {
+		h.respondWithError(w, "Name and ownerId are required", http.StatusBadRequest)
+		return
+	}
+
+	req.Kind = strings.ToUpper(req.Kind)
+	if req.Kind == "" {
+		req.Kind = models.ServiceAccountKindUser
+	}
+	if req.Kind != models.ServiceAccountKindUser && req.Kind != models.ServiceAccountKindAppClient {
+		h.respondWithError(w, "Kind must be USER or APP_CLIENT", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(now) {
+		h.respondWithError(w, "expiresAt must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	if req.ReviewBy != nil && !req.ReviewBy.After(now) {
+		h.respondWithError(w, "reviewBy must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	if req.RotationIntervalDays < 0 {
+		h.respondWithError(w, "rotationIntervalDays cannot be negative", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.serviceAccountsSvc.CreateServiceAccount(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to create service account")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "Service account created successfully", account)
+}
+
+func (h *Handler) GetServiceAccounts(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get service accounts request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.serviceAccountsSvc.GetServiceAccounts(r.Context()))
+}
+
+func (h *Handler) GetServiceAccount(w http.ResponseWriter, r *http.Request) {
+	accountID := chi.URLParam(r, "serviceAccountID")
+	if accountID == "" {
+		h.respondWithError(w, "Service account ID is required", http.StatusBadRequest)
+		return
+	}
+
+	account, err := h.serviceAccountsSvc.GetServiceAccount(r.Context(), accountID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve service account")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", account)
+}
+
+func (h *Handler) DeleteServiceAccount(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	accountID := chi.URLParam(r, "serviceAccountID")
+	if accountID == "" {
+		h.respondWithError(w, "Service account ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Delete service account request received", "serviceAccountId", accountID, "callerId", caller.UserID)
+
+	if err := h.serviceAccountsSvc.DeleteServiceAccount(r.Context(), caller.UserID, accountID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete service account")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Service account deleted successfully", nil)
+}
+
+func (h *Handler) ReviewServiceAccount(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	accountID := chi.URLParam(r, "serviceAccountID")
+	if accountID == "" {
+		h.respondWithError(w, "Service account ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Review service account request received", "serviceAccountId", accountID, "callerId", caller.UserID)
+
+	account, err := h.serviceAccountsSvc.ReviewServiceAccount(r.Context(), accountID, caller.UserID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to review service account")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Service account reviewed successfully", account)
+}
+
+func (h *Handler) RotateCredential(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	accountID := chi.URLParam(r, "serviceAccountID")
+	if accountID == "" {
+		h.respondWithError(w, "Service account ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Rotate service account credential request received", "serviceAccountId", accountID, "callerId", caller.UserID)
+
+	rotation, err := h.serviceAccountsSvc.RotateCredential(r.Context(), caller.UserID, accountID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to rotate service account credential")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusAccepted, "Credential rotation started", rotation)
+}
+
+func (h *Handler) GetPastReview(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		h.respondWithError(w, "Format must be one of json or csv", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get service accounts past review request received", "format", format)
+
+	accounts := h.serviceAccountsSvc.GetPastReview(r.Context(), time.Now().UTC())
+
+	if format == "csv" {
+		header, rows := pastReviewToCSV(accounts)
+		response.RespondCSV(w, "service-accounts-past-review.csv", header, rows)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", accounts)
+}
+
+func pastReviewToCSV(accounts []*models.ServiceAccountPastReview) ([]string, [][]string) {
+	header := []string{"id", "name", "kind", "oktaId", "ownerId", "reviewBy", "daysOverdue", "lastReviewed"}
+
+	rows := make([][]string, len(accounts))
+	for i, account := range accounts {
+		var lastReviewed string
+		if account.LastReviewed != nil {
+			lastReviewed = account.LastReviewed.Format(time.RFC3339)
+		}
+
+		rows[i] = []string{
+			account.ID, account.Name, account.Kind, account.OktaID, account.OwnerID,
+			account.ReviewBy.Format(time.RFC3339), strconv.Itoa(account.DaysOverdue), lastReviewed,
+		}
+	}
+
+	return header, rows
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, serviceaccount_service.ErrServiceAccountNotFound):
+		h.respondWithError(w, "Service account not found", http.StatusNotFound)
+	case errors.Is(err, serviceaccount_service.ErrInvalidName),
+		errors.Is(err, serviceaccount_service.ErrOwnerNotFound):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, serviceaccount_service.ErrDuplicateName),
+		errors.Is(err, serviceaccount_service.ErrRotationInProgress):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, serviceaccount_service.ErrNotOwner):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
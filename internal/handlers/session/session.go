@@ -0,0 +1,90 @@
+package session_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	session_service "github.com/iamBelugaa/iam/internal/services/session"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log        *zap.SugaredLogger
+	sessionSvc *session_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *session_service.Service) *Handler {
+	return &Handler{log: log, sessionSvc: svc}
+}
+
+// CORS answers preflight requests from the allowed origins and lets their
+// pages read the exchange response. Other origins get no CORS headers, so
+// browsers keep the response from them.
+func (h *Handler) CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if !h.sessionSvc.AllowedOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "7200")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// CookieRedirect exchanges a session token for the URL the browser must be
+// sent to for Okta to set its session cookie.
+func (h *Handler) CookieRedirect(w http.ResponseWriter, r *http.Request) {
+	var req models.SessionCookieRedirectRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode session cookie redirect request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	origin := r.Header.Get("Origin")
+	logger.FromContext(r.Context(), h.log).Infow("Session cookie redirect request received", "origin", origin)
+
+	redirect, err := h.sessionSvc.CookieRedirect(r.Context(), origin, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to exchange session token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.RespondSuccess(w, http.StatusOK, "Success", redirect)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, session_service.ErrNotConfigured):
+		h.respondWithError(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, session_service.ErrOriginNotAllowed):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, session_service.ErrSessionTokenRequired),
+		errors.Is(err, session_service.ErrRedirectURLNotAllowed):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,130 @@
+package sod_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log    *zap.SugaredLogger
+	sodSvc *sod_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *sod_service.Service) *Handler {
+	return &Handler{log: log, sodSvc: svc}
+}
+
+func (h *Handler) CreatePolicy(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Create SoD policy request received")
+
+	var req models.CreateSoDPolicyRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create SoD policy request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Name == "" || req.GroupA == "" || req.GroupB == "" {
+		h.respondWithError(w, "Name, groupA and groupB are required", http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupA == req.GroupB {
+		h.respondWithError(w, "groupA and groupB must be different groups", http.StatusBadRequest)
+		return
+	}
+
+	req.Mode = strings.ToUpper(req.Mode)
+	if req.Mode == "" {
+		req.Mode = models.SoDModeEnforce
+	}
+	if req.Mode != models.SoDModeEnforce && req.Mode != models.SoDModeFlag {
+		h.respondWithError(w, "Mode must be ENFORCE or FLAG", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.sodSvc.CreatePolicy(r.Context(), &req)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create SoD policy", zap.Error(err), "name", req.Name)
+		h.respondWithError(w, "Failed to create SoD policy", http.StatusInternalServerError)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusCreated, "SoD policy created successfully", policy)
+}
+
+func (h *Handler) GetPolicies(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get SoD policies request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.sodSvc.GetPolicies(r.Context()))
+}
+
+func (h *Handler) GetPolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "policyID")
+	if policyID == "" {
+		h.respondWithError(w, "Policy ID is required", http.StatusBadRequest)
+		return
+	}
+
+	policy, err := h.sodSvc.GetPolicy(r.Context(), policyID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve SoD policy")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", policy)
+}
+
+func (h *Handler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	policyID := chi.URLParam(r, "policyID")
+	if policyID == "" {
+		h.respondWithError(w, "Policy ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Delete SoD policy request received", "policyId", policyID)
+
+	if err := h.sodSvc.DeletePolicy(r.Context(), policyID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete SoD policy")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "SoD policy deleted successfully", nil)
+}
+
+func (h *Handler) GetViolations(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get SoD violations request received")
+
+	violations, err := h.sodSvc.GetViolations(r.Context())
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to build SoD violations report", zap.Error(err))
+		h.respondWithError(w, "Failed to build SoD violations report", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("SoD violations retrieved successfully", "count", len(violations))
+	response.RespondSuccess(w, http.StatusOK, "Success", violations)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	if errors.Is(err, sod_service.ErrPolicyNotFound) {
+		h.respondWithError(w, "SoD policy not found", http.StatusNotFound)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+	h.respondWithError(w, message, http.StatusInternalServerError)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,100 @@
+package sync_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	sync_service "github.com/iamBelugaa/iam/internal/services/sync"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log     *zap.SugaredLogger
+	syncSvc *sync_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *sync_service.Service) *Handler {
+	return &Handler{log: log, syncSvc: svc}
+}
+
+func (h *Handler) GetSpokes(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get spoke orgs request received")
+	response.RespondSuccess(w, http.StatusOK, "Success", h.syncSvc.GetSpokes())
+}
+
+func (h *Handler) Push(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Sync push request received")
+
+	var req models.SyncPushRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode sync push request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Spoke == "" {
+		h.respondWithError(w, "Spoke is required", http.StatusBadRequest)
+		return
+	}
+
+	if req.Direction == "" {
+		req.Direction = models.SyncDirectionHubToSpoke
+	}
+	if req.Direction != models.SyncDirectionHubToSpoke && req.Direction != models.SyncDirectionSpokeToHub {
+		h.respondWithError(w, "Direction must be HUB_TO_SPOKE or SPOKE_TO_HUB", http.StatusBadRequest)
+		return
+	}
+
+	if req.ConflictStrategy == "" {
+		req.ConflictStrategy = models.ConflictStrategySkip
+	}
+	switch req.ConflictStrategy {
+	case models.ConflictStrategySkip, models.ConflictStrategyOverwrite, models.ConflictStrategyFail:
+	default:
+		h.respondWithError(w, "Conflict strategy must be SKIP, OVERWRITE or FAIL", http.StatusBadRequest)
+		return
+	}
+
+	if len(req.UserIDs) == 0 && len(req.GroupIDs) == 0 {
+		h.respondWithError(w, "At least one user or group is required", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.syncSvc.Push(r.Context(), actorFromRequest(r), &req)
+	if err != nil {
+		if errors.Is(err, sync_service.ErrUnknownSpoke) {
+			h.respondWithError(w, "Unknown spoke org", http.StatusNotFound)
+			return
+		}
+
+		logger.FromContext(r.Context(), h.log).Infow("Failed to push between orgs", zap.Error(err), "spoke", req.Spoke)
+		h.respondWithError(w, "Failed to push between orgs", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Sync push completed", "spoke", req.Spoke, "direction", req.Direction, "dryRun", req.DryRun)
+	response.RespondSuccess(w, http.StatusOK, "Push completed", result)
+}
+
+// actorFromRequest is the user who made the request, or the client when the
+// access token does not identify a user.
+func actorFromRequest(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok {
+		return ""
+	}
+	if caller.UserID != "" {
+		return caller.UserID
+	}
+	return caller.ClientID
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
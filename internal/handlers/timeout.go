@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/iamBelugaa/iam/internal/ratelimit"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// writeDeadlineGrace is how long past a request's deadline its response may
+// still be written, so the 504 that answers it is not cut off.
+const writeDeadlineGrace = 5 * time.Second
+
+// RequestTimeouts bound how long an API request may take.
+type RequestTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Bulk  time.Duration
+	// Routes overrides the timeout of single routes, keyed by method and
+	// pattern such as "GET /api/v1/reports/inactive-users".
+	Routes map[string]time.Duration
+}
+
+// bulkRoutes are the routes, below /api/v1 or /api/v1/orgs/{org}, that read
+// or change many resources in one request and get the bulk timeout.
+var bulkRoutes = map[string]bool{
+	http.MethodPost + " /batch:get":                            true,
+	http.MethodPost + " /graphql":                              true,
+	http.MethodPost + " /group-policy/check":                   true,
+	http.MethodPost + " /users/lookup":                         true,
+	http.MethodPost + " /users/bulk-deactivate/dry-run":        true,
+	http.MethodPost + " /groups/{groupID}/members:check":       true,
+	http.MethodPost + " /backup":                               true,
+	http.MethodPost + " /restore":                              true,
+	http.MethodPost + " /sync/push":                            true,
+	http.MethodPost + " /unused-access/revocations":            true,
+	http.MethodGet + " /groups/export":                         true,
+	http.MethodGet + " /groups/{groupID}/members/export":       true,
+	http.MethodGet + " /groups/{groupID}/members/history/diff": true,
+	http.MethodGet + " /reports/group-app-matrix":              true,
+	http.MethodGet + " /reports/inactive-users":                true,
+	http.MethodGet + " /reports/service-accounts-past-review":  true,
+	http.MethodGet + " /unused-access/apps/{appID}":            true,
+}
+
+// unboundedRoutes are kept open for as long as the client listens.
+var unboundedRoutes = map[string]bool{
+	http.MethodGet + " /events/stream": true,
+}
+
+// requestDeadlines gives every API request a deadline by its route: the
+// configured override, else the bulk timeout for bulk routes and NDJSON
+// streams, else the read or write timeout by the rate limit class. Okta calls
+// are made with the request's context, so they are cancelled once it passes.
+// A request that fails because its deadline passed is answered with 504.
+func requestDeadlines(mux *chi.Mux, timeouts RequestTimeouts) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			pattern := strings.TrimSuffix(mux.Find(chi.NewRouteContext(), r.Method, r.URL.Path), "/")
+			route := strings.TrimPrefix(pattern, APIVersion1URL)
+			route = strings.TrimPrefix(route, "/orgs/{org}")
+			key := r.Method + " " + route
+
+			timeout, ok := timeouts.Routes[r.Method+" "+pattern]
+			switch {
+			case ok:
+			case unboundedRoutes[key]:
+				next.ServeHTTP(w, r)
+				return
+			case bulkRoutes[key] || response.WantsStream(r):
+				timeout = timeouts.Bulk
+			case routeClass(r) == ratelimit.Read:
+				timeout = timeouts.Read
+			default:
+				timeout = timeouts.Write
+			}
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			r = r.WithContext(ctx)
+
+			// The server's write timeout would otherwise close the connection
+			// of a request allowed longer than it.
+			http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + writeDeadlineGrace))
+
+			next.ServeHTTP(&timeoutWriter{ResponseWriter: w, r: r, route: pattern, timeout: timeout}, r)
+		})
+	}
+}
+
+type timeoutWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	route    string
+	timeout  time.Duration
+	replaced bool
+}
+
+// WriteHeader answers 504 instead of the 5xx a handler sends when it failed
+// because the request's deadline passed.
+func (w *timeoutWriter) WriteHeader(status int) {
+	if status >= http.StatusInternalServerError && errors.Is(w.r.Context().Err(), context.DeadlineExceeded) {
+		w.replaced = true
+		w.Header().Del("Content-Length")
+		w.Header().Del("Retry-After")
+		response.RespondError(w.ResponseWriter, http.StatusGatewayTimeout, "TIMEOUT",
+			"The request did not complete in time", map[string]string{
+				"route":   w.r.Method + " " + w.route,
+				"timeout": w.timeout.String(),
+			},
+		)
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write drops the handler's own error body once it has been replaced.
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.replaced {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *timeoutWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,78 @@
+package tokenexchange_handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	tokenexchange_service "github.com/iamBelugaa/iam/internal/services/tokenexchange"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log         *zap.SugaredLogger
+	exchangeSvc *tokenexchange_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *tokenexchange_service.Service) *Handler {
+	return &Handler{log: log, exchangeSvc: svc}
+}
+
+// Exchange swaps the user's token in the body for a token for another
+// audience. The caller is the service asking, known by the client ID of its
+// own access token or API key.
+func (h *Handler) Exchange(w http.ResponseWriter, r *http.Request) {
+	var req models.TokenExchangeRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode token exchange request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var clientID string
+	if caller, ok := auth.CallerFromContext(r.Context()); ok {
+		clientID = caller.ClientID
+	}
+	logger.FromContext(r.Context(), h.log).Infow("Token exchange request received",
+		"clientId", clientID, "audience", req.Audience,
+	)
+
+	token, err := h.exchangeSvc.Exchange(r.Context(), clientID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to exchange token")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.RespondSuccess(w, http.StatusOK, "Token exchanged", token)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, tokenexchange_service.ErrNotConfigured):
+		h.respondWithError(w, err.Error(), http.StatusServiceUnavailable)
+	case errors.Is(err, tokenexchange_service.ErrClientNotAllowed),
+		errors.Is(err, tokenexchange_service.ErrAudienceNotAllowed),
+		errors.Is(err, tokenexchange_service.ErrScopeNotAllowed):
+		h.respondWithError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, tokenexchange_service.ErrSubjectTokenRequired),
+		errors.Is(err, tokenexchange_service.ErrUnsupportedTokenType),
+		errors.Is(err, tokenexchange_service.ErrAudienceRequired):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, tokenexchange_service.ErrExchangeRejected):
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
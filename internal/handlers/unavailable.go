@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// oktaUnavailable answers 503 with a Retry-After header instead of the 500 a
+// handler sends when it failed because an Okta circuit breaker was open, so
+// clients can tell an Okta outage from a bug and back off.
+func oktaUnavailable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(okta.TrackUnavailable(r.Context()))
+		next.ServeHTTP(&unavailableWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+type unavailableWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (w *unavailableWriter) WriteHeader(status int) {
+	if status == http.StatusInternalServerError {
+		if retryAfter, ok := okta.Unavailable(w.r.Context()); ok {
+			w.replaced = true
+			w.Header().Del("Content-Length")
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			response.RespondError(w.ResponseWriter, http.StatusServiceUnavailable, "OKTA_UNAVAILABLE",
+				"Okta is unavailable, retry later", nil,
+			)
+			return
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write drops the handler's own error body once it has been replaced.
+func (w *unavailableWriter) Write(data []byte) (int, error) {
+	if w.replaced {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *unavailableWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
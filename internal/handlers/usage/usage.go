@@ -0,0 +1,137 @@
+package usage_handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	usage_service "github.com/iamBelugaa/iam/internal/services/usage"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log      *zap.SugaredLogger
+	usageSvc *usage_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *usage_service.Service) *Handler {
+	return &Handler{log: log, usageSvc: svc}
+}
+
+func (h *Handler) GetAppUnusedAccess(w http.ResponseWriter, r *http.Request) {
+	appID := chi.URLParam(r, "appID")
+
+	filter, ok := h.filter(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get app unused access request received", "appId", appID, "days", filter.Days)
+
+	suggestions, err := h.usageSvc.GetAppUnusedAccess(r.Context(), appID, filter)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to build unused access suggestions")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", suggestions)
+}
+
+func (h *Handler) GetUserUnusedAccess(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+
+	filter, ok := h.filter(w, r)
+	if !ok {
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get user unused access request received", "userId", userID, "days", filter.Days)
+
+	suggestions, err := h.usageSvc.GetUserUnusedAccess(r.Context(), userID, filter)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to build unused access suggestions")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", suggestions)
+}
+
+func (h *Handler) RevokeUnusedAccess(w http.ResponseWriter, r *http.Request) {
+	caller, ok := h.caller(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RevokeUnusedAccessRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode revoke unused access request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.AppID == "" {
+		h.respondWithError(w, "App ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Revoke unused access request received",
+		"appId", req.AppID, "userCount", len(req.UserIDs), "callerId", caller.UserID,
+	)
+
+	job, err := h.usageSvc.RevokeUnusedAccess(r.Context(), caller.UserID, &req)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to start revocation")
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusAccepted, "Revocation started", job)
+}
+
+func (h *Handler) filter(w http.ResponseWriter, r *http.Request) (*models.UnusedAccessFilter, bool) {
+	var filter models.UnusedAccessFilter
+	if days := r.URL.Query().Get("days"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed <= 0 {
+			h.respondWithError(w, "Days must be a positive number", http.StatusBadRequest)
+			return nil, false
+		}
+		filter.Days = parsed
+	}
+	return &filter, true
+}
+
+func (h *Handler) caller(w http.ResponseWriter, r *http.Request) (*auth.Caller, bool) {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok || caller.UserID == "" {
+		h.respondWithError(w, "The access token does not identify a user", http.StatusForbidden)
+		return nil, false
+	}
+	return caller, true
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message string) {
+	switch {
+	case errors.Is(err, app_service.ErrAppNotFound):
+		h.respondWithError(w, "App not found", http.StatusNotFound)
+	case errors.Is(err, usage_service.ErrUserNotFound):
+		h.respondWithError(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, usage_service.ErrNoUsers),
+		errors.Is(err, usage_service.ErrWindowTooLong):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err))
+		h.respondWithError(w, message, http.StatusInternalServerError)
+	}
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
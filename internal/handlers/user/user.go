@@ -1,15 +1,22 @@
 package user_handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"reflect"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
 	"github.com/iamBelugaa/iam/internal/models"
 	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/patch"
+	"github.com/iamBelugaa/iam/pkg/request"
 	"github.com/iamBelugaa/iam/pkg/response"
 )
 
@@ -23,39 +30,61 @@ func New(log *zap.SugaredLogger, svc *user_service.Service) *Handler {
 }
 
 func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Create user request received")
+	logger.FromContext(r.Context(), h.log).Infow("Create user request received")
 
 	var req models.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode create user request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body - please check your JSON format", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create user request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	user, err := h.usersSvc.CreateUser(r.Context(), &req)
 	if err != nil {
-		h.log.Infow("Failed to create user", zap.Error(err), "email", req.Email)
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create user", zap.Error(err), "email", req.Email)
 		h.respondWithError(w, "Failed to create user - please try again", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User created successfully", "userId", user.ID, "email", user.Email)
+	logger.FromContext(r.Context(), h.log).Infow("User created successfully", "userId", user.ID, "email", user.Email)
 	response.RespondSuccess(
 		w, http.StatusCreated, fmt.Sprintf("User %s created successfully", user.Email), user,
 	)
 }
 
 func (h *Handler) GetUsers(w http.ResponseWriter, r *http.Request) {
-	h.log.Infow("Get users request received")
+	logger.FromContext(r.Context(), h.log).Infow("Get users request received")
+
+	if response.WantsStream(r) {
+		stream := response.NewStream(w)
+		err := h.usersSvc.StreamUsers(r.Context(), func(user *models.User) error {
+			return stream.Write(user)
+		})
+		if err == nil {
+			stream.Close()
+			return
+		}
+
+		// Once records have been sent the status cannot change, so the
+		// error is only logged and the truncated stream ends.
+		logger.FromContext(r.Context(), h.log).Infow("Failed to stream users", zap.Error(err), "streamStarted", stream.Started())
+		if !stream.Started() {
+			h.respondWithError(w, "Failed to retrieve users", http.StatusInternalServerError)
+		}
+		return
+	}
 
 	users, err := h.usersSvc.GetUsers(r.Context())
 	if err != nil {
-		h.log.Infow("Failed to get users", zap.Error(err))
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get users", zap.Error(err))
 		h.respondWithError(w, "Failed to retrieve users", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("Users retrieved successfully", "count", len(users))
+	logger.FromContext(r.Context(), h.log).Infow("Users retrieved successfully", "count", len(users))
 	response.RespondSuccess(w, http.StatusOK, "Success", users)
 }
 
@@ -66,16 +95,17 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Get user request received", zap.String("userId", userID))
+	logger.FromContext(r.Context(), h.log).Infow("Get user request received", zap.String("userId", userID))
 
 	user, err := h.usersSvc.GetUser(r.Context(), userID)
 	if err != nil {
-		h.log.Infow("Failed to get user", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to retrieve user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User retrieved successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User retrieved successfully", "userId", userID)
+	w.Header().Set("ETag", userETag(user))
 	response.RespondSuccess(w, http.StatusOK, "Success", user)
 }
 
@@ -86,26 +116,121 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Update user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Update user request received", "userId", userID)
 
 	var req models.UpdateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.Infow("Failed to decode update user request", zap.Error(err))
-		h.respondWithError(w, "Invalid request body", http.StatusBadRequest)
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode update user request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !h.checkPrecondition(w, r, userID) {
 		return
 	}
 
 	user, err := h.usersSvc.UpdateUser(r.Context(), userID, &req)
 	if err != nil {
-		h.log.Infow("Failed to update user", zap.Error(err), "userId", userID)
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to update user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User updated successfully", zap.String("userId", userID))
+	logger.FromContext(r.Context(), h.log).Infow("User updated successfully", zap.String("userId", userID))
+	w.Header().Set("ETag", userETag(user))
 	response.RespondSuccess(w, http.StatusOK, "User updated successfully", user)
 }
 
+// PatchUser applies a JSON Merge Patch or JSON Patch to the fields PUT
+// accepts, so a client can change one of them without sending the others.
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "userID")
+	if userID == "" {
+		h.respondWithError(w, "User ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Patch user request received", "userId", userID)
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, patch.MaxBodySize))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		h.respondWithError(w, "Request body is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		h.respondWithError(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.usersSvc.GetUser(r.Context(), userID)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return
+	}
+	if !h.preconditionMet(w, r, user) {
+		return
+	}
+
+	profile := user.Profile
+	if profile == nil {
+		profile = make(map[string]any)
+	}
+	current := map[string]any{
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+		"profile":   profile,
+	}
+
+	var patched models.UpdateUserRequest
+	if err := patch.Apply(r.Header.Get("Content-Type"), current, body, &patched); err != nil {
+		h.respondPatchError(w, r, err)
+		return
+	}
+	if patched.FirstName == "" || patched.LastName == "" {
+		h.respondWithError(w, "The user's firstName and lastName cannot be removed", http.StatusUnprocessableEntity)
+		return
+	}
+
+	// Okta updates users partially, so only what the patch changed is sent,
+	// with null for the profile attributes it removed.
+	req := &models.UpdateUserRequest{Profile: make(map[string]any)}
+	if patched.FirstName != user.FirstName {
+		req.FirstName = patched.FirstName
+	}
+	if patched.LastName != user.LastName {
+		req.LastName = patched.LastName
+	}
+	for key, value := range patched.Profile {
+		if previous, ok := user.Profile[key]; !ok || !reflect.DeepEqual(previous, value) {
+			req.Profile[key] = value
+		}
+	}
+	for key := range user.Profile {
+		if _, ok := patched.Profile[key]; !ok {
+			req.Profile[key] = nil
+		}
+	}
+
+	updated, err := h.usersSvc.UpdateUser(r.Context(), userID, req)
+	if err != nil {
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to patch user", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Failed to update user", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("User patched successfully", "userId", userID)
+	w.Header().Set("ETag", userETag(updated))
+	response.RespondSuccess(w, http.StatusOK, "User updated successfully", updated)
+}
+
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userID := chi.URLParam(r, "userID")
 	if userID == "" {
@@ -113,16 +238,23 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Delete user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Delete user request received", "userId", userID)
+
+	if !h.checkPrecondition(w, r, userID) {
+		return
+	}
 
 	err := h.usersSvc.DeleteUser(r.Context(), userID)
 	if err != nil {
-		h.log.Infow("Failed to delete user", zap.Error(err), "userId", userID)
+		if h.respondIfRejected(w, err) {
+			return
+		}
+		logger.FromContext(r.Context(), h.log).Infow("Failed to delete user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to delete user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User deleted successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User deleted successfully", "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User deleted successfully", nil)
 }
 
@@ -133,16 +265,16 @@ func (h *Handler) ActivateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Activate user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Activate user request received", "userId", userID)
 
 	err := h.usersSvc.ActivateUser(r.Context(), userID)
 	if err != nil {
-		h.log.Infow("Failed to activate user", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to activate user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to activate user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User activated successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User activated successfully", "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User activated successfully", nil)
 }
 
@@ -153,15 +285,15 @@ func (h *Handler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Deactivate user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Deactivate user request received", "userId", userID)
 
 	if err := h.usersSvc.DeactivateUser(r.Context(), userID); err != nil {
-		h.log.Infow("Failed to deactivate user", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to deactivate user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to deactivate user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User deactivated successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User deactivated successfully", "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User deactivated successfully", nil)
 }
 
@@ -172,15 +304,15 @@ func (h *Handler) SuspendUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Suspend user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Suspend user request received", "userId", userID)
 
 	if err := h.usersSvc.SuspendUser(r.Context(), userID); err != nil {
-		h.log.Infow("Failed to suspend user", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to suspend user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to suspend user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User suspended successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User suspended successfully", "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User suspended successfully", nil)
 }
 
@@ -191,18 +323,98 @@ func (h *Handler) UnSuspendUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.log.Infow("Unsuspend user request received", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("Unsuspend user request received", "userId", userID)
 
 	if err := h.usersSvc.UnsuspendUser(r.Context(), userID); err != nil {
-		h.log.Infow("Failed to unsuspend user", zap.Error(err), "userId", userID)
+		logger.FromContext(r.Context(), h.log).Infow("Failed to unsuspend user", zap.Error(err), "userId", userID)
 		h.respondWithError(w, "Failed to unsuspend user", http.StatusInternalServerError)
 		return
 	}
 
-	h.log.Infow("User unsuspended successfully", "userId", userID)
+	logger.FromContext(r.Context(), h.log).Infow("User unsuspended successfully", "userId", userID)
 	response.RespondSuccess(w, http.StatusOK, "User unsuspended successfully", nil)
 }
 
+// checkPrecondition answers 412 and returns false when the request has an
+// If-Match header that does not name the user's current entity tag, so a
+// client does not overwrite or delete a change made since it read the user.
+// A user that no longer exists has no tag to match, so it fails too.
+func (h *Handler) checkPrecondition(w http.ResponseWriter, r *http.Request, userID string) bool {
+	if r.Header.Get("If-Match") == "" {
+		return true
+	}
+
+	user, err := h.usersSvc.GetUser(r.Context(), userID)
+	if errors.Is(err, user_service.ErrUserNotFound) {
+		logger.FromContext(r.Context(), h.log).Infow("User for If-Match not found", "userId", userID)
+		response.RespondError(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+			"The user no longer exists", nil,
+		)
+		return false
+	}
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to get user for If-Match", zap.Error(err), "userId", userID)
+		h.respondWithError(w, "Failed to retrieve user", http.StatusInternalServerError)
+		return false
+	}
+
+	return h.preconditionMet(w, r, user)
+}
+
+// preconditionMet is checkPrecondition for a user already read.
+func (h *Handler) preconditionMet(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	etag := userETag(user)
+	if !request.IfMatch(r, etag) {
+		logger.FromContext(r.Context(), h.log).Infow("User changed since it was read", "userId", user.ID)
+		w.Header().Set("ETag", etag)
+		response.RespondError(w, http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+			"The user has changed since it was read", nil,
+		)
+		return false
+	}
+	return true
+}
+
+// respondPatchError answers a patch that could not be applied: 415 for other
+// media types, 400 for malformed patches, 409 for patches that do not fit the
+// resource as it is, and 422 for patches that would make it invalid.
+func (h *Handler) respondPatchError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, patch.ErrUnsupportedMediaType):
+		h.respondWithError(w, err.Error(), http.StatusUnsupportedMediaType)
+	case errors.Is(err, patch.ErrInvalidPatch):
+		h.respondWithError(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, patch.ErrConflict):
+		h.respondWithError(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, patch.ErrInvalidResult):
+		h.respondWithError(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logger.FromContext(r.Context(), h.log).Infow("Failed to apply patch", zap.Error(err))
+		h.respondWithError(w, "Failed to apply patch", http.StatusInternalServerError)
+	}
+}
+
+// userETag changes whenever Okta updates the user, such as its profile or
+// status.
+func userETag(user *models.User) string {
+	var lastUpdated string
+	if user.LastUpdated != nil {
+		lastUpdated = user.LastUpdated.Format(time.RFC3339Nano)
+	}
+	return response.ETag(user.ID, lastUpdated, user.Status)
+}
+
 func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
 	response.RespondError(w, statusCode, "API_ERROR", message, nil)
 }
+
+// respondIfRejected answers 422 with the hook's message when a before hook
+// rejected the operation.
+func (h *Handler) respondIfRejected(w http.ResponseWriter, err error) bool {
+	var rejectedErr *hooks.RejectedError
+	if !errors.As(err, &rejectedErr) {
+		return false
+	}
+	response.RespondError(w, http.StatusUnprocessableEntity, "HOOK_REJECTED", rejectedErr.Error(), nil)
+	return true
+}
@@ -0,0 +1,133 @@
+package webhook_handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/request"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+type Handler struct {
+	log         *zap.SugaredLogger
+	webhooksSvc *webhook_service.Service
+}
+
+func New(log *zap.SugaredLogger, svc *webhook_service.Service) *Handler {
+	return &Handler{log: log, webhooksSvc: svc}
+}
+
+func (h *Handler) CreateSubscriber(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Create webhook subscriber request received")
+
+	var req models.CreateWebhookSubscriberRequest
+	if err := request.Decode(r, &req); err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to decode create webhook subscriber request", zap.Error(err))
+		h.respondWithError(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		h.respondWithError(w, "A valid http or https URL is required", http.StatusBadRequest)
+		return
+	}
+
+	subscriber, err := h.webhooksSvc.CreateSubscriber(r.Context(), &req)
+	if err != nil {
+		logger.FromContext(r.Context(), h.log).Infow("Failed to create webhook subscriber", zap.Error(err), "url", req.URL)
+		h.respondWithError(w, "Failed to create webhook subscriber", http.StatusInternalServerError)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Webhook subscriber created successfully", "subscriberId", subscriber.ID)
+	response.RespondSuccess(w, http.StatusCreated, "Webhook subscriber created successfully", subscriber)
+}
+
+func (h *Handler) GetSubscribers(w http.ResponseWriter, r *http.Request) {
+	logger.FromContext(r.Context(), h.log).Infow("Get webhook subscribers request received")
+
+	subscribers := h.webhooksSvc.GetSubscribers(r.Context())
+
+	logger.FromContext(r.Context(), h.log).Infow("Webhook subscribers retrieved successfully", "count", len(subscribers))
+	response.RespondSuccess(w, http.StatusOK, "Success", subscribers)
+}
+
+func (h *Handler) GetSubscriber(w http.ResponseWriter, r *http.Request) {
+	subscriberID := chi.URLParam(r, "subscriberID")
+	if subscriberID == "" {
+		h.respondWithError(w, "Subscriber ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Get webhook subscriber request received", "subscriberId", subscriberID)
+
+	subscriber, err := h.webhooksSvc.GetSubscriber(r.Context(), subscriberID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to retrieve webhook subscriber", subscriberID)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Success", subscriber)
+}
+
+func (h *Handler) DeleteSubscriber(w http.ResponseWriter, r *http.Request) {
+	subscriberID := chi.URLParam(r, "subscriberID")
+	if subscriberID == "" {
+		h.respondWithError(w, "Subscriber ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Delete webhook subscriber request received", "subscriberId", subscriberID)
+
+	if err := h.webhooksSvc.DeleteSubscriber(r.Context(), subscriberID); err != nil {
+		h.handleServiceError(w, r, err, "Failed to delete webhook subscriber", subscriberID)
+		return
+	}
+
+	response.RespondSuccess(w, http.StatusOK, "Webhook subscriber deleted successfully", nil)
+}
+
+func (h *Handler) TestSubscriber(w http.ResponseWriter, r *http.Request) {
+	subscriberID := chi.URLParam(r, "subscriberID")
+	if subscriberID == "" {
+		h.respondWithError(w, "Subscriber ID is required", http.StatusBadRequest)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Test webhook subscriber request received", "subscriberId", subscriberID)
+
+	result, err := h.webhooksSvc.TestSubscriber(r.Context(), subscriberID)
+	if err != nil {
+		h.handleServiceError(w, r, err, "Failed to send test event", subscriberID)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow("Test event sent to webhook subscriber",
+		"subscriberId", subscriberID,
+		"success", result.Success,
+		"statusCode", result.StatusCode,
+	)
+	response.RespondSuccess(w, http.StatusOK, "Test event sent", result)
+}
+
+func (h *Handler) handleServiceError(w http.ResponseWriter, r *http.Request, err error, message, subscriberID string) {
+	if errors.Is(err, webhook_service.ErrSubscriberNotFound) {
+		h.respondWithError(w, "Webhook subscriber not found", http.StatusNotFound)
+		return
+	}
+
+	logger.FromContext(r.Context(), h.log).Infow(message, zap.Error(err), "subscriberId", subscriberID)
+	h.respondWithError(w, message, http.StatusInternalServerError)
+}
+
+func (h *Handler) respondWithError(w http.ResponseWriter, message string, statusCode int) {
+	response.RespondError(w, statusCode, "API_ERROR", message, nil)
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+const (
+	AccessRequestStatusPending  string = "PENDING"
+	AccessRequestStatusApproved string = "APPROVED"
+	AccessRequestStatusDenied   string = "DENIED"
+)
+
+const (
+	AuditActionAccessRequested       string = "access_request.created"
+	AuditActionAccessRequestApproved string = "access_request.approved"
+	AuditActionAccessRequestDenied   string = "access_request.denied"
+)
+
+// ProtectedGroup represents a group whose membership can only be obtained
+// through an approved access request.
+type ProtectedGroup struct {
+	GroupID     string   `json:"groupId"`
+	ApproverIDs []string `json:"approverIds"`
+	// MaxDurationHours caps the membership granted on approval; 0 allows permanent access.
+	MaxDurationHours int `json:"maxDurationHours,omitempty"`
+}
+
+// UpdateProtectedGroupRequest represents the data needed to protect a group.
+type UpdateProtectedGroupRequest struct {
+	ApproverIDs      []string `json:"approverIds"`
+	MaxDurationHours int      `json:"maxDurationHours"`
+}
+
+// AccessRequest represents a user's request to join a protected group.
+type AccessRequest struct {
+	ID              string     `json:"id"`
+	GroupID         string     `json:"groupId"`
+	RequesterID     string     `json:"requesterId"`
+	Justification   string     `json:"justification"`
+	DurationHours   int        `json:"durationHours,omitempty"`
+	Status          string     `json:"status"`
+	Created         time.Time  `json:"created"`
+	DecidedBy       string     `json:"decidedBy,omitempty"`
+	DecidedAt       *time.Time `json:"decidedAt,omitempty"`
+	DecisionComment string     `json:"decisionComment,omitempty"`
+	ExpiresAt       *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CreateAccessRequestRequest represents the data needed to request access to a group.
+// A zero DurationHours requests permanent membership.
+type CreateAccessRequestRequest struct {
+	GroupID       string `json:"groupId"`
+	Justification string `json:"justification"`
+	DurationHours int    `json:"durationHours"`
+}
+
+// AccessRequestDecision represents an approver's decision on a request.
+type AccessRequestDecision struct {
+	Comment string `json:"comment"`
+}
+
+// AccessRequestFilter narrows the access requests returned by a listing.
+type AccessRequestFilter struct {
+	Status      string
+	GroupID     string
+	RequesterID string
+	ApproverID  string
+}
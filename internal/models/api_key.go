@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	ResourceTypeAPIKey string = "api_key"
+
+	AuditActionAPIKeyIssued  string = "api_key.issued"
+	AuditActionAPIKeyRotated string = "api_key.rotated"
+	AuditActionAPIKeyRevoked string = "api_key.revoked"
+)
+
+const (
+	// APIKeyScopeRead allows reads, including the read-only POST routes.
+	APIKeyScopeRead string = "read"
+	// APIKeyScopeWrite allows every request and implies APIKeyScopeRead.
+	APIKeyScopeWrite string = "write"
+)
+
+// APIKey is a credential a machine client, such as a CI pipeline, sends in
+// the X-API-Key header instead of an access token. Only a hash of its secret
+// is kept; the key itself is returned once, when it is issued or rotated.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	CreatedBy string     `json:"createdBy"`
+	Created   time.Time  `json:"created"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	LastUsed  *time.Time `json:"lastUsed,omitempty"`
+	Rotated   *time.Time `json:"rotated,omitempty"`
+	Revoked   *time.Time `json:"revoked,omitempty"`
+	RevokedBy string     `json:"revokedBy,omitempty"`
+}
+
+// HasScope reports whether the key allows requests needing scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.Scopes {
+		if granted == scope || granted == APIKeyScopeWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// IssuedAPIKey is returned when a key is issued or rotated. Key is never
+// returned again.
+type IssuedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+	// PreviousKeyExpires is when the secret replaced by a rotation stops
+	// working.
+	PreviousKeyExpires *time.Time `json:"previousKeyExpires,omitempty"`
+}
+
+// CreateAPIKeyRequest represents the data needed to issue an API key. Keys
+// issued without an expiry last for the configured default lifetime.
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
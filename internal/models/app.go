@@ -0,0 +1,124 @@
+package models
+
+import (
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+)
+
+const (
+	AppStatusActive   string = "ACTIVE"
+	AppStatusInactive string = "INACTIVE"
+
+	AccessPathDirect string = "DIRECT"
+	AccessPathGroup  string = "GROUP"
+)
+
+// App represents an application integration configured in Okta.
+// Examples: "Salesforce", "GitHub Enterprise", "Internal Billing Portal".
+type App struct {
+	ID          string     `json:"id"`
+	Label       string     `json:"label"`
+	Status      string     `json:"status"`
+	SignOnMode  string     `json:"signOnMode"`
+	Created     *time.Time `json:"created,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+// AppAccess lists one page of the users who can use an app, each with every
+// path their access comes from. Counts cover all users, not just the page.
+type AppAccess struct {
+	App    *App             `json:"app"`
+	Counts AppAccessCounts  `json:"counts"`
+	Users  []*AppAccessUser `json:"users"`
+	// Next is the after cursor of the following page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+type AppAccessCounts struct {
+	Users int `json:"users"`
+	// Direct and ViaGroups overlap for users assigned both ways.
+	Direct         int `json:"direct"`
+	ViaGroups      int `json:"viaGroups"`
+	AssignedGroups int `json:"assignedGroups"`
+}
+
+type AppAccessUser struct {
+	UserID string       `json:"userId"`
+	Login  string       `json:"login"`
+	Paths  []AccessPath `json:"paths"`
+}
+
+// AccessPath is a direct assignment, or membership of an assigned group.
+type AccessPath struct {
+	Type      string `json:"type"`
+	GroupID   string `json:"groupId,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+}
+
+// GroupApp is an app a group is assigned to, with what the assignment grants.
+type GroupApp struct {
+	App        *App               `json:"app"`
+	Assignment GroupAppAssignment `json:"assignment"`
+}
+
+// GroupAppAssignment is how a group is assigned to an app. Profile holds the
+// app profile attributes given to members who get the app through the group;
+// when several of a user's groups are assigned, the lowest Priority wins.
+type GroupAppAssignment struct {
+	Priority    *int32         `json:"priority,omitempty"`
+	Profile     map[string]any `json:"profile,omitempty"`
+	LastUpdated *time.Time     `json:"lastUpdated,omitempty"`
+}
+
+// AppGroup is a group assigned to an app, with what the assignment grants.
+type AppGroup struct {
+	GroupID    string             `json:"groupId"`
+	GroupName  string             `json:"groupName"`
+	Assignment GroupAppAssignment `json:"assignment"`
+}
+
+// AppAccessPage selects a page of users ordered by login. After is the login
+// of the last user of the previous page. A Path of DIRECT or GROUP keeps only
+// the users with an access path of that type.
+type AppAccessPage struct {
+	Limit int
+	After string
+	Path  string
+}
+
+func ConvertOktaAppToModel(oktaApp *okta.ListApplications200ResponseInner) *App {
+	var base *okta.Application
+
+	switch instance := oktaApp.GetActualInstance().(type) {
+	case *okta.AutoLoginApplication:
+		base = &instance.Application
+	case *okta.BasicAuthApplication:
+		base = &instance.Application
+	case *okta.BookmarkApplication:
+		base = &instance.Application
+	case *okta.BrowserPluginApplication:
+		base = &instance.Application
+	case *okta.OpenIdConnectApplication:
+		base = &instance.Application
+	case *okta.Saml11Application:
+		base = &instance.Application
+	case *okta.SamlApplication:
+		base = &instance.Application
+	case *okta.SecurePasswordStoreApplication:
+		base = &instance.Application
+	case *okta.WsFederationApplication:
+		base = &instance.Application
+	default:
+		return &App{}
+	}
+
+	return &App{
+		ID:          base.GetId(),
+		Label:       base.GetLabel(),
+		Status:      base.GetStatus(),
+		SignOnMode:  base.GetSignOnMode(),
+		Created:     base.Created,
+		LastUpdated: base.LastUpdated,
+	}
+}
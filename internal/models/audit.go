@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+const (
+	AuditActionMembershipExpired string = "group.membership.expired"
+)
+
+// AuditEntry records a change made to Okta by this service.
+type AuditEntry struct {
+	ID           string         `json:"id"`
+	Timestamp    time.Time      `json:"timestamp"`
+	Actor        string         `json:"actor"`
+	Action       string         `json:"action"`
+	ResourceType string         `json:"resourceType"`
+	ResourceID   string         `json:"resourceId"`
+	Details      map[string]any `json:"details,omitempty"`
+}
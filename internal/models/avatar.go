@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// AvatarURL is a time-limited, signed link to a user's profile photo.
+type AvatarURL struct {
+	UserID    string    `json:"userId"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,150 @@
+package models
+
+import "time"
+
+// BackupFormatVersion is the version of the archive layout written by
+// POST /backup. Restores refuse archives of a newer version.
+const BackupFormatVersion = 1
+
+const (
+	RestoreKindGroup      string = "GROUP"
+	RestoreKindJoinPolicy string = "JOIN_POLICY"
+	RestoreKindGroupRule  string = "GROUP_RULE"
+	RestoreKindSoDPolicy  string = "SOD_POLICY"
+	RestoreKindMembership string = "MEMBERSHIP"
+)
+
+const (
+	RestoreActionCreate string = "CREATE"
+	RestoreActionUpdate string = "UPDATE"
+	RestoreActionAdd    string = "ADD"
+	RestoreActionRemove string = "REMOVE"
+)
+
+const (
+	RestoreOutcomeApplied string = "APPLIED"
+	RestoreOutcomeFailed  string = "FAILED"
+)
+
+const (
+	ResourceTypeBackup string = "backup"
+
+	AuditActionBackupCreated  string = "backup.created"
+	AuditActionBackupRestored string = "backup.restored"
+)
+
+// Backup describes a stored archive of the IAM configuration: every
+// Okta-mastered group with its join policy and members, the group rules and
+// the SoD policies.
+type Backup struct {
+	ID            string       `json:"id"`
+	FormatVersion int          `json:"formatVersion"`
+	Label         string       `json:"label,omitempty"`
+	CreatedBy     string       `json:"createdBy,omitempty"`
+	Created       time.Time    `json:"created"`
+	Counts        BackupCounts `json:"counts"`
+	Size          int          `json:"size"`
+}
+
+// BackupCounts counts what an archive holds.
+type BackupCounts struct {
+	Groups      int `json:"groups"`
+	GroupRules  int `json:"groupRules"`
+	SoDPolicies int `json:"sodPolicies"`
+	Memberships int `json:"memberships"`
+}
+
+// CreateBackupRequest represents the optional data accepted when taking a
+// backup.
+type CreateBackupRequest struct {
+	Label string `json:"label,omitempty"`
+}
+
+// BackupArchive is the content of a backup, stored gzipped as JSON.
+type BackupArchive struct {
+	Backup
+	Groups      []*BackupGroup     `json:"groups"`
+	GroupRules  []*BackupGroupRule `json:"groupRules"`
+	SoDPolicies []*SoDPolicy       `json:"sodPolicies"`
+}
+
+// BackupGroup is a group as it was when backed up.
+type BackupGroup struct {
+	ID          string         `json:"id"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	JoinPolicy  string         `json:"joinPolicy"`
+	Profile     map[string]any `json:"profile,omitempty"`
+	Members     []BackupMember `json:"members"`
+}
+
+// BackupMember identifies a member by ID, with the login for people reading
+// the archive or a restore preview.
+type BackupMember struct {
+	ID    string `json:"id"`
+	Login string `json:"login,omitempty"`
+}
+
+// BackupGroupRule is a group rule as it was when backed up.
+type BackupGroupRule struct {
+	ID             string   `json:"id"`
+	Name           string   `json:"name"`
+	Status         string   `json:"status"`
+	ExpressionType string   `json:"expressionType,omitempty"`
+	Expression     string   `json:"expression"`
+	GroupIDs       []string `json:"groupIds"`
+	ExcludedUsers  []string `json:"excludedUsers,omitempty"`
+}
+
+// RestoreRequest replays a backup. Include limits the restore to some kinds
+// of change (GROUP, JOIN_POLICY, GROUP_RULE, SOD_POLICY, MEMBERSHIP); all are
+// restored by default. Members added since the backup are only removed with
+// RemoveExtraMembers.
+type RestoreRequest struct {
+	BackupID           string   `json:"backupId"`
+	Include            []string `json:"include,omitempty"`
+	RemoveExtraMembers bool     `json:"removeExtraMembers"`
+	// DryRun returns the diff without changing anything.
+	DryRun bool `json:"dryRun"`
+}
+
+// Restore is the diff between a backup and the org, and what became of each
+// change once applied. Items the org already matches are only counted.
+type Restore struct {
+	BackupID string           `json:"backupId"`
+	DryRun   bool             `json:"dryRun"`
+	Summary  RestoreSummary   `json:"summary"`
+	Changes  []*RestoreChange `json:"changes"`
+}
+
+// RestoreSummary counts the changes by action.
+type RestoreSummary struct {
+	Create    int `json:"create"`
+	Update    int `json:"update"`
+	Add       int `json:"add"`
+	Remove    int `json:"remove"`
+	Unchanged int `json:"unchanged"`
+	Failed    int `json:"failed"`
+}
+
+// RestoreChange is one difference between the backup and the org. Key is the
+// group, rule or policy name, or "group/login" for a membership. CurrentID
+// is the matching item in the org, if any; Diff holds the fields an update
+// changes.
+type RestoreChange struct {
+	Kind      string         `json:"kind"`
+	Action    string         `json:"action"`
+	Key       string         `json:"key"`
+	BackupID  string         `json:"backupId,omitempty"`
+	CurrentID string         `json:"currentId,omitempty"`
+	Diff      []*RestoreDiff `json:"diff,omitempty"`
+	Outcome   string         `json:"outcome,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// RestoreDiff is a field whose current value the restore replaces.
+type RestoreDiff struct {
+	Field   string `json:"field"`
+	Current any    `json:"current"`
+	Backup  any    `json:"backup"`
+}
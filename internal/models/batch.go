@@ -0,0 +1,46 @@
+package models
+
+const (
+	ResourceTypeUser  string = "user"
+	ResourceTypeGroup string = "group"
+)
+
+// MaxBatchGetResources caps the number of references accepted by a single
+// batch read so one request cannot fan out into an unbounded number of Okta calls.
+const MaxBatchGetResources = 100
+
+// MaxUserLookups caps the number of identifiers resolved by a single lookup.
+const MaxUserLookups = 100
+
+// ResourceRef identifies a single resource by its type and ID.
+type ResourceRef struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// BatchGetRequest represents a list of resources to be fetched in one call.
+type BatchGetRequest struct {
+	Resources []ResourceRef `json:"resources"`
+}
+
+// BatchGetResult holds the outcome of fetching one referenced resource.
+// Exactly one of Data or Error is set.
+type BatchGetResult struct {
+	Type  string `json:"type"`
+	ID    string `json:"id"`
+	Data  any    `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// UserLookupRequest lists the logins or emails of users to resolve.
+type UserLookupRequest struct {
+	Identifiers []string `json:"identifiers"`
+}
+
+// UserLookupResult holds the outcome of resolving one identifier. Exactly
+// one of User or Error is set.
+type UserLookupResult struct {
+	Identifier string `json:"identifier"`
+	User       *User  `json:"user,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
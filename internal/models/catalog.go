@@ -0,0 +1,39 @@
+package models
+
+// Catalog actions tell a UI what a user can do with a listed group.
+const (
+	CatalogActionJoin    string = "JOIN"
+	CatalogActionRequest string = "REQUEST"
+	CatalogActionPending string = "PENDING"
+	CatalogActionNone    string = "NONE"
+)
+
+const (
+	JoinStatusJoined    string = "JOINED"
+	JoinStatusRequested string = "REQUESTED"
+
+	AuditActionGroupSelfJoined string = "group.self_joined"
+)
+
+// CatalogGroup is a group listed in the self-service catalog for a user.
+type CatalogGroup struct {
+	*Group
+	IsMember bool   `json:"isMember"`
+	Action   string `json:"action"`
+}
+
+// JoinGroupRequest is sent when a user joins a group from the catalog. The
+// justification and duration are only used by APPROVAL groups. Consent is
+// required while a consent text for joins is configured.
+type JoinGroupRequest struct {
+	Justification string                  `json:"justification"`
+	DurationHours int                     `json:"durationHours"`
+	Consent       *ConsentAcknowledgement `json:"consent,omitempty"`
+}
+
+// JoinGroupResult reports whether the user joined the group or an access
+// request was opened on their behalf.
+type JoinGroupResult struct {
+	Status        string         `json:"status"`
+	AccessRequest *AccessRequest `json:"accessRequest,omitempty"`
+}
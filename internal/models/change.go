@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+const (
+	// ChangeSourceAPI marks a mutation made through this service's API.
+	ChangeSourceAPI = "api"
+	// ChangeSourceOkta marks a change found in Okta when the directory index
+	// was refreshed, whoever made it.
+	ChangeSourceOkta = "okta"
+
+	ChangeTypeAPIRequest         = "api.request"
+	ChangeTypeUserCreated        = "user.created"
+	ChangeTypeUserUpdated        = "user.updated"
+	ChangeTypeUserDeleted        = "user.deleted"
+	ChangeTypeGroupCreated       = "group.created"
+	ChangeTypeGroupUpdated       = "group.updated"
+	ChangeTypeGroupDeleted       = "group.deleted"
+	ChangeTypeGroupMemberAdded   = "group.member.added"
+	ChangeTypeGroupMemberRemoved = "group.member.removed"
+)
+
+// Change is one entry of the change feed. Sequence increases by one with
+// every change; Cursor resumes the feed right after this change.
+type Change struct {
+	Sequence     int64          `json:"sequence"`
+	Cursor       string         `json:"cursor"`
+	Source       string         `json:"source"`
+	Type         string         `json:"type"`
+	ResourceType string         `json:"resourceType"`
+	ResourceID   string         `json:"resourceId,omitempty"`
+	OccurredAt   time.Time      `json:"occurredAt"`
+	RequestID    string         `json:"requestId,omitempty"`
+	Details      map[string]any `json:"details,omitempty"`
+}
+
+// ChangeFeed is one page of changes, oldest first.
+type ChangeFeed struct {
+	Changes []*Change `json:"changes"`
+	// Next is the cursor to ask for the following page with. It is returned
+	// even when the page is empty, so consumers can keep polling with it.
+	Next string `json:"next"`
+	// HasMore is set when more changes are already waiting after this page.
+	HasMore bool `json:"hasMore"`
+}
+
+// ChangeFeedPage selects the changes after Cursor, or from the oldest kept
+// change when Cursor is empty.
+type ChangeFeedPage struct {
+	Cursor string
+	Limit  int
+}
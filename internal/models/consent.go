@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	ConsentActionGroupJoin string = "group.join"
+)
+
+// ConsentText is what a user acknowledges before a self-service action. A
+// new version is published whenever the text changes.
+type ConsentText struct {
+	Action  string `json:"action"`
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+// ConsentAcknowledgement is sent with a self-service action to acknowledge
+// the version of its consent text the user was shown.
+type ConsentAcknowledgement struct {
+	Version string `json:"version"`
+}
+
+// ConsentRecord is a user's acknowledgement of a consent text, kept with the
+// text as it read then. It is recorded before the action is taken, so it
+// stands even if the action then fails.
+type ConsentRecord struct {
+	ID           string    `json:"id"`
+	UserID       string    `json:"userId"`
+	Action       string    `json:"action"`
+	Version      string    `json:"version"`
+	Text         string    `json:"text"`
+	ResourceType string    `json:"resourceType"`
+	ResourceID   string    `json:"resourceId"`
+	RequestID    string    `json:"requestId,omitempty"`
+	Recorded     time.Time `json:"recorded"`
+}
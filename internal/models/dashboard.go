@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Dashboard is the data behind the operations dashboard. Every section is
+// kept up to date in the background, as the change feed and the directory
+// index advance, rather than computed when asked for.
+type Dashboard struct {
+	Users     *DashboardUsers     `json:"users"`
+	Groups    *DashboardGroups    `json:"groups"`
+	Mutations *DashboardMutations `json:"mutations"`
+	Okta      *DashboardOkta      `json:"okta"`
+}
+
+// DashboardUsers counts the users of the directory index by status. It is
+// empty until the index has been built.
+type DashboardUsers struct {
+	Index    *DirectoryIndex `json:"index,omitempty"`
+	Total    int             `json:"total"`
+	ByStatus map[string]int  `json:"byStatus"`
+}
+
+// DashboardGroups counts the groups created and deleted in Okta each day, as
+// the directory index saw them, oldest day first. Days without any are
+// left out.
+type DashboardGroups struct {
+	Total int                  `json:"total"`
+	Days  []*DashboardGroupDay `json:"days"`
+	// Since is the first day counted: the start of the window kept, or the
+	// day counting started, whichever is later.
+	Since time.Time `json:"since"`
+}
+
+type DashboardGroupDay struct {
+	Date    string `json:"date"`
+	Created int    `json:"created"`
+	Deleted int    `json:"deleted"`
+}
+
+// DashboardMutations is the mutations made through this service: how many
+// since counting started, and the most recent ones, newest first.
+type DashboardMutations struct {
+	Total  int       `json:"total"`
+	Since  time.Time `json:"since"`
+	Recent []*Change `json:"recent"`
+}
+
+// DashboardOkta is the Okta rate limit headroom and the depth of the work
+// queues, as of UpdatedAt.
+type DashboardOkta struct {
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Headroom is the share, from 0 to 1, left of the most used rate limit
+	// window.
+	Headroom       float64          `json:"headroom"`
+	RateLimits     []*OktaRateLimit `json:"rateLimits"`
+	Jobs           int              `json:"jobs"`
+	Retries        int              `json:"retries"`
+	WebhookBacklog int              `json:"webhookBacklog"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+const (
+	JobTypeBulkDeactivation string = "user.bulk_deactivation"
+
+	DeactivationStepDeactivate = "deactivate_users"
+
+	AuditActionUserBulkDeactivated string = "user.bulk_deactivated"
+)
+
+const (
+	DeactivationOutcomeDeactivated string = "DEACTIVATED"
+	DeactivationOutcomeSkipped     string = "SKIPPED"
+	DeactivationOutcomeFailed      string = "FAILED"
+)
+
+// BulkDeactivationRequest selects users to deactivate, by ID or with an Okta
+// search expression, for a dry run. Its preview lists exactly who would be
+// deactivated, and only a preview can be started.
+type BulkDeactivationRequest struct {
+	UserIDs []string `json:"userIds"`
+	// Filter is an Okta search expression such as
+	// profile.department eq "Contractors".
+	Filter string `json:"filter"`
+	// Exclude lists user IDs or logins to leave out, besides those excluded
+	// by configuration.
+	Exclude []string `json:"exclude"`
+}
+
+// StartBulkDeactivationRequest starts the deactivation a dry run previewed.
+type StartBulkDeactivationRequest struct {
+	PreviewID string `json:"previewId"`
+}
+
+// BulkDeactivationPreview is the outcome of a dry run: the users a
+// deactivation would change and those it would leave out. It can be started
+// once, by the caller who made it, until it expires.
+type BulkDeactivationPreview struct {
+	ID        string                   `json:"id"`
+	Users     []*DeactivationTarget    `json:"users"`
+	Excluded  []*DeactivationExclusion `json:"excluded"`
+	Created   time.Time                `json:"created"`
+	ExpiresAt time.Time                `json:"expiresAt"`
+}
+
+// DeactivationTarget is a user a bulk deactivation would deactivate.
+type DeactivationTarget struct {
+	UserID string `json:"userId"`
+	Login  string `json:"login"`
+	Status string `json:"status"`
+}
+
+// DeactivationExclusion is a user a bulk deactivation leaves out, and why.
+type DeactivationExclusion struct {
+	UserID string `json:"userId"`
+	Login  string `json:"login,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// DeactivationResult is what a bulk deactivation did to one user.
+type DeactivationResult struct {
+	UserID  string `json:"userId"`
+	Login   string `json:"login"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
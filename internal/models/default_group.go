@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	// DefaultGroupAttributeUserType and DefaultGroupAttributeDepartment are
+	// the profile attributes default group rules match new users on.
+	DefaultGroupAttributeUserType   string = "userType"
+	DefaultGroupAttributeDepartment string = "department"
+
+	DefaultGroupRuleSourceConfig string = "CONFIG"
+	DefaultGroupRuleSourceAPI    string = "API"
+
+	AuditActionDefaultGroupsApplied string = "user.default_groups_applied"
+
+	JobTypeDefaultGroupRemediation string = "user.default_group_remediation"
+
+	RemediationStepFind = "find_missing"
+	RemediationStepAdd  = "add_members"
+)
+
+// DefaultGroupRule adds new users whose profile Attribute equals Value to
+// GroupIDs. Rules from configuration cannot be changed through the API.
+type DefaultGroupRule struct {
+	ID        string     `json:"id"`
+	Attribute string     `json:"attribute"`
+	Value     string     `json:"value"`
+	GroupIDs  []string   `json:"groupIds"`
+	Source    string     `json:"source"`
+	Created   *time.Time `json:"created,omitempty"`
+}
+
+type CreateDefaultGroupRuleRequest struct {
+	Attribute string   `json:"attribute"`
+	Value     string   `json:"value"`
+	GroupIDs  []string `json:"groupIds"`
+}
+
+// MissingDefaultGroups is a user who is not a member of some of the groups
+// the default group rules give them.
+type MissingDefaultGroups struct {
+	UserID   string   `json:"userId"`
+	Login    string   `json:"login"`
+	GroupIDs []string `json:"groupIds"`
+}
+
+// RemediateDefaultGroupsRequest adds the listed users to the default groups
+// they are missing. Without user IDs, every user in the report is remediated.
+type RemediateDefaultGroupsRequest struct {
+	UserIDs []string `json:"userIds,omitempty"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+const (
+	DeviceAuthorizationStatusPending  string = "PENDING"
+	DeviceAuthorizationStatusSlowDown string = "SLOW_DOWN"
+	DeviceAuthorizationStatusApproved string = "APPROVED"
+	DeviceAuthorizationStatusDenied   string = "DENIED"
+	DeviceAuthorizationStatusExpired  string = "EXPIRED"
+)
+
+const (
+	ResourceTypeDeviceAuthorization string = "device_authorization"
+
+	AuditActionDeviceAuthorizationStarted  string = "device_authorization.started"
+	AuditActionDeviceAuthorizationApproved string = "device_authorization.approved"
+	AuditActionDeviceAuthorizationDenied   string = "device_authorization.denied"
+	AuditActionDeviceAuthorizationExpired  string = "device_authorization.expired"
+)
+
+// StartDeviceAuthorizationRequest starts a device authorization grant.
+// ClientName labels the device in the audit log, such as "iam-cli" or
+// "lobby-kiosk". Scopes default to the configured scopes.
+type StartDeviceAuthorizationRequest struct {
+	ClientName string   `json:"clientName,omitempty"`
+	Scopes     []string `json:"scopes,omitempty"`
+}
+
+// DeviceAuthorization is a started device flow. The device shows the user
+// UserCode and VerificationURI, then polls with DeviceCode every Interval
+// seconds until the user approves or ExpiresAt passes. ID identifies the flow
+// in the audit log, which never holds the device code.
+type DeviceAuthorization struct {
+	ID                      string    `json:"id"`
+	DeviceCode              string    `json:"deviceCode"`
+	UserCode                string    `json:"userCode"`
+	VerificationURI         string    `json:"verificationUri"`
+	VerificationURIComplete string    `json:"verificationUriComplete,omitempty"`
+	Interval                int       `json:"interval"`
+	ExpiresAt               time.Time `json:"expiresAt"`
+}
+
+type PollDeviceAuthorizationRequest struct {
+	DeviceCode string `json:"deviceCode"`
+}
+
+// DeviceAuthorizationStatus is the outcome of a poll. Tokens are only set
+// once the user has approved; Interval is the wait before the next poll
+// while the flow is pending.
+type DeviceAuthorizationStatus struct {
+	ID       string        `json:"id"`
+	Status   string        `json:"status"`
+	Interval int           `json:"interval,omitempty"`
+	Tokens   *DeviceTokens `json:"tokens,omitempty"`
+}
+
+// DeviceTokens are the tokens Okta issued to the device.
+type DeviceTokens struct {
+	TokenType    string `json:"tokenType"`
+	AccessToken  string `json:"accessToken"`
+	IDToken      string `json:"idToken,omitempty"`
+	RefreshToken string `json:"refreshToken,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	ExpiresIn    int    `json:"expiresIn"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// DirectoryIndex describes the local index a directory response was served
+// from. Directory reads never reach Okta, so the data is as old as AgeSeconds.
+type DirectoryIndex struct {
+	RefreshedAt time.Time `json:"refreshedAt"`
+	AgeSeconds  int64     `json:"ageSeconds"`
+	// Stale is set when the last refreshes failed and the index is older than
+	// twice the refresh interval.
+	Stale      bool `json:"stale"`
+	UserCount  int  `json:"userCount"`
+	GroupCount int  `json:"groupCount"`
+}
+
+type DirectoryUsers struct {
+	Index *DirectoryIndex `json:"index"`
+	Users []*User         `json:"users"`
+}
+
+type DirectoryGroups struct {
+	Index  *DirectoryIndex `json:"index"`
+	Groups []*Group        `json:"groups"`
+}
+
+// DirectoryUser is a user together with the groups they belong to.
+type DirectoryUser struct {
+	Index  *DirectoryIndex `json:"index"`
+	User   *User           `json:"user"`
+	Groups []*Group        `json:"groups"`
+}
+
+// DirectoryGroup is a group together with its members.
+type DirectoryGroup struct {
+	Index   *DirectoryIndex `json:"index"`
+	Group   *Group          `json:"group"`
+	Members []*User         `json:"members"`
+}
+
+// MaxMembershipCheckUsers bounds how many users one membership check may ask
+// about.
+const MaxMembershipCheckUsers = 1000
+
+// Sources a membership check is answered from.
+const (
+	MembershipCheckSourceIndex string = "index"
+	MembershipCheckSourceOkta  string = "okta"
+)
+
+// CheckGroupMembersRequest lists the users to check the membership of.
+type CheckGroupMembersRequest struct {
+	UserIDs []string `json:"userIds"`
+}
+
+// GroupMembershipCheck says, for each user ID asked about, whether the user
+// is a member of the group. Index is set when Source is the directory index.
+type GroupMembershipCheck struct {
+	GroupID string          `json:"groupId"`
+	Source  string          `json:"source"`
+	Index   *DirectoryIndex `json:"index,omitempty"`
+	Members map[string]bool `json:"members"`
+}
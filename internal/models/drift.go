@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const WebhookEventDriftDetected string = "iam.drift.detected"
+
+// DriftEvent is a change Okta logged that was not made through this
+// service, such as an edit in the Okta admin console.
+type DriftEvent struct {
+	// ID is the UUID of the System Log event.
+	ID             string        `json:"id"`
+	EventType      string        `json:"eventType"`
+	DisplayMessage string        `json:"displayMessage,omitempty"`
+	Published      time.Time     `json:"published"`
+	DetectedAt     time.Time     `json:"detectedAt"`
+	Actor          *DriftParty   `json:"actor"`
+	Targets        []*DriftParty `json:"targets"`
+}
+
+// DriftParty is the actor or a target of a System Log event.
+type DriftParty struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	AlternateID string `json:"alternateId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// DriftFilter selects drift events by when Okta logged them, their type
+// and who made them. Zero values match everything.
+type DriftFilter struct {
+	Since     time.Time
+	Until     time.Time
+	EventType string
+	ActorID   string
+}
+
+// DriftReport lists the out-of-band changes found so far, newest first, with
+// counts by event type and by actor login.
+type DriftReport struct {
+	// CheckedUntil is how far the System Log has been compared; changes
+	// logged after it have not been looked at yet.
+	CheckedUntil time.Time      `json:"checkedUntil"`
+	Total        int            `json:"total"`
+	ByEventType  map[string]int `json:"byEventType"`
+	ByActor      map[string]int `json:"byActor"`
+	Events       []*DriftEvent  `json:"events"`
+}
@@ -0,0 +1,20 @@
+package models
+
+const (
+	ExportFormatCSV   string = "csv"
+	ExportFormatJSONL string = "jsonl"
+)
+
+// MemberExportRecord is one group membership in an export, with the profile
+// attributes the caller asked for.
+type MemberExportRecord struct {
+	GroupID    string         `json:"groupId"`
+	GroupName  string         `json:"groupName"`
+	UserID     string         `json:"userId"`
+	Login      string         `json:"login"`
+	Email      string         `json:"email"`
+	FirstName  string         `json:"firstName"`
+	LastName   string         `json:"lastName"`
+	Status     string         `json:"status"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
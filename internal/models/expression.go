@@ -0,0 +1,46 @@
+package models
+
+const (
+	ExpressionErrorParse      string = "PARSE"
+	ExpressionErrorEvaluation string = "EVALUATION"
+)
+
+// EvaluateExpressionRequest names an Okta Expression Language expression, or
+// the group rule whose expression is tested, and the user to evaluate it
+// against: one fetched from Okta with their groups, or a profile and groups
+// supplied in the request. Without either, user attributes are null.
+type EvaluateExpressionRequest struct {
+	Expression  string            `json:"expression,omitempty"`
+	GroupRuleID string            `json:"groupRuleId,omitempty"`
+	UserID      string            `json:"userId,omitempty"`
+	Profile     map[string]any    `json:"profile,omitempty"`
+	Groups      []ExpressionGroup `json:"groups,omitempty"`
+}
+
+// ExpressionGroup is a group the user is taken to be a member of by the
+// group membership functions, such as isMemberOfGroupName.
+type ExpressionGroup struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ExpressionEvaluation is the result of an expression, or the error that
+// kept it from being parsed or evaluated. Type names the JSON type of the
+// result. A group rule's expression must evaluate to a boolean.
+type ExpressionEvaluation struct {
+	Expression  string           `json:"expression"`
+	GroupRuleID string           `json:"groupRuleId,omitempty"`
+	UserID      string           `json:"userId,omitempty"`
+	Valid       bool             `json:"valid"`
+	Result      any              `json:"result"`
+	Type        string           `json:"type,omitempty"`
+	Error       *ExpressionError `json:"error,omitempty"`
+}
+
+// ExpressionError is a parse or evaluation error, at a position counted in
+// characters from 1.
+type ExpressionError struct {
+	Kind     string `json:"kind"`
+	Message  string `json:"message"`
+	Position int    `json:"position"`
+}
@@ -12,6 +12,28 @@ const (
 	GroupTypeOkta    string = "OKTA_GROUP"
 )
 
+// Join policies control whether users can join a group on their own.
+// Groups without a policy are INVITE_ONLY.
+const (
+	// JoinPolicyOpen lets any user join through the catalog.
+	JoinPolicyOpen string = "OPEN"
+	// JoinPolicyApproval requires an approved access request.
+	JoinPolicyApproval string = "APPROVAL"
+	// JoinPolicyInviteOnly leaves membership to admins.
+	JoinPolicyInviteOnly string = "INVITE_ONLY"
+	// JoinPolicyHidden is invite-only and also kept out of the catalog.
+	JoinPolicyHidden string = "HIDDEN"
+)
+
+// IsJoinPolicy reports whether policy is one of the join policies.
+func IsJoinPolicy(policy string) bool {
+	switch policy {
+	case JoinPolicyOpen, JoinPolicyApproval, JoinPolicyInviteOnly, JoinPolicyHidden:
+		return true
+	}
+	return false
+}
+
 // Group represents a collection of users with similar access needs.
 // For example: "Engineering", "Sales", "Managers", "contractors".
 type Group struct {
@@ -19,17 +41,21 @@ type Group struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
 	Type        string         `json:"type"`
+	JoinPolicy  string         `json:"joinPolicy"`
 	Created     time.Time      `json:"created"`
 	LastUpdated time.Time      `json:"lastUpdated"`
 	Profile     map[string]any `json:"profile,omitempty"`
+	Metadata    *GroupMetadata `json:"metadata,omitempty"`
 	Members     []User         `json:"members,omitempty"`
 	Roles       []Role         `json:"roles,omitempty"`
 }
 
 // CreateGroupRequest represents the data needed to create a new group.
+// JoinPolicy defaults to INVITE_ONLY.
 type CreateGroupRequest struct {
 	Name        string         `json:"name"`
 	Description string         `json:"description"`
+	JoinPolicy  string         `json:"joinPolicy,omitempty"`
 	Profile     map[string]any `json:"profile,omitempty"`
 }
 
@@ -37,9 +63,41 @@ type CreateGroupRequest struct {
 type UpdateGroupRequest struct {
 	Name        string         `json:"name,omitempty"`
 	Description string         `json:"description,omitempty"`
+	JoinPolicy  string         `json:"joinPolicy,omitempty"`
 	Profile     map[string]any `json:"profile,omitempty"`
 }
 
+// AddGroupMemberRequest represents the optional data accepted when adding
+// a user to a group. A nil ExpiresAt makes the membership permanent.
+type AddGroupMemberRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// What a group member listing can be expanded with: the member's factor
+// enrollment, and the groups the member belongs to in User.Groups.
+const (
+	MemberExpandFactors string = "factors"
+	MemberExpandGroups  string = "groups"
+)
+
+// GroupMember represents a user in a group together with the expiry of a
+// time-bound membership, if any, and what the listing was expanded with.
+type GroupMember struct {
+	*User
+	ExpiresAt        *time.Time        `json:"expiresAt,omitempty"`
+	RemainingSeconds *int64            `json:"remainingSeconds,omitempty"`
+	Factors          *FactorEnrollment `json:"factors,omitempty"`
+	// ExpandErrors holds, by expansion, why it failed for this member.
+	ExpandErrors map[string]string `json:"expandErrors,omitempty"`
+}
+
+// MembershipExpiry represents a time-bound membership of a user in a group.
+type MembershipExpiry struct {
+	GroupID   string    `json:"groupId"`
+	UserID    string    `json:"userId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
 // GroupRoleAssignment represents assigning a role to a group
 type GroupRoleAssignment struct {
 	RoleID  string `json:"roleId"`
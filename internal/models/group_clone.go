@@ -0,0 +1,33 @@
+package models
+
+const (
+	JobTypeGroupClone string = "group.clone"
+
+	GroupCloneStepMembers = "copy_members"
+	GroupCloneStepApps    = "copy_apps"
+)
+
+// CloneGroupRequest names the copy of a group and chooses what is copied
+// besides the group's description, profile and join policy.
+type CloneGroupRequest struct {
+	Name string `json:"name"`
+	// Description replaces the source group's description when set.
+	Description string `json:"description,omitempty"`
+	CopyMembers bool   `json:"copyMembers"`
+	CopyApps    bool   `json:"copyApps"`
+}
+
+// GroupClone is the outcome of cloning a group. Large groups have their
+// members and apps copied by a job that continues after the response;
+// otherwise they have been copied already and are listed. Members whose
+// time-bound membership has ended are skipped rather than added.
+type GroupClone struct {
+	SourceGroupID  string   `json:"sourceGroupId"`
+	Group          *Group   `json:"group"`
+	Job            *Job     `json:"job,omitempty"`
+	CopiedMembers  []string `json:"copiedMembers,omitempty"`
+	SkippedMembers []string `json:"skippedMembers,omitempty"`
+	FailedMembers  []string `json:"failedMembers,omitempty"`
+	CopiedApps     []string `json:"copiedApps,omitempty"`
+	FailedApps     []string `json:"failedApps,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Classifications say how sensitive the access a group grants is.
+const (
+	ClassificationPublic       string = "PUBLIC"
+	ClassificationInternal     string = "INTERNAL"
+	ClassificationConfidential string = "CONFIDENTIAL"
+	ClassificationRestricted   string = "RESTRICTED"
+)
+
+// IsClassification reports whether classification is one of the
+// classifications.
+func IsClassification(classification string) bool {
+	switch classification {
+	case ClassificationPublic, ClassificationInternal, ClassificationConfidential, ClassificationRestricted:
+		return true
+	}
+	return false
+}
+
+// GroupMetadata holds what an Okta group profile cannot: which users own the
+// group, which cost center pays for it, how sensitive its access is, and
+// free-form tags. It is stored by this service, keyed by group ID. Owners may
+// manage the group's members without being admins.
+type GroupMetadata struct {
+	GroupID        string            `json:"groupId"`
+	Owners         []string          `json:"owners,omitempty"`
+	CostCenter     string            `json:"costCenter,omitempty"`
+	Classification string            `json:"classification,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+	LastUpdated    time.Time         `json:"lastUpdated"`
+}
+
+// SetGroupMetadataRequest replaces a group's metadata, except its owners,
+// which are assigned and removed one at a time.
+type SetGroupMetadataRequest struct {
+	CostCenter     string            `json:"costCenter,omitempty"`
+	Classification string            `json:"classification,omitempty"`
+	Tags           map[string]string `json:"tags,omitempty"`
+}
+
+// GroupTagFilter selects groups whose metadata has Key set, to Value when it
+// is not empty. Key is owner, costCenter, classification or a tag; an owner
+// filter matches any of the group's owners.
+type GroupTagFilter struct {
+	Key   string
+	Value string
+}
@@ -0,0 +1,48 @@
+package models
+
+const (
+	GroupPolicyRulePattern  string = "NAME_PATTERN"
+	GroupPolicyRulePrefix   string = "NAME_PREFIX"
+	GroupPolicyRuleRequired string = "REQUIRED"
+	GroupPolicyRuleType     string = "GROUP_TYPE"
+)
+
+// GroupPolicyRule is what the name, description and tags of a group of one
+// type must look like. Tags are group profile attributes.
+type GroupPolicyRule struct {
+	GroupType           string   `json:"groupType,omitempty"`
+	NamePattern         string   `json:"namePattern,omitempty"`
+	NamePrefix          string   `json:"namePrefix,omitempty"`
+	DescriptionRequired bool     `json:"descriptionRequired"`
+	RequiredTags        []string `json:"requiredTags,omitempty"`
+}
+
+// GroupPolicy lists the rules groups are held to. The type of a group is read
+// from its TypeAttribute profile attribute; groups without one follow Default.
+type GroupPolicy struct {
+	TypeAttribute string             `json:"typeAttribute"`
+	Default       *GroupPolicyRule   `json:"default"`
+	Types         []*GroupPolicyRule `json:"types"`
+}
+
+// GroupPolicyViolation is a rule a group breaks. Message says what to change.
+type GroupPolicyViolation struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// CheckGroupPolicyRequest is a group to test against the rules without
+// creating it.
+type CheckGroupPolicyRequest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Profile     map[string]any `json:"profile,omitempty"`
+}
+
+// GroupPolicyCheck is the outcome of testing a group against the rules.
+type GroupPolicyCheck struct {
+	GroupType  string                  `json:"groupType,omitempty"`
+	Compliant  bool                    `json:"compliant"`
+	Violations []*GroupPolicyViolation `json:"violations"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// GroupStats summarizes every group so clients need not page through all of
+// them: how many there are of each type, how many have no members, and how
+// many fall in each size bucket. Source says whether it was computed from
+// the directory index, described by Index, or from Okta.
+type GroupStats struct {
+	Source           string             `json:"source"`
+	Index            *DirectoryIndex    `json:"index,omitempty"`
+	TotalGroups      int                `json:"totalGroups"`
+	EmptyGroups      int                `json:"emptyGroups"`
+	TotalMemberships int                `json:"totalMemberships"`
+	ByType           map[string]int     `json:"byType"`
+	SizeBuckets      []*GroupSizeBucket `json:"sizeBuckets"`
+}
+
+// GroupSizeBucket counts the groups with at least Min members and, unless
+// Max is nil, at most Max.
+type GroupSizeBucket struct {
+	Label string `json:"label"`
+	Min   int    `json:"min"`
+	Max   *int   `json:"max,omitempty"`
+	Count int    `json:"count"`
+}
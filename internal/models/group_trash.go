@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// DeletedGroup is a snapshot of a group taken just before it was deleted,
+// kept in the trash until Expires so the group can be restored. Listings
+// leave Members out.
+type DeletedGroup struct {
+	ID          string                `json:"id"`
+	GroupID     string                `json:"groupId"`
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	JoinPolicy  string                `json:"joinPolicy"`
+	Profile     map[string]any        `json:"profile,omitempty"`
+	Metadata    *GroupMetadata        `json:"metadata,omitempty"`
+	MemberCount int                   `json:"memberCount"`
+	Members     []*DeletedGroupMember `json:"members,omitempty"`
+	Deleted     time.Time             `json:"deleted"`
+	Expires     time.Time             `json:"expires"`
+}
+
+// DeletedGroupMember is a member of a deleted group, with the expiry of a
+// time-bound membership.
+type DeletedGroupMember struct {
+	UserID    string     `json:"userId"`
+	Login     string     `json:"login"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// GroupRestore is the outcome of restoring a deleted group. Okta gives the
+// recreated group a new ID. Members whose time-bound membership ended while
+// the group was in the trash are skipped rather than added.
+type GroupRestore struct {
+	Group           *Group   `json:"group"`
+	RestoredMembers []string `json:"restoredMembers"`
+	SkippedMembers  []string `json:"skippedMembers,omitempty"`
+	FailedMembers   []string `json:"failedMembers,omitempty"`
+}
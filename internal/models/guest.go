@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const (
+	GuestStatusActive      string = "ACTIVE"
+	GuestStatusSuspended   string = "SUSPENDED"
+	GuestStatusDeactivated string = "DEACTIVATED"
+)
+
+const (
+	ResourceTypeGuest string = "guest"
+
+	AuditActionGuestCreated           string = "guest.created"
+	AuditActionGuestAttested          string = "guest.attested"
+	AuditActionGuestAttestationLapsed string = "guest.attestation_lapsed"
+	AuditActionGuestExpired           string = "guest.expired"
+	AuditActionGuestOffboarded        string = "guest.offboarded"
+)
+
+// Guest is an external collaborator sponsored by an employee. Guests always
+// expire, may only join eligible groups, and are suspended when their
+// sponsor does not re-attest by AttestBy.
+type Guest struct {
+	ID           string     `json:"id"`
+	UserID       string     `json:"userId"`
+	Email        string     `json:"email"`
+	FirstName    string     `json:"firstName"`
+	LastName     string     `json:"lastName"`
+	Company      string     `json:"company,omitempty"`
+	SponsorID    string     `json:"sponsorId"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	AttestBy     time.Time  `json:"attestBy"`
+	LastAttested *time.Time `json:"lastAttested,omitempty"`
+	Created      time.Time  `json:"created"`
+}
+
+// CreateGuestRequest represents the data needed to invite a guest. The
+// caller becomes the sponsor.
+type CreateGuestRequest struct {
+	Email     string    `json:"email"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Company   string    `json:"company"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AttestGuestRequest confirms a guest still needs access, optionally moving
+// the expiry date.
+type AttestGuestRequest struct {
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// GuestFilter narrows a guest listing. Empty fields match every guest.
+type GuestFilter struct {
+	SponsorID string
+	Status    string
+}
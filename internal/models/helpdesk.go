@@ -0,0 +1,15 @@
+package models
+
+const (
+	AuditActionPasswordExpired         string = "user.password_expired"
+	AuditActionPasswordResetSent       string = "user.password_reset_sent"
+	AuditActionTemporaryPasswordIssued string = "user.temporary_password_issued"
+	AuditActionUserUnlocked            string = "user.unlocked"
+)
+
+// TemporaryPassword is the one-time password a helpdesk agent reads out to a
+// user, who must change it at their next sign-in.
+type TemporaryPassword struct {
+	UserID       string `json:"userId"`
+	TempPassword string `json:"tempPassword"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	MembershipBasisSnapshot = "snapshot"
+	MembershipBasisCurrent  = "current"
+)
+
+// HistoricalMember identifies a member of a group at some point in time.
+type HistoricalMember struct {
+	ID    string `json:"id"`
+	Login string `json:"login,omitempty"`
+}
+
+// MembershipSnapshot holds the members of every group at one point in time.
+type MembershipSnapshot struct {
+	TakenAt time.Time                      `json:"takenAt"`
+	Groups  map[string][]*HistoricalMember `json:"groups"`
+}
+
+// MembershipBasis is the known membership a past membership was derived
+// from: a snapshot, or the group's current members.
+type MembershipBasis struct {
+	Type string    `json:"type"`
+	At   time.Time `json:"at"`
+}
+
+// GroupMembersAsOf is the membership of a group at a past time, derived from
+// the basis by replaying or undoing the membership changes Okta logged
+// between the two.
+type GroupMembersAsOf struct {
+	GroupID        string              `json:"groupId"`
+	AsOf           time.Time           `json:"asOf"`
+	Basis          *MembershipBasis    `json:"basis"`
+	ChangesApplied int                 `json:"changesApplied"`
+	Members        []*HistoricalMember `json:"members"`
+}
+
+// GroupMembershipDiff is how a group's membership changed between From and
+// To: who joined and who left. Each end is derived like GroupMembersAsOf, from
+// its own basis.
+type GroupMembershipDiff struct {
+	GroupID   string              `json:"groupId"`
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	FromBasis *MembershipBasis    `json:"fromBasis"`
+	ToBasis   *MembershipBasis    `json:"toBasis"`
+	Added     []*HistoricalMember `json:"added"`
+	Removed   []*HistoricalMember `json:"removed"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	InvitationStatusPending  string = "PENDING"
+	InvitationStatusAccepted string = "ACCEPTED"
+	InvitationStatusExpired  string = "EXPIRED"
+	InvitationStatusRevoked  string = "REVOKED"
+)
+
+const (
+	ResourceTypeInvitation string = "invitation"
+
+	AuditActionInvitationCreated  string = "invitation.created"
+	AuditActionInvitationAccepted string = "invitation.accepted"
+	AuditActionInvitationRevoked  string = "invitation.revoked"
+
+	WebhookEventInvitationAccepted string = "iam.invitation.accepted"
+)
+
+// Invitation is an expiring link that lets an invitee pre-register. When it
+// is accepted a STAGED Okta user is created with the invite's attributes and
+// the sponsor is notified. URL is only returned when the invitation is created.
+type Invitation struct {
+	ID         string         `json:"id"`
+	Email      string         `json:"email"`
+	FirstName  string         `json:"firstName,omitempty"`
+	LastName   string         `json:"lastName,omitempty"`
+	Profile    map[string]any `json:"profile,omitempty"`
+	SponsorID  string         `json:"sponsorId"`
+	CreatedBy  string         `json:"createdBy"`
+	Status     string         `json:"status"`
+	URL        string         `json:"url,omitempty"`
+	UserID     string         `json:"userId,omitempty"`
+	ExpiresAt  time.Time      `json:"expiresAt"`
+	AcceptedAt *time.Time     `json:"acceptedAt,omitempty"`
+	Created    time.Time      `json:"created"`
+}
+
+// CreateInvitationRequest represents the data needed to invite someone.
+// SponsorID defaults to the caller and ExpiresAt to the invitation TTL.
+// Profile attributes are applied to the user as-is; the invitee cannot
+// change them.
+type CreateInvitationRequest struct {
+	Email     string         `json:"email"`
+	FirstName string         `json:"firstName"`
+	LastName  string         `json:"lastName"`
+	Profile   map[string]any `json:"profile"`
+	SponsorID string         `json:"sponsorId"`
+	ExpiresAt *time.Time     `json:"expiresAt"`
+}
+
+// InvitationPreview is what the invitee sees before completing the form.
+type InvitationPreview struct {
+	Email     string    `json:"email"`
+	FirstName string    `json:"firstName,omitempty"`
+	LastName  string    `json:"lastName,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AcceptInvitationRequest is the minimal form completed by the invitee.
+// Names default to the ones on the invitation.
+type AcceptInvitationRequest struct {
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	MobilePhone string `json:"mobilePhone"`
+}
+
+// InvitationFilter narrows an invitation listing. Empty fields match every invitation.
+type InvitationFilter struct {
+	SponsorID string
+	Status    string
+}
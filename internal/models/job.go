@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	JobStatusPending   string = "PENDING"
+	JobStatusRunning   string = "RUNNING"
+	JobStatusSucceeded string = "SUCCEEDED"
+	JobStatusFailed    string = "FAILED"
+)
+
+const (
+	JobStepStatusPending   string = "PENDING"
+	JobStepStatusRunning   string = "RUNNING"
+	JobStepStatusSucceeded string = "SUCCEEDED"
+	JobStepStatusFailed    string = "FAILED"
+	JobStepStatusSkipped   string = "SKIPPED"
+)
+
+// Job tracks a long running operation that continues after the request that
+// started it has returned. Steps are reported in the order they run.
+type Job struct {
+	ID           string     `json:"id"`
+	Type         string     `json:"type"`
+	ResourceType string     `json:"resourceType"`
+	ResourceID   string     `json:"resourceId"`
+	Status       string     `json:"status"`
+	Steps        []*JobStep `json:"steps"`
+	Error        string     `json:"error,omitempty"`
+	Created      time.Time  `json:"created"`
+	LastUpdated  time.Time  `json:"lastUpdated"`
+	Completed    *time.Time `json:"completed,omitempty"`
+}
+
+// JobStep is a single stage of a job.
+type JobStep struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Started   *time.Time `json:"started,omitempty"`
+	Completed *time.Time `json:"completed,omitempty"`
+}
+
+// JobFilter narrows a job listing. Empty fields match every job.
+type JobFilter struct {
+	Type       string
+	Status     string
+	ResourceID string
+}
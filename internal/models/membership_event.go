@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+const (
+	MembershipEventAdded   string = "ADDED"
+	MembershipEventRemoved string = "REMOVED"
+)
+
+// MembershipEvent is a user added to or removed from a group, as Okta logged
+// it, whether the change was made through this service or not.
+type MembershipEvent struct {
+	// ID is the UUID of the System Log event.
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	GroupID   string    `json:"groupId"`
+	GroupName string    `json:"groupName,omitempty"`
+	UserID    string    `json:"userId"`
+	UserLogin string    `json:"userLogin,omitempty"`
+	Actor     *LogActor `json:"actor"`
+	Published time.Time `json:"published"`
+}
+
+// LogActor is the user or app that made a logged change.
+type LogActor struct {
+	ID          string `json:"id"`
+	Type        string `json:"type"`
+	AlternateID string `json:"alternateId,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+}
+
+// MembershipEventFilter selects membership events. Zero values match
+// everything.
+type MembershipEventFilter struct {
+	GroupID string
+	UserID  string
+	ActorID string
+	Action  string
+	Since   time.Time
+	Until   time.Time
+}
+
+// MembershipEventList is the stored membership events matching a filter,
+// newest first. The store holds the changes logged from IngestedSince until
+// IngestedUntil; later ones have not been read yet.
+type MembershipEventList struct {
+	IngestedSince time.Time          `json:"ingestedSince"`
+	IngestedUntil time.Time          `json:"ingestedUntil"`
+	Total         int                `json:"total"`
+	Events        []*MembershipEvent `json:"events"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+const (
+	MigrationActionCreate   string = "CREATE"
+	MigrationActionUpdate   string = "UPDATE"
+	MigrationActionSkip     string = "SKIP"
+	MigrationActionConflict string = "CONFLICT"
+	MigrationActionAdd      string = "ADD"
+)
+
+const (
+	MigrationStepExport string = "export"
+	MigrationStepPlan   string = "plan"
+	MigrationStepImport string = "import"
+)
+
+const (
+	JobTypeOrgMigration string = "org.migration"
+	ResourceTypeOrg     string = "org"
+
+	AuditActionOrgMigrated string = "org.migrated"
+)
+
+// MigrationRequest copies users, groups and memberships from one configured
+// org to another. Without GroupIDs every user and every Okta-mastered group
+// of the source org is copied; with them, only those groups and their
+// members. Users are matched by login and groups by name; ConflictStrategy
+// says what happens to those that already exist in the target org.
+type MigrationRequest struct {
+	SourceOrg string   `json:"sourceOrg"`
+	TargetOrg string   `json:"targetOrg"`
+	GroupIDs  []string `json:"groupIds,omitempty"`
+	// FieldMapping copies source profile attributes (keys) to differently
+	// named target attributes (values) in addition to login, email and names.
+	FieldMapping     map[string]string `json:"fieldMapping,omitempty"`
+	ConflictStrategy string            `json:"conflictStrategy"`
+	Activate         bool              `json:"activate"`
+	// DryRun stops after the plan, so it can be reviewed before anything is
+	// written to the target org.
+	DryRun bool `json:"dryRun"`
+}
+
+// Migration is a run of a MigrationRequest. Its job reports the progress of
+// the export, plan and import steps; the plan is filled in once it is made,
+// and its outcomes once the import ends.
+type Migration struct {
+	ID               string         `json:"id"`
+	SourceOrg        string         `json:"sourceOrg"`
+	TargetOrg        string         `json:"targetOrg"`
+	GroupIDs         []string       `json:"groupIds,omitempty"`
+	ConflictStrategy string         `json:"conflictStrategy"`
+	DryRun           bool           `json:"dryRun"`
+	CreatedBy        string         `json:"createdBy,omitempty"`
+	Created          time.Time      `json:"created"`
+	Job              *Job           `json:"job"`
+	Plan             *MigrationPlan `json:"plan,omitempty"`
+}
+
+// MigrationPlan lists what the import does to each user, group and
+// membership. Outcome, and Error for failures, are set once it has run.
+type MigrationPlan struct {
+	Summary     MigrationSummary       `json:"summary"`
+	Users       []*MigrationItem       `json:"users"`
+	Groups      []*MigrationItem       `json:"groups"`
+	Memberships []*MigrationMembership `json:"memberships"`
+}
+
+// MigrationSummary counts the planned actions.
+type MigrationSummary struct {
+	UsersToCreate    int `json:"usersToCreate"`
+	UsersToUpdate    int `json:"usersToUpdate"`
+	GroupsToCreate   int `json:"groupsToCreate"`
+	GroupsToUpdate   int `json:"groupsToUpdate"`
+	MembershipsToAdd int `json:"membershipsToAdd"`
+	Skipped          int `json:"skipped"`
+	Conflicts        int `json:"conflicts"`
+	Failed           int `json:"failed"`
+}
+
+// MigrationItem is one user, keyed by login, or one group, keyed by name.
+// TargetID is empty until a user or group to create has been created.
+type MigrationItem struct {
+	SourceID string `json:"sourceId"`
+	TargetID string `json:"targetId,omitempty"`
+	Key      string `json:"key"`
+	Action   string `json:"action"`
+	Outcome  string `json:"outcome,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// MigrationMembership is one source membership, to ADD in the target org or
+// SKIP because the target member already has it.
+type MigrationMembership struct {
+	GroupKey string `json:"group"`
+	UserKey  string `json:"user"`
+	Action   string `json:"action"`
+	Outcome  string `json:"outcome,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
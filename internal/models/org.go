@@ -0,0 +1,8 @@
+package models
+
+// Org is a configured Okta org that the org-scoped endpoints can address.
+type Org struct {
+	Name    string `json:"name"`
+	Domain  string `json:"domain"`
+	Primary bool   `json:"primary"`
+}
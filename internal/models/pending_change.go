@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+const (
+	PendingChangeOperationAdd    string = "ADD"
+	PendingChangeOperationRemove string = "REMOVE"
+)
+
+const (
+	PendingChangeStatusPending  string = "PENDING"
+	PendingChangeStatusApproved string = "APPROVED"
+	PendingChangeStatusRejected string = "REJECTED"
+	PendingChangeStatusExpired  string = "EXPIRED"
+)
+
+const (
+	AuditActionGroupChangesProtected   string = "group.changes_protected"
+	AuditActionGroupChangesUnprotected string = "group.changes_unprotected"
+	AuditActionPendingChangeCreated    string = "pending_change.created"
+	AuditActionPendingChangeApproved   string = "pending_change.approved"
+	AuditActionPendingChangeRejected   string = "pending_change.rejected"
+	AuditActionPendingChangeExpired    string = "pending_change.expired"
+)
+
+// ChangeProtectedGroup is a group under the two-person rule: membership
+// changes made through its member routes wait as pending changes until an
+// admin other than the one who made them approves them.
+type ChangeProtectedGroup struct {
+	GroupID     string    `json:"groupId"`
+	ProtectedBy string    `json:"protectedBy"`
+	Protected   time.Time `json:"protected"`
+}
+
+// PendingMembershipChange is a membership change to a protected group held
+// for approval. MembershipExpiresAt is the expiry of the membership an ADD
+// grants; StaleAt is when the change expires if no one decides on it.
+type PendingMembershipChange struct {
+	ID                  string     `json:"id"`
+	GroupID             string     `json:"groupId"`
+	UserID              string     `json:"userId"`
+	Operation           string     `json:"operation"`
+	MembershipExpiresAt *time.Time `json:"membershipExpiresAt,omitempty"`
+	Status              string     `json:"status"`
+	RequestedBy         string     `json:"requestedBy"`
+	Requested           time.Time  `json:"requested"`
+	StaleAt             time.Time  `json:"staleAt"`
+	DecidedBy           string     `json:"decidedBy,omitempty"`
+	Decided             *time.Time `json:"decided,omitempty"`
+	Comment             string     `json:"comment,omitempty"`
+}
+
+// PendingChangeDecision represents an admin's decision on a pending change.
+type PendingChangeDecision struct {
+	Comment string `json:"comment"`
+}
+
+// PendingChangeFilter narrows the pending changes returned by a listing.
+type PendingChangeFilter struct {
+	Status  string
+	GroupID string
+}
@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+const (
+	ProfileAttributeTypeString  string = "string"
+	ProfileAttributeTypeNumber  string = "number"
+	ProfileAttributeTypeBoolean string = "boolean"
+
+	AuditActionProfileAttributesSubmitted string = "user.profile_attributes_submitted"
+)
+
+// ProfileRequirement asks the users assigned to AppIDs, and the members of
+// GroupIDs, for a profile attribute they have not filled in yet. Values
+// restrict a string attribute to a list, Pattern to a regular expression it
+// must match in full.
+type ProfileRequirement struct {
+	ID          string    `json:"id"`
+	Attribute   string    `json:"attribute"`
+	Label       string    `json:"label"`
+	Description string    `json:"description,omitempty"`
+	Type        string    `json:"type"`
+	Pattern     string    `json:"pattern,omitempty"`
+	Values      []string  `json:"values,omitempty"`
+	AppIDs      []string  `json:"appIds,omitempty"`
+	GroupIDs    []string  `json:"groupIds,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// CreateProfileRequirementRequest defines a requirement. Type defaults to
+// string, and at least one app or group is required.
+type CreateProfileRequirementRequest struct {
+	Attribute   string   `json:"attribute"`
+	Label       string   `json:"label"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Values      []string `json:"values,omitempty"`
+	AppIDs      []string `json:"appIds,omitempty"`
+	GroupIDs    []string `json:"groupIds,omitempty"`
+}
+
+// MissingProfileAttribute is an attribute the portal asks the user for,
+// with the apps and groups of theirs that require it.
+type MissingProfileAttribute struct {
+	Attribute   string             `json:"attribute"`
+	Label       string             `json:"label"`
+	Description string             `json:"description,omitempty"`
+	Type        string             `json:"type"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Values      []string           `json:"values,omitempty"`
+	RequiredBy  []*ProfileRequirer `json:"requiredBy"`
+}
+
+// ProfileRequirer is an app or group a requirement applies through.
+type ProfileRequirer struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+}
+
+// SubmitProfileAttributesRequest fills in missing attributes, by name.
+type SubmitProfileAttributesRequest struct {
+	Attributes map[string]any `json:"attributes"`
+}
+
+// ProfileAttributeError is why a submitted attribute was refused.
+type ProfileAttributeError struct {
+	Attribute string `json:"attribute"`
+	Message   string `json:"message"`
+}
+
+// ProfileSubmission is the outcome of a submission: the attributes written to
+// the Okta profile and those the user is still asked for.
+type ProfileSubmission struct {
+	Updated []string                   `json:"updated"`
+	Missing []*MissingProfileAttribute `json:"missing"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+const (
+	// ReconciliationModeForward completes a saga: its failed step and the
+	// steps after it are retried.
+	ReconciliationModeForward string = "FORWARD"
+	// ReconciliationModeUndo rolls a saga back: the undoing of its steps
+	// that failed to be undone is retried.
+	ReconciliationModeUndo string = "UNDO"
+)
+
+const (
+	ReconciliationStatusPending string = "PENDING"
+	// ReconciliationStatusStuck means the steps failed every attempt. They
+	// are only retried when an operator forces it.
+	ReconciliationStatusStuck string = "STUCK"
+	// ReconciliationStatusResolved means the intended state was reached; the
+	// saga is no longer pending.
+	ReconciliationStatusResolved string = "RESOLVED"
+)
+
+// Reconciliation is a saga whose intended state has not been reached yet,
+// with the steps that remain to reach it, in the order they are retried.
+type Reconciliation struct {
+	SagaID       string     `json:"sagaId"`
+	SagaType     string     `json:"sagaType"`
+	ResourceType string     `json:"resourceType"`
+	ResourceID   string     `json:"resourceId,omitempty"`
+	Mode         string     `json:"mode"`
+	Status       string     `json:"status"`
+	Steps        []string   `json:"steps"`
+	Attempts     int        `json:"attempts"`
+	MaxAttempts  int        `json:"maxAttempts"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastAttempt  *time.Time `json:"lastAttempt,omitempty"`
+	Created      time.Time  `json:"created"`
+}
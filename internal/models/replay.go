@@ -0,0 +1,91 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OktaRequest is a request this service sent to Okta.
+type OktaRequest struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Query  string `json:"query,omitempty"`
+}
+
+// OktaInteraction is a request sent to Okta and the response it got. A
+// request that got no response, such as one refused by an open circuit
+// breaker, has a zero status and the error instead.
+type OktaInteraction struct {
+	OktaRequest
+	RequestBody json.RawMessage `json:"requestBody,omitempty"`
+	Status      int             `json:"status"`
+	// Link is the pagination Link header of the response.
+	Link         string          `json:"link,omitempty"`
+	ResponseBody json.RawMessage `json:"responseBody,omitempty"`
+	Error        string          `json:"error,omitempty"`
+}
+
+// ReplayRequest is an inbound API request.
+type ReplayRequest struct {
+	Method string `json:"method"`
+	// Path is the request path with its query string.
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// ReplayResponse is the response to an inbound API request. Bodies that are
+// not JSON, or too large to keep, are left out.
+type ReplayResponse struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+}
+
+// ReplayFixture is a request, the response it got and the Okta interactions
+// made while serving it, in order. Credentials and other secrets are
+// replaced with REDACTED. It is what a capture is exported as, and what
+// oktamock.NewReplay serves the interactions of.
+type ReplayFixture struct {
+	Request      *ReplayRequest     `json:"request"`
+	Response     *ReplayResponse    `json:"response"`
+	Interactions []*OktaInteraction `json:"interactions"`
+	// Truncated is set when interactions were left out because the request
+	// made more than a capture keeps.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ReplayCapture is a failed request kept for replaying. Captures imported
+// from a fixture have no route or request ID.
+type ReplayCapture struct {
+	ID        string    `json:"id"`
+	Route     string    `json:"route,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	Captured  time.Time `json:"captured"`
+	ReplayFixture
+}
+
+// ReplayCaptureSummary describes a capture without its bodies.
+type ReplayCaptureSummary struct {
+	ID           string    `json:"id"`
+	Method       string    `json:"method"`
+	Path         string    `json:"path"`
+	Route        string    `json:"route,omitempty"`
+	Status       int       `json:"status"`
+	RequestID    string    `json:"requestId,omitempty"`
+	Interactions int       `json:"interactions"`
+	Captured     time.Time `json:"captured"`
+}
+
+// ReplayResult is the outcome of replaying a capture against the recorded
+// Okta interactions.
+type ReplayResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	// Reproduced reports whether the replay got the captured status.
+	Reproduced bool `json:"reproduced"`
+	// Unmatched are the Okta requests the replay made that no recorded
+	// interaction answered, and Unused counts the recorded interactions it
+	// never made; either means it took a different path than the capture.
+	Unmatched []*OktaRequest `json:"unmatched"`
+	Unused    int            `json:"unused"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+const (
+	InactivityReasonNeverLoggedIn string = "NEVER_LOGGED_IN"
+	InactivityReasonInactive      string = "INACTIVE"
+)
+
+const (
+	AuditActionUserAutoSuspended string = "user.auto_suspended"
+)
+
+// GroupAppMatrix is a grid of groups against the apps they grant.
+// Each row is a group; AppIDs lists the columns (apps) assigned to it.
+type GroupAppMatrix struct {
+	Apps []*App               `json:"apps"`
+	Rows []*GroupAppMatrixRow `json:"rows"`
+}
+
+// GroupAppMatrixRow represents a single group and the apps assigned to it.
+type GroupAppMatrixRow struct {
+	GroupID   string   `json:"groupId"`
+	GroupName string   `json:"groupName"`
+	AppIDs    []string `json:"appIds"`
+}
+
+// GroupAppMatrixFilter narrows the groups and apps included in the matrix.
+type GroupAppMatrixFilter struct {
+	GroupQuery   string
+	AppQuery     string
+	OnlyAssigned bool
+}
+
+// InactiveUser represents an account with no sign-in activity within the
+// report window. DaysInactive counts from the last login, or from the
+// activation or creation date when the user never signed in.
+type InactiveUser struct {
+	*User
+	DaysInactive int    `json:"daysInactive"`
+	Reason       string `json:"reason"`
+}
+
+// InactiveUsersFilter configures the inactive users report.
+type InactiveUsersFilter struct {
+	Days            int
+	ExcludeGroupIDs []string
+	Now             time.Time
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	RetryStatusPending   string = "PENDING"
+	RetryStatusRunning   string = "RUNNING"
+	RetryStatusSucceeded string = "SUCCEEDED"
+	RetryStatusFailed    string = "FAILED"
+)
+
+// RetryEntry is a mutation that failed because Okta was unavailable, queued
+// to be replayed once it is back. A FAILED entry ran out of attempts or was
+// rejected on replay, and waits for an operator to retry or discard it.
+type RetryEntry struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	// Path is the request path with its query string.
+	Path  string          `json:"path"`
+	Route string          `json:"route"`
+	Body  json.RawMessage `json:"body,omitempty"`
+	// RequestID is the ID of the request that failed; replays reuse it.
+	RequestID   string     `json:"requestId,omitempty"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"maxAttempts"`
+	LastStatus  int        `json:"lastStatus"`
+	LastError   string     `json:"lastError,omitempty"`
+	NextAttempt *time.Time `json:"nextAttempt,omitempty"`
+	Created     time.Time  `json:"created"`
+	LastUpdated time.Time  `json:"lastUpdated"`
+}
+
+// RetryFilter narrows a retry queue listing. Empty fields match every entry.
+type RetryFilter struct {
+	Status string
+	Route  string
+}
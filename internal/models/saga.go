@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+const (
+	SagaStatusRunning string = "RUNNING"
+	// SagaStatusSucceeded means every step succeeded.
+	SagaStatusSucceeded string = "SUCCEEDED"
+	// SagaStatusCompensated means a step failed and every step that had
+	// succeeded was undone.
+	SagaStatusCompensated string = "COMPENSATED"
+	// SagaStatusFailed means a step failed and undoing the earlier steps
+	// failed too. The undoing is retried by the reconciliation worker, and
+	// the saga is COMPENSATED once it succeeds.
+	SagaStatusFailed string = "FAILED"
+	// SagaStatusReconciling means a step failed and, instead of undoing the
+	// earlier steps, it and the steps after it are retried by the
+	// reconciliation worker.
+	SagaStatusReconciling string = "RECONCILING"
+)
+
+const (
+	SagaStepStatusPending            string = "PENDING"
+	SagaStepStatusRunning            string = "RUNNING"
+	SagaStepStatusSucceeded          string = "SUCCEEDED"
+	SagaStepStatusFailed             string = "FAILED"
+	SagaStepStatusSkipped            string = "SKIPPED"
+	SagaStepStatusCompensated        string = "COMPENSATED"
+	SagaStepStatusCompensationFailed string = "COMPENSATION_FAILED"
+)
+
+const (
+	SagaTypeOnboarding      string = "user.onboarding"
+	SagaTypeTeamScaffolding string = "group.team_scaffolding"
+)
+
+// Saga records an operation made of several Okta changes that either all
+// take effect or are undone. Steps are reported in the order they run; when
+// one fails, the ones before it are compensated in reverse order.
+type Saga struct {
+	ID           string      `json:"id"`
+	Type         string      `json:"type"`
+	ResourceType string      `json:"resourceType"`
+	ResourceID   string      `json:"resourceId,omitempty"`
+	Status       string      `json:"status"`
+	Steps        []*SagaStep `json:"steps"`
+	Error        string      `json:"error,omitempty"`
+	Created      time.Time   `json:"created"`
+	LastUpdated  time.Time   `json:"lastUpdated"`
+	Completed    *time.Time  `json:"completed,omitempty"`
+}
+
+// SagaStep is a single change of a saga.
+type SagaStep struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	Message   string     `json:"message,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Started   *time.Time `json:"started,omitempty"`
+	Completed *time.Time `json:"completed,omitempty"`
+	// Compensated is when the step was undone, or failed to be.
+	Compensated *time.Time `json:"compensated,omitempty"`
+}
+
+// SagaFilter narrows a saga listing. Empty fields match every saga.
+type SagaFilter struct {
+	Type       string
+	Status     string
+	ResourceID string
+}
+
+// OnboardUserRequest creates a user and adds them to groups. With
+// Reconcile, a failed group addition is retried later instead of the user
+// being deleted again.
+type OnboardUserRequest struct {
+	User      CreateUserRequest `json:"user"`
+	GroupIDs  []string          `json:"groupIds"`
+	Reconcile bool              `json:"reconcile"`
+}
+
+// CreateTeamRequest creates a group for a team and adds its members. With
+// Reconcile, a failed member addition is retried later instead of the group
+// being deleted again.
+type CreateTeamRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	JoinPolicy  string   `json:"joinPolicy,omitempty"`
+	MemberIDs   []string `json:"memberIds"`
+	Reconcile   bool     `json:"reconcile"`
+}
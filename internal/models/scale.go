@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// ScaleMetrics is the workload of one replica, for autoscaling worker
+// replicas on it rather than on CPU.
+type ScaleMetrics struct {
+	// Jobs counts the background jobs pending or running.
+	Jobs int `json:"jobs"`
+	// Retries counts the queued retries pending or running.
+	Retries int `json:"retries"`
+	// WebhookBacklog counts the webhook deliveries in flight.
+	WebhookBacklog int `json:"webhookBacklog"`
+	// RateLimitPressure is the highest utilization of any Okta rate limit
+	// window, from 0 to 1.
+	RateLimitPressure float64          `json:"rateLimitPressure"`
+	RateLimits        []*OktaRateLimit `json:"rateLimits"`
+	Budgets           []*OktaBudget    `json:"budgets"`
+}
+
+// OktaRateLimit is an Okta rate limit window of an endpoint class, such as
+// users or groups, as the last response from it reported.
+type OktaRateLimit struct {
+	Org       string    `json:"org"`
+	Class     string    `json:"class"`
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+	// Utilization is the share of the limit used, from 0 to 1.
+	Utilization float64 `json:"utilization"`
+}
+
+// OktaBudget is the part of an Okta rate limit window a subsystem may use:
+// interactive for requests served to callers, sync for background workers
+// and batch for jobs.
+type OktaBudget struct {
+	Org       string `json:"org"`
+	Class     string `json:"class"`
+	Subsystem string `json:"subsystem"`
+	// Share is the percentage of the limit set aside for the subsystem.
+	// Interactive requests get what the others are not given, and may use
+	// the whole limit.
+	Share int `json:"share"`
+	// Allowance is how many requests the subsystem may make in the window.
+	Allowance int `json:"allowance"`
+	// Used is how many it has made in the window so far.
+	Used int `json:"used"`
+	// Waiting counts its requests waiting for the window to reset.
+	Waiting int       `json:"waiting"`
+	Reset   time.Time `json:"reset"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+const (
+	ScheduledChangeOperationAdd    string = "ADD"
+	ScheduledChangeOperationRemove string = "REMOVE"
+)
+
+const (
+	ScheduledChangeStatusScheduled string = "SCHEDULED"
+	ScheduledChangeStatusRunning   string = "RUNNING"
+	ScheduledChangeStatusSucceeded string = "SUCCEEDED"
+	ScheduledChangeStatusFailed    string = "FAILED"
+	ScheduledChangeStatusCancelled string = "CANCELLED"
+)
+
+const (
+	AuditActionScheduledChangeCreated   string = "scheduled_change.created"
+	AuditActionScheduledChangeCancelled string = "scheduled_change.cancelled"
+	AuditActionScheduledChangeApplied   string = "scheduled_change.applied"
+	AuditActionScheduledChangeFailed    string = "scheduled_change.failed"
+)
+
+// ScheduledMembershipChange adds a user to or removes them from a group at a
+// future time, such as a contractor's start or end date. A change that fails
+// because Okta is unavailable is tried again with a growing wait until it
+// runs out of attempts; a FAILED change is kept for an operator to look at.
+type ScheduledMembershipChange struct {
+	ID        string    `json:"id"`
+	GroupID   string    `json:"groupId"`
+	UserID    string    `json:"userId"`
+	Operation string    `json:"operation"`
+	RunAt     time.Time `json:"runAt"`
+	// MembershipExpiresAt bounds the membership an ADD grants.
+	MembershipExpiresAt *time.Time `json:"membershipExpiresAt,omitempty"`
+	Status              string     `json:"status"`
+	Attempts            int        `json:"attempts"`
+	MaxAttempts         int        `json:"maxAttempts"`
+	NextAttempt         *time.Time `json:"nextAttempt,omitempty"`
+	LastError           string     `json:"lastError,omitempty"`
+	CreatedBy           string     `json:"createdBy,omitempty"`
+	Created             time.Time  `json:"created"`
+	CancelledBy         string     `json:"cancelledBy,omitempty"`
+	LastUpdated         time.Time  `json:"lastUpdated"`
+}
+
+// ScheduleMembershipChangeRequest represents the data needed to schedule a
+// membership change. Operation is ADD or REMOVE.
+type ScheduleMembershipChangeRequest struct {
+	GroupID             string     `json:"groupId"`
+	UserID              string     `json:"userId"`
+	Operation           string     `json:"operation"`
+	RunAt               time.Time  `json:"runAt"`
+	MembershipExpiresAt *time.Time `json:"membershipExpiresAt"`
+}
+
+// ScheduledChangeFilter narrows a scheduled change listing. Empty fields
+// match every change.
+type ScheduledChangeFilter struct {
+	Status  string
+	GroupID string
+	UserID  string
+}
@@ -0,0 +1,127 @@
+package models
+
+import "time"
+
+const (
+	SecurityAlertBruteForce       string = "BRUTE_FORCE"
+	SecurityAlertImpossibleTravel string = "IMPOSSIBLE_TRAVEL"
+)
+
+const WebhookEventSecurityAlert string = "iam.security.alert"
+
+// SecurityAlert is a suspicious sign-in pattern found in the System Log: a
+// burst of failed sign-ins for one user or from one IP address
+// (BRUTE_FORCE), or two successful sign-ins of a user too far apart for the
+// time between them (IMPOSSIBLE_TRAVEL).
+type SecurityAlert struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	UserID     string    `json:"userId,omitempty"`
+	UserLogin  string    `json:"userLogin,omitempty"`
+	IPAddress  string    `json:"ipAddress,omitempty"`
+	FirstSeen  time.Time `json:"firstSeen"`
+	LastSeen   time.Time `json:"lastSeen"`
+	DetectedAt time.Time `json:"detectedAt"`
+	// EventIDs are the UUIDs of the System Log events behind the alert.
+	EventIDs []string `json:"eventIds"`
+
+	// Failures is how many failed sign-ins a brute-force alert counts.
+	Failures int `json:"failures,omitempty"`
+
+	// From and To are the sign-ins of an impossible-travel alert.
+	From       *SignInLocation `json:"from,omitempty"`
+	To         *SignInLocation `json:"to,omitempty"`
+	DistanceKm float64         `json:"distanceKm,omitempty"`
+	SpeedKmh   float64         `json:"speedKmh,omitempty"`
+
+	// Suspended is set once the user was suspended automatically.
+	Suspended    bool   `json:"suspended"`
+	SuspendError string `json:"suspendError,omitempty"`
+}
+
+// SignInLocation is where and when Okta placed a sign-in.
+type SignInLocation struct {
+	At        time.Time `json:"at"`
+	IPAddress string    `json:"ipAddress,omitempty"`
+	City      string    `json:"city,omitempty"`
+	Country   string    `json:"country,omitempty"`
+	Lat       float64   `json:"lat"`
+	Lon       float64   `json:"lon"`
+}
+
+// SecurityAlertFilter selects security alerts. Zero values match
+// everything.
+type SecurityAlertFilter struct {
+	Type      string
+	UserID    string
+	IPAddress string
+	Since     time.Time
+	Until     time.Time
+}
+
+// SecurityAlertList is the alerts matching a filter, newest first.
+type SecurityAlertList struct {
+	// CheckedUntil is how far the System Log has been read; sign-ins logged
+	// after it have not been looked at yet.
+	CheckedUntil time.Time        `json:"checkedUntil"`
+	Total        int              `json:"total"`
+	Alerts       []*SecurityAlert `json:"alerts"`
+}
+
+const (
+	LockdownStatusLocked   string = "LOCKED"
+	LockdownStatusUnlocked string = "UNLOCKED"
+)
+
+const (
+	ResourceTypeIncident string = "incident"
+
+	AuditActionSecurityLockdown string = "security.lockdown"
+	AuditActionSecurityUnlock   string = "security.unlock"
+)
+
+// LockdownRequest locks down the users named, and the members of the group,
+// for an incident: they are suspended and signed out, and with ClearFactors
+// their factors are reset.
+type LockdownRequest struct {
+	IncidentID   string   `json:"incidentId"`
+	Reason       string   `json:"reason,omitempty"`
+	UserIDs      []string `json:"userIds,omitempty"`
+	GroupID      string   `json:"groupId,omitempty"`
+	ClearFactors bool     `json:"clearFactors"`
+}
+
+// UnlockRequest lifts the lockdown of an incident.
+type UnlockRequest struct {
+	IncidentID string `json:"incidentId"`
+}
+
+// Lockdown is a break-glass lockdown and what became of each user. It stays
+// LOCKED until every user it suspended has been unsuspended.
+type Lockdown struct {
+	IncidentID   string          `json:"incidentId"`
+	Reason       string          `json:"reason,omitempty"`
+	Status       string          `json:"status"`
+	GroupID      string          `json:"groupId,omitempty"`
+	ClearFactors bool            `json:"clearFactors"`
+	LockedBy     string          `json:"lockedBy,omitempty"`
+	Locked       time.Time       `json:"locked"`
+	UnlockedBy   string          `json:"unlockedBy,omitempty"`
+	Unlocked     *time.Time      `json:"unlocked,omitempty"`
+	Users        []*LockdownUser `json:"users"`
+}
+
+// LockdownUser is a user of a lockdown. Only users the lockdown suspended,
+// who were ACTIVE, are unsuspended by the unlock; sessions and factors cannot
+// be given back.
+type LockdownUser struct {
+	UserID          string `json:"userId"`
+	Login           string `json:"login,omitempty"`
+	PreviousStatus  string `json:"previousStatus,omitempty"`
+	Suspended       bool   `json:"suspended"`
+	SessionsRevoked bool   `json:"sessionsRevoked"`
+	FactorsCleared  bool   `json:"factorsCleared"`
+	Unsuspended     bool   `json:"unsuspended"`
+	Error           string `json:"error,omitempty"`
+	UnlockError     string `json:"unlockError,omitempty"`
+}
@@ -0,0 +1,19 @@
+package models
+
+const (
+	AuditActionSelfProfileUpdated  string = "user.self_profile_updated"
+	AuditActionSelfSessionsRevoked string = "user.self_sessions_revoked"
+)
+
+// UpdateMyProfileRequest changes attributes of the caller's own profile.
+// Only the attributes the service allows users to edit are accepted.
+type UpdateMyProfileRequest struct {
+	Profile map[string]any `json:"profile"`
+}
+
+// RevokeMySessionsRequest signs the caller out of every Okta session and,
+// with OAuthTokens, revokes the tokens Okta issued them, including the one
+// the request was made with.
+type RevokeMySessionsRequest struct {
+	OAuthTokens bool `json:"oauthTokens,omitempty"`
+}
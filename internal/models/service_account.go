@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+const (
+	ServiceAccountKindUser      string = "USER"
+	ServiceAccountKindAppClient string = "APP_CLIENT"
+)
+
+const (
+	ResourceTypeServiceAccount string = "service_account"
+
+	AuditActionServiceAccountCreated         string = "service_account.created"
+	AuditActionServiceAccountReviewed        string = "service_account.reviewed"
+	AuditActionServiceAccountDeleted         string = "service_account.deleted"
+	AuditActionServiceAccountRotationDue     string = "service_account.rotation_due"
+	AuditActionServiceAccountReviewOverdue   string = "service_account.review_overdue"
+	AuditActionServiceAccountRotationStarted string = "service_account.rotation_started"
+	AuditActionServiceAccountRotated         string = "service_account.rotated"
+)
+
+// ServiceAccount is a non-human identity registered through this service.
+// It is backed by either an Okta user or an OAuth service app client.
+// ClientSecret is only returned when an app client is created.
+type ServiceAccount struct {
+	ID                   string     `json:"id"`
+	Name                 string     `json:"name"`
+	Kind                 string     `json:"kind"`
+	OktaID               string     `json:"oktaId"`
+	ClientID             string     `json:"clientId,omitempty"`
+	ClientSecret         string     `json:"clientSecret,omitempty"`
+	OwnerID              string     `json:"ownerId"`
+	Description          string     `json:"description,omitempty"`
+	ExpiresAt            *time.Time `json:"expiresAt,omitempty"`
+	ReviewBy             time.Time  `json:"reviewBy"`
+	LastReviewed         *time.Time `json:"lastReviewed,omitempty"`
+	LastReviewedBy       string     `json:"lastReviewedBy,omitempty"`
+	RotationIntervalDays int        `json:"rotationIntervalDays"`
+	CredentialRotated    time.Time  `json:"credentialRotated"`
+	RotationJobID        string     `json:"rotationJobId,omitempty"`
+	Created              time.Time  `json:"created"`
+}
+
+// NextRotation reports when the account's credential should next be rotated.
+func (a *ServiceAccount) NextRotation() time.Time {
+	return a.CredentialRotated.AddDate(0, 0, a.RotationIntervalDays)
+}
+
+// CreateServiceAccountRequest represents the data needed to register a service account.
+type CreateServiceAccountRequest struct {
+	Name                 string     `json:"name"`
+	Kind                 string     `json:"kind"`
+	OwnerID              string     `json:"ownerId"`
+	Description          string     `json:"description"`
+	ExpiresAt            *time.Time `json:"expiresAt"`
+	ReviewBy             *time.Time `json:"reviewBy"`
+	RotationIntervalDays int        `json:"rotationIntervalDays"`
+}
+
+// ServiceAccountPastReview is a service account whose review date has passed.
+type ServiceAccountPastReview struct {
+	*ServiceAccount
+	DaysOverdue int `json:"daysOverdue"`
+}
+
+const (
+	JobTypeCredentialRotation string = "service_account.credential_rotation"
+
+	RotationStepGenerate = "generate"
+	RotationStepNotify   = "notify_owner"
+	RotationStepVerify   = "verify_usage"
+	RotationStepRevoke   = "revoke_old"
+)
+
+// CredentialRotation is returned when a rotation starts. The new credential
+// is only ever returned here; the rest of the rotation is tracked by Job.
+type CredentialRotation struct {
+	Job          *Job   `json:"job"`
+	ClientID     string `json:"clientId,omitempty"`
+	ClientSecret string `json:"clientSecret,omitempty"`
+	Password     string `json:"password,omitempty"`
+}
@@ -0,0 +1,22 @@
+package models
+
+const (
+	ResourceTypeSession string = "session"
+
+	AuditActionSessionCookieRedirectIssued   string = "session.cookie_redirect_issued"
+	AuditActionSessionCookieRedirectRejected string = "session.cookie_redirect_rejected"
+)
+
+// SessionCookieRedirectRequest exchanges the session token of an embedded
+// authentication flow for the URL that sets the Okta session cookie and then
+// sends the browser to RedirectURL.
+type SessionCookieRedirectRequest struct {
+	SessionToken string `json:"sessionToken"`
+	RedirectURL  string `json:"redirectUrl"`
+}
+
+// SessionCookieRedirect is where to send the browser. The URL holds the
+// one-time session token, so it must not be logged or cached.
+type SessionCookieRedirect struct {
+	URL string `json:"url"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	SoDModeEnforce string = "ENFORCE"
+	SoDModeFlag    string = "FLAG"
+)
+
+const (
+	AuditActionSoDViolationFlagged string = "sod.violation.flagged"
+)
+
+// SoDPolicy declares two groups whose memberships must not be held by the
+// same user. Enforced policies reject additions; flagged policies allow them
+// but record the violation.
+type SoDPolicy struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	GroupA      string    `json:"groupA"`
+	GroupB      string    `json:"groupB"`
+	Mode        string    `json:"mode"`
+	Created     time.Time `json:"created"`
+}
+
+// CreateSoDPolicyRequest represents the data needed to create a SoD policy.
+type CreateSoDPolicyRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	GroupA      string `json:"groupA"`
+	GroupB      string `json:"groupB"`
+	Mode        string `json:"mode"`
+}
+
+// SoDViolation represents a user who holds, or would hold, both groups of a policy.
+type SoDViolation struct {
+	PolicyID           string `json:"policyId"`
+	PolicyName         string `json:"policyName"`
+	Mode               string `json:"mode"`
+	UserID             string `json:"userId"`
+	GroupID            string `json:"groupId"`
+	ConflictingGroupID string `json:"conflictingGroupId"`
+}
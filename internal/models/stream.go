@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Stream event types.
+const (
+	StreamEventMembershipAdded   = "membership.added"
+	StreamEventMembershipRemoved = "membership.removed"
+	StreamEventUserLifecycle     = "user.lifecycle"
+	StreamEventJobProgress       = "job.progress"
+	// StreamEventMissed tells a client resuming the stream that events were
+	// dropped before it caught up, so it should reload what it shows.
+	StreamEventMissed = "stream.missed"
+)
+
+// Stream event sources, besides ChangeSourceAPI for changes made through
+// the API and ChangeSourceOkta for changes read from the System Log.
+const (
+	StreamSourceJob = "job"
+)
+
+// User lifecycle actions.
+const (
+	UserLifecycleCreated     = "created"
+	UserLifecycleActivated   = "activated"
+	UserLifecycleDeactivated = "deactivated"
+	UserLifecycleSuspended   = "suspended"
+	UserLifecycleUnsuspended = "unsuspended"
+	UserLifecycleDeleted     = "deleted"
+)
+
+// StreamEvent is one event of the live event stream. IDs increase by one
+// with every event published since the server started.
+type StreamEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Source     string    `json:"source"`
+	OccurredAt time.Time `json:"occurredAt"`
+	RequestID  string    `json:"requestId,omitempty"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// StreamMembershipChange is the data of membership.added and
+// membership.removed events. Org is set for changes to another org than the
+// primary one; Actor for changes read from the System Log.
+type StreamMembershipChange struct {
+	Org     string    `json:"org,omitempty"`
+	GroupID string    `json:"groupId"`
+	UserID  string    `json:"userId"`
+	Actor   *LogActor `json:"actor,omitempty"`
+}
+
+// StreamUserLifecycle is the data of user.lifecycle events.
+type StreamUserLifecycle struct {
+	Org    string `json:"org,omitempty"`
+	UserID string `json:"userId"`
+	Action string `json:"action"`
+}
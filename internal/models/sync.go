@@ -0,0 +1,58 @@
+package models
+
+const (
+	SyncDirectionHubToSpoke string = "HUB_TO_SPOKE"
+	SyncDirectionSpokeToHub string = "SPOKE_TO_HUB"
+)
+
+const (
+	ConflictStrategySkip      string = "SKIP"
+	ConflictStrategyOverwrite string = "OVERWRITE"
+	ConflictStrategyFail      string = "FAIL"
+)
+
+const AuditActionSyncPushed string = "org.sync_pushed"
+
+const (
+	SyncOutcomeCreated  string = "CREATED"
+	SyncOutcomeUpdated  string = "UPDATED"
+	SyncOutcomeSkipped  string = "SKIPPED"
+	SyncOutcomeConflict string = "CONFLICT"
+	SyncOutcomeFailed   string = "FAILED"
+)
+
+// SyncPushRequest represents a push of selected users and groups between the
+// hub org and one spoke org.
+type SyncPushRequest struct {
+	Spoke     string   `json:"spoke"`
+	Direction string   `json:"direction"`
+	UserIDs   []string `json:"userIds"`
+	GroupIDs  []string `json:"groupIds"`
+	// FieldMapping copies source profile attributes (keys) to differently named
+	// target attributes (values) in addition to login, email and names.
+	FieldMapping     map[string]string `json:"fieldMapping,omitempty"`
+	ConflictStrategy string            `json:"conflictStrategy"`
+	Activate         bool              `json:"activate"`
+	DryRun           bool              `json:"dryRun"`
+}
+
+// SyncPushResult reports the outcome of every pushed user and group.
+// In a dry run the outcomes describe what would have happened.
+type SyncPushResult struct {
+	Spoke     string            `json:"spoke"`
+	Direction string            `json:"direction"`
+	DryRun    bool              `json:"dryRun"`
+	Users     []*SyncItemResult `json:"users"`
+	Groups    []*SyncItemResult `json:"groups"`
+}
+
+// SyncItemResult reports the outcome of pushing one user or group.
+type SyncItemResult struct {
+	SourceID       string `json:"sourceId"`
+	TargetID       string `json:"targetId,omitempty"`
+	Key            string `json:"key,omitempty"`
+	Outcome        string `json:"outcome"`
+	MembersAdded   int    `json:"membersAdded,omitempty"`
+	MembersMissing int    `json:"membersMissing,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+// Token types of RFC 8693.
+const (
+	TokenTypeAccessToken string = "urn:ietf:params:oauth:token-type:access_token"
+)
+
+const (
+	ResourceTypeTokenExchange string = "token_exchange"
+
+	AuditActionTokenExchangeIssued   string = "token_exchange.issued"
+	AuditActionTokenExchangeRejected string = "token_exchange.rejected"
+)
+
+// TokenExchangeRequest swaps a user's access token for a token for another
+// audience, acting for the same user. The fields are named as in RFC 8693.
+// Scope is space separated; without it, every scope the calling service may
+// request is.
+type TokenExchangeRequest struct {
+	SubjectToken       string `json:"subject_token"`
+	SubjectTokenType   string `json:"subject_token_type"`
+	RequestedTokenType string `json:"requested_token_type,omitempty"`
+	Audience           string `json:"audience"`
+	Scope              string `json:"scope,omitempty"`
+}
+
+// TokenExchangeResponse is the issued token, named as in RFC 8693.
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int    `json:"expires_in"`
+	Scope           string `json:"scope,omitempty"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	ResourceTypeApp string = "app"
+
+	AppAssignmentScopeUser  string = "USER"
+	AppAssignmentScopeGroup string = "GROUP"
+
+	AuditActionAppAccessRevoked string = "app.access_revoked"
+
+	JobTypeUnusedAccessRevocation string = "app.unused_access_revocation"
+
+	RevocationStepVerify   = "verify_unused"
+	RevocationStepUnassign = "unassign"
+)
+
+// UnusedAccess is an app assignment older than the usage window that has no
+// sign-in to the app recorded in the System Log during that window.
+type UnusedAccess struct {
+	AppID        string    `json:"appId"`
+	AppLabel     string    `json:"appLabel"`
+	UserID       string    `json:"userId"`
+	UserName     string    `json:"userName,omitempty"`
+	Scope        string    `json:"scope"`
+	Assigned     time.Time `json:"assigned"`
+	DaysAssigned int       `json:"daysAssigned"`
+	// Revocable is false for assignments inherited from a group, which can
+	// only be removed by taking the user out of that group.
+	Revocable bool `json:"revocable"`
+}
+
+// UnusedAccessFilter sets the usage window. A zero Days uses the configured
+// default.
+type UnusedAccessFilter struct {
+	Days int
+	Now  time.Time
+}
+
+// RevokeUnusedAccessRequest revokes the assignments of the listed users to
+// an app, after checking that they are still unused.
+type RevokeUnusedAccessRequest struct {
+	AppID   string   `json:"appId"`
+	UserIDs []string `json:"userIds"`
+	Days    int      `json:"days,omitempty"`
+}
@@ -23,6 +23,8 @@ type User struct {
 	FirstName   string         `json:"firstName"`
 	LastName    string         `json:"lastName"`
 	Login       string         `json:"login"`
+	UserType    string         `json:"userType,omitempty"`
+	Department  string         `json:"department,omitempty"`
 	Status      string         `json:"status"`
 	Created     time.Time      `json:"created"`
 	Activated   *time.Time     `json:"activated,omitempty"`
@@ -68,6 +70,8 @@ func ConvertOktaUserToModel(oktaUser *okta.User) *User {
 		user.FirstName = oktaUser.Profile.GetFirstName()
 		user.LastName = oktaUser.Profile.GetLastName()
 		user.Login = oktaUser.Profile.GetLogin()
+		user.UserType = oktaUser.Profile.GetUserType()
+		user.Department = oktaUser.Profile.GetDepartment()
 		user.Profile = oktaUser.Profile.AdditionalProperties
 	}
 
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+const FactorStatusActive string = "ACTIVE"
+
+// UserAccess is everything a user can reach and how they sign in, gathered
+// in one place for helpdesk and security.
+type UserAccess struct {
+	User   *User    `json:"user"`
+	Groups []*Group `json:"groups"`
+	// Apps are assigned directly or through a group.
+	Apps       []*App            `json:"apps"`
+	AdminRoles []*Role           `json:"adminRoles"`
+	Factors    *FactorEnrollment `json:"factors"`
+}
+
+// FactorEnrollment is the sign-in factors a user has enrolled.
+type FactorEnrollment struct {
+	// Enrolled reports whether at least one factor is active.
+	Enrolled bool      `json:"enrolled"`
+	Factors  []*Factor `json:"factors"`
+}
+
+// Factor is a sign-in factor a user has enrolled, such as an Okta Verify
+// push or an SMS.
+type Factor struct {
+	ID          string     `json:"id"`
+	FactorType  string     `json:"factorType"`
+	Provider    string     `json:"provider"`
+	Status      string     `json:"status"`
+	Created     *time.Time `json:"created,omitempty"`
+	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
+}
+
+// UserAccessComparison is how the group memberships and app assignments of
+// User and OtherUser differ, as when one should get the same access as the
+// other.
+type UserAccessComparison struct {
+	User      *User            `json:"user"`
+	OtherUser *User            `json:"otherUser"`
+	Groups    *GroupComparison `json:"groups"`
+	Apps      *AppComparison   `json:"apps"`
+}
+
+// GroupComparison is the groups both users are in and those only one is in.
+type GroupComparison struct {
+	Shared        []*Group `json:"shared"`
+	OnlyUser      []*Group `json:"onlyUser"`
+	OnlyOtherUser []*Group `json:"onlyOtherUser"`
+}
+
+// AppComparison is the apps both users are assigned and those only one is
+// assigned.
+type AppComparison struct {
+	Shared        []*App `json:"shared"`
+	OnlyUser      []*App `json:"onlyUser"`
+	OnlyOtherUser []*App `json:"onlyOtherUser"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+const (
+	WebhookEventTest string = "iam.webhook.test"
+)
+
+// WebhookSubscriber represents a downstream receiver registered for IAM events.
+// Secret is only returned when the subscriber is created.
+type WebhookSubscriber struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description,omitempty"`
+	Events      []string  `json:"events"`
+	Secret      string    `json:"secret,omitempty"`
+	Created     time.Time `json:"created"`
+}
+
+// CreateWebhookSubscriberRequest represents the data needed to register a subscriber.
+type CreateWebhookSubscriberRequest struct {
+	URL         string   `json:"url"`
+	Description string   `json:"description"`
+	Events      []string `json:"events"`
+}
+
+// WebhookEvent is the envelope delivered to subscribers.
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data,omitempty"`
+}
+
+// WebhookDeliveryResult reports how a subscriber responded to a delivery.
+type WebhookDeliveryResult struct {
+	EventID    string `json:"eventId"`
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
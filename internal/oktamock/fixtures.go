@@ -0,0 +1,121 @@
+package oktamock
+
+import (
+	"strings"
+	"time"
+)
+
+// Fixtures is the directory a server starts with.
+type Fixtures struct {
+	Users  []FixtureUser
+	Groups []FixtureGroup
+}
+
+// FixtureUser is a user. An empty ID or status is filled in with a generated
+// ID and ACTIVE.
+type FixtureUser struct {
+	ID      string
+	Status  string
+	Profile map[string]any
+}
+
+// FixtureGroup is a group and the IDs of its members. An empty type is
+// OKTA_GROUP.
+type FixtureGroup struct {
+	ID          string
+	Name        string
+	Description string
+	Type        string
+	Members     []string
+}
+
+// DefaultFixtures is a small directory: four users, one of them suspended,
+// and three groups, one of them empty.
+func DefaultFixtures() *Fixtures {
+	return &Fixtures{
+		Users: []FixtureUser{
+			{ID: "00ualice000000000001", Profile: profile("Alice", "Anders", "Engineering")},
+			{ID: "00ubob00000000000002", Profile: profile("Bob", "Baker", "Engineering")},
+			{ID: "00ucarol000000000003", Profile: profile("Carol", "Chen", "Finance")},
+			{ID: "00udave0000000000004", Status: "SUSPENDED", Profile: profile("Dave", "Diaz", "Finance")},
+		},
+		Groups: []FixtureGroup{
+			{
+				ID:          "00gengineering000001",
+				Name:        "Engineering",
+				Description: "Everyone in engineering",
+				Members:     []string{"00ualice000000000001", "00ubob00000000000002"},
+			},
+			{
+				ID:      "00gfinance0000000002",
+				Name:    "Finance",
+				Members: []string{"00ucarol000000000003", "00udave0000000000004"},
+			},
+			{ID: "00gcontractors000003", Name: "Contractors"},
+		},
+	}
+}
+
+func profile(firstName, lastName, department string) map[string]any {
+	login := strings.ToLower(firstName + "." + lastName + "@example.com")
+	return map[string]any{
+		"firstName":  firstName,
+		"lastName":   lastName,
+		"email":      login,
+		"login":      login,
+		"department": department,
+	}
+}
+
+// load adds fixtures to the directory. Callers hold mu or own the server.
+func (s *Server) load(fixtures *Fixtures) {
+	now := timestamp(time.Now())
+
+	for _, fixture := range fixtures.Users {
+		id, status := fixture.ID, fixture.Status
+		if id == "" {
+			id = s.newID("00u")
+		}
+		if status == "" {
+			status = "ACTIVE"
+		}
+
+		profile := make(map[string]any, len(fixture.Profile))
+		for key, value := range fixture.Profile {
+			profile[key] = value
+		}
+		s.users[id] = map[string]any{
+			"id":          id,
+			"status":      status,
+			"created":     now,
+			"lastUpdated": now,
+			"profile":     profile,
+		}
+		s.userIDs = append(s.userIDs, id)
+	}
+
+	for _, fixture := range fixtures.Groups {
+		id, groupType := fixture.ID, fixture.Type
+		if id == "" {
+			id = s.newID("00g")
+		}
+		if groupType == "" {
+			groupType = "OKTA_GROUP"
+		}
+
+		s.groups[id] = map[string]any{
+			"id":                    id,
+			"type":                  groupType,
+			"created":               now,
+			"lastUpdated":           now,
+			"lastMembershipUpdated": now,
+			"profile":               map[string]any{"name": fixture.Name, "description": fixture.Description},
+		}
+		s.groupIDs = append(s.groupIDs, id)
+
+		s.members[id] = make(map[string]bool)
+		for _, userID := range fixture.Members {
+			s.members[id][userID] = true
+		}
+	}
+}
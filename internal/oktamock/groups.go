@@ -0,0 +1,187 @@
+package oktamock
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *Server) listGroups(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	expression := query.Get("filter")
+	if search := query.Get("search"); search != "" {
+		expression = search
+	}
+	prefix := strings.ToLower(query.Get("q"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.groupIDs))
+	for _, id := range s.groupIDs {
+		group := s.groups[id]
+		ok, err := matches(group, expression)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "E0000031", "Invalid search criteria: "+err.Error())
+			return
+		}
+		if ok && (prefix == "" || strings.HasPrefix(strings.ToLower(text(lookup(group, "profile.name"))), prefix)) {
+			ids = append(ids, id)
+		}
+	}
+	s.page(w, r, ids, func(id string) any { return s.groups[id] })
+}
+
+func (s *Server) createGroup(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Profile map[string]any `json:"profile"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if text(body.Profile["name"]) == "" {
+		writeError(w, http.StatusBadRequest, "E0000001", "Api validation failed: name")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, group := range s.groups {
+		if lookup(group, "profile.name") == body.Profile["name"] {
+			writeError(w, http.StatusBadRequest, "E0000001",
+				"Api validation failed: name: An object with this field already exists in the current organization",
+			)
+			return
+		}
+	}
+
+	now := timestamp(time.Now())
+	id := s.newID("00g")
+	group := map[string]any{
+		"id":                    id,
+		"type":                  "OKTA_GROUP",
+		"created":               now,
+		"lastUpdated":           now,
+		"lastMembershipUpdated": now,
+		"profile":               body.Profile,
+	}
+	s.groups[id] = group
+	s.groupIDs = append(s.groupIDs, id)
+	s.members[id] = make(map[string]bool)
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *Server) getGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.groups[chi.URLParam(r, "groupID")]
+	if !ok {
+		notFound(w, "UserGroup", chi.URLParam(r, "groupID"))
+		return
+	}
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *Server) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Profile map[string]any `json:"profile"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.groups[chi.URLParam(r, "groupID")]
+	if !ok {
+		notFound(w, "UserGroup", chi.URLParam(r, "groupID"))
+		return
+	}
+	if group["type"] != "OKTA_GROUP" {
+		writeError(w, http.StatusBadRequest, "E0000060", "Unsupported operation.")
+		return
+	}
+
+	group["profile"] = body.Profile
+	group["lastUpdated"] = timestamp(time.Now())
+	writeJSON(w, http.StatusOK, group)
+}
+
+func (s *Server) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groupID := chi.URLParam(r, "groupID")
+	if _, ok := s.groups[groupID]; !ok {
+		notFound(w, "UserGroup", groupID)
+		return
+	}
+
+	delete(s.groups, groupID)
+	delete(s.members, groupID)
+	s.groupIDs = remove(s.groupIDs, groupID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listGroupUsers(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groupID := chi.URLParam(r, "groupID")
+	if _, ok := s.groups[groupID]; !ok {
+		notFound(w, "UserGroup", groupID)
+		return
+	}
+
+	ids := make([]string, 0)
+	for _, userID := range s.userIDs {
+		if s.members[groupID][userID] {
+			ids = append(ids, userID)
+		}
+	}
+	s.page(w, r, ids, func(id string) any { return s.users[id] })
+}
+
+func (s *Server) addGroupUser(w http.ResponseWriter, r *http.Request) {
+	s.setMembership(w, r, true)
+}
+
+func (s *Server) removeGroupUser(w http.ResponseWriter, r *http.Request) {
+	s.setMembership(w, r, false)
+}
+
+func (s *Server) setMembership(w http.ResponseWriter, r *http.Request, member bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groupID, userID := chi.URLParam(r, "groupID"), chi.URLParam(r, "userID")
+	group, ok := s.groups[groupID]
+	if !ok {
+		notFound(w, "UserGroup", groupID)
+		return
+	}
+	if _, ok := s.users[userID]; !ok {
+		notFound(w, "User", userID)
+		return
+	}
+	if group["type"] != "OKTA_GROUP" {
+		writeError(w, http.StatusBadRequest, "E0000060", "Unsupported operation.")
+		return
+	}
+
+	if s.members[groupID][userID] != member {
+		if member {
+			s.members[groupID][userID] = true
+		} else {
+			delete(s.members[groupID], userID)
+		}
+		group["lastMembershipUpdated"] = timestamp(time.Now())
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,388 @@
+// Package oktamock is a fake Okta management API for exercising services and
+// handlers without a live org. It serves users, groups and group memberships
+// from memory, either over an httptest server or in process as an
+// http.Handler, speaks the wire format and Link header pagination the SDK
+// expects, and can simulate rate limiting and outages. Apps, roles, factors,
+// group rules and the system log are served empty, so code that reads them
+// works against it.
+// Replay serves the interactions recorded with a captured request instead of
+// an org, to re-execute the request offline.
+//
+// Services take the SDK client, so the mock is injected by handing them
+// Client:
+//
+//	server := oktamock.New(oktamock.DefaultFixtures())
+//	defer server.Close()
+//	users := user_service.New(log, server.Client(), nil)
+package oktamock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+)
+
+// DefaultPageSize is how many items a list returns when the request sets no
+// limit. It is small so that tests cross page boundaries with few fixtures.
+const DefaultPageSize = 2
+
+// Request is a request the server received.
+type Request struct {
+	Method string
+	Path   string
+	Query  string
+}
+
+// Server is a fake Okta org.
+type Server struct {
+	// URL is the org URL, such as http://127.0.0.1:51234. It is empty for an
+	// org served in process.
+	URL    string
+	server *httptest.Server
+	router http.Handler
+
+	mu       sync.Mutex
+	users    map[string]map[string]any
+	groups   map[string]map[string]any
+	members  map[string]map[string]bool
+	userIDs  []string
+	groupIDs []string
+	nextID   int
+	requests []Request
+
+	pageSize int
+	// rateLimit is how many requests a window allows; zero disables it.
+	rateLimit   int
+	rateWindow  time.Duration
+	windowStart time.Time
+	windowUsed  int
+	// failures are the statuses the next requests fail with, in order.
+	failures []int
+}
+
+// New starts a server holding fixtures. Close it when done.
+func New(fixtures *Fixtures) *Server {
+	s := NewOrg(fixtures)
+	s.server = httptest.NewServer(s.router)
+	s.URL = s.server.URL
+	return s
+}
+
+// NewOrg returns an org holding fixtures that is served in process through
+// ServeHTTP rather than over a listener.
+func NewOrg(fixtures *Fixtures) *Server {
+	s := &Server{
+		users:    make(map[string]map[string]any),
+		groups:   make(map[string]map[string]any),
+		members:  make(map[string]map[string]bool),
+		pageSize: DefaultPageSize,
+	}
+	if fixtures != nil {
+		s.load(fixtures)
+	}
+
+	router := chi.NewRouter()
+	router.Use(s.simulate)
+	router.Route("/api/v1/users", func(r chi.Router) {
+		r.Get("/", s.listUsers)
+		r.Post("/", s.createUser)
+		r.Route("/{userID}", func(r chi.Router) {
+			r.Get("/", s.getUser)
+			r.Post("/", s.updateUser)
+			r.Put("/", s.replaceUser)
+			r.Delete("/", s.deleteUser)
+			r.Get("/groups", s.listUserGroups)
+			r.Get("/roles", s.listEmpty)
+			r.Get("/factors", s.listEmpty)
+			r.Delete("/sessions", s.revokeSessions)
+			r.Post("/lifecycle/{action}", s.userLifecycle)
+			r.Post("/credentials/change_password", s.changePassword)
+		})
+	})
+	router.Route("/api/v1/groups", func(r chi.Router) {
+		r.Get("/", s.listGroups)
+		r.Post("/", s.createGroup)
+		r.Get("/rules", s.listEmpty)
+		r.Route("/{groupID}", func(r chi.Router) {
+			r.Get("/", s.getGroup)
+			r.Put("/", s.replaceGroup)
+			r.Delete("/", s.deleteGroup)
+			r.Get("/users", s.listGroupUsers)
+			r.Get("/roles", s.listEmpty)
+			r.Put("/users/{userID}", s.addGroupUser)
+			r.Delete("/users/{userID}", s.removeGroupUser)
+		})
+	})
+	router.Get("/api/v1/org", s.getOrg)
+	router.Get("/api/v1/logs", s.listEmpty)
+	router.Get("/api/v1/apps", s.listEmpty)
+	router.Get("/api/v1/iam/roles", s.listRoles)
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "E0000022", "The endpoint does not support the provided HTTP method")
+	})
+
+	s.router = router
+	return s
+}
+
+// ServeHTTP serves a request to the org. A request sent in process from a
+// chi handler carries that handler's routing context, which would make the
+// org's router treat itself as mounted under the caller's route; it is
+// dropped first.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.router.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, nil)))
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	if s.server != nil {
+		s.server.Close()
+	}
+}
+
+// Client returns an SDK client for a server started with New. Its rate limit
+// retries and response cache are off, so callers see every simulated failure.
+func (s *Server) Client() *okta.APIClient {
+	config, err := okta.NewConfiguration(
+		okta.WithOrgUrl(s.URL),
+		okta.WithToken("oktamock"),
+		okta.WithTestingDisableHttpsCheck(true),
+		okta.WithRateLimitMaxRetries(0),
+		okta.WithCache(false),
+	)
+	if err != nil {
+		panic(fmt.Sprintf("oktamock: failed to configure client: %v", err))
+	}
+	// The SDK keeps only the host name of the org URL; the port matters here.
+	config.Host = s.server.Listener.Addr().String()
+	config.HTTPClient = s.server.Client()
+	return okta.NewAPIClient(config)
+}
+
+// SetPageSize sets how many items a list returns when the request sets no
+// limit.
+func (s *Server) SetPageSize(size int) {
+	s.mu.Lock()
+	s.pageSize = size
+	s.mu.Unlock()
+}
+
+// SetRateLimit allows limit requests per window and answers the rest with
+// 429 and the X-Rate-Limit headers Okta sends. A zero limit disables it.
+func (s *Server) SetRateLimit(limit int, window time.Duration) {
+	s.mu.Lock()
+	s.rateLimit = limit
+	s.rateWindow = window
+	s.windowStart = time.Now()
+	s.windowUsed = 0
+	s.mu.Unlock()
+}
+
+// FailNext makes the next count requests fail with status, such as 500 or
+// 503 to simulate an outage.
+func (s *Server) FailNext(count, status int) {
+	s.mu.Lock()
+	for range count {
+		s.failures = append(s.failures, status)
+	}
+	s.mu.Unlock()
+}
+
+// Requests returns the requests received so far, oldest first.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// Members returns the IDs of the members of groupID.
+func (s *Server) Members(groupID string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0)
+	for _, userID := range s.userIDs {
+		if s.members[groupID][userID] {
+			ids = append(ids, userID)
+		}
+	}
+	return ids
+}
+
+// simulate records every request and applies the simulated failures and
+// rate limit before it is served.
+func (s *Server) simulate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery})
+
+		if len(s.failures) > 0 {
+			status := s.failures[0]
+			s.failures = s.failures[1:]
+			s.mu.Unlock()
+			writeError(w, status, "E0000009", http.StatusText(status))
+			return
+		}
+
+		if s.rateLimit > 0 {
+			now := time.Now()
+			if now.Sub(s.windowStart) >= s.rateWindow {
+				s.windowStart = now
+				s.windowUsed = 0
+			}
+			s.windowUsed++
+			reset := s.windowStart.Add(s.rateWindow)
+
+			w.Header().Set("X-Rate-Limit-Limit", strconv.Itoa(s.rateLimit))
+			w.Header().Set("X-Rate-Limit-Remaining", strconv.Itoa(max(s.rateLimit-s.windowUsed, 0)))
+			w.Header().Set("X-Rate-Limit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			if s.windowUsed > s.rateLimit {
+				s.mu.Unlock()
+				writeError(w, http.StatusTooManyRequests, "E0000047", "API call exceeded rate limit due to too many requests.")
+				return
+			}
+		}
+		s.mu.Unlock()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newID returns an Okta-like ID with prefix, such as 00u for users.
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%017d", prefix, s.nextID)
+}
+
+// page serves the items after the after parameter, at most limit of them,
+// with a Link header to the next page when there is one.
+func (s *Server) page(w http.ResponseWriter, r *http.Request, ids []string, item func(id string) any) {
+	limit := s.pageSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, "E0000001", "Api validation failed: limit")
+			return
+		}
+		limit = parsed
+	}
+
+	start := 0
+	if after := r.URL.Query().Get("after"); after != "" {
+		for i, id := range ids {
+			if id == after {
+				start = i + 1
+				break
+			}
+		}
+	}
+	end := min(start+limit, len(ids))
+
+	items := make([]any, 0, end-start)
+	for _, id := range ids[start:end] {
+		items = append(items, item(id))
+	}
+
+	if end < len(ids) {
+		query := r.URL.Query()
+		query.Set("after", ids[end-1])
+		query.Set("limit", strconv.Itoa(limit))
+		w.Header().Add("Link", fmt.Sprintf(`<%s%s?%s>; rel="next"`, baseURL(r), r.URL.Path, query.Encode()))
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+// baseURL is the org URL the request was sent to. A request served in process
+// carries the client's absolute URL; one received over a listener does not.
+func baseURL(r *http.Request) string {
+	if r.URL.IsAbs() {
+		return r.URL.Scheme + "://" + r.URL.Host
+	}
+	return "http://" + r.Host
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// writeError answers with an error in Okta's format.
+func writeError(w http.ResponseWriter, status int, code, summary string) {
+	writeJSON(w, status, map[string]any{
+		"errorCode":    code,
+		"errorSummary": summary,
+		"errorLink":    code,
+		"errorId":      "oaemock" + strconv.FormatInt(time.Now().UnixNano(), 36),
+		"errorCauses":  []any{},
+	})
+}
+
+func notFound(w http.ResponseWriter, kind, id string) {
+	writeError(w, http.StatusNotFound, "E0000007", fmt.Sprintf("Not found: Resource not found: %s (%s)", id, kind))
+}
+
+// decode reads a JSON request body into v, answering 400 when it is not one.
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, "E0000003", "The request body was not well-formed.")
+		return false
+	}
+	return true
+}
+
+// matches reports whether item satisfies expression, a filter or search of
+// `attribute eq "value"` clauses joined by "or" or "and". Attributes are
+// top-level fields, such as status, or profile fields, such as
+// profile.department.
+func matches(item map[string]any, expression string) (bool, error) {
+	expression = strings.TrimSpace(expression)
+	if expression == "" {
+		return true, nil
+	}
+
+	for _, alternative := range strings.Split(expression, " or ") {
+		all := true
+		for _, clause := range strings.Split(alternative, " and ") {
+			attribute, value, ok := strings.Cut(strings.Trim(strings.TrimSpace(clause), "()"), " eq ")
+			value, unquoted := strings.CutPrefix(strings.TrimSpace(value), `"`)
+			value, closed := strings.CutSuffix(value, `"`)
+			if !ok || !unquoted || !closed {
+				return false, fmt.Errorf("unsupported expression %q", clause)
+			}
+			if fmt.Sprint(lookup(item, strings.TrimSpace(attribute))) != value {
+				all = false
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// lookup returns the value at a dotted path such as profile.login.
+func lookup(item map[string]any, path string) any {
+	var value any = item
+	for _, key := range strings.Split(path, ".") {
+		fields, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = fields[key]
+	}
+	return value
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
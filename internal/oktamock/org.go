@@ -0,0 +1,33 @@
+package oktamock
+
+import (
+	"net/http"
+	"strings"
+)
+
+// getOrg answers with the org's settings, which clients read to test the
+// connection.
+func (s *Server) getOrg(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if r.URL.IsAbs() {
+		host = r.URL.Host
+	}
+	subdomain, _, _ := strings.Cut(host, ".")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"id":          "00omock0000000000001",
+		"companyName": "oktamock",
+		"subdomain":   subdomain,
+		"status":      "ACTIVE",
+		"website":     baseURL(r),
+	})
+}
+
+// listEmpty serves an empty list, for the resources the org has none of.
+func (s *Server) listEmpty(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, []any{})
+}
+
+// listRoles serves an empty list of custom roles.
+func (s *Server) listRoles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"roles": []any{}})
+}
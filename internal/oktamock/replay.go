@@ -0,0 +1,103 @@
+package oktamock
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+// Replay answers requests with recorded interactions instead of an org, so
+// that a captured request can be served again offline exactly as Okta served
+// it. Each interaction answers one request with the same method, path and
+// query, in the order they were recorded. Requests no interaction answers
+// get a 404 and are noted; interactions that got no response, such as those
+// refused by an open circuit breaker, are answered with a 503.
+//
+// It is an http.Handler, so a fixture exported from the replay endpoints can
+// be served to a client in a test:
+//
+//	server := httptest.NewServer(oktamock.NewReplay(fixture.Interactions))
+type Replay struct {
+	mu           sync.Mutex
+	interactions []*models.OktaInteraction
+	used         []bool
+	unmatched    []*models.OktaRequest
+}
+
+func NewReplay(interactions []*models.OktaInteraction) *Replay {
+	return &Replay{interactions: interactions, used: make([]bool, len(interactions))}
+}
+
+func (rp *Replay) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	interaction := rp.take(r)
+	if interaction == nil {
+		writeError(w, http.StatusNotFound, "E0000007", "Not found: no recorded interaction for "+r.Method+" "+r.URL.Path)
+		return
+	}
+	if interaction.Status == 0 {
+		writeError(w, http.StatusServiceUnavailable, "E0000009", interaction.Error)
+		return
+	}
+
+	if interaction.Link != "" {
+		w.Header().Set("Link", interaction.Link)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(interaction.Status)
+	_, _ = w.Write(interaction.ResponseBody)
+}
+
+// take returns the first unused interaction that answers r, and marks it
+// used.
+func (rp *Replay) take(r *http.Request) *models.OktaInteraction {
+	query := canonicalQuery(r.URL.RawQuery)
+
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	for i, interaction := range rp.interactions {
+		if !rp.used[i] && interaction.Method == r.Method && interaction.Path == r.URL.Path &&
+			canonicalQuery(interaction.Query) == query {
+			rp.used[i] = true
+			return interaction
+		}
+	}
+
+	rp.unmatched = append(rp.unmatched, &models.OktaRequest{
+		Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery,
+	})
+	return nil
+}
+
+// Unmatched returns the requests no interaction answered, oldest first.
+func (rp *Replay) Unmatched() []*models.OktaRequest {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	return append([]*models.OktaRequest{}, rp.unmatched...)
+}
+
+// Unused counts the interactions no request was answered with.
+func (rp *Replay) Unused() int {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+
+	unused := 0
+	for _, used := range rp.used {
+		if !used {
+			unused++
+		}
+	}
+	return unused
+}
+
+// canonicalQuery sorts the parameters of query, so that the order the SDK
+// adds them in does not matter.
+func canonicalQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return query
+	}
+	return values.Encode()
+}
@@ -0,0 +1,328 @@
+package oktamock
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// lifecycleTransitions maps each lifecycle action to the statuses it applies
+// to and the status it leaves the user in; an empty one leaves it as is.
+var lifecycleTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	"activate": {from: []string{"STAGED", "PROVISIONED", "DEPROVISIONED"}, to: "ACTIVE"},
+	"deactivate": {
+		from: []string{"STAGED", "PROVISIONED", "ACTIVE", "RECOVERY", "PASSWORD_EXPIRED", "LOCKED_OUT", "SUSPENDED"},
+		to:   "DEPROVISIONED",
+	},
+	"suspend":         {from: []string{"ACTIVE"}, to: "SUSPENDED"},
+	"unsuspend":       {from: []string{"SUSPENDED"}, to: "ACTIVE"},
+	"expire_password": {from: []string{"ACTIVE", "PASSWORD_EXPIRED"}, to: "PASSWORD_EXPIRED"},
+	"expire_password_with_temp_password": {
+		from: []string{"ACTIVE", "PASSWORD_EXPIRED"},
+		to:   "PASSWORD_EXPIRED",
+	},
+	"reset_password": {
+		from: []string{"ACTIVE", "RECOVERY", "PASSWORD_EXPIRED", "LOCKED_OUT"},
+		to:   "RECOVERY",
+	},
+	"unlock": {from: []string{"LOCKED_OUT"}, to: "ACTIVE"},
+	"reset_factors": {
+		from: []string{"STAGED", "PROVISIONED", "ACTIVE", "RECOVERY", "PASSWORD_EXPIRED", "LOCKED_OUT", "SUSPENDED"},
+	},
+}
+
+func (s *Server) listUsers(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	expression := query.Get("filter")
+	if search := query.Get("search"); search != "" {
+		expression = search
+	}
+	prefix := strings.ToLower(query.Get("q"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.userIDs))
+	for _, id := range s.userIDs {
+		user := s.users[id]
+		ok, err := matches(user, expression)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "E0000031", "Invalid search criteria: "+err.Error())
+			return
+		}
+		if ok && (prefix == "" || profileHasPrefix(user, prefix)) {
+			ids = append(ids, id)
+		}
+	}
+	s.page(w, r, ids, func(id string) any { return s.users[id] })
+}
+
+func (s *Server) createUser(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Profile map[string]any `json:"profile"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	login, _ := body.Profile["login"].(string)
+	if login == "" {
+		writeError(w, http.StatusBadRequest, "E0000001", "Api validation failed: login")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUser(login) != nil {
+		writeError(w, http.StatusBadRequest, "E0000001",
+			"Api validation failed: login: An object with this field already exists in the current organization",
+		)
+		return
+	}
+
+	status := "STAGED"
+	if r.URL.Query().Get("activate") != "false" {
+		status = "ACTIVE"
+	}
+
+	now := timestamp(time.Now())
+	id := s.newID("00u")
+	user := map[string]any{
+		"id":          id,
+		"status":      status,
+		"created":     now,
+		"lastUpdated": now,
+		"profile":     body.Profile,
+	}
+	if status == "ACTIVE" {
+		user["activated"] = now
+	}
+	s.users[id] = user
+	s.userIDs = append(s.userIDs, id)
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+func (s *Server) getUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+// updateUser merges the given profile attributes into the user's profile.
+func (s *Server) updateUser(w http.ResponseWriter, r *http.Request) {
+	s.writeProfile(w, r, false)
+}
+
+// replaceUser replaces the user's profile.
+func (s *Server) replaceUser(w http.ResponseWriter, r *http.Request) {
+	s.writeProfile(w, r, true)
+}
+
+func (s *Server) writeProfile(w http.ResponseWriter, r *http.Request, replace bool) {
+	var body struct {
+		Profile map[string]any `json:"profile"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+
+	profile := user["profile"].(map[string]any)
+	if replace {
+		profile = make(map[string]any)
+	}
+	for key, value := range body.Profile {
+		profile[key] = value
+	}
+	user["profile"] = profile
+	user["lastUpdated"] = timestamp(time.Now())
+
+	writeJSON(w, http.StatusOK, user)
+}
+
+// deleteUser deactivates an active user and deletes a deactivated one, as
+// Okta does.
+func (s *Server) deleteUser(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+
+	id := user["id"].(string)
+	if user["status"] != "DEPROVISIONED" {
+		s.setStatus(user, "DEPROVISIONED")
+	} else {
+		delete(s.users, id)
+		s.userIDs = remove(s.userIDs, id)
+		for _, members := range s.members {
+			delete(members, id)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listUserGroups(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+
+	ids := make([]string, 0)
+	for _, groupID := range s.groupIDs {
+		if s.members[groupID][user["id"].(string)] {
+			ids = append(ids, groupID)
+		}
+	}
+	s.page(w, r, ids, func(id string) any { return s.groups[id] })
+}
+
+func (s *Server) userLifecycle(w http.ResponseWriter, r *http.Request) {
+	action := chi.URLParam(r, "action")
+	transition, ok := lifecycleTransitions[action]
+	if !ok {
+		writeError(w, http.StatusNotFound, "E0000022", "The endpoint does not support the provided HTTP method")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+
+	status, _ := user["status"].(string)
+	if !slices.Contains(transition.from, status) {
+		writeError(w, http.StatusForbidden, "E0000038",
+			"This operation is not allowed in the user's current status.",
+		)
+		return
+	}
+	if transition.to != "" {
+		s.setStatus(user, transition.to)
+	}
+
+	switch action {
+	case "expire_password":
+		writeJSON(w, http.StatusOK, user)
+	case "expire_password_with_temp_password":
+		writeJSON(w, http.StatusOK, map[string]any{"tempPassword": uuid.NewString()[:12]})
+	default:
+		writeJSON(w, http.StatusOK, map[string]any{})
+	}
+}
+
+// revokeSessions answers as Okta does for an existing user. The org keeps no
+// sessions, so there is nothing to revoke.
+func (s *Server) revokeSessions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findUser(chi.URLParam(r, "userID")) == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) changePassword(w http.ResponseWriter, r *http.Request) {
+	var body map[string]any
+	if !decode(w, r, &body) {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.findUser(chi.URLParam(r, "userID"))
+	if user == nil {
+		notFound(w, "User", chi.URLParam(r, "userID"))
+		return
+	}
+	user["passwordChanged"] = timestamp(time.Now())
+
+	writeJSON(w, http.StatusOK, map[string]any{"password": map[string]any{}})
+}
+
+// findUser returns the user with the given ID or login. Callers hold mu.
+func (s *Server) findUser(idOrLogin string) map[string]any {
+	if user, ok := s.users[idOrLogin]; ok {
+		return user
+	}
+	for _, user := range s.users {
+		if strings.EqualFold(text(lookup(user, "profile.login")), idOrLogin) {
+			return user
+		}
+	}
+	return nil
+}
+
+// setStatus moves user to status. Callers hold mu.
+func (s *Server) setStatus(user map[string]any, status string) {
+	now := timestamp(time.Now())
+	user["status"] = status
+	user["statusChanged"] = now
+	user["lastUpdated"] = now
+	if status == "ACTIVE" && user["activated"] == nil {
+		user["activated"] = now
+	}
+}
+
+// profileHasPrefix reports whether the login, email, first name or last
+// name starts with prefix, as Okta's q parameter matches.
+func profileHasPrefix(user map[string]any, prefix string) bool {
+	for _, attribute := range []string{"login", "email", "firstName", "lastName"} {
+		if strings.HasPrefix(strings.ToLower(text(lookup(user, "profile."+attribute))), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// text is value when it is a string, and empty otherwise.
+func text(value any) string {
+	text, _ := value.(string)
+	return text
+}
+
+func remove(ids []string, id string) []string {
+	result := ids[:0]
+	for _, existing := range ids {
+		if existing != id {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
@@ -0,0 +1,59 @@
+// Package orgs holds the services of every configured Okta org, so that the
+// org-scoped endpoints can serve any of them. Each org has its own Okta
+// client and its own service instances; nothing is shared between orgs.
+package orgs
+
+import (
+	"sort"
+
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+)
+
+// Header selects an org on the user, group and role endpoints, as an
+// alternative to the /orgs/{org} path prefix.
+const Header = "X-Okta-Org"
+
+// Services are the services behind the org-scoped endpoints of one org.
+type Services struct {
+	Name   string
+	Domain string
+	Users  *user_service.Service
+	Groups *group_service.Service
+	Roles  *role_service.Service
+}
+
+type Registry struct {
+	primary string
+	orgs    map[string]*Services
+}
+
+// NewRegistry creates a registry whose primary org serves the unprefixed API.
+func NewRegistry(primary *Services, others ...*Services) *Registry {
+	r := &Registry{primary: primary.Name, orgs: map[string]*Services{primary.Name: primary}}
+	for _, org := range others {
+		r.orgs[org.Name] = org
+	}
+	return r
+}
+
+// Primary is the name of the org behind the unprefixed API.
+func (r *Registry) Primary() string {
+	return r.primary
+}
+
+func (r *Registry) Get(name string) (*Services, bool) {
+	org, ok := r.orgs[name]
+	return org, ok
+}
+
+// All lists every org, sorted by name.
+func (r *Registry) All() []*Services {
+	result := make([]*Services, 0, len(r.orgs))
+	for _, org := range r.orgs {
+		result = append(result, org)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
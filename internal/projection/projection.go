@@ -0,0 +1,112 @@
+// Package projection trims API responses to the fields a client asks for
+// with ?fields=, so a UI that only shows IDs and names does not receive
+// whole users and groups.
+package projection
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+// QueryParam lists the fields to keep, comma separated, with dots for nested
+// fields such as "profile.costCenter". It may be repeated.
+const QueryParam = "fields"
+
+// tree holds the selected fields: a nil subtree keeps the whole field.
+type tree map[string]tree
+
+// Middleware projects the data of successful JSON responses to GET requests
+// that have a fields parameter: an object keeps only the selected fields,
+// and a list keeps them in each of its objects. Streamed lines are projected
+// one by one. Malformed selections are answered with 400.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values, ok := r.URL.Query()[QueryParam]
+		if !ok || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		selected, ok := parse(values)
+		if !ok {
+			response.RespondError(w, http.StatusBadRequest, "API_ERROR",
+				"fields must be comma-separated field names, with dots for nested fields", nil,
+			)
+			return
+		}
+
+		rw := response.NewRewriter(w, func(value any, line bool) {
+			if line {
+				project(value, selected)
+				return
+			}
+			// Only the data of the envelope is projected, and never that
+			// of error responses.
+			envelope, ok := value.(map[string]any)
+			if ok && envelope["success"] == true {
+				project(envelope["data"], selected)
+			}
+		})
+		next.ServeHTTP(rw, r)
+		rw.Finish()
+	})
+}
+
+func parse(values []string) (tree, bool) {
+	selected := make(tree)
+	for _, value := range values {
+		for field := range strings.SplitSeq(value, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+
+			node := selected
+			path := strings.Split(field, ".")
+			for i, name := range path {
+				if name == "" {
+					return nil, false
+				}
+				child, seen := node[name]
+				if seen && child == nil {
+					// The whole field is already selected.
+					break
+				}
+				if i == len(path)-1 {
+					node[name] = nil
+					break
+				}
+				if child == nil {
+					child = make(tree)
+					node[name] = child
+				}
+				node = child
+			}
+		}
+	}
+	return selected, len(selected) > 0
+}
+
+// project removes the fields selected does not name from value, in place.
+// Lists are projected element by element.
+func project(value any, selected tree) {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, child := range v {
+			subtree, ok := selected[name]
+			if !ok {
+				delete(v, name)
+				continue
+			}
+			if subtree != nil {
+				project(child, subtree)
+			}
+		}
+	case []any:
+		for _, child := range v {
+			project(child, selected)
+		}
+	}
+}
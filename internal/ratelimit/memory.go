@@ -0,0 +1,52 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// memoryStore keeps the buckets of one instance. A bucket expires once it
+// would have refilled, as a full bucket is the same as none.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets *gocache.Cache
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: gocache.New(gocache.NoExpiration, time.Minute)}
+}
+
+func (s *memoryStore) take(_ context.Context, key string, limit Limit) (*Result, error) {
+	now := time.Now()
+	perSecond := float64(limit.PerMinute) / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &bucket{tokens: float64(limit.Burst), at: now}
+	if item, found := s.buckets.Get(key); found {
+		b = item.(*bucket)
+		b.tokens = min(float64(limit.Burst), b.tokens+now.Sub(b.at).Seconds()*perSecond)
+		b.at = now
+	}
+
+	result := &Result{}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+	}
+	result.Remaining = int(b.tokens)
+
+	s.buckets.Set(key, b, time.Duration(float64(limit.Burst)/perSecond*float64(time.Second))+time.Second)
+	return result, nil
+}
@@ -0,0 +1,170 @@
+// Package ratelimit throttles API clients with a token bucket per client and
+// route class. A client is the API key of a valid X-API-Key, the Okta app or
+// user of a valid bearer token, or else the client IP, so unverifiable keys
+// and tokens cannot be rotated to dodge the limit. Buckets live in memory, or in Redis when several instances must
+// share them.
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/auth"
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const (
+	LimitHeader     = "X-RateLimit-Limit"
+	RemainingHeader = "X-RateLimit-Remaining"
+)
+
+// Class groups routes that share a limit.
+type Class string
+
+const (
+	Read  Class = "read"
+	Write Class = "write"
+)
+
+// Limit lets a client make Burst requests at once and PerMinute requests a
+// minute after that.
+type Limit struct {
+	PerMinute int
+	Burst     int
+}
+
+// Result is the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, when not allowed.
+	RetryAfter time.Duration
+}
+
+// store takes one token from the bucket of key, creating a full one if
+// there is none.
+type store interface {
+	take(ctx context.Context, key string, limit Limit) (*Result, error)
+}
+
+type Limiter struct {
+	log      *zap.SugaredLogger
+	verifier *auth.Verifier
+	store    store
+	limits   map[Class]Limit
+}
+
+// New builds a limiter from cfg, backed by Redis when cfg.RedisURL is set.
+func New(log *zap.SugaredLogger, cfg *config.RateLimitConfig, verifier *auth.Verifier) (*Limiter, error) {
+	limiter := &Limiter{
+		log:      log,
+		verifier: verifier,
+		store:    newMemoryStore(),
+		limits: map[Class]Limit{
+			Read:  {PerMinute: cfg.ReadsPerMinute, Burst: cfg.ReadBurst},
+			Write: {PerMinute: cfg.WritesPerMinute, Burst: cfg.WriteBurst},
+		},
+	}
+
+	if cfg.RedisURL != "" {
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit Redis URL: %w", err)
+		}
+		limiter.store = newRedisStore(redis.NewClient(options))
+	}
+
+	return limiter, nil
+}
+
+// Middleware rejects requests over their client's limit for the class
+// classify puts them in with 429 and a Retry-After header. Classes with a
+// zero limit are not limited. When the store fails the request is let
+// through, so a Redis outage does not take the API down with it.
+func (l *Limiter) Middleware(classify func(*http.Request) Class) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class := classify(r)
+			limit := l.limits[class]
+			if limit.PerMinute <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			client := l.identify(r)
+			result, err := l.store.take(r.Context(), string(class)+":"+client, limit)
+			if err != nil {
+				logger.FromContext(r.Context(), l.log).Infow("Failed to check rate limit; allowing request", zap.Error(err))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set(LimitHeader, strconv.Itoa(limit.PerMinute))
+			w.Header().Set(RemainingHeader, strconv.Itoa(result.Remaining))
+			if !result.Allowed {
+				seconds := int(math.Ceil(result.RetryAfter.Seconds()))
+				logger.FromContext(r.Context(), l.log).Infow("Rate limit exceeded",
+					"client", client, "class", class, "retryAfterSeconds", seconds,
+				)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				response.RespondError(w, http.StatusTooManyRequests, "RATE_LIMITED",
+					"Rate limit exceeded; retry after "+strconv.Itoa(seconds)+" seconds", nil,
+				)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// identify names the client a request is counted against: a valid API key
+// by its ID, the app of a valid token, its user, or else the client IP.
+func (l *Limiter) identify(r *http.Request) string {
+	caller, ok := auth.CallerFromContext(r.Context())
+	if !ok && l.verifier != nil {
+		if rawKey := r.Header.Get(auth.APIKeyHeader); rawKey != "" {
+			// API key callers are named apikey:<id> as their client.
+			caller, _ = l.verifier.VerifyAPIKey(r, rawKey)
+		} else {
+			scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+			if found && strings.EqualFold(scheme, "Bearer") && token != "" {
+				caller, _ = l.verifier.Verify(r.Context(), token)
+			}
+		}
+	}
+
+	switch {
+	case caller != nil && caller.ClientID != "":
+		return "client:" + caller.ClientID
+	case caller != nil && caller.Subject != "":
+		return "subject:" + hash(caller.Subject)
+	}
+
+	// RemoteAddr is the peer, or the client a trusted proxy forwarded for;
+	// forwarding headers from anyone else are ignored.
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// hash keeps logins out of Redis keys and logs.
+func hash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
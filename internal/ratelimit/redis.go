@@ -0,0 +1,58 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes from a bucket in one step, on the Redis
+// clock so instances with skewed clocks agree. It returns whether the token
+// was taken, the whole tokens left and the milliseconds until the next one.
+var takeScript = redis.NewScript(`
+local perMs = tonumber(ARGV[1]) / 60000
+local burst = tonumber(ARGV[2])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'at')
+local tokens = tonumber(bucket[1]) or burst
+local at = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - at) * perMs)
+
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / perMs)
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'at', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / perMs) + 1000)
+return {allowed, math.floor(tokens), wait}
+`)
+
+// redisStore shares buckets between every instance using the same Redis.
+type redisStore struct {
+	client *redis.Client
+}
+
+func newRedisStore(client *redis.Client) *redisStore {
+	return &redisStore{client: client}
+}
+
+func (s *redisStore) take(ctx context.Context, key string, limit Limit) (*Result, error) {
+	values, err := takeScript.Run(ctx, s.client, []string{"iam:ratelimit:" + key}, limit.PerMinute, limit.Burst).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take a rate limit token from Redis: %w", err)
+	}
+
+	return &Result{
+		Allowed:    values[0] == 1,
+		Remaining:  int(values[1]),
+		RetryAfter: time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
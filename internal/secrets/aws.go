@@ -0,0 +1,46 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// awsProvider reads secrets from AWS Secrets Manager. Credentials and region
+// come from the standard AWS environment, shared config or instance role.
+type awsProvider struct {
+	client *secretsmanager.Client
+}
+
+func newAWSProvider(ctx context.Context) (*awsProvider, error) {
+	awsConfig, err := aws_config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+	return &awsProvider{client: secretsmanager.NewFromConfig(awsConfig)}, nil
+}
+
+func (p *awsProvider) Get(ctx context.Context, name string) (string, error) {
+	secretID, key := splitKey(name)
+
+	output, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: &secretID})
+	if err != nil {
+		var notFound *types.ResourceNotFoundException
+		if errors.As(err, &notFound) {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, secretID)
+		}
+		return "", fmt.Errorf("failed to get secret from AWS Secrets Manager: %w", err)
+	}
+
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret %s has no string value", secretID)
+	}
+	if key == "" {
+		return *output.SecretString, nil
+	}
+	return selectKey(*output.SecretString, key)
+}
@@ -0,0 +1,19 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// envProvider reads secrets from environment variables. Values only change
+// with the process environment, so it never rotates anything by itself.
+type envProvider struct{}
+
+func (envProvider) Get(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
@@ -0,0 +1,34 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileProvider reads each secret from a file in dir, such as a mounted
+// Kubernetes or Docker secret, which is rewritten in place on rotation.
+type fileProvider struct {
+	dir string
+}
+
+func (p *fileProvider) Get(_ context.Context, name string) (string, error) {
+	if name != filepath.Base(name) {
+		return "", fmt.Errorf("secret name %q must be a file name in the secrets directory", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.dir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("%w: no file %s in %s", ErrSecretNotFound, name, p.dir)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Editors and echo leave a trailing newline that is not part of a token.
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
@@ -0,0 +1,150 @@
+// Package secrets reads credentials from a pluggable provider and keeps them
+// current, so a rotated secret is picked up without restarting the server.
+//
+// Secret names are provider specific: an environment variable for env, a file
+// name for file, and a secret ID or KV path for aws and vault. For aws and
+// vault, "name#key" selects one key of a JSON or key/value secret.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+)
+
+const (
+	ProviderEnv   = "env"
+	ProviderFile  = "file"
+	ProviderAWS   = "aws"
+	ProviderVault = "vault"
+)
+
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Provider returns the current value of a named secret.
+type Provider interface {
+	Get(ctx context.Context, name string) (string, error)
+}
+
+// NewProvider creates the provider selected by cfg.Provider.
+func NewProvider(ctx context.Context, cfg *config.SecretsConfig) (Provider, error) {
+	switch cfg.Provider {
+	case ProviderEnv:
+		return envProvider{}, nil
+	case ProviderFile:
+		return &fileProvider{dir: cfg.Dir}, nil
+	case ProviderAWS:
+		return newAWSProvider(ctx)
+	case ProviderVault:
+		return newVaultProvider(cfg)
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %q", cfg.Provider)
+	}
+}
+
+// Secret holds the latest value read for one name. It is safe for
+// concurrent use.
+type Secret struct {
+	name  string
+	value atomic.Pointer[string]
+}
+
+func (s *Secret) Name() string {
+	return s.name
+}
+
+// Value returns the value read by the latest successful load.
+func (s *Secret) Value() string {
+	return *s.value.Load()
+}
+
+// Store loads secrets from a provider and reloads them on Refresh.
+type Store struct {
+	log      *zap.SugaredLogger
+	provider Provider
+
+	mu      sync.Mutex
+	secrets map[string]*Secret
+}
+
+func NewStore(log *zap.SugaredLogger, provider Provider) *Store {
+	return &Store{log: log, provider: provider, secrets: make(map[string]*Secret)}
+}
+
+// Load reads a secret and keeps it up to date from then on. Loading the same
+// name again returns the same Secret.
+func (s *Store) Load(ctx context.Context, name string) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if secret, ok := s.secrets[name]; ok {
+		return secret, nil
+	}
+
+	value, err := s.provider.Get(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secret %s: %w", name, err)
+	}
+
+	secret := &Secret{name: name}
+	secret.value.Store(&value)
+	s.secrets[name] = secret
+	return secret, nil
+}
+
+// Refresh reads every loaded secret again. A secret that cannot be read
+// keeps its previous value.
+func (s *Store) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	secrets := make([]*Secret, 0, len(s.secrets))
+	for _, secret := range s.secrets {
+		secrets = append(secrets, secret)
+	}
+	s.mu.Unlock()
+
+	var errs []error
+	for _, secret := range secrets {
+		value, err := s.provider.Get(ctx, secret.name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to reload secret %s: %w", secret.name, err))
+			continue
+		}
+
+		if value != secret.Value() {
+			secret.value.Store(&value)
+			s.log.Infow("Secret rotated", "secret", secret.name)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// splitKey splits "name#key" into its parts.
+func splitKey(name string) (string, string) {
+	name, key, _ := strings.Cut(name, "#")
+	return name, key
+}
+
+// selectKey returns the string field key of a JSON object secret.
+func selectKey(value, key string) (string, error) {
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object, so key %q cannot be selected", key)
+	}
+	return stringField(fields, key)
+}
+
+func stringField(fields map[string]any, key string) (string, error) {
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: the secret has no string key %q", ErrSecretNotFound, key)
+	}
+	return value, nil
+}
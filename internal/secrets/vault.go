@@ -0,0 +1,82 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/config"
+)
+
+// vaultProvider reads secrets from a HashiCorp Vault KV version 2 engine.
+// The name is the secret's path in the engine; without a #key the secret
+// must hold exactly one key.
+type vaultProvider struct {
+	addr   string
+	token  string
+	mount  string
+	client *http.Client
+}
+
+func newVaultProvider(cfg *config.SecretsConfig) (*vaultProvider, error) {
+	if cfg.VaultAddr == "" || cfg.VaultToken == "" {
+		return nil, errors.New("the vault secrets provider requires VAULT_ADDR and VAULT_TOKEN")
+	}
+
+	return &vaultProvider{
+		addr:   strings.TrimRight(cfg.VaultAddr, "/"),
+		token:  cfg.VaultToken,
+		mount:  strings.Trim(cfg.VaultMount, "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *vaultProvider) Get(ctx context.Context, name string) (string, error) {
+	path, key := splitKey(name)
+
+	target := p.addr + "/v1/" + url.PathEscape(p.mount) + "/data/" + strings.Trim(path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build Vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from Vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: %s/%s", ErrSecretNotFound, p.mount, path)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned unexpected status code: %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	fields := body.Data.Data
+	if key != "" {
+		return stringField(fields, key)
+	}
+	if len(fields) != 1 {
+		return "", fmt.Errorf("secret %s has %d keys; select one with %s#<key>", path, len(fields), path)
+	}
+	for field := range fields {
+		key = field
+	}
+	return stringField(fields, key)
+}
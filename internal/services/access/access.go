@@ -0,0 +1,221 @@
+package access_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+)
+
+// Service gathers a user's groups, app assignments, admin roles and factors
+// into one view, and compares the access of two users. The Okta calls behind it are independent, so they are made
+// in parallel; the first to fail cancels the others.
+type Service struct {
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	usersSvc *user_service.Service
+	appsSvc  *app_service.Service
+	rolesSvc *role_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, usersSvc *user_service.Service,
+	appsSvc *app_service.Service, rolesSvc *role_service.Service,
+) *Service {
+	return &Service{log: log, client: client, usersSvc: usersSvc, appsSvc: appsSvc, rolesSvc: rolesSvc}
+}
+
+func (s *Service) GetUserAccess(ctx context.Context, userID string) (*models.UserAccess, error) {
+	logger.FromContext(ctx, s.log).Infow("Building user access view", "userId", userID)
+
+	access := &models.UserAccess{}
+	err := parallel(ctx,
+		func(ctx context.Context) (err error) {
+			access.User, err = s.usersSvc.GetUser(ctx, userID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			access.Groups, err = s.usersSvc.GetUserGroups(ctx, userID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			access.Apps, err = s.appsSvc.GetUserApps(ctx, userID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			access.AdminRoles, err = s.rolesSvc.GetUserRoles(ctx, userID)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			access.Factors, err = s.GetFactors(ctx, userID)
+			return err
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User access view built",
+		"userId", userID,
+		"groups", len(access.Groups),
+		"apps", len(access.Apps),
+		"adminRoles", len(access.AdminRoles),
+		"factors", len(access.Factors.Factors),
+	)
+	return access, nil
+}
+
+// CompareUsers diffs the group memberships and app assignments of two users,
+// for granting one the same access as the other.
+func (s *Service) CompareUsers(ctx context.Context, userID, otherUserID string) (*models.UserAccessComparison, error) {
+	logger.FromContext(ctx, s.log).Infow("Comparing user access", "userId", userID, "otherUserId", otherUserID)
+
+	var user, other models.UserAccess
+	calls := make([]func(context.Context) error, 0, 6)
+	for _, side := range []struct {
+		id     string
+		access *models.UserAccess
+	}{{userID, &user}, {otherUserID, &other}} {
+		calls = append(calls,
+			func(ctx context.Context) (err error) {
+				side.access.User, err = s.usersSvc.GetUser(ctx, side.id)
+				return err
+			},
+			func(ctx context.Context) (err error) {
+				side.access.Groups, err = s.usersSvc.GetUserGroups(ctx, side.id)
+				return err
+			},
+			func(ctx context.Context) (err error) {
+				side.access.Apps, err = s.appsSvc.GetUserApps(ctx, side.id)
+				return err
+			},
+		)
+	}
+	if err := parallel(ctx, calls...); err != nil {
+		return nil, err
+	}
+
+	comparison := &models.UserAccessComparison{
+		User:      user.User,
+		OtherUser: other.User,
+		Groups:    &models.GroupComparison{},
+		Apps:      &models.AppComparison{},
+	}
+	comparison.Groups.Shared, comparison.Groups.OnlyUser, comparison.Groups.OnlyOtherUser = compare(
+		user.Groups, other.Groups, func(group *models.Group) string { return group.ID },
+	)
+	comparison.Apps.Shared, comparison.Apps.OnlyUser, comparison.Apps.OnlyOtherUser = compare(
+		user.Apps, other.Apps, func(app *models.App) string { return app.ID },
+	)
+
+	logger.FromContext(ctx, s.log).Infow("User access compared",
+		"userId", userID,
+		"otherUserId", otherUserID,
+		"sharedGroups", len(comparison.Groups.Shared),
+		"sharedApps", len(comparison.Apps.Shared),
+	)
+	return comparison, nil
+}
+
+// compare splits two lists into the entries both have and those only one
+// has, matching entries by key. Each keeps the order it had in its list.
+func compare[T any](a, b []T, key func(T) string) (shared, onlyA, onlyB []T) {
+	shared, onlyA, onlyB = []T{}, []T{}, []T{}
+
+	inB := make(map[string]bool, len(b))
+	for _, entry := range b {
+		inB[key(entry)] = true
+	}
+	inA := make(map[string]bool, len(a))
+	for _, entry := range a {
+		inA[key(entry)] = true
+		if inB[key(entry)] {
+			shared = append(shared, entry)
+		} else {
+			onlyA = append(onlyA, entry)
+		}
+	}
+	for _, entry := range b {
+		if !inA[key(entry)] {
+			onlyB = append(onlyB, entry)
+		}
+	}
+	return shared, onlyA, onlyB
+}
+
+// parallel makes calls at once and waits for them all. The first to fail
+// cancels the others, and its error is returned, except that a missing user
+// is reported over anything else, since it makes the other calls fail too.
+func parallel(ctx context.Context, calls ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(calls))
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if errs[i] = call(ctx); errs[i] != nil {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if errors.Is(err, user_service.ErrUserNotFound) {
+			return err
+		}
+	}
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return err
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// GetFactors lists the user's enrolled factors. Okta returns each factor
+// type as its own shape, so only the fields they share are kept.
+func (s *Service) GetFactors(ctx context.Context, userID string) (*models.FactorEnrollment, error) {
+	factors, response, err := s.client.UserFactorAPI.ListFactors(ctx, userID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get user factors from Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		return nil, fmt.Errorf("failed to get user factors from Okta: %w", err)
+	}
+
+	enrollment := &models.FactorEnrollment{Factors: make([]*models.Factor, 0, len(factors))}
+	for i := range factors {
+		data, err := json.Marshal(factors[i].GetActualInstance())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode user factor: %w", err)
+		}
+
+		var factor models.Factor
+		if err := json.Unmarshal(data, &factor); err != nil {
+			return nil, fmt.Errorf("failed to decode user factor: %w", err)
+		}
+		enrollment.Factors = append(enrollment.Factors, &factor)
+	}
+
+	enrollment.Enrolled = slices.ContainsFunc(enrollment.Factors, func(factor *models.Factor) bool {
+		return factor.Status == models.FactorStatusActive
+	})
+	return enrollment, nil
+}
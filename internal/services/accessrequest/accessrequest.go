@@ -0,0 +1,307 @@
+package accessrequest_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrAccessRequestNotFound = errors.New("access request not found")
+	ErrGroupNotProtected     = errors.New("group is not protected by access requests")
+	ErrNotApprover           = errors.New("caller is not an approver for this group")
+	ErrSelfApproval          = errors.New("requesters cannot decide on their own access requests")
+	ErrRequestNotPending     = errors.New("access request is no longer pending")
+	ErrDurationExceeded      = errors.New("requested duration exceeds the group's maximum")
+	ErrDuplicateRequest      = errors.New("a pending access request for this group already exists")
+	ErrApprovalNotRequired   = errors.New("group's join policy does not accept access requests")
+)
+
+type Service struct {
+	log       *zap.SugaredLogger
+	groupsSvc *group_service.Service
+	auditSvc  *audit_service.Service
+
+	mu              sync.RWMutex
+	requests        map[string]*models.AccessRequest
+	protectedGroups map[string]*models.ProtectedGroup
+}
+
+func New(log *zap.SugaredLogger, groupsSvc *group_service.Service, auditSvc *audit_service.Service) *Service {
+	return &Service{
+		log:             log,
+		groupsSvc:       groupsSvc,
+		auditSvc:        auditSvc,
+		requests:        make(map[string]*models.AccessRequest),
+		protectedGroups: make(map[string]*models.ProtectedGroup),
+	}
+}
+
+func (s *Service) ProtectGroup(ctx context.Context, groupID string, req *models.UpdateProtectedGroupRequest) (*models.ProtectedGroup, error) {
+	logger.FromContext(ctx, s.log).Infow("Protecting group with access requests", "groupId", groupID, "approverCount", len(req.ApproverIDs))
+
+	// Make sure the group exists before accepting requests for it.
+	if _, err := s.groupsSvc.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	protected := &models.ProtectedGroup{
+		GroupID:          groupID,
+		ApproverIDs:      slices.Clone(req.ApproverIDs),
+		MaxDurationHours: req.MaxDurationHours,
+	}
+
+	s.mu.Lock()
+	s.protectedGroups[groupID] = protected
+	s.mu.Unlock()
+
+	// Protected groups are joined through approved requests.
+	if err := s.groupsSvc.SetJoinPolicy(ctx, groupID, models.JoinPolicyApproval); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group protected successfully", "groupId", groupID)
+	return protected, nil
+}
+
+func (s *Service) UnprotectGroup(ctx context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.protectedGroups[groupID]; !ok {
+		return ErrGroupNotProtected
+	}
+
+	delete(s.protectedGroups, groupID)
+
+	if s.groupsSvc.JoinPolicy(groupID) == models.JoinPolicyApproval {
+		if err := s.groupsSvc.SetJoinPolicy(ctx, groupID, models.JoinPolicyInviteOnly); err != nil {
+			return err
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group protection removed", "groupId", groupID)
+	return nil
+}
+
+func (s *Service) GetProtectedGroups(ctx context.Context) []*models.ProtectedGroup {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.ProtectedGroup, 0, len(s.protectedGroups))
+	for _, protected := range s.protectedGroups {
+		result = append(result, protected)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].GroupID < result[j].GroupID })
+	return result
+}
+
+func (s *Service) CreateRequest(ctx context.Context, requesterID string, req *models.CreateAccessRequestRequest) (*models.AccessRequest, error) {
+	logger.FromContext(ctx, s.log).Infow("Creating access request", "groupId", req.GroupID, "requesterId", requesterID)
+
+	// A group can be protected while an admin has since opened or hidden it.
+	if s.groupsSvc.JoinPolicy(req.GroupID) != models.JoinPolicyApproval {
+		return nil, ErrApprovalNotRequired
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	protected, ok := s.protectedGroups[req.GroupID]
+	if !ok {
+		return nil, ErrGroupNotProtected
+	}
+
+	if protected.MaxDurationHours > 0 && (req.DurationHours == 0 || req.DurationHours > protected.MaxDurationHours) {
+		return nil, ErrDurationExceeded
+	}
+
+	for _, existing := range s.requests {
+		if existing.GroupID == req.GroupID && existing.RequesterID == requesterID &&
+			existing.Status == models.AccessRequestStatusPending {
+			return nil, ErrDuplicateRequest
+		}
+	}
+
+	request := &models.AccessRequest{
+		ID:            uuid.NewString(),
+		GroupID:       req.GroupID,
+		RequesterID:   requesterID,
+		Justification: req.Justification,
+		DurationHours: req.DurationHours,
+		Status:        models.AccessRequestStatusPending,
+		Created:       time.Now().UTC(),
+	}
+	s.requests[request.ID] = request
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        requesterID,
+		Action:       models.AuditActionAccessRequested,
+		ResourceType: models.ResourceTypeGroup,
+		ResourceID:   req.GroupID,
+		Details: map[string]any{
+			"accessRequestId": request.ID,
+			"justification":   req.Justification,
+			"durationHours":   req.DurationHours,
+		},
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Access request created successfully", "accessRequestId", request.ID, "groupId", req.GroupID)
+	copied := *request
+	return &copied, nil
+}
+
+func (s *Service) GetRequest(ctx context.Context, requestID string) (*models.AccessRequest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	request, ok := s.requests[requestID]
+	if !ok {
+		return nil, ErrAccessRequestNotFound
+	}
+
+	copied := *request
+	return &copied, nil
+}
+
+// GetRequests returns the requests matching the filter, newest first.
+func (s *Service) GetRequests(ctx context.Context, filter *models.AccessRequestFilter) []*models.AccessRequest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.AccessRequest, 0)
+	for _, request := range s.requests {
+		if filter.Status != "" && request.Status != filter.Status {
+			continue
+		}
+		if filter.GroupID != "" && request.GroupID != filter.GroupID {
+			continue
+		}
+		if filter.RequesterID != "" && request.RequesterID != filter.RequesterID {
+			continue
+		}
+		if filter.ApproverID != "" {
+			protected, ok := s.protectedGroups[request.GroupID]
+			if !ok || !slices.Contains(protected.ApproverIDs, filter.ApproverID) {
+				continue
+			}
+		}
+
+		copied := *request
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result
+}
+
+// Approve grants the requested membership, time-bound when the request
+// carries a duration, and marks the request approved.
+func (s *Service) Approve(ctx context.Context, requestID, approverID string, decision *models.AccessRequestDecision) (*models.AccessRequest, error) {
+	logger.FromContext(ctx, s.log).Infow("Approving access request", "accessRequestId", requestID, "approverId", approverID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := s.decidable(requestID, approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiresAt *time.Time
+	if request.DurationHours > 0 {
+		expiry := time.Now().UTC().Add(time.Duration(request.DurationHours) * time.Hour)
+		expiresAt = &expiry
+	}
+
+	if err := s.groupsSvc.AddUserToGroup(ctx, request.GroupID, request.RequesterID, expiresAt); err != nil {
+		return nil, fmt.Errorf("failed to grant group membership: %w", err)
+	}
+
+	s.decide(ctx, request, models.AccessRequestStatusApproved, approverID, decision.Comment)
+	request.ExpiresAt = expiresAt
+
+	logger.FromContext(ctx, s.log).Infow("Access request approved successfully", "accessRequestId", requestID, "groupId", request.GroupID)
+	copied := *request
+	return &copied, nil
+}
+
+func (s *Service) Deny(ctx context.Context, requestID, approverID string, decision *models.AccessRequestDecision) (*models.AccessRequest, error) {
+	logger.FromContext(ctx, s.log).Infow("Denying access request", "accessRequestId", requestID, "approverId", approverID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	request, err := s.decidable(requestID, approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.decide(ctx, request, models.AccessRequestStatusDenied, approverID, decision.Comment)
+
+	logger.FromContext(ctx, s.log).Infow("Access request denied", "accessRequestId", requestID, "groupId", request.GroupID)
+	copied := *request
+	return &copied, nil
+}
+
+// decidable returns the request if approverID may decide on it. Callers must hold s.mu.
+func (s *Service) decidable(requestID, approverID string) (*models.AccessRequest, error) {
+	request, ok := s.requests[requestID]
+	if !ok {
+		return nil, ErrAccessRequestNotFound
+	}
+
+	if request.Status != models.AccessRequestStatusPending {
+		return nil, ErrRequestNotPending
+	}
+
+	if request.RequesterID == approverID {
+		return nil, ErrSelfApproval
+	}
+
+	protected, ok := s.protectedGroups[request.GroupID]
+	if !ok || !slices.Contains(protected.ApproverIDs, approverID) {
+		return nil, ErrNotApprover
+	}
+
+	return request, nil
+}
+
+// decide records the outcome on the request and in the audit trail. Callers must hold s.mu.
+func (s *Service) decide(ctx context.Context, request *models.AccessRequest, status, approverID, comment string) {
+	now := time.Now().UTC()
+	request.Status = status
+	request.DecidedBy = approverID
+	request.DecidedAt = &now
+	request.DecisionComment = comment
+
+	action := models.AuditActionAccessRequestDenied
+	if status == models.AccessRequestStatusApproved {
+		action = models.AuditActionAccessRequestApproved
+	}
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        approverID,
+		Action:       action,
+		ResourceType: models.ResourceTypeGroup,
+		ResourceID:   request.GroupID,
+		Details: map[string]any{
+			"accessRequestId": request.ID,
+			"requesterId":     request.RequesterID,
+			"comment":         comment,
+		},
+	})
+}
@@ -0,0 +1,397 @@
+package apikey_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const (
+	keysKey = "keys.json"
+	// keyPrefix starts every key so that leaked keys are easy to recognise
+	// in logs and by secret scanners.
+	keyPrefix = "iam_"
+	// lastUsedInterval bounds how often a key's last use is written to the
+	// store, so a busy pipeline does not rewrite it on every request.
+	lastUsedInterval = time.Minute
+)
+
+var (
+	ErrKeyNotFound    = errors.New("API key not found")
+	ErrKeyRevoked     = errors.New("API key has been revoked")
+	ErrKeyExpired     = errors.New("API key has expired")
+	ErrInvalidKey     = errors.New("invalid API key")
+	ErrNameRequired   = errors.New("name is required")
+	ErrNoScopes       = errors.New("at least one scope is required")
+	ErrInvalidScope   = errors.New("scopes must be read or write")
+	ErrExpiryInPast   = errors.New("expiresAt must be in the future")
+	ErrExpiryTooLate  = errors.New("expiresAt exceeds the maximum API key lifetime")
+	ErrUserKeyManager = errors.New("API keys can only be managed with a user's access token")
+)
+
+// storedKey is an API key as it is kept: the hash of its secret and, after a
+// rotation, the hash of the secret it replaced until that stops working.
+type storedKey struct {
+	*models.APIKey
+	Hash            string     `json:"hash"`
+	PreviousHash    string     `json:"previousHash,omitempty"`
+	PreviousExpires *time.Time `json:"previousExpires,omitempty"`
+}
+
+// Service issues, rotates and revokes the API keys machine clients use
+// instead of access tokens, and verifies the keys they present. Users manage
+// the keys they issued. Keys are stored as one object on every change.
+type Service struct {
+	log      *zap.SugaredLogger
+	cfg      *config.APIKeysConfig
+	store    objectstore.Store
+	auditSvc *audit_service.Service
+
+	mu sync.Mutex
+	// keys are keyed by ID and read from the store on first use.
+	keys   map[string]*storedKey
+	loaded bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.APIKeysConfig, store objectstore.Store, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:      log,
+		cfg:      cfg,
+		store:    store,
+		auditSvc: auditSvc,
+		keys:     make(map[string]*storedKey),
+	}
+}
+
+// Keys returns the keys the user issued, revoked ones included, newest first.
+func (s *Service) Keys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	keys := make([]*models.APIKey, 0)
+	for _, key := range s.keys {
+		if key.CreatedBy == userID {
+			keys = append(keys, copyKey(key.APIKey))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Created.After(keys[j].Created) })
+
+	return keys, nil
+}
+
+// Issue creates a key for the user and returns it with its secret.
+func (s *Service) Issue(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.IssuedAPIKey, error) {
+	if userID == "" {
+		return nil, ErrUserKeyManager
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrNameRequired
+	}
+
+	scopes, err := validateScopes(req.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.cfg.DefaultTTL)
+	if req.ExpiresAt != nil {
+		switch {
+		case !req.ExpiresAt.After(now):
+			return nil, ErrExpiryInPast
+		case req.ExpiresAt.After(now.Add(s.cfg.MaxTTL)):
+			return nil, ErrExpiryTooLate
+		}
+		expiresAt = req.ExpiresAt.UTC()
+	}
+
+	id := uuid.NewString()
+	secret, err := newKey(id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &storedKey{
+		APIKey: &models.APIKey{
+			ID:        id,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedBy: userID,
+			Created:   now,
+			ExpiresAt: expiresAt,
+		},
+		Hash: hash(secret),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	s.keys[id] = key
+	if err := s.save(ctx); err != nil {
+		delete(s.keys, id)
+		return nil, err
+	}
+
+	s.record(ctx, userID, models.AuditActionAPIKeyIssued, key.APIKey, map[string]any{
+		"name": name, "scopes": scopes, "expiresAt": expiresAt,
+	})
+	return &models.IssuedAPIKey{APIKey: copyKey(key.APIKey), Key: secret}, nil
+}
+
+// Rotate replaces the secret of one of the user's keys. The old secret keeps
+// working for the configured grace period; the key's scopes and expiry are
+// unchanged.
+func (s *Service) Rotate(ctx context.Context, userID, keyID string) (*models.IssuedAPIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.ownKey(ctx, userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case key.Revoked != nil:
+		return nil, ErrKeyRevoked
+	case !now.Before(key.ExpiresAt):
+		return nil, ErrKeyExpired
+	}
+
+	secret, err := newKey(key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := *key
+	rotated := *key
+	rotated.APIKey = copyKey(key.APIKey)
+	rotated.Rotated = &now
+	rotated.Hash = hash(secret)
+	rotated.PreviousHash, rotated.PreviousExpires = "", nil
+	if s.cfg.RotationGrace > 0 {
+		previousExpires := now.Add(s.cfg.RotationGrace)
+		rotated.PreviousHash, rotated.PreviousExpires = key.Hash, &previousExpires
+	}
+
+	s.keys[key.ID] = &rotated
+	if err := s.save(ctx); err != nil {
+		s.keys[key.ID] = &previous
+		return nil, err
+	}
+
+	s.record(ctx, userID, models.AuditActionAPIKeyRotated, rotated.APIKey, map[string]any{
+		"previousKeyExpires": rotated.PreviousExpires,
+	})
+	return &models.IssuedAPIKey{
+		APIKey: copyKey(rotated.APIKey), Key: secret, PreviousKeyExpires: rotated.PreviousExpires,
+	}, nil
+}
+
+// Revoke stops one of the user's keys, and any secret it was rotated from,
+// from working. Revoked keys stay listed.
+func (s *Service) Revoke(ctx context.Context, userID, keyID string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.ownKey(ctx, userID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.Revoked != nil {
+		return nil, ErrKeyRevoked
+	}
+
+	now := time.Now().UTC()
+	previous := *key
+	revoked := *key
+	revoked.APIKey = copyKey(key.APIKey)
+	revoked.Revoked = &now
+	revoked.RevokedBy = userID
+	revoked.PreviousHash, revoked.PreviousExpires = "", nil
+
+	s.keys[key.ID] = &revoked
+	if err := s.save(ctx); err != nil {
+		s.keys[key.ID] = &previous
+		return nil, err
+	}
+
+	s.record(ctx, userID, models.AuditActionAPIKeyRevoked, revoked.APIKey, nil)
+	return copyKey(revoked.APIKey), nil
+}
+
+// Verify returns the key a client presented, provided it is current and
+// neither revoked nor expired.
+func (s *Service) Verify(ctx context.Context, rawKey string) (*models.APIKey, error) {
+	id, _, found := strings.Cut(strings.TrimPrefix(rawKey, keyPrefix), "_")
+	if !found || !strings.HasPrefix(rawKey, keyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, ErrInvalidKey
+	}
+
+	now := time.Now().UTC()
+	presented := hash(rawKey)
+	current := subtle.ConstantTimeCompare([]byte(presented), []byte(key.Hash)) == 1
+	previous := key.PreviousHash != "" && key.PreviousExpires != nil && now.Before(*key.PreviousExpires) &&
+		subtle.ConstantTimeCompare([]byte(presented), []byte(key.PreviousHash)) == 1
+
+	switch {
+	case !current && !previous:
+		return nil, ErrInvalidKey
+	case key.Revoked != nil:
+		return nil, ErrKeyRevoked
+	case !now.Before(key.ExpiresAt):
+		return nil, ErrKeyExpired
+	}
+
+	if key.LastUsed == nil || now.Sub(*key.LastUsed) >= lastUsedInterval {
+		key.LastUsed = &now
+		if err := s.save(ctx); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to record API key use", "keyId", key.ID, zap.Error(err))
+		}
+	}
+
+	return copyKey(key.APIKey), nil
+}
+
+// ownKey returns the user's key with the given ID. Keys of other users are
+// reported as not found. Callers hold mu.
+func (s *Service) ownKey(ctx context.Context, userID, keyID string) (*storedKey, error) {
+	if userID == "" {
+		return nil, ErrUserKeyManager
+	}
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	key, ok := s.keys[keyID]
+	if !ok || key.CreatedBy != userID {
+		return nil, ErrKeyNotFound
+	}
+	return key, nil
+}
+
+func (s *Service) record(ctx context.Context, actor, action string, key *models.APIKey, details map[string]any) {
+	logger.FromContext(ctx, s.log).Infow("API key changed", "action", action, "keyId", key.ID, "actor", actor)
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeAPIKey,
+		ResourceID:   key.ID,
+		Details:      details,
+	})
+}
+
+// load reads the keys from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, keysKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read API keys: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.keys); err != nil {
+			return fmt.Errorf("failed to decode API keys: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the keys. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.keys)
+	if err != nil {
+		return fmt.Errorf("failed to encode API keys: %w", err)
+	}
+	if err := s.store.Put(ctx, keysKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store API keys: %w", err)
+	}
+	return nil
+}
+
+func validateScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrNoScopes
+	}
+
+	valid := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope != models.APIKeyScopeRead && scope != models.APIKeyScopeWrite {
+			return nil, ErrInvalidScope
+		}
+		if !slices.Contains(valid, scope) {
+			valid = append(valid, scope)
+		}
+	}
+	return valid, nil
+}
+
+// newKey returns a key for the given ID: the prefix, the ID and 32 random
+// bytes. The ID lets a key be found without comparing it to every hash.
+func newKey(id string) (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return keyPrefix + id + "_" + hex.EncodeToString(secret), nil
+}
+
+func hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func copyKey(key *models.APIKey) *models.APIKey {
+	copied := *key
+	copied.Scopes = slices.Clone(key.Scopes)
+	return &copied
+}
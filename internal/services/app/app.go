@@ -0,0 +1,323 @@
+package app_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+var (
+	ErrAppNotFound   = errors.New("app not found")
+	ErrGroupNotFound = errors.New("group not found")
+)
+
+type Service struct {
+	client *okta.APIClient
+	log    *zap.SugaredLogger
+}
+
+func New(log *zap.SugaredLogger, client *okta.APIClient) *Service {
+	return &Service{log: log, client: client}
+}
+
+func (s *Service) GetApp(ctx context.Context, appID string) (*models.App, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting app from Okta", "appId", appID)
+
+	app, response, err := s.client.ApplicationAPI.GetApplication(ctx, appID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get app from Okta", zap.Error(err), "appId", appID, "statusCode", okta_client.StatusCode(response))
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrAppNotFound
+		}
+		return nil, fmt.Errorf("failed to get app from Okta: %w", err)
+	}
+
+	return models.ConvertOktaAppToModel(app), nil
+}
+
+// GetUserApps lists the apps assigned to the user, directly or through a group.
+func (s *Service) GetUserApps(ctx context.Context, userID string) ([]*models.App, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting user apps from Okta", "userId", userID)
+
+	apps, err := s.listApps(ctx, fmt.Sprintf("user.id eq %q", userID))
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get user apps from Okta", zap.Error(err), "userId", userID)
+		return nil, fmt.Errorf("failed to get user apps from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User apps retrieved successfully from Okta", "userId", userID, "count", len(apps))
+	return apps, nil
+}
+
+// GetGroupApps lists the apps the group is assigned to.
+func (s *Service) GetGroupApps(ctx context.Context, groupID string) ([]*models.App, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting group apps from Okta", "groupId", groupID)
+
+	apps, err := s.listApps(ctx, fmt.Sprintf("group.id eq %q", groupID))
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get group apps from Okta", zap.Error(err), "groupId", groupID)
+		return nil, fmt.Errorf("failed to get group apps from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group apps retrieved successfully from Okta", "groupId", groupID, "count", len(apps))
+	return apps, nil
+}
+
+// GetGroupAppAssignments lists the apps the group is assigned to, ordered by
+// label, each with the priority and app profile the assignment gives the
+// group's members.
+func (s *Service) GetGroupAppAssignments(ctx context.Context, groupID string) ([]*models.GroupApp, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting group app assignments from Okta", "groupId", groupID)
+
+	// Okta answers the app filter of a group that does not exist with no
+	// apps, so the group is looked up first.
+	if _, response, err := s.client.GroupAPI.GetGroup(ctx, groupID).Execute(); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get group from Okta", zap.Error(err), "groupId", groupID, "statusCode", okta_client.StatusCode(response))
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrGroupNotFound
+		}
+		return nil, fmt.Errorf("failed to get group from Okta: %w", err)
+	}
+
+	apps, err := s.GetGroupApps(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.GroupApp, 0, len(apps))
+	for _, app := range apps {
+		assignment, response, err := s.client.ApplicationGroupsAPI.GetApplicationGroupAssignment(ctx, app.ID, groupID).Execute()
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			// Unassigned since the apps were listed.
+			continue
+		}
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to get app group assignment from Okta", zap.Error(err), "appId", app.ID, "groupId", groupID)
+			return nil, fmt.Errorf("failed to get assignment of group %s to app %s from Okta: %w", groupID, app.ID, err)
+		}
+
+		result = append(result, &models.GroupApp{
+			App: app,
+			Assignment: models.GroupAppAssignment{
+				Priority:    assignment.Priority,
+				Profile:     assignment.Profile,
+				LastUpdated: assignment.LastUpdated,
+			},
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return strings.ToLower(result[i].App.Label) < strings.ToLower(result[j].App.Label)
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Group app assignments retrieved successfully from Okta", "groupId", groupID, "count", len(result))
+	return result, nil
+}
+
+// AssignGroup assigns the app to the group, with the priority and app profile
+// the assignment gives the group's members.
+func (s *Service) AssignGroup(ctx context.Context, appID, groupID string, assignment *models.GroupAppAssignment) error {
+	logger.FromContext(ctx, s.log).Infow("Assigning app to group in Okta", "appId", appID, "groupId", groupID)
+
+	oktaAssignment := okta.ApplicationGroupAssignment{Priority: assignment.Priority, Profile: assignment.Profile}
+	_, response, err := s.client.ApplicationGroupsAPI.AssignGroupToApplication(ctx, appID, groupID).
+		ApplicationGroupAssignment(oktaAssignment).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to assign app to group in Okta", zap.Error(err),
+			"appId", appID, "groupId", groupID, "statusCode", okta_client.StatusCode(response),
+		)
+		return fmt.Errorf("failed to assign app %s to group %s in Okta: %w", appID, groupID, err)
+	}
+	return nil
+}
+
+func (s *Service) listApps(ctx context.Context, filter string) ([]*models.App, error) {
+	oktaApps, response, err := s.client.ApplicationAPI.ListApplications(ctx).Filter(filter).Execute()
+	if err == nil {
+		oktaApps, err = pagination.All(oktaApps, response)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.App, len(oktaApps))
+	for i := range oktaApps {
+		result[i] = models.ConvertOktaAppToModel(&oktaApps[i])
+	}
+	return result, nil
+}
+
+// GetAppAccess answers who has access to the app and why: every user with a
+// direct assignment or membership of an assigned group, ordered by login.
+// The full set is built on every call so the counts are exact; only the
+// requested page of users is returned.
+func (s *Service) GetAppAccess(ctx context.Context, appID string, page *models.AppAccessPage) (*models.AppAccess, error) {
+	app, err := s.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Getting app access from Okta", "appId", appID)
+
+	access := make(map[string]*models.AppAccessUser)
+	userAccess := func(userID, login string) *models.AppAccessUser {
+		user, ok := access[userID]
+		if !ok {
+			user = &models.AppAccessUser{UserID: userID, Login: login, Paths: make([]models.AccessPath, 0, 1)}
+			access[userID] = user
+		}
+		return user
+	}
+
+	// Okta reports a user assigned both directly and through a group with
+	// the USER scope, so direct assignments are read from the app users and
+	// group paths from the members of the assigned groups.
+	appUsers, response, err := s.client.ApplicationUsersAPI.ListApplicationUsers(ctx, appID).Expand("user").Execute()
+	if err == nil {
+		appUsers, err = pagination.All(appUsers, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get app users from Okta", zap.Error(err), "appId", appID)
+		return nil, fmt.Errorf("failed to get users of app %s from Okta: %w", appID, err)
+	}
+
+	direct := 0
+	for i := range appUsers {
+		if appUsers[i].GetScope() != models.AppAssignmentScopeUser {
+			continue
+		}
+		user := userAccess(appUsers[i].GetId(), embeddedString(appUsers[i].GetEmbedded(), "user", "login"))
+		user.Paths = append(user.Paths, models.AccessPath{Type: models.AccessPathDirect})
+		direct++
+	}
+
+	assignments, response, err := s.client.ApplicationGroupsAPI.ListApplicationGroupAssignments(ctx, appID).
+		Expand("group").Execute()
+	if err == nil {
+		assignments, err = pagination.All(assignments, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get app group assignments from Okta", zap.Error(err), "appId", appID)
+		return nil, fmt.Errorf("failed to get group assignments of app %s from Okta: %w", appID, err)
+	}
+
+	viaGroups := make(map[string]bool)
+	for _, assignment := range assignments {
+		path := models.AccessPath{
+			Type:      models.AccessPathGroup,
+			GroupID:   assignment.GetId(),
+			GroupName: embeddedString(assignment.GetEmbedded(), "group", "name"),
+		}
+
+		members, response, err := s.client.GroupAPI.ListGroupUsers(ctx, path.GroupID).Execute()
+		if err == nil {
+			members, err = pagination.All(members, response)
+		}
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to get group members from Okta", zap.Error(err), "appId", appID, "groupId", path.GroupID)
+			return nil, fmt.Errorf("failed to get members of group %s from Okta: %w", path.GroupID, err)
+		}
+
+		for i := range members {
+			user := userAccess(members[i].GetId(), members[i].Profile.GetLogin())
+			user.Paths = append(user.Paths, path)
+			viaGroups[user.UserID] = true
+		}
+	}
+
+	users := make([]*models.AppAccessUser, 0, len(access))
+	for _, user := range access {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool { return strings.ToLower(users[i].Login) < strings.ToLower(users[j].Login) })
+
+	result := &models.AppAccess{
+		App: app,
+		Counts: models.AppAccessCounts{
+			Users:          len(users),
+			Direct:         direct,
+			ViaGroups:      len(viaGroups),
+			AssignedGroups: len(assignments),
+		},
+	}
+
+	if page.Path != "" {
+		users = slices.DeleteFunc(users, func(user *models.AppAccessUser) bool {
+			return !slices.ContainsFunc(user.Paths, func(path models.AccessPath) bool { return path.Type == page.Path })
+		})
+	}
+
+	start := 0
+	if page.After != "" {
+		after := strings.ToLower(page.After)
+		start = sort.Search(len(users), func(i int) bool { return strings.ToLower(users[i].Login) > after })
+	}
+	end := min(start+page.Limit, len(users))
+	result.Users = users[start:end]
+	if end < len(users) {
+		result.Next = users[end-1].Login
+	}
+
+	logger.FromContext(ctx, s.log).Infow("App access retrieved successfully from Okta",
+		"appId", appID, "userCount", len(users), "returnedCount", len(result.Users),
+	)
+	return result, nil
+}
+
+// GetAppGroups lists the groups assigned to the app, ordered by name, with
+// the priority and app profile each assignment gives the group's members.
+func (s *Service) GetAppGroups(ctx context.Context, appID string) ([]*models.AppGroup, error) {
+	if _, err := s.GetApp(ctx, appID); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Getting app group assignments from Okta", "appId", appID)
+
+	assignments, response, err := s.client.ApplicationGroupsAPI.ListApplicationGroupAssignments(ctx, appID).
+		Expand("group").Execute()
+	if err == nil {
+		assignments, err = pagination.All(assignments, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get app group assignments from Okta", zap.Error(err), "appId", appID)
+		return nil, fmt.Errorf("failed to get group assignments of app %s from Okta: %w", appID, err)
+	}
+
+	groups := make([]*models.AppGroup, len(assignments))
+	for i, assignment := range assignments {
+		groups[i] = &models.AppGroup{
+			GroupID:   assignment.GetId(),
+			GroupName: embeddedString(assignment.GetEmbedded(), "group", "name"),
+			Assignment: models.GroupAppAssignment{
+				Priority:    assignment.Priority,
+				Profile:     assignment.Profile,
+				LastUpdated: assignment.LastUpdated,
+			},
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return strings.ToLower(groups[i].GroupName) < strings.ToLower(groups[j].GroupName)
+	})
+
+	logger.FromContext(ctx, s.log).Infow("App group assignments retrieved successfully from Okta", "appId", appID, "count", len(groups))
+	return groups, nil
+}
+
+// embeddedString reads _embedded.<resource>.profile.<field>, which Okta
+// includes when the list is requested with expand=<resource>.
+func embeddedString(embedded map[string]map[string]any, resource, field string) string {
+	profile, _ := embedded[resource]["profile"].(map[string]any)
+	value, _ := profile[field].(string)
+	return value
+}
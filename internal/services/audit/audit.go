@@ -0,0 +1,72 @@
+package audit_service
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+type Service struct {
+	log *zap.SugaredLogger
+	// store keeps every entry, one object each, so the trail outlives the
+	// process. It is nil when the trail is only kept in memory.
+	store objectstore.Store
+	// redactor masks personal data in the details of every entry before it
+	// is kept.
+	redactor *logger.Redactor
+
+	mu      sync.RWMutex
+	entries []*models.AuditEntry
+}
+
+func New(log *zap.SugaredLogger, store objectstore.Store, redactor *logger.Redactor) *Service {
+	return &Service{log: log, store: store, redactor: redactor}
+}
+
+// Record stamps the entry with an ID and timestamp, masks personal data in
+// its details and appends it to the audit trail. The actor is kept as it is:
+// the trail must say who made each change.
+func (s *Service) Record(ctx context.Context, entry *models.AuditEntry) {
+	entry.ID = uuid.NewString()
+	entry.Timestamp = time.Now().UTC()
+	entry.Details = s.redactor.Map(entry.Details)
+
+	s.mu.Lock()
+	s.entries = append(s.entries, entry)
+	s.mu.Unlock()
+	s.persist(ctx, entry)
+
+	logger.FromContext(ctx, s.log).Infow("Audit entry recorded",
+		"auditId", entry.ID,
+		"actor", entry.Actor,
+		"action", entry.Action,
+		"resourceType", entry.ResourceType,
+		"resourceId", entry.ResourceID,
+	)
+}
+
+// persist writes the entry to the store under its day, in the order entries
+// were recorded. A failure is logged rather than failing the operation
+// audited, which has already happened.
+func (s *Service) persist(ctx context.Context, entry *models.AuditEntry) {
+	if s.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		key := entry.Timestamp.Format("2006/01/02/150405.000000000") + "-" + entry.ID + ".json"
+		err = s.store.Put(context.WithoutCancel(ctx), key, "application/json", data)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to store audit entry", zap.Error(err), "auditId", entry.ID)
+	}
+}
@@ -0,0 +1,150 @@
+package avatar_service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+var (
+	ErrAvatarNotFound     = errors.New("avatar not found")
+	ErrInvalidSignature   = errors.New("invalid or expired avatar signature")
+	ErrUnsupportedContent = errors.New("avatar must be a PNG, JPEG, GIF or WebP image")
+	ErrAvatarTooLarge     = errors.New("avatar exceeds the maximum size")
+)
+
+var allowedContentTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+type Service struct {
+	log      *zap.SugaredLogger
+	cfg      *config.AvatarsConfig
+	store    objectstore.Store
+	usersSvc *user_service.Service
+}
+
+func New(log *zap.SugaredLogger, cfg *config.AvatarsConfig, store objectstore.Store, usersSvc *user_service.Service) *Service {
+	return &Service{log: log, cfg: cfg, store: store, usersSvc: usersSvc}
+}
+
+// MaxSizeBytes is the largest avatar accepted by Upload.
+func (s *Service) MaxSizeBytes() int {
+	return s.cfg.MaxSizeBytes
+}
+
+// Upload stores the image as the user's avatar, replacing any existing one.
+// The content type is sniffed from the data rather than trusted from the client.
+func (s *Service) Upload(ctx context.Context, userID string, data []byte) (*models.AvatarURL, error) {
+	logger.FromContext(ctx, s.log).Infow("Uploading user avatar", "userId", userID, "size", len(data))
+
+	if len(data) > s.cfg.MaxSizeBytes {
+		return nil, ErrAvatarTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	if !slices.Contains(allowedContentTypes, contentType) {
+		return nil, ErrUnsupportedContent
+	}
+
+	// Only keep avatars for users that exist in Okta.
+	if _, err := s.usersSvc.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Put(ctx, objectKey(userID), contentType, data); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to store user avatar", zap.Error(err), "userId", userID)
+		return nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User avatar uploaded successfully", "userId", userID, "contentType", contentType)
+	return s.signedURL(userID), nil
+}
+
+// GetURL returns a signed URL for the user's avatar.
+func (s *Service) GetURL(ctx context.Context, userID string) (*models.AvatarURL, error) {
+	if _, err := s.store.Get(ctx, objectKey(userID)); err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	return s.signedURL(userID), nil
+}
+
+// Open verifies a signed URL's parameters and returns the avatar it points to.
+func (s *Service) Open(ctx context.Context, userID, expires, signature string) (*objectstore.Object, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := s.sign(userID, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidSignature
+	}
+
+	object, err := s.store.Get(ctx, objectKey(userID))
+	if err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+
+	return object, nil
+}
+
+func (s *Service) Delete(ctx context.Context, userID string) error {
+	logger.FromContext(ctx, s.log).Infow("Deleting user avatar", "userId", userID)
+
+	if err := s.store.Delete(ctx, objectKey(userID)); err != nil {
+		if errors.Is(err, objectstore.ErrNotFound) {
+			return ErrAvatarNotFound
+		}
+		return fmt.Errorf("failed to delete avatar: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User avatar deleted successfully", "userId", userID)
+	return nil
+}
+
+func (s *Service) signedURL(userID string) *models.AvatarURL {
+	expiresAt := time.Now().Add(s.cfg.URLTTL).UTC().Truncate(time.Second)
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.sign(userID, expires))
+
+	return &models.AvatarURL{
+		UserID:    userID,
+		URL:       fmt.Sprintf("%s/avatars/%s?%s", s.cfg.BaseURL, url.PathEscape(userID), query.Encode()),
+		ExpiresAt: expiresAt,
+	}
+}
+
+func (s *Service) sign(userID, expires string) string {
+	mac := hmac.New(sha256.New, []byte(s.cfg.URLSecret))
+	mac.Write([]byte(userID + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func objectKey(userID string) string {
+	return "avatars/" + url.PathEscape(userID)
+}
@@ -0,0 +1,871 @@
+package backup_service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/expression"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+const (
+	indexKey         = "index.json"
+	ruleTypeGroup    = "group_rule"
+	ruleStatusActive = "ACTIVE"
+	// expressionTypeOkta is the type of Okta Expression Language
+	// expressions, which restored rules are checked against before being
+	// pushed.
+	expressionTypeOkta = "urn:okta:expression:1.0"
+)
+
+var (
+	ErrBackupNotFound   = errors.New("backup not found")
+	ErrBackupIDRequired = errors.New("backupId is required")
+	ErrNewerFormat      = errors.New("backup was written by a newer format version")
+	ErrInvalidInclude   = errors.New("include must list GROUP, JOIN_POLICY, GROUP_RULE, SOD_POLICY or MEMBERSHIP")
+	errGroupNotRestored = errors.New("the group could not be restored")
+)
+
+// restoreKinds are the kinds of change a restore can be limited to.
+var restoreKinds = []string{
+	models.RestoreKindGroup, models.RestoreKindJoinPolicy, models.RestoreKindGroupRule,
+	models.RestoreKindSoDPolicy, models.RestoreKindMembership,
+}
+
+// Service takes backups of the IAM configuration — Okta-mastered groups with
+// their join policies and members, group rules and SoD policies — and
+// restores them, for recovering from misconfigurations. Archives and an
+// index of them are kept in the object store, which may be a local
+// directory or an S3 bucket. Users themselves are not backed up: restored
+// memberships refer to users by ID.
+type Service struct {
+	log       *zap.SugaredLogger
+	client    *okta.APIClient
+	store     objectstore.Store
+	groupsSvc *group_service.Service
+	sodSvc    *sod_service.Service
+	auditSvc  *audit_service.Service
+
+	// mu serializes updates of the index.
+	mu sync.Mutex
+}
+
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, store objectstore.Store,
+	groupsSvc *group_service.Service, sodSvc *sod_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:       log,
+		client:    client,
+		store:     store,
+		groupsSvc: groupsSvc,
+		sodSvc:    sodSvc,
+		auditSvc:  auditSvc,
+	}
+}
+
+// Create archives the current configuration and adds it to the index.
+func (s *Service) Create(ctx context.Context, actor string, req *models.CreateBackupRequest) (*models.Backup, error) {
+	logger.FromContext(ctx, s.log).Infow("Creating backup", "label", req.Label)
+
+	archive := &models.BackupArchive{
+		Backup: models.Backup{
+			ID:            uuid.NewString(),
+			FormatVersion: models.BackupFormatVersion,
+			Label:         req.Label,
+			CreatedBy:     actor,
+			Created:       time.Now().UTC(),
+		},
+	}
+
+	if err := s.groupsSvc.StreamGroups(ctx, func(group *models.Group) error {
+		if group.Type != models.GroupTypeOkta {
+			return nil
+		}
+
+		backup := &models.BackupGroup{
+			ID:          group.ID,
+			Name:        group.Name,
+			Description: group.Description,
+			JoinPolicy:  group.JoinPolicy,
+			Profile:     group.Profile,
+			Members:     []models.BackupMember{},
+		}
+		if err := s.groupsSvc.StreamGroupMembers(ctx, group.ID, func(user *models.User) error {
+			backup.Members = append(backup.Members, models.BackupMember{ID: user.ID, Login: user.Login})
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		archive.Groups = append(archive.Groups, backup)
+		archive.Counts.Memberships += len(backup.Members)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	rules, err := s.listRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		archive.GroupRules = append(archive.GroupRules, convertRule(&rules[i]))
+	}
+
+	archive.SoDPolicies = s.sodSvc.GetPolicies(ctx)
+	archive.Counts.Groups = len(archive.Groups)
+	archive.Counts.GroupRules = len(archive.GroupRules)
+	archive.Counts.SoDPolicies = len(archive.SoDPolicies)
+
+	data, err := encodeArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+	archive.Size = len(data)
+
+	if err := s.store.Put(ctx, archiveKey(archive.ID), "application/gzip", data); err != nil {
+		return nil, fmt.Errorf("failed to store backup: %w", err)
+	}
+
+	backup := archive.Backup
+	if err := s.addToIndex(ctx, &backup); err != nil {
+		return nil, err
+	}
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionBackupCreated,
+		ResourceType: models.ResourceTypeBackup,
+		ResourceID:   backup.ID,
+		Details:      map[string]any{"label": backup.Label, "counts": backup.Counts},
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Backup created successfully", "backupId", backup.ID, "counts", backup.Counts)
+	return &backup, nil
+}
+
+// Backups lists every backup, newest first.
+func (s *Service) Backups(ctx context.Context) ([]*models.Backup, error) {
+	backups, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(backups, func(i, j int) bool { return backups[i].Created.After(backups[j].Created) })
+	return backups, nil
+}
+
+func (s *Service) Backup(ctx context.Context, backupID string) (*models.Backup, error) {
+	backups, err := s.index(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backup := range backups {
+		if backup.ID == backupID {
+			return backup, nil
+		}
+	}
+	return nil, ErrBackupNotFound
+}
+
+// Restore compares the backup with the org and, unless req.DryRun, applies
+// the differences: groups are created or updated first, so that join
+// policies, memberships, rules and SoD policies can refer to them. Groups
+// that no longer exist are matched by name before being recreated, and the
+// group IDs of rules and policies are translated to the recreated groups.
+// A failed change does not stop the others.
+func (s *Service) Restore(ctx context.Context, actor string, req *models.RestoreRequest) (*models.Restore, error) {
+	if req.BackupID == "" {
+		return nil, ErrBackupIDRequired
+	}
+	for _, kind := range req.Include {
+		if !slices.Contains(restoreKinds, kind) {
+			return nil, ErrInvalidInclude
+		}
+	}
+
+	archive, err := s.archive(ctx, req.BackupID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Restoring backup", "backupId", req.BackupID, "dryRun", req.DryRun)
+
+	plan, err := s.plan(ctx, archive, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.DryRun {
+		for _, step := range plan.steps {
+			if err := step.apply(ctx); err != nil {
+				step.change.Outcome = models.RestoreOutcomeFailed
+				step.change.Error = err.Error()
+				continue
+			}
+			step.change.Outcome = models.RestoreOutcomeApplied
+		}
+	}
+
+	restore := &models.Restore{BackupID: req.BackupID, DryRun: req.DryRun, Changes: make([]*models.RestoreChange, len(plan.steps))}
+	restore.Summary.Unchanged = plan.unchanged
+	for i, step := range plan.steps {
+		restore.Changes[i] = step.change
+		switch step.change.Action {
+		case models.RestoreActionCreate:
+			restore.Summary.Create++
+		case models.RestoreActionUpdate:
+			restore.Summary.Update++
+		case models.RestoreActionAdd:
+			restore.Summary.Add++
+		case models.RestoreActionRemove:
+			restore.Summary.Remove++
+		}
+		if step.change.Outcome == models.RestoreOutcomeFailed {
+			restore.Summary.Failed++
+		}
+	}
+
+	if !req.DryRun {
+		s.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionBackupRestored,
+			ResourceType: models.ResourceTypeBackup,
+			ResourceID:   req.BackupID,
+			Details:      map[string]any{"include": req.Include, "summary": restore.Summary},
+		})
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Backup restored", "backupId", req.BackupID, "dryRun", req.DryRun,
+		"summary", restore.Summary,
+	)
+	return restore, nil
+}
+
+// step is a change of the restore together with how to apply it.
+type step struct {
+	change *models.RestoreChange
+	apply  func(ctx context.Context) error
+}
+
+type restorePlan struct {
+	steps     []*step
+	unchanged int
+	// groupIDs maps the ID of every backed up group to its ID in the org. It
+	// is filled in for recreated groups as they are created.
+	groupIDs map[string]string
+}
+
+func (p *restorePlan) add(change *models.RestoreChange, apply func(ctx context.Context) error) {
+	p.steps = append(p.steps, &step{change: change, apply: apply})
+}
+
+// mapGroupIDs translates backed up group IDs to IDs in the org, keeping IDs
+// of groups that were not backed up.
+func (p *restorePlan) mapGroupIDs(ids []string) []string {
+	mapped := make([]string, len(ids))
+	for i, id := range ids {
+		mapped[i] = id
+		if current, ok := p.groupIDs[id]; ok {
+			mapped[i] = current
+		}
+	}
+	return mapped
+}
+
+func (s *Service) plan(ctx context.Context, archive *models.BackupArchive, req *models.RestoreRequest) (*restorePlan, error) {
+	included := func(kind string) bool {
+		return len(req.Include) == 0 || slices.Contains(req.Include, kind)
+	}
+
+	byID := make(map[string]*models.Group)
+	byName := make(map[string]*models.Group)
+	if err := s.groupsSvc.StreamGroups(ctx, func(group *models.Group) error {
+		if group.Type == models.GroupTypeOkta {
+			byID[group.ID] = group
+			byName[group.Name] = group
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	plan := &restorePlan{groupIDs: make(map[string]string, len(archive.Groups))}
+	// created holds the groups the restore creates; their memberships are
+	// all added.
+	created := make(map[string]bool)
+
+	for _, group := range archive.Groups {
+		current := byID[group.ID]
+		if current == nil {
+			current = byName[group.Name]
+		}
+
+		if current == nil {
+			if !included(models.RestoreKindGroup) {
+				continue
+			}
+			created[group.ID] = true
+			change := &models.RestoreChange{
+				Kind:     models.RestoreKindGroup,
+				Action:   models.RestoreActionCreate,
+				Key:      group.Name,
+				BackupID: group.ID,
+			}
+			plan.add(change, s.createGroup(plan, change, group, included(models.RestoreKindJoinPolicy)))
+			continue
+		}
+
+		plan.groupIDs[group.ID] = current.ID
+		if included(models.RestoreKindGroup) {
+			if diff := groupDiff(current, group); len(diff) > 0 {
+				plan.add(&models.RestoreChange{
+					Kind:      models.RestoreKindGroup,
+					Action:    models.RestoreActionUpdate,
+					Key:       group.Name,
+					BackupID:  group.ID,
+					CurrentID: current.ID,
+					Diff:      diff,
+				}, func(ctx context.Context) error {
+					_, err := s.groupsSvc.UpdateGroup(ctx, current.ID, &models.UpdateGroupRequest{
+						Name:        group.Name,
+						Description: group.Description,
+						Profile:     group.Profile,
+					})
+					return err
+				})
+			} else {
+				plan.unchanged++
+			}
+		}
+
+		if included(models.RestoreKindJoinPolicy) {
+			if current.JoinPolicy != group.JoinPolicy {
+				plan.add(&models.RestoreChange{
+					Kind:      models.RestoreKindJoinPolicy,
+					Action:    models.RestoreActionUpdate,
+					Key:       group.Name,
+					BackupID:  group.ID,
+					CurrentID: current.ID,
+					Diff:      []*models.RestoreDiff{{Field: "joinPolicy", Current: current.JoinPolicy, Backup: group.JoinPolicy}},
+				}, func(ctx context.Context) error {
+					return s.groupsSvc.SetJoinPolicy(ctx, current.ID, group.JoinPolicy)
+				})
+			} else {
+				plan.unchanged++
+			}
+		}
+	}
+
+	if included(models.RestoreKindMembership) {
+		for _, group := range archive.Groups {
+			if err := s.planMemberships(ctx, plan, group, created[group.ID], req.RemoveExtraMembers); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if included(models.RestoreKindGroupRule) {
+		if err := s.planRules(ctx, plan, archive.GroupRules); err != nil {
+			return nil, err
+		}
+	}
+
+	if included(models.RestoreKindSoDPolicy) {
+		s.planPolicies(ctx, plan, archive.SoDPolicies)
+	}
+
+	return plan, nil
+}
+
+// createGroup recreates the group, recording its new ID in the plan for the
+// changes that refer to it.
+func (s *Service) createGroup(
+	plan *restorePlan, change *models.RestoreChange, group *models.BackupGroup, withJoinPolicy bool,
+) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		req := &models.CreateGroupRequest{Name: group.Name, Description: group.Description, Profile: group.Profile}
+		if withJoinPolicy {
+			req.JoinPolicy = group.JoinPolicy
+		}
+
+		restored, err := s.groupsSvc.CreateGroup(ctx, req)
+		if err != nil {
+			return err
+		}
+		plan.groupIDs[group.ID] = restored.ID
+		change.CurrentID = restored.ID
+		return nil
+	}
+}
+
+// planMemberships adds the backed up members missing from the group and,
+// with removeExtra, removes those who joined since. Every member of a group
+// the restore creates is added.
+func (s *Service) planMemberships(
+	ctx context.Context, plan *restorePlan, group *models.BackupGroup, created, removeExtra bool,
+) error {
+	currentID, matched := plan.groupIDs[group.ID]
+	if !matched && !created {
+		return nil
+	}
+
+	current := make(map[string]*models.User)
+	if matched {
+		if err := s.groupsSvc.StreamGroupMembers(ctx, currentID, func(user *models.User) error {
+			current[user.ID] = user
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	backedUp := make(map[string]bool, len(group.Members))
+	for _, member := range group.Members {
+		backedUp[member.ID] = true
+		if current[member.ID] != nil {
+			plan.unchanged++
+			continue
+		}
+
+		userID := member.ID
+		change := &models.RestoreChange{
+			Kind:      models.RestoreKindMembership,
+			Action:    models.RestoreActionAdd,
+			Key:       membershipKey(group.Name, member.Login, member.ID),
+			BackupID:  group.ID,
+			CurrentID: currentID,
+		}
+		plan.add(change, func(ctx context.Context) error {
+			groupID, ok := plan.groupIDs[group.ID]
+			if !ok {
+				return errGroupNotRestored
+			}
+			change.CurrentID = groupID
+			return s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil)
+		})
+	}
+
+	if !removeExtra {
+		return nil
+	}
+
+	extra := make([]*models.User, 0)
+	for id, user := range current {
+		if !backedUp[id] {
+			extra = append(extra, user)
+		}
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i].Login < extra[j].Login })
+
+	for _, user := range extra {
+		userID := user.ID
+		plan.add(&models.RestoreChange{
+			Kind:      models.RestoreKindMembership,
+			Action:    models.RestoreActionRemove,
+			Key:       membershipKey(group.Name, user.Login, user.ID),
+			BackupID:  group.ID,
+			CurrentID: currentID,
+		}, func(ctx context.Context) error {
+			return s.groupsSvc.RemoveUserFromGroup(ctx, currentID, userID)
+		})
+	}
+	return nil
+}
+
+// planRules recreates missing rules and puts changed ones back. Okta only
+// replaces inactive rules, so an active rule is deactivated first; a rule
+// ends up active when it was active in the backup. A rule whose expression
+// does not parse is not pushed: its change fails, and a dry run shows why.
+func (s *Service) planRules(ctx context.Context, plan *restorePlan, backups []*models.BackupGroupRule) error {
+	rules, err := s.listRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]*models.BackupGroupRule, len(rules))
+	byName := make(map[string]*models.BackupGroupRule, len(rules))
+	for i := range rules {
+		rule := convertRule(&rules[i])
+		byID[rule.ID] = rule
+		byName[rule.Name] = rule
+	}
+
+	for _, rule := range backups {
+		current := byID[rule.ID]
+		if current == nil {
+			current = byName[rule.Name]
+		}
+
+		invalid := ruleExpressionError(rule)
+
+		if current == nil {
+			change := &models.RestoreChange{
+				Kind:     models.RestoreKindGroupRule,
+				Action:   models.RestoreActionCreate,
+				Key:      rule.Name,
+				BackupID: rule.ID,
+			}
+			if invalid != nil {
+				change.Error = invalid.Error()
+			}
+			plan.add(change, func(ctx context.Context) error {
+				if invalid != nil {
+					return invalid
+				}
+				created, _, err := s.client.GroupAPI.CreateGroupRule(ctx).
+					GroupRule(oktaRule(rule, plan.mapGroupIDs(rule.GroupIDs))).Execute()
+				if err != nil {
+					return fmt.Errorf("failed to create group rule in Okta: %w", err)
+				}
+				return s.setRuleStatus(ctx, created.GetId(), rule.Status)
+			})
+			continue
+		}
+
+		diff := ruleDiff(current, rule, plan.mapGroupIDs(rule.GroupIDs))
+		if len(diff) == 0 {
+			plan.unchanged++
+			continue
+		}
+
+		replace := slices.ContainsFunc(diff, func(d *models.RestoreDiff) bool { return d.Field != "status" })
+		change := &models.RestoreChange{
+			Kind:      models.RestoreKindGroupRule,
+			Action:    models.RestoreActionUpdate,
+			Key:       rule.Name,
+			BackupID:  rule.ID,
+			CurrentID: current.ID,
+			Diff:      diff,
+		}
+		if !replace {
+			invalid = nil
+		} else if invalid != nil {
+			change.Error = invalid.Error()
+		}
+		plan.add(change, func(ctx context.Context) error {
+			if invalid != nil {
+				return invalid
+			}
+			if replace {
+				if current.Status == ruleStatusActive {
+					if _, err := s.client.GroupAPI.DeactivateGroupRule(ctx, current.ID).Execute(); err != nil {
+						return fmt.Errorf("failed to deactivate group rule in Okta: %w", err)
+					}
+				}
+				if _, _, err := s.client.GroupAPI.ReplaceGroupRule(ctx, current.ID).
+					GroupRule(oktaRule(rule, plan.mapGroupIDs(rule.GroupIDs))).Execute(); err != nil {
+					return fmt.Errorf("failed to replace group rule in Okta: %w", err)
+				}
+			}
+			return s.setRuleStatus(ctx, current.ID, rule.Status)
+		})
+	}
+	return nil
+}
+
+// setRuleStatus activates or deactivates the rule to match status.
+func (s *Service) setRuleStatus(ctx context.Context, ruleID, status string) error {
+	if status == ruleStatusActive {
+		if _, err := s.client.GroupAPI.ActivateGroupRule(ctx, ruleID).Execute(); err != nil {
+			return fmt.Errorf("failed to activate group rule in Okta: %w", err)
+		}
+		return nil
+	}
+	if _, err := s.client.GroupAPI.DeactivateGroupRule(ctx, ruleID).Execute(); err != nil {
+		return fmt.Errorf("failed to deactivate group rule in Okta: %w", err)
+	}
+	return nil
+}
+
+// planPolicies recreates missing SoD policies. Policies cannot be edited, so
+// a changed policy is replaced, which gives it a new ID.
+func (s *Service) planPolicies(ctx context.Context, plan *restorePlan, backups []*models.SoDPolicy) {
+	byID := make(map[string]*models.SoDPolicy)
+	byName := make(map[string]*models.SoDPolicy)
+	for _, policy := range s.sodSvc.GetPolicies(ctx) {
+		byID[policy.ID] = policy
+		byName[policy.Name] = policy
+	}
+
+	for _, policy := range backups {
+		current := byID[policy.ID]
+		if current == nil {
+			current = byName[policy.Name]
+		}
+
+		create := func(ctx context.Context) error {
+			groups := plan.mapGroupIDs([]string{policy.GroupA, policy.GroupB})
+			_, err := s.sodSvc.CreatePolicy(ctx, &models.CreateSoDPolicyRequest{
+				Name:        policy.Name,
+				Description: policy.Description,
+				GroupA:      groups[0],
+				GroupB:      groups[1],
+				Mode:        policy.Mode,
+			})
+			return err
+		}
+
+		if current == nil {
+			plan.add(&models.RestoreChange{
+				Kind:     models.RestoreKindSoDPolicy,
+				Action:   models.RestoreActionCreate,
+				Key:      policy.Name,
+				BackupID: policy.ID,
+			}, create)
+			continue
+		}
+
+		groups := plan.mapGroupIDs([]string{policy.GroupA, policy.GroupB})
+		var diff []*models.RestoreDiff
+		diff = appendDiff(diff, "name", current.Name, policy.Name)
+		diff = appendDiff(diff, "description", current.Description, policy.Description)
+		diff = appendDiff(diff, "groupA", current.GroupA, groups[0])
+		diff = appendDiff(diff, "groupB", current.GroupB, groups[1])
+		diff = appendDiff(diff, "mode", current.Mode, policy.Mode)
+		if len(diff) == 0 {
+			plan.unchanged++
+			continue
+		}
+
+		plan.add(&models.RestoreChange{
+			Kind:      models.RestoreKindSoDPolicy,
+			Action:    models.RestoreActionUpdate,
+			Key:       policy.Name,
+			BackupID:  policy.ID,
+			CurrentID: current.ID,
+			Diff:      diff,
+		}, func(ctx context.Context) error {
+			if err := s.sodSvc.DeletePolicy(ctx, current.ID); err != nil {
+				return err
+			}
+			return create(ctx)
+		})
+	}
+}
+
+func (s *Service) listRules(ctx context.Context) ([]okta.GroupRule, error) {
+	rules, response, err := s.client.GroupAPI.ListGroupRules(ctx).Execute()
+	if err == nil {
+		rules, err = pagination.All(rules, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list group rules from Okta", zap.Error(err))
+		return nil, fmt.Errorf("failed to list group rules from Okta: %w", err)
+	}
+	return rules, nil
+}
+
+// archive reads and decodes the backup's archive.
+func (s *Service) archive(ctx context.Context, backupID string) (*models.BackupArchive, error) {
+	object, err := s.store.Get(ctx, archiveKey(backupID))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(object.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress backup: %w", err)
+	}
+
+	var archive models.BackupArchive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return nil, fmt.Errorf("failed to decode backup: %w", err)
+	}
+	if archive.FormatVersion > models.BackupFormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrNewerFormat, archive.FormatVersion)
+	}
+	return &archive, nil
+}
+
+func (s *Service) index(ctx context.Context) ([]*models.Backup, error) {
+	object, err := s.store.Get(ctx, indexKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return []*models.Backup{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup index: %w", err)
+	}
+
+	var backups []*models.Backup
+	if err := json.Unmarshal(object.Data, &backups); err != nil {
+		return nil, fmt.Errorf("failed to decode backup index: %w", err)
+	}
+	return backups, nil
+}
+
+func (s *Service) addToIndex(ctx context.Context, backup *models.Backup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups, err := s.index(ctx)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(append(backups, backup))
+	if err != nil {
+		return fmt.Errorf("failed to encode backup index: %w", err)
+	}
+	if err := s.store.Put(ctx, indexKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store backup index: %w", err)
+	}
+	return nil
+}
+
+func encodeArchive(archive *models.BackupArchive) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(writer).Encode(archive); err != nil {
+		return nil, fmt.Errorf("failed to encode backup: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress backup: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func archiveKey(backupID string) string {
+	return backupID + ".json.gz"
+}
+
+func membershipKey(group, login, userID string) string {
+	if login == "" {
+		login = userID
+	}
+	return group + "/" + login
+}
+
+func groupDiff(current *models.Group, backup *models.BackupGroup) []*models.RestoreDiff {
+	var diff []*models.RestoreDiff
+	diff = appendDiff(diff, "name", current.Name, backup.Name)
+	diff = appendDiff(diff, "description", current.Description, backup.Description)
+	if (len(current.Profile) > 0 || len(backup.Profile) > 0) && !reflect.DeepEqual(current.Profile, backup.Profile) {
+		diff = append(diff, &models.RestoreDiff{Field: "profile", Current: current.Profile, Backup: backup.Profile})
+	}
+	return diff
+}
+
+// ruleDiff compares the rule with its backup, whose group IDs have been
+// translated to groupIDs.
+func ruleDiff(current, backup *models.BackupGroupRule, groupIDs []string) []*models.RestoreDiff {
+	var diff []*models.RestoreDiff
+	diff = appendDiff(diff, "name", current.Name, backup.Name)
+	diff = appendDiff(diff, "expression", current.Expression, backup.Expression)
+	if !sameSet(current.GroupIDs, groupIDs) {
+		diff = append(diff, &models.RestoreDiff{Field: "groupIds", Current: current.GroupIDs, Backup: groupIDs})
+	}
+	if !sameSet(current.ExcludedUsers, backup.ExcludedUsers) {
+		diff = append(diff, &models.RestoreDiff{Field: "excludedUsers", Current: current.ExcludedUsers, Backup: backup.ExcludedUsers})
+	}
+	if (current.Status == ruleStatusActive) != (backup.Status == ruleStatusActive) {
+		diff = append(diff, &models.RestoreDiff{Field: "status", Current: current.Status, Backup: backup.Status})
+	}
+	return diff
+}
+
+func appendDiff(diff []*models.RestoreDiff, field, current, backup string) []*models.RestoreDiff {
+	if current == backup {
+		return diff
+	}
+	return append(diff, &models.RestoreDiff{Field: field, Current: current, Backup: backup})
+}
+
+func sameSet(a, b []string) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
+
+func convertRule(rule *okta.GroupRule) *models.BackupGroupRule {
+	converted := &models.BackupGroupRule{
+		ID:       rule.GetId(),
+		Name:     rule.GetName(),
+		Status:   rule.GetStatus(),
+		GroupIDs: []string{},
+	}
+
+	if conditions := rule.Conditions; conditions != nil {
+		if expression := conditions.Expression; expression != nil {
+			converted.ExpressionType = expression.GetType()
+			converted.Expression = expression.GetValue()
+		}
+		if people := conditions.People; people != nil && people.Users != nil {
+			converted.ExcludedUsers = people.Users.Exclude
+		}
+	}
+
+	if actions := rule.Actions; actions != nil && actions.AssignUserToGroups != nil {
+		converted.GroupIDs = actions.AssignUserToGroups.GroupIds
+	}
+	return converted
+}
+
+// ruleExpressionError is why Okta would reject the rule's expression, or nil
+// when it parses or is not an Okta Expression Language expression.
+func ruleExpressionError(rule *models.BackupGroupRule) error {
+	if rule.ExpressionType != "" && rule.ExpressionType != expressionTypeOkta {
+		return nil
+	}
+	if _, err := expression.Parse(rule.Expression); err != nil {
+		return fmt.Errorf("invalid group rule expression: %w", err)
+	}
+	return nil
+}
+
+// oktaRule builds the rule to create or replace from its backup, assigning
+// users to groupIDs.
+func oktaRule(rule *models.BackupGroupRule, groupIDs []string) okta.GroupRule {
+	conditions := &okta.GroupRuleConditions{
+		Expression: &okta.GroupRuleExpression{Value: okta.PtrString(rule.Expression)},
+	}
+	if rule.ExpressionType != "" {
+		conditions.Expression.Type = okta.PtrString(rule.ExpressionType)
+	}
+	if len(rule.ExcludedUsers) > 0 {
+		conditions.People = &okta.GroupRulePeopleCondition{
+			Users: &okta.GroupRuleUserCondition{Exclude: rule.ExcludedUsers},
+		}
+	}
+
+	return okta.GroupRule{
+		Type:       okta.PtrString(ruleTypeGroup),
+		Name:       okta.PtrString(rule.Name),
+		Conditions: conditions,
+		Actions: &okta.GroupRuleAction{
+			AssignUserToGroups: &okta.GroupRuleGroupAssignment{GroupIds: groupIDs},
+		},
+	}
+}
@@ -0,0 +1,136 @@
+package batch_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// maxConcurrentFetches bounds the number of in-flight Okta calls per batch.
+const maxConcurrentFetches = 10
+
+type Service struct {
+	log       *zap.SugaredLogger
+	usersSvc  *user_service.Service
+	groupsSvc *group_service.Service
+}
+
+func New(log *zap.SugaredLogger, usersSvc *user_service.Service, groupsSvc *group_service.Service) *Service {
+	return &Service{log: log, usersSvc: usersSvc, groupsSvc: groupsSvc}
+}
+
+// BatchGet fetches every referenced resource concurrently. Failures are
+// reported per item so that one missing resource does not fail the batch.
+// Results are returned in the same order as the references.
+func (s *Service) BatchGet(ctx context.Context, refs []models.ResourceRef) []*models.BatchGetResult {
+	logger.FromContext(ctx, s.log).Infow("Fetching batch of resources", "count", len(refs))
+
+	var wg sync.WaitGroup
+	results := make([]*models.BatchGetResult, len(refs))
+	semaphore := make(chan struct{}, maxConcurrentFetches)
+
+	for i, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result := &models.BatchGetResult{Type: ref.Type, ID: ref.ID}
+
+			data, err := s.fetch(ctx, ref)
+			if err != nil {
+				logger.FromContext(ctx, s.log).Infow("Failed to fetch batch resource", zap.Error(err), "type", ref.Type, "id", ref.ID)
+				result.Error = err.Error()
+			} else {
+				result.Data = data
+			}
+
+			results[i] = result
+		}()
+	}
+
+	wg.Wait()
+
+	logger.FromContext(ctx, s.log).Infow("Batch of resources fetched", "count", len(results))
+	return results
+}
+
+// LookupUsers resolves each identifier to a user concurrently, first as a
+// login and then, for identifiers that look like one, as a primary email.
+// As with BatchGet, failures such as unknown or ambiguous identifiers are
+// reported per item, and results keep the order of the identifiers.
+func (s *Service) LookupUsers(ctx context.Context, identifiers []string) []*models.UserLookupResult {
+	logger.FromContext(ctx, s.log).Infow("Looking up batch of users", "count", len(identifiers))
+
+	var wg sync.WaitGroup
+	results := make([]*models.UserLookupResult, len(identifiers))
+	semaphore := make(chan struct{}, maxConcurrentFetches)
+
+	for i, identifier := range identifiers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result := &models.UserLookupResult{Identifier: identifier}
+
+			user, err := s.lookupUser(ctx, identifier)
+			if err != nil {
+				logger.FromContext(ctx, s.log).Infow("Failed to look up user", zap.Error(err), "identifier", identifier)
+				result.Error = err.Error()
+			} else {
+				result.User = user
+			}
+
+			results[i] = result
+		}()
+	}
+
+	wg.Wait()
+
+	logger.FromContext(ctx, s.log).Infow("Batch of users looked up", "count", len(results))
+	return results
+}
+
+func (s *Service) lookupUser(ctx context.Context, identifier string) (*models.User, error) {
+	user, err := s.usersSvc.GetUser(ctx, identifier)
+	if !errors.Is(err, user_service.ErrUserNotFound) || !strings.Contains(identifier, "@") {
+		return user, err
+	}
+
+	// Logins often differ from emails, so fall back to searching by email.
+	users, err := s.usersSvc.FindUsersByEmail(ctx, identifier)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(users) == 0:
+		return nil, user_service.ErrUserNotFound
+	case len(users) > 1:
+		return nil, fmt.Errorf("%d users have email %s", len(users), identifier)
+	}
+	return users[0], nil
+}
+
+func (s *Service) fetch(ctx context.Context, ref models.ResourceRef) (any, error) {
+	switch ref.Type {
+	case models.ResourceTypeUser:
+		return s.usersSvc.GetUser(ctx, ref.ID)
+	case models.ResourceTypeGroup:
+		return s.groupsSvc.GetGroup(ctx, ref.ID)
+	default:
+		return nil, fmt.Errorf("unsupported resource type %q", ref.Type)
+	}
+}
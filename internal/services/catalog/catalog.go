@@ -0,0 +1,167 @@
+package catalog_service
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	accessrequest_service "github.com/iamBelugaa/iam/internal/services/accessrequest"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	consent_service "github.com/iamBelugaa/iam/internal/services/consent"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrJoinNotAllowed = errors.New("group does not allow self-service joins")
+	ErrAlreadyMember  = errors.New("user is already a member of the group")
+)
+
+type Service struct {
+	log               *zap.SugaredLogger
+	usersSvc          *user_service.Service
+	groupsSvc         *group_service.Service
+	accessRequestsSvc *accessrequest_service.Service
+	auditSvc          *audit_service.Service
+	consentSvc        *consent_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger,
+	usersSvc *user_service.Service,
+	groupsSvc *group_service.Service,
+	accessRequestsSvc *accessrequest_service.Service,
+	auditSvc *audit_service.Service,
+	consentSvc *consent_service.Service,
+) *Service {
+	return &Service{
+		log:               log,
+		usersSvc:          usersSvc,
+		groupsSvc:         groupsSvc,
+		accessRequestsSvc: accessRequestsSvc,
+		auditSvc:          auditSvc,
+		consentSvc:        consentSvc,
+	}
+}
+
+// GetGroups lists the groups userID can see in the catalog, with the action
+// available to them. HIDDEN groups are never listed.
+func (s *Service) GetGroups(ctx context.Context, userID string) ([]*models.CatalogGroup, error) {
+	groups, err := s.groupsSvc.GetGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberOf, err := s.memberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := s.accessRequestsSvc.GetRequests(ctx, &models.AccessRequestFilter{
+		RequesterID: userID,
+		Status:      models.AccessRequestStatusPending,
+	})
+
+	result := make([]*models.CatalogGroup, 0, len(groups))
+	for _, group := range groups {
+		if group.JoinPolicy == models.JoinPolicyHidden {
+			continue
+		}
+
+		entry := &models.CatalogGroup{Group: group, IsMember: memberOf[group.ID]}
+		switch {
+		case entry.IsMember:
+			entry.Action = models.CatalogActionNone
+		case group.JoinPolicy == models.JoinPolicyOpen:
+			entry.Action = models.CatalogActionJoin
+		case group.JoinPolicy == models.JoinPolicyApproval:
+			entry.Action = models.CatalogActionRequest
+			if slices.ContainsFunc(pending, func(r *models.AccessRequest) bool { return r.GroupID == group.ID }) {
+				entry.Action = models.CatalogActionPending
+			}
+		default:
+			entry.Action = models.CatalogActionNone
+		}
+
+		result = append(result, entry)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Catalog groups retrieved", "userId", userID, "count", len(result))
+	return result, nil
+}
+
+// JoinGroup adds userID to an OPEN group, or opens an access request for an
+// APPROVAL group. Other groups cannot be joined through the catalog. Either
+// way the user's consent, when required, is recorded first.
+func (s *Service) JoinGroup(
+	ctx context.Context, userID, groupID string, req *models.JoinGroupRequest,
+) (*models.JoinGroupResult, error) {
+	policy := s.groupsSvc.JoinPolicy(groupID)
+	logger.FromContext(ctx, s.log).Infow("Catalog join requested", "userId", userID, "groupId", groupID, "joinPolicy", policy)
+
+	if policy != models.JoinPolicyOpen && policy != models.JoinPolicyApproval {
+		return nil, ErrJoinNotAllowed
+	}
+
+	memberOf, err := s.memberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if memberOf[groupID] {
+		return nil, ErrAlreadyMember
+	}
+
+	consent, err := s.consentSvc.Record(
+		ctx, userID, models.ConsentActionGroupJoin, models.ResourceTypeGroup, groupID, req.Consent,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == models.JoinPolicyApproval {
+		request, err := s.accessRequestsSvc.CreateRequest(ctx, userID, &models.CreateAccessRequestRequest{
+			GroupID:       groupID,
+			Justification: req.Justification,
+			DurationHours: req.DurationHours,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &models.JoinGroupResult{Status: models.JoinStatusRequested, AccessRequest: request}, nil
+	}
+
+	if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+		return nil, err
+	}
+
+	entry := &models.AuditEntry{
+		Actor:        userID,
+		Action:       models.AuditActionGroupSelfJoined,
+		ResourceType: models.ResourceTypeGroup,
+		ResourceID:   groupID,
+	}
+	if consent != nil {
+		entry.Details = map[string]any{"consentId": consent.ID, "consentVersion": consent.Version}
+	}
+	s.auditSvc.Record(ctx, entry)
+
+	logger.FromContext(ctx, s.log).Infow("User joined group from the catalog", "userId", userID, "groupId", groupID)
+	return &models.JoinGroupResult{Status: models.JoinStatusJoined}, nil
+}
+
+func (s *Service) memberships(ctx context.Context, userID string) (map[string]bool, error) {
+	groups, err := s.usersSvc.GetUserGroups(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberOf := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		memberOf[group.ID] = true
+	}
+	return memberOf, nil
+}
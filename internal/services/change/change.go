@@ -0,0 +1,172 @@
+package change_service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrInvalidCursor = errors.New("cursor is not a change feed cursor")
+	ErrCursorExpired = errors.New(
+		"cursor is older than the oldest change kept, or from before a restart; resync and start again without a cursor",
+	)
+)
+
+// Service keeps an ordered feed of the changes this service made or saw.
+// Every change gets the next sequence number, so a consumer that stores the
+// cursor of the last change it handled resumes exactly after it. The feed is
+// kept in memory and holds the most recent changes only; cursors it can no
+// longer resume from are rejected rather than silently skipping changes.
+type Service struct {
+	log        *zap.SugaredLogger
+	maxChanges int
+	// epoch tells cursors of this process apart from those of an earlier
+	// one, whose sequence numbers restarted from one.
+	epoch string
+
+	mu       sync.RWMutex
+	sequence int64
+	changes  []*models.Change
+}
+
+func New(log *zap.SugaredLogger, maxChanges int) *Service {
+	return &Service{
+		log:        log,
+		maxChanges: maxChanges,
+		epoch:      strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// Record appends the changes to the feed in the order given, stamping each
+// with its sequence number, cursor and, when unset, the current time and the
+// request ID from ctx.
+func (s *Service) Record(ctx context.Context, changes ...*models.Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	now := time.Now().UTC()
+	requestID := logger.RequestID(ctx)
+
+	s.mu.Lock()
+	for _, change := range changes {
+		s.sequence++
+		change.Sequence = s.sequence
+		change.Cursor = s.cursor(s.sequence)
+		if change.OccurredAt.IsZero() {
+			change.OccurredAt = now
+		}
+		if change.RequestID == "" {
+			change.RequestID = requestID
+		}
+		s.changes = append(s.changes, change)
+	}
+	// Trimming in batches keeps appends cheap once the feed is full.
+	if len(s.changes) > s.maxChanges+s.maxChanges/10 {
+		s.changes = append([]*models.Change(nil), s.changes[len(s.changes)-s.maxChanges:]...)
+	}
+	last := s.sequence
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Changes recorded", "count", len(changes), "lastSequence", last)
+}
+
+// GetChanges returns up to page.Limit changes after page.Cursor.
+func (s *Service) GetChanges(ctx context.Context, page *models.ChangeFeedPage) (*models.ChangeFeed, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	after := int64(0)
+	if page.Cursor != "" {
+		var err error
+		if after, err = s.parseCursor(page.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// The oldest change kept must directly follow the cursor, or the changes
+	// in between are gone. A consumer starting without a cursor gets the
+	// oldest change kept.
+	first := s.sequence + 1
+	if len(s.changes) > 0 {
+		first = s.changes[0].Sequence
+	}
+	if page.Cursor != "" && after+1 < first {
+		return nil, ErrCursorExpired
+	}
+	if page.Cursor == "" {
+		after = first - 1
+	}
+
+	start := int(after + 1 - first)
+	end := min(start+page.Limit, len(s.changes))
+
+	feed := &models.ChangeFeed{
+		Changes: make([]*models.Change, 0, end-start),
+		Next:    s.cursor(after),
+		HasMore: end < len(s.changes),
+	}
+	for _, change := range s.changes[start:end] {
+		copied := *change
+		feed.Changes = append(feed.Changes, &copied)
+	}
+	if len(feed.Changes) > 0 {
+		feed.Next = feed.Changes[len(feed.Changes)-1].Cursor
+	}
+	return feed, nil
+}
+
+// Between returns the kept changes from source that occurred between from and
+// to, oldest first.
+func (s *Service) Between(source string, from, to time.Time) []*models.Change {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Change, 0)
+	for _, change := range s.changes {
+		if change.Source == source && !change.OccurredAt.Before(from) && !change.OccurredAt.After(to) {
+			copied := *change
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+func (s *Service) cursor(sequence int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s.epoch + ":" + strconv.FormatInt(sequence, 10)))
+}
+
+func (s *Service) parseCursor(cursor string) (int64, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	epoch, number, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+	sequence, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || sequence < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	if epoch != s.epoch {
+		return 0, ErrCursorExpired
+	}
+	if sequence > s.sequence {
+		return 0, fmt.Errorf("%w: it is ahead of the feed", ErrInvalidCursor)
+	}
+	return sequence, nil
+}
@@ -0,0 +1,164 @@
+package consent_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+var (
+	ErrConsentRequired = errors.New("consent is required")
+	ErrConsentOutdated = errors.New("consent was given to an outdated text")
+)
+
+// Service records the consent users give before self-service actions. Each
+// user's records are stored together, so their history is read at once.
+type Service struct {
+	log   *zap.SugaredLogger
+	store objectstore.Store
+	// texts are the current consent texts by action.
+	texts map[string]*models.ConsentText
+
+	mu sync.Mutex
+}
+
+func New(log *zap.SugaredLogger, cfg *config.ConsentConfig, store objectstore.Store) *Service {
+	texts := make(map[string]*models.ConsentText)
+	if cfg.GroupJoinText != "" {
+		texts[models.ConsentActionGroupJoin] = &models.ConsentText{
+			Action:  models.ConsentActionGroupJoin,
+			Version: cfg.GroupJoinVersion,
+			Text:    cfg.GroupJoinText,
+		}
+	}
+	return &Service{log: log, store: store, texts: texts}
+}
+
+// GetTexts lists the current consent texts, by action.
+func (s *Service) GetTexts(ctx context.Context) []*models.ConsentText {
+	result := make([]*models.ConsentText, 0, len(s.texts))
+	for _, text := range s.texts {
+		copied := *text
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Action < result[j].Action })
+	return result
+}
+
+// Record checks that userID acknowledged the current text of action and
+// records it, before the action is taken on the resource. It records
+// nothing, and returns nil, when action has no consent text.
+func (s *Service) Record(
+	ctx context.Context,
+	userID, action, resourceType, resourceID string,
+	acknowledgement *models.ConsentAcknowledgement,
+) (*models.ConsentRecord, error) {
+	text, ok := s.texts[action]
+	if !ok {
+		return nil, nil
+	}
+
+	switch {
+	case acknowledgement == nil || acknowledgement.Version == "":
+		return nil, fmt.Errorf("%w: acknowledge version %s of the %s consent text", ErrConsentRequired, text.Version, action)
+	case acknowledgement.Version != text.Version:
+		return nil, fmt.Errorf("%w: version %s was acknowledged, the current version is %s",
+			ErrConsentOutdated, acknowledgement.Version, text.Version,
+		)
+	}
+
+	record := &models.ConsentRecord{
+		ID:           uuid.NewString(),
+		UserID:       userID,
+		Action:       action,
+		Version:      text.Version,
+		Text:         text.Text,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		RequestID:    logger.RequestID(ctx),
+		Recorded:     time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(ctx, userID, append(records, record)); err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Consent recorded",
+		"consentId", record.ID,
+		"userId", userID,
+		"action", action,
+		"version", record.Version,
+	)
+	copied := *record
+	return &copied, nil
+}
+
+// GetRecords lists the consent userID has given, newest first.
+func (s *Service) GetRecords(ctx context.Context, userID string) ([]*models.ConsentRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, err := s.load(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Recorded.After(records[j].Recorded) })
+	return records, nil
+}
+
+// load reads the records of userID, oldest first. Callers hold mu.
+func (s *Service) load(ctx context.Context, userID string) ([]*models.ConsentRecord, error) {
+	records := make([]*models.ConsentRecord, 0)
+
+	object, err := s.store.Get(ctx, recordsKey(userID))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consent records: %w", err)
+	}
+
+	if err := json.Unmarshal(object.Data, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode consent records: %w", err)
+	}
+	return records, nil
+}
+
+// save stores the records of userID. Callers hold mu.
+func (s *Service) save(ctx context.Context, userID string, records []*models.ConsentRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return fmt.Errorf("failed to encode consent records: %w", err)
+	}
+
+	if err := s.store.Put(ctx, recordsKey(userID), "application/json", data); err != nil {
+		return fmt.Errorf("failed to store consent records: %w", err)
+	}
+	return nil
+}
+
+func recordsKey(userID string) string {
+	return "users/" + url.PathEscape(userID) + ".json"
+}
@@ -0,0 +1,236 @@
+package dashboard_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	scaling_service "github.com/iamBelugaa/iam/internal/services/scaling"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// feedPageSize is how many changes Update reads from the feed at a time.
+const feedPageSize = 500
+
+// Service keeps the figures of the ops dashboard. Rather than scanning the
+// change feed and calling Okta when the dashboard is opened, Update follows
+// the feed from where it last stopped and folds each new change into running
+// counts, and the directory index counts users by status as it is built, so
+// reading the dashboard only copies what is already there.
+type Service struct {
+	log          *zap.SugaredLogger
+	cfg          *config.DashboardConfig
+	changesSvc   *change_service.Service
+	directorySvc *directory_service.Service
+	scalingSvc   *scaling_service.Service
+
+	mu     sync.RWMutex
+	cursor string
+	since  time.Time
+	// groupDays counts group creations and deletions by day, as YYYY-MM-DD.
+	groupDays map[string]*models.DashboardGroupDay
+	// mutations counts the API mutations since counting started; recent
+	// holds the latest of them, oldest first.
+	mutations int
+	recent    []*models.Change
+	okta      *models.DashboardOkta
+}
+
+func New(
+	log *zap.SugaredLogger,
+	cfg *config.DashboardConfig,
+	changesSvc *change_service.Service,
+	directorySvc *directory_service.Service,
+	scalingSvc *scaling_service.Service,
+) *Service {
+	return &Service{
+		log:          log,
+		cfg:          cfg,
+		changesSvc:   changesSvc,
+		directorySvc: directorySvc,
+		scalingSvc:   scalingSvc,
+		since:        time.Now().UTC(),
+		groupDays:    make(map[string]*models.DashboardGroupDay),
+		recent:       make([]*models.Change, 0, cfg.RecentMutations),
+		okta:         &models.DashboardOkta{RateLimits: make([]*models.OktaRateLimit, 0)},
+	}
+}
+
+// Update folds the changes recorded since the last update into the counts
+// and takes a new reading of the Okta rate limits and work queues.
+func (s *Service) Update(ctx context.Context) error {
+	log := logger.FromContext(ctx, s.log)
+
+	read := 0
+	for {
+		feed, err := s.changesSvc.GetChanges(ctx, &models.ChangeFeedPage{Cursor: s.cursor, Limit: feedPageSize})
+		if errors.Is(err, change_service.ErrCursorExpired) {
+			// The changes since the last update were dropped from the feed,
+			// or the feed restarted. Carry on from the oldest change kept;
+			// the counts miss whatever was lost.
+			log.Infow("Dashboard fell behind the change feed; some changes were not counted", "cursor", s.cursor)
+			s.cursor = ""
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the change feed: %w", err)
+		}
+
+		s.apply(feed.Changes)
+		s.cursor = feed.Next
+		read += len(feed.Changes)
+		if !feed.HasMore {
+			break
+		}
+	}
+
+	metrics, err := s.scalingSvc.GetMetrics(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read the workload: %w", err)
+	}
+
+	s.mu.Lock()
+	s.okta = &models.DashboardOkta{
+		UpdatedAt:      time.Now().UTC(),
+		Headroom:       1 - metrics.RateLimitPressure,
+		RateLimits:     metrics.RateLimits,
+		Jobs:           metrics.Jobs,
+		Retries:        metrics.Retries,
+		WebhookBacklog: metrics.WebhookBacklog,
+	}
+	s.mu.Unlock()
+
+	if read > 0 {
+		log.Infow("Dashboard updated", "changes", read)
+	}
+	return nil
+}
+
+func (s *Service) apply(changes []*models.Change) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, change := range changes {
+		switch {
+		case change.Source == models.ChangeSourceAPI && change.Type == models.ChangeTypeAPIRequest:
+			s.mutations++
+			if len(s.recent) == s.cfg.RecentMutations {
+				s.recent = append(s.recent[:0], s.recent[1:]...)
+			}
+			s.recent = append(s.recent, change)
+
+		case change.Source == models.ChangeSourceOkta &&
+			(change.Type == models.ChangeTypeGroupCreated || change.Type == models.ChangeTypeGroupDeleted):
+			date := change.OccurredAt.UTC().Format(time.DateOnly)
+			day, ok := s.groupDays[date]
+			if !ok {
+				day = &models.DashboardGroupDay{Date: date}
+				s.groupDays[date] = day
+			}
+			if change.Type == models.ChangeTypeGroupCreated {
+				day.Created++
+			} else {
+				day.Deleted++
+			}
+		}
+	}
+
+	oldest := s.firstDay().Format(time.DateOnly)
+	for date := range s.groupDays {
+		if date < oldest {
+			delete(s.groupDays, date)
+		}
+	}
+}
+
+// firstDay is the first day whose group changes are kept. Callers hold mu.
+func (s *Service) firstDay() time.Time {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	return today.AddDate(0, 0, 1-s.cfg.Days)
+}
+
+func (s *Service) GetDashboard(ctx context.Context) *models.Dashboard {
+	return &models.Dashboard{
+		Users:     s.GetUsers(ctx),
+		Groups:    s.GetGroups(ctx),
+		Mutations: s.GetMutations(ctx),
+		Okta:      s.GetOkta(ctx),
+	}
+}
+
+// GetUsers counts the users by status. The counts are empty until the
+// directory index has been built.
+func (s *Service) GetUsers(ctx context.Context) *models.DashboardUsers {
+	users := &models.DashboardUsers{ByStatus: make(map[string]int)}
+
+	statuses, index, err := s.directorySvc.UserStatuses()
+	if err != nil {
+		return users
+	}
+
+	users.Index = index
+	users.Total = index.UserCount
+	users.ByStatus = statuses
+	return users
+}
+
+func (s *Service) GetGroups(ctx context.Context) *models.DashboardGroups {
+	groups := &models.DashboardGroups{}
+	if _, index, err := s.directorySvc.UserStatuses(); err == nil {
+		groups.Total = index.GroupCount
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups.Since = s.firstDay()
+	if s.since.After(groups.Since) {
+		groups.Since = s.since
+	}
+
+	oldest := groups.Since.Format(time.DateOnly)
+	groups.Days = make([]*models.DashboardGroupDay, 0, len(s.groupDays))
+	for date, day := range s.groupDays {
+		if date >= oldest {
+			copied := *day
+			groups.Days = append(groups.Days, &copied)
+		}
+	}
+	sort.Slice(groups.Days, func(i, j int) bool { return groups.Days[i].Date < groups.Days[j].Date })
+	return groups
+}
+
+func (s *Service) GetMutations(ctx context.Context) *models.DashboardMutations {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mutations := &models.DashboardMutations{
+		Total:  s.mutations,
+		Since:  s.since,
+		Recent: make([]*models.Change, 0, len(s.recent)),
+	}
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		copied := *s.recent[i]
+		mutations.Recent = append(mutations.Recent, &copied)
+	}
+	return mutations
+}
+
+// GetOkta returns the last reading of the Okta rate limits and work queues.
+// UpdatedAt is zero until the first update.
+func (s *Service) GetOkta(ctx context.Context) *models.DashboardOkta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	okta := *s.okta
+	return &okta
+}
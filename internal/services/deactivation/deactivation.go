@@ -0,0 +1,333 @@
+package deactivation_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrPreviewRequired      = errors.New("previewId of a dry run is required")
+	ErrSelectionRequired    = errors.New("userIds or filter is required")
+	ErrSelectionConflict    = errors.New("userIds and filter cannot be combined")
+	ErrTooManyUsers         = errors.New("too many users to deactivate at once")
+	ErrPreviewNotFound      = errors.New("bulk deactivation preview not found")
+	ErrPreviewExpired       = errors.New("bulk deactivation preview has expired; run the dry run again")
+	ErrDeactivationNotFound = errors.New("bulk deactivation not found")
+)
+
+// Service deactivates users in bulk, as a background job. A deactivation is
+// always previewed by a dry run first and then started from that preview, so
+// exactly the users that were reviewed are changed. Users excluded by
+// configuration, such as break-glass accounts and members of excluded groups,
+// are never deactivated; the exclusions are checked again as the job runs.
+// Deactivations are spaced out so a large batch does not exhaust the org's
+// rate limits.
+type Service struct {
+	log       *zap.SugaredLogger
+	cfg       *config.BulkDeactivationConfig
+	usersSvc  *user_service.Service
+	groupsSvc *group_service.Service
+	auditSvc  *audit_service.Service
+	jobsSvc   *job_service.Service
+
+	mu       sync.Mutex
+	previews map[string]*preview
+	// results holds each job's results, by job ID, in the order the users
+	// were deactivated.
+	results map[string][]*models.DeactivationResult
+}
+
+type preview struct {
+	actor string
+	*models.BulkDeactivationPreview
+	exclude []string
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.BulkDeactivationConfig, usersSvc *user_service.Service,
+	groupsSvc *group_service.Service, auditSvc *audit_service.Service, jobsSvc *job_service.Service,
+) *Service {
+	return &Service{
+		log:       log,
+		cfg:       cfg,
+		usersSvc:  usersSvc,
+		groupsSvc: groupsSvc,
+		auditSvc:  auditSvc,
+		jobsSvc:   jobsSvc,
+		previews:  make(map[string]*preview),
+		results:   make(map[string][]*models.DeactivationResult),
+	}
+}
+
+// Preview finds the users req selects and splits them into those a
+// deactivation would change and those it leaves out. The preview can be
+// started by actor until it expires.
+func (s *Service) Preview(
+	ctx context.Context, actor string, req *models.BulkDeactivationRequest,
+) (*models.BulkDeactivationPreview, error) {
+	switch {
+	case len(req.UserIDs) == 0 && req.Filter == "":
+		return nil, ErrSelectionRequired
+	case len(req.UserIDs) > 0 && req.Filter != "":
+		return nil, ErrSelectionConflict
+	case len(req.UserIDs) > s.cfg.MaxUsers:
+		return nil, fmt.Errorf("%w: %d users were selected, at most %d can be deactivated",
+			ErrTooManyUsers, len(req.UserIDs), s.cfg.MaxUsers,
+		)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Previewing bulk deactivation",
+		"userCount", len(req.UserIDs), "filter", req.Filter, "actor", actor,
+	)
+
+	now := time.Now().UTC()
+	result := &models.BulkDeactivationPreview{
+		ID:        uuid.NewString(),
+		Users:     make([]*models.DeactivationTarget, 0),
+		Excluded:  make([]*models.DeactivationExclusion, 0),
+		Created:   now,
+		ExpiresAt: now.Add(s.cfg.PreviewTTL),
+	}
+
+	users, err := s.selectUsers(ctx, req, result)
+	if err != nil {
+		return nil, err
+	}
+
+	excludedMembers, err := s.excludedGroupMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		if reason := s.exclusion(user, actor, req.Exclude, excludedMembers); reason != "" {
+			result.Excluded = append(result.Excluded, &models.DeactivationExclusion{
+				UserID: user.ID, Login: user.Login, Reason: reason,
+			})
+			continue
+		}
+		result.Users = append(result.Users, &models.DeactivationTarget{
+			UserID: user.ID, Login: user.Login, Status: user.Status,
+		})
+	}
+
+	if len(result.Users) > s.cfg.MaxUsers {
+		return nil, fmt.Errorf("%w: %d users would be deactivated, at most %d can be",
+			ErrTooManyUsers, len(result.Users), s.cfg.MaxUsers,
+		)
+	}
+
+	s.mu.Lock()
+	for id, stored := range s.previews {
+		if !stored.ExpiresAt.After(now) {
+			delete(s.previews, id)
+		}
+	}
+	s.previews[result.ID] = &preview{actor: actor, BulkDeactivationPreview: result, exclude: slices.Clone(req.Exclude)}
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Bulk deactivation previewed",
+		"previewId", result.ID, "userCount", len(result.Users), "excludedCount", len(result.Excluded),
+	)
+	return result, nil
+}
+
+// Start starts a job that deactivates the users of a preview actor made. A
+// preview can be started once.
+func (s *Service) Start(ctx context.Context, actor, previewID string) (*models.Job, error) {
+	if previewID == "" {
+		return nil, ErrPreviewRequired
+	}
+
+	s.mu.Lock()
+	stored, ok := s.previews[previewID]
+	if ok && stored.actor == actor {
+		delete(s.previews, previewID)
+	}
+	s.mu.Unlock()
+
+	switch {
+	case !ok || stored.actor != actor:
+		return nil, ErrPreviewNotFound
+	case !stored.ExpiresAt.After(time.Now()):
+		return nil, ErrPreviewExpired
+	}
+
+	tracker := s.jobsSvc.Create(
+		models.JobTypeBulkDeactivation, models.ResourceTypeUser, "",
+		[]string{models.DeactivationStepDeactivate},
+	)
+
+	s.mu.Lock()
+	s.results[tracker.JobID()] = make([]*models.DeactivationResult, 0, len(stored.Users))
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Starting bulk deactivation",
+		"previewId", previewID, "userCount", len(stored.Users), "jobId", tracker.JobID(),
+	)
+
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		return tracker.Step(models.DeactivationStepDeactivate, func() (string, error) {
+			return s.deactivate(ctx, tracker.JobID(), stored)
+		})
+	})
+
+	return tracker.Job(), nil
+}
+
+// Results returns what the job has done so far, user by user.
+func (s *Service) Results(ctx context.Context, jobID string) ([]*models.DeactivationResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	results, ok := s.results[jobID]
+	if !ok {
+		return nil, ErrDeactivationNotFound
+	}
+
+	copied := make([]*models.DeactivationResult, len(results))
+	for i, result := range results {
+		resultCopy := *result
+		copied[i] = &resultCopy
+	}
+	return copied, nil
+}
+
+func (s *Service) deactivate(ctx context.Context, jobID string, stored *preview) (string, error) {
+	// Group memberships may have changed since the preview.
+	excludedMembers, err := s.excludedGroupMembers(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var deactivated, failed int
+	for i, target := range stored.Users {
+		if i > 0 && s.cfg.Interval > 0 {
+			timer := time.NewTimer(s.cfg.Interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return "", ctx.Err()
+			case <-timer.C:
+			}
+		}
+
+		result := &models.DeactivationResult{UserID: target.UserID, Login: target.Login}
+		user := &models.User{ID: target.UserID, Login: target.Login}
+		if reason := s.exclusion(user, stored.actor, stored.exclude, excludedMembers); reason != "" {
+			result.Outcome = models.DeactivationOutcomeSkipped
+			result.Error = reason
+		} else if err := s.usersSvc.DeactivateUser(ctx, target.UserID); err != nil {
+			failed++
+			result.Outcome = models.DeactivationOutcomeFailed
+			result.Error = err.Error()
+		} else {
+			deactivated++
+			result.Outcome = models.DeactivationOutcomeDeactivated
+			s.auditSvc.Record(ctx, &models.AuditEntry{
+				Actor:        stored.actor,
+				Action:       models.AuditActionUserBulkDeactivated,
+				ResourceType: models.ResourceTypeUser,
+				ResourceID:   target.UserID,
+				Details:      map[string]any{"jobId": jobID, "previewId": stored.ID},
+			})
+		}
+
+		s.mu.Lock()
+		s.results[jobID] = append(s.results[jobID], result)
+		s.mu.Unlock()
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Bulk deactivation finished",
+		"jobId", jobID, "deactivated", deactivated, "failed", failed,
+	)
+	if failed > 0 {
+		return "", fmt.Errorf("%d of %d deactivations failed; see the results", failed, len(stored.Users))
+	}
+	return fmt.Sprintf("Deactivated %d of %d users", deactivated, len(stored.Users)), nil
+}
+
+// selectUsers returns the users req selects. Requested IDs that match no user
+// are noted as excluded.
+func (s *Service) selectUsers(
+	ctx context.Context, req *models.BulkDeactivationRequest, result *models.BulkDeactivationPreview,
+) ([]*models.User, error) {
+	if req.Filter != "" {
+		return s.usersSvc.SearchUsers(ctx, req.Filter)
+	}
+
+	users := make([]*models.User, 0, len(req.UserIDs))
+	seen := make(map[string]bool, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		user, err := s.usersSvc.GetUser(ctx, userID)
+		if errors.Is(err, user_service.ErrUserNotFound) {
+			result.Excluded = append(result.Excluded, &models.DeactivationExclusion{UserID: userID, Reason: "user not found"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// excludedGroupMembers returns the IDs of the members of the excluded groups,
+// with the group each is a member of.
+func (s *Service) excludedGroupMembers(ctx context.Context) (map[string]string, error) {
+	members := make(map[string]string)
+	for _, groupID := range s.cfg.ExcludedGroups {
+		err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(member *models.User) error {
+			if _, ok := members[member.ID]; !ok {
+				members[member.ID] = groupID
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read members of excluded group %s: %w", groupID, err)
+		}
+	}
+	return members, nil
+}
+
+// exclusion returns why user must not be deactivated, or "" when it may be.
+func (s *Service) exclusion(user *models.User, actor string, exclude []string, excludedMembers map[string]string) string {
+	matches := func(entry string) bool {
+		return entry == user.ID || (user.Login != "" && strings.EqualFold(entry, user.Login))
+	}
+
+	switch {
+	case user.ID == actor:
+		return "the caller cannot deactivate themselves"
+	case slices.ContainsFunc(s.cfg.ExcludedUsers, matches):
+		return "excluded by configuration"
+	case excludedMembers[user.ID] != "":
+		return "member of excluded group " + excludedMembers[user.ID]
+	case slices.ContainsFunc(exclude, matches):
+		return "excluded by the request"
+	case user.Status == models.UserStatusDeprovisioned:
+		return "already deactivated"
+	}
+	return ""
+}
@@ -0,0 +1,425 @@
+package defaultgroup_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const (
+	rulesKey = "rules.json"
+	// hookActor is the audit actor of the memberships added when a user is
+	// created.
+	hookActor = "system:default-groups"
+)
+
+var (
+	ErrRuleNotFound     = errors.New("default group rule not found")
+	ErrConfiguredRule   = errors.New("default group rules from configuration can only be changed there")
+	ErrInvalidAttribute = errors.New("attribute must be userType or department")
+	ErrValueRequired    = errors.New("value is required")
+	ErrNoGroups         = errors.New("at least one group ID is required")
+)
+
+// Service adds users created through this service to the default groups of
+// their user type and department, and finds and fixes users who are missing
+// them. Rules come from configuration and from the API; the latter are stored
+// as one object on every change.
+type Service struct {
+	log        *zap.SugaredLogger
+	store      objectstore.Store
+	usersSvc   *user_service.Service
+	groupsSvc  *group_service.Service
+	auditSvc   *audit_service.Service
+	jobsSvc    *job_service.Service
+	configured []*models.DefaultGroupRule
+
+	mu sync.Mutex
+	// rules are the rules created through the API, keyed by ID. They are
+	// read from the store on first use.
+	rules  map[string]*models.DefaultGroupRule
+	loaded bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.DefaultGroupsConfig, store objectstore.Store,
+	usersSvc *user_service.Service, groupsSvc *group_service.Service,
+	auditSvc *audit_service.Service, jobsSvc *job_service.Service,
+) *Service {
+	s := &Service{
+		log:       log,
+		store:     store,
+		usersSvc:  usersSvc,
+		groupsSvc: groupsSvc,
+		auditSvc:  auditSvc,
+		jobsSvc:   jobsSvc,
+		rules:     make(map[string]*models.DefaultGroupRule),
+	}
+
+	for userType, groupIDs := range cfg.UserTypes {
+		s.configured = append(s.configured, configuredRule(models.DefaultGroupAttributeUserType, userType, groupIDs))
+	}
+	for department, groupIDs := range cfg.Departments {
+		s.configured = append(s.configured, configuredRule(models.DefaultGroupAttributeDepartment, department, groupIDs))
+	}
+	sort.Slice(s.configured, func(i, j int) bool { return s.configured[i].ID < s.configured[j].ID })
+
+	return s
+}
+
+func configuredRule(attribute, value string, groupIDs []string) *models.DefaultGroupRule {
+	return &models.DefaultGroupRule{
+		ID:        "config:" + attribute + ":" + value,
+		Attribute: attribute,
+		Value:     value,
+		GroupIDs:  groupIDs,
+		Source:    models.DefaultGroupRuleSourceConfig,
+	}
+}
+
+// Rules returns the rules from configuration, then those created through the
+// API in the order they were created.
+func (s *Service) Rules(ctx context.Context) ([]*models.DefaultGroupRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	stored := make([]*models.DefaultGroupRule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		stored = append(stored, copyRule(rule))
+	}
+	sort.Slice(stored, func(i, j int) bool { return stored[i].Created.Before(*stored[j].Created) })
+
+	rules := make([]*models.DefaultGroupRule, 0, len(s.configured)+len(stored))
+	for _, rule := range s.configured {
+		rules = append(rules, copyRule(rule))
+	}
+	return append(rules, stored...), nil
+}
+
+// CreateRule adds a rule after checking that its groups exist. It applies to
+// users created from now on; the report lists existing users it leaves
+// without their defaults.
+func (s *Service) CreateRule(ctx context.Context, req *models.CreateDefaultGroupRuleRequest) (*models.DefaultGroupRule, error) {
+	if req.Attribute != models.DefaultGroupAttributeUserType && req.Attribute != models.DefaultGroupAttributeDepartment {
+		return nil, ErrInvalidAttribute
+	}
+	if strings.TrimSpace(req.Value) == "" {
+		return nil, ErrValueRequired
+	}
+	if len(req.GroupIDs) == 0 {
+		return nil, ErrNoGroups
+	}
+
+	var groupIDs []string
+	for _, groupID := range req.GroupIDs {
+		if slices.Contains(groupIDs, groupID) {
+			continue
+		}
+		if _, err := s.groupsSvc.GetGroup(ctx, groupID); err != nil {
+			return nil, err
+		}
+		groupIDs = append(groupIDs, groupID)
+	}
+
+	now := time.Now().UTC()
+	rule := &models.DefaultGroupRule{
+		ID:        uuid.NewString(),
+		Attribute: req.Attribute,
+		Value:     strings.TrimSpace(req.Value),
+		GroupIDs:  groupIDs,
+		Source:    models.DefaultGroupRuleSourceAPI,
+		Created:   &now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	s.rules[rule.ID] = rule
+	if err := s.save(ctx); err != nil {
+		delete(s.rules, rule.ID)
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Default group rule created",
+		"ruleId", rule.ID, "attribute", rule.Attribute, "value", rule.Value, "groupCount", len(rule.GroupIDs),
+	)
+	return copyRule(rule), nil
+}
+
+func (s *Service) DeleteRule(ctx context.Context, ruleID string) error {
+	if strings.HasPrefix(ruleID, "config:") {
+		return ErrConfiguredRule
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	rule, ok := s.rules[ruleID]
+	if !ok {
+		return ErrRuleNotFound
+	}
+
+	delete(s.rules, ruleID)
+	if err := s.save(ctx); err != nil {
+		s.rules[ruleID] = rule
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Default group rule deleted", "ruleId", ruleID)
+	return nil
+}
+
+// AfterCreateUser is a hooks.CreateUser after hook that adds the new user to
+// their default groups. A group the user cannot join does not stop the
+// others; the user then shows up in the report.
+func (s *Service) AfterCreateUser(ctx context.Context, event *hooks.Event) error {
+	user, ok := event.Result.(*models.User)
+	if !ok {
+		return nil
+	}
+
+	rules, err := s.Rules(ctx)
+	if err != nil {
+		return err
+	}
+
+	groupIDs := groupsFor(rules, user)
+	if len(groupIDs) == 0 {
+		return nil
+	}
+
+	added, err := s.addToGroups(ctx, hookActor, user.ID, groupIDs)
+	logger.FromContext(ctx, s.log).Infow("Default groups applied to new user",
+		"userId", user.ID, "added", len(added), "groupCount", len(groupIDs),
+	)
+	return err
+}
+
+// MissingDefaults lists the users, other than deprovisioned ones, who are
+// not members of every default group their user type and department give
+// them.
+func (s *Service) MissingDefaults(ctx context.Context) ([]*models.MissingDefaultGroups, error) {
+	rules, err := s.Rules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.MissingDefaultGroups, 0)
+	if len(rules) == 0 {
+		return result, nil
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Finding users missing their default groups", "ruleCount", len(rules))
+
+	// Each group's members are read once, when the first user who should be
+	// in it is found.
+	members := make(map[string]map[string]bool)
+	err = s.usersSvc.StreamUsers(ctx, func(user *models.User) error {
+		if user.Status == models.UserStatusDeprovisioned {
+			return nil
+		}
+
+		var missing []string
+		for _, groupID := range groupsFor(rules, user) {
+			groupMembers, ok := members[groupID]
+			if !ok {
+				groupMembers = make(map[string]bool)
+				err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(member *models.User) error {
+					groupMembers[member.ID] = true
+					return nil
+				})
+				if err != nil {
+					return err
+				}
+				members[groupID] = groupMembers
+			}
+			if !groupMembers[user.ID] {
+				missing = append(missing, groupID)
+			}
+		}
+
+		if len(missing) > 0 {
+			result = append(result, &models.MissingDefaultGroups{UserID: user.ID, Login: user.Login, GroupIDs: missing})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Users missing default groups found", "count", len(result))
+	return result, nil
+}
+
+// Remediate starts a job that adds users to the default groups they are
+// missing. The job builds the report again, so only users still missing
+// groups are changed.
+func (s *Service) Remediate(
+	ctx context.Context, actor string, req *models.RemediateDefaultGroupsRequest,
+) (*models.Job, error) {
+	tracker := s.jobsSvc.Create(
+		models.JobTypeDefaultGroupRemediation, models.ResourceTypeUser, "",
+		[]string{models.RemediationStepFind, models.RemediationStepAdd},
+	)
+
+	logger.FromContext(ctx, s.log).Infow("Remediating missing default groups",
+		"userCount", len(req.UserIDs), "jobId", tracker.JobID(),
+	)
+
+	userIDs := slices.Clone(req.UserIDs)
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		return s.remediate(ctx, tracker, actor, userIDs)
+	})
+
+	return tracker.Job(), nil
+}
+
+func (s *Service) remediate(ctx context.Context, tracker *job_service.Tracker, actor string, userIDs []string) error {
+	var missing []*models.MissingDefaultGroups
+
+	err := tracker.Step(models.RemediationStepFind, func() (string, error) {
+		report, err := s.MissingDefaults(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		for _, entry := range report {
+			if len(userIDs) == 0 || slices.Contains(userIDs, entry.UserID) {
+				missing = append(missing, entry)
+			}
+		}
+		return fmt.Sprintf("%d users are missing default groups", len(missing)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tracker.Step(models.RemediationStepAdd, func() (string, error) {
+		var count int
+		for _, entry := range missing {
+			added, err := s.addToGroups(ctx, actor, entry.UserID, entry.GroupIDs)
+			count += len(added)
+			if err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("Added %d memberships for %d users", count, len(missing)), nil
+	})
+}
+
+// addToGroups adds the user to each group, audits the memberships added and
+// returns them with the errors of the others joined.
+func (s *Service) addToGroups(ctx context.Context, actor, userID string, groupIDs []string) ([]string, error) {
+	var added []string
+	var errs []error
+	for _, groupID := range groupIDs {
+		if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to add user to default group", zap.Error(err),
+				"groupId", groupID, "userId", userID,
+			)
+			errs = append(errs, fmt.Errorf("failed to add user %s to default group %s: %w", userID, groupID, err))
+			continue
+		}
+		added = append(added, groupID)
+	}
+
+	if len(added) > 0 {
+		s.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionDefaultGroupsApplied,
+			ResourceType: models.ResourceTypeUser,
+			ResourceID:   userID,
+			Details:      map[string]any{"groupIds": added},
+		})
+	}
+	return added, errors.Join(errs...)
+}
+
+// groupsFor returns the groups the rules give the user, each once.
+func groupsFor(rules []*models.DefaultGroupRule, user *models.User) []string {
+	var groupIDs []string
+	for _, rule := range rules {
+		value := user.Department
+		if rule.Attribute == models.DefaultGroupAttributeUserType {
+			value = user.UserType
+		}
+		if !strings.EqualFold(strings.TrimSpace(value), rule.Value) {
+			continue
+		}
+		for _, groupID := range rule.GroupIDs {
+			if !slices.Contains(groupIDs, groupID) {
+				groupIDs = append(groupIDs, groupID)
+			}
+		}
+	}
+	return groupIDs
+}
+
+// load reads the rules from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, rulesKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read default group rules: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.rules); err != nil {
+			return fmt.Errorf("failed to decode default group rules: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the rules created through the API. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.rules)
+	if err != nil {
+		return fmt.Errorf("failed to encode default group rules: %w", err)
+	}
+	if err := s.store.Put(ctx, rulesKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store default group rules: %w", err)
+	}
+	return nil
+}
+
+func copyRule(rule *models.DefaultGroupRule) *models.DefaultGroupRule {
+	copied := *rule
+	copied.GroupIDs = slices.Clone(rule.GroupIDs)
+	return &copied
+}
@@ -0,0 +1,416 @@
+package device_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwt"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+const deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+var defaultScopes = []string{"openid", "profile", "offline_access"}
+
+var (
+	ErrNotConfigured        = errors.New("the device authorization grant is not configured")
+	ErrDeviceCodeRequired   = errors.New("device code is required")
+	ErrDeviceCodeNotFound   = errors.New("device code not found; start a new device authorization")
+	ErrAuthorizationFailure = errors.New("the authorization server rejected the request")
+)
+
+// session is a started device flow, keyed by the hash of its device code.
+type session struct {
+	id         string
+	clientName string
+	userCode   string
+	scopes     []string
+	interval   int
+	expiresAt  time.Time
+}
+
+// Service wraps Okta's device authorization grant for devices without a
+// browser, such as CLI tools and kiosk apps. The device starts a flow here,
+// the user approves it on another device, and the device polls here for its
+// tokens. Every flow is audited from start to approval, denial or expiry.
+// Flows are kept in memory only; a restart makes devices start over.
+type Service struct {
+	log      *zap.SugaredLogger
+	cfg      *config.DeviceAuthConfig
+	issuer   string
+	client   *http.Client
+	auditSvc *audit_service.Service
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	// authorizeURL and tokenURL are discovered from the issuer on first use.
+	authorizeURL string
+	tokenURL     string
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.DeviceAuthConfig, issuer string, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:      log,
+		cfg:      cfg,
+		issuer:   issuer,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		auditSvc: auditSvc,
+		sessions: make(map[string]*session),
+	}
+}
+
+// Start asks Okta for a device code and the user code to show the user.
+func (s *Service) Start(
+	ctx context.Context, req *models.StartDeviceAuthorizationRequest,
+) (*models.DeviceAuthorization, error) {
+	if s.cfg.ClientID == "" {
+		return nil, ErrNotConfigured
+	}
+
+	authorizeURL, _, err := s.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = s.cfg.Scopes
+	}
+	if len(scopes) == 0 {
+		scopes = defaultScopes
+	}
+
+	var result struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	form := url.Values{"client_id": {s.cfg.ClientID}, "scope": {strings.Join(scopes, " ")}}
+	if oauthErr, err := s.post(ctx, authorizeURL, form, &result); err != nil {
+		return nil, err
+	} else if oauthErr != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAuthorizationFailure, oauthErr)
+	}
+
+	now := time.Now().UTC()
+	started := &session{
+		id:         uuid.NewString(),
+		clientName: req.ClientName,
+		userCode:   result.UserCode,
+		scopes:     scopes,
+		interval:   result.Interval,
+		expiresAt:  now.Add(time.Duration(result.ExpiresIn) * time.Second),
+	}
+
+	s.mu.Lock()
+	expired := s.purge(now)
+	s.sessions[hashDeviceCode(result.DeviceCode)] = started
+	s.mu.Unlock()
+
+	for _, lapsed := range expired {
+		s.record(ctx, actorFor(lapsed), models.AuditActionDeviceAuthorizationExpired, lapsed, nil)
+	}
+	s.record(ctx, actorFor(started), models.AuditActionDeviceAuthorizationStarted, started, map[string]any{
+		"expiresAt": started.expiresAt,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Device authorization started",
+		"deviceAuthorizationId", started.id, "clientName", started.clientName,
+	)
+	return &models.DeviceAuthorization{
+		ID:                      started.id,
+		DeviceCode:              result.DeviceCode,
+		UserCode:                result.UserCode,
+		VerificationURI:         result.VerificationURI,
+		VerificationURIComplete: result.VerificationURIComplete,
+		Interval:                result.Interval,
+		ExpiresAt:               started.expiresAt,
+	}, nil
+}
+
+// Poll asks Okta whether the user has approved the device. Flows end on
+// approval, denial or expiry, after which their device code is unknown here.
+func (s *Service) Poll(
+	ctx context.Context, req *models.PollDeviceAuthorizationRequest,
+) (*models.DeviceAuthorizationStatus, error) {
+	if s.cfg.ClientID == "" {
+		return nil, ErrNotConfigured
+	}
+	if req.DeviceCode == "" {
+		return nil, ErrDeviceCodeRequired
+	}
+
+	key := hashDeviceCode(req.DeviceCode)
+	s.mu.Lock()
+	polled, ok := s.sessions[key]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrDeviceCodeNotFound
+	}
+
+	_, tokenURL, err := s.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		TokenType    string `json:"token_type"`
+		AccessToken  string `json:"access_token"`
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	form := url.Values{
+		"client_id":   {s.cfg.ClientID},
+		"device_code": {req.DeviceCode},
+		"grant_type":  {deviceCodeGrantType},
+	}
+	oauthErr, err := s.post(ctx, tokenURL, form, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.DeviceAuthorizationStatus{ID: polled.id}
+	if oauthErr != nil {
+		switch oauthErr.Code {
+		case "authorization_pending":
+			status.Status = models.DeviceAuthorizationStatusPending
+			status.Interval = s.interval(key, 0)
+		case "slow_down":
+			// RFC 8628 has the device wait five more seconds from now on.
+			status.Status = models.DeviceAuthorizationStatusSlowDown
+			status.Interval = s.interval(key, 5)
+		case "access_denied":
+			status.Status = models.DeviceAuthorizationStatusDenied
+			s.finish(ctx, key, polled, actorFor(polled), models.AuditActionDeviceAuthorizationDenied, nil)
+		case "expired_token":
+			status.Status = models.DeviceAuthorizationStatusExpired
+			s.finish(ctx, key, polled, actorFor(polled), models.AuditActionDeviceAuthorizationExpired, nil)
+		default:
+			return nil, fmt.Errorf("%w: %s", ErrAuthorizationFailure, oauthErr)
+		}
+		return status, nil
+	}
+
+	// The token came straight from the issuer's token endpoint, so its
+	// claims name the user without verifying its signature.
+	userID := tokenUser(result.AccessToken)
+	s.finish(ctx, key, polled, userID, models.AuditActionDeviceAuthorizationApproved, map[string]any{
+		"userId": userID,
+		"scope":  result.Scope,
+	})
+
+	status.Status = models.DeviceAuthorizationStatusApproved
+	status.Tokens = &models.DeviceTokens{
+		TokenType:    result.TokenType,
+		AccessToken:  result.AccessToken,
+		IDToken:      result.IDToken,
+		RefreshToken: result.RefreshToken,
+		Scope:        result.Scope,
+		ExpiresIn:    result.ExpiresIn,
+	}
+	return status, nil
+}
+
+// interval raises the polling interval of a flow by extra seconds and
+// returns it.
+func (s *Service) interval(key string, extra int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	polled, ok := s.sessions[key]
+	if !ok {
+		return 0
+	}
+	polled.interval += extra
+	return polled.interval
+}
+
+// finish forgets a flow and audits how it ended. A flow polled concurrently
+// is only audited once.
+func (s *Service) finish(
+	ctx context.Context, key string, ended *session, actor, action string, details map[string]any,
+) {
+	s.mu.Lock()
+	_, ok := s.sessions[key]
+	delete(s.sessions, key)
+	s.mu.Unlock()
+
+	if ok {
+		s.record(ctx, actor, action, ended, details)
+		logger.FromContext(ctx, s.log).Infow("Device authorization ended",
+			"deviceAuthorizationId", ended.id, "action", action,
+		)
+	}
+}
+
+// purge forgets the flows past their expiry and returns them. Callers hold mu.
+func (s *Service) purge(now time.Time) []*session {
+	var expired []*session
+	for key, started := range s.sessions {
+		if !started.expiresAt.After(now) {
+			expired = append(expired, started)
+			delete(s.sessions, key)
+		}
+	}
+	return expired
+}
+
+func (s *Service) record(ctx context.Context, actor, action string, flow *session, details map[string]any) {
+	if details == nil {
+		details = make(map[string]any)
+	}
+	details["clientName"] = flow.clientName
+	details["userCode"] = flow.userCode
+	details["scopes"] = flow.scopes
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeDeviceAuthorization,
+		ResourceID:   flow.id,
+		Details:      details,
+	})
+}
+
+// oauthError is an error response of the authorization server.
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (e *oauthError) String() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// post sends a form to the authorization server. An OAuth error response is
+// returned as oauthError rather than as an error, as pending and slow_down
+// are expected answers while polling.
+func (s *Service) post(ctx context.Context, target string, form url.Values, out any) (*oauthError, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the authorization server: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the authorization server response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var oauthErr oauthError
+		if err := json.Unmarshal(body, &oauthErr); err != nil || oauthErr.Code == "" {
+			return nil, fmt.Errorf("authorization server returned status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+		}
+		return &oauthErr, nil
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return nil, fmt.Errorf("failed to decode the authorization server response: %w", err)
+	}
+	return nil, nil
+}
+
+// endpoints discovers the device authorization and token endpoints of the
+// issuer once.
+func (s *Service) endpoints(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	authorizeURL, tokenURL := s.authorizeURL, s.tokenURL
+	s.mu.Unlock()
+	if authorizeURL != "" && tokenURL != "" {
+		return authorizeURL, tokenURL, nil
+	}
+
+	discoveryURL := strings.TrimRight(s.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to build discovery request: %w", err)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to fetch issuer discovery document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("issuer discovery returned unexpected status code: %d", res.StatusCode)
+	}
+
+	var document struct {
+		DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint"`
+		TokenEndpoint               string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&document); err != nil {
+		return "", "", fmt.Errorf("failed to decode issuer discovery document: %w", err)
+	}
+	if document.DeviceAuthorizationEndpoint == "" || document.TokenEndpoint == "" {
+		return "", "", errors.New("issuer discovery document has no device authorization or token endpoint")
+	}
+
+	s.mu.Lock()
+	s.authorizeURL, s.tokenURL = document.DeviceAuthorizationEndpoint, document.TokenEndpoint
+	s.mu.Unlock()
+	return document.DeviceAuthorizationEndpoint, document.TokenEndpoint, nil
+}
+
+// tokenUser returns the Okta user ID of an access token, or its subject when
+// it has none.
+func tokenUser(accessToken string) string {
+	token, err := jwt.ParseString(accessToken)
+	if err != nil {
+		return "unknown"
+	}
+	if uid, ok := token.Get("uid"); ok {
+		if userID, ok := uid.(string); ok && userID != "" {
+			return userID
+		}
+	}
+	return token.Subject()
+}
+
+// actorFor names the device in the audit log until a user has approved it.
+func actorFor(flow *session) string {
+	if flow.clientName == "" {
+		return "device"
+	}
+	return "device:" + flow.clientName
+}
+
+func hashDeviceCode(deviceCode string) string {
+	sum := sha256.Sum256([]byte(deviceCode))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,518 @@
+package directory_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrIndexNotReady = errors.New("the directory index has not been built yet")
+	ErrUserNotFound  = errors.New("user not found in the directory index")
+	ErrGroupNotFound = errors.New("group not found in the directory index")
+)
+
+// Service keeps a materialized copy of users, groups and memberships and
+// answers reads from it alone, so read-heavy consumers never reach Okta.
+// Refresh builds a new index off to the side and swaps it in; reads keep
+// using the previous index until then. The differences between the two are
+// added to the change feed.
+type Service struct {
+	log        *zap.SugaredLogger
+	usersSvc   *user_service.Service
+	groupsSvc  *group_service.Service
+	changesSvc *change_service.Service
+	staleAfter time.Duration
+
+	mu    sync.RWMutex
+	index *index
+}
+
+// index is never modified after it is built.
+type index struct {
+	refreshedAt  time.Time
+	users        map[string]*models.User
+	userIDsLogin map[string]string
+	groups       map[string]*models.Group
+	members      map[string][]string
+	userGroups   map[string][]string
+	sortedUsers  []*models.User
+	sortedGroups []*models.Group
+	// userStatuses counts the users by status.
+	userStatuses map[string]int
+}
+
+func New(
+	log *zap.SugaredLogger,
+	usersSvc *user_service.Service,
+	groupsSvc *group_service.Service,
+	changesSvc *change_service.Service,
+	staleAfter time.Duration,
+) *Service {
+	return &Service{log: log, usersSvc: usersSvc, groupsSvc: groupsSvc, changesSvc: changesSvc, staleAfter: staleAfter}
+}
+
+// Refresh rebuilds the index from Okta. On failure the current index is kept.
+func (s *Service) Refresh(ctx context.Context) error {
+	started := time.Now()
+	logger.FromContext(ctx, s.log).Infow("Refreshing directory index")
+
+	idx := &index{
+		users:        make(map[string]*models.User),
+		userIDsLogin: make(map[string]string),
+		groups:       make(map[string]*models.Group),
+		members:      make(map[string][]string),
+		userGroups:   make(map[string][]string),
+		userStatuses: make(map[string]int),
+	}
+
+	err := s.usersSvc.StreamUsers(ctx, func(user *models.User) error {
+		idx.users[user.ID] = user
+		idx.userStatuses[user.Status]++
+		idx.userIDsLogin[strings.ToLower(user.Login)] = user.ID
+		idx.sortedUsers = append(idx.sortedUsers, user)
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to refresh directory index users", zap.Error(err))
+		return fmt.Errorf("failed to index users: %w", err)
+	}
+
+	err = s.groupsSvc.StreamGroups(ctx, func(group *models.Group) error {
+		idx.groups[group.ID] = group
+		idx.sortedGroups = append(idx.sortedGroups, group)
+		return nil
+	})
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to refresh directory index groups", zap.Error(err))
+		return fmt.Errorf("failed to index groups: %w", err)
+	}
+
+	for groupID := range idx.groups {
+		err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(member *models.User) error {
+			idx.members[groupID] = append(idx.members[groupID], member.ID)
+			idx.userGroups[member.ID] = append(idx.userGroups[member.ID], groupID)
+			return nil
+		})
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to refresh directory index members", zap.Error(err), "groupId", groupID)
+			return fmt.Errorf("failed to index members of group %s: %w", groupID, err)
+		}
+	}
+
+	sort.Slice(idx.sortedUsers, func(i, j int) bool { return idx.sortedUsers[i].Login < idx.sortedUsers[j].Login })
+	sort.Slice(idx.sortedGroups, func(i, j int) bool { return idx.sortedGroups[i].Name < idx.sortedGroups[j].Name })
+	idx.refreshedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	previous := s.index
+	s.index = idx
+	s.mu.Unlock()
+
+	// The first index has nothing to compare with.
+	var changes []*models.Change
+	if previous != nil {
+		changes = diff(previous, idx)
+		s.changesSvc.Record(ctx, changes...)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Directory index refreshed",
+		"userCount", len(idx.users),
+		"groupCount", len(idx.groups),
+		"changeCount", len(changes),
+		"duration", time.Since(started),
+	)
+	return nil
+}
+
+// Freshness describes the current index.
+func (s *Service) Freshness() (*models.DirectoryIndex, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return s.describe(idx), nil
+}
+
+// GetUsers lists indexed users, sorted by login. A non-empty query keeps the
+// users whose login, email or name starts with it, ignoring case.
+func (s *Service) GetUsers(query string) (*models.DirectoryUsers, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	result := make([]*models.User, 0)
+	for _, user := range idx.sortedUsers {
+		if query == "" || matchesUser(user, query) {
+			result = append(result, copyUser(user))
+		}
+	}
+
+	return &models.DirectoryUsers{Index: s.describe(idx), Users: result}, nil
+}
+
+// GetUser returns a user by ID or login, with the groups they belong to.
+func (s *Service) GetUser(userIDOrLogin string) (*models.DirectoryUser, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := idx.users[userIDOrLogin]
+	if !ok {
+		user, ok = idx.users[idx.userIDsLogin[strings.ToLower(userIDOrLogin)]]
+	}
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	groups := make([]*models.Group, 0, len(idx.userGroups[user.ID]))
+	for _, groupID := range idx.userGroups[user.ID] {
+		groups = append(groups, copyGroup(idx.groups[groupID]))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return &models.DirectoryUser{Index: s.describe(idx), User: copyUser(user), Groups: groups}, nil
+}
+
+// GetGroups lists indexed groups, sorted by name. A non-empty query keeps the
+// groups whose name starts with it, ignoring case.
+func (s *Service) GetGroups(query string) (*models.DirectoryGroups, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	query = strings.ToLower(query)
+	result := make([]*models.Group, 0)
+	for _, group := range idx.sortedGroups {
+		if query == "" || strings.HasPrefix(strings.ToLower(group.Name), query) {
+			result = append(result, copyGroup(group))
+		}
+	}
+
+	return &models.DirectoryGroups{Index: s.describe(idx), Groups: result}, nil
+}
+
+// GetGroup returns a group with its members.
+func (s *Service) GetGroup(groupID string) (*models.DirectoryGroup, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	group, ok := idx.groups[groupID]
+	if !ok {
+		return nil, ErrGroupNotFound
+	}
+
+	members := make([]*models.User, 0, len(idx.members[groupID]))
+	for _, userID := range idx.members[groupID] {
+		if user, ok := idx.users[userID]; ok {
+			members = append(members, copyUser(user))
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Login < members[j].Login })
+
+	return &models.DirectoryGroup{Index: s.describe(idx), Group: copyGroup(group), Members: members}, nil
+}
+
+// CheckMembers reports whether each user is a member of the group. It is
+// answered from the index while the index is fresh and has the group, and
+// from Okta otherwise, or when consistent is set.
+func (s *Service) CheckMembers(
+	ctx context.Context, groupID string, userIDs []string, consistent bool,
+) (*models.GroupMembershipCheck, error) {
+	result := &models.GroupMembershipCheck{GroupID: groupID, Members: make(map[string]bool, len(userIDs))}
+	for _, userID := range userIDs {
+		result.Members[userID] = false
+	}
+
+	if idx, err := s.current(); err == nil && !consistent {
+		described := s.describe(idx)
+		if _, ok := idx.groups[groupID]; ok && !described.Stale {
+			for _, memberID := range idx.members[groupID] {
+				if _, asked := result.Members[memberID]; asked {
+					result.Members[memberID] = true
+				}
+			}
+			result.Source = models.MembershipCheckSourceIndex
+			result.Index = described
+			return result, nil
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Checking group members in Okta", "groupId", groupID, "userCount", len(userIDs))
+
+	err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(member *models.User) error {
+		if _, asked := result.Members[member.ID]; asked {
+			result.Members[member.ID] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.Source = models.MembershipCheckSourceOkta
+	return result, nil
+}
+
+// groupSizeBounds are the smallest sizes of the group size buckets.
+var groupSizeBounds = []int{0, 1, 11, 101, 1001, 10001}
+
+// GroupStats counts groups by type and size. It is computed from the index
+// while the index is fresh, and from Okta otherwise, or when consistent is
+// set, which reads the members of every group.
+func (s *Service) GroupStats(ctx context.Context, consistent bool) (*models.GroupStats, error) {
+	if idx, err := s.current(); err == nil && !consistent {
+		described := s.describe(idx)
+		if !described.Stale {
+			stats := newGroupStats()
+			for groupID, group := range idx.groups {
+				countGroup(stats, group, len(idx.members[groupID]))
+			}
+			stats.Source = models.MembershipCheckSourceIndex
+			stats.Index = described
+			return stats, nil
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Computing group stats from Okta")
+
+	var groups []*models.Group
+	err := s.groupsSvc.StreamGroups(ctx, func(group *models.Group) error {
+		groups = append(groups, group)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := newGroupStats()
+	for _, group := range groups {
+		var size int
+		err := s.groupsSvc.StreamGroupMembers(ctx, group.ID, func(*models.User) error {
+			size++
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		countGroup(stats, group, size)
+	}
+	stats.Source = models.MembershipCheckSourceOkta
+	return stats, nil
+}
+
+func newGroupStats() *models.GroupStats {
+	stats := &models.GroupStats{ByType: make(map[string]int)}
+	for i, lower := range groupSizeBounds {
+		bucket := &models.GroupSizeBucket{Min: lower, Label: fmt.Sprintf("%d+", lower)}
+		if i+1 < len(groupSizeBounds) {
+			upper := groupSizeBounds[i+1] - 1
+			bucket.Max = &upper
+			bucket.Label = fmt.Sprintf("%d-%d", lower, upper)
+			if lower == upper {
+				bucket.Label = fmt.Sprint(lower)
+			}
+		}
+		stats.SizeBuckets = append(stats.SizeBuckets, bucket)
+	}
+	return stats
+}
+
+func countGroup(stats *models.GroupStats, group *models.Group, size int) {
+	stats.TotalGroups++
+	stats.TotalMemberships += size
+	stats.ByType[group.Type]++
+	if size == 0 {
+		stats.EmptyGroups++
+	}
+	for i := len(stats.SizeBuckets) - 1; i >= 0; i-- {
+		if size >= stats.SizeBuckets[i].Min {
+			stats.SizeBuckets[i].Count++
+			break
+		}
+	}
+}
+
+// UserStatuses counts the indexed users by status, as of the last refresh.
+func (s *Service) UserStatuses() (map[string]int, *models.DirectoryIndex, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, nil, err
+	}
+	return maps.Clone(idx.userStatuses), s.describe(idx), nil
+}
+
+// Memberships returns the members of every indexed group as of the last
+// refresh.
+func (s *Service) Memberships() (*models.MembershipSnapshot, error) {
+	idx, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &models.MembershipSnapshot{
+		TakenAt: idx.refreshedAt,
+		Groups:  make(map[string][]*models.HistoricalMember, len(idx.groups)),
+	}
+	for groupID := range idx.groups {
+		members := make([]*models.HistoricalMember, 0, len(idx.members[groupID]))
+		for _, userID := range idx.members[groupID] {
+			member := &models.HistoricalMember{ID: userID}
+			if user, ok := idx.users[userID]; ok {
+				member.Login = user.Login
+			}
+			members = append(members, member)
+		}
+		snapshot.Groups[groupID] = members
+	}
+	return snapshot, nil
+}
+
+func (s *Service) current() (*index, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.index == nil {
+		return nil, ErrIndexNotReady
+	}
+	return s.index, nil
+}
+
+func (s *Service) describe(idx *index) *models.DirectoryIndex {
+	age := time.Since(idx.refreshedAt)
+	return &models.DirectoryIndex{
+		RefreshedAt: idx.refreshedAt,
+		AgeSeconds:  int64(age.Seconds()),
+		Stale:       age > s.staleAfter,
+		UserCount:   len(idx.users),
+		GroupCount:  len(idx.groups),
+	}
+}
+
+// diff lists the changes from one index to the next: users, then groups,
+// then memberships, each sorted by ID.
+func diff(previous, next *index) []*models.Change {
+	var changes []*models.Change
+	change := func(changeType, resourceType, resourceID string, details map[string]any) {
+		changes = append(changes, &models.Change{
+			Source:       models.ChangeSourceOkta,
+			Type:         changeType,
+			ResourceType: resourceType,
+			ResourceID:   resourceID,
+			Details:      details,
+		})
+	}
+
+	for _, userID := range sortedKeys(previous.users, next.users) {
+		before, after := previous.users[userID], next.users[userID]
+		switch {
+		case before == nil:
+			change(models.ChangeTypeUserCreated, models.ResourceTypeUser, userID, map[string]any{"login": after.Login})
+		case after == nil:
+			change(models.ChangeTypeUserDeleted, models.ResourceTypeUser, userID, map[string]any{"login": before.Login})
+		case before.Status != after.Status || !sameTime(before.LastUpdated, after.LastUpdated):
+			change(models.ChangeTypeUserUpdated, models.ResourceTypeUser, userID, map[string]any{
+				"login": after.Login, "status": after.Status,
+			})
+		}
+	}
+
+	for _, groupID := range sortedKeys(previous.groups, next.groups) {
+		before, after := previous.groups[groupID], next.groups[groupID]
+		switch {
+		case before == nil:
+			change(models.ChangeTypeGroupCreated, models.ResourceTypeGroup, groupID, map[string]any{"name": after.Name})
+		case after == nil:
+			change(models.ChangeTypeGroupDeleted, models.ResourceTypeGroup, groupID, map[string]any{"name": before.Name})
+		case !before.LastUpdated.Equal(after.LastUpdated):
+			change(models.ChangeTypeGroupUpdated, models.ResourceTypeGroup, groupID, map[string]any{"name": after.Name})
+		}
+	}
+
+	// Members of a deleted group are not reported as removed one by one.
+	for _, groupID := range sortedKeys(previous.members, next.members) {
+		if next.groups[groupID] == nil {
+			continue
+		}
+		before, after := toSet(previous.members[groupID]), toSet(next.members[groupID])
+		for _, userID := range sortedKeys(before, after) {
+			switch {
+			case !before[userID]:
+				change(models.ChangeTypeGroupMemberAdded, models.ResourceTypeGroup, groupID, map[string]any{"userId": userID})
+			case !after[userID]:
+				change(models.ChangeTypeGroupMemberRemoved, models.ResourceTypeGroup, groupID, map[string]any{"userId": userID})
+			}
+		}
+	}
+
+	return changes
+}
+
+// sortedKeys returns the keys of both maps once each, sorted.
+func sortedKeys[V any](a, b map[string]V) []string {
+	keys := make([]string, 0, len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func toSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func matchesUser(user *models.User, query string) bool {
+	for _, value := range []string{user.Login, user.Email, user.FirstName, user.LastName} {
+		if strings.HasPrefix(strings.ToLower(value), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyUser and copyGroup keep callers from modifying the shared index.
+func copyUser(user *models.User) *models.User {
+	copied := *user
+	return &copied
+}
+
+func copyGroup(group *models.Group) *models.Group {
+	copied := *group
+	return &copied
+}
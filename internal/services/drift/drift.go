@@ -0,0 +1,258 @@
+package drift_service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	change_service "github.com/iamBelugaa/iam/internal/services/change"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+const (
+	// logDelay keeps checks clear of the newest System Log events, which
+	// Okta may still be writing, so none is skipped by moving past it.
+	logDelay = 2 * time.Minute
+
+	// maxEvents is how many drift events are kept; older ones are dropped.
+	maxEvents = 10000
+)
+
+// watchedEventTypes are the System Log events of changes this service can
+// also make, and so the ones that may have been made around it.
+var watchedEventTypes = []string{
+	"user.lifecycle.create",
+	"user.lifecycle.activate",
+	"user.lifecycle.deactivate",
+	"user.lifecycle.suspend",
+	"user.lifecycle.unsuspend",
+	"user.lifecycle.delete.initiated",
+	"user.account.update_profile",
+	"user.account.privilege.grant",
+	"user.account.privilege.revoke",
+	"group.lifecycle.create",
+	"group.lifecycle.delete",
+	"group.profile.update",
+	"group.user_membership.add",
+	"group.user_membership.remove",
+	"group.privilege.grant",
+	"group.privilege.revoke",
+	"application.user_membership.add",
+	"application.user_membership.remove",
+}
+
+// Service finds changes Okta logged that were not made through this service.
+// A logged change is attributed to the service when its actor is one the
+// service acts as, or when a mutation of one of its targets was made through
+// the API within the correlation window; anything else is drift. Drift is
+// kept for the report and published to webhook subscribers of
+// iam.drift.detected.
+type Service struct {
+	log         *zap.SugaredLogger
+	client      *okta.APIClient
+	cfg         *config.DriftConfig
+	actors      map[string]bool
+	changesSvc  *change_service.Service
+	webhooksSvc *webhook_service.Service
+
+	mu sync.RWMutex
+	// checkedUntil is where the next check starts. Changes logged before
+	// the service started are not looked at.
+	checkedUntil time.Time
+	events       []*models.DriftEvent
+}
+
+func New(
+	log *zap.SugaredLogger,
+	client *okta.APIClient,
+	cfg *config.DriftConfig,
+	clientID string,
+	changesSvc *change_service.Service,
+	webhooksSvc *webhook_service.Service,
+) *Service {
+	actors := make(map[string]bool)
+	for _, actor := range append(slices.Clone(cfg.ServiceActors), clientID) {
+		if actor != "" {
+			actors[actor] = true
+		}
+	}
+
+	return &Service{
+		log:          log,
+		client:       client,
+		cfg:          cfg,
+		actors:       actors,
+		changesSvc:   changesSvc,
+		webhooksSvc:  webhooksSvc,
+		checkedUntil: time.Now().UTC().Add(-logDelay),
+	}
+}
+
+// Detect compares the changes logged since the last check with the
+// mutations made through the API and records the rest as drift.
+func (s *Service) Detect(ctx context.Context) error {
+	s.mu.RLock()
+	since := s.checkedUntil
+	s.mu.RUnlock()
+
+	until := time.Now().UTC().Add(-logDelay)
+	if !until.After(since) {
+		return nil
+	}
+
+	filter := fmt.Sprintf(`(eventType eq "%s") and outcome.result eq "SUCCESS"`,
+		strings.Join(watchedEventTypes, `" or eventType eq "`))
+
+	events, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).
+		Since(since).Until(until).Filter(filter).SortOrder("ASCENDING").Execute()
+	if err == nil {
+		events, err = pagination.All(events, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err), "filter", filter)
+		return fmt.Errorf("failed to list changes from Okta: %w", err)
+	}
+
+	window := s.cfg.CorrelationWindow
+	changes := s.changesSvc.Between(models.ChangeSourceAPI, since.Add(-window), until.Add(window))
+
+	now := time.Now().UTC()
+	found := make([]*models.DriftEvent, 0)
+	for i := range events {
+		event := &events[i]
+		if s.actors[event.Actor.GetId()] || s.explained(event, changes) {
+			continue
+		}
+		found = append(found, newDriftEvent(event, now))
+	}
+
+	s.mu.Lock()
+	s.checkedUntil = until
+	s.events = append(s.events, found...)
+	if len(s.events) > maxEvents {
+		s.events = append([]*models.DriftEvent(nil), s.events[len(s.events)-maxEvents:]...)
+	}
+	s.mu.Unlock()
+
+	for _, event := range found {
+		s.webhooksSvc.Publish(ctx, models.WebhookEventDriftDetected, event)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Checked Okta for out-of-band changes",
+		"since", since,
+		"until", until,
+		"eventCount", len(events),
+		"driftCount", len(found),
+	)
+	return nil
+}
+
+// GetReport lists the drift events matching filter, newest first.
+func (s *Service) GetReport(ctx context.Context, filter *models.DriftFilter) *models.DriftReport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	report := &models.DriftReport{
+		CheckedUntil: s.checkedUntil,
+		ByEventType:  make(map[string]int),
+		ByActor:      make(map[string]int),
+		Events:       make([]*models.DriftEvent, 0),
+	}
+
+	for i := len(s.events) - 1; i >= 0; i-- {
+		event := s.events[i]
+		if !matches(event, filter) {
+			continue
+		}
+
+		report.Events = append(report.Events, event)
+		report.ByEventType[event.EventType]++
+		report.ByActor[actorName(event.Actor)]++
+	}
+	report.Total = len(report.Events)
+
+	logger.FromContext(ctx, s.log).Infow("Drift report built", "total", report.Total)
+	return report
+}
+
+// explained reports whether a mutation through the API touched one of the
+// event's targets close enough in time to be the change the event logged.
+func (s *Service) explained(event *okta.LogEvent, changes []*models.Change) bool {
+	published := event.GetPublished()
+	for _, change := range changes {
+		if published.Sub(change.OccurredAt).Abs() > s.cfg.CorrelationWindow {
+			continue
+		}
+
+		ids := []string{change.ResourceID}
+		if params, ok := change.Details["params"].(map[string]string); ok {
+			for _, id := range params {
+				ids = append(ids, id)
+			}
+		}
+		for _, target := range event.Target {
+			if target.GetId() != "" && slices.Contains(ids, target.GetId()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matches(event *models.DriftEvent, filter *models.DriftFilter) bool {
+	switch {
+	case !filter.Since.IsZero() && event.Published.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !event.Published.Before(filter.Until):
+		return false
+	case filter.EventType != "" && event.EventType != filter.EventType:
+		return false
+	case filter.ActorID != "" && event.Actor.ID != filter.ActorID:
+		return false
+	}
+	return true
+}
+
+func newDriftEvent(event *okta.LogEvent, detectedAt time.Time) *models.DriftEvent {
+	drift := &models.DriftEvent{
+		ID:             event.GetUuid(),
+		EventType:      event.GetEventType(),
+		DisplayMessage: event.GetDisplayMessage(),
+		Published:      event.GetPublished(),
+		DetectedAt:     detectedAt,
+		Actor: &models.DriftParty{
+			ID:          event.Actor.GetId(),
+			Type:        event.Actor.GetType(),
+			AlternateID: event.Actor.GetAlternateId(),
+			DisplayName: event.Actor.GetDisplayName(),
+		},
+		Targets: make([]*models.DriftParty, 0, len(event.Target)),
+	}
+
+	for _, target := range event.Target {
+		drift.Targets = append(drift.Targets, &models.DriftParty{
+			ID:          target.GetId(),
+			Type:        target.GetType(),
+			AlternateID: target.GetAlternateId(),
+			DisplayName: target.GetDisplayName(),
+		})
+	}
+	return drift
+}
+
+func actorName(actor *models.DriftParty) string {
+	if actor.AlternateID != "" {
+		return actor.AlternateID
+	}
+	return actor.ID
+}
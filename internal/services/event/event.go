@@ -0,0 +1,139 @@
+package event_service
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// Service is the bus live events are published on and streamed from. It
+// keeps the latest events so a client that reconnects can resume after the
+// last one it saw. Publishing never waits for subscribers: one whose buffer
+// is full is dropped, and is expected to reconnect and resume.
+type Service struct {
+	log *zap.SugaredLogger
+	cfg *config.EventsConfig
+
+	mu       sync.Mutex
+	sequence int64
+	// recent holds the latest events, oldest first.
+	recent      []*models.StreamEvent
+	subscribers map[*Subscription]bool
+}
+
+// Subscription receives the events of the types it asked for. Events is
+// closed when the subscription is cancelled, or dropped for falling behind.
+type Subscription struct {
+	Events <-chan *models.StreamEvent
+
+	events chan *models.StreamEvent
+	types  []string
+}
+
+func New(log *zap.SugaredLogger, cfg *config.EventsConfig) *Service {
+	return &Service{log: log, cfg: cfg, subscribers: make(map[*Subscription]bool)}
+}
+
+// Publish sends an event to every subscriber of its type, stamped with the
+// next ID, the current time and the request ID from ctx.
+func (s *Service) Publish(ctx context.Context, eventType, source string, data any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sequence++
+	event := &models.StreamEvent{
+		ID:         strconv.FormatInt(s.sequence, 10),
+		Type:       eventType,
+		Source:     source,
+		OccurredAt: time.Now().UTC(),
+		RequestID:  logger.RequestID(ctx),
+		Data:       data,
+	}
+
+	s.recent = append(s.recent, event)
+	// Trimming in batches keeps appends cheap once the buffer is full.
+	if len(s.recent) > s.cfg.Replay+s.cfg.Replay/10 {
+		s.recent = append([]*models.StreamEvent(nil), s.recent[len(s.recent)-s.cfg.Replay:]...)
+	}
+
+	for subscription := range s.subscribers {
+		if !subscription.wants(eventType) {
+			continue
+		}
+		select {
+		case subscription.events <- event:
+		default:
+			s.drop(subscription)
+			logger.FromContext(ctx, s.log).Infow("Dropped a live event subscriber that fell behind", "eventId", event.ID)
+		}
+	}
+}
+
+// Subscribe starts a subscription to the events of types, or of every type
+// when types is empty. With lastEventID, the kept events after it are
+// returned to be sent first; missed reports that some events after it are
+// no longer kept, or that the ID is from before a restart.
+func (s *Service) Subscribe(types []string, lastEventID string) (subscription *Subscription, replay []*models.StreamEvent, missed bool) {
+	events := make(chan *models.StreamEvent, s.cfg.SubscriberBuffer)
+	subscription = &Subscription{Events: events, events: events, types: types}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.subscribers[subscription] = true
+	if lastEventID == "" {
+		return subscription, nil, false
+	}
+
+	after, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil || after < 0 || after > s.sequence {
+		return subscription, nil, true
+	}
+
+	first := s.sequence + 1
+	if len(s.recent) > 0 {
+		first = s.sequence - int64(len(s.recent)) + 1
+	}
+	missed = after+1 < first
+
+	for _, event := range s.recent[max(after+1-first, 0):] {
+		if subscription.wants(event.Type) {
+			replay = append(replay, event)
+		}
+	}
+	return subscription, replay, missed
+}
+
+// Unsubscribe cancels the subscription. It is safe to call more than once.
+func (s *Service) Unsubscribe(subscription *Subscription) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drop(subscription)
+}
+
+// Subscribers counts the open subscriptions.
+func (s *Service) Subscribers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.subscribers)
+}
+
+// drop removes the subscription and closes its channel. Callers hold mu.
+func (s *Service) drop(subscription *Subscription) {
+	if s.subscribers[subscription] {
+		delete(s.subscribers, subscription)
+		close(subscription.events)
+	}
+}
+
+func (sub *Subscription) wants(eventType string) bool {
+	return len(sub.types) == 0 || slices.Contains(sub.types, eventType)
+}
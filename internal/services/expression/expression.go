@@ -0,0 +1,158 @@
+package expression_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/expression"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+)
+
+var (
+	ErrNoExpression      = errors.New("one of expression and groupRuleId is required")
+	ErrTwoExpressions    = errors.New("expression and groupRuleId cannot both be set")
+	ErrTwoUsers          = errors.New("userId cannot be combined with profile or groups")
+	ErrGroupRuleNotFound = errors.New("group rule not found")
+)
+
+// Service evaluates Okta Expression Language expressions, such as those of
+// group rules and profile mappings, against a user without changing anything
+// in Okta, so mistakes show before the expression is deployed.
+type Service struct {
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	usersSvc *user_service.Service
+}
+
+func New(log *zap.SugaredLogger, client *okta.APIClient, usersSvc *user_service.Service) *Service {
+	return &Service{log: log, client: client, usersSvc: usersSvc}
+}
+
+// Evaluate evaluates the expression against the user. An expression that
+// cannot be parsed or evaluated is reported in the result rather than
+// returned as an error.
+func (s *Service) Evaluate(
+	ctx context.Context, req *models.EvaluateExpressionRequest,
+) (*models.ExpressionEvaluation, error) {
+	switch {
+	case req.Expression == "" && req.GroupRuleID == "":
+		return nil, ErrNoExpression
+	case req.Expression != "" && req.GroupRuleID != "":
+		return nil, ErrTwoExpressions
+	case req.UserID != "" && (req.Profile != nil || req.Groups != nil):
+		return nil, ErrTwoUsers
+	}
+
+	result := &models.ExpressionEvaluation{
+		Expression:  req.Expression,
+		GroupRuleID: req.GroupRuleID,
+		UserID:      req.UserID,
+	}
+	if req.GroupRuleID != "" {
+		src, err := s.groupRuleExpression(ctx, req.GroupRuleID)
+		if err != nil {
+			return nil, err
+		}
+		result.Expression = src
+	}
+
+	env, err := s.env(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Evaluating expression",
+		"groupRuleId", req.GroupRuleID, "userId", req.UserID, "length", len(result.Expression),
+	)
+
+	expr, err := expression.Parse(result.Expression)
+	if err != nil {
+		result.Error = expressionError(models.ExpressionErrorParse, err)
+		return result, nil
+	}
+	result.Valid = true
+
+	value, err := expr.Eval(env)
+	if err != nil {
+		result.Error = expressionError(models.ExpressionErrorEvaluation, err)
+		return result, nil
+	}
+	result.Result = value
+	result.Type = expression.TypeOf(value)
+
+	if _, ok := value.(bool); req.GroupRuleID != "" && !ok {
+		result.Error = &models.ExpressionError{
+			Kind:     models.ExpressionErrorEvaluation,
+			Message:  fmt.Sprintf("a group rule expression must evaluate to a boolean, got %s", result.Type),
+			Position: 1,
+		}
+	}
+	return result, nil
+}
+
+// env is the user the expression is evaluated against: the one named by
+// UserID, read from Okta with their groups, or the profile and groups of the
+// request.
+func (s *Service) env(ctx context.Context, req *models.EvaluateExpressionRequest) (*expression.Env, error) {
+	if req.UserID == "" {
+		profile := req.Profile
+		if profile == nil {
+			profile = make(map[string]any)
+		}
+		env := &expression.Env{Vars: map[string]map[string]any{"user": profile}}
+		for _, group := range req.Groups {
+			env.Groups = append(env.Groups, expression.Group{ID: group.ID, Name: group.Name})
+		}
+		return env, nil
+	}
+
+	profile, err := s.usersSvc.GetUserProfile(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.usersSvc.GetUserGroups(ctx, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &expression.Env{Vars: map[string]map[string]any{"user": profile}}
+	for _, group := range groups {
+		env.Groups = append(env.Groups, expression.Group{ID: group.ID, Name: group.Name})
+	}
+	return env, nil
+}
+
+func (s *Service) groupRuleExpression(ctx context.Context, ruleID string) (string, error) {
+	rule, response, err := s.client.GroupAPI.GetGroupRule(ctx, ruleID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get group rule from Okta", zap.Error(err),
+			"groupRuleId", ruleID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return "", ErrGroupRuleNotFound
+		}
+		return "", fmt.Errorf("failed to get group rule from Okta: %w", err)
+	}
+
+	if conditions := rule.Conditions; conditions != nil && conditions.Expression != nil {
+		return conditions.Expression.GetValue(), nil
+	}
+	return "", nil
+}
+
+func expressionError(kind string, err error) *models.ExpressionError {
+	var exprErr *expression.Error
+	if errors.As(err, &exprErr) {
+		return &models.ExpressionError{Kind: kind, Message: exprErr.Message, Position: exprErr.Position}
+	}
+	return &models.ExpressionError{Kind: kind, Message: err.Error()}
+}
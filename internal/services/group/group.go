@@ -2,24 +2,139 @@ package group_service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	"github.com/iamBelugaa/iam/internal/models"
+	grouppolicy_service "github.com/iamBelugaa/iam/internal/services/grouppolicy"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
 	"github.com/okta/okta-sdk-golang/v5/okta"
 	"go.uber.org/zap"
 )
 
+// stateKey is the object holding the join policies and membership
+// expirations.
+const stateKey = "state.json"
+
+var (
+	ErrInvalidJoinPolicy = errors.New("join policy must be one of OPEN, APPROVAL, INVITE_ONLY or HIDDEN")
+	ErrGroupNotFound     = errors.New("group not found")
+)
+
 type Service struct {
-	client *okta.APIClient
-	log    *zap.SugaredLogger
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	sodSvc   *sod_service.Service
+	guestSvc *guest_service.Service
+	// policySvc holds names, descriptions and tags to the naming policy. It
+	// is nil when the org has none.
+	policySvc *grouppolicy_service.Service
+	// hooks run around creates, updates, deletes and membership changes. It
+	// is nil when the org runs none.
+	hooks *hooks.Registry
+	// store keeps state across restarts, stored as one object on every
+	// change. It is nil when state is only kept in memory.
+	store objectstore.Store
+
+	mu    sync.RWMutex
+	state state
+}
+
+// state is what Okta does not hold for groups.
+type state struct {
+	// Expirations tracks time-bound memberships as groupID -> userID -> expiry.
+	Expirations map[string]map[string]time.Time `json:"expirations"`
+	// JoinPolicies holds the join policy of every group that is not INVITE_ONLY.
+	JoinPolicies map[string]string `json:"joinPolicies"`
+}
+
+// New returns the service with the join policies and membership expirations
+// read from store, which may be nil.
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, store objectstore.Store, sodSvc *sod_service.Service,
+	guestSvc *guest_service.Service, policySvc *grouppolicy_service.Service, hooks *hooks.Registry,
+) (*Service, error) {
+	s := &Service{
+		log:       log,
+		client:    client,
+		sodSvc:    sodSvc,
+		guestSvc:  guestSvc,
+		policySvc: policySvc,
+		hooks:     hooks,
+		store:     store,
+		state: state{
+			Expirations:  make(map[string]map[string]time.Time),
+			JoinPolicies: make(map[string]string),
+		},
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read group state: %w", err)
+	}
+	if err := json.Unmarshal(object.Data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to decode group state: %w", err)
+	}
+	if s.state.Expirations == nil {
+		s.state.Expirations = make(map[string]map[string]time.Time)
+	}
+	if s.state.JoinPolicies == nil {
+		s.state.JoinPolicies = make(map[string]string)
+	}
+	return s, nil
 }
 
-func New(log *zap.SugaredLogger, client *okta.APIClient) *Service {
-	return &Service{log: log, client: client}
+// save stores the state. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("failed to encode group state: %w", err)
+	}
+	if err := s.store.Put(ctx, stateKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store group state: %w", err)
+	}
+	return nil
 }
 
 func (s *Service) CreateGroup(ctx context.Context, req *models.CreateGroupRequest) (*models.Group, error) {
-	s.log.Infow("Creating group in Okta", "name", req.Name)
+	logger.FromContext(ctx, s.log).Infow("Creating group in Okta", "name", req.Name)
+
+	if req.JoinPolicy != "" && !models.IsJoinPolicy(req.JoinPolicy) {
+		return nil, ErrInvalidJoinPolicy
+	}
+
+	if err := s.policySvc.Enforce(ctx, &models.CheckGroupPolicyRequest{
+		Name:        req.Name,
+		Description: req.Description,
+		Profile:     req.Profile,
+	}); err != nil {
+		return nil, err
+	}
+
+	event := &hooks.Event{Operation: hooks.CreateGroup, Input: req}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return nil, err
+	}
 
 	profile := okta.GroupProfile{
 		Name:        &req.Name,
@@ -34,52 +149,77 @@ func (s *Service) CreateGroup(ctx context.Context, req *models.CreateGroupReques
 		CreateGroup(ctx).Group(okta.Group{Profile: &profile}).Execute()
 
 	if err != nil {
-		s.log.Infow("Failed to create group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to create group in Okta", zap.Error(err),
 			"name", req.Name,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to create group in Okta: %w", err)
 	}
 
-	s.log.Infow("Group created successfully in Okta", "groupId", *group.Id, "name", req.Name)
-	return models.ConvertOktaGroupToModel(group), nil
+	if req.JoinPolicy != "" {
+		if err := s.setJoinPolicy(ctx, group.GetId(), req.JoinPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group created successfully in Okta", "groupId", *group.Id, "name", req.Name)
+
+	created := s.convertGroup(group)
+	s.runAfter(ctx, event, created.ID, created)
+	return created, nil
 }
 
 func (s *Service) GetGroup(ctx context.Context, groupID string) (*models.Group, error) {
-	s.log.Infow("Getting group from Okta", "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Getting group from Okta", "groupId", groupID)
 
 	group, response, err := s.client.GroupAPI.GetGroup(ctx, groupID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get group from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get group from Okta", zap.Error(err),
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrGroupNotFound
+		}
 		return nil, fmt.Errorf("failed to get group from Okta: %w", err)
 	}
 
-	return models.ConvertOktaGroupToModel(group), nil
+	return s.convertGroup(group), nil
 }
 
 func (s *Service) GetGroups(ctx context.Context) ([]*models.Group, error) {
-	s.log.Infow("Getting groups from Okta")
+	logger.FromContext(ctx, s.log).Infow("Getting groups from Okta")
 
 	groups, _, err := s.client.GroupAPI.ListGroups(ctx).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get groups from Okta", zap.Error(err))
+		logger.FromContext(ctx, s.log).Infow("Failed to get groups from Okta", zap.Error(err))
 		return nil, fmt.Errorf("failed to get groups from Okta: %w", err)
 	}
 
 	result := make([]*models.Group, len(groups))
 	for i := range groups {
-		result[i] = models.ConvertOktaGroupToModel(&groups[i])
+		result[i] = s.convertGroup(&groups[i])
 	}
 
-	s.log.Infow("Groups retrieved successfully from Okta", "count", len(result))
+	logger.FromContext(ctx, s.log).Infow("Groups retrieved successfully from Okta", "count", len(result))
 	return result, nil
 }
 
 func (s *Service) UpdateGroup(ctx context.Context, groupID string, req *models.UpdateGroupRequest) (*models.Group, error) {
-	s.log.Infow("Updating group in Okta", zap.String("groupId", groupID))
+	logger.FromContext(ctx, s.log).Infow("Updating group in Okta", zap.String("groupId", groupID))
+
+	if req.JoinPolicy != "" && !models.IsJoinPolicy(req.JoinPolicy) {
+		return nil, ErrInvalidJoinPolicy
+	}
+
+	if err := s.enforcePolicyOnUpdate(ctx, groupID, req); err != nil {
+		return nil, err
+	}
+
+	event := &hooks.Event{Operation: hooks.UpdateGroup, ResourceID: groupID, Input: req}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return nil, err
+	}
 
 	var updateNeeded bool
 	var profile okta.GroupProfile
@@ -100,106 +240,372 @@ func (s *Service) UpdateGroup(ctx context.Context, groupID string, req *models.U
 	}
 
 	if !updateNeeded {
-		return s.GetGroup(ctx, groupID)
+		group, err := s.GetGroup(ctx, groupID)
+		if err != nil {
+			return nil, err
+		}
+
+		if req.JoinPolicy != "" {
+			if err := s.setJoinPolicy(ctx, groupID, req.JoinPolicy); err != nil {
+				return nil, err
+			}
+			group.JoinPolicy = req.JoinPolicy
+		}
+		return group, nil
 	}
 
 	updatedGroup, response, err := s.client.GroupAPI.
 		ReplaceGroup(ctx, groupID).Group(okta.Group{Profile: &profile}).Execute()
 	if err != nil {
-		s.log.Infow("Failed to update group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to update group in Okta", zap.Error(err),
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to update group in Okta: %w", err)
 	}
 
-	s.log.Info("Group updated successfully in Okta", "groupId", groupID)
-	return models.ConvertOktaGroupToModel(updatedGroup), nil
+	if req.JoinPolicy != "" {
+		if err := s.setJoinPolicy(ctx, groupID, req.JoinPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Info("Group updated successfully in Okta", "groupId", groupID)
+
+	updated := s.convertGroup(updatedGroup)
+	s.runAfter(ctx, event, groupID, updated)
+	return updated, nil
 }
 
 func (s *Service) DeleteGroup(ctx context.Context, groupID string) error {
-	s.log.Infow("Deleting group from Okta", "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Deleting group from Okta", "groupId", groupID)
+
+	event := &hooks.Event{Operation: hooks.DeleteGroup, ResourceID: groupID}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return err
+	}
 
 	response, err := s.client.GroupAPI.DeleteGroup(ctx, groupID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to delete group from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to delete group from Okta", zap.Error(err),
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to delete group from Okta: %w", err)
 	}
 
-	s.log.Infow("Group deleted successfully from Okta", "groupId", groupID)
+	s.mu.Lock()
+	delete(s.state.JoinPolicies, groupID)
+	delete(s.state.Expirations, groupID)
+	err = s.save(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group deleted successfully from Okta", "groupId", groupID)
+	s.runAfter(ctx, event, groupID, nil)
 	return nil
 }
 
-func (s *Service) AddUserToGroup(ctx context.Context, groupID, userID string) error {
-	s.log.Infow("Adding user to group in Okta", "groupId", groupID, "userId", userID)
+// enforcePolicyOnUpdate checks the group as the update leaves it: fields the
+// request does not set keep their current value.
+func (s *Service) enforcePolicyOnUpdate(ctx context.Context, groupID string, req *models.UpdateGroupRequest) error {
+	if s.policySvc.Empty() || (req.Name == "" && req.Description == "" && len(req.Profile) == 0) {
+		return nil
+	}
+
+	current, err := s.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+
+	check := &models.CheckGroupPolicyRequest{
+		Name:        current.Name,
+		Description: current.Description,
+		Profile:     current.Profile,
+	}
+	if req.Name != "" {
+		check.Name = req.Name
+	}
+	if req.Description != "" {
+		check.Description = req.Description
+	}
+	if len(req.Profile) > 0 {
+		check.Profile = req.Profile
+	}
+
+	return s.policySvc.Enforce(ctx, check)
+}
+
+// JoinPolicy returns the group's join policy.
+func (s *Service) JoinPolicy(groupID string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if policy, ok := s.state.JoinPolicies[groupID]; ok {
+		return policy
+	}
+	return models.JoinPolicyInviteOnly
+}
+
+func (s *Service) setJoinPolicy(ctx context.Context, groupID, policy string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy == models.JoinPolicyInviteOnly {
+		delete(s.state.JoinPolicies, groupID)
+	} else {
+		s.state.JoinPolicies[groupID] = policy
+	}
+	return s.save(ctx)
+}
+
+// SetJoinPolicy changes the group's join policy.
+func (s *Service) SetJoinPolicy(ctx context.Context, groupID, policy string) error {
+	if !models.IsJoinPolicy(policy) {
+		return ErrInvalidJoinPolicy
+	}
+
+	if err := s.setJoinPolicy(ctx, groupID, policy); err != nil {
+		return err
+	}
+	s.log.Infow("Group join policy updated", "groupId", groupID, "joinPolicy", policy)
+	return nil
+}
+
+func (s *Service) convertGroup(group *okta.Group) *models.Group {
+	result := models.ConvertOktaGroupToModel(group)
+	result.JoinPolicy = s.JoinPolicy(result.ID)
+	return result
+}
+
+// AddUserToGroup adds the user to the group. When expiresAt is set the
+// membership is time-bound and will be removed by the expiry worker; otherwise
+// any previously recorded expiry is cleared and the membership is permanent.
+// Additions that break an enforced SoD policy return a *sod_service.ViolationError,
+// and guests can only join eligible groups (guest_service.ErrGroupNotEligible).
+func (s *Service) AddUserToGroup(ctx context.Context, groupID, userID string, expiresAt *time.Time) error {
+	logger.FromContext(ctx, s.log).Infow("Adding user to group in Okta", "groupId", groupID, "userId", userID, "expiresAt", expiresAt)
+
+	if err := s.guestSvc.CheckMembership(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	if err := s.sodSvc.CheckMembership(ctx, groupID, userID); err != nil {
+		return err
+	}
+
+	event := &hooks.Event{Operation: hooks.AddGroupMember, ResourceID: groupID, MemberID: userID}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return err
+	}
 
 	response, err := s.client.GroupAPI.AssignUserToGroup(ctx, groupID, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to add user to group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to add user to group in Okta", zap.Error(err),
 			"groupId", groupID,
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to add user to group in Okta: %w", err)
 	}
 
-	s.log.Infow("User added to group successfully in Okta", "groupId", groupID, "userId", userID)
+	if err := s.setMembershipExpiry(ctx, groupID, userID, expiresAt); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User added to group successfully in Okta", "groupId", groupID, "userId", userID)
+	s.runAfter(ctx, event, groupID, nil)
 	return nil
 }
 
 func (s *Service) RemoveUserFromGroup(ctx context.Context, groupID, userID string) error {
-	s.log.Infow("Removing user from group in Okta", "groupId", groupID, "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Removing user from group in Okta", "groupId", groupID, "userId", userID)
+
+	event := &hooks.Event{Operation: hooks.RemoveGroupMember, ResourceID: groupID, MemberID: userID}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return err
+	}
 
 	response, err := s.client.GroupAPI.UnassignUserFromGroup(ctx, groupID, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to remove user from group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to remove user from group in Okta", zap.Error(err),
 			"groupId", groupID,
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to remove user from group in Okta: %w", err)
 	}
 
-	s.log.Infow("User removed from group successfully in Okta", "groupId", groupID, "userId", userID)
+	if err := s.setMembershipExpiry(ctx, groupID, userID, nil); err != nil {
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User removed from group successfully in Okta", "groupId", groupID, "userId", userID)
+	s.runAfter(ctx, event, groupID, nil)
 	return nil
 }
 
+// runAfter runs the after hooks of event once its operation succeeded. Their
+// errors are only logged, as the change has been made.
+func (s *Service) runAfter(ctx context.Context, event *hooks.Event, resourceID string, result any) {
+	event.ResourceID = resourceID
+	event.Result = result
+	if err := s.hooks.RunAfter(ctx, event); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to run after hooks", zap.Error(err),
+			"operation", event.Operation,
+			"resourceId", resourceID,
+		)
+	}
+}
+
+// GetMembershipExpirations returns the expiry of every time-bound membership
+// in the group, keyed by user ID.
+func (s *Service) GetMembershipExpirations(groupID string) map[string]time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]time.Time, len(s.state.Expirations[groupID]))
+	for userID, expiresAt := range s.state.Expirations[groupID] {
+		result[userID] = expiresAt
+	}
+
+	return result
+}
+
+// ExpiredMemberships returns every time-bound membership whose expiry is at or before now.
+func (s *Service) ExpiredMemberships(now time.Time) []*models.MembershipExpiry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.MembershipExpiry
+	for groupID, members := range s.state.Expirations {
+		for userID, expiresAt := range members {
+			if !expiresAt.After(now) {
+				result = append(result, &models.MembershipExpiry{
+					GroupID: groupID, UserID: userID, ExpiresAt: expiresAt,
+				})
+			}
+		}
+	}
+
+	return result
+}
+
+func (s *Service) setMembershipExpiry(ctx context.Context, groupID, userID string, expiresAt *time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expirations := s.state.Expirations
+	if expiresAt == nil {
+		if _, ok := expirations[groupID][userID]; !ok {
+			return nil
+		}
+		delete(expirations[groupID], userID)
+		if len(expirations[groupID]) == 0 {
+			delete(expirations, groupID)
+		}
+		return s.save(ctx)
+	}
+
+	if expirations[groupID] == nil {
+		expirations[groupID] = make(map[string]time.Time)
+	}
+	expirations[groupID][userID] = *expiresAt
+	return s.save(ctx)
+}
+
 func (s *Service) GetGroupMembers(ctx context.Context, groupID string) ([]*models.User, error) {
-	s.log.Infow("Getting group members from Okta", "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Getting group members from Okta", "groupId", groupID)
 
 	users, response, err := s.client.GroupAPI.ListGroupUsers(ctx, groupID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get group members from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get group members from Okta", zap.Error(err),
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to get group members from Okta: %w", err)
 	}
 
 	result := make([]*models.User, len(users))
-	for i, user := range users {
-		result[i] = models.ConvertOktaUserToModel(&okta.User{
-			Id:                    user.Id,
-			Created:               user.Created,
-			Activated:             user.Activated,
-			LastLogin:             user.LastLogin,
-			Credentials:           user.Credentials,
-			LastUpdated:           user.LastUpdated,
-			PasswordChanged:       user.PasswordChanged,
-			Profile:               user.Profile,
-			RealmId:               user.RealmId,
-			Status:                user.Status,
-			StatusChanged:         user.StatusChanged,
-			TransitioningToStatus: user.TransitioningToStatus,
-			Type:                  user.Type,
-			Links:                 user.Links,
-			AdditionalProperties:  user.AdditionalProperties,
-		})
+	for i := range users {
+		result[i] = convertGroupMember(&users[i])
 	}
 
-	s.log.Infow("Group members retrieved successfully from Okta", "groupId", groupID, "memberCount", len(result))
+	logger.FromContext(ctx, s.log).Infow("Group members retrieved successfully from Okta", "groupId", groupID, "memberCount", len(result))
 	return result, nil
 }
+
+// StreamGroups passes every group to emit, fetching pages from Okta only as
+// the previous page has been emitted.
+func (s *Service) StreamGroups(ctx context.Context, emit func(*models.Group) error) error {
+	logger.FromContext(ctx, s.log).Infow("Streaming groups from Okta")
+
+	var count int
+	groups, response, err := s.client.GroupAPI.ListGroups(ctx).Execute()
+	if err == nil {
+		err = pagination.Each(groups, response, func(page []okta.Group) error {
+			for i := range page {
+				if err := emit(s.convertGroup(&page[i])); err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to stream groups from Okta", zap.Error(err), "emittedCount", count)
+		return fmt.Errorf("failed to stream groups from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Groups streamed successfully from Okta", "count", count)
+	return nil
+}
+
+// StreamGroupMembers passes every member of groupID to emit, page by page.
+func (s *Service) StreamGroupMembers(ctx context.Context, groupID string, emit func(*models.User) error) error {
+	logger.FromContext(ctx, s.log).Infow("Streaming group members from Okta", "groupId", groupID)
+
+	var count int
+	users, response, err := s.client.GroupAPI.ListGroupUsers(ctx, groupID).Execute()
+	if err == nil {
+		err = pagination.Each(users, response, func(page []okta.GroupMember) error {
+			for i := range page {
+				if err := emit(convertGroupMember(&page[i])); err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to stream group members from Okta", zap.Error(err), "groupId", groupID, "emittedCount", count)
+		return fmt.Errorf("failed to stream group members from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group members streamed successfully from Okta", "groupId", groupID, "memberCount", count)
+	return nil
+}
+
+func convertGroupMember(user *okta.GroupMember) *models.User {
+	return models.ConvertOktaUserToModel(&okta.User{
+		Id:                    user.Id,
+		Created:               user.Created,
+		Activated:             user.Activated,
+		LastLogin:             user.LastLogin,
+		Credentials:           user.Credentials,
+		LastUpdated:           user.LastUpdated,
+		PasswordChanged:       user.PasswordChanged,
+		Profile:               user.Profile,
+		RealmId:               user.RealmId,
+		Status:                user.Status,
+		StatusChanged:         user.StatusChanged,
+		TransitioningToStatus: user.TransitioningToStatus,
+		Type:                  user.Type,
+		Links:                 user.Links,
+		AdditionalProperties:  user.AdditionalProperties,
+	})
+}
@@ -0,0 +1,138 @@
+package group_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// maxConcurrentExpansions bounds the number of members expanded at once.
+const maxConcurrentExpansions = 10
+
+var ErrInvalidMemberExpand = errors.New("expand must be a comma-separated list of factors and groups")
+
+// ParseMemberExpand reads the expansions of a member listing, given comma
+// separated or repeated.
+func ParseMemberExpand(values []string) ([]string, error) {
+	var expand []string
+	for _, value := range values {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if name != models.MemberExpandFactors && name != models.MemberExpandGroups {
+				return nil, ErrInvalidMemberExpand
+			}
+			if !slices.Contains(expand, name) {
+				expand = append(expand, name)
+			}
+		}
+	}
+	return expand, nil
+}
+
+// ExpandGroupMembers adds the expansions named to each member, with
+// maxConcurrentExpansions workers expanding one member at a time. An expansion that fails for a
+// member does not stop the others; it is left out and its error noted in the
+// member's ExpandErrors.
+func (s *Service) ExpandGroupMembers(ctx context.Context, members []*models.GroupMember, expand []string) {
+	logger.FromContext(ctx, s.log).Infow("Expanding group members", "memberCount", len(members), "expand", expand)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed int
+		queue  = make(chan *models.GroupMember)
+	)
+
+	for range min(maxConcurrentExpansions, len(members)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for member := range queue {
+				for _, name := range expand {
+					if err := s.expandMember(ctx, member, name); err != nil {
+						logger.FromContext(ctx, s.log).Infow("Failed to expand group member", zap.Error(err),
+							"userId", member.ID, "expand", name,
+						)
+						mu.Lock()
+						if member.ExpandErrors == nil {
+							member.ExpandErrors = make(map[string]string)
+						}
+						member.ExpandErrors[name] = err.Error()
+						failed++
+						mu.Unlock()
+					}
+				}
+			}
+		}()
+	}
+
+	for _, member := range members {
+		queue <- member
+	}
+	close(queue)
+	wg.Wait()
+
+	logger.FromContext(ctx, s.log).Infow("Group members expanded", "memberCount", len(members), "failedCount", failed)
+}
+
+func (s *Service) expandMember(ctx context.Context, member *models.GroupMember, name string) error {
+	switch name {
+	case models.MemberExpandFactors:
+		factors, err := s.memberFactors(ctx, member.ID)
+		if err != nil {
+			return err
+		}
+		member.Factors = factors
+	case models.MemberExpandGroups:
+		groups, _, err := s.client.UserAPI.ListUserGroups(ctx, member.ID).Execute()
+		if err != nil {
+			return fmt.Errorf("failed to get user groups from Okta: %w", err)
+		}
+		member.Groups = make([]models.Group, len(groups))
+		for i := range groups {
+			member.Groups[i] = *s.convertGroup(&groups[i])
+		}
+	}
+	return nil
+}
+
+// memberFactors lists the user's enrolled factors. Okta returns each factor
+// type as its own shape, so only the fields they share are kept.
+func (s *Service) memberFactors(ctx context.Context, userID string) (*models.FactorEnrollment, error) {
+	factors, _, err := s.client.UserFactorAPI.ListFactors(ctx, userID).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user factors from Okta: %w", err)
+	}
+
+	enrollment := &models.FactorEnrollment{Factors: make([]*models.Factor, 0, len(factors))}
+	for i := range factors {
+		data, err := json.Marshal(factors[i].GetActualInstance())
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode user factor: %w", err)
+		}
+
+		var factor models.Factor
+		if err := json.Unmarshal(data, &factor); err != nil {
+			return nil, fmt.Errorf("failed to decode user factor: %w", err)
+		}
+		enrollment.Factors = append(enrollment.Factors, &factor)
+	}
+
+	enrollment.Enrolled = slices.ContainsFunc(enrollment.Factors, func(factor *models.Factor) bool {
+		return factor.Status == models.FactorStatusActive
+	})
+	return enrollment, nil
+}
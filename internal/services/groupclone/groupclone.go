@@ -0,0 +1,180 @@
+package groupclone_service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// Service creates groups as copies of existing ones, optionally with their
+// members and app assignments. Copies are made through the group and app
+// services, so naming policies, hooks, SoD policies and guest eligibility
+// apply to them as to any other change.
+type Service struct {
+	log       *zap.SugaredLogger
+	cfg       *config.GroupCloneConfig
+	groupsSvc *group_service.Service
+	appsSvc   *app_service.Service
+	jobsSvc   *job_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.GroupCloneConfig, groupsSvc *group_service.Service,
+	appsSvc *app_service.Service, jobsSvc *job_service.Service,
+) *Service {
+	return &Service{log: log, cfg: cfg, groupsSvc: groupsSvc, appsSvc: appsSvc, jobsSvc: jobsSvc}
+}
+
+// cloneMember is a member of the source group, with the expiry of a
+// time-bound membership.
+type cloneMember struct {
+	userID    string
+	expiresAt *time.Time
+}
+
+// Clone creates a group named req.Name with the source group's description,
+// profile and join policy, then copies the members and app assignments
+// requested. When there are more of them than the job threshold they are
+// copied by a job, which the result carries. Members and apps that cannot be
+// copied are reported rather than failing the clone, as the group has been
+// created by then.
+func (s *Service) Clone(ctx context.Context, groupID string, req *models.CloneGroupRequest) (*models.GroupClone, error) {
+	source, err := s.groupsSvc.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	var members []*cloneMember
+	if req.CopyMembers {
+		expirations := s.groupsSvc.GetMembershipExpirations(groupID)
+		err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(user *models.User) error {
+			member := &cloneMember{userID: user.ID}
+			if expiresAt, ok := expirations[user.ID]; ok {
+				member.expiresAt = &expiresAt
+			}
+			members = append(members, member)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the members of the group to clone: %w", err)
+		}
+	}
+
+	var apps []*models.GroupApp
+	if req.CopyApps {
+		if apps, err = s.appsSvc.GetGroupAppAssignments(ctx, groupID); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Cloning group",
+		"sourceGroupId", groupID, "name", req.Name, "memberCount", len(members), "appCount", len(apps),
+	)
+
+	description := source.Description
+	if req.Description != "" {
+		description = req.Description
+	}
+	group, err := s.groupsSvc.CreateGroup(ctx, &models.CreateGroupRequest{
+		Name:        req.Name,
+		Description: description,
+		JoinPolicy:  source.JoinPolicy,
+		Profile:     maps.Clone(source.Profile),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.GroupClone{SourceGroupID: groupID, Group: group}
+	if len(members)+len(apps) <= s.cfg.JobThreshold {
+		s.copyMembers(ctx, result, members)
+		s.copyApps(ctx, result, apps)
+
+		logger.FromContext(ctx, s.log).Infow("Group cloned",
+			"sourceGroupId", groupID, "groupId", group.ID,
+			"copiedMembers", len(result.CopiedMembers), "failedMembers", len(result.FailedMembers),
+			"copiedApps", len(result.CopiedApps), "failedApps", len(result.FailedApps),
+		)
+		return result, nil
+	}
+
+	var steps []string
+	if req.CopyMembers {
+		steps = append(steps, models.GroupCloneStepMembers)
+	}
+	if req.CopyApps {
+		steps = append(steps, models.GroupCloneStepApps)
+	}
+	tracker := s.jobsSvc.Create(models.JobTypeGroupClone, models.ResourceTypeGroup, group.ID, steps)
+
+	logger.FromContext(ctx, s.log).Infow("Group cloned, copying its members and apps in a job",
+		"sourceGroupId", groupID, "groupId", group.ID, "jobId", tracker.JobID(),
+	)
+
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		copied := &models.GroupClone{SourceGroupID: groupID, Group: group}
+		if req.CopyMembers {
+			err := tracker.Step(models.GroupCloneStepMembers, func() (string, error) {
+				s.copyMembers(ctx, copied, members)
+				return fmt.Sprintf("Added %d members, skipped %d whose membership had ended, %d could not be added",
+					len(copied.CopiedMembers), len(copied.SkippedMembers), len(copied.FailedMembers)), ctx.Err()
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if req.CopyApps {
+			return tracker.Step(models.GroupCloneStepApps, func() (string, error) {
+				s.copyApps(ctx, copied, apps)
+				return fmt.Sprintf("Assigned %d apps, %d could not be assigned",
+					len(copied.CopiedApps), len(copied.FailedApps)), ctx.Err()
+			})
+		}
+		return nil
+	})
+
+	result.Job = tracker.Job()
+	return result, nil
+}
+
+// copyMembers adds the members to the clone, keeping the expiry of
+// time-bound memberships.
+func (s *Service) copyMembers(ctx context.Context, result *models.GroupClone, members []*cloneMember) {
+	now := time.Now()
+	for _, member := range members {
+		if member.expiresAt != nil && !member.expiresAt.After(now) {
+			result.SkippedMembers = append(result.SkippedMembers, member.userID)
+			continue
+		}
+		if err := s.groupsSvc.AddUserToGroup(ctx, result.Group.ID, member.userID, member.expiresAt); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to add member to cloned group", zap.Error(err),
+				"groupId", result.Group.ID, "userId", member.userID,
+			)
+			result.FailedMembers = append(result.FailedMembers, member.userID)
+			continue
+		}
+		result.CopiedMembers = append(result.CopiedMembers, member.userID)
+	}
+}
+
+// copyApps assigns the apps to the clone with the same priority and app
+// profile.
+func (s *Service) copyApps(ctx context.Context, result *models.GroupClone, apps []*models.GroupApp) {
+	for _, app := range apps {
+		if err := s.appsSvc.AssignGroup(ctx, app.App.ID, result.Group.ID, &app.Assignment); err != nil {
+			result.FailedApps = append(result.FailedApps, app.App.ID)
+			continue
+		}
+		result.CopiedApps = append(result.CopiedApps, app.App.ID)
+	}
+}
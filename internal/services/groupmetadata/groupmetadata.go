@@ -0,0 +1,293 @@
+package groupmetadata_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const metadataKey = "metadata.json"
+
+var (
+	ErrMetadataNotFound      = errors.New("group metadata not found")
+	ErrOwnerNotFound         = errors.New("user is not an owner of the group")
+	ErrInvalidClassification = errors.New("classification must be one of PUBLIC, INTERNAL, CONFIDENTIAL or RESTRICTED")
+	ErrInvalidTagFilter      = errors.New("tag filters must be key or key:value")
+)
+
+// Service keeps the metadata of groups that Okta group profiles cannot hold.
+// All of it is stored as one object on every change, so it survives a
+// restart.
+type Service struct {
+	log   *zap.SugaredLogger
+	store objectstore.Store
+
+	mu sync.Mutex
+	// metadata is keyed by group ID. It is read from the store on first use.
+	metadata map[string]*models.GroupMetadata
+	loaded   bool
+}
+
+func New(log *zap.SugaredLogger, store objectstore.Store) *Service {
+	return &Service{log: log, store: store, metadata: make(map[string]*models.GroupMetadata)}
+}
+
+func (s *Service) GetMetadata(ctx context.Context, groupID string) (*models.GroupMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	metadata, ok := s.metadata[groupID]
+	if !ok {
+		return nil, ErrMetadataNotFound
+	}
+	return copyMetadata(metadata), nil
+}
+
+// SetMetadata replaces the group's metadata, keeping its owners. The caller
+// checks that the group exists.
+func (s *Service) SetMetadata(
+	ctx context.Context, groupID string, req *models.SetGroupMetadataRequest,
+) (*models.GroupMetadata, error) {
+	if req.Classification != "" && !models.IsClassification(req.Classification) {
+		return nil, ErrInvalidClassification
+	}
+
+	metadata, err := s.update(ctx, groupID, func(metadata *models.GroupMetadata) error {
+		metadata.CostCenter = req.CostCenter
+		metadata.Classification = req.Classification
+		metadata.Tags = maps.Clone(req.Tags)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group metadata updated", "groupId", groupID)
+	return metadata, nil
+}
+
+// AddOwner makes the user an owner of the group. The caller checks that the
+// group exists.
+func (s *Service) AddOwner(ctx context.Context, groupID, userID string) (*models.GroupMetadata, error) {
+	metadata, err := s.update(ctx, groupID, func(metadata *models.GroupMetadata) error {
+		if !slices.Contains(metadata.Owners, userID) {
+			metadata.Owners = append(metadata.Owners, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group owner added", "groupId", groupID, "userId", userID)
+	return metadata, nil
+}
+
+func (s *Service) RemoveOwner(ctx context.Context, groupID, userID string) (*models.GroupMetadata, error) {
+	metadata, err := s.update(ctx, groupID, func(metadata *models.GroupMetadata) error {
+		index := slices.Index(metadata.Owners, userID)
+		if index < 0 {
+			return ErrOwnerNotFound
+		}
+		metadata.Owners = slices.Delete(metadata.Owners, index, index+1)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group owner removed", "groupId", groupID, "userId", userID)
+	return metadata, nil
+}
+
+// IsOwner reports whether the user owns the group.
+func (s *Service) IsOwner(ctx context.Context, groupID, userID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return false, err
+	}
+
+	metadata, ok := s.metadata[groupID]
+	return ok && userID != "" && slices.Contains(metadata.Owners, userID), nil
+}
+
+// update applies fn to a copy of the group's metadata, which is empty when
+// the group has none yet, and stores the result unless fn fails.
+func (s *Service) update(
+	ctx context.Context, groupID string, fn func(metadata *models.GroupMetadata) error,
+) (*models.GroupMetadata, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	metadata := &models.GroupMetadata{GroupID: groupID}
+	previous, existed := s.metadata[groupID]
+	if existed {
+		metadata = copyMetadata(previous)
+	}
+	if err := fn(metadata); err != nil {
+		return nil, err
+	}
+	metadata.LastUpdated = time.Now().UTC()
+
+	s.metadata[groupID] = metadata
+	if err := s.save(ctx); err != nil {
+		if existed {
+			s.metadata[groupID] = previous
+		} else {
+			delete(s.metadata, groupID)
+		}
+		return nil, err
+	}
+
+	return copyMetadata(metadata), nil
+}
+
+func (s *Service) DeleteMetadata(ctx context.Context, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	previous, ok := s.metadata[groupID]
+	if !ok {
+		return ErrMetadataNotFound
+	}
+
+	delete(s.metadata, groupID)
+	if err := s.save(ctx); err != nil {
+		s.metadata[groupID] = previous
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group metadata deleted", "groupId", groupID)
+	return nil
+}
+
+// Attach sets the metadata of each group that has any.
+func (s *Service) Attach(ctx context.Context, groups ...*models.Group) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		if metadata, ok := s.metadata[group.ID]; ok {
+			group.Metadata = copyMetadata(metadata)
+		}
+	}
+	return nil
+}
+
+// ParseTagFilters reads filters given as "key" or "key:value".
+func ParseTagFilters(values []string) ([]models.GroupTagFilter, error) {
+	filters := make([]models.GroupTagFilter, 0, len(values))
+	for _, value := range values {
+		key, tagValue, _ := strings.Cut(value, ":")
+		if key == "" {
+			return nil, ErrInvalidTagFilter
+		}
+		filters = append(filters, models.GroupTagFilter{Key: key, Value: tagValue})
+	}
+	return filters, nil
+}
+
+// Matches reports whether the attached metadata of group passes every
+// filter.
+func Matches(group *models.Group, filters []models.GroupTagFilter) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	if group.Metadata == nil {
+		return false
+	}
+
+	for _, filter := range filters {
+		var value string
+		switch filter.Key {
+		case "owner":
+			if len(group.Metadata.Owners) == 0 {
+				return false
+			}
+			if filter.Value != "" && !slices.Contains(group.Metadata.Owners, filter.Value) {
+				return false
+			}
+			continue
+		case "costCenter":
+			value = group.Metadata.CostCenter
+		case "classification":
+			value = group.Metadata.Classification
+		default:
+			value = group.Metadata.Tags[filter.Key]
+		}
+
+		if value == "" || (filter.Value != "" && value != filter.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// load reads the metadata from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, metadataKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read group metadata: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.metadata); err != nil {
+			return fmt.Errorf("failed to decode group metadata: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores all metadata. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode group metadata: %w", err)
+	}
+	if err := s.store.Put(ctx, metadataKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store group metadata: %w", err)
+	}
+	return nil
+}
+
+func copyMetadata(metadata *models.GroupMetadata) *models.GroupMetadata {
+	copied := *metadata
+	copied.Owners = slices.Clone(metadata.Owners)
+	copied.Tags = maps.Clone(metadata.Tags)
+	return &copied
+}
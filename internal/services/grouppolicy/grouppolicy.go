@@ -0,0 +1,197 @@
+package grouppolicy_service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// ViolationError is returned when a group breaks the naming or tagging rules.
+type ViolationError struct {
+	Violations []*models.GroupPolicyViolation
+}
+
+func (e *ViolationError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.Message
+	}
+	return fmt.Sprintf("group does not follow the naming policy: %s", strings.Join(messages, "; "))
+}
+
+type rule struct {
+	*config.GroupPolicyRule
+	groupType string
+	pattern   *regexp.Regexp
+}
+
+// Service holds groups to the configured naming convention and tagging
+// rules, which depend on the group's type.
+type Service struct {
+	log           *zap.SugaredLogger
+	typeAttribute string
+	untyped       *rule
+	types         map[string]*rule
+}
+
+func New(log *zap.SugaredLogger, cfg *config.GroupPolicyConfig) *Service {
+	s := &Service{
+		log:           log,
+		typeAttribute: cfg.TypeAttribute,
+		untyped:       newRule("", cfg.Default),
+		types:         make(map[string]*rule, len(cfg.Types)),
+	}
+	for groupType, r := range cfg.Types {
+		s.types[groupType] = newRule(groupType, r)
+	}
+	return s
+}
+
+func newRule(groupType string, cfg *config.GroupPolicyRule) *rule {
+	r := &rule{GroupPolicyRule: cfg, groupType: groupType}
+	if cfg.NamePattern != "" {
+		r.pattern = regexp.MustCompile(cfg.NamePattern)
+	}
+	return r
+}
+
+// Empty reports whether there are no rules, so any group is allowed. A nil
+// Service has none.
+func (s *Service) Empty() bool {
+	return s == nil || (len(s.types) == 0 && s.untyped.empty())
+}
+
+func (r *rule) empty() bool {
+	return r.NamePattern == "" && r.NamePrefix == "" && !r.DescriptionRequired && len(r.RequiredTags) == 0
+}
+
+// Policy returns the rules, with the group types sorted by name.
+func (s *Service) Policy() *models.GroupPolicy {
+	policy := &models.GroupPolicy{
+		TypeAttribute: s.typeAttribute,
+		Default:       s.untyped.model(),
+		Types:         make([]*models.GroupPolicyRule, 0, len(s.types)),
+	}
+	for _, r := range s.types {
+		policy.Types = append(policy.Types, r.model())
+	}
+	sort.Slice(policy.Types, func(i, j int) bool { return policy.Types[i].GroupType < policy.Types[j].GroupType })
+	return policy
+}
+
+func (r *rule) model() *models.GroupPolicyRule {
+	return &models.GroupPolicyRule{
+		GroupType:           r.groupType,
+		NamePattern:         r.NamePattern,
+		NamePrefix:          r.NamePrefix,
+		DescriptionRequired: r.DescriptionRequired,
+		RequiredTags:        r.RequiredTags,
+	}
+}
+
+// Check tests the group against the rule of its type and lists every rule it
+// breaks.
+func (s *Service) Check(req *models.CheckGroupPolicyRequest) *models.GroupPolicyCheck {
+	result := &models.GroupPolicyCheck{Violations: []*models.GroupPolicyViolation{}}
+	if s.Empty() {
+		result.Compliant = true
+		return result
+	}
+
+	r := s.untyped
+	groupType := stringValue(req.Profile[s.typeAttribute])
+	if groupType != "" && len(s.types) > 0 {
+		result.GroupType = groupType
+		if typed, ok := s.types[groupType]; ok {
+			r = typed
+		} else {
+			result.Violations = append(result.Violations, &models.GroupPolicyViolation{
+				Field: "profile." + s.typeAttribute,
+				Rule:  models.GroupPolicyRuleType,
+				Message: fmt.Sprintf("profile.%s must be one of %s, not %q",
+					s.typeAttribute, strings.Join(s.typeNames(), ", "), groupType,
+				),
+			})
+		}
+	}
+
+	result.Violations = append(result.Violations, r.check(req)...)
+	result.Compliant = len(result.Violations) == 0
+	return result
+}
+
+// Enforce returns a *ViolationError when the group breaks the rule of its
+// type.
+func (s *Service) Enforce(ctx context.Context, req *models.CheckGroupPolicyRequest) error {
+	if s.Empty() {
+		return nil
+	}
+
+	result := s.Check(req)
+	if result.Compliant {
+		return nil
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group rejected by naming policy",
+		"name", req.Name, "groupType", result.GroupType, "violations", len(result.Violations),
+	)
+	return &ViolationError{Violations: result.Violations}
+}
+
+func (r *rule) check(req *models.CheckGroupPolicyRequest) []*models.GroupPolicyViolation {
+	var violations []*models.GroupPolicyViolation
+	violate := func(field, ruleName, format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		if r.groupType != "" {
+			message += fmt.Sprintf(" for %s groups", r.groupType)
+		}
+		violations = append(violations, &models.GroupPolicyViolation{Field: field, Rule: ruleName, Message: message})
+	}
+
+	if r.NamePrefix != "" && !strings.HasPrefix(req.Name, r.NamePrefix) {
+		violate("name", models.GroupPolicyRulePrefix, "name must start with %q", r.NamePrefix)
+	}
+	if r.pattern != nil && !r.pattern.MatchString(req.Name) {
+		violate("name", models.GroupPolicyRulePattern, "name must match %s", r.NamePattern)
+	}
+	if r.DescriptionRequired && strings.TrimSpace(req.Description) == "" {
+		violate("description", models.GroupPolicyRuleRequired, "description is required")
+	}
+	for _, tag := range r.RequiredTags {
+		if stringValue(req.Profile[tag]) == "" {
+			violate("profile."+tag, models.GroupPolicyRuleRequired, "profile.%s is required", tag)
+		}
+	}
+
+	return violations
+}
+
+func (s *Service) typeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for groupType := range s.types {
+		names = append(names, groupType)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// stringValue returns a profile attribute as a string, or "" when it is not
+// set.
+func stringValue(value any) string {
+	if value == nil {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,328 @@
+package grouptrash_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	groupmetadata_service "github.com/iamBelugaa/iam/internal/services/groupmetadata"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const trashKey = "trash.json"
+
+var ErrDeletedGroupNotFound = errors.New("deleted group not found in the trash")
+
+// Service keeps a snapshot of every deleted group, with its members and
+// metadata, for the retention period, and recreates groups from them. The
+// snapshot is taken by a before hook of DeleteGroup and moved to the trash
+// by an after hook, so only groups that were deleted end up there. The trash
+// is stored as one object on every change.
+type Service struct {
+	log         *zap.SugaredLogger
+	cfg         *config.GroupTrashConfig
+	store       objectstore.Store
+	groupsSvc   *group_service.Service
+	metadataSvc *groupmetadata_service.Service
+
+	mu sync.Mutex
+	// pending holds the snapshots of groups being deleted, keyed by group ID.
+	pending map[string]*models.DeletedGroup
+	// deleted is keyed by trash ID. It is read from the store on first use.
+	deleted map[string]*models.DeletedGroup
+	loaded  bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.GroupTrashConfig, store objectstore.Store,
+	groupsSvc *group_service.Service, metadataSvc *groupmetadata_service.Service,
+) *Service {
+	return &Service{
+		log:         log,
+		cfg:         cfg,
+		store:       store,
+		groupsSvc:   groupsSvc,
+		metadataSvc: metadataSvc,
+		pending:     make(map[string]*models.DeletedGroup),
+		deleted:     make(map[string]*models.DeletedGroup),
+	}
+}
+
+// BeforeDeleteGroup is a hooks.DeleteGroup before hook that snapshots the
+// group. A group that cannot be snapshotted is not deleted.
+func (s *Service) BeforeDeleteGroup(ctx context.Context, event *hooks.Event) error {
+	groupID := event.ResourceID
+
+	group, err := s.groupsSvc.GetGroup(ctx, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot group before deleting it: %w", err)
+	}
+
+	snapshot := &models.DeletedGroup{
+		GroupID:     group.ID,
+		Name:        group.Name,
+		Description: group.Description,
+		JoinPolicy:  group.JoinPolicy,
+		Profile:     group.Profile,
+		Members:     make([]*models.DeletedGroupMember, 0),
+	}
+
+	expirations := s.groupsSvc.GetMembershipExpirations(groupID)
+	err = s.groupsSvc.StreamGroupMembers(ctx, groupID, func(user *models.User) error {
+		member := &models.DeletedGroupMember{UserID: user.ID, Login: user.Login}
+		if expiresAt, ok := expirations[user.ID]; ok {
+			member.ExpiresAt = &expiresAt
+		}
+		snapshot.Members = append(snapshot.Members, member)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to snapshot group members before deleting the group: %w", err)
+	}
+	snapshot.MemberCount = len(snapshot.Members)
+
+	metadata, err := s.metadataSvc.GetMetadata(ctx, groupID)
+	if err != nil && !errors.Is(err, groupmetadata_service.ErrMetadataNotFound) {
+		return fmt.Errorf("failed to snapshot group metadata before deleting the group: %w", err)
+	}
+	snapshot.Metadata = metadata
+
+	s.mu.Lock()
+	s.pending[groupID] = snapshot
+	s.mu.Unlock()
+	return nil
+}
+
+// AfterDeleteGroup is a hooks.DeleteGroup after hook that moves the group's
+// snapshot to the trash.
+func (s *Service) AfterDeleteGroup(ctx context.Context, event *hooks.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, ok := s.pending[event.ResourceID]
+	if !ok {
+		return nil
+	}
+	delete(s.pending, event.ResourceID)
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	snapshot.ID = uuid.NewString()
+	snapshot.Deleted = now
+	snapshot.Expires = now.Add(s.cfg.Retention)
+
+	s.deleted[snapshot.ID] = snapshot
+	s.purge(now)
+	if err := s.save(ctx); err != nil {
+		delete(s.deleted, snapshot.ID)
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Deleted group moved to the trash",
+		"trashId", snapshot.ID, "groupId", snapshot.GroupID, "memberCount", snapshot.MemberCount,
+	)
+	return nil
+}
+
+// GetDeletedGroups lists the groups in the trash, most recently deleted
+// first, without their members.
+func (s *Service) GetDeletedGroups(ctx context.Context) ([]*models.DeletedGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]*models.DeletedGroup, 0, len(s.deleted))
+	for _, deleted := range s.deleted {
+		if deleted.Expires.After(now) {
+			listed := *deleted
+			listed.Members = nil
+			result = append(result, &listed)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Deleted.After(result[j].Deleted) })
+	return result, nil
+}
+
+func (s *Service) GetDeletedGroup(ctx context.Context, trashID string) (*models.DeletedGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	deleted, ok := s.deleted[trashID]
+	if !ok || !deleted.Expires.After(time.Now()) {
+		return nil, ErrDeletedGroupNotFound
+	}
+	copied := *deleted
+	return &copied, nil
+}
+
+// Restore recreates a deleted group from its snapshot, adds its members back
+// and restores its metadata. Members that cannot be added are reported rather
+// than failing the restore, as the group has been recreated by then.
+func (s *Service) Restore(ctx context.Context, trashID string) (*models.GroupRestore, error) {
+	deleted, err := s.take(ctx, trashID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Restoring deleted group",
+		"trashId", trashID, "groupId", deleted.GroupID, "memberCount", deleted.MemberCount,
+	)
+
+	group, err := s.groupsSvc.CreateGroup(ctx, &models.CreateGroupRequest{
+		Name:        deleted.Name,
+		Description: deleted.Description,
+		JoinPolicy:  deleted.JoinPolicy,
+		Profile:     deleted.Profile,
+	})
+	if err != nil {
+		s.putBack(ctx, deleted)
+		return nil, err
+	}
+
+	result := &models.GroupRestore{Group: group, RestoredMembers: make([]string, 0, len(deleted.Members))}
+	now := time.Now()
+	for _, member := range deleted.Members {
+		if member.ExpiresAt != nil && !member.ExpiresAt.After(now) {
+			result.SkippedMembers = append(result.SkippedMembers, member.UserID)
+			continue
+		}
+		if err := s.groupsSvc.AddUserToGroup(ctx, group.ID, member.UserID, member.ExpiresAt); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to add member to restored group", zap.Error(err),
+				"groupId", group.ID, "userId", member.UserID,
+			)
+			result.FailedMembers = append(result.FailedMembers, member.UserID)
+			continue
+		}
+		result.RestoredMembers = append(result.RestoredMembers, member.UserID)
+	}
+
+	if deleted.Metadata != nil {
+		group.Metadata, err = s.restoreMetadata(ctx, group.ID, deleted.Metadata)
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to restore group metadata", zap.Error(err), "groupId", group.ID)
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Deleted group restored",
+		"trashId", trashID, "groupId", group.ID,
+		"restored", len(result.RestoredMembers), "skipped", len(result.SkippedMembers), "failed", len(result.FailedMembers),
+	)
+	return result, nil
+}
+
+func (s *Service) restoreMetadata(
+	ctx context.Context, groupID string, metadata *models.GroupMetadata,
+) (*models.GroupMetadata, error) {
+	restored, err := s.metadataSvc.SetMetadata(ctx, groupID, &models.SetGroupMetadataRequest{
+		CostCenter:     metadata.CostCenter,
+		Classification: metadata.Classification,
+		Tags:           metadata.Tags,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range metadata.Owners {
+		if restored, err = s.metadataSvc.AddOwner(ctx, groupID, owner); err != nil {
+			return nil, err
+		}
+	}
+	return restored, nil
+}
+
+// take removes the deleted group from the trash, so that concurrent restores
+// cannot both recreate it.
+func (s *Service) take(ctx context.Context, trashID string) (*models.DeletedGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	deleted, ok := s.deleted[trashID]
+	if !ok || !deleted.Expires.After(time.Now()) {
+		return nil, ErrDeletedGroupNotFound
+	}
+
+	delete(s.deleted, trashID)
+	if err := s.save(ctx); err != nil {
+		s.deleted[trashID] = deleted
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// putBack returns a deleted group to the trash after a failed restore.
+func (s *Service) putBack(ctx context.Context, deleted *models.DeletedGroup) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deleted[deleted.ID] = deleted
+	if err := s.save(ctx); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to return deleted group to the trash", zap.Error(err), "trashId", deleted.ID)
+	}
+}
+
+// purge drops the snapshots past their retention. Callers hold mu.
+func (s *Service) purge(now time.Time) {
+	for trashID, deleted := range s.deleted {
+		if !deleted.Expires.After(now) {
+			delete(s.deleted, trashID)
+		}
+	}
+}
+
+// load reads the trash from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, trashKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read group trash: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.deleted); err != nil {
+			return fmt.Errorf("failed to decode group trash: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the trash. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.deleted)
+	if err != nil {
+		return fmt.Errorf("failed to encode group trash: %w", err)
+	}
+	if err := s.store.Put(ctx, trashKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store group trash: %w", err)
+	}
+	return nil
+}
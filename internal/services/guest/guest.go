@@ -0,0 +1,388 @@
+package guest_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+// stateKey is the object holding the guests.
+const stateKey = "state.json"
+
+var (
+	ErrGuestNotFound    = errors.New("guest not found")
+	ErrNotSponsor       = errors.New("caller is not the guest's sponsor")
+	ErrInvalidSponsor   = errors.New("sponsor must be an active, non-guest user")
+	ErrExpiryTooFar     = errors.New("guest expiry exceeds the maximum guest duration")
+	ErrGuestDeactivated = errors.New("guest has already been deactivated")
+	ErrGroupNotEligible = errors.New("guests cannot be added to this group")
+)
+
+type Service struct {
+	log      *zap.SugaredLogger
+	cfg      *config.GuestsConfig
+	usersSvc *user_service.Service
+	auditSvc *audit_service.Service
+	// store keeps the guests across restarts, stored as one object on every
+	// change. It is nil when they are only kept in memory.
+	store objectstore.Store
+
+	mu     sync.RWMutex
+	guests map[string]*models.Guest
+}
+
+// state is the guests, keyed by ID.
+type state struct {
+	Guests map[string]*models.Guest `json:"guests"`
+}
+
+// New returns the service with the guests read from store, which may be nil,
+// so expiry, attestation and group eligibility still apply after a restart.
+func New(
+	log *zap.SugaredLogger, cfg *config.GuestsConfig, store objectstore.Store,
+	usersSvc *user_service.Service, auditSvc *audit_service.Service,
+) (*Service, error) {
+	s := &Service{
+		log:      log,
+		cfg:      cfg,
+		usersSvc: usersSvc,
+		auditSvc: auditSvc,
+		store:    store,
+		guests:   make(map[string]*models.Guest),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read guests: %w", err)
+	}
+
+	var stored state
+	if err := json.Unmarshal(object.Data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode guests: %w", err)
+	}
+	for id, guest := range stored.Guests {
+		s.guests[id] = guest
+	}
+	return s, nil
+}
+
+// save stores the guests. Callers hold mu. A failure is logged rather than
+// returned: the guest's Okta user has been changed either way.
+func (s *Service) save() {
+	if s.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(state{Guests: s.guests})
+	if err == nil {
+		err = s.store.Put(context.Background(), stateKey, "application/json", data)
+	}
+	if err != nil {
+		s.log.Infow("Failed to store guests", zap.Error(err))
+	}
+}
+
+// CreateGuest creates and activates an Okta user for the guest, sponsored by
+// sponsorID. Okta sends the guest its activation email.
+func (s *Service) CreateGuest(ctx context.Context, sponsorID string, req *models.CreateGuestRequest) (*models.Guest, error) {
+	logger.FromContext(ctx, s.log).Infow("Creating guest", "email", req.Email, "sponsorId", sponsorID, "expiresAt", req.ExpiresAt)
+
+	now := time.Now().UTC()
+	if req.ExpiresAt.After(now.Add(s.cfg.MaxDuration)) {
+		return nil, ErrExpiryTooFar
+	}
+
+	sponsor, err := s.usersSvc.GetUser(ctx, sponsorID)
+	if err != nil {
+		return nil, err
+	}
+	if sponsor.Status != models.UserStatusActive || s.isGuest(sponsorID) {
+		return nil, ErrInvalidSponsor
+	}
+
+	profile := map[string]any{"userType": "Guest"}
+	if req.Company != "" {
+		profile["organization"] = req.Company
+	}
+
+	user, err := s.usersSvc.CreateUser(ctx, &models.CreateUserRequest{
+		Email:     req.Email,
+		Login:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Profile:   profile,
+		Activate:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	guest := &models.Guest{
+		ID:        uuid.NewString(),
+		UserID:    user.ID,
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Company:   req.Company,
+		SponsorID: sponsorID,
+		Status:    models.GuestStatusActive,
+		ExpiresAt: req.ExpiresAt.UTC(),
+		AttestBy:  now.Add(s.cfg.AttestationInterval),
+		Created:   now,
+	}
+
+	s.mu.Lock()
+	s.guests[guest.ID] = guest
+	s.save()
+	s.mu.Unlock()
+
+	s.record(ctx, sponsorID, models.AuditActionGuestCreated, guest, map[string]any{
+		"userId":    guest.UserID,
+		"expiresAt": guest.ExpiresAt,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Guest created successfully", "guestId", guest.ID, "userId", guest.UserID)
+	copied := *guest
+	return &copied, nil
+}
+
+func (s *Service) GetGuests(ctx context.Context, filter *models.GuestFilter) []*models.Guest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Guest, 0, len(s.guests))
+	for _, guest := range s.guests {
+		if filter.SponsorID != "" && guest.SponsorID != filter.SponsorID {
+			continue
+		}
+		if filter.Status != "" && guest.Status != filter.Status {
+			continue
+		}
+
+		copied := *guest
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ExpiresAt.Before(result[j].ExpiresAt) })
+	return result
+}
+
+func (s *Service) GetGuest(ctx context.Context, guestID string) (*models.Guest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	guest, ok := s.guests[guestID]
+	if !ok {
+		return nil, ErrGuestNotFound
+	}
+
+	copied := *guest
+	return &copied, nil
+}
+
+// AttestGuest records the sponsor's confirmation that the guest still needs
+// access, restoring a guest suspended for a lapsed attestation.
+func (s *Service) AttestGuest(
+	ctx context.Context, guestID, sponsorID string, req *models.AttestGuestRequest,
+) (*models.Guest, error) {
+	now := time.Now().UTC()
+	if req.ExpiresAt != nil && req.ExpiresAt.After(now.Add(s.cfg.MaxDuration)) {
+		return nil, ErrExpiryTooFar
+	}
+
+	guest, err := s.sponsoredGuest(guestID, sponsorID)
+	if err != nil {
+		return nil, err
+	}
+
+	if guest.Status == models.GuestStatusSuspended {
+		if err := s.usersSvc.UnsuspendUser(ctx, guest.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	guest = s.guests[guestID]
+	guest.Status = models.GuestStatusActive
+	guest.LastAttested = &now
+	guest.AttestBy = now.Add(s.cfg.AttestationInterval)
+	if req.ExpiresAt != nil {
+		guest.ExpiresAt = req.ExpiresAt.UTC()
+	}
+	attested := *guest
+	s.save()
+	s.mu.Unlock()
+
+	s.record(ctx, sponsorID, models.AuditActionGuestAttested, &attested, map[string]any{
+		"attestBy":  attested.AttestBy,
+		"expiresAt": attested.ExpiresAt,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Guest attested successfully", "guestId", guestID, "attestBy", attested.AttestBy)
+	return &attested, nil
+}
+
+// OffboardGuest deactivates the guest ahead of its expiry.
+func (s *Service) OffboardGuest(ctx context.Context, guestID, sponsorID string) error {
+	if _, err := s.sponsoredGuest(guestID, sponsorID); err != nil {
+		return err
+	}
+	return s.DeactivateGuest(ctx, sponsorID, guestID, models.AuditActionGuestOffboarded)
+}
+
+// DeactivateGuest deactivates the guest's Okta user, recording action in the audit log.
+func (s *Service) DeactivateGuest(ctx context.Context, actor, guestID, action string) error {
+	guest, err := s.GetGuest(ctx, guestID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.usersSvc.DeactivateUser(ctx, guest.UserID); err != nil {
+		return err
+	}
+
+	s.setStatus(guestID, models.GuestStatusDeactivated)
+	s.record(ctx, actor, action, guest, map[string]any{"userId": guest.UserID, "expiresAt": guest.ExpiresAt})
+
+	logger.FromContext(ctx, s.log).Infow("Guest deactivated successfully", "guestId", guestID, "action", action)
+	return nil
+}
+
+// SuspendGuest suspends the guest's Okta user until the sponsor re-attests.
+func (s *Service) SuspendGuest(ctx context.Context, actor, guestID string) error {
+	guest, err := s.GetGuest(ctx, guestID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.usersSvc.SuspendUser(ctx, guest.UserID); err != nil {
+		return err
+	}
+
+	s.setStatus(guestID, models.GuestStatusSuspended)
+	s.record(ctx, actor, models.AuditActionGuestAttestationLapsed, guest, map[string]any{
+		"userId":    guest.UserID,
+		"sponsorId": guest.SponsorID,
+		"attestBy":  guest.AttestBy,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Guest suspended for lapsed attestation", "guestId", guestID)
+	return nil
+}
+
+// ExpiredGuests lists guests that are past their expiry but not yet deactivated.
+func (s *Service) ExpiredGuests(now time.Time) []*models.Guest {
+	return s.collect(func(guest *models.Guest) bool {
+		return guest.Status != models.GuestStatusDeactivated && !guest.ExpiresAt.After(now)
+	})
+}
+
+// LapsedAttestations lists active guests whose sponsor missed the attestation date.
+func (s *Service) LapsedAttestations(now time.Time) []*models.Guest {
+	return s.collect(func(guest *models.Guest) bool {
+		return guest.Status == models.GuestStatusActive && !guest.AttestBy.After(now)
+	})
+}
+
+// CheckMembership rejects adding a current guest to a group that is not
+// listed in the guest eligible groups. Other users are not affected.
+func (s *Service) CheckMembership(ctx context.Context, groupID, userID string) error {
+	if !s.isGuest(userID) {
+		return nil
+	}
+
+	if !slices.Contains(s.cfg.EligibleGroups, groupID) {
+		logger.FromContext(ctx, s.log).Infow("Guest group membership rejected", "groupId", groupID, "userId", userID)
+		return ErrGroupNotEligible
+	}
+
+	return nil
+}
+
+func (s *Service) isGuest(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, guest := range s.guests {
+		if guest.UserID == userID && guest.Status != models.GuestStatusDeactivated {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) sponsoredGuest(guestID, sponsorID string) (*models.Guest, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	guest, ok := s.guests[guestID]
+	if !ok {
+		return nil, ErrGuestNotFound
+	}
+
+	if guest.SponsorID != sponsorID {
+		return nil, ErrNotSponsor
+	}
+
+	if guest.Status == models.GuestStatusDeactivated {
+		return nil, ErrGuestDeactivated
+	}
+
+	copied := *guest
+	return &copied, nil
+}
+
+func (s *Service) setStatus(guestID, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if guest, ok := s.guests[guestID]; ok {
+		guest.Status = status
+		s.save()
+	}
+}
+
+func (s *Service) collect(match func(*models.Guest) bool) []*models.Guest {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.Guest
+	for _, guest := range s.guests {
+		if match(guest) {
+			copied := *guest
+			result = append(result, &copied)
+		}
+	}
+	return result
+}
+
+func (s *Service) record(ctx context.Context, actor, action string, guest *models.Guest, details map[string]any) {
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeGuest,
+		ResourceID:   guest.ID,
+		Details:      details,
+	})
+}
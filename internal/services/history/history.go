@@ -0,0 +1,453 @@
+package history_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	membershipevent_service "github.com/iamBelugaa/iam/internal/services/membershipevent"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+var (
+	ErrAsOfInFuture       = errors.New("asOf must not be in the future")
+	ErrInvalidRange       = errors.New("from must be before to")
+	ErrHistoryUnavailable = errors.New(
+		"asOf is older than the System Log retention and no membership snapshot is close enough to it",
+	)
+)
+
+const (
+	// logRetention is how far back Okta keeps System Log events, and so the
+	// oldest membership change that can be replayed or undone.
+	logRetention = 90 * 24 * time.Hour
+
+	manifestKey       = "snapshots/manifest.json"
+	membershipAdded   = "group.user_membership.add"
+	membershipRemoved = "group.user_membership.remove"
+	membershipFilter  = `(eventType eq "` + membershipAdded + `" or eventType eq "` + membershipRemoved +
+		`") and target.id eq "%s"`
+)
+
+// Service answers what a group's membership was at a past time. It keeps
+// periodic snapshots of every group's members, taken from the directory
+// index, and derives the membership at any other time from the nearest
+// snapshot, or the current members, and the membership changes Okta logged
+// in between. Changes are read from the local membership event store as far
+// as it reaches, which may be further back than the System Log, and from
+// Okta after that.
+type Service struct {
+	log          *zap.SugaredLogger
+	client       *okta.APIClient
+	cfg          *config.HistoryConfig
+	store        objectstore.Store
+	groupsSvc    *group_service.Service
+	directorySvc *directory_service.Service
+	eventsSvc    *membershipevent_service.Service
+
+	mu sync.Mutex
+	// taken lists the times of the stored snapshots, oldest first. It is
+	// read from the manifest on first use.
+	taken []time.Time
+}
+
+func New(
+	log *zap.SugaredLogger,
+	client *okta.APIClient,
+	cfg *config.HistoryConfig,
+	store objectstore.Store,
+	groupsSvc *group_service.Service,
+	directorySvc *directory_service.Service,
+	eventsSvc *membershipevent_service.Service,
+) *Service {
+	return &Service{
+		log:          log,
+		client:       client,
+		cfg:          cfg,
+		store:        store,
+		groupsSvc:    groupsSvc,
+		directorySvc: directorySvc,
+		eventsSvc:    eventsSvc,
+	}
+}
+
+// SnapshotIfDue stores the directory index's memberships when the newest
+// snapshot is older than the snapshot interval, and deletes snapshots older
+// than the retention period.
+func (s *Service) SnapshotIfDue(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	taken, err := s.snapshotTimes(ctx)
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 && time.Since(taken[len(taken)-1]) < s.cfg.SnapshotInterval {
+		return nil
+	}
+
+	snapshot, err := s.directorySvc.Memberships()
+	if err != nil {
+		return fmt.Errorf("failed to read memberships from the directory index: %w", err)
+	}
+	if len(taken) > 0 && !snapshot.TakenAt.After(taken[len(taken)-1]) {
+		// The index has not been refreshed since the last snapshot.
+		return nil
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode membership snapshot: %w", err)
+	}
+	if err := s.store.Put(ctx, snapshotKey(snapshot.TakenAt), "application/json", data); err != nil {
+		return fmt.Errorf("failed to store membership snapshot: %w", err)
+	}
+
+	cutoff := time.Now().Add(-s.cfg.Retention)
+	kept := make([]time.Time, 0, len(taken)+1)
+	for _, at := range taken {
+		if at.Before(cutoff) {
+			if err := s.store.Delete(ctx, snapshotKey(at)); err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+				logger.FromContext(ctx, s.log).Infow("Failed to delete expired membership snapshot", zap.Error(err), "takenAt", at)
+			}
+			continue
+		}
+		kept = append(kept, at)
+	}
+	kept = append(kept, snapshot.TakenAt)
+
+	if err := s.saveManifest(ctx, kept); err != nil {
+		return err
+	}
+	s.taken = kept
+
+	logger.FromContext(ctx, s.log).Infow("Membership snapshot stored",
+		"takenAt", snapshot.TakenAt,
+		"groupCount", len(snapshot.Groups),
+		"snapshotCount", len(kept),
+	)
+	return nil
+}
+
+// GetGroupMembersAsOf returns the members of a group at asOf.
+func (s *Service) GetGroupMembersAsOf(ctx context.Context, groupID string, asOf time.Time) (*models.GroupMembersAsOf, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting historical group members", "groupId", groupID, "asOf", asOf)
+
+	now := time.Now().UTC()
+	if asOf.After(now) {
+		return nil, ErrAsOfInFuture
+	}
+
+	basis, err := s.closestBasis(ctx, asOf, now)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.membersAt(ctx, groupID, basis)
+	if err != nil {
+		return nil, err
+	}
+
+	forward := basis.At.Before(asOf)
+	since, until := asOf, basis.At
+	if forward {
+		since, until = basis.At, asOf
+	}
+
+	events, err := s.membershipChanges(ctx, groupID, since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	// Going forward from an older basis replays the changes; going back from
+	// a newer one undoes them, newest first.
+	if !forward {
+		for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+			events[i], events[j] = events[j], events[i]
+		}
+	}
+
+	for _, change := range events {
+		if change.added == forward {
+			members[change.member.ID] = change.member
+		} else {
+			delete(members, change.member.ID)
+		}
+	}
+	applied := len(events)
+
+	result := &models.GroupMembersAsOf{
+		GroupID:        groupID,
+		AsOf:           asOf,
+		Basis:          basis,
+		ChangesApplied: applied,
+		Members:        make([]*models.HistoricalMember, 0, len(members)),
+	}
+	for _, member := range members {
+		result.Members = append(result.Members, member)
+	}
+	sort.Slice(result.Members, func(i, j int) bool { return result.Members[i].Login < result.Members[j].Login })
+
+	logger.FromContext(ctx, s.log).Infow("Historical group members derived",
+		"groupId", groupID,
+		"asOf", asOf,
+		"basis", basis.Type,
+		"basisAt", basis.At,
+		"changesApplied", applied,
+		"memberCount", len(result.Members),
+	)
+	return result, nil
+}
+
+// DiffGroupMembers returns the members who joined and left the group
+// between from and to.
+func (s *Service) DiffGroupMembers(
+	ctx context.Context, groupID string, from, to time.Time,
+) (*models.GroupMembershipDiff, error) {
+	if !from.Before(to) {
+		return nil, ErrInvalidRange
+	}
+
+	before, err := s.GetGroupMembersAsOf(ctx, groupID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.GetGroupMembersAsOf(ctx, groupID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.GroupMembershipDiff{
+		GroupID:   groupID,
+		From:      from,
+		To:        to,
+		FromBasis: before.Basis,
+		ToBasis:   after.Basis,
+		Added:     make([]*models.HistoricalMember, 0),
+		Removed:   make([]*models.HistoricalMember, 0),
+	}
+
+	// Both member lists are sorted by login, and so are the differences.
+	was := make(map[string]bool, len(before.Members))
+	for _, member := range before.Members {
+		was[member.ID] = true
+	}
+	is := make(map[string]bool, len(after.Members))
+	for _, member := range after.Members {
+		is[member.ID] = true
+		if !was[member.ID] {
+			diff.Added = append(diff.Added, member)
+		}
+	}
+	for _, member := range before.Members {
+		if !is[member.ID] {
+			diff.Removed = append(diff.Removed, member)
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group membership diff derived",
+		"groupId", groupID, "from", from, "to", to, "added", len(diff.Added), "removed", len(diff.Removed),
+	)
+	return diff, nil
+}
+
+// closestBasis picks the snapshot or current membership closest to asOf
+// whose changes since or until asOf are still in the System Log.
+func (s *Service) closestBasis(ctx context.Context, asOf, now time.Time) (*models.MembershipBasis, error) {
+	s.mu.Lock()
+	taken, err := s.snapshotTimes(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := []*models.MembershipBasis{{Type: models.MembershipBasisCurrent, At: now}}
+	// The newest snapshot at or before asOf and the oldest one after it.
+	i := sort.Search(len(taken), func(i int) bool { return taken[i].After(asOf) })
+	if i > 0 {
+		candidates = append(candidates, &models.MembershipBasis{Type: models.MembershipBasisSnapshot, At: taken[i-1]})
+	}
+	if i < len(taken) {
+		candidates = append(candidates, &models.MembershipBasis{Type: models.MembershipBasisSnapshot, At: taken[i]})
+	}
+
+	oldestLogged := now.Add(-logRetention)
+	ingestedSince, err := s.eventsSvc.IngestedSince(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !ingestedSince.IsZero() && ingestedSince.Before(oldestLogged) {
+		oldestLogged = ingestedSince
+	}
+	var best *models.MembershipBasis
+	for _, candidate := range candidates {
+		// The changes between the basis and asOf start at the earlier of the
+		// two, and a snapshot taken exactly at asOf needs none.
+		if !candidate.At.Equal(asOf) && (candidate.At.Before(oldestLogged) || asOf.Before(oldestLogged)) {
+			continue
+		}
+		if best == nil || distance(candidate.At, asOf) < distance(best.At, asOf) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return nil, ErrHistoryUnavailable
+	}
+	return best, nil
+}
+
+func (s *Service) membersAt(
+	ctx context.Context, groupID string, basis *models.MembershipBasis,
+) (map[string]*models.HistoricalMember, error) {
+	members := make(map[string]*models.HistoricalMember)
+
+	if basis.Type == models.MembershipBasisCurrent {
+		err := s.groupsSvc.StreamGroupMembers(ctx, groupID, func(user *models.User) error {
+			members[user.ID] = &models.HistoricalMember{ID: user.ID, Login: user.Login}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return members, nil
+	}
+
+	object, err := s.store.Get(ctx, snapshotKey(basis.At))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership snapshot: %w", err)
+	}
+
+	var snapshot models.MembershipSnapshot
+	if err := json.Unmarshal(object.Data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode membership snapshot: %w", err)
+	}
+
+	// A group missing from the snapshot did not exist yet, or no longer did.
+	for _, member := range snapshot.Groups[groupID] {
+		members[member.ID] = member
+	}
+	return members, nil
+}
+
+// membershipChanges returns the group's membership changes between since
+// and until, oldest first. The event store answers as far as it reaches and
+// the System Log the rest.
+func (s *Service) membershipChanges(ctx context.Context, groupID string, since, until time.Time) ([]*membershipChange, error) {
+	changes := make([]*membershipChange, 0)
+
+	stored, coveredUntil, ok, err := s.eventsSvc.GroupChanges(ctx, groupID, since, until)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for _, event := range stored {
+			changes = append(changes, &membershipChange{
+				member: &models.HistoricalMember{ID: event.UserID, Login: event.UserLogin},
+				added:  event.Action == models.MembershipEventAdded,
+			})
+		}
+		if !coveredUntil.Before(until) {
+			return changes, nil
+		}
+		since = coveredUntil
+	}
+
+	filter := fmt.Sprintf(membershipFilter, groupID)
+
+	events, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).
+		Since(since).Until(until).Filter(filter).SortOrder("ASCENDING").Execute()
+	if err == nil {
+		events, err = pagination.All(events, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err), "filter", filter)
+		return nil, fmt.Errorf("failed to list membership changes from Okta: %w", err)
+	}
+
+	for i := range events {
+		if member := changedMember(&events[i]); member != nil {
+			changes = append(changes, &membershipChange{
+				member: member,
+				added:  events[i].GetEventType() == membershipAdded,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// snapshotTimes returns the times of the stored snapshots. Callers hold mu.
+func (s *Service) snapshotTimes(ctx context.Context) ([]time.Time, error) {
+	if s.taken != nil {
+		return s.taken, nil
+	}
+
+	object, err := s.store.Get(ctx, manifestKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		s.taken = []time.Time{}
+		return s.taken, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read membership snapshot manifest: %w", err)
+	}
+
+	var taken []time.Time
+	if err := json.Unmarshal(object.Data, &taken); err != nil {
+		return nil, fmt.Errorf("failed to decode membership snapshot manifest: %w", err)
+	}
+	sort.Slice(taken, func(i, j int) bool { return taken[i].Before(taken[j]) })
+
+	s.taken = taken
+	return taken, nil
+}
+
+func (s *Service) saveManifest(ctx context.Context, taken []time.Time) error {
+	data, err := json.Marshal(taken)
+	if err != nil {
+		return fmt.Errorf("failed to encode membership snapshot manifest: %w", err)
+	}
+	if err := s.store.Put(ctx, manifestKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store membership snapshot manifest: %w", err)
+	}
+	return nil
+}
+
+func snapshotKey(takenAt time.Time) string {
+	return "snapshots/" + takenAt.UTC().Format("20060102T150405.000000000Z") + ".json"
+}
+
+// membershipChange is a member added to or removed from a group.
+type membershipChange struct {
+	member *models.HistoricalMember
+	added  bool
+}
+
+// changedMember returns the user whose membership the event changed.
+func changedMember(event *okta.LogEvent) *models.HistoricalMember {
+	for _, target := range event.Target {
+		if target.GetType() == "User" && target.GetId() != "" {
+			return &models.HistoricalMember{ID: target.GetId(), Login: target.GetAlternateId()}
+		}
+	}
+	return nil
+}
+
+func distance(a, b time.Time) time.Duration {
+	if a.After(b) {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
@@ -0,0 +1,404 @@
+package invitation_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+// stateKey is the object holding the invitations.
+const stateKey = "state.json"
+
+var (
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationExpired  = errors.New("invitation has expired")
+	ErrInvitationUsed     = errors.New("invitation is no longer pending")
+	ErrExpiryTooFar       = errors.New("invitation expiry exceeds the maximum invitation lifetime")
+	ErrInvalidSponsor     = errors.New("sponsor must be an active user")
+	ErrNameRequired       = errors.New("first and last name are required")
+)
+
+type Service struct {
+	log         *zap.SugaredLogger
+	cfg         *config.InvitationsConfig
+	usersSvc    *user_service.Service
+	auditSvc    *audit_service.Service
+	webhooksSvc *webhook_service.Service
+	// store keeps the invitations across restarts, stored as one object on
+	// every change. It is nil when they are only kept in memory.
+	store objectstore.Store
+
+	mu          sync.RWMutex
+	invitations map[string]*models.Invitation
+	// tokens maps the SHA-256 of each invitation token to its invitation ID,
+	// so the token itself is never stored.
+	tokens map[string]string
+}
+
+// state is the invitations, keyed by ID, and the hashes of their tokens.
+type state struct {
+	Invitations map[string]*models.Invitation `json:"invitations"`
+	Tokens      map[string]string             `json:"tokens"`
+}
+
+// New returns the service with the invitations read from store, which may be
+// nil, so links sent before a restart still work after it.
+func New(
+	log *zap.SugaredLogger, cfg *config.InvitationsConfig, store objectstore.Store, usersSvc *user_service.Service,
+	auditSvc *audit_service.Service, webhooksSvc *webhook_service.Service,
+) (*Service, error) {
+	s := &Service{
+		log:         log,
+		cfg:         cfg,
+		usersSvc:    usersSvc,
+		auditSvc:    auditSvc,
+		webhooksSvc: webhooksSvc,
+		store:       store,
+		invitations: make(map[string]*models.Invitation),
+		tokens:      make(map[string]string),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read invitations: %w", err)
+	}
+
+	var stored state
+	if err := json.Unmarshal(object.Data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode invitations: %w", err)
+	}
+	maps.Copy(s.invitations, stored.Invitations)
+	maps.Copy(s.tokens, stored.Tokens)
+	return s, nil
+}
+
+// save stores the invitations. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(state{Invitations: s.invitations, Tokens: s.tokens})
+	if err != nil {
+		return fmt.Errorf("failed to encode invitations: %w", err)
+	}
+	if err := s.store.Put(ctx, stateKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store invitations: %w", err)
+	}
+	return nil
+}
+
+// CreateInvitation generates an invitation link for req.Email. The link is
+// only returned here.
+func (s *Service) CreateInvitation(
+	ctx context.Context, actor string, req *models.CreateInvitationRequest,
+) (*models.Invitation, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(s.cfg.TTL)
+	if req.ExpiresAt != nil {
+		if req.ExpiresAt.After(expiresAt) {
+			return nil, ErrExpiryTooFar
+		}
+		expiresAt = req.ExpiresAt.UTC()
+	}
+
+	sponsorID := req.SponsorID
+	if sponsorID == "" {
+		sponsorID = actor
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Creating invitation", "email", req.Email, "sponsorId", sponsorID, "expiresAt", expiresAt)
+
+	sponsor, err := s.usersSvc.GetUser(ctx, sponsorID)
+	if err != nil {
+		return nil, err
+	}
+	if sponsor.Status != models.UserStatusActive {
+		return nil, ErrInvalidSponsor
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	encoded := hex.EncodeToString(token)
+
+	link, err := url.JoinPath(s.cfg.BaseURL, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build invitation link: %w", err)
+	}
+
+	invitation := &models.Invitation{
+		ID:        uuid.NewString(),
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Profile:   req.Profile,
+		SponsorID: sponsorID,
+		CreatedBy: actor,
+		Status:    models.InvitationStatusPending,
+		ExpiresAt: expiresAt,
+		Created:   now,
+	}
+
+	s.mu.Lock()
+	s.invitations[invitation.ID] = invitation
+	s.tokens[hashToken(encoded)] = invitation.ID
+	if err := s.save(ctx); err != nil {
+		delete(s.invitations, invitation.ID)
+		delete(s.tokens, hashToken(encoded))
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	s.record(ctx, actor, models.AuditActionInvitationCreated, invitation, map[string]any{
+		"email":     invitation.Email,
+		"sponsorId": invitation.SponsorID,
+		"expiresAt": invitation.ExpiresAt,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Invitation created successfully", "invitationId", invitation.ID)
+	created := view(invitation, now)
+	created.URL = link
+	return created, nil
+}
+
+func (s *Service) GetInvitations(ctx context.Context, filter *models.InvitationFilter) []*models.Invitation {
+	now := time.Now().UTC()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Invitation, 0, len(s.invitations))
+	for _, invitation := range s.invitations {
+		viewed := view(invitation, now)
+		if filter.SponsorID != "" && viewed.SponsorID != filter.SponsorID {
+			continue
+		}
+		if filter.Status != "" && viewed.Status != filter.Status {
+			continue
+		}
+		result = append(result, viewed)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result
+}
+
+func (s *Service) GetInvitation(ctx context.Context, invitationID string) (*models.Invitation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invitation, ok := s.invitations[invitationID]
+	if !ok {
+		return nil, ErrInvitationNotFound
+	}
+
+	return view(invitation, time.Now().UTC()), nil
+}
+
+// RevokeInvitation invalidates a pending invitation's link.
+func (s *Service) RevokeInvitation(ctx context.Context, actor, invitationID string) error {
+	s.mu.Lock()
+	invitation, ok := s.invitations[invitationID]
+	if !ok {
+		s.mu.Unlock()
+		return ErrInvitationNotFound
+	}
+	if err := checkPending(invitation, time.Now().UTC()); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	invitation.Status = models.InvitationStatusRevoked
+	if err := s.save(ctx); err != nil {
+		invitation.Status = models.InvitationStatusPending
+		s.mu.Unlock()
+		return err
+	}
+	revoked := *invitation
+	s.mu.Unlock()
+
+	s.record(ctx, actor, models.AuditActionInvitationRevoked, &revoked, nil)
+
+	logger.FromContext(ctx, s.log).Infow("Invitation revoked successfully", "invitationId", invitationID)
+	return nil
+}
+
+// PreviewInvitation returns what the invitee may see of a pending invitation.
+func (s *Service) PreviewInvitation(ctx context.Context, token string) (*models.InvitationPreview, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	invitation, ok := s.invitations[s.tokens[hashToken(token)]]
+	if !ok {
+		return nil, ErrInvitationNotFound
+	}
+	if err := checkPending(invitation, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+
+	return &models.InvitationPreview{
+		Email:     invitation.Email,
+		FirstName: invitation.FirstName,
+		LastName:  invitation.LastName,
+		ExpiresAt: invitation.ExpiresAt,
+	}, nil
+}
+
+// AcceptInvitation creates a STAGED Okta user from the invitation and the
+// invitee's form, then notifies the sponsor. Each link can be used once.
+func (s *Service) AcceptInvitation(
+	ctx context.Context, token string, req *models.AcceptInvitationRequest,
+) (*models.Invitation, error) {
+	now := time.Now().UTC()
+
+	// Claim the invitation before calling Okta so that concurrent
+	// submissions of the same link cannot create two users.
+	s.mu.Lock()
+	invitation, ok := s.invitations[s.tokens[hashToken(token)]]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrInvitationNotFound
+	}
+	if err := checkPending(invitation, now); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	firstName, lastName := invitation.FirstName, invitation.LastName
+	if req.FirstName != "" {
+		firstName = req.FirstName
+	}
+	if req.LastName != "" {
+		lastName = req.LastName
+	}
+	if firstName == "" || lastName == "" {
+		s.mu.Unlock()
+		return nil, ErrNameRequired
+	}
+
+	original := *invitation
+	invitation.Status = models.InvitationStatusAccepted
+	invitation.FirstName, invitation.LastName = firstName, lastName
+	if err := s.save(ctx); err != nil {
+		*invitation = original
+		s.mu.Unlock()
+		return nil, err
+	}
+	claimed := *invitation
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Accepting invitation", "invitationId", claimed.ID, "email", claimed.Email)
+
+	profile := make(map[string]any, len(claimed.Profile)+1)
+	if req.MobilePhone != "" {
+		profile["mobilePhone"] = req.MobilePhone
+	}
+	maps.Copy(profile, claimed.Profile)
+
+	user, err := s.usersSvc.CreateUser(ctx, &models.CreateUserRequest{
+		Email:     claimed.Email,
+		Login:     claimed.Email,
+		FirstName: claimed.FirstName,
+		LastName:  claimed.LastName,
+		Profile:   profile,
+	})
+	if err != nil {
+		s.mu.Lock()
+		*invitation = original
+		if err := s.save(ctx); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to store released invitation", zap.Error(err), "invitationId", claimed.ID)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	// The user exists either way, so a failure to store it is only logged.
+	s.mu.Lock()
+	invitation.UserID = user.ID
+	invitation.AcceptedAt = &now
+	if err := s.save(ctx); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to store accepted invitation", zap.Error(err), "invitationId", claimed.ID)
+	}
+	accepted := *invitation
+	s.mu.Unlock()
+
+	s.record(ctx, accepted.Email, models.AuditActionInvitationAccepted, &accepted, map[string]any{
+		"userId":    accepted.UserID,
+		"sponsorId": accepted.SponsorID,
+	})
+
+	s.webhooksSvc.Publish(ctx, models.WebhookEventInvitationAccepted, map[string]any{
+		"invitationId": accepted.ID,
+		"userId":       accepted.UserID,
+		"email":        accepted.Email,
+		"sponsorId":    accepted.SponsorID,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Invitation accepted successfully", "invitationId", accepted.ID, "userId", accepted.UserID)
+	return &accepted, nil
+}
+
+// checkPending reports why an invitation can no longer be used, if it can't.
+func checkPending(invitation *models.Invitation, now time.Time) error {
+	if invitation.Status != models.InvitationStatusPending {
+		return ErrInvitationUsed
+	}
+	if !invitation.ExpiresAt.After(now) {
+		return ErrInvitationExpired
+	}
+	return nil
+}
+
+// view copies the invitation, reporting a lapsed pending invitation as expired.
+func view(invitation *models.Invitation, now time.Time) *models.Invitation {
+	copied := *invitation
+	if copied.Status == models.InvitationStatusPending && !copied.ExpiresAt.After(now) {
+		copied.Status = models.InvitationStatusExpired
+	}
+	return &copied
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *Service) record(
+	ctx context.Context, actor, action string, invitation *models.Invitation, details map[string]any,
+) {
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeInvitation,
+		ResourceID:   invitation.ID,
+		Details:      details,
+	})
+}
@@ -0,0 +1,281 @@
+package job_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+// Service keeps track of background jobs and runs their asynchronous parts
+// on a context that lives as long as the server.
+type Service struct {
+	ctx context.Context
+	log *zap.SugaredLogger
+	// store keeps a copy of every job as it changes, so jobs can still be
+	// looked up after a restart. It is nil when jobs are only kept in memory.
+	store objectstore.Store
+	// eventsSvc is sent a snapshot of every job as it changes.
+	eventsSvc *event_service.Service
+
+	mu   sync.RWMutex
+	jobs map[string]*models.Job
+}
+
+func New(
+	ctx context.Context, log *zap.SugaredLogger, store objectstore.Store, eventsSvc *event_service.Service,
+) *Service {
+	return &Service{ctx: ctx, log: log, store: store, eventsSvc: eventsSvc, jobs: make(map[string]*models.Job)}
+}
+
+// Tracker reports the progress of one job.
+type Tracker struct {
+	svc   *Service
+	jobID string
+}
+
+// Create registers a pending job with the named steps.
+func (s *Service) Create(jobType, resourceType, resourceID string, steps []string) *Tracker {
+	now := time.Now().UTC()
+	job := &models.Job{
+		ID:           uuid.NewString(),
+		Type:         jobType,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Status:       models.JobStatusPending,
+		Steps:        make([]*models.JobStep, len(steps)),
+		Created:      now,
+		LastUpdated:  now,
+	}
+
+	for i, name := range steps {
+		job.Steps[i] = &models.JobStep{Name: name, Status: models.JobStepStatusPending}
+	}
+
+	s.mu.Lock()
+	s.jobs[job.ID] = job
+	snapshot := copyJob(job)
+	s.mu.Unlock()
+	s.persist(snapshot)
+	s.eventsSvc.Publish(s.ctx, models.StreamEventJobProgress, models.StreamSourceJob, snapshot)
+
+	s.log.Infow("Job created", "jobId", job.ID, "type", jobType, "resourceId", resourceID)
+	return &Tracker{svc: s, jobID: job.ID}
+}
+
+// Go runs fn in the background and finishes the job with its result.
+func (s *Service) Go(tracker *Tracker, fn func(ctx context.Context) error) {
+	go func() {
+		tracker.Finish(fn(s.ctx))
+	}()
+}
+
+func (s *Service) GetJob(ctx context.Context, jobID string) (*models.Job, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[jobID]
+	if ok {
+		job = copyJob(job)
+	}
+	s.mu.RUnlock()
+
+	if !ok {
+		return s.loadJob(ctx, jobID)
+	}
+	return job, nil
+}
+
+// loadJob reads a job this process does not know from the store: one
+// created before a restart, or by another replica sharing the store. It is
+// as it was last stored, so a job whose server stopped while running it
+// stays RUNNING.
+func (s *Service) loadJob(ctx context.Context, jobID string) (*models.Job, error) {
+	if s.store == nil {
+		return nil, ErrJobNotFound
+	}
+
+	object, err := s.store.Get(ctx, jobKey(jobID))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+
+	var job models.Job
+	if err := json.Unmarshal(object.Data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetJobs lists matching jobs created since the server started, newest
+// first.
+func (s *Service) GetJobs(ctx context.Context, filter *models.JobFilter) []*models.Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if filter.Type != "" && job.Type != filter.Type {
+			continue
+		}
+		if filter.Status != "" && job.Status != filter.Status {
+			continue
+		}
+		if filter.ResourceID != "" && job.ResourceID != filter.ResourceID {
+			continue
+		}
+		result = append(result, copyJob(job))
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result
+}
+
+// Unfinished counts the jobs pending or running.
+func (s *Service) Unfinished() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, job := range s.jobs {
+		if job.Status == models.JobStatusPending || job.Status == models.JobStatusRunning {
+			count++
+		}
+	}
+	return count
+}
+
+func (t *Tracker) JobID() string {
+	return t.jobID
+}
+
+// Job returns a snapshot of the tracked job.
+func (t *Tracker) Job() *models.Job {
+	job, _ := t.svc.GetJob(context.Background(), t.jobID)
+	return job
+}
+
+// Step runs fn as the named step, recording its outcome. The message returned
+// by fn is shown on the step; an error fails the step and is returned.
+func (t *Tracker) Step(name string, fn func() (string, error)) error {
+	step := t.update(func(job *models.Job, now time.Time) *models.JobStep {
+		job.Status = models.JobStatusRunning
+		step := findStep(job, name)
+		if step != nil {
+			step.Status = models.JobStepStatusRunning
+			step.Started = &now
+		}
+		return step
+	})
+	if step == nil {
+		return fmt.Errorf("job %s has no step %q", t.jobID, name)
+	}
+
+	message, err := fn()
+
+	t.update(func(job *models.Job, now time.Time) *models.JobStep {
+		step := findStep(job, name)
+		step.Completed = &now
+		step.Message = message
+		step.Status = models.JobStepStatusSucceeded
+		if err != nil {
+			step.Status = models.JobStepStatusFailed
+			step.Message = err.Error()
+		}
+		return step
+	})
+
+	return err
+}
+
+// Finish completes the job. On failure the steps that never ran are skipped.
+func (t *Tracker) Finish(err error) {
+	t.update(func(job *models.Job, now time.Time) *models.JobStep {
+		job.Completed = &now
+		job.Status = models.JobStatusSucceeded
+
+		if err != nil {
+			job.Status = models.JobStatusFailed
+			job.Error = err.Error()
+
+			for _, step := range job.Steps {
+				if step.Status == models.JobStepStatusPending {
+					step.Status = models.JobStepStatusSkipped
+				}
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.svc.log.Infow("Job failed", zap.Error(err), "jobId", t.jobID)
+		return
+	}
+	t.svc.log.Infow("Job completed successfully", "jobId", t.jobID)
+}
+
+func (t *Tracker) update(fn func(job *models.Job, now time.Time) *models.JobStep) *models.JobStep {
+	t.svc.mu.Lock()
+	job := t.svc.jobs[t.jobID]
+	now := time.Now().UTC()
+	job.LastUpdated = now
+	step := fn(job, now)
+	snapshot := copyJob(job)
+	t.svc.mu.Unlock()
+
+	t.svc.persist(snapshot)
+	t.svc.eventsSvc.Publish(t.svc.ctx, models.StreamEventJobProgress, models.StreamSourceJob, snapshot)
+	return step
+}
+
+// persist writes the job to the store. A failure is logged: the job carries
+// on, and can still be looked up until the server stops.
+func (s *Service) persist(job *models.Job) {
+	if s.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(job)
+	if err == nil {
+		err = s.store.Put(context.WithoutCancel(s.ctx), jobKey(job.ID), "application/json", data)
+	}
+	if err != nil {
+		s.log.Infow("Failed to store job", zap.Error(err), "jobId", job.ID)
+	}
+}
+
+func jobKey(jobID string) string {
+	return jobID + ".json"
+}
+
+func findStep(job *models.Job, name string) *models.JobStep {
+	for _, step := range job.Steps {
+		if step.Name == name {
+			return step
+		}
+	}
+	return nil
+}
+
+func copyJob(job *models.Job) *models.Job {
+	copied := *job
+	copied.Steps = make([]*models.JobStep, len(job.Steps))
+	for i, step := range job.Steps {
+		stepCopy := *step
+		copied.Steps[i] = &stepCopy
+	}
+	return &copied
+}
@@ -0,0 +1,442 @@
+package membershipevent_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	event_service "github.com/iamBelugaa/iam/internal/services/event"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+const (
+	// logDelay keeps ingestion clear of the newest System Log events, which
+	// Okta may still be writing, so none is skipped by moving past it.
+	logDelay = 2 * time.Minute
+
+	manifestKey       = "manifest.json"
+	membershipAdded   = "group.user_membership.add"
+	membershipRemoved = "group.user_membership.remove"
+	membershipFilter  = `(eventType eq "` + membershipAdded + `" or eventType eq "` + membershipRemoved +
+		`") and outcome.result eq "SUCCESS"`
+)
+
+// manifest records which part of the System Log the store holds and the days
+// it has events for.
+type manifest struct {
+	IngestedSince time.Time `json:"ingestedSince"`
+	IngestedUntil time.Time `json:"ingestedUntil"`
+	Days          []string  `json:"days"`
+}
+
+// Service keeps a local copy of the group membership changes Okta logged,
+// including those made outside this service, so that who changed a
+// membership can be answered, and past memberships derived, after the System
+// Log has dropped them. Events are read from the System Log in order and
+// stored by the day they were logged; the manifest records how far the store
+// reaches.
+type Service struct {
+	log    *zap.SugaredLogger
+	client *okta.APIClient
+	cfg    *config.MembershipEventsConfig
+	store  objectstore.Store
+	// eventsSvc is sent the changes of every ingestion but the first, whose
+	// backfill is history rather than news.
+	eventsSvc *event_service.Service
+
+	// ingestMu serializes ingestion, so that mu is not held while Okta is
+	// being read.
+	ingestMu sync.Mutex
+
+	mu       sync.RWMutex
+	manifest *manifest
+	// events are every stored event, oldest first, and ids their IDs. They
+	// are read from the store on first use.
+	events []*models.MembershipEvent
+	ids    map[string]bool
+	loaded bool
+}
+
+func New(
+	log *zap.SugaredLogger,
+	client *okta.APIClient,
+	cfg *config.MembershipEventsConfig,
+	store objectstore.Store,
+	eventsSvc *event_service.Service,
+) *Service {
+	return &Service{
+		log:       log,
+		client:    client,
+		cfg:       cfg,
+		store:     store,
+		eventsSvc: eventsSvc,
+		manifest:  &manifest{},
+		ids:       make(map[string]bool),
+	}
+}
+
+// Ingest stores the membership changes logged since the last ingestion, and
+// drops the days past the retention period. The first ingestion reads back
+// as far as the backfill period.
+func (s *Service) Ingest(ctx context.Context) error {
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+
+	s.mu.Lock()
+	err := s.load(ctx)
+	since := s.manifest.IngestedUntil
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	first := since.IsZero()
+	if first {
+		since = now.Add(-s.cfg.Backfill)
+	}
+	until := now.Add(-logDelay)
+	if !until.After(since) {
+		return nil
+	}
+
+	logEvents, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).
+		Since(since).Until(until).Filter(membershipFilter).SortOrder("ASCENDING").Execute()
+	if err == nil {
+		logEvents, err = pagination.All(logEvents, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err), "filter", membershipFilter)
+		return fmt.Errorf("failed to list membership changes from Okta: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ingested := make([]*models.MembershipEvent, 0, len(logEvents))
+	touched := make(map[string]bool)
+	for i := range logEvents {
+		event := newMembershipEvent(&logEvents[i])
+		if event == nil || s.ids[event.ID] {
+			continue
+		}
+		ingested = append(ingested, event)
+		touched[dayOf(event.Published)] = true
+	}
+
+	previous := *s.manifest
+	next := previous
+	if first {
+		next.IngestedSince = since
+	}
+	next.IngestedUntil = until
+
+	events := append(s.events[:len(s.events):len(s.events)], ingested...)
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Published.Before(events[j].Published) })
+
+	// Whole days past the retention period are dropped.
+	cutoff := now.Add(-s.cfg.Retention)
+	var expired []string
+	for len(events) > 0 && dayOf(events[0].Published) < dayOf(cutoff) {
+		events = events[1:]
+	}
+	next.Days = make([]string, 0, len(previous.Days)+len(touched))
+	for _, day := range previous.Days {
+		if day < dayOf(cutoff) {
+			expired = append(expired, day)
+			continue
+		}
+		next.Days = append(next.Days, day)
+	}
+	for day := range touched {
+		if day >= dayOf(cutoff) && !containsDay(previous.Days, day) {
+			next.Days = append(next.Days, day)
+		}
+	}
+	sort.Strings(next.Days)
+	if len(expired) > 0 && next.IngestedSince.Before(startOfDay(cutoff)) {
+		next.IngestedSince = startOfDay(cutoff)
+	}
+
+	for day := range touched {
+		if day < dayOf(cutoff) {
+			continue
+		}
+		if err := s.saveDay(ctx, day, eventsOn(events, day)); err != nil {
+			return err
+		}
+	}
+	if err := s.saveManifest(ctx, &next); err != nil {
+		return err
+	}
+	for _, day := range expired {
+		if err := s.store.Delete(ctx, dayKey(day)); err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+			logger.FromContext(ctx, s.log).Infow("Failed to delete expired membership events", zap.Error(err), "day", day)
+		}
+	}
+
+	s.manifest = &next
+	s.events = events
+	for _, event := range ingested {
+		s.ids[event.ID] = true
+	}
+	if len(expired) > 0 {
+		s.ids = make(map[string]bool, len(events))
+		for _, event := range events {
+			s.ids[event.ID] = true
+		}
+	}
+
+	if !first {
+		for _, event := range ingested {
+			eventType := models.StreamEventMembershipAdded
+			if event.Action == models.MembershipEventRemoved {
+				eventType = models.StreamEventMembershipRemoved
+			}
+			s.eventsSvc.Publish(ctx, eventType, models.ChangeSourceOkta, &models.StreamMembershipChange{
+				GroupID: event.GroupID,
+				UserID:  event.UserID,
+				Actor:   event.Actor,
+			})
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Membership changes ingested from Okta",
+		"since", since,
+		"until", until,
+		"eventCount", len(ingested),
+		"storedCount", len(events),
+	)
+	return nil
+}
+
+// GetEvents lists the stored events matching filter, newest first.
+func (s *Service) GetEvents(ctx context.Context, filter *models.MembershipEventFilter) (*models.MembershipEventList, error) {
+	s.mu.Lock()
+	err := s.load(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := &models.MembershipEventList{
+		IngestedSince: s.manifest.IngestedSince,
+		IngestedUntil: s.manifest.IngestedUntil,
+		Events:        make([]*models.MembershipEvent, 0),
+	}
+	for i := len(s.events) - 1; i >= 0; i-- {
+		if matches(s.events[i], filter) {
+			list.Events = append(list.Events, s.events[i])
+		}
+	}
+	list.Total = len(list.Events)
+
+	logger.FromContext(ctx, s.log).Infow("Membership events listed", "total", list.Total)
+	return list, nil
+}
+
+// GroupChanges returns the group's membership changes logged from since,
+// oldest first, up to until or as far as the store reaches, whichever is
+// earlier, and where that is. ok is false when the store does not hold the
+// changes logged at since.
+func (s *Service) GroupChanges(
+	ctx context.Context, groupID string, since, until time.Time,
+) (changes []*models.MembershipEvent, coveredUntil time.Time, ok bool, err error) {
+	s.mu.Lock()
+	err = s.load(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, time.Time{}, false, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if since.Before(s.manifest.IngestedSince) || !since.Before(s.manifest.IngestedUntil) {
+		return nil, time.Time{}, false, nil
+	}
+
+	coveredUntil = until
+	if s.manifest.IngestedUntil.Before(until) {
+		coveredUntil = s.manifest.IngestedUntil
+	}
+	filter := &models.MembershipEventFilter{GroupID: groupID, Since: since, Until: coveredUntil}
+	for _, event := range s.events {
+		if matches(event, filter) {
+			changes = append(changes, event)
+		}
+	}
+	return changes, coveredUntil, true, nil
+}
+
+// IngestedSince returns the oldest time the store holds changes from, or the
+// zero time before the first ingestion.
+func (s *Service) IngestedSince(ctx context.Context) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return time.Time{}, err
+	}
+	return s.manifest.IngestedSince, nil
+}
+
+// load reads the manifest and every stored day once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, manifestKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read membership event manifest: %w", err)
+	}
+	if err == nil {
+		var stored manifest
+		if err := json.Unmarshal(object.Data, &stored); err != nil {
+			return fmt.Errorf("failed to decode membership event manifest: %w", err)
+		}
+		s.manifest = &stored
+	}
+
+	events := make([]*models.MembershipEvent, 0)
+	for _, day := range s.manifest.Days {
+		object, err := s.store.Get(ctx, dayKey(day))
+		if errors.Is(err, objectstore.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read membership events of %s: %w", day, err)
+		}
+
+		var stored []*models.MembershipEvent
+		if err := json.Unmarshal(object.Data, &stored); err != nil {
+			return fmt.Errorf("failed to decode membership events of %s: %w", day, err)
+		}
+		events = append(events, stored...)
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Published.Before(events[j].Published) })
+
+	s.events = events
+	for _, event := range events {
+		s.ids[event.ID] = true
+	}
+	s.loaded = true
+	return nil
+}
+
+func (s *Service) saveDay(ctx context.Context, day string, events []*models.MembershipEvent) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return fmt.Errorf("failed to encode membership events: %w", err)
+	}
+	if err := s.store.Put(ctx, dayKey(day), "application/json", data); err != nil {
+		return fmt.Errorf("failed to store membership events: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) saveManifest(ctx context.Context, stored *manifest) error {
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to encode membership event manifest: %w", err)
+	}
+	if err := s.store.Put(ctx, manifestKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store membership event manifest: %w", err)
+	}
+	return nil
+}
+
+// newMembershipEvent returns the membership change a System Log event
+// records, or nil when it names no user or group.
+func newMembershipEvent(event *okta.LogEvent) *models.MembershipEvent {
+	membership := &models.MembershipEvent{
+		ID:        event.GetUuid(),
+		Action:    models.MembershipEventAdded,
+		Published: event.GetPublished().UTC(),
+		Actor: &models.LogActor{
+			ID:          event.Actor.GetId(),
+			Type:        event.Actor.GetType(),
+			AlternateID: event.Actor.GetAlternateId(),
+			DisplayName: event.Actor.GetDisplayName(),
+		},
+	}
+	if event.GetEventType() == membershipRemoved {
+		membership.Action = models.MembershipEventRemoved
+	}
+
+	for _, target := range event.Target {
+		switch target.GetType() {
+		case "User":
+			membership.UserID, membership.UserLogin = target.GetId(), target.GetAlternateId()
+		case "UserGroup":
+			membership.GroupID, membership.GroupName = target.GetId(), target.GetDisplayName()
+		}
+	}
+
+	if membership.ID == "" || membership.UserID == "" || membership.GroupID == "" {
+		return nil
+	}
+	return membership
+}
+
+func matches(event *models.MembershipEvent, filter *models.MembershipEventFilter) bool {
+	switch {
+	case filter.GroupID != "" && event.GroupID != filter.GroupID:
+		return false
+	case filter.UserID != "" && event.UserID != filter.UserID:
+		return false
+	case filter.ActorID != "" && event.Actor.ID != filter.ActorID:
+		return false
+	case filter.Action != "" && event.Action != filter.Action:
+		return false
+	case !filter.Since.IsZero() && event.Published.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !event.Published.Before(filter.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// eventsOn returns the events logged on day.
+func eventsOn(events []*models.MembershipEvent, day string) []*models.MembershipEvent {
+	result := make([]*models.MembershipEvent, 0)
+	for _, event := range events {
+		if dayOf(event.Published) == day {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+func containsDay(days []string, day string) bool {
+	i := sort.SearchStrings(days, day)
+	return i < len(days) && days[i] == day
+}
+
+func dayOf(at time.Time) string {
+	return at.UTC().Format(time.DateOnly)
+}
+
+func startOfDay(at time.Time) time.Time {
+	return at.UTC().Truncate(24 * time.Hour)
+}
+
+func dayKey(day string) string {
+	return "events/" + day + ".json"
+}
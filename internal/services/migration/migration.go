@@ -0,0 +1,611 @@
+package migration_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	sync_service "github.com/iamBelugaa/iam/internal/services/sync"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+var (
+	ErrMigrationNotFound   = errors.New("migration not found")
+	ErrUnknownOrg          = errors.New("unknown org")
+	ErrSameOrg             = errors.New("source and target org must differ")
+	ErrInvalidConflict     = errors.New("conflict strategy must be SKIP, OVERWRITE or FAIL")
+	ErrSourceGroupNotFound = errors.New("group not found in the source org")
+	ErrUnresolvedConflicts = errors.New("the plan has conflicts")
+	ErrImportFailures      = errors.New("some items could not be imported")
+	errTargetMissing       = errors.New("the user or group could not be created in the target org")
+)
+
+// Service moves users, groups and memberships between configured orgs, such
+// as when consolidating orgs. Every migration runs as a job: export reads the
+// source org, plan matches it against the target org, and import carries the
+// plan out. Migrations are kept in memory, like the jobs that run them.
+type Service struct {
+	log      *zap.SugaredLogger
+	clients  map[string]*okta.APIClient
+	jobsSvc  *job_service.Service
+	auditSvc *audit_service.Service
+
+	mu         sync.RWMutex
+	migrations map[string]*models.Migration
+}
+
+// New creates the service. clients holds the client of every org by name,
+// the primary org included.
+func New(
+	log *zap.SugaredLogger, clients map[string]*okta.APIClient,
+	jobsSvc *job_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:        log,
+		clients:    clients,
+		jobsSvc:    jobsSvc,
+		auditSvc:   auditSvc,
+		migrations: make(map[string]*models.Migration),
+	}
+}
+
+// export is what the export step reads from the source org: the profiles of
+// users and the members of groups, both by source ID.
+type export struct {
+	users   map[string]*okta.UserProfile
+	groups  []*okta.Group
+	members map[string][]string
+}
+
+// Start validates the request and starts its job. The returned migration has
+// no plan yet.
+func (s *Service) Start(ctx context.Context, actor string, req *models.MigrationRequest) (*models.Migration, error) {
+	source, ok := s.clients[req.SourceOrg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOrg, req.SourceOrg)
+	}
+	target, ok := s.clients[req.TargetOrg]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownOrg, req.TargetOrg)
+	}
+	if req.SourceOrg == req.TargetOrg {
+		return nil, ErrSameOrg
+	}
+
+	if req.ConflictStrategy == "" {
+		req.ConflictStrategy = models.ConflictStrategySkip
+	}
+	switch req.ConflictStrategy {
+	case models.ConflictStrategySkip, models.ConflictStrategyOverwrite, models.ConflictStrategyFail:
+	default:
+		return nil, ErrInvalidConflict
+	}
+
+	steps := []string{models.MigrationStepExport, models.MigrationStepPlan}
+	if !req.DryRun {
+		steps = append(steps, models.MigrationStepImport)
+	}
+	tracker := s.jobsSvc.Create(models.JobTypeOrgMigration, models.ResourceTypeOrg, req.TargetOrg, steps)
+
+	migration := &models.Migration{
+		ID:               uuid.NewString(),
+		SourceOrg:        req.SourceOrg,
+		TargetOrg:        req.TargetOrg,
+		GroupIDs:         req.GroupIDs,
+		ConflictStrategy: req.ConflictStrategy,
+		DryRun:           req.DryRun,
+		CreatedBy:        actor,
+		Created:          time.Now().UTC(),
+		Job:              tracker.Job(),
+	}
+
+	s.mu.Lock()
+	s.migrations[migration.ID] = migration
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Starting org migration",
+		"migrationId", migration.ID, "sourceOrg", req.SourceOrg, "targetOrg", req.TargetOrg,
+		"groupCount", len(req.GroupIDs), "dryRun", req.DryRun, "jobId", tracker.JobID(),
+	)
+
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		return s.run(ctx, tracker, migration.ID, actor, source, target, req)
+	})
+
+	return s.withJob(migration), nil
+}
+
+// Migrations lists every migration, newest first, without their plans.
+func (s *Service) Migrations(ctx context.Context) []*models.Migration {
+	s.mu.RLock()
+	result := make([]*models.Migration, 0, len(s.migrations))
+	for _, migration := range s.migrations {
+		summary := *migration
+		summary.Plan = nil
+		result = append(result, &summary)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	for i, migration := range result {
+		result[i] = s.withJob(migration)
+	}
+	return result
+}
+
+// Migration returns a migration with its plan.
+func (s *Service) Migration(ctx context.Context, migrationID string) (*models.Migration, error) {
+	s.mu.RLock()
+	migration, ok := s.migrations[migrationID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrMigrationNotFound
+	}
+	return s.withJob(migration), nil
+}
+
+// withJob returns a copy of migration with the current state of its job.
+// Plans are replaced rather than changed once stored, so the copy can share
+// them.
+func (s *Service) withJob(migration *models.Migration) *models.Migration {
+	s.mu.RLock()
+	copied := *migration
+	s.mu.RUnlock()
+
+	if job, err := s.jobsSvc.GetJob(context.Background(), copied.Job.ID); err == nil {
+		copied.Job = job
+	}
+	return &copied
+}
+
+func (s *Service) setPlan(migrationID string, plan *models.MigrationPlan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.migrations[migrationID].Plan = copyPlan(plan)
+}
+
+func (s *Service) run(
+	ctx context.Context, tracker *job_service.Tracker, migrationID, actor string,
+	source, target *okta.APIClient, req *models.MigrationRequest,
+) error {
+	var exported *export
+	err := tracker.Step(models.MigrationStepExport, func() (string, error) {
+		var err error
+		if exported, err = s.export(ctx, source, req.GroupIDs); err != nil {
+			return "", err
+		}
+
+		memberships := 0
+		for _, members := range exported.members {
+			memberships += len(members)
+		}
+		return fmt.Sprintf("Exported %d users, %d groups and %d memberships",
+			len(exported.users), len(exported.groups), memberships), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var plan *models.MigrationPlan
+	var profiles map[string]*okta.UserProfile
+	err = tracker.Step(models.MigrationStepPlan, func() (string, error) {
+		var err error
+		if plan, profiles, err = s.plan(ctx, target, exported, req); err != nil {
+			return "", err
+		}
+		s.setPlan(migrationID, plan)
+
+		if plan.Summary.Conflicts > 0 {
+			return "", fmt.Errorf("%w: %d users and groups already exist in the target org", ErrUnresolvedConflicts, plan.Summary.Conflicts)
+		}
+		return describePlan(&plan.Summary), nil
+	})
+	if err != nil || req.DryRun {
+		return err
+	}
+
+	err = tracker.Step(models.MigrationStepImport, func() (string, error) {
+		s.importPlan(ctx, target, exported, plan, profiles, req)
+		s.setPlan(migrationID, plan)
+
+		if plan.Summary.Failed > 0 {
+			return "", fmt.Errorf("%w: %d failed", ErrImportFailures, plan.Summary.Failed)
+		}
+		return fmt.Sprintf("Created %d users and %d groups, updated %d users and %d groups, added %d memberships",
+			plan.Summary.UsersToCreate, plan.Summary.GroupsToCreate, plan.Summary.UsersToUpdate,
+			plan.Summary.GroupsToUpdate, plan.Summary.MembershipsToAdd,
+		), nil
+	})
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionOrgMigrated,
+		ResourceType: models.ResourceTypeOrg,
+		ResourceID:   req.TargetOrg,
+		Details: map[string]any{
+			"migrationId": migrationID,
+			"jobId":       tracker.JobID(),
+			"sourceOrg":   req.SourceOrg,
+			"summary":     plan.Summary,
+		},
+	})
+	return err
+}
+
+// export reads the users, groups and memberships to migrate. Without
+// groupIDs that is every user and every group mastered by Okta; groups of
+// apps and built-in groups such as Everyone stay behind.
+func (s *Service) export(ctx context.Context, source *okta.APIClient, groupIDs []string) (*export, error) {
+	exported := &export{users: make(map[string]*okta.UserProfile), members: make(map[string][]string)}
+
+	if len(groupIDs) == 0 {
+		users, response, err := source.UserAPI.ListUsers(ctx).Execute()
+		if err == nil {
+			users, err = pagination.All(users, response)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source users: %w", err)
+		}
+		for i := range users {
+			exported.users[users[i].GetId()] = users[i].Profile
+		}
+
+		groups, response, err := source.GroupAPI.ListGroups(ctx).Execute()
+		if err == nil {
+			groups, err = pagination.All(groups, response)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list source groups: %w", err)
+		}
+		for i := range groups {
+			if groups[i].GetType() == models.GroupTypeOkta {
+				exported.groups = append(exported.groups, &groups[i])
+			}
+		}
+	} else {
+		for _, groupID := range groupIDs {
+			group, response, err := source.GroupAPI.GetGroup(ctx, groupID).Execute()
+			if err != nil {
+				if okta_client.StatusCode(response) == http.StatusNotFound {
+					return nil, fmt.Errorf("%w: %s", ErrSourceGroupNotFound, groupID)
+				}
+				return nil, fmt.Errorf("failed to get source group %s: %w", groupID, err)
+			}
+			exported.groups = append(exported.groups, group)
+		}
+	}
+
+	for _, group := range exported.groups {
+		members, response, err := source.GroupAPI.ListGroupUsers(ctx, group.GetId()).Execute()
+		if err == nil {
+			members, err = pagination.All(members, response)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list members of source group %s: %w", group.GetId(), err)
+		}
+
+		for i := range members {
+			userID := members[i].GetId()
+			exported.members[group.GetId()] = append(exported.members[group.GetId()], userID)
+			if _, ok := exported.users[userID]; !ok && len(groupIDs) > 0 {
+				exported.users[userID] = members[i].Profile
+			}
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Exported source org",
+		"userCount", len(exported.users), "groupCount", len(exported.groups),
+	)
+	return exported, nil
+}
+
+// plan matches the export against the target org. It also returns the
+// mapped profile of every user by source ID, for the import to send.
+func (s *Service) plan(
+	ctx context.Context, target *okta.APIClient, exported *export, req *models.MigrationRequest,
+) (*models.MigrationPlan, map[string]*okta.UserProfile, error) {
+	targetUsers, response, err := target.UserAPI.ListUsers(ctx).Execute()
+	if err == nil {
+		targetUsers, err = pagination.All(targetUsers, response)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list target users: %w", err)
+	}
+	userIDs := make(map[string]string, len(targetUsers))
+	for i := range targetUsers {
+		if targetUsers[i].Profile != nil {
+			userIDs[targetUsers[i].Profile.GetLogin()] = targetUsers[i].GetId()
+		}
+	}
+
+	targetGroups, response, err := target.GroupAPI.ListGroups(ctx).Execute()
+	if err == nil {
+		targetGroups, err = pagination.All(targetGroups, response)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list target groups: %w", err)
+	}
+	groupIDs := make(map[string]string, len(targetGroups))
+	for i := range targetGroups {
+		if targetGroups[i].Profile != nil {
+			groupIDs[targetGroups[i].Profile.GetName()] = targetGroups[i].GetId()
+		}
+	}
+
+	plan := &models.MigrationPlan{
+		Users:       make([]*models.MigrationItem, 0, len(exported.users)),
+		Groups:      make([]*models.MigrationItem, 0, len(exported.groups)),
+		Memberships: make([]*models.MigrationMembership, 0),
+	}
+	profiles := make(map[string]*okta.UserProfile, len(exported.users))
+	logins := make(map[string]string, len(exported.users))
+
+	for userID, sourceProfile := range exported.users {
+		profile, err := sync_service.MapProfile(sourceProfile, req.FieldMapping)
+		if err != nil {
+			return nil, nil, fmt.Errorf("user %s: %w", userID, err)
+		}
+		profiles[userID] = profile
+		logins[userID] = profile.GetLogin()
+
+		item := &models.MigrationItem{SourceID: userID, Key: profile.GetLogin()}
+		item.TargetID, item.Action = resolve(userIDs, item.Key, req.ConflictStrategy)
+		plan.Users = append(plan.Users, item)
+	}
+
+	// Only the memberships of groups that already exist in the target org can
+	// already be there.
+	existingMembers := make(map[string]map[string]bool)
+	groupNames := make(map[string]string, len(exported.groups))
+	for _, group := range exported.groups {
+		item := &models.MigrationItem{SourceID: group.GetId()}
+		if group.Profile != nil {
+			item.Key = group.Profile.GetName()
+		}
+		groupNames[group.GetId()] = item.Key
+		item.TargetID, item.Action = resolve(groupIDs, item.Key, req.ConflictStrategy)
+		plan.Groups = append(plan.Groups, item)
+
+		if item.TargetID == "" {
+			continue
+		}
+		members, response, err := target.GroupAPI.ListGroupUsers(ctx, item.TargetID).Execute()
+		if err == nil {
+			members, err = pagination.All(members, response)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list members of target group %s: %w", item.TargetID, err)
+		}
+		existingMembers[item.TargetID] = make(map[string]bool, len(members))
+		for i := range members {
+			existingMembers[item.TargetID][members[i].GetId()] = true
+		}
+	}
+
+	for _, group := range plan.Groups {
+		for _, userID := range exported.members[group.SourceID] {
+			if _, ok := logins[userID]; !ok {
+				// Deprovisioned users are not listed, so they are not
+				// migrated and neither are their memberships.
+				continue
+			}
+			membership := &models.MigrationMembership{
+				GroupKey: group.Key,
+				UserKey:  logins[userID],
+				Action:   models.MigrationActionAdd,
+			}
+			if targetUserID := userIDs[membership.UserKey]; targetUserID != "" &&
+				existingMembers[group.TargetID][targetUserID] {
+				membership.Action = models.MigrationActionSkip
+			}
+			plan.Memberships = append(plan.Memberships, membership)
+		}
+	}
+
+	sort.Slice(plan.Users, func(i, j int) bool { return plan.Users[i].Key < plan.Users[j].Key })
+	sort.Slice(plan.Groups, func(i, j int) bool { return plan.Groups[i].Key < plan.Groups[j].Key })
+	sort.Slice(plan.Memberships, func(i, j int) bool {
+		a, b := plan.Memberships[i], plan.Memberships[j]
+		if a.GroupKey != b.GroupKey {
+			return a.GroupKey < b.GroupKey
+		}
+		return a.UserKey < b.UserKey
+	})
+	plan.Summary = summarize(plan)
+
+	logger.FromContext(ctx, s.log).Infow("Planned org migration", "summary", plan.Summary)
+	return plan, profiles, nil
+}
+
+// resolve decides what happens to a user or group, by key, given the IDs of
+// those already in the target org.
+func resolve(existing map[string]string, key, strategy string) (string, string) {
+	targetID, ok := existing[key]
+	switch {
+	case !ok:
+		return "", models.MigrationActionCreate
+	case strategy == models.ConflictStrategyOverwrite:
+		return targetID, models.MigrationActionUpdate
+	case strategy == models.ConflictStrategyFail:
+		return targetID, models.MigrationActionConflict
+	default:
+		return targetID, models.MigrationActionSkip
+	}
+}
+
+// importPlan carries out the plan, recording the outcome of every item. A
+// failed item does not stop the others; memberships of users or groups that
+// could not be created fail with them.
+func (s *Service) importPlan(
+	ctx context.Context, target *okta.APIClient, exported *export,
+	plan *models.MigrationPlan, profiles map[string]*okta.UserProfile, req *models.MigrationRequest,
+) {
+	userIDs := make(map[string]string, len(plan.Users))
+	for _, item := range plan.Users {
+		profile := profiles[item.SourceID]
+
+		switch item.Action {
+		case models.MigrationActionCreate:
+			created, _, err := target.UserAPI.CreateUser(ctx).
+				Body(okta.CreateUserRequest{Profile: *profile}).Activate(req.Activate).Execute()
+			if err != nil {
+				fail(&item.Outcome, &item.Error, fmt.Errorf("failed to create target user: %w", err))
+				continue
+			}
+			item.TargetID = created.GetId()
+			item.Outcome = models.SyncOutcomeCreated
+		case models.MigrationActionUpdate:
+			if _, _, err := target.UserAPI.UpdateUser(ctx, item.TargetID).
+				User(okta.UpdateUserRequest{Profile: profile}).Execute(); err != nil {
+				fail(&item.Outcome, &item.Error, fmt.Errorf("failed to update target user: %w", err))
+				continue
+			}
+			item.Outcome = models.SyncOutcomeUpdated
+		default:
+			item.Outcome = models.SyncOutcomeSkipped
+		}
+		userIDs[item.Key] = item.TargetID
+	}
+
+	groupsBySource := make(map[string]*okta.Group, len(exported.groups))
+	for _, group := range exported.groups {
+		groupsBySource[group.GetId()] = group
+	}
+
+	groupIDs := make(map[string]string, len(plan.Groups))
+	for _, item := range plan.Groups {
+		profile := okta.GroupProfile{}
+		if source := groupsBySource[item.SourceID]; source.Profile != nil {
+			profile.Name = source.Profile.Name
+			profile.Description = source.Profile.Description
+		}
+
+		switch item.Action {
+		case models.MigrationActionCreate:
+			created, _, err := target.GroupAPI.CreateGroup(ctx).Group(okta.Group{Profile: &profile}).Execute()
+			if err != nil {
+				fail(&item.Outcome, &item.Error, fmt.Errorf("failed to create target group: %w", err))
+				continue
+			}
+			item.TargetID = created.GetId()
+			item.Outcome = models.SyncOutcomeCreated
+		case models.MigrationActionUpdate:
+			if _, _, err := target.GroupAPI.ReplaceGroup(ctx, item.TargetID).
+				Group(okta.Group{Profile: &profile}).Execute(); err != nil {
+				fail(&item.Outcome, &item.Error, fmt.Errorf("failed to update target group: %w", err))
+				continue
+			}
+			item.Outcome = models.SyncOutcomeUpdated
+		default:
+			item.Outcome = models.SyncOutcomeSkipped
+		}
+		groupIDs[item.Key] = item.TargetID
+	}
+
+	for _, membership := range plan.Memberships {
+		if membership.Action != models.MigrationActionAdd {
+			membership.Outcome = models.SyncOutcomeSkipped
+			continue
+		}
+
+		groupID, userID := groupIDs[membership.GroupKey], userIDs[membership.UserKey]
+		if groupID == "" || userID == "" {
+			fail(&membership.Outcome, &membership.Error, errTargetMissing)
+			continue
+		}
+		if _, err := target.GroupAPI.AssignUserToGroup(ctx, groupID, userID).Execute(); err != nil {
+			fail(&membership.Outcome, &membership.Error, fmt.Errorf("failed to add target group member: %w", err))
+			continue
+		}
+		membership.Outcome = models.SyncOutcomeCreated
+	}
+
+	plan.Summary = summarize(plan)
+	logger.FromContext(ctx, s.log).Infow("Imported org migration", "summary", plan.Summary)
+}
+
+func fail(outcome, message *string, err error) {
+	*outcome = models.SyncOutcomeFailed
+	*message = err.Error()
+}
+
+func summarize(plan *models.MigrationPlan) models.MigrationSummary {
+	var summary models.MigrationSummary
+	count := func(item *models.MigrationItem, create, update *int) {
+		switch item.Action {
+		case models.MigrationActionCreate:
+			*create++
+		case models.MigrationActionUpdate:
+			*update++
+		case models.MigrationActionConflict:
+			summary.Conflicts++
+		default:
+			summary.Skipped++
+		}
+		if item.Outcome == models.SyncOutcomeFailed {
+			summary.Failed++
+		}
+	}
+
+	for _, item := range plan.Users {
+		count(item, &summary.UsersToCreate, &summary.UsersToUpdate)
+	}
+	for _, item := range plan.Groups {
+		count(item, &summary.GroupsToCreate, &summary.GroupsToUpdate)
+	}
+	for _, membership := range plan.Memberships {
+		if membership.Action == models.MigrationActionAdd {
+			summary.MembershipsToAdd++
+		} else {
+			summary.Skipped++
+		}
+		if membership.Outcome == models.SyncOutcomeFailed {
+			summary.Failed++
+		}
+	}
+	return summary
+}
+
+func describePlan(summary *models.MigrationSummary) string {
+	return fmt.Sprintf("%d users to create and %d to update, %d groups to create and %d to update, "+
+		"%d memberships to add, %d unchanged",
+		summary.UsersToCreate, summary.UsersToUpdate, summary.GroupsToCreate, summary.GroupsToUpdate,
+		summary.MembershipsToAdd, summary.Skipped,
+	)
+}
+
+func copyPlan(plan *models.MigrationPlan) *models.MigrationPlan {
+	copied := &models.MigrationPlan{
+		Summary:     plan.Summary,
+		Users:       make([]*models.MigrationItem, len(plan.Users)),
+		Groups:      make([]*models.MigrationItem, len(plan.Groups)),
+		Memberships: make([]*models.MigrationMembership, len(plan.Memberships)),
+	}
+	for i, item := range plan.Users {
+		itemCopy := *item
+		copied.Users[i] = &itemCopy
+	}
+	for i, item := range plan.Groups {
+		itemCopy := *item
+		copied.Groups[i] = &itemCopy
+	}
+	for i, membership := range plan.Memberships {
+		membershipCopy := *membership
+		copied.Memberships[i] = &membershipCopy
+	}
+	return copied
+}
@@ -0,0 +1,471 @@
+package pendingchange_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const (
+	stateKey = "state.json"
+	// expiryActor is the audit actor of changes that went stale.
+	expiryActor = "system:pending-changes"
+)
+
+var (
+	ErrChangeNotFound    = errors.New("pending change not found")
+	ErrGroupNotProtected = errors.New("group is not under the two-person rule")
+	ErrGroupProtected    = errors.New("group is already under the two-person rule")
+	ErrRequesterRequired = errors.New("membership changes to protected groups require an authenticated caller")
+	ErrSelfApproval      = errors.New("changes must be approved by an admin other than the one who made them")
+	ErrChangeNotPending  = errors.New("pending change has already been decided")
+	ErrChangeStale       = errors.New("pending change has expired")
+	ErrDuplicateChange   = errors.New("the same change to this membership is already pending")
+	ErrMembershipExpiry  = errors.New("membershipExpiresAt must be in the future")
+	ErrInvalidStatus     = errors.New("status must be PENDING, APPROVED, REJECTED or EXPIRED")
+	ErrChangesPending    = errors.New("group has pending changes; approve or reject them first")
+)
+
+// state is what the service stores, as one object on every change.
+type state struct {
+	Groups  map[string]*models.ChangeProtectedGroup    `json:"groups"`
+	Changes map[string]*models.PendingMembershipChange `json:"changes"`
+}
+
+// Service enforces the two-person rule on groups marked as protected: their
+// membership changes are held as pending changes, applied once a second
+// admin approves them and expired when no one decides in time. Who counts as
+// an admin is decided by the routes; the service only keeps requesters from
+// approving their own changes.
+type Service struct {
+	log       *zap.SugaredLogger
+	cfg       *config.PendingChangesConfig
+	store     objectstore.Store
+	groupsSvc *group_service.Service
+	auditSvc  *audit_service.Service
+
+	mu     sync.Mutex
+	state  state
+	loaded bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.PendingChangesConfig, store objectstore.Store,
+	groupsSvc *group_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:       log,
+		cfg:       cfg,
+		store:     store,
+		groupsSvc: groupsSvc,
+		auditSvc:  auditSvc,
+		state: state{
+			Groups:  make(map[string]*models.ChangeProtectedGroup),
+			Changes: make(map[string]*models.PendingMembershipChange),
+		},
+	}
+}
+
+// ProtectedGroups returns the groups under the two-person rule by group ID.
+func (s *Service) ProtectedGroups(ctx context.Context) ([]*models.ChangeProtectedGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	groups := make([]*models.ChangeProtectedGroup, 0, len(s.state.Groups))
+	for _, group := range s.state.Groups {
+		copied := *group
+		groups = append(groups, &copied)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].GroupID < groups[j].GroupID })
+
+	return groups, nil
+}
+
+// IsProtected reports whether the group is under the two-person rule.
+func (s *Service) IsProtected(ctx context.Context, groupID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return false, err
+	}
+
+	_, ok := s.state.Groups[groupID]
+	return ok, nil
+}
+
+// Protect puts the group under the two-person rule.
+func (s *Service) Protect(ctx context.Context, actor, groupID string) (*models.ChangeProtectedGroup, error) {
+	// Make sure the group exists before holding its changes.
+	if _, err := s.groupsSvc.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if _, ok := s.state.Groups[groupID]; ok {
+		return nil, ErrGroupProtected
+	}
+
+	group := &models.ChangeProtectedGroup{GroupID: groupID, ProtectedBy: actor, Protected: time.Now().UTC()}
+	s.state.Groups[groupID] = group
+	if err := s.save(ctx); err != nil {
+		delete(s.state.Groups, groupID)
+		return nil, err
+	}
+
+	s.record(ctx, actor, models.AuditActionGroupChangesProtected, groupID, nil)
+
+	copied := *group
+	return &copied, nil
+}
+
+// Unprotect takes the group out of the two-person rule. A group with changes
+// still pending cannot be, so that they are not left without a rule.
+func (s *Service) Unprotect(ctx context.Context, actor, groupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	group, ok := s.state.Groups[groupID]
+	if !ok {
+		return ErrGroupNotProtected
+	}
+	now := time.Now()
+	for _, change := range s.state.Changes {
+		if change.GroupID == groupID && change.Status == models.PendingChangeStatusPending && now.Before(change.StaleAt) {
+			return ErrChangesPending
+		}
+	}
+
+	delete(s.state.Groups, groupID)
+	if err := s.save(ctx); err != nil {
+		s.state.Groups[groupID] = group
+		return err
+	}
+
+	s.record(ctx, actor, models.AuditActionGroupChangesUnprotected, groupID, nil)
+	return nil
+}
+
+// Propose holds a membership change to a protected group for approval.
+// membershipExpiresAt bounds the membership an ADD grants; nil is permanent.
+func (s *Service) Propose(
+	ctx context.Context, requester, groupID, userID, operation string, membershipExpiresAt *time.Time,
+) (*models.PendingMembershipChange, error) {
+	if requester == "" {
+		return nil, ErrRequesterRequired
+	}
+
+	now := time.Now().UTC()
+	if membershipExpiresAt != nil && !membershipExpiresAt.After(now) {
+		return nil, ErrMembershipExpiry
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if _, ok := s.state.Groups[groupID]; !ok {
+		return nil, ErrGroupNotProtected
+	}
+
+	for _, existing := range s.state.Changes {
+		if existing.GroupID == groupID && existing.UserID == userID && existing.Operation == operation &&
+			existing.Status == models.PendingChangeStatusPending && now.Before(existing.StaleAt) {
+			return nil, ErrDuplicateChange
+		}
+	}
+
+	change := &models.PendingMembershipChange{
+		ID:                  uuid.NewString(),
+		GroupID:             groupID,
+		UserID:              userID,
+		Operation:           operation,
+		MembershipExpiresAt: membershipExpiresAt,
+		Status:              models.PendingChangeStatusPending,
+		RequestedBy:         requester,
+		Requested:           now,
+		StaleAt:             now.Add(s.cfg.TTL),
+	}
+	s.state.Changes[change.ID] = change
+	if err := s.save(ctx); err != nil {
+		delete(s.state.Changes, change.ID)
+		return nil, err
+	}
+
+	s.record(ctx, requester, models.AuditActionPendingChangeCreated, groupID, map[string]any{
+		"pendingChangeId": change.ID,
+		"userId":          userID,
+		"operation":       operation,
+	})
+
+	copied := *change
+	return &copied, nil
+}
+
+// Change returns a pending change, whatever its status.
+func (s *Service) Change(ctx context.Context, changeID string) (*models.PendingMembershipChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	change, ok := s.state.Changes[changeID]
+	if !ok {
+		return nil, ErrChangeNotFound
+	}
+
+	copied := *change
+	return &copied, nil
+}
+
+// Changes returns the changes matching the filter, newest first.
+func (s *Service) Changes(ctx context.Context, filter *models.PendingChangeFilter) ([]*models.PendingMembershipChange, error) {
+	switch filter.Status {
+	case "", models.PendingChangeStatusPending, models.PendingChangeStatusApproved,
+		models.PendingChangeStatusRejected, models.PendingChangeStatusExpired:
+	default:
+		return nil, ErrInvalidStatus
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	changes := make([]*models.PendingMembershipChange, 0)
+	for _, change := range s.state.Changes {
+		if filter.Status != "" && change.Status != filter.Status {
+			continue
+		}
+		if filter.GroupID != "" && change.GroupID != filter.GroupID {
+			continue
+		}
+
+		copied := *change
+		changes = append(changes, &copied)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Requested.After(changes[j].Requested) })
+
+	return changes, nil
+}
+
+// Approve applies the change and marks it approved. The approver must not be
+// the admin who made it. A change that fails to apply stays pending.
+func (s *Service) Approve(
+	ctx context.Context, approver, changeID string, decision *models.PendingChangeDecision,
+) (*models.PendingMembershipChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, err := s.decidable(ctx, changeID)
+	if err != nil {
+		return nil, err
+	}
+	if change.RequestedBy == approver {
+		return nil, ErrSelfApproval
+	}
+
+	switch change.Operation {
+	case models.PendingChangeOperationAdd:
+		err = s.groupsSvc.AddUserToGroup(ctx, change.GroupID, change.UserID, change.MembershipExpiresAt)
+	case models.PendingChangeOperationRemove:
+		err = s.groupsSvc.RemoveUserFromGroup(ctx, change.GroupID, change.UserID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply pending change: %w", err)
+	}
+
+	return s.decide(ctx, change, models.PendingChangeStatusApproved, approver, decision.Comment)
+}
+
+// Reject marks the change rejected without applying it. Requesters may
+// reject their own changes to withdraw them.
+func (s *Service) Reject(
+	ctx context.Context, approver, changeID string, decision *models.PendingChangeDecision,
+) (*models.PendingMembershipChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	change, err := s.decidable(ctx, changeID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.decide(ctx, change, models.PendingChangeStatusRejected, approver, decision.Comment)
+}
+
+// ExpireStale marks the pending changes no one decided on in time expired
+// and returns how many there were.
+func (s *Service) ExpireStale(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return 0, err
+	}
+
+	var expired []*models.PendingMembershipChange
+	for _, change := range s.state.Changes {
+		if change.Status == models.PendingChangeStatusPending && !now.Before(change.StaleAt) {
+			expired = append(expired, change)
+		}
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+
+	decided := now.UTC()
+	for _, change := range expired {
+		change.Status = models.PendingChangeStatusExpired
+		change.Decided = &decided
+	}
+	if err := s.save(ctx); err != nil {
+		for _, change := range expired {
+			change.Status, change.Decided = models.PendingChangeStatusPending, nil
+		}
+		return 0, err
+	}
+
+	for _, change := range expired {
+		s.record(ctx, expiryActor, models.AuditActionPendingChangeExpired, change.GroupID, map[string]any{
+			"pendingChangeId": change.ID,
+			"userId":          change.UserID,
+			"operation":       change.Operation,
+		})
+	}
+	return len(expired), nil
+}
+
+// decidable returns the change if it is still pending and not stale. Callers
+// hold mu.
+func (s *Service) decidable(ctx context.Context, changeID string) (*models.PendingMembershipChange, error) {
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	change, ok := s.state.Changes[changeID]
+	switch {
+	case !ok:
+		return nil, ErrChangeNotFound
+	case change.Status != models.PendingChangeStatusPending:
+		return nil, ErrChangeNotPending
+	case !time.Now().Before(change.StaleAt):
+		// The worker has not expired it yet.
+		return nil, ErrChangeStale
+	}
+	return change, nil
+}
+
+// decide records the outcome on the change, in the store and in the audit
+// trail. Callers hold mu.
+func (s *Service) decide(
+	ctx context.Context, change *models.PendingMembershipChange, status, approver, comment string,
+) (*models.PendingMembershipChange, error) {
+	previous := *change
+
+	now := time.Now().UTC()
+	change.Status = status
+	change.DecidedBy = approver
+	change.Decided = &now
+	change.Comment = comment
+	if err := s.save(ctx); err != nil {
+		// An approved change has been applied; keep the decision in memory
+		// so it is not applied twice, and report the failure to store it.
+		if status != models.PendingChangeStatusApproved {
+			*change = previous
+		}
+		return nil, err
+	}
+
+	action := models.AuditActionPendingChangeRejected
+	if status == models.PendingChangeStatusApproved {
+		action = models.AuditActionPendingChangeApproved
+	}
+	s.record(ctx, approver, action, change.GroupID, map[string]any{
+		"pendingChangeId": change.ID,
+		"userId":          change.UserID,
+		"operation":       change.Operation,
+		"requestedBy":     change.RequestedBy,
+		"comment":         comment,
+	})
+
+	copied := *change
+	return &copied, nil
+}
+
+func (s *Service) record(ctx context.Context, actor, action, groupID string, details map[string]any) {
+	logger.FromContext(ctx, s.log).Infow("Two-person rule event", "action", action, "groupId", groupID, "actor", actor)
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeGroup,
+		ResourceID:   groupID,
+		Details:      details,
+	})
+}
+
+// load reads the state from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, stateKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read pending changes: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.state); err != nil {
+			return fmt.Errorf("failed to decode pending changes: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the state. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(&s.state)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending changes: %w", err)
+	}
+	if err := s.store.Put(ctx, stateKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store pending changes: %w", err)
+	}
+	return nil
+}
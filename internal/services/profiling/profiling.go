@@ -0,0 +1,484 @@
+package profiling_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const requirementsKey = "requirements.json"
+
+var (
+	ErrRequirementNotFound = errors.New("profile requirement not found")
+	ErrAttributeRequired   = errors.New("attribute is required")
+	ErrAttributeProtected  = errors.New("login and email cannot be collected through progressive profiling")
+	ErrAttributeDefined    = errors.New("a requirement for this attribute already exists")
+	ErrLabelRequired       = errors.New("label is required")
+	ErrInvalidType         = errors.New("type must be string, number or boolean")
+	ErrInvalidPattern      = errors.New("pattern is not a valid regular expression")
+	ErrStringOnly          = errors.New("pattern and values only apply to string attributes")
+	ErrNoScope             = errors.New("at least one app or group ID is required")
+	ErrNoAttributes        = errors.New("at least one attribute is required")
+)
+
+// protectedAttributes identify the user to Okta and to the apps it signs
+// them in to, so users cannot change them from the portal.
+var protectedAttributes = []string{"login", "email"}
+
+// ValidationError is returned when submitted attributes are refused. None of
+// the submission is written when any attribute is.
+type ValidationError struct {
+	Errors []*models.ProfileAttributeError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, attributeErr := range e.Errors {
+		messages[i] = attributeErr.Attribute + ": " + attributeErr.Message
+	}
+	return fmt.Sprintf("profile attributes were refused: %s", strings.Join(messages, "; "))
+}
+
+// Service collects profile attributes from users a little at a time: the
+// requirements of the apps and groups a user has name the attributes the
+// portal asks them for, and their answers are validated and written to the
+// Okta profile. Requirements are stored as one object on every change.
+type Service struct {
+	log       *zap.SugaredLogger
+	store     objectstore.Store
+	usersSvc  *user_service.Service
+	groupsSvc *group_service.Service
+	appsSvc   *app_service.Service
+	auditSvc  *audit_service.Service
+
+	mu sync.Mutex
+	// requirements are keyed by ID. They are read from the store on first
+	// use.
+	requirements map[string]*models.ProfileRequirement
+	loaded       bool
+}
+
+func New(
+	log *zap.SugaredLogger, store objectstore.Store, usersSvc *user_service.Service,
+	groupsSvc *group_service.Service, appsSvc *app_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:          log,
+		store:        store,
+		usersSvc:     usersSvc,
+		groupsSvc:    groupsSvc,
+		appsSvc:      appsSvc,
+		auditSvc:     auditSvc,
+		requirements: make(map[string]*models.ProfileRequirement),
+	}
+}
+
+// Requirements returns the requirements in the order they were created.
+func (s *Service) Requirements(ctx context.Context) ([]*models.ProfileRequirement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	requirements := make([]*models.ProfileRequirement, 0, len(s.requirements))
+	for _, requirement := range s.requirements {
+		requirements = append(requirements, copyRequirement(requirement))
+	}
+	sort.Slice(requirements, func(i, j int) bool { return requirements[i].Created.Before(requirements[j].Created) })
+	return requirements, nil
+}
+
+// CreateRequirement adds a requirement after checking that its apps and
+// groups exist. An attribute has at most one requirement, so users are never
+// held to two definitions of it; more apps and groups share it instead.
+func (s *Service) CreateRequirement(
+	ctx context.Context, req *models.CreateProfileRequirementRequest,
+) (*models.ProfileRequirement, error) {
+	requirement := &models.ProfileRequirement{
+		Attribute:   strings.TrimSpace(req.Attribute),
+		Label:       strings.TrimSpace(req.Label),
+		Description: strings.TrimSpace(req.Description),
+		Type:        req.Type,
+		Pattern:     req.Pattern,
+		Values:      slices.Clone(req.Values),
+	}
+	if requirement.Type == "" {
+		requirement.Type = models.ProfileAttributeTypeString
+	}
+
+	switch {
+	case requirement.Attribute == "":
+		return nil, ErrAttributeRequired
+	case slices.Contains(protectedAttributes, requirement.Attribute):
+		return nil, ErrAttributeProtected
+	case requirement.Label == "":
+		return nil, ErrLabelRequired
+	case requirement.Type != models.ProfileAttributeTypeString &&
+		requirement.Type != models.ProfileAttributeTypeNumber &&
+		requirement.Type != models.ProfileAttributeTypeBoolean:
+		return nil, ErrInvalidType
+	case requirement.Type != models.ProfileAttributeTypeString && (requirement.Pattern != "" || len(requirement.Values) > 0):
+		return nil, ErrStringOnly
+	case len(req.AppIDs) == 0 && len(req.GroupIDs) == 0:
+		return nil, ErrNoScope
+	}
+	if requirement.Pattern != "" {
+		if _, err := compilePattern(requirement.Pattern); err != nil {
+			return nil, ErrInvalidPattern
+		}
+	}
+
+	for _, appID := range req.AppIDs {
+		if slices.Contains(requirement.AppIDs, appID) {
+			continue
+		}
+		if _, err := s.appsSvc.GetApp(ctx, appID); err != nil {
+			return nil, err
+		}
+		requirement.AppIDs = append(requirement.AppIDs, appID)
+	}
+	for _, groupID := range req.GroupIDs {
+		if slices.Contains(requirement.GroupIDs, groupID) {
+			continue
+		}
+		if _, err := s.groupsSvc.GetGroup(ctx, groupID); err != nil {
+			return nil, err
+		}
+		requirement.GroupIDs = append(requirement.GroupIDs, groupID)
+	}
+
+	requirement.ID = uuid.NewString()
+	requirement.Created = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, existing := range s.requirements {
+		if existing.Attribute == requirement.Attribute {
+			return nil, ErrAttributeDefined
+		}
+	}
+
+	s.requirements[requirement.ID] = requirement
+	if err := s.save(ctx); err != nil {
+		delete(s.requirements, requirement.ID)
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Profile requirement created",
+		"requirementId", requirement.ID, "attribute", requirement.Attribute,
+		"appCount", len(requirement.AppIDs), "groupCount", len(requirement.GroupIDs),
+	)
+	return copyRequirement(requirement), nil
+}
+
+// DeleteRequirement stops asking for the attribute. Values users already
+// gave stay in their profiles.
+func (s *Service) DeleteRequirement(ctx context.Context, requirementID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	requirement, ok := s.requirements[requirementID]
+	if !ok {
+		return ErrRequirementNotFound
+	}
+
+	delete(s.requirements, requirementID)
+	if err := s.save(ctx); err != nil {
+		s.requirements[requirementID] = requirement
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Profile requirement deleted", "requirementId", requirementID)
+	return nil
+}
+
+// Missing lists the attributes the user's apps and groups require that their
+// profile leaves empty, in the order the requirements were created.
+func (s *Service) Missing(ctx context.Context, userID string) ([]*models.MissingProfileAttribute, error) {
+	logger.FromContext(ctx, s.log).Infow("Finding missing profile attributes", "userId", userID)
+
+	applicable, profile, err := s.applicable(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := make([]*models.MissingProfileAttribute, 0)
+	for _, entry := range applicable {
+		if isEmpty(profile[entry.requirement.Attribute]) {
+			missing = append(missing, entry.missing())
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Missing profile attributes found", "userId", userID, "count", len(missing))
+	return missing, nil
+}
+
+// Submit validates the attributes against the requirements that apply to the
+// user and writes them to their Okta profile together. Only attributes the
+// user is asked for are accepted; one already filled in can be corrected.
+func (s *Service) Submit(
+	ctx context.Context, actor, userID string, req *models.SubmitProfileAttributesRequest,
+) (*models.ProfileSubmission, error) {
+	if len(req.Attributes) == 0 {
+		return nil, ErrNoAttributes
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Submitting profile attributes", "userId", userID, "count", len(req.Attributes))
+
+	applicable, _, err := s.applicable(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(req.Attributes))
+	for name := range req.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var attributeErrs []*models.ProfileAttributeError
+	profile := make(map[string]any, len(names))
+	for _, name := range names {
+		index := slices.IndexFunc(applicable, func(entry *applicableRequirement) bool {
+			return entry.requirement.Attribute == name
+		})
+		if index < 0 {
+			attributeErrs = append(attributeErrs, &models.ProfileAttributeError{
+				Attribute: name, Message: "is not requested of this user",
+			})
+			continue
+		}
+
+		value, err := validate(applicable[index].requirement, req.Attributes[name])
+		if err != nil {
+			attributeErrs = append(attributeErrs, &models.ProfileAttributeError{Attribute: name, Message: err.Error()})
+			continue
+		}
+		profile[name] = value
+	}
+	if len(attributeErrs) > 0 {
+		logger.FromContext(ctx, s.log).Infow("Profile attributes refused", "userId", userID, "errorCount", len(attributeErrs))
+		return nil, &ValidationError{Errors: attributeErrs}
+	}
+
+	user, err := s.usersSvc.UpdateUser(ctx, userID, &models.UpdateUserRequest{Profile: profile})
+	if err != nil {
+		return nil, err
+	}
+
+	// Values are personal data, so only the attribute names are audited.
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionProfileAttributesSubmitted,
+		ResourceType: models.ResourceTypeUser,
+		ResourceID:   user.ID,
+		Details:      map[string]any{"attributes": names},
+	})
+
+	submission := &models.ProfileSubmission{Updated: names, Missing: make([]*models.MissingProfileAttribute, 0)}
+	for _, entry := range applicable {
+		if _, ok := profile[entry.requirement.Attribute]; !ok && isEmpty(entry.value) {
+			submission.Missing = append(submission.Missing, entry.missing())
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Profile attributes submitted",
+		"userId", userID, "updated", len(names), "missing", len(submission.Missing),
+	)
+	return submission, nil
+}
+
+// applicableRequirement is a requirement that applies to a user, with the
+// apps and groups of theirs it applies through and their current value.
+type applicableRequirement struct {
+	requirement *models.ProfileRequirement
+	requiredBy  []*models.ProfileRequirer
+	value       any
+}
+
+func (a *applicableRequirement) missing() *models.MissingProfileAttribute {
+	return &models.MissingProfileAttribute{
+		Attribute:   a.requirement.Attribute,
+		Label:       a.requirement.Label,
+		Description: a.requirement.Description,
+		Type:        a.requirement.Type,
+		Pattern:     a.requirement.Pattern,
+		Values:      a.requirement.Values,
+		RequiredBy:  a.requiredBy,
+	}
+}
+
+// applicable returns the requirements that apply to the user through their
+// apps and groups, and the user's profile.
+func (s *Service) applicable(
+	ctx context.Context, userID string,
+) ([]*applicableRequirement, map[string]any, error) {
+	profile, err := s.usersSvc.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	requirements, err := s.Requirements(ctx)
+	if err != nil || len(requirements) == 0 {
+		return nil, profile, err
+	}
+
+	groups, err := s.usersSvc.GetUserGroups(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	apps, err := s.appsSvc.GetUserApps(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var applicable []*applicableRequirement
+	for _, requirement := range requirements {
+		var requiredBy []*models.ProfileRequirer
+		for _, app := range apps {
+			if slices.Contains(requirement.AppIDs, app.ID) {
+				requiredBy = append(requiredBy, &models.ProfileRequirer{
+					ResourceType: models.ResourceTypeApp, ID: app.ID, Name: app.Label,
+				})
+			}
+		}
+		for _, group := range groups {
+			if slices.Contains(requirement.GroupIDs, group.ID) {
+				requiredBy = append(requiredBy, &models.ProfileRequirer{
+					ResourceType: models.ResourceTypeGroup, ID: group.ID, Name: group.Name,
+				})
+			}
+		}
+		if len(requiredBy) > 0 {
+			applicable = append(applicable, &applicableRequirement{
+				requirement: requirement,
+				requiredBy:  requiredBy,
+				value:       profile[requirement.Attribute],
+			})
+		}
+	}
+	return applicable, profile, nil
+}
+
+// validate checks a submitted value against the requirement and returns it
+// as it is written to the profile.
+func validate(requirement *models.ProfileRequirement, value any) (any, error) {
+	switch requirement.Type {
+	case models.ProfileAttributeTypeNumber:
+		if _, ok := value.(float64); !ok {
+			return nil, errors.New("must be a number")
+		}
+		return value, nil
+	case models.ProfileAttributeTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return nil, errors.New("must be true or false")
+		}
+		return value, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, errors.New("cannot be empty")
+	}
+	if len(requirement.Values) > 0 && !slices.Contains(requirement.Values, text) {
+		return nil, fmt.Errorf("must be one of %s", strings.Join(requirement.Values, ", "))
+	}
+	if requirement.Pattern != "" {
+		pattern, err := compilePattern(requirement.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		if !pattern.MatchString(text) {
+			return nil, fmt.Errorf("must match %s", requirement.Pattern)
+		}
+	}
+	return text, nil
+}
+
+// compilePattern compiles a requirement's pattern so that it matches whole
+// values only.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// isEmpty reports whether a profile value counts as not filled in.
+func isEmpty(value any) bool {
+	if value == nil {
+		return true
+	}
+	text, ok := value.(string)
+	return ok && strings.TrimSpace(text) == ""
+}
+
+// load reads the requirements from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, requirementsKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read profile requirements: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.requirements); err != nil {
+			return fmt.Errorf("failed to decode profile requirements: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the requirements. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.requirements)
+	if err != nil {
+		return fmt.Errorf("failed to encode profile requirements: %w", err)
+	}
+	if err := s.store.Put(ctx, requirementsKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store profile requirements: %w", err)
+	}
+	return nil
+}
+
+func copyRequirement(requirement *models.ProfileRequirement) *models.ProfileRequirement {
+	copied := *requirement
+	copied.Values = slices.Clone(requirement.Values)
+	copied.AppIDs = slices.Clone(requirement.AppIDs)
+	copied.GroupIDs = slices.Clone(requirement.GroupIDs)
+	return &copied
+}
@@ -0,0 +1,216 @@
+package provisioning_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrNameRequired = errors.New("name is required")
+	ErrNoGroups     = errors.New("at least one group ID is required")
+)
+
+// Service runs operations that make several Okta changes, such as onboarding
+// a user into their groups, as sagas: when a change fails, the ones made
+// before it are undone, so Okta is never left with half an operation.
+type Service struct {
+	log       *zap.SugaredLogger
+	sagasSvc  *saga_service.Service
+	usersSvc  *user_service.Service
+	groupsSvc *group_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, sagasSvc *saga_service.Service,
+	usersSvc *user_service.Service, groupsSvc *group_service.Service,
+) *Service {
+	s := &Service{log: log, sagasSvc: sagasSvc, usersSvc: usersSvc, groupsSvc: groupsSvc}
+	sagasSvc.RegisterResumer(models.SagaTypeOnboarding, s.resumeOnboarding)
+	sagasSvc.RegisterResumer(models.SagaTypeTeamScaffolding, s.resumeTeam)
+	return s
+}
+
+// OnboardUser creates the user and adds them to each group. If an addition
+// fails, the user is deleted again, which also ends the memberships already
+// added, or with Reconcile the addition is retried later. The saga is
+// returned whether or not it succeeded.
+func (s *Service) OnboardUser(ctx context.Context, req *models.OnboardUserRequest) (*models.Saga, error) {
+	groupIDs := unique(req.GroupIDs)
+	if len(groupIDs) == 0 {
+		return nil, ErrNoGroups
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Onboarding user", "email", req.User.Email, "groups", len(groupIDs))
+
+	var execution *saga_service.Execution
+	var userID string
+
+	steps := []saga_service.Step{{
+		Name: "create-user",
+		Do: func(ctx context.Context) (string, error) {
+			user, err := s.usersSvc.CreateUser(ctx, &req.User)
+			if err != nil {
+				return "", err
+			}
+			userID = user.ID
+			execution.SetResourceID(userID)
+			return fmt.Sprintf("Created user %s", userID), nil
+		},
+		Undo: func(ctx context.Context) error {
+			return s.usersSvc.DeleteUser(ctx, userID)
+		},
+	}}
+	for _, groupID := range groupIDs {
+		steps = append(steps, saga_service.Step{
+			Name: "add-to-group:" + groupID,
+			Do: func(ctx context.Context) (string, error) {
+				if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Added user %s to group %s", userID, groupID), nil
+			},
+		})
+	}
+
+	execution = s.sagasSvc.Start(models.SagaTypeOnboarding, models.ResourceTypeUser, steps)
+	if req.Reconcile {
+		execution.Reconcile()
+	}
+	err := execution.Run(ctx)
+	return execution.Saga(), err
+}
+
+// CreateTeam creates the team's group and adds its members. If an addition
+// fails, the group is deleted again, or with Reconcile the addition is
+// retried later. The saga is returned whether or not it succeeded.
+func (s *Service) CreateTeam(ctx context.Context, req *models.CreateTeamRequest) (*models.Saga, error) {
+	if req.Name == "" {
+		return nil, ErrNameRequired
+	}
+	memberIDs := unique(req.MemberIDs)
+
+	logger.FromContext(ctx, s.log).Infow("Creating team", "name", req.Name, "members", len(memberIDs))
+
+	var execution *saga_service.Execution
+	var groupID string
+
+	steps := []saga_service.Step{{
+		Name: "create-group",
+		Do: func(ctx context.Context) (string, error) {
+			group, err := s.groupsSvc.CreateGroup(ctx, &models.CreateGroupRequest{
+				Name:        req.Name,
+				Description: req.Description,
+				JoinPolicy:  req.JoinPolicy,
+			})
+			if err != nil {
+				return "", err
+			}
+			groupID = group.ID
+			execution.SetResourceID(groupID)
+			return fmt.Sprintf("Created group %s", groupID), nil
+		},
+		Undo: func(ctx context.Context) error {
+			return s.groupsSvc.DeleteGroup(ctx, groupID)
+		},
+	}}
+	for _, userID := range memberIDs {
+		steps = append(steps, saga_service.Step{
+			Name: "add-member:" + userID,
+			Do: func(ctx context.Context) (string, error) {
+				if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Added user %s to group %s", userID, groupID), nil
+			},
+		})
+	}
+
+	execution = s.sagasSvc.Start(models.SagaTypeTeamScaffolding, models.ResourceTypeGroup, steps)
+	if req.Reconcile {
+		execution.Reconcile()
+	}
+	err := execution.Run(ctx)
+	return execution.Saga(), err
+}
+
+// resumeOnboarding rebuilds a step of an onboarding saga recorded before a
+// restart, from its name and the user the saga created.
+func (s *Service) resumeOnboarding(saga *models.Saga, name string) *saga_service.Step {
+	userID := saga.ResourceID
+	if userID == "" {
+		return nil
+	}
+
+	if name == "create-user" {
+		return &saga_service.Step{
+			Name: name,
+			Undo: func(ctx context.Context) error {
+				return s.usersSvc.DeleteUser(ctx, userID)
+			},
+		}
+	}
+	if groupID, ok := strings.CutPrefix(name, "add-to-group:"); ok {
+		return &saga_service.Step{
+			Name: name,
+			Do: func(ctx context.Context) (string, error) {
+				if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Added user %s to group %s", userID, groupID), nil
+			},
+		}
+	}
+	return nil
+}
+
+// resumeTeam rebuilds a step of a team scaffolding saga recorded before a
+// restart, from its name and the group the saga created.
+func (s *Service) resumeTeam(saga *models.Saga, name string) *saga_service.Step {
+	groupID := saga.ResourceID
+	if groupID == "" {
+		return nil
+	}
+
+	if name == "create-group" {
+		return &saga_service.Step{
+			Name: name,
+			Undo: func(ctx context.Context) error {
+				return s.groupsSvc.DeleteGroup(ctx, groupID)
+			},
+		}
+	}
+	if userID, ok := strings.CutPrefix(name, "add-member:"); ok {
+		return &saga_service.Step{
+			Name: name,
+			Do: func(ctx context.Context) (string, error) {
+				if err := s.groupsSvc.AddUserToGroup(ctx, groupID, userID, nil); err != nil {
+					return "", err
+				}
+				return fmt.Sprintf("Added user %s to group %s", userID, groupID), nil
+			},
+		}
+	}
+	return nil
+}
+
+// unique drops empty and repeated IDs, keeping the first of each.
+func unique(ids []string) []string {
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id != "" && !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
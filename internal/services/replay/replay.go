@@ -0,0 +1,437 @@
+package replay_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/internal/oktamock"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/okta"
+)
+
+var (
+	ErrCaptureNotFound = errors.New("replay capture not found")
+	ErrInvalidFixture  = errors.New("a fixture needs a request with a method and an absolute path, and a response")
+	ErrNotStandalone   = errors.New("captures are replayed only against the in-memory Okta org; " +
+		"run the server with OKTA_BACKEND=memory and import the fixture there")
+	ErrNestedReplay = errors.New("a replay cannot replay a capture")
+)
+
+const (
+	manifestKey = "captures.json"
+
+	// redacted replaces the values of secrets.
+	redacted = "REDACTED"
+)
+
+// sensitiveNames are the parts of field, parameter and header names whose
+// values are never kept, compared in lower case.
+var sensitiveNames = []string{
+	"password", "secret", "token", "answer", "credential", "devicecode", "privatekey", "apikey", "assertion",
+}
+
+type replayKey struct{}
+
+// Service keeps failed requests, sanitized, with the Okta interactions made
+// while serving them, and replays them against those interactions instead of
+// Okta. Replays go through the API the requests came through, so they are
+// only run by a standalone server: there, nothing a replay changes leaves the
+// process. Captures from a production server are exported as fixtures and
+// imported into a standalone one. Each capture is stored as its own object,
+// listed in a manifest that is stored on every change.
+type Service struct {
+	log   *zap.SugaredLogger
+	cfg   *config.ReplayConfig
+	store objectstore.Store
+	// handler serves replays; it is the router the requests came through.
+	handler    http.Handler
+	standalone bool
+
+	mu sync.Mutex
+	// captures are oldest first. They are read from the store on first use.
+	captures []*models.ReplayCaptureSummary
+	loaded   bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.ReplayConfig, store objectstore.Store, handler http.Handler,
+	standalone bool,
+) *Service {
+	return &Service{log: log, cfg: cfg, store: store, handler: handler, standalone: standalone}
+}
+
+// Replaying reports whether ctx is that of a replayed request, which must
+// not be captured again.
+func Replaying(ctx context.Context) bool {
+	return ctx.Value(replayKey{}) != nil
+}
+
+// Capture sanitizes and keeps capture, whose route, request ID and fixture
+// are set, dropping the oldest captures beyond the limit.
+func (s *Service) Capture(ctx context.Context, capture *models.ReplayCapture) (*models.ReplayCapture, error) {
+	capture.ID = uuid.NewString()
+	capture.Captured = time.Now().UTC()
+	sanitizeFixture(&capture.ReplayFixture, capture.Route)
+
+	data, err := json.Marshal(capture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode replay capture: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(ctx, captureKey(capture.ID), "application/json", data); err != nil {
+		return nil, fmt.Errorf("failed to store replay capture: %w", err)
+	}
+
+	previous := s.captures
+	s.captures = append(slices.Clip(s.captures), summarize(capture))
+	dropped := s.captures[:max(len(s.captures)-s.cfg.MaxCaptures, 0)]
+	s.captures = s.captures[len(dropped):]
+	if err := s.save(ctx); err != nil {
+		s.captures = previous
+		_ = s.store.Delete(ctx, captureKey(capture.ID))
+		return nil, err
+	}
+
+	for _, old := range dropped {
+		if err := s.store.Delete(ctx, captureKey(old.ID)); err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+			logger.FromContext(ctx, s.log).Infow("Failed to delete dropped replay capture",
+				"captureId", old.ID, zap.Error(err),
+			)
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Request captured for replay",
+		"captureId", capture.ID, "method", capture.Request.Method, "path", capture.Request.Path,
+		"status", capture.Response.Status, "interactions", len(capture.Interactions),
+	)
+	return capture, nil
+}
+
+// Import keeps a fixture exported from another server as a capture.
+func (s *Service) Import(ctx context.Context, fixture *models.ReplayFixture) (*models.ReplayCapture, error) {
+	if fixture.Request == nil || fixture.Request.Method == "" || !strings.HasPrefix(fixture.Request.Path, "/") ||
+		fixture.Response == nil {
+		return nil, ErrInvalidFixture
+	}
+	if fixture.Interactions == nil {
+		fixture.Interactions = make([]*models.OktaInteraction, 0)
+	}
+	return s.Capture(ctx, &models.ReplayCapture{ReplayFixture: *fixture})
+}
+
+// GetCaptures lists the captures, newest first.
+func (s *Service) GetCaptures(ctx context.Context) ([]*models.ReplayCaptureSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	captures := make([]*models.ReplayCaptureSummary, 0, len(s.captures))
+	for _, capture := range slices.Backward(s.captures) {
+		copied := *capture
+		captures = append(captures, &copied)
+	}
+	return captures, nil
+}
+
+func (s *Service) GetCapture(ctx context.Context, captureID string) (*models.ReplayCapture, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if s.find(captureID) < 0 {
+		return nil, ErrCaptureNotFound
+	}
+
+	object, err := s.store.Get(ctx, captureKey(captureID))
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return nil, ErrCaptureNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay capture: %w", err)
+	}
+
+	var capture models.ReplayCapture
+	if err := json.Unmarshal(object.Data, &capture); err != nil {
+		return nil, fmt.Errorf("failed to decode replay capture: %w", err)
+	}
+	return &capture, nil
+}
+
+func (s *Service) DeleteCapture(ctx context.Context, captureID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	index := s.find(captureID)
+	if index < 0 {
+		return ErrCaptureNotFound
+	}
+
+	deleted := s.captures[index]
+	s.captures = slices.Delete(s.captures, index, index+1)
+	if err := s.save(ctx); err != nil {
+		s.captures = slices.Insert(s.captures, index, deleted)
+		return err
+	}
+	if err := s.store.Delete(ctx, captureKey(captureID)); err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		logger.FromContext(ctx, s.log).Infow("Failed to delete replay capture", "captureId", captureID, zap.Error(err))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Replay capture deleted", "captureId", captureID)
+	return nil
+}
+
+// Run sends the captured request through the API again, with every Okta
+// request answered from the captured interactions, and reports how the
+// outcome compares with the capture.
+func (s *Service) Run(ctx context.Context, captureID string) (*models.ReplayResult, error) {
+	if !s.standalone {
+		return nil, ErrNotStandalone
+	}
+	if Replaying(ctx) {
+		return nil, ErrNestedReplay
+	}
+
+	capture, err := s.GetCapture(ctx, captureID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The request is routed afresh, so the routing context of the request
+	// asking for the replay is dropped.
+	replay := oktamock.NewReplay(capture.Interactions)
+	ctx = context.WithValue(context.WithValue(ctx, chi.RouteCtxKey, nil), replayKey{}, captureID)
+	ctx = okta.ReplayInteractions(ctx, replay)
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(
+		ctx, capture.Request.Method, capture.Request.Path, bytes.NewReader(capture.Request.Body),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build replayed request: %w", err)
+	}
+	for name, value := range capture.Request.Headers {
+		req.Header.Set(name, value)
+	}
+	if capture.RequestID != "" {
+		req.Header.Set(logger.RequestIDHeader, capture.RequestID)
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	s.handler.ServeHTTP(recorder, req)
+
+	result := &models.ReplayResult{
+		Status:     recorder.Code,
+		Reproduced: recorder.Code == capture.Response.Status,
+		Unmatched:  replay.Unmatched(),
+		Unused:     replay.Unused(),
+	}
+	if body := recorder.Body.Bytes(); json.Valid(body) {
+		result.Body = body
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Captured request replayed",
+		"captureId", captureID,
+		"method", capture.Request.Method,
+		"path", capture.Request.Path,
+		"status", result.Status,
+		"reproduced", result.Reproduced,
+		"unmatched", len(result.Unmatched),
+		"unused", result.Unused,
+	)
+	return result, nil
+}
+
+// find returns the index of the capture with captureID, or -1. Callers hold
+// mu.
+func (s *Service) find(captureID string) int {
+	return slices.IndexFunc(s.captures, func(capture *models.ReplayCaptureSummary) bool {
+		return capture.ID == captureID
+	})
+}
+
+// load reads the manifest from the store once. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, manifestKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read replay captures: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.captures); err != nil {
+			return fmt.Errorf("failed to decode replay captures: %w", err)
+		}
+	}
+
+	s.loaded = true
+	return nil
+}
+
+// save stores the manifest. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.captures)
+	if err != nil {
+		return fmt.Errorf("failed to encode replay captures: %w", err)
+	}
+	if err := s.store.Put(ctx, manifestKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store replay captures: %w", err)
+	}
+	return nil
+}
+
+func captureKey(captureID string) string {
+	return "captures/" + captureID + ".json"
+}
+
+func summarize(capture *models.ReplayCapture) *models.ReplayCaptureSummary {
+	return &models.ReplayCaptureSummary{
+		ID:           capture.ID,
+		Method:       capture.Request.Method,
+		Path:         capture.Request.Path,
+		Route:        capture.Route,
+		Status:       capture.Response.Status,
+		RequestID:    capture.RequestID,
+		Interactions: len(capture.Interactions),
+		Captured:     capture.Captured,
+	}
+}
+
+// sanitizeFixture replaces every secret in fixture: the values of sensitive
+// path parameters of route, query parameters, headers and JSON fields.
+func sanitizeFixture(fixture *models.ReplayFixture, route string) {
+	request := fixture.Request
+	request.Path = SanitizePath(request.Path, route)
+	for name := range request.Headers {
+		if sensitive(name) {
+			request.Headers[name] = redacted
+		}
+	}
+	request.Body = sanitizeJSON(request.Body)
+	fixture.Response.Body = sanitizeJSON(fixture.Response.Body)
+
+	for _, interaction := range fixture.Interactions {
+		interaction.Query = sanitizeQuery(interaction.Query)
+		interaction.RequestBody = sanitizeJSON(interaction.RequestBody)
+		interaction.ResponseBody = sanitizeJSON(interaction.ResponseBody)
+	}
+}
+
+// SanitizePath redacts the segments of path that route has sensitive
+// parameters at, such as the token of /api/v1/invite/{token}, and the
+// sensitive query parameters.
+func SanitizePath(path, route string) string {
+	path, query, hasQuery := strings.Cut(path, "?")
+
+	segments := strings.Split(path, "/")
+	for i, param := range strings.Split(route, "/") {
+		name, isParam := strings.CutPrefix(param, "{")
+		if isParam && i < len(segments) && sensitive(strings.TrimSuffix(name, "}")) {
+			segments[i] = redacted
+		}
+	}
+	path = strings.Join(segments, "/")
+
+	if hasQuery {
+		path += "?" + sanitizeQuery(query)
+	}
+	return path
+}
+
+func sanitizeQuery(query string) string {
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return ""
+	}
+
+	changed := false
+	for name := range values {
+		if sensitive(name) {
+			values.Set(name, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return query
+	}
+	return values.Encode()
+}
+
+// sanitizeJSON redacts the sensitive fields of a JSON document, at any depth.
+// Documents that cannot be decoded are dropped rather than kept unredacted.
+func sanitizeJSON(data json.RawMessage) json.RawMessage {
+	if len(data) == 0 {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+	redactFields(value)
+
+	sanitized, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	return sanitized
+}
+
+func redactFields(value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for name, child := range v {
+			if sensitive(name) {
+				v[name] = redacted
+				continue
+			}
+			redactFields(child)
+		}
+	case []any:
+		for _, child := range v {
+			redactFields(child)
+		}
+	}
+}
+
+func sensitive(name string) bool {
+	name = strings.ToLower(name)
+	return slices.ContainsFunc(sensitiveNames, func(part string) bool {
+		return strings.Contains(name, part)
+	})
+}
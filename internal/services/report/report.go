@@ -0,0 +1,198 @@
+package report_service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+type Service struct {
+	client *okta.APIClient
+	log    *zap.SugaredLogger
+	cfg    *config.ReportsConfig
+}
+
+func New(log *zap.SugaredLogger, client *okta.APIClient, cfg *config.ReportsConfig) *Service {
+	return &Service{log: log, client: client, cfg: cfg}
+}
+
+func (s *Service) GetGroupAppMatrix(ctx context.Context, filter *models.GroupAppMatrixFilter) (*models.GroupAppMatrix, error) {
+	logger.FromContext(ctx, s.log).Infow("Building group app matrix from Okta",
+		"groupQuery", filter.GroupQuery,
+		"appQuery", filter.AppQuery,
+	)
+
+	appsReq := s.client.ApplicationAPI.ListApplications(ctx)
+	if filter.AppQuery != "" {
+		appsReq = appsReq.Q(filter.AppQuery)
+	}
+
+	oktaApps, response, err := appsReq.Execute()
+	if err == nil {
+		oktaApps, err = pagination.All(oktaApps, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get apps from Okta", zap.Error(err))
+		return nil, fmt.Errorf("failed to get apps from Okta: %w", err)
+	}
+
+	groupsReq := s.client.GroupAPI.ListGroups(ctx)
+	if filter.GroupQuery != "" {
+		groupsReq = groupsReq.Q(filter.GroupQuery)
+	}
+
+	oktaGroups, response, err := groupsReq.Execute()
+	if err == nil {
+		oktaGroups, err = pagination.All(oktaGroups, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get groups from Okta", zap.Error(err))
+		return nil, fmt.Errorf("failed to get groups from Okta: %w", err)
+	}
+
+	matrix := &models.GroupAppMatrix{
+		Apps: make([]*models.App, len(oktaApps)),
+		Rows: make([]*models.GroupAppMatrixRow, 0, len(oktaGroups)),
+	}
+
+	assignments := make(map[string][]string)
+	for i := range oktaApps {
+		app := models.ConvertOktaAppToModel(&oktaApps[i])
+		matrix.Apps[i] = app
+
+		groupAssignments, response, err := s.client.ApplicationGroupsAPI.
+			ListApplicationGroupAssignments(ctx, app.ID).Execute()
+		if err == nil {
+			groupAssignments, err = pagination.All(groupAssignments, response)
+		}
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to get app group assignments from Okta", zap.Error(err), "appId", app.ID)
+			return nil, fmt.Errorf("failed to get group assignments for app %s from Okta: %w", app.ID, err)
+		}
+
+		for _, assignment := range groupAssignments {
+			groupID := assignment.GetId()
+			assignments[groupID] = append(assignments[groupID], app.ID)
+		}
+	}
+
+	for i := range oktaGroups {
+		group := models.ConvertOktaGroupToModel(&oktaGroups[i])
+
+		appIDs := assignments[group.ID]
+		if len(appIDs) == 0 && filter.OnlyAssigned {
+			continue
+		}
+		if appIDs == nil {
+			appIDs = []string{}
+		}
+
+		matrix.Rows = append(matrix.Rows, &models.GroupAppMatrixRow{
+			GroupID:   group.ID,
+			GroupName: group.Name,
+			AppIDs:    appIDs,
+		})
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Group app matrix built successfully",
+		"groupCount", len(matrix.Rows),
+		"appCount", len(matrix.Apps),
+	)
+	return matrix, nil
+}
+
+// inactivityStatuses are the user statuses that can still sign in, and so are
+// meaningful to report as inactive.
+var inactivityStatuses = []string{
+	models.UserStatusActive,
+	models.UserStatusProvisioned,
+	models.UserStatusRecovery,
+	models.UserStatusLockedOut,
+	models.UserStatusPasswordExpired,
+}
+
+// GetInactiveUsers lists users without sign-in activity for filter.Days days
+// (the configured default when zero). Members of the configured exclusion
+// groups and of filter.ExcludeGroupIDs are never reported.
+func (s *Service) GetInactiveUsers(ctx context.Context, filter *models.InactiveUsersFilter) ([]*models.InactiveUser, error) {
+	if filter.Days <= 0 {
+		filter.Days = s.cfg.InactiveUserDays
+	}
+	if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+	filter.ExcludeGroupIDs = append(slices.Clone(s.cfg.InactiveUserExcludedGroups), filter.ExcludeGroupIDs...)
+
+	logger.FromContext(ctx, s.log).Infow("Building inactive users report from Okta",
+		"days", filter.Days,
+		"excludedGroupCount", len(filter.ExcludeGroupIDs),
+	)
+
+	excluded := make(map[string]bool)
+	for _, groupID := range filter.ExcludeGroupIDs {
+		members, response, err := s.client.GroupAPI.ListGroupUsers(ctx, groupID).Execute()
+		if err == nil {
+			members, err = pagination.All(members, response)
+		}
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to get exclusion group members from Okta", zap.Error(err), "groupId", groupID)
+			return nil, fmt.Errorf("failed to get members of exclusion group %s from Okta: %w", groupID, err)
+		}
+
+		for _, member := range members {
+			excluded[member.GetId()] = true
+		}
+	}
+
+	users, response, err := s.client.UserAPI.ListUsers(ctx).Execute()
+	if err == nil {
+		users, err = pagination.All(users, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get users from Okta", zap.Error(err))
+		return nil, fmt.Errorf("failed to get users from Okta: %w", err)
+	}
+
+	cutoff := filter.Now.AddDate(0, 0, -filter.Days)
+	result := make([]*models.InactiveUser, 0)
+
+	for i := range users {
+		user := models.ConvertOktaUserToModel(&users[i])
+		if excluded[user.ID] || !slices.Contains(inactivityStatuses, user.Status) {
+			continue
+		}
+
+		reason := models.InactivityReasonInactive
+		since := user.LastLogin
+
+		if since == nil {
+			reason = models.InactivityReasonNeverLoggedIn
+			since = user.Activated
+			if since == nil {
+				since = &user.Created
+			}
+		}
+
+		if since.After(cutoff) {
+			continue
+		}
+
+		result = append(result, &models.InactiveUser{
+			User:         user,
+			DaysInactive: int(filter.Now.Sub(*since) / (24 * time.Hour)),
+			Reason:       reason,
+		})
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Inactive users report built successfully", "userCount", len(result))
+	return result, nil
+}
@@ -0,0 +1,393 @@
+package retry_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+var (
+	ErrEntryNotFound = errors.New("retry queue entry not found")
+	ErrEntryRunning  = errors.New("retry queue entry is being replayed")
+	ErrQueueFull     = errors.New("retry queue is full")
+)
+
+const (
+	queueKey = "queue.json"
+
+	// maxBackoff caps the wait between replays of an entry.
+	maxBackoff = time.Hour
+)
+
+type replayKey struct{}
+
+// Service keeps mutations that failed because Okta was unavailable and
+// replays them through the API once it is back. The queue is bounded and
+// stored as a whole on every change, so entries survive a restart. A replay
+// that fails the same way is tried again later, with a growing wait, until
+// the entry runs out of attempts; one that fails any other way, such as a
+// conflict with a change made meanwhile, is left for an operator.
+type Service struct {
+	log   *zap.SugaredLogger
+	cfg   *config.RetryQueueConfig
+	store objectstore.Store
+	// handler serves replays; it is the router the mutations came through.
+	handler http.Handler
+
+	mu sync.Mutex
+	// entries are oldest first. They are read from the store on first use.
+	entries []*models.RetryEntry
+	loaded  bool
+}
+
+func New(
+	log *zap.SugaredLogger,
+	cfg *config.RetryQueueConfig,
+	store objectstore.Store,
+	handler http.Handler,
+) *Service {
+	return &Service{log: log, cfg: cfg, store: store, handler: handler}
+}
+
+// Replaying reports whether ctx is that of a replayed mutation, which must
+// not be queued again.
+func Replaying(ctx context.Context) bool {
+	return ctx.Value(replayKey{}) != nil
+}
+
+// Enqueue adds entry, whose method, path, route, body and request ID are set,
+// to the queue with its first replay one backoff from now.
+func (s *Service) Enqueue(ctx context.Context, entry *models.RetryEntry) (*models.RetryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+	if len(s.entries) >= s.cfg.MaxEntries {
+		return nil, ErrQueueFull
+	}
+
+	now := time.Now().UTC()
+	next := now.Add(s.cfg.Backoff)
+	entry.ID = uuid.NewString()
+	entry.Status = models.RetryStatusPending
+	entry.MaxAttempts = s.cfg.MaxAttempts
+	entry.NextAttempt = &next
+	entry.Created = now
+	entry.LastUpdated = now
+
+	s.entries = append(s.entries, entry)
+	if err := s.save(ctx); err != nil {
+		s.entries = s.entries[:len(s.entries)-1]
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Mutation queued for retry",
+		"entryId", entry.ID, "method", entry.Method, "path", entry.Path, "status", entry.LastStatus,
+	)
+	copied := *entry
+	return &copied, nil
+}
+
+// GetEntries lists the queued entries matching filter, oldest first.
+func (s *Service) GetEntries(ctx context.Context, filter *models.RetryFilter) ([]*models.RetryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	entries := make([]*models.RetryEntry, 0)
+	for _, entry := range s.entries {
+		if filter.Status != "" && entry.Status != filter.Status {
+			continue
+		}
+		if filter.Route != "" && entry.Route != filter.Route {
+			continue
+		}
+		copied := *entry
+		entries = append(entries, &copied)
+	}
+	return entries, nil
+}
+
+// Unfinished counts the queued entries pending or running.
+func (s *Service) Unfinished(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range s.entries {
+		if entry.Status == models.RetryStatusPending || entry.Status == models.RetryStatusRunning {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *Service) GetEntry(ctx context.Context, entryID string) (*models.RetryEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	entry := s.find(entryID)
+	if entry == nil {
+		return nil, ErrEntryNotFound
+	}
+	copied := *entry
+	return &copied, nil
+}
+
+// Retry replays the entry now, with its attempts reset, and returns it as it
+// stands afterwards. An entry that succeeded is no longer queued.
+func (s *Service) Retry(ctx context.Context, entryID string) (*models.RetryEntry, error) {
+	s.mu.Lock()
+	if err := s.load(ctx); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	entry := s.find(entryID)
+	if entry == nil {
+		s.mu.Unlock()
+		return nil, ErrEntryNotFound
+	}
+	if entry.Status == models.RetryStatusRunning {
+		s.mu.Unlock()
+		return nil, ErrEntryRunning
+	}
+	entry.Attempts = 0
+	entry.Status = models.RetryStatusRunning
+	copied := *entry
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Retrying queued mutation", "entryId", entryID)
+	return s.replay(ctx, &copied), nil
+}
+
+// Discard removes the entry without replaying it.
+func (s *Service) Discard(ctx context.Context, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(s.entries, func(entry *models.RetryEntry) bool { return entry.ID == entryID })
+	if index < 0 {
+		return ErrEntryNotFound
+	}
+
+	discarded := s.entries[index]
+	s.entries = slices.Delete(s.entries, index, index+1)
+	if err := s.save(ctx); err != nil {
+		s.entries = slices.Insert(s.entries, index, discarded)
+		return err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Queued mutation discarded",
+		"entryId", entryID, "method", discarded.Method, "path", discarded.Path,
+	)
+	return nil
+}
+
+// Process replays every pending entry whose next attempt is due, oldest
+// first.
+func (s *Service) Process(ctx context.Context) error {
+	s.mu.Lock()
+	if err := s.load(ctx); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	now := time.Now().UTC()
+	due := make([]*models.RetryEntry, 0)
+	for _, entry := range s.entries {
+		if entry.Status == models.RetryStatusPending && entry.NextAttempt != nil && !entry.NextAttempt.After(now) {
+			entry.Status = models.RetryStatusRunning
+			copied := *entry
+			due = append(due, &copied)
+		}
+	}
+	s.mu.Unlock()
+
+	for i, entry := range due {
+		if ctx.Err() != nil {
+			// Hand the entries not replayed back for the next run.
+			s.mu.Lock()
+			for _, skipped := range due[i:] {
+				if queued := s.find(skipped.ID); queued != nil {
+					queued.Status = models.RetryStatusPending
+				}
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+		s.replay(ctx, entry)
+	}
+	return nil
+}
+
+// replay sends entry through the API again and records the outcome.
+func (s *Service) replay(ctx context.Context, entry *models.RetryEntry) *models.RetryEntry {
+	ctx = okta.TrackRetryable(context.WithValue(ctx, replayKey{}, entry.ID))
+
+	recorder := httptest.NewRecorder()
+	req, err := http.NewRequestWithContext(ctx, entry.Method, entry.Path, bytes.NewReader(entry.Body))
+	if err != nil {
+		recorder.WriteHeader(http.StatusBadRequest)
+	} else {
+		if len(entry.Body) > 0 {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if entry.RequestID != "" {
+			req.Header.Set(logger.RequestIDHeader, entry.RequestID)
+		}
+		req.RemoteAddr = "127.0.0.1:0"
+		s.handler.ServeHTTP(recorder, req)
+	}
+
+	status := recorder.Code
+	now := time.Now().UTC()
+	entry.Attempts++
+	entry.LastStatus = status
+	entry.LastError = ""
+	entry.LastUpdated = now
+
+	retryable := (status >= http.StatusInternalServerError || status == http.StatusTooManyRequests) &&
+		okta.Retryable(ctx)
+	switch {
+	case status >= 200 && status < 300:
+		entry.Status = models.RetryStatusSucceeded
+		entry.NextAttempt = nil
+	case retryable && entry.Attempts < entry.MaxAttempts:
+		entry.Status = models.RetryStatusPending
+		next := now.Add(s.backoff(entry.Attempts))
+		entry.NextAttempt = &next
+		entry.LastError = errorMessage(recorder)
+	default:
+		entry.Status = models.RetryStatusFailed
+		entry.NextAttempt = nil
+		entry.LastError = errorMessage(recorder)
+	}
+
+	s.mu.Lock()
+	index := slices.IndexFunc(s.entries, func(queued *models.RetryEntry) bool { return queued.ID == entry.ID })
+	// An entry discarded while it was replayed stays discarded.
+	if index >= 0 {
+		if entry.Status == models.RetryStatusSucceeded {
+			s.entries = slices.Delete(s.entries, index, index+1)
+		} else {
+			copied := *entry
+			s.entries[index] = &copied
+		}
+		if err := s.save(ctx); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to store retry queue", zap.Error(err))
+		}
+	}
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Queued mutation replayed",
+		"entryId", entry.ID,
+		"method", entry.Method,
+		"path", entry.Path,
+		"status", status,
+		"attempts", entry.Attempts,
+		"outcome", entry.Status,
+	)
+	return entry
+}
+
+// backoff is the wait after the given number of failed replays.
+func (s *Service) backoff(attempts int) time.Duration {
+	wait := s.cfg.Backoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+// find returns the queued entry with entryID. Callers hold mu.
+func (s *Service) find(entryID string) *models.RetryEntry {
+	for _, entry := range s.entries {
+		if entry.ID == entryID {
+			return entry
+		}
+	}
+	return nil
+}
+
+// load reads the queue from the store once. Entries that were being replayed
+// when the process stopped are pending again. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, queueKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read retry queue: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.entries); err != nil {
+			return fmt.Errorf("failed to decode retry queue: %w", err)
+		}
+	}
+
+	for _, entry := range s.entries {
+		if entry.Status == models.RetryStatusRunning {
+			entry.Status = models.RetryStatusPending
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+// save stores the whole queue. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode retry queue: %w", err)
+	}
+	if err := s.store.Put(ctx, queueKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store retry queue: %w", err)
+	}
+	return nil
+}
+
+// errorMessage is the message of the error response a replay got, or its
+// status text when the body is not one.
+func errorMessage(recorder *httptest.ResponseRecorder) string {
+	var body response.ErrorResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err == nil && body.Message != "" {
+		return body.Message
+	}
+	return http.StatusText(recorder.Code)
+}
@@ -8,6 +8,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
 )
 
 type Service struct {
@@ -20,7 +22,7 @@ func New(log *zap.SugaredLogger, client *okta.APIClient) *Service {
 }
 
 func (s *Service) CreateRole(ctx context.Context, req *models.CreateRoleRequest) (*models.Role, error) {
-	s.log.Infow("Creating role in Okta", "name", req.Name)
+	logger.FromContext(ctx, s.log).Infow("Creating role in Okta", "name", req.Name)
 
 	createRoleRequest := okta.CreateIamRoleRequest{
 		Label:       req.Name,
@@ -29,33 +31,33 @@ func (s *Service) CreateRole(ctx context.Context, req *models.CreateRoleRequest)
 
 	role, response, err := s.client.RoleAPI.CreateRole(ctx).Instance(createRoleRequest).Execute()
 	if err != nil {
-		s.log.Infow("Failed to create role in Okta", zap.Error(err), "name", req.Name, "statusCode", response.StatusCode)
+		logger.FromContext(ctx, s.log).Infow("Failed to create role in Okta", zap.Error(err), "name", req.Name, "statusCode", okta_client.StatusCode(response))
 		return nil, fmt.Errorf("failed to create role in Okta: %w", err)
 	}
 
-	s.log.Infow("Role created successfully in Okta", "roleId", *role.Id, "name", req.Name)
+	logger.FromContext(ctx, s.log).Infow("Role created successfully in Okta", "roleId", *role.Id, "name", req.Name)
 	return models.ConvertOktaIamRoleToModel(role), nil
 }
 
 func (s *Service) GetRole(ctx context.Context, roleID string) (*models.Role, error) {
-	s.log.Infow("Getting role from Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Getting role from Okta", "roleId", roleID)
 
 	role, response, err := s.client.RoleAPI.GetRole(ctx, roleID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get role from Okta", zap.Error(err), "roleId", roleID, "statusCode", response.StatusCode)
+		logger.FromContext(ctx, s.log).Infow("Failed to get role from Okta", zap.Error(err), "roleId", roleID, "statusCode", okta_client.StatusCode(response))
 		return nil, fmt.Errorf("failed to get role from Okta: %w", err)
 	}
 
-	s.log.Infow("Role retrieved successfully from Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Role retrieved successfully from Okta", "roleId", roleID)
 	return models.ConvertOktaIamRoleToModel(role), nil
 }
 
 func (s *Service) GetRoles(ctx context.Context) ([]*models.Role, error) {
-	s.log.Infow("Getting roles from Okta")
+	logger.FromContext(ctx, s.log).Infow("Getting roles from Okta")
 
 	roles, _, err := s.client.RoleAPI.ListRoles(ctx).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get roles from Okta", zap.Error(err))
+		logger.FromContext(ctx, s.log).Infow("Failed to get roles from Okta", zap.Error(err))
 		return nil, fmt.Errorf("failed to get roles from Okta: %w", err)
 	}
 
@@ -64,12 +66,12 @@ func (s *Service) GetRoles(ctx context.Context) ([]*models.Role, error) {
 		result[i] = models.ConvertOktaIamRoleToModel(&roles.Roles[i])
 	}
 
-	s.log.Infow("Roles retrieved successfully from Okta", "count", len(result))
+	logger.FromContext(ctx, s.log).Infow("Roles retrieved successfully from Okta", "count", len(result))
 	return result, nil
 }
 
 func (s *Service) UpdateRole(ctx context.Context, roleID string, req *models.UpdateRoleRequest) (*models.Role, error) {
-	s.log.Infow("Updating role in Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Updating role in Okta", "roleId", roleID)
 
 	updateRoleRequest := okta.UpdateIamRoleRequest{}
 	updateNeeded := false
@@ -90,32 +92,32 @@ func (s *Service) UpdateRole(ctx context.Context, roleID string, req *models.Upd
 
 	role, response, err := s.client.RoleAPI.ReplaceRole(ctx, roleID).Instance(updateRoleRequest).Execute()
 	if err != nil {
-		s.log.Infow("Failed to update role in Okta", zap.Error(err), "roleId", roleID, "statusCode", response.StatusCode)
+		logger.FromContext(ctx, s.log).Infow("Failed to update role in Okta", zap.Error(err), "roleId", roleID, "statusCode", okta_client.StatusCode(response))
 		return nil, fmt.Errorf("failed to update role in Okta: %w", err)
 	}
 
-	s.log.Infow("Role updated successfully in Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Role updated successfully in Okta", "roleId", roleID)
 	return models.ConvertOktaIamRoleToModel(role), nil
 }
 
 func (s *Service) DeleteRole(ctx context.Context, roleID string) error {
-	s.log.Infow("Deleting role from Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Deleting role from Okta", "roleId", roleID)
 
 	response, err := s.client.RoleAPI.DeleteRole(ctx, roleID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to delete role from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to delete role from Okta", zap.Error(err),
 			"roleId", roleID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to delete role from Okta: %w", err)
 	}
 
-	s.log.Infow("Role deleted successfully from Okta", "roleId", roleID)
+	logger.FromContext(ctx, s.log).Infow("Role deleted successfully from Okta", "roleId", roleID)
 	return nil
 }
 
 func (s *Service) AssignRoleToUser(ctx context.Context, userID, roleID string) error {
-	s.log.Infow("Assigning role to user in Okta", "roleId", roleID, "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Assigning role to user in Okta", "roleId", roleID, "userId", userID)
 
 	assignRoleRequest := okta.AssignRoleRequest{
 		Type: &roleID,
@@ -124,37 +126,37 @@ func (s *Service) AssignRoleToUser(ctx context.Context, userID, roleID string) e
 	_, response, err := s.client.RoleAssignmentAPI.
 		AssignRoleToUser(ctx, userID).AssignRoleRequest(assignRoleRequest).Execute()
 	if err != nil {
-		s.log.Infow("Failed to assign role to user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to assign role to user in Okta", zap.Error(err),
 			"roleId", roleID,
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to assign role to user in Okta: %w", err)
 	}
 
-	s.log.Infow("Role assigned to user successfully in Okta", "roleId", roleID, "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Role assigned to user successfully in Okta", "roleId", roleID, "userId", userID)
 	return nil
 }
 
 func (s *Service) UnassignRoleFromUser(ctx context.Context, userID, roleID string) error {
-	s.log.Infow("Unassigning role from user in Okta", "roleId", roleID, "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Unassigning role from user in Okta", "roleId", roleID, "userId", userID)
 
 	response, err := s.client.RoleAssignmentAPI.UnassignRoleFromUser(ctx, userID, roleID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to unassign role from user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to unassign role from user in Okta", zap.Error(err),
 			"roleId", roleID,
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to unassign role from user in Okta: %w", err)
 	}
 
-	s.log.Infow("Role unassigned from user successfully in Okta", "roleId", roleID, "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Role unassigned from user successfully in Okta", "roleId", roleID, "userId", userID)
 	return nil
 }
 
 func (s *Service) AssignRoleToGroup(ctx context.Context, groupID, roleID string) error {
-	s.log.Infow("Assigning role to group in Okta", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Assigning role to group in Okta", "roleId", roleID, "groupId", groupID)
 
 	assignRoleRequest := okta.AssignRoleRequest{
 		Type: &roleID,
@@ -163,43 +165,43 @@ func (s *Service) AssignRoleToGroup(ctx context.Context, groupID, roleID string)
 	_, response, err := s.client.RoleAssignmentAPI.
 		AssignRoleToGroup(ctx, groupID).AssignRoleRequest(assignRoleRequest).Execute()
 	if err != nil {
-		s.log.Infow("Failed to assign role to group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to assign role to group in Okta", zap.Error(err),
 			"roleId", roleID,
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to assign role to group in Okta: %w", err)
 	}
 
-	s.log.Infow("Role assigned to group successfully in Okta", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Role assigned to group successfully in Okta", "roleId", roleID, "groupId", groupID)
 	return nil
 }
 
 func (s *Service) UnassignRoleFromGroup(ctx context.Context, groupID, roleID string) error {
-	s.log.Infow("Unassigning role from group in Okta", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Unassigning role from group in Okta", "roleId", roleID, "groupId", groupID)
 
 	response, err := s.client.RoleAssignmentAPI.UnassignRoleFromGroup(ctx, groupID, roleID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to unassign role from group in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to unassign role from group in Okta", zap.Error(err),
 			"roleId", roleID,
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to unassign role from group in Okta: %w", err)
 	}
 
-	s.log.Infow("Role unassigned from group successfully in Okta", "roleId", roleID, "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Role unassigned from group successfully in Okta", "roleId", roleID, "groupId", groupID)
 	return nil
 }
 
 func (s *Service) GetUserRoles(ctx context.Context, userID string) ([]*models.Role, error) {
-	s.log.Infow("Getting user roles from Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Getting user roles from Okta", "userId", userID)
 
 	roles, response, err := s.client.RoleAssignmentAPI.ListAssignedRolesForUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get user roles from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get user roles from Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to get user roles from Okta: %w", err)
 	}
@@ -209,18 +211,18 @@ func (s *Service) GetUserRoles(ctx context.Context, userID string) ([]*models.Ro
 		result[i] = models.ConvertOktaRoleToModel(&roles[i])
 	}
 
-	s.log.Infow("User roles retrieved successfully from Okta", "userId", userID, "roleCount", len(result))
+	logger.FromContext(ctx, s.log).Infow("User roles retrieved successfully from Okta", "userId", userID, "roleCount", len(result))
 	return result, nil
 }
 
 func (s *Service) GetGroupRoles(ctx context.Context, groupID string) ([]*models.Role, error) {
-	s.log.Infow("Getting group roles from Okta", "groupId", groupID)
+	logger.FromContext(ctx, s.log).Infow("Getting group roles from Okta", "groupId", groupID)
 
 	roles, response, err := s.client.RoleAssignmentAPI.ListGroupAssignedRoles(ctx, groupID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get group roles from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get group roles from Okta", zap.Error(err),
 			"groupId", groupID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to get group roles from Okta: %w", err)
 	}
@@ -230,6 +232,6 @@ func (s *Service) GetGroupRoles(ctx context.Context, groupID string) ([]*models.
 		result[i] = models.ConvertOktaRoleToModel(&roles[i])
 	}
 
-	s.log.Infow("Group roles retrieved successfully from Okta", "groupId", groupID, "roleCount", len(result))
+	logger.FromContext(ctx, s.log).Infow("Group roles retrieved successfully from Okta", "groupId", groupID, "roleCount", len(result))
 	return result, nil
 }
@@ -0,0 +1,281 @@
+package saga_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrNotReconciling  = errors.New("the saga is not being reconciled")
+	ErrReconcileActive = errors.New("the saga's steps are being retried")
+)
+
+// reconciliation is a saga left short of its intended state and the steps,
+// in order, that reach it.
+type reconciliation struct {
+	item  *models.Reconciliation
+	steps []pendingStep
+	// cause is the error the saga failed with, kept as its error once an
+	// undo is reconciled.
+	cause   string
+	running bool
+}
+
+// pendingStep is a step, or the undoing of one, still to be made.
+type pendingStep struct {
+	index int
+	name  string
+	apply func(ctx context.Context) (string, error)
+}
+
+// Pending lists the sagas being reconciled, oldest first.
+func (s *Service) Pending(ctx context.Context) []*models.Reconciliation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Reconciliation, 0, len(s.pending))
+	for _, r := range s.pending {
+		result = append(result, copyReconciliation(r.item))
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+
+	logger.FromContext(ctx, s.log).Infow("Pending reconciliations listed", "count", len(result))
+	return result
+}
+
+// Reconcile retries the remaining steps of every pending saga that is not
+// stuck.
+func (s *Service) Reconcile(ctx context.Context) {
+	s.mu.Lock()
+	due := make([]*reconciliation, 0, len(s.pending))
+	for _, r := range s.pending {
+		if r.item.Status == models.ReconciliationStatusPending && !r.running {
+			r.running = true
+			due = append(due, r)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range due {
+		if ctx.Err() != nil {
+			s.mu.Lock()
+			r.running = false
+			s.mu.Unlock()
+			continue
+		}
+		s.attempt(ctx, r)
+	}
+}
+
+// Retry retries the remaining steps of a saga now, stuck or not, and returns
+// where its reconciliation stands.
+func (s *Service) Retry(ctx context.Context, sagaID string) (*models.Reconciliation, error) {
+	s.mu.Lock()
+	r, ok := s.pending[sagaID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrNotReconciling
+	}
+	if r.running {
+		s.mu.Unlock()
+		return nil, ErrReconcileActive
+	}
+	r.running = true
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Retrying saga reconciliation", "sagaId", sagaID)
+	return s.attempt(ctx, r), nil
+}
+
+// leaveForward leaves the failed step and the steps after it for
+// reconciliation.
+func (e *Execution) leaveForward(failed int, cause error) {
+	steps := make([]pendingStep, 0, len(e.steps)-failed)
+	for i, step := range e.steps[failed:] {
+		steps = append(steps, pendingStep{index: failed + i, name: step.Name, apply: step.Do})
+	}
+
+	e.update(func(saga *models.Saga, now time.Time) {
+		saga.Status = models.SagaStatusReconciling
+		saga.Error = cause.Error()
+	})
+	e.svc.track(e.sagaID, models.ReconciliationModeForward, steps, cause.Error(), cause)
+}
+
+// leaveUndo leaves the undoing of the steps that failed to be undone, in
+// the order it was tried, for reconciliation.
+func (e *Execution) leaveUndo(indexes []int, cause, undoErr error) {
+	steps := make([]pendingStep, 0, len(indexes))
+	for _, i := range indexes {
+		undo := e.steps[i].Undo
+		steps = append(steps, pendingStep{
+			index: i,
+			name:  e.steps[i].Name,
+			apply: func(ctx context.Context) (string, error) { return "", undo(ctx) },
+		})
+	}
+	e.svc.track(e.sagaID, models.ReconciliationModeUndo, steps, cause.Error(), undoErr)
+}
+
+func (s *Service) track(sagaID, mode string, steps []pendingStep, cause string, lastErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	saga := s.sagas[sagaID]
+	item := &models.Reconciliation{
+		SagaID:       sagaID,
+		SagaType:     saga.Type,
+		ResourceType: saga.ResourceType,
+		ResourceID:   saga.ResourceID,
+		Mode:         mode,
+		Status:       models.ReconciliationStatusPending,
+		Steps:        stepNames(steps),
+		MaxAttempts:  s.cfg.MaxAttempts,
+		LastError:    lastErr.Error(),
+		Created:      time.Now().UTC(),
+	}
+	s.pending[sagaID] = &reconciliation{item: item, steps: steps, cause: cause}
+	s.save()
+}
+
+// attempt makes the remaining steps of r in order, stopping at the first
+// that fails. Once none remain, the saga has reached its intended state and
+// is no longer pending. r must be marked running.
+func (s *Service) attempt(ctx context.Context, r *reconciliation) *models.Reconciliation {
+	sagaID := r.item.SagaID
+	undo := r.item.Mode == models.ReconciliationModeUndo
+
+	s.mu.Lock()
+	now := time.Now().UTC()
+	r.item.Attempts++
+	r.item.LastAttempt = &now
+	if err := s.resume(r); err != nil {
+		r.item.Status = models.ReconciliationStatusStuck
+		r.item.LastError = err.Error()
+		r.running = false
+		s.save()
+		result := copyReconciliation(r.item)
+		s.mu.Unlock()
+
+		logger.FromContext(ctx, s.log).Infow("Failed to resume saga reconciliation", zap.Error(err), "sagaId", sagaID)
+		return result
+	}
+	s.save()
+	s.mu.Unlock()
+
+	for len(r.steps) > 0 {
+		step := r.steps[0]
+		message, err := step.apply(ctx)
+
+		s.mu.Lock()
+		saga := s.sagas[sagaID]
+		now := time.Now().UTC()
+		saga.LastUpdated = now
+		sagaStep := saga.Steps[step.index]
+		if err != nil {
+			sagaStep.Error = err.Error()
+			r.item.LastError = err.Error()
+			if r.item.Attempts >= r.item.MaxAttempts {
+				r.item.Status = models.ReconciliationStatusStuck
+			}
+			r.running = false
+			s.save()
+			result := copyReconciliation(r.item)
+			s.mu.Unlock()
+
+			logger.FromContext(ctx, s.log).Infow("Failed to reconcile saga step", zap.Error(err),
+				"sagaId", sagaID, "step", step.name, "attempts", result.Attempts, "status", result.Status,
+			)
+			return result
+		}
+
+		sagaStep.Error = ""
+		if undo {
+			sagaStep.Status = models.SagaStepStatusCompensated
+			sagaStep.Compensated = &now
+		} else {
+			sagaStep.Status = models.SagaStepStatusSucceeded
+			sagaStep.Message = message
+			if sagaStep.Started == nil {
+				sagaStep.Started = &now
+			}
+			sagaStep.Completed = &now
+		}
+		r.steps = r.steps[1:]
+		r.item.Steps = stepNames(r.steps)
+		s.save()
+		s.mu.Unlock()
+	}
+
+	s.mu.Lock()
+	saga := s.sagas[sagaID]
+	now = time.Now().UTC()
+	saga.LastUpdated = now
+	saga.Completed = &now
+	if undo {
+		saga.Status = models.SagaStatusCompensated
+		saga.Error = r.cause
+	} else {
+		saga.Status = models.SagaStatusSucceeded
+		saga.Error = ""
+	}
+	delete(s.pending, sagaID)
+	r.item.Status = models.ReconciliationStatusResolved
+	r.item.LastError = ""
+	r.running = false
+	s.save()
+	result := copyReconciliation(r.item)
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Saga reconciled", "sagaId", sagaID, "mode", result.Mode, "attempts", result.Attempts)
+	return result
+}
+
+// resume rebuilds the steps of r that were read from the store, with the
+// resumer of its saga type. Callers hold mu.
+func (s *Service) resume(r *reconciliation) error {
+	undo := r.item.Mode == models.ReconciliationModeUndo
+	for i, step := range r.steps {
+		if step.apply != nil {
+			continue
+		}
+
+		var rebuilt *Step
+		if resume, ok := s.resumers[r.item.SagaType]; ok {
+			rebuilt = resume(copySaga(s.sagas[r.item.SagaID]), step.name)
+		}
+		switch {
+		case rebuilt != nil && undo && rebuilt.Undo != nil:
+			undoStep := rebuilt.Undo
+			r.steps[i].apply = func(ctx context.Context) (string, error) { return "", undoStep(ctx) }
+		case rebuilt != nil && !undo && rebuilt.Do != nil:
+			r.steps[i].apply = rebuilt.Do
+		default:
+			return fmt.Errorf("step %s cannot be resumed after a restart", step.name)
+		}
+	}
+	return nil
+}
+
+func stepNames(steps []pendingStep) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[i] = step.name
+	}
+	return names
+}
+
+func copyReconciliation(item *models.Reconciliation) *models.Reconciliation {
+	copied := *item
+	copied.Steps = slices.Clone(item.Steps)
+	return &copied
+}
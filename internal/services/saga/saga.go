@@ -0,0 +1,384 @@
+package saga_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+// stateKey is the object holding the sagas and their reconciliations.
+const stateKey = "state.json"
+
+var (
+	ErrSagaNotFound = errors.New("saga not found")
+	// ErrReconciling is returned, wrapping the step's error, when a failed
+	// step is left for the reconciliation worker.
+	ErrReconciling = errors.New("the operation is incomplete and will be retried")
+)
+
+// Step is one change of a saga.
+type Step struct {
+	Name string
+	// Do makes the change. The message it returns is shown on the step.
+	Do func(ctx context.Context) (string, error)
+	// Undo reverts the change after a later step failed. It is nil for
+	// changes that need no undoing, such as those an earlier step's undo
+	// already reverts.
+	Undo func(ctx context.Context) error
+}
+
+// Service runs composite operations as sagas and keeps the record of each,
+// so clients can see how far one got and what was undone. Sagas left short
+// of their intended state, completed or undone, are reconciled: their
+// remaining steps are retried until they succeed.
+type Service struct {
+	log *zap.SugaredLogger
+	cfg *config.ReconciliationConfig
+	// store keeps the sagas and reconciliations across restarts, stored as
+	// one object on every change. It is nil when they are only kept in
+	// memory.
+	store objectstore.Store
+
+	mu    sync.RWMutex
+	sagas map[string]*models.Saga
+	// pending are the sagas being reconciled, by saga ID.
+	pending map[string]*reconciliation
+	// resumers rebuild the steps of reconciliations read from store, by saga
+	// type.
+	resumers map[string]Resumer
+}
+
+// state is what is stored: the sagas, and the reconciliations by the
+// indexes of the saga steps they have left.
+type state struct {
+	Sagas           map[string]*models.Saga          `json:"sagas"`
+	Reconciliations map[string]*storedReconciliation `json:"reconciliations"`
+}
+
+type storedReconciliation struct {
+	Item  *models.Reconciliation `json:"item"`
+	Steps []int                  `json:"steps"`
+	Cause string                 `json:"cause"`
+}
+
+// Resumer rebuilds the named step of a saga recorded before a restart, so
+// its reconciliation can go on. It returns nil for a step it cannot rebuild.
+type Resumer func(saga *models.Saga, name string) *Step
+
+// New returns the service with the sagas and reconciliations read from
+// store, which may be nil. The steps of the reconciliations are rebuilt by
+// the resumer of their saga type once they are retried.
+func New(log *zap.SugaredLogger, cfg *config.ReconciliationConfig, store objectstore.Store) (*Service, error) {
+	s := &Service{
+		log:      log,
+		cfg:      cfg,
+		store:    store,
+		sagas:    make(map[string]*models.Saga),
+		pending:  make(map[string]*reconciliation),
+		resumers: make(map[string]Resumer),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read saga state: %w", err)
+	}
+
+	var stored state
+	if err := json.Unmarshal(object.Data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode saga state: %w", err)
+	}
+	for id, saga := range stored.Sagas {
+		s.sagas[id] = saga
+	}
+	for id, r := range stored.Reconciliations {
+		saga, ok := s.sagas[id]
+		if !ok {
+			continue
+		}
+		steps := make([]pendingStep, 0, len(r.Steps))
+		for _, i := range r.Steps {
+			if i >= 0 && i < len(saga.Steps) {
+				steps = append(steps, pendingStep{index: i, name: saga.Steps[i].Name})
+			}
+		}
+		s.pending[id] = &reconciliation{item: r.Item, steps: steps, cause: r.Cause}
+	}
+	return s, nil
+}
+
+// RegisterResumer sets how the steps of sagas of sagaType are rebuilt after
+// a restart.
+func (s *Service) RegisterResumer(sagaType string, resume Resumer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resumers[sagaType] = resume
+}
+
+// save stores the sagas and reconciliations. Callers hold mu. A failure is
+// logged rather than returned: the saga's changes have been made either way.
+func (s *Service) save() {
+	if s.store == nil {
+		return
+	}
+
+	stored := state{
+		Sagas:           s.sagas,
+		Reconciliations: make(map[string]*storedReconciliation, len(s.pending)),
+	}
+	for id, r := range s.pending {
+		steps := make([]int, len(r.steps))
+		for i, step := range r.steps {
+			steps[i] = step.index
+		}
+		stored.Reconciliations[id] = &storedReconciliation{Item: r.item, Steps: steps, Cause: r.cause}
+	}
+
+	data, err := json.Marshal(stored)
+	if err == nil {
+		err = s.store.Put(context.Background(), stateKey, "application/json", data)
+	}
+	if err != nil {
+		s.log.Infow("Failed to store saga state", zap.Error(err))
+	}
+}
+
+// Execution is one saga being run.
+type Execution struct {
+	svc    *Service
+	sagaID string
+	steps  []Step
+	// reconcile leaves a failed step for reconciliation instead of undoing
+	// the steps before it.
+	reconcile bool
+}
+
+// Start registers a saga of the given steps. Nothing runs until Run.
+func (s *Service) Start(sagaType, resourceType string, steps []Step) *Execution {
+	now := time.Now().UTC()
+	saga := &models.Saga{
+		ID:           uuid.NewString(),
+		Type:         sagaType,
+		ResourceType: resourceType,
+		Status:       models.SagaStatusRunning,
+		Steps:        make([]*models.SagaStep, len(steps)),
+		Created:      now,
+		LastUpdated:  now,
+	}
+
+	for i, step := range steps {
+		saga.Steps[i] = &models.SagaStep{Name: step.Name, Status: models.SagaStepStatusPending}
+	}
+
+	s.mu.Lock()
+	s.sagas[saga.ID] = saga
+	s.save()
+	s.mu.Unlock()
+
+	s.log.Infow("Saga created", "sagaId", saga.ID, "type", sagaType)
+	return &Execution{svc: s, sagaID: saga.ID, steps: steps}
+}
+
+func (s *Service) GetSaga(ctx context.Context, sagaID string) (*models.Saga, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	saga, ok := s.sagas[sagaID]
+	if !ok {
+		return nil, ErrSagaNotFound
+	}
+
+	return copySaga(saga), nil
+}
+
+// GetSagas lists matching sagas, newest first.
+func (s *Service) GetSagas(ctx context.Context, filter *models.SagaFilter) []*models.Saga {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.Saga, 0, len(s.sagas))
+	for _, saga := range s.sagas {
+		if filter.Type != "" && saga.Type != filter.Type {
+			continue
+		}
+		if filter.Status != "" && saga.Status != filter.Status {
+			continue
+		}
+		if filter.ResourceID != "" && saga.ResourceID != filter.ResourceID {
+			continue
+		}
+		result = append(result, copySaga(saga))
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.After(result[j].Created) })
+	return result
+}
+
+func (e *Execution) SagaID() string {
+	return e.sagaID
+}
+
+// Saga returns a snapshot of the saga.
+func (e *Execution) Saga() *models.Saga {
+	saga, _ := e.svc.GetSaga(context.Background(), e.sagaID)
+	return saga
+}
+
+// SetResourceID records the resource the saga creates, once a step has
+// created it.
+func (e *Execution) SetResourceID(resourceID string) {
+	e.update(func(saga *models.Saga, now time.Time) {
+		saga.ResourceID = resourceID
+	})
+}
+
+// Reconcile has a failed step retried later, with the steps after it,
+// instead of the steps before it being undone. A failure of the first step
+// is not, as nothing has been changed yet.
+func (e *Execution) Reconcile() {
+	e.reconcile = true
+}
+
+// Run runs the steps in order. When one fails, the steps that succeeded are
+// undone in reverse order and the step's error is returned. Undoing goes on
+// after ctx is cancelled, as a half-made change is worse than a slow one.
+// With Reconcile, the failed step and those after it are left for
+// reconciliation instead, and ErrReconciling is returned.
+func (e *Execution) Run(ctx context.Context) error {
+	for i, step := range e.steps {
+		e.update(func(saga *models.Saga, now time.Time) {
+			saga.Steps[i].Status = models.SagaStepStatusRunning
+			saga.Steps[i].Started = &now
+		})
+
+		message, err := step.Do(ctx)
+
+		e.update(func(saga *models.Saga, now time.Time) {
+			saga.Steps[i].Completed = &now
+			saga.Steps[i].Message = message
+			saga.Steps[i].Status = models.SagaStepStatusSucceeded
+			if err != nil {
+				saga.Steps[i].Status = models.SagaStepStatusFailed
+				saga.Steps[i].Error = err.Error()
+			}
+		})
+
+		if err != nil && e.reconcile && i > 0 {
+			logger.FromContext(ctx, e.svc.log).Infow("Saga step failed, leaving it for reconciliation",
+				zap.Error(err), "sagaId", e.sagaID, "step", step.Name,
+			)
+			e.leaveForward(i, err)
+			return fmt.Errorf("%w: %w", ErrReconciling, err)
+		}
+		if err != nil {
+			logger.FromContext(ctx, e.svc.log).Infow("Saga step failed, compensating",
+				zap.Error(err), "sagaId", e.sagaID, "step", step.Name,
+			)
+			e.compensate(context.WithoutCancel(ctx), i, err)
+			return err
+		}
+	}
+
+	e.update(func(saga *models.Saga, now time.Time) {
+		saga.Status = models.SagaStatusSucceeded
+		saga.Completed = &now
+	})
+
+	logger.FromContext(ctx, e.svc.log).Infow("Saga completed successfully", "sagaId", e.sagaID)
+	return nil
+}
+
+// compensate undoes the steps before failed, last first, and skips the ones
+// after it. A step whose undo fails is left for an operator; the others are
+// still undone.
+func (e *Execution) compensate(ctx context.Context, failed int, cause error) {
+	e.update(func(saga *models.Saga, now time.Time) {
+		for _, step := range saga.Steps[failed+1:] {
+			step.Status = models.SagaStepStatusSkipped
+		}
+	})
+
+	var undoErrs []error
+	var notUndone []int
+	for i := failed - 1; i >= 0; i-- {
+		step := e.steps[i]
+		if step.Undo == nil {
+			continue
+		}
+
+		err := step.Undo(ctx)
+		if err != nil {
+			logger.FromContext(ctx, e.svc.log).Infow("Failed to compensate saga step",
+				zap.Error(err), "sagaId", e.sagaID, "step", step.Name,
+			)
+			undoErrs = append(undoErrs, fmt.Errorf("%s: %w", step.Name, err))
+			notUndone = append(notUndone, i)
+		}
+
+		e.update(func(saga *models.Saga, now time.Time) {
+			saga.Steps[i].Compensated = &now
+			saga.Steps[i].Status = models.SagaStepStatusCompensated
+			if err != nil {
+				saga.Steps[i].Status = models.SagaStepStatusCompensationFailed
+				saga.Steps[i].Error = err.Error()
+			}
+		})
+	}
+
+	e.update(func(saga *models.Saga, now time.Time) {
+		saga.Completed = &now
+		saga.Status = models.SagaStatusCompensated
+		saga.Error = cause.Error()
+		if len(undoErrs) > 0 {
+			saga.Status = models.SagaStatusFailed
+			saga.Error = fmt.Errorf("%w; compensation failed: %w", cause, errors.Join(undoErrs...)).Error()
+		}
+	})
+
+	if len(undoErrs) > 0 {
+		e.leaveUndo(notUndone, cause, errors.Join(undoErrs...))
+		logger.FromContext(ctx, e.svc.log).Infow("Saga failed and was not fully compensated, leaving it for reconciliation",
+			"sagaId", e.sagaID,
+		)
+		return
+	}
+	logger.FromContext(ctx, e.svc.log).Infow("Saga compensated", "sagaId", e.sagaID)
+}
+
+func (e *Execution) update(fn func(saga *models.Saga, now time.Time)) {
+	e.svc.mu.Lock()
+	defer e.svc.mu.Unlock()
+
+	saga := e.svc.sagas[e.sagaID]
+	now := time.Now().UTC()
+	saga.LastUpdated = now
+	fn(saga, now)
+	e.svc.save()
+}
+
+func copySaga(saga *models.Saga) *models.Saga {
+	copied := *saga
+	copied.Steps = make([]*models.SagaStep, len(saga.Steps))
+	for i, step := range saga.Steps {
+		stepCopy := *step
+		copied.Steps[i] = &stepCopy
+	}
+	return &copied
+}
@@ -0,0 +1,59 @@
+package scaling_service
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/okta"
+)
+
+// Service reports the workload of this replica: its unfinished jobs and
+// retries, its webhook deliveries in flight and how close it runs to the
+// rate limits of its orgs. An external scaler, such as KEDA's metrics API
+// scaler, polls it to size worker replicas on real IAM work rather than CPU.
+type Service struct {
+	log         *zap.SugaredLogger
+	jobsSvc     *job_service.Service
+	webhooksSvc *webhook_service.Service
+	retrySvc    *retry_service.Service
+	backends    []okta.Backend
+}
+
+func New(
+	log *zap.SugaredLogger,
+	jobsSvc *job_service.Service,
+	webhooksSvc *webhook_service.Service,
+	retrySvc *retry_service.Service,
+	backends []okta.Backend,
+) *Service {
+	return &Service{log: log, jobsSvc: jobsSvc, webhooksSvc: webhooksSvc, retrySvc: retrySvc, backends: backends}
+}
+
+func (s *Service) GetMetrics(ctx context.Context) (*models.ScaleMetrics, error) {
+	retries, err := s.retrySvc.Unfinished(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count queued retries: %w", err)
+	}
+
+	metrics := &models.ScaleMetrics{
+		Jobs:           s.jobsSvc.Unfinished(),
+		Retries:        retries,
+		WebhookBacklog: s.webhooksSvc.Backlog(),
+		RateLimits:     make([]*models.OktaRateLimit, 0),
+		Budgets:        make([]*models.OktaBudget, 0),
+	}
+	for _, backend := range s.backends {
+		for _, window := range backend.RateLimits() {
+			metrics.RateLimits = append(metrics.RateLimits, window)
+			metrics.RateLimitPressure = max(metrics.RateLimitPressure, window.Utilization)
+		}
+		metrics.Budgets = append(metrics.Budgets, backend.Budgets()...)
+	}
+	return metrics, nil
+}
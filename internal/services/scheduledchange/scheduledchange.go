@@ -0,0 +1,418 @@
+package scheduledchange_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/okta"
+)
+
+const (
+	changesKey = "changes.json"
+	// workerActor is the audit actor of the changes the worker applies.
+	workerActor = "system:scheduled-changes"
+	// maxBackoff caps the wait between attempts at a change.
+	maxBackoff = time.Hour
+)
+
+var (
+	ErrChangeNotFound      = errors.New("scheduled change not found")
+	ErrChangeNotScheduled  = errors.New("only changes that have not run yet can be cancelled")
+	ErrGroupIDRequired     = errors.New("groupId is required")
+	ErrUserIDRequired      = errors.New("userId is required")
+	ErrInvalidOperation    = errors.New("operation must be ADD or REMOVE")
+	ErrRunAtInPast         = errors.New("runAt must be in the future")
+	ErrMembershipExpiry    = errors.New("membershipExpiresAt must be after runAt")
+	ErrExpiryOnRemove      = errors.New("membershipExpiresAt only applies to ADD")
+	ErrGroupProtected      = errors.New("group is under the two-person rule; change its members through its member routes")
+	ErrInvalidStatusFilter = errors.New("status must be SCHEDULED, RUNNING, SUCCEEDED, FAILED or CANCELLED")
+)
+
+// Service keeps membership changes scheduled for a future time and applies
+// them once they are due. The changes are stored as a whole on every change,
+// so they survive a restart. Groups under the two-person rule cannot have
+// changes scheduled, as those would skip the second admin.
+type Service struct {
+	log        *zap.SugaredLogger
+	cfg        *config.ScheduledChangesConfig
+	store      objectstore.Store
+	groupsSvc  *group_service.Service
+	changesSvc *pendingchange_service.Service
+	auditSvc   *audit_service.Service
+
+	mu sync.Mutex
+	// changes are keyed by ID and read from the store on first use.
+	changes map[string]*models.ScheduledMembershipChange
+	loaded  bool
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.ScheduledChangesConfig, store objectstore.Store,
+	groupsSvc *group_service.Service, changesSvc *pendingchange_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:        log,
+		cfg:        cfg,
+		store:      store,
+		groupsSvc:  groupsSvc,
+		changesSvc: changesSvc,
+		auditSvc:   auditSvc,
+		changes:    make(map[string]*models.ScheduledMembershipChange),
+	}
+}
+
+// Schedule queues a membership change to run at req.RunAt.
+func (s *Service) Schedule(
+	ctx context.Context, actor string, req *models.ScheduleMembershipChangeRequest,
+) (*models.ScheduledMembershipChange, error) {
+	now := time.Now().UTC()
+	switch {
+	case req.GroupID == "":
+		return nil, ErrGroupIDRequired
+	case req.UserID == "":
+		return nil, ErrUserIDRequired
+	case req.Operation != models.ScheduledChangeOperationAdd && req.Operation != models.ScheduledChangeOperationRemove:
+		return nil, ErrInvalidOperation
+	case !req.RunAt.After(now):
+		return nil, ErrRunAtInPast
+	case req.MembershipExpiresAt != nil && req.Operation == models.ScheduledChangeOperationRemove:
+		return nil, ErrExpiryOnRemove
+	case req.MembershipExpiresAt != nil && !req.MembershipExpiresAt.After(req.RunAt):
+		return nil, ErrMembershipExpiry
+	}
+
+	if err := s.checkUnprotected(ctx, req.GroupID); err != nil {
+		return nil, err
+	}
+	// Make sure the group exists rather than finding out when the change runs.
+	if _, err := s.groupsSvc.GetGroup(ctx, req.GroupID); err != nil {
+		return nil, err
+	}
+
+	runAt := req.RunAt.UTC()
+	change := &models.ScheduledMembershipChange{
+		ID:                  uuid.NewString(),
+		GroupID:             req.GroupID,
+		UserID:              req.UserID,
+		Operation:           req.Operation,
+		RunAt:               runAt,
+		MembershipExpiresAt: req.MembershipExpiresAt,
+		Status:              models.ScheduledChangeStatusScheduled,
+		MaxAttempts:         s.cfg.MaxAttempts,
+		NextAttempt:         &runAt,
+		CreatedBy:           actor,
+		Created:             now,
+		LastUpdated:         now,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	s.changes[change.ID] = change
+	if err := s.save(ctx); err != nil {
+		delete(s.changes, change.ID)
+		return nil, err
+	}
+
+	s.record(ctx, actor, models.AuditActionScheduledChangeCreated, change, map[string]any{"runAt": runAt})
+
+	copied := *change
+	return &copied, nil
+}
+
+// Changes returns the changes matching filter, the soonest to run first.
+func (s *Service) Changes(
+	ctx context.Context, filter *models.ScheduledChangeFilter,
+) ([]*models.ScheduledMembershipChange, error) {
+	switch filter.Status {
+	case "", models.ScheduledChangeStatusScheduled, models.ScheduledChangeStatusRunning,
+		models.ScheduledChangeStatusSucceeded, models.ScheduledChangeStatusFailed,
+		models.ScheduledChangeStatusCancelled:
+	default:
+		return nil, ErrInvalidStatusFilter
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	changes := make([]*models.ScheduledMembershipChange, 0)
+	for _, change := range s.changes {
+		if filter.Status != "" && change.Status != filter.Status {
+			continue
+		}
+		if filter.GroupID != "" && change.GroupID != filter.GroupID {
+			continue
+		}
+		if filter.UserID != "" && change.UserID != filter.UserID {
+			continue
+		}
+
+		copied := *change
+		changes = append(changes, &copied)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].RunAt.Before(changes[j].RunAt) })
+
+	return changes, nil
+}
+
+func (s *Service) Change(ctx context.Context, changeID string) (*models.ScheduledMembershipChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	change, ok := s.changes[changeID]
+	if !ok {
+		return nil, ErrChangeNotFound
+	}
+
+	copied := *change
+	return &copied, nil
+}
+
+// Cancel keeps a change that has not run yet from running. Cancelled changes
+// stay listed.
+func (s *Service) Cancel(ctx context.Context, actor, changeID string) (*models.ScheduledMembershipChange, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(ctx); err != nil {
+		return nil, err
+	}
+
+	change, ok := s.changes[changeID]
+	if !ok {
+		return nil, ErrChangeNotFound
+	}
+	if change.Status != models.ScheduledChangeStatusScheduled {
+		return nil, ErrChangeNotScheduled
+	}
+
+	previous := *change
+	change.Status = models.ScheduledChangeStatusCancelled
+	change.NextAttempt = nil
+	change.CancelledBy = actor
+	change.LastUpdated = time.Now().UTC()
+	if err := s.save(ctx); err != nil {
+		*change = previous
+		return nil, err
+	}
+
+	s.record(ctx, actor, models.AuditActionScheduledChangeCancelled, change, nil)
+
+	copied := *change
+	return &copied, nil
+}
+
+// Process applies every scheduled change whose next attempt is due, the
+// soonest first.
+func (s *Service) Process(ctx context.Context) error {
+	s.mu.Lock()
+	if err := s.load(ctx); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	now := time.Now().UTC()
+	due := make([]*models.ScheduledMembershipChange, 0)
+	for _, change := range s.changes {
+		if change.Status == models.ScheduledChangeStatusScheduled && change.NextAttempt != nil &&
+			!change.NextAttempt.After(now) {
+			change.Status = models.ScheduledChangeStatusRunning
+			copied := *change
+			due = append(due, &copied)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttempt.Before(*due[j].NextAttempt) })
+
+	for i, change := range due {
+		if ctx.Err() != nil {
+			// Hand the changes not applied back for the next run.
+			s.mu.Lock()
+			for _, skipped := range due[i:] {
+				if queued, ok := s.changes[skipped.ID]; ok {
+					queued.Status = models.ScheduledChangeStatusScheduled
+				}
+			}
+			s.mu.Unlock()
+			return ctx.Err()
+		}
+		s.apply(ctx, change)
+	}
+	return nil
+}
+
+// apply makes the change in Okta and records the outcome. A change that
+// failed in a way worth retrying is scheduled again after a backoff until it
+// runs out of attempts.
+func (s *Service) apply(ctx context.Context, change *models.ScheduledMembershipChange) {
+	trackedCtx := okta.TrackRetryable(ctx)
+
+	err := s.checkUnprotected(trackedCtx, change.GroupID)
+	if err == nil {
+		switch change.Operation {
+		case models.ScheduledChangeOperationAdd:
+			err = s.groupsSvc.AddUserToGroup(trackedCtx, change.GroupID, change.UserID, change.MembershipExpiresAt)
+		case models.ScheduledChangeOperationRemove:
+			err = s.groupsSvc.RemoveUserFromGroup(trackedCtx, change.GroupID, change.UserID)
+		}
+	}
+
+	now := time.Now().UTC()
+	change.Attempts++
+	change.LastError = ""
+	change.LastUpdated = now
+
+	switch {
+	case err == nil:
+		change.Status = models.ScheduledChangeStatusSucceeded
+		change.NextAttempt = nil
+	case okta.Retryable(trackedCtx) && change.Attempts < change.MaxAttempts:
+		change.Status = models.ScheduledChangeStatusScheduled
+		next := now.Add(s.backoff(change.Attempts))
+		change.NextAttempt = &next
+		change.LastError = err.Error()
+	default:
+		change.Status = models.ScheduledChangeStatusFailed
+		change.NextAttempt = nil
+		change.LastError = err.Error()
+	}
+
+	s.mu.Lock()
+	if _, ok := s.changes[change.ID]; ok {
+		copied := *change
+		s.changes[change.ID] = &copied
+		if err := s.save(ctx); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to store scheduled changes", zap.Error(err))
+		}
+	}
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Scheduled membership change run",
+		"scheduledChangeId", change.ID,
+		"groupId", change.GroupID,
+		"userId", change.UserID,
+		"operation", change.Operation,
+		"attempts", change.Attempts,
+		"outcome", change.Status,
+		zap.Error(err),
+	)
+
+	switch change.Status {
+	case models.ScheduledChangeStatusSucceeded:
+		s.record(ctx, workerActor, models.AuditActionScheduledChangeApplied, change, map[string]any{
+			"createdBy": change.CreatedBy,
+		})
+	case models.ScheduledChangeStatusFailed:
+		s.record(ctx, workerActor, models.AuditActionScheduledChangeFailed, change, map[string]any{
+			"createdBy": change.CreatedBy,
+			"attempts":  change.Attempts,
+			"error":     change.LastError,
+		})
+	}
+}
+
+// checkUnprotected refuses groups under the two-person rule.
+func (s *Service) checkUnprotected(ctx context.Context, groupID string) error {
+	protected, err := s.changesSvc.IsProtected(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if protected {
+		return ErrGroupProtected
+	}
+	return nil
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (s *Service) backoff(attempts int) time.Duration {
+	wait := s.cfg.Backoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, maxBackoff)
+}
+
+func (s *Service) record(
+	ctx context.Context, actor, action string, change *models.ScheduledMembershipChange, details map[string]any,
+) {
+	if details == nil {
+		details = make(map[string]any)
+	}
+	details["scheduledChangeId"] = change.ID
+	details["userId"] = change.UserID
+	details["operation"] = change.Operation
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeGroup,
+		ResourceID:   change.GroupID,
+		Details:      details,
+	})
+}
+
+// load reads the changes from the store once. Changes that were running when
+// the process stopped are scheduled again. Callers hold mu.
+func (s *Service) load(ctx context.Context) error {
+	if s.loaded {
+		return nil
+	}
+
+	object, err := s.store.Get(ctx, changesKey)
+	if err != nil && !errors.Is(err, objectstore.ErrNotFound) {
+		return fmt.Errorf("failed to read scheduled changes: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(object.Data, &s.changes); err != nil {
+			return fmt.Errorf("failed to decode scheduled changes: %w", err)
+		}
+	}
+
+	for _, change := range s.changes {
+		if change.Status == models.ScheduledChangeStatusRunning {
+			change.Status = models.ScheduledChangeStatusScheduled
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+// save stores every change. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	data, err := json.Marshal(s.changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode scheduled changes: %w", err)
+	}
+	if err := s.store.Put(ctx, changesKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store scheduled changes: %w", err)
+	}
+	return nil
+}
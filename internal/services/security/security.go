@@ -0,0 +1,375 @@
+package security_service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	webhook_service "github.com/iamBelugaa/iam/internal/services/webhook"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+const (
+	// logDelay keeps detection clear of the newest System Log events, which
+	// Okta may still be writing, so none is skipped by moving past it.
+	logDelay = 2 * time.Minute
+
+	// maxAlerts is how many alerts are kept; older ones are dropped.
+	maxAlerts = 10000
+
+	// minTravelDistance is how far apart two sign-ins must be placed before
+	// their speed is looked at, as IP geolocation is only accurate to a
+	// region.
+	minTravelDistance = 100.0
+
+	// earthRadius is the mean radius of the Earth in km.
+	earthRadius = 6371.0
+
+	sessionStart = "user.session.start"
+	actor        = "system:security"
+)
+
+// signInEventTypes are the System Log events of primary and second factor
+// sign-ins.
+var signInEventTypes = []string{
+	sessionStart,
+	"user.authentication.auth_via_mfa",
+	"user.authentication.verify",
+}
+
+// failure is a failed sign-in counted towards a brute-force alert.
+type failure struct {
+	eventID string
+	at      time.Time
+	userID  string
+	login   string
+	ip      string
+}
+
+// Service reads sign-ins from the System Log and raises alerts on
+// brute-force and impossible-travel patterns. Failed sign-ins are counted
+// per user and per IP address over a sliding window; successful ones are
+// compared with the user's previous sign-in, by the distance between where
+// Okta placed them and the time between them. Alerts are kept for GET
+// /security/alerts and published to webhook subscribers of
+// iam.security.alert; with auto-suspension on, the user an alert names is
+// suspended. Incidents are contained with break-glass lockdowns.
+type Service struct {
+	log         *zap.SugaredLogger
+	client      *okta.APIClient
+	cfg         *config.SecurityConfig
+	usersSvc    *user_service.Service
+	groupsSvc   *group_service.Service
+	auditSvc    *audit_service.Service
+	webhooksSvc *webhook_service.Service
+
+	mu sync.RWMutex
+	// checkedUntil is where the next check starts. Sign-ins logged before
+	// the service started are not looked at.
+	checkedUntil time.Time
+	// failures are the recent failed sign-ins by "user:<id>" and
+	// "ip:<address>", and lastSignIns the latest located successful
+	// sign-in of each user.
+	failures    map[string][]*failure
+	lastSignIns map[string]*models.SignInLocation
+	alerts      []*models.SecurityAlert
+	// lockdowns are the break-glass lockdowns by incident ID.
+	lockdowns map[string]*models.Lockdown
+
+	// lockdownMu serializes lockdowns and unlocks, so that one incident is
+	// not locked down or lifted twice at once.
+	lockdownMu sync.Mutex
+}
+
+func New(
+	log *zap.SugaredLogger,
+	client *okta.APIClient,
+	cfg *config.SecurityConfig,
+	usersSvc *user_service.Service,
+	groupsSvc *group_service.Service,
+	auditSvc *audit_service.Service,
+	webhooksSvc *webhook_service.Service,
+) *Service {
+	return &Service{
+		log:          log,
+		client:       client,
+		cfg:          cfg,
+		usersSvc:     usersSvc,
+		groupsSvc:    groupsSvc,
+		auditSvc:     auditSvc,
+		webhooksSvc:  webhooksSvc,
+		checkedUntil: time.Now().UTC().Add(-logDelay),
+		failures:     make(map[string][]*failure),
+		lastSignIns:  make(map[string]*models.SignInLocation),
+		lockdowns:    make(map[string]*models.Lockdown),
+	}
+}
+
+// Detect reads the sign-ins logged since the last check and raises alerts
+// on the patterns they complete.
+func (s *Service) Detect(ctx context.Context) error {
+	s.mu.RLock()
+	since := s.checkedUntil
+	s.mu.RUnlock()
+
+	until := time.Now().UTC().Add(-logDelay)
+	if !until.After(since) {
+		return nil
+	}
+
+	filter := fmt.Sprintf(`eventType eq "%s"`, strings.Join(signInEventTypes, `" or eventType eq "`))
+	events, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).
+		Since(since).Until(until).Filter(filter).SortOrder("ASCENDING").Execute()
+	if err == nil {
+		events, err = pagination.All(events, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err), "filter", filter)
+		return fmt.Errorf("failed to list sign-ins from Okta: %w", err)
+	}
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	found := make([]*models.SecurityAlert, 0)
+	for i := range events {
+		event := &events[i]
+		switch event.Outcome.GetResult() {
+		case "FAILURE", "DENY":
+			found = append(found, s.countFailure(event, now)...)
+		case "SUCCESS":
+			if event.GetEventType() != sessionStart {
+				continue
+			}
+			if alert := s.compareSignIn(event, now); alert != nil {
+				found = append(found, alert)
+			}
+		}
+	}
+	s.pruneFailures(until)
+	s.checkedUntil = until
+	s.mu.Unlock()
+
+	for _, alert := range found {
+		if s.cfg.AutoSuspend && alert.UserID != "" {
+			s.suspend(ctx, alert)
+		}
+	}
+
+	s.mu.Lock()
+	s.alerts = append(s.alerts, found...)
+	if len(s.alerts) > maxAlerts {
+		s.alerts = append([]*models.SecurityAlert(nil), s.alerts[len(s.alerts)-maxAlerts:]...)
+	}
+	s.mu.Unlock()
+
+	for _, alert := range found {
+		s.webhooksSvc.Publish(ctx, models.WebhookEventSecurityAlert, alert)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Checked Okta sign-ins for attacks",
+		"since", since,
+		"until", until,
+		"eventCount", len(events),
+		"alertCount", len(found),
+	)
+	return nil
+}
+
+// Alerts lists the alerts matching filter, newest first.
+func (s *Service) Alerts(ctx context.Context, filter *models.SecurityAlertFilter) *models.SecurityAlertList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := &models.SecurityAlertList{CheckedUntil: s.checkedUntil, Alerts: make([]*models.SecurityAlert, 0)}
+	for i := len(s.alerts) - 1; i >= 0; i-- {
+		if alert := s.alerts[i]; matches(alert, filter) {
+			list.Alerts = append(list.Alerts, alert)
+		}
+	}
+	list.Total = len(list.Alerts)
+
+	logger.FromContext(ctx, s.log).Infow("Security alerts listed", "total", list.Total)
+	return list
+}
+
+// countFailure adds a failed sign-in to the counts of its user and IP
+// address, and returns an alert for each that reaches the threshold. A
+// count that raised an alert starts over.
+func (s *Service) countFailure(event *okta.LogEvent, now time.Time) []*models.SecurityAlert {
+	f := &failure{
+		eventID: event.GetUuid(),
+		at:      event.GetPublished().UTC(),
+		ip:      event.Client.GetIpAddress(),
+	}
+	// Failed sign-ins for logins that do not exist name an unknown actor.
+	if event.Actor.GetType() == "User" && event.Actor.GetId() != "" && event.Actor.GetId() != "unknown" {
+		f.userID, f.login = event.Actor.GetId(), event.Actor.GetAlternateId()
+	}
+
+	alerts := make([]*models.SecurityAlert, 0)
+	for _, key := range []string{"user:" + f.userID, "ip:" + f.ip} {
+		if strings.HasSuffix(key, ":") {
+			continue
+		}
+
+		recent := s.failures[key][:0]
+		for _, previous := range s.failures[key] {
+			if f.at.Sub(previous.at) < s.cfg.BruteForceWindow {
+				recent = append(recent, previous)
+			}
+		}
+		recent = append(recent, f)
+		if len(recent) < s.cfg.BruteForceThreshold {
+			s.failures[key] = recent
+			continue
+		}
+		delete(s.failures, key)
+
+		alert := &models.SecurityAlert{
+			ID:         uuid.NewString(),
+			Type:       models.SecurityAlertBruteForce,
+			FirstSeen:  recent[0].at,
+			LastSeen:   f.at,
+			DetectedAt: now,
+			Failures:   len(recent),
+			EventIDs:   make([]string, 0, len(recent)),
+		}
+		for _, counted := range recent {
+			alert.EventIDs = append(alert.EventIDs, counted.eventID)
+		}
+		if strings.HasPrefix(key, "user:") {
+			alert.UserID, alert.UserLogin = f.userID, f.login
+		} else {
+			alert.IPAddress = f.ip
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// compareSignIn records a successful sign-in as the user's latest, and
+// returns an alert when reaching it from the previous one would have taken
+// more than the maximum travel speed.
+func (s *Service) compareSignIn(event *okta.LogEvent, now time.Time) *models.SecurityAlert {
+	userID := event.Actor.GetId()
+	geo := event.Client.GetGeographicalContext()
+	location := geo.GetGeolocation()
+	if userID == "" || location.Lat == nil || location.Lon == nil {
+		return nil
+	}
+
+	current := &models.SignInLocation{
+		At:        event.GetPublished().UTC(),
+		IPAddress: event.Client.GetIpAddress(),
+		City:      geo.GetCity(),
+		Country:   geo.GetCountry(),
+		Lat:       location.GetLat(),
+		Lon:       location.GetLon(),
+	}
+	previous := s.lastSignIns[userID]
+	s.lastSignIns[userID] = current
+	if previous == nil {
+		return nil
+	}
+
+	distance := haversine(previous, current)
+	if distance < minTravelDistance {
+		return nil
+	}
+	// Sign-ins logged within a minute of each other are taken as a minute
+	// apart, so the speed stays finite.
+	hours := max(current.At.Sub(previous.At), time.Minute).Hours()
+	speed := distance / hours
+	if speed <= float64(s.cfg.MaxTravelSpeed) {
+		return nil
+	}
+
+	return &models.SecurityAlert{
+		ID:         uuid.NewString(),
+		Type:       models.SecurityAlertImpossibleTravel,
+		UserID:     userID,
+		UserLogin:  event.Actor.GetAlternateId(),
+		IPAddress:  current.IPAddress,
+		FirstSeen:  previous.At,
+		LastSeen:   current.At,
+		DetectedAt: now,
+		EventIDs:   []string{event.GetUuid()},
+		From:       previous,
+		To:         current,
+		DistanceKm: math.Round(distance),
+		SpeedKmh:   math.Round(speed),
+	}
+}
+
+// pruneFailures drops the counts whose failures are all outside the window
+// ending at until, so that addresses seen once are not kept forever.
+func (s *Service) pruneFailures(until time.Time) {
+	for key, failures := range s.failures {
+		if until.Sub(failures[len(failures)-1].at) >= s.cfg.BruteForceWindow {
+			delete(s.failures, key)
+		}
+	}
+}
+
+// suspend suspends the user an alert names, and audits it.
+func (s *Service) suspend(ctx context.Context, alert *models.SecurityAlert) {
+	if err := s.usersSvc.SuspendUser(ctx, alert.UserID); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to suspend user after security alert", zap.Error(err),
+			"userId", alert.UserID, "alertId", alert.ID,
+		)
+		alert.SuspendError = err.Error()
+		return
+	}
+
+	alert.Suspended = true
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionUserAutoSuspended,
+		ResourceType: models.ResourceTypeUser,
+		ResourceID:   alert.UserID,
+		Details: map[string]any{
+			"reason":  alert.Type,
+			"alertId": alert.ID,
+		},
+	})
+}
+
+func matches(alert *models.SecurityAlert, filter *models.SecurityAlertFilter) bool {
+	switch {
+	case filter.Type != "" && alert.Type != filter.Type:
+		return false
+	case filter.UserID != "" && alert.UserID != filter.UserID:
+		return false
+	case filter.IPAddress != "" && alert.IPAddress != filter.IPAddress:
+		return false
+	case !filter.Since.IsZero() && alert.DetectedAt.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !alert.DetectedAt.Before(filter.Until):
+		return false
+	default:
+		return true
+	}
+}
+
+// haversine returns the great-circle distance between two places in km.
+func haversine(from, to *models.SignInLocation) float64 {
+	lat1, lat2 := from.Lat*math.Pi/180, to.Lat*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (to.Lon - from.Lon) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(a))
+}
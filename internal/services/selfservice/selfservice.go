@@ -0,0 +1,128 @@
+package selfservice_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	access_service "github.com/iamBelugaa/iam/internal/services/access"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrNoAttributes        = errors.New("at least one profile attribute is required")
+	ErrAttributeNotAllowed = errors.New("profile attributes cannot be changed through self-service")
+)
+
+// Service serves the caller's own profile, groups, factors and sessions, so
+// end-user portals can call this service with the user's access token. The
+// user is always the caller; the routes take it from the token.
+type Service struct {
+	log       *zap.SugaredLogger
+	usersSvc  *user_service.Service
+	accessSvc *access_service.Service
+	auditSvc  *audit_service.Service
+	// attributes are the profile attributes users may change themselves.
+	attributes []string
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.SelfServiceConfig, usersSvc *user_service.Service,
+	accessSvc *access_service.Service, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:        log,
+		usersSvc:   usersSvc,
+		accessSvc:  accessSvc,
+		auditSvc:   auditSvc,
+		attributes: cfg.ProfileAttributes,
+	}
+}
+
+func (s *Service) GetProfile(ctx context.Context, userID string) (*models.User, error) {
+	return s.usersSvc.GetUser(ctx, userID)
+}
+
+// UpdateProfile changes the caller's profile. The whole update is refused
+// when it names an attribute users may not change.
+func (s *Service) UpdateProfile(ctx context.Context, userID string, req *models.UpdateMyProfileRequest) (*models.User, error) {
+	if len(req.Profile) == 0 {
+		return nil, ErrNoAttributes
+	}
+
+	var names, refused []string
+	for name := range req.Profile {
+		if !slices.Contains(s.attributes, name) {
+			refused = append(refused, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(refused) > 0 {
+		sort.Strings(refused)
+		return nil, fmt.Errorf("%w: %s", ErrAttributeNotAllowed, strings.Join(refused, ", "))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Updating own profile", "userId", userID, "attributes", names)
+
+	// firstName and lastName have fields of their own in an update.
+	update := &models.UpdateUserRequest{Profile: make(map[string]any, len(req.Profile))}
+	for name, value := range req.Profile {
+		switch text, _ := value.(string); name {
+		case "firstName":
+			update.FirstName = text
+		case "lastName":
+			update.LastName = text
+		default:
+			update.Profile[name] = value
+		}
+	}
+
+	user, err := s.usersSvc.UpdateUser(ctx, userID, update)
+	if err != nil {
+		return nil, err
+	}
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        userID,
+		Action:       models.AuditActionSelfProfileUpdated,
+		ResourceType: models.ResourceTypeUser,
+		ResourceID:   userID,
+		Details:      map[string]any{"attributes": names},
+	})
+	return user, nil
+}
+
+func (s *Service) GetGroups(ctx context.Context, userID string) ([]*models.Group, error) {
+	return s.usersSvc.GetUserGroups(ctx, userID)
+}
+
+func (s *Service) GetFactors(ctx context.Context, userID string) (*models.FactorEnrollment, error) {
+	return s.accessSvc.GetFactors(ctx, userID)
+}
+
+// RevokeSessions signs the caller out everywhere, such as after losing a
+// device.
+func (s *Service) RevokeSessions(ctx context.Context, userID string, req *models.RevokeMySessionsRequest) error {
+	if err := s.usersSvc.RevokeUserSessions(ctx, userID, req.OAuthTokens); err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        userID,
+		Action:       models.AuditActionSelfSessionsRevoked,
+		ResourceType: models.ResourceTypeUser,
+		ResourceID:   userID,
+		Details:      map[string]any{"oauthTokens": req.OAuthTokens},
+	})
+	return nil
+}
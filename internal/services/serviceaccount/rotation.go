@@ -0,0 +1,259 @@
+package serviceaccount_service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+)
+
+// RotateCredential issues a new credential for the owner's account and returns it
+// together with a job that finishes the rotation in the background: the
+// owner is notified, the System Log is watched until the new credential is
+// used, and only then is the old credential revoked. If the new credential
+// is not seen within the verify timeout the job fails and nothing is revoked.
+func (s *Service) RotateCredential(ctx context.Context, actor, accountID string) (*models.CredentialRotation, error) {
+	tracker, account, err := s.startRotation(accountID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Rotating service account credential", "serviceAccountId", accountID, "jobId", tracker.JobID())
+
+	started := time.Now().UTC()
+	rotation := &models.CredentialRotation{ClientID: account.ClientID}
+
+	var oldSecretIDs []string
+	err = tracker.Step(models.RotationStepGenerate, func() (string, error) {
+		if account.Kind == models.ServiceAccountKindAppClient {
+			secrets, err := s.secrets.list(ctx, account.OktaID)
+			if err != nil {
+				return "", fmt.Errorf("failed to list client secrets in Okta: %w", err)
+			}
+
+			secret, err := s.secrets.create(ctx, account.OktaID)
+			if err != nil {
+				return "", fmt.Errorf("failed to create client secret in Okta: %w", err)
+			}
+
+			for _, old := range secrets {
+				oldSecretIDs = append(oldSecretIDs, old.ID)
+			}
+
+			rotation.ClientSecret = secret.ClientSecret
+			return fmt.Sprintf("Created client secret %s", secret.ID), nil
+		}
+
+		password, err := s.replacePassword(ctx, account.OktaID)
+		if err != nil {
+			return "", err
+		}
+
+		rotation.Password = password
+		return "Replaced password", nil
+	})
+	if err != nil {
+		tracker.Finish(err)
+		s.endRotation(accountID, nil)
+		return nil, err
+	}
+
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		err := s.completeRotation(ctx, tracker, actor, account, started, oldSecretIDs)
+		if err != nil {
+			s.endRotation(accountID, nil)
+			return err
+		}
+
+		s.endRotation(accountID, &started)
+		return nil
+	})
+
+	rotation.Job = tracker.Job()
+	return rotation, nil
+}
+
+func (s *Service) completeRotation(
+	ctx context.Context, tracker *job_service.Tracker, actor string,
+	account *models.ServiceAccount, started time.Time, oldSecretIDs []string,
+) error {
+	err := tracker.Step(models.RotationStepNotify, func() (string, error) {
+		s.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionServiceAccountRotationStarted,
+			ResourceType: models.ResourceTypeServiceAccount,
+			ResourceID:   account.ID,
+			Details: map[string]any{
+				"ownerId": account.OwnerID,
+				"jobId":   tracker.JobID(),
+			},
+		})
+		return fmt.Sprintf("Notified owner %s", account.OwnerID), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tracker.Step(models.RotationStepVerify, func() (string, error) {
+		used, err := s.waitForUsage(ctx, account, started)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("New credential used at %s", used.Format(time.RFC3339)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = tracker.Step(models.RotationStepRevoke, func() (string, error) {
+		if account.Kind == models.ServiceAccountKindAppClient {
+			for _, secretID := range oldSecretIDs {
+				if err := s.secrets.revoke(ctx, account.OktaID, secretID); err != nil {
+					return "", fmt.Errorf("failed to revoke client secret %s in Okta: %w", secretID, err)
+				}
+			}
+			return fmt.Sprintf("Revoked %d client secrets", len(oldSecretIDs)), nil
+		}
+
+		// The old password stopped working when it was replaced; what remains
+		// are sessions and tokens obtained with it.
+		response, err := s.client.UserAPI.RevokeUserSessions(ctx, account.OktaID).OauthTokens(true).Execute()
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to revoke service user sessions in Okta", zap.Error(err),
+				"userId", account.OktaID, "statusCode", okta_client.StatusCode(response),
+			)
+			return "", fmt.Errorf("failed to revoke service user sessions in Okta: %w", err)
+		}
+		return "Revoked sessions established with the old password", nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionServiceAccountRotated,
+		ResourceType: models.ResourceTypeServiceAccount,
+		ResourceID:   account.ID,
+		Details:      map[string]any{"jobId": tracker.JobID()},
+	})
+
+	return nil
+}
+
+// waitForUsage polls the System Log until the account authenticates
+// successfully after started. Okta does not record which of an app's client
+// secrets was presented, so any token grant after the rotation began counts.
+func (s *Service) waitForUsage(ctx context.Context, account *models.ServiceAccount, started time.Time) (time.Time, error) {
+	filter := fmt.Sprintf(`eventType eq "user.session.start" and actor.id eq "%s" and outcome.result eq "SUCCESS"`, account.OktaID)
+	if account.Kind == models.ServiceAccountKindAppClient {
+		filter = fmt.Sprintf(`eventType sw "app.oauth2" and actor.id eq "%s" and outcome.result eq "SUCCESS"`, account.ClientID)
+	}
+
+	deadline := time.NewTimer(s.cfg.RotationVerifyTimeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(s.cfg.RotationVerifyInterval)
+	defer ticker.Stop()
+
+	for {
+		events, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).Since(started).Filter(filter).Limit(1).Execute()
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err),
+				"serviceAccountId", account.ID, "statusCode", okta_client.StatusCode(response),
+			)
+		} else if len(events) > 0 {
+			return events[0].GetPublished(), nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return time.Time{}, ctx.Err()
+		case <-deadline.C:
+			return time.Time{}, fmt.Errorf(
+				"new credential was not used within %s; the old credential was left active", s.cfg.RotationVerifyTimeout,
+			)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) replacePassword(ctx context.Context, userID string) (string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+
+	// The suffix satisfies the character class rules of common password policies.
+	password := base64.RawURLEncoding.EncodeToString(random) + "aA1!"
+
+	_, response, err := s.client.UserAPI.UpdateUser(ctx, userID).User(okta.UpdateUserRequest{
+		Credentials: &okta.UserCredentials{Password: &okta.PasswordCredential{Value: &password}},
+	}).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to replace service user password in Okta", zap.Error(err),
+			"userId", userID, "statusCode", okta_client.StatusCode(response),
+		)
+		return "", fmt.Errorf("failed to replace service user password in Okta: %w", err)
+	}
+
+	return password, nil
+}
+
+func (s *Service) startRotation(accountID, actor string) (*job_service.Tracker, *models.ServiceAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return nil, nil, ErrServiceAccountNotFound
+	}
+
+	if account.OwnerID != actor {
+		return nil, nil, ErrNotOwner
+	}
+
+	if account.RotationJobID != "" {
+		return nil, nil, ErrRotationInProgress
+	}
+
+	tracker := s.jobsSvc.Create(
+		models.JobTypeCredentialRotation, models.ResourceTypeServiceAccount, accountID,
+		[]string{
+			models.RotationStepGenerate, models.RotationStepNotify,
+			models.RotationStepVerify, models.RotationStepRevoke,
+		},
+	)
+
+	account.RotationJobID = tracker.JobID()
+	s.save()
+	copied := *account
+	return tracker, &copied, nil
+}
+
+// endRotation releases the account for another rotation, recording rotated
+// as the new credential date when the rotation completed.
+func (s *Service) endRotation(accountID string, rotated *time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return
+	}
+
+	account.RotationJobID = ""
+	if rotated != nil {
+		account.CredentialRotated = *rotated
+	}
+	s.save()
+}
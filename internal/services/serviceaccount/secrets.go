@@ -0,0 +1,93 @@
+package serviceaccount_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+)
+
+// clientSecret is an OAuth client secret as returned by the Okta app
+// credentials API, which the SDK does not cover.
+type clientSecret struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// secretsClient manages the client secrets of an OAuth app. Okta allows two
+// active secrets per client, which is what makes zero-downtime rotation work.
+type secretsClient struct {
+	client *okta.APIClient
+}
+
+func (c *secretsClient) list(ctx context.Context, appID string) ([]clientSecret, error) {
+	var secrets []clientSecret
+	err := c.do(ctx, http.MethodGet, "/api/v1/apps/"+appID+"/credentials/secrets", nil, &secrets)
+	return secrets, err
+}
+
+func (c *secretsClient) create(ctx context.Context, appID string) (*clientSecret, error) {
+	var secret clientSecret
+	if err := c.do(ctx, http.MethodPost, "/api/v1/apps/"+appID+"/credentials/secrets", struct{}{}, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// revoke deactivates a secret, which Okta requires before it can be deleted.
+func (c *secretsClient) revoke(ctx context.Context, appID, secretID string) error {
+	path := "/api/v1/apps/" + appID + "/credentials/secrets/" + secretID
+	if err := c.do(ctx, http.MethodPost, path+"/lifecycle/deactivate", nil, nil); err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodDelete, path, nil, nil)
+}
+
+func (c *secretsClient) do(ctx context.Context, method, path string, body, out any) error {
+	cfg := c.client.GetConfig()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(cfg.Okta.Client.OrgUrl, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", cfg.Okta.Client.AuthorizationMode+" "+cfg.Okta.Client.Token)
+
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("okta api returned status %d: %s", res.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(out)
+}
@@ -0,0 +1,449 @@
+package serviceaccount_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+)
+
+// stateKey is the object holding the registry.
+const stateKey = "state.json"
+
+var (
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	ErrInvalidName            = errors.New("service account name does not match the naming convention")
+	ErrDuplicateName          = errors.New("service account name is already registered")
+	ErrOwnerNotFound          = errors.New("service account owner not found")
+	ErrNotOwner               = errors.New("caller is not the service account owner")
+	ErrRotationInProgress     = errors.New("a credential rotation is already in progress")
+)
+
+type Service struct {
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	cfg      *config.ServiceAccountsConfig
+	auditSvc *audit_service.Service
+	jobsSvc  *job_service.Service
+	secrets  *secretsClient
+	pattern  *regexp.Regexp
+	// store keeps the registry across restarts, stored as one object on
+	// every change. It is nil when it is only kept in memory.
+	store objectstore.Store
+
+	mu       sync.RWMutex
+	accounts map[string]*models.ServiceAccount
+}
+
+// state is the registry, keyed by account ID. Client secrets are never part
+// of it.
+type state struct {
+	Accounts map[string]*models.ServiceAccount `json:"accounts"`
+}
+
+// New returns the service with the registry read from store, which may be nil.
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, cfg *config.ServiceAccountsConfig, store objectstore.Store,
+	auditSvc *audit_service.Service, jobsSvc *job_service.Service,
+) (*Service, error) {
+	s := &Service{
+		log:      log,
+		client:   client,
+		cfg:      cfg,
+		auditSvc: auditSvc,
+		jobsSvc:  jobsSvc,
+		secrets:  &secretsClient{client: client},
+		pattern:  regexp.MustCompile(cfg.NamePattern),
+		store:    store,
+		accounts: make(map[string]*models.ServiceAccount),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service accounts: %w", err)
+	}
+
+	var stored state
+	if err := json.Unmarshal(object.Data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode service accounts: %w", err)
+	}
+	for id, account := range stored.Accounts {
+		// A rotation does not outlive the server that ran it, so the account
+		// is free for another.
+		account.RotationJobID = ""
+		s.accounts[id] = account
+	}
+	return s, nil
+}
+
+// save stores the registry. Callers hold mu. A failure is logged rather than
+// returned: the account's Okta identity has been changed either way.
+func (s *Service) save() {
+	if s.store == nil {
+		return
+	}
+
+	data, err := json.Marshal(state{Accounts: s.accounts})
+	if err == nil {
+		err = s.store.Put(context.Background(), stateKey, "application/json", data)
+	}
+	if err != nil {
+		s.log.Infow("Failed to store service accounts", zap.Error(err))
+	}
+}
+
+// CreateServiceAccount provisions the backing identity in Okta and registers
+// it. The name must match the configured convention and the owner must be an
+// existing Okta user.
+func (s *Service) CreateServiceAccount(
+	ctx context.Context, actor string, req *models.CreateServiceAccountRequest,
+) (*models.ServiceAccount, error) {
+	logger.FromContext(ctx, s.log).Infow("Creating service account", "name", req.Name, "kind", req.Kind, "ownerId", req.OwnerID)
+
+	if !s.pattern.MatchString(req.Name) {
+		return nil, ErrInvalidName
+	}
+
+	if s.nameTaken(req.Name) {
+		return nil, ErrDuplicateName
+	}
+
+	if _, response, err := s.client.UserAPI.GetUser(ctx, req.OwnerID).Execute(); err != nil {
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrOwnerNotFound
+		}
+		return nil, fmt.Errorf("failed to get service account owner from Okta: %w", err)
+	}
+
+	now := time.Now().UTC()
+	account := &models.ServiceAccount{
+		ID:                   uuid.NewString(),
+		Name:                 req.Name,
+		Kind:                 req.Kind,
+		OwnerID:              req.OwnerID,
+		Description:          req.Description,
+		ExpiresAt:            req.ExpiresAt,
+		ReviewBy:             now.Add(s.cfg.ReviewInterval),
+		RotationIntervalDays: req.RotationIntervalDays,
+		CredentialRotated:    now,
+		Created:              now,
+	}
+
+	if req.ReviewBy != nil {
+		account.ReviewBy = req.ReviewBy.UTC()
+	}
+
+	if account.RotationIntervalDays == 0 {
+		account.RotationIntervalDays = s.cfg.RotationIntervalDays
+	}
+
+	var err error
+	switch req.Kind {
+	case models.ServiceAccountKindAppClient:
+		err = s.createAppClient(ctx, account)
+	default:
+		err = s.createUser(ctx, account)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.nameTakenLocked(account.Name) {
+		s.mu.Unlock()
+		// Another request registered the name while Okta was being called.
+		s.decommission(ctx, account)
+		return nil, ErrDuplicateName
+	}
+	stored := *account
+	stored.ClientSecret = ""
+	s.accounts[account.ID] = &stored
+	s.save()
+	s.mu.Unlock()
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionServiceAccountCreated,
+		ResourceType: models.ResourceTypeServiceAccount,
+		ResourceID:   account.ID,
+		Details: map[string]any{
+			"name":    account.Name,
+			"kind":    account.Kind,
+			"oktaId":  account.OktaID,
+			"ownerId": account.OwnerID,
+		},
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Service account created successfully", "serviceAccountId", account.ID, "oktaId", account.OktaID)
+	return account, nil
+}
+
+func (s *Service) createUser(ctx context.Context, account *models.ServiceAccount) error {
+	login := account.Name + "@" + s.cfg.LoginDomain
+
+	var profile okta.UserProfile
+	profile.SetLogin(login)
+	profile.SetEmail(login)
+	profile.SetFirstName("Service")
+	profile.SetLastName(account.Name)
+
+	user, response, err := s.client.UserAPI.
+		CreateUser(ctx).Body(okta.CreateUserRequest{Profile: profile}).Activate(true).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to create service user in Okta", zap.Error(err), "name", account.Name, "statusCode", okta_client.StatusCode(response))
+		return fmt.Errorf("failed to create service user in Okta: %w", err)
+	}
+
+	account.OktaID = user.GetId()
+	return nil
+}
+
+// createAppClient registers an OAuth service app that authenticates with the
+// client credentials grant.
+func (s *Service) createAppClient(ctx context.Context, account *models.ServiceAccount) error {
+	oauthClient := okta.NewOpenIdConnectApplicationSettingsClient()
+	oauthClient.SetApplicationType("service")
+	oauthClient.SetGrantTypes([]string{"client_credentials"})
+	oauthClient.SetResponseTypes([]string{"token"})
+
+	settings := okta.OpenIdConnectApplicationSettings{OauthClient: oauthClient}
+
+	credentials := okta.OAuthApplicationCredentials{OauthClient: &okta.ApplicationCredentialsOAuthClient{}}
+	credentials.OauthClient.SetTokenEndpointAuthMethod("client_secret_basic")
+	credentials.OauthClient.SetAutoKeyRotation(true)
+
+	app := okta.NewOpenIdConnectApplication(credentials, "oidc_client", settings, account.Name, "OPENID_CONNECT")
+
+	created, response, err := s.client.ApplicationAPI.
+		CreateApplication(ctx).
+		Application(okta.OpenIdConnectApplicationAsListApplications200ResponseInner(app)).
+		Activate(true).
+		Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to create service app in Okta", zap.Error(err), "name", account.Name, "statusCode", okta_client.StatusCode(response))
+		return fmt.Errorf("failed to create service app in Okta: %w", err)
+	}
+
+	oidc := created.OpenIdConnectApplication
+	if oidc == nil {
+		return fmt.Errorf("failed to create service app in Okta: unexpected application type")
+	}
+
+	account.OktaID = oidc.GetId()
+	if oidc.Credentials.OauthClient != nil {
+		account.ClientID = oidc.Credentials.OauthClient.GetClientId()
+		account.ClientSecret = oidc.Credentials.OauthClient.GetClientSecret()
+	}
+
+	return nil
+}
+
+func (s *Service) GetServiceAccounts(ctx context.Context) []*models.ServiceAccount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.ServiceAccount, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		copied := *account
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func (s *Service) GetServiceAccount(ctx context.Context, accountID string) (*models.ServiceAccount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, ok := s.accounts[accountID]
+	if !ok {
+		return nil, ErrServiceAccountNotFound
+	}
+
+	copied := *account
+	return &copied, nil
+}
+
+// DeleteServiceAccount deactivates and removes the backing Okta identity and
+// drops the account from the registry.
+func (s *Service) DeleteServiceAccount(ctx context.Context, actor, accountID string) error {
+	s.mu.Lock()
+	account, ok := s.accounts[accountID]
+	if ok {
+		delete(s.accounts, accountID)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return ErrServiceAccountNotFound
+	}
+
+	if err := s.decommission(ctx, account); err != nil {
+		s.mu.Lock()
+		s.accounts[accountID] = account
+		s.save()
+		s.mu.Unlock()
+		return err
+	}
+
+	s.mu.Lock()
+	s.save()
+	s.mu.Unlock()
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       models.AuditActionServiceAccountDeleted,
+		ResourceType: models.ResourceTypeServiceAccount,
+		ResourceID:   account.ID,
+		Details:      map[string]any{"name": account.Name, "oktaId": account.OktaID},
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Service account deleted successfully", "serviceAccountId", accountID)
+	return nil
+}
+
+func (s *Service) decommission(ctx context.Context, account *models.ServiceAccount) error {
+	if account.Kind == models.ServiceAccountKindAppClient {
+		if response, err := s.client.ApplicationAPI.DeactivateApplication(ctx, account.OktaID).Execute(); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to deactivate service app in Okta", zap.Error(err), "appId", account.OktaID, "statusCode", okta_client.StatusCode(response))
+			return fmt.Errorf("failed to deactivate service app in Okta: %w", err)
+		}
+
+		if response, err := s.client.ApplicationAPI.DeleteApplication(ctx, account.OktaID).Execute(); err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to delete service app in Okta", zap.Error(err), "appId", account.OktaID, "statusCode", okta_client.StatusCode(response))
+			return fmt.Errorf("failed to delete service app in Okta: %w", err)
+		}
+
+		return nil
+	}
+
+	// Deleting an active user first deactivates it, so the call is repeated.
+	for range 2 {
+		response, err := s.client.UserAPI.DeleteUser(ctx, account.OktaID).Execute()
+		if err != nil {
+			if okta_client.StatusCode(response) == http.StatusNotFound {
+				return nil
+			}
+			logger.FromContext(ctx, s.log).Infow("Failed to delete service user in Okta", zap.Error(err), "userId", account.OktaID, "statusCode", okta_client.StatusCode(response))
+			return fmt.Errorf("failed to delete service user in Okta: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReviewServiceAccount records the owner's attestation that the account is
+// still needed and pushes the next review date out by the review interval.
+func (s *Service) ReviewServiceAccount(ctx context.Context, accountID, reviewedBy string) (*models.ServiceAccount, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	account, ok := s.accounts[accountID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrServiceAccountNotFound
+	}
+
+	if account.OwnerID != reviewedBy {
+		s.mu.Unlock()
+		return nil, ErrNotOwner
+	}
+
+	account.LastReviewed = &now
+	account.LastReviewedBy = reviewedBy
+	account.ReviewBy = now.Add(s.cfg.ReviewInterval)
+	reviewed := *account
+	s.save()
+	s.mu.Unlock()
+
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        reviewedBy,
+		Action:       models.AuditActionServiceAccountReviewed,
+		ResourceType: models.ResourceTypeServiceAccount,
+		ResourceID:   accountID,
+		Details:      map[string]any{"reviewBy": reviewed.ReviewBy},
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Service account reviewed successfully", "serviceAccountId", accountID, "reviewBy", reviewed.ReviewBy)
+	return &reviewed, nil
+}
+
+// GetPastReview lists accounts whose review date is before now, most overdue first.
+func (s *Service) GetPastReview(ctx context.Context, now time.Time) []*models.ServiceAccountPastReview {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.ServiceAccountPastReview
+	for _, account := range s.accounts {
+		if !account.ReviewBy.Before(now) {
+			continue
+		}
+
+		copied := *account
+		result = append(result, &models.ServiceAccountPastReview{
+			ServiceAccount: &copied,
+			DaysOverdue:    int(now.Sub(account.ReviewBy).Hours() / 24),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ReviewBy.Before(result[j].ReviewBy) })
+	return result
+}
+
+// GetRotationDue lists accounts whose credential is due for rotation at now.
+func (s *Service) GetRotationDue(ctx context.Context, now time.Time) []*models.ServiceAccount {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var result []*models.ServiceAccount
+	for _, account := range s.accounts {
+		if account.NextRotation().After(now) {
+			continue
+		}
+
+		copied := *account
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].NextRotation().Before(result[j].NextRotation()) })
+	return result
+}
+
+func (s *Service) nameTaken(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nameTakenLocked(name)
+}
+
+func (s *Service) nameTakenLocked(name string) bool {
+	for _, account := range s.accounts {
+		if account.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package session_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+var (
+	ErrNotConfigured         = errors.New("the session token exchange is not configured")
+	ErrOriginNotAllowed      = errors.New("the request origin is not allowed to exchange session tokens")
+	ErrSessionTokenRequired  = errors.New("session token is required")
+	ErrRedirectURLNotAllowed = errors.New("redirect URL must be an absolute URL on an allowed origin")
+)
+
+// Service exchanges the session tokens of embedded authentication flows for
+// Okta session cookie redirects, so that legacy apps using embedded login go
+// through one audited component. Both the page asking for the exchange and
+// the page the browser ends up on must be on an allowed origin.
+type Service struct {
+	log      *zap.SugaredLogger
+	cfg      *config.SessionExchangeConfig
+	client   *okta.APIClient
+	auditSvc *audit_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, cfg *config.SessionExchangeConfig, client *okta.APIClient,
+	auditSvc *audit_service.Service,
+) *Service {
+	return &Service{log: log, cfg: cfg, client: client, auditSvc: auditSvc}
+}
+
+// AllowedOrigin reports whether origin may ask for an exchange.
+func (s *Service) AllowedOrigin(origin string) bool {
+	return origin != "" && slices.Contains(s.cfg.AllowedOrigins, origin)
+}
+
+// CookieRedirect returns the Okta URL that turns the session token into a
+// session cookie and then redirects to req.RedirectURL. origin is the Origin
+// header of the request. Rejected exchanges are audited too.
+func (s *Service) CookieRedirect(
+	ctx context.Context, origin string, req *models.SessionCookieRedirectRequest,
+) (*models.SessionCookieRedirect, error) {
+	if len(s.cfg.AllowedOrigins) == 0 {
+		return nil, ErrNotConfigured
+	}
+
+	if err := s.check(origin, req); err != nil {
+		s.record(ctx, origin, models.AuditActionSessionCookieRedirectRejected, req, map[string]any{
+			"reason": err.Error(),
+		})
+		logger.FromContext(ctx, s.log).Infow("Session token exchange rejected", zap.Error(err), "origin", origin)
+		return nil, err
+	}
+
+	query := url.Values{"token": {req.SessionToken}, "redirectUrl": {req.RedirectURL}}
+	orgURL := strings.TrimRight(s.client.GetConfig().Okta.Client.OrgUrl, "/")
+	redirect := &models.SessionCookieRedirect{URL: orgURL + "/login/sessionCookieRedirect?" + query.Encode()}
+
+	s.record(ctx, origin, models.AuditActionSessionCookieRedirectIssued, req, nil)
+	logger.FromContext(ctx, s.log).Infow("Session token exchanged for a cookie redirect", "origin", origin)
+	return redirect, nil
+}
+
+func (s *Service) check(origin string, req *models.SessionCookieRedirectRequest) error {
+	if !s.AllowedOrigin(origin) {
+		return ErrOriginNotAllowed
+	}
+	if req.SessionToken == "" {
+		return ErrSessionTokenRequired
+	}
+
+	redirectURL, err := url.Parse(req.RedirectURL)
+	if err != nil || !redirectURL.IsAbs() || redirectURL.User != nil || !s.AllowedOrigin(redirectURL.Scheme+"://"+redirectURL.Host) {
+		return ErrRedirectURLNotAllowed
+	}
+	return nil
+}
+
+// record audits an exchange. The session token is a credential, so only its
+// hash is kept, as the resource ID, to correlate with Okta's own logs of its
+// use.
+func (s *Service) record(
+	ctx context.Context, origin, action string, req *models.SessionCookieRedirectRequest, details map[string]any,
+) {
+	if details == nil {
+		details = make(map[string]any)
+	}
+	details["origin"] = origin
+	details["redirectUrl"] = req.RedirectURL
+
+	var tokenHash string
+	if req.SessionToken != "" {
+		sum := sha256.Sum256([]byte(req.SessionToken))
+		tokenHash = hex.EncodeToString(sum[:])
+	}
+
+	actor := "origin:" + origin
+	if origin == "" {
+		actor = "origin:unknown"
+	}
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeSession,
+		ResourceID:   tokenHash,
+		Details:      details,
+	})
+}
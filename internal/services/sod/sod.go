@@ -0,0 +1,312 @@
+package sod_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+// stateKey is the object holding the policies.
+const stateKey = "state.json"
+
+var ErrPolicyNotFound = errors.New("sod policy not found")
+
+// ViolationError is returned when a membership would break an enforced policy.
+type ViolationError struct {
+	Violations []*models.SoDViolation
+}
+
+func (e *ViolationError) Error() string {
+	names := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		names[i] = violation.PolicyName
+	}
+	return fmt.Sprintf("membership violates separation of duties policies: %s", strings.Join(names, ", "))
+}
+
+type Service struct {
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	auditSvc *audit_service.Service
+	// store keeps the policies across restarts, stored as one object on
+	// every change. It is nil when they are only kept in memory.
+	store objectstore.Store
+
+	mu    sync.RWMutex
+	state state
+}
+
+// state is the policies, keyed by ID.
+type state struct {
+	Policies map[string]*models.SoDPolicy `json:"policies"`
+}
+
+// New returns the service with the policies read from store, which may be nil.
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, store objectstore.Store, auditSvc *audit_service.Service,
+) (*Service, error) {
+	s := &Service{
+		log:      log,
+		client:   client,
+		auditSvc: auditSvc,
+		store:    store,
+		state:    state{Policies: make(map[string]*models.SoDPolicy)},
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sod policies: %w", err)
+	}
+	if err := json.Unmarshal(object.Data, &s.state); err != nil {
+		return nil, fmt.Errorf("failed to decode sod policies: %w", err)
+	}
+	if s.state.Policies == nil {
+		s.state.Policies = make(map[string]*models.SoDPolicy)
+	}
+	return s, nil
+}
+
+// save stores the policies. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(s.state)
+	if err != nil {
+		return fmt.Errorf("failed to encode sod policies: %w", err)
+	}
+	if err := s.store.Put(ctx, stateKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store sod policies: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) CreatePolicy(ctx context.Context, req *models.CreateSoDPolicyRequest) (*models.SoDPolicy, error) {
+	logger.FromContext(ctx, s.log).Infow("Creating SoD policy", "name", req.Name, "groupA", req.GroupA, "groupB", req.GroupB)
+
+	policy := &models.SoDPolicy{
+		ID:          uuid.NewString(),
+		Name:        req.Name,
+		Description: req.Description,
+		GroupA:      req.GroupA,
+		GroupB:      req.GroupB,
+		Mode:        req.Mode,
+		Created:     time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Policies[policy.ID] = policy
+	if err := s.save(ctx); err != nil {
+		delete(s.state.Policies, policy.ID)
+		logger.FromContext(ctx, s.log).Infow("Failed to store SoD policy", zap.Error(err), "policyId", policy.ID)
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("SoD policy created successfully", "policyId", policy.ID)
+	return policy, nil
+}
+
+func (s *Service) GetPolicies(ctx context.Context) []*models.SoDPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.SoDPolicy, 0, len(s.state.Policies))
+	for _, policy := range s.state.Policies {
+		result = append(result, policy)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Created.Before(result[j].Created) })
+	return result
+}
+
+func (s *Service) GetPolicy(ctx context.Context, policyID string) (*models.SoDPolicy, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	policy, ok := s.state.Policies[policyID]
+	if !ok {
+		return nil, ErrPolicyNotFound
+	}
+	return policy, nil
+}
+
+func (s *Service) DeletePolicy(ctx context.Context, policyID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	policy, ok := s.state.Policies[policyID]
+	if !ok {
+		return ErrPolicyNotFound
+	}
+
+	delete(s.state.Policies, policyID)
+	if err := s.save(ctx); err != nil {
+		s.state.Policies[policyID] = policy
+		logger.FromContext(ctx, s.log).Infow("Failed to store SoD policy deletion", zap.Error(err), "policyId", policyID)
+		return err
+	}
+	logger.FromContext(ctx, s.log).Infow("SoD policy deleted successfully", "policyId", policyID)
+	return nil
+}
+
+// CheckMembership vets adding userID to groupID against every policy that
+// involves the group. Violations of flagged policies are audited and allowed;
+// violations of enforced policies are returned as a *ViolationError.
+func (s *Service) CheckMembership(ctx context.Context, groupID, userID string) error {
+	conflicting := s.conflictingPolicies(groupID)
+	if len(conflicting) == 0 {
+		return nil
+	}
+
+	groups, response, err := s.client.UserAPI.ListUserGroups(ctx, userID).Execute()
+	if err == nil {
+		groups, err = pagination.All(groups, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get user groups for SoD check", zap.Error(err), "userId", userID)
+		return fmt.Errorf("failed to check separation of duties: %w", err)
+	}
+
+	memberOf := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		memberOf[group.GetId()] = true
+	}
+
+	var enforced []*models.SoDViolation
+	for otherGroupID, policies := range conflicting {
+		if !memberOf[otherGroupID] {
+			continue
+		}
+
+		for _, policy := range policies {
+			violation := &models.SoDViolation{
+				PolicyID:           policy.ID,
+				PolicyName:         policy.Name,
+				Mode:               policy.Mode,
+				UserID:             userID,
+				GroupID:            groupID,
+				ConflictingGroupID: otherGroupID,
+			}
+
+			if policy.Mode == models.SoDModeEnforce {
+				enforced = append(enforced, violation)
+				continue
+			}
+
+			logger.FromContext(ctx, s.log).Infow("SoD violation flagged", "policyId", policy.ID, "userId", userID, "groupId", groupID)
+			s.auditSvc.Record(ctx, &models.AuditEntry{
+				Actor:        "system:sod",
+				Action:       models.AuditActionSoDViolationFlagged,
+				ResourceType: models.ResourceTypeGroup,
+				ResourceID:   groupID,
+				Details: map[string]any{
+					"policyId":           policy.ID,
+					"userId":             userID,
+					"conflictingGroupId": otherGroupID,
+				},
+			})
+		}
+	}
+
+	if len(enforced) > 0 {
+		logger.FromContext(ctx, s.log).Infow("SoD violation rejected", "userId", userID, "groupId", groupID, "violationCount", len(enforced))
+		return &ViolationError{Violations: enforced}
+	}
+
+	return nil
+}
+
+// GetViolations lists every user who currently holds both groups of a policy.
+func (s *Service) GetViolations(ctx context.Context) ([]*models.SoDViolation, error) {
+	logger.FromContext(ctx, s.log).Infow("Building SoD violations report")
+
+	violations := make([]*models.SoDViolation, 0)
+	for _, policy := range s.GetPolicies(ctx) {
+		membersA, err := s.groupMemberIDs(ctx, policy.GroupA)
+		if err != nil {
+			return nil, err
+		}
+
+		membersB, err := s.groupMemberIDs(ctx, policy.GroupB)
+		if err != nil {
+			return nil, err
+		}
+
+		for userID := range membersA {
+			if membersB[userID] {
+				violations = append(violations, &models.SoDViolation{
+					PolicyID:           policy.ID,
+					PolicyName:         policy.Name,
+					Mode:               policy.Mode,
+					UserID:             userID,
+					GroupID:            policy.GroupA,
+					ConflictingGroupID: policy.GroupB,
+				})
+			}
+		}
+	}
+
+	logger.FromContext(ctx, s.log).Infow("SoD violations report built successfully", "violationCount", len(violations))
+	return violations, nil
+}
+
+// conflictingPolicies returns the policies involving groupID keyed by the
+// group on the other side of each policy.
+func (s *Service) conflictingPolicies(groupID string) map[string][]*models.SoDPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]*models.SoDPolicy)
+	for _, policy := range s.state.Policies {
+		switch groupID {
+		case policy.GroupA:
+			result[policy.GroupB] = append(result[policy.GroupB], policy)
+		case policy.GroupB:
+			result[policy.GroupA] = append(result[policy.GroupA], policy)
+		}
+	}
+
+	return result
+}
+
+func (s *Service) groupMemberIDs(ctx context.Context, groupID string) (map[string]bool, error) {
+	members, response, err := s.client.GroupAPI.ListGroupUsers(ctx, groupID).Execute()
+	if err == nil {
+		members, err = pagination.All(members, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get group members for SoD report", zap.Error(err), "groupId", groupID)
+		return nil, fmt.Errorf("failed to get members of group %s: %w", groupID, err)
+	}
+
+	result := make(map[string]bool, len(members))
+	for _, member := range members {
+		result[member.GetId()] = true
+	}
+
+	return result, nil
+}
@@ -0,0 +1,338 @@
+package sync_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+var ErrUnknownSpoke = errors.New("unknown spoke org")
+
+// baseProfileAttributes are always copied between orgs.
+var baseProfileAttributes = []string{"login", "email", "firstName", "lastName"}
+
+// Service pushes users and groups between the hub org and its spoke orgs.
+type Service struct {
+	log      *zap.SugaredLogger
+	hub      *okta.APIClient
+	spokes   map[string]*okta.APIClient
+	auditSvc *audit_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, hub *okta.APIClient, spokes map[string]*okta.APIClient, auditSvc *audit_service.Service,
+) *Service {
+	return &Service{log: log, hub: hub, spokes: spokes, auditSvc: auditSvc}
+}
+
+// GetSpokes returns the names of the configured spoke orgs.
+func (s *Service) GetSpokes() []string {
+	names := make([]string, 0, len(s.spokes))
+	for name := range s.spokes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Push copies the requested users and groups in req.Direction. Pushes that are
+// not dry runs are audited as made by actor.
+func (s *Service) Push(ctx context.Context, actor string, req *models.SyncPushRequest) (*models.SyncPushResult, error) {
+	spoke, ok := s.spokes[req.Spoke]
+	if !ok {
+		return nil, ErrUnknownSpoke
+	}
+
+	source, target := s.hub, spoke
+	if req.Direction == models.SyncDirectionSpokeToHub {
+		source, target = spoke, s.hub
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Pushing users and groups between orgs",
+		"spoke", req.Spoke,
+		"direction", req.Direction,
+		"userCount", len(req.UserIDs),
+		"groupCount", len(req.GroupIDs),
+		"dryRun", req.DryRun,
+		"actor", actor,
+	)
+
+	result := &models.SyncPushResult{
+		Spoke:     req.Spoke,
+		Direction: req.Direction,
+		DryRun:    req.DryRun,
+		Users:     make([]*models.SyncItemResult, 0, len(req.UserIDs)),
+		Groups:    make([]*models.SyncItemResult, 0, len(req.GroupIDs)),
+	}
+
+	for _, userID := range req.UserIDs {
+		result.Users = append(result.Users, s.pushUser(ctx, source, target, userID, req))
+	}
+
+	for _, groupID := range req.GroupIDs {
+		result.Groups = append(result.Groups, s.pushGroup(ctx, source, target, groupID, req))
+	}
+
+	if !req.DryRun {
+		s.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionSyncPushed,
+			ResourceType: models.ResourceTypeOrg,
+			ResourceID:   req.Spoke,
+			Details: map[string]any{
+				"direction": req.Direction,
+				"userIds":   req.UserIDs,
+				"groupIds":  req.GroupIDs,
+				"outcomes":  outcomes(result),
+			},
+		})
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Push between orgs completed", "spoke", req.Spoke, "direction", req.Direction)
+	return result, nil
+}
+
+// outcomes counts the users and groups of result by outcome.
+func outcomes(result *models.SyncPushResult) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range result.Users {
+		counts[item.Outcome]++
+	}
+	for _, item := range result.Groups {
+		counts[item.Outcome]++
+	}
+	return counts
+}
+
+func (s *Service) pushUser(ctx context.Context, source, target *okta.APIClient, userID string, req *models.SyncPushRequest) *models.SyncItemResult {
+	item := &models.SyncItemResult{SourceID: userID}
+
+	sourceUser, _, err := source.UserAPI.GetUser(ctx, userID).Execute()
+	if err != nil {
+		return failed(item, fmt.Errorf("failed to get source user: %w", err))
+	}
+
+	profile, err := MapProfile(sourceUser.Profile, req.FieldMapping)
+	if err != nil {
+		return failed(item, err)
+	}
+	item.Key = profile.GetLogin()
+
+	existing, found, err := findUserByLogin(ctx, target, item.Key)
+	if err != nil {
+		return failed(item, err)
+	}
+
+	if !found {
+		item.Outcome = models.SyncOutcomeCreated
+		if req.DryRun {
+			return item
+		}
+
+		created, _, err := target.UserAPI.CreateUser(ctx).
+			Body(okta.CreateUserRequest{Profile: *profile}).Activate(req.Activate).Execute()
+		if err != nil {
+			return failed(item, fmt.Errorf("failed to create target user: %w", err))
+		}
+
+		item.TargetID = created.GetId()
+		return item
+	}
+
+	item.TargetID = existing
+	switch req.ConflictStrategy {
+	case models.ConflictStrategyFail:
+		item.Outcome = models.SyncOutcomeConflict
+		item.Error = "user already exists in target org"
+		return item
+	case models.ConflictStrategyOverwrite:
+		item.Outcome = models.SyncOutcomeUpdated
+		if req.DryRun {
+			return item
+		}
+
+		if _, _, err := target.UserAPI.UpdateUser(ctx, existing).
+			User(okta.UpdateUserRequest{Profile: profile}).Execute(); err != nil {
+			return failed(item, fmt.Errorf("failed to update target user: %w", err))
+		}
+		return item
+	default:
+		item.Outcome = models.SyncOutcomeSkipped
+		return item
+	}
+}
+
+func (s *Service) pushGroup(ctx context.Context, source, target *okta.APIClient, groupID string, req *models.SyncPushRequest) *models.SyncItemResult {
+	item := &models.SyncItemResult{SourceID: groupID}
+
+	sourceGroup, _, err := source.GroupAPI.GetGroup(ctx, groupID).Execute()
+	if err != nil {
+		return failed(item, fmt.Errorf("failed to get source group: %w", err))
+	}
+
+	if sourceGroup.Profile == nil {
+		return failed(item, errors.New("source group has no profile"))
+	}
+
+	profile := okta.GroupProfile{
+		Name:        sourceGroup.Profile.Name,
+		Description: sourceGroup.Profile.Description,
+	}
+	item.Key = profile.GetName()
+
+	targetID, found, err := findGroupByName(ctx, target, item.Key)
+	if err != nil {
+		return failed(item, err)
+	}
+
+	switch {
+	case !found:
+		item.Outcome = models.SyncOutcomeCreated
+		if !req.DryRun {
+			created, _, err := target.GroupAPI.CreateGroup(ctx).Group(okta.Group{Profile: &profile}).Execute()
+			if err != nil {
+				return failed(item, fmt.Errorf("failed to create target group: %w", err))
+			}
+			targetID = created.GetId()
+		}
+	case req.ConflictStrategy == models.ConflictStrategyFail:
+		item.TargetID = targetID
+		item.Outcome = models.SyncOutcomeConflict
+		item.Error = "group already exists in target org"
+		return item
+	case req.ConflictStrategy == models.ConflictStrategyOverwrite:
+		item.Outcome = models.SyncOutcomeUpdated
+		if !req.DryRun {
+			if _, _, err := target.GroupAPI.ReplaceGroup(ctx, targetID).
+				Group(okta.Group{Profile: &profile}).Execute(); err != nil {
+				return failed(item, fmt.Errorf("failed to update target group: %w", err))
+			}
+		}
+	default:
+		item.TargetID = targetID
+		item.Outcome = models.SyncOutcomeSkipped
+		return item
+	}
+	item.TargetID = targetID
+
+	// Mirror memberships for members that already exist in the target org.
+	members, response, err := source.GroupAPI.ListGroupUsers(ctx, groupID).Execute()
+	if err == nil {
+		members, err = pagination.All(members, response)
+	}
+	if err != nil {
+		return failed(item, fmt.Errorf("failed to list source group members: %w", err))
+	}
+
+	for _, member := range members {
+		if member.Profile == nil {
+			continue
+		}
+
+		targetUserID, found, err := findUserByLogin(ctx, target, member.Profile.GetLogin())
+		if err != nil || !found {
+			item.MembersMissing++
+			continue
+		}
+
+		if !req.DryRun && targetID != "" {
+			if _, err := target.GroupAPI.AssignUserToGroup(ctx, targetID, targetUserID).Execute(); err != nil {
+				item.MembersMissing++
+				continue
+			}
+		}
+		item.MembersAdded++
+	}
+
+	return item
+}
+
+// MapProfile builds the target profile from the base attributes plus the
+// attributes named in mapping. Migrations between orgs map profiles the same
+// way.
+func MapProfile(source *okta.UserProfile, mapping map[string]string) (*okta.UserProfile, error) {
+	if source == nil {
+		return nil, errors.New("source user has no profile")
+	}
+
+	raw, err := json.Marshal(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode source profile: %w", err)
+	}
+
+	var attributes map[string]any
+	if err := json.Unmarshal(raw, &attributes); err != nil {
+		return nil, fmt.Errorf("failed to decode source profile: %w", err)
+	}
+
+	mapped := make(map[string]any, len(baseProfileAttributes)+len(mapping))
+	for _, name := range baseProfileAttributes {
+		if value, ok := attributes[name]; ok {
+			mapped[name] = value
+		}
+	}
+	for from, to := range mapping {
+		if value, ok := attributes[from]; ok {
+			mapped[to] = value
+		}
+	}
+
+	raw, err = json.Marshal(mapped)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode target profile: %w", err)
+	}
+
+	var profile okta.UserProfile
+	if err := json.Unmarshal(raw, &profile); err != nil {
+		return nil, fmt.Errorf("failed to build target profile: %w", err)
+	}
+
+	return &profile, nil
+}
+
+func findUserByLogin(ctx context.Context, client *okta.APIClient, login string) (string, bool, error) {
+	user, response, err := client.UserAPI.GetUser(ctx, login).Execute()
+	if err != nil {
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to look up user %s: %w", login, err)
+	}
+
+	return user.GetId(), true, nil
+}
+
+func findGroupByName(ctx context.Context, client *okta.APIClient, name string) (string, bool, error) {
+	groups, _, err := client.GroupAPI.ListGroups(ctx).Q(name).Execute()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to look up group %s: %w", name, err)
+	}
+
+	// Q is a prefix match, so confirm the exact name.
+	for _, group := range groups {
+		if group.Profile != nil && group.Profile.GetName() == name {
+			return group.GetId(), true, nil
+		}
+	}
+
+	return "", false, nil
+}
+
+func failed(item *models.SyncItemResult, err error) *models.SyncItemResult {
+	item.Outcome = models.SyncOutcomeFailed
+	item.Error = err.Error()
+	return item
+}
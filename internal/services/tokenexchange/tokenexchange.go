@@ -0,0 +1,288 @@
+package tokenexchange_service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/internal/secrets"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+const tokenExchangeGrantType = "urn:ietf:params:oauth:grant-type:token-exchange"
+
+var (
+	ErrNotConfigured        = errors.New("the token exchange is not configured")
+	ErrClientNotAllowed     = errors.New("the calling client is not allowed to exchange tokens")
+	ErrSubjectTokenRequired = errors.New("subject_token is required")
+	ErrUnsupportedTokenType = errors.New("only access tokens can be exchanged, for access tokens")
+	ErrAudienceRequired     = errors.New("audience is required")
+	ErrAudienceNotAllowed   = errors.New("the calling client may not request tokens for this audience")
+	ErrScopeNotAllowed      = errors.New("the calling client may not request these scopes")
+	ErrExchangeRejected     = errors.New("the authorization server rejected the exchange")
+)
+
+// Service swaps users' access tokens for narrowly scoped tokens for other
+// audiences (RFC 8693), so internal services act for a user only with what
+// they need. Each calling service, known by the client ID of its own token,
+// may only request the audiences and scopes of its policy. The exchange
+// itself is made by one Okta service app at the issuer, and every exchange,
+// allowed or rejected, is audited.
+type Service struct {
+	log          *zap.SugaredLogger
+	cfg          *config.TokenExchangeConfig
+	issuer       string
+	clientSecret *secrets.Secret
+	client       *http.Client
+	auditSvc     *audit_service.Service
+
+	// tokenURL is discovered from the issuer on first use.
+	mu       sync.Mutex
+	tokenURL string
+}
+
+// New creates the service. clientSecret is nil while the exchange is off.
+func New(
+	log *zap.SugaredLogger, cfg *config.TokenExchangeConfig, issuer string, clientSecret *secrets.Secret,
+	auditSvc *audit_service.Service,
+) *Service {
+	return &Service{
+		log:          log,
+		cfg:          cfg,
+		issuer:       issuer,
+		clientSecret: clientSecret,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		auditSvc:     auditSvc,
+	}
+}
+
+// Exchange checks the request against the policy of clientID, the calling
+// service, and has the issuer exchange the subject token.
+func (s *Service) Exchange(
+	ctx context.Context, clientID string, req *models.TokenExchangeRequest,
+) (*models.TokenExchangeResponse, error) {
+	if s.cfg.ClientID == "" {
+		return nil, ErrNotConfigured
+	}
+
+	scopes, err := s.check(clientID, req)
+	if err != nil {
+		s.record(ctx, clientID, models.AuditActionTokenExchangeRejected, req, map[string]any{"reason": err.Error()})
+		logger.FromContext(ctx, s.log).Infow("Token exchange rejected", zap.Error(err),
+			"clientId", clientID, "audience", req.Audience,
+		)
+		return nil, err
+	}
+
+	tokenURL, err := s.endpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":         {tokenExchangeGrantType},
+		"subject_token":      {req.SubjectToken},
+		"subject_token_type": {models.TokenTypeAccessToken},
+		"audience":           {req.Audience},
+		"scope":              {strings.Join(scopes, " ")},
+	}
+	var result models.TokenExchangeResponse
+	if err := s.post(ctx, tokenURL, form, &result); err != nil {
+		if errors.Is(err, ErrExchangeRejected) {
+			s.record(ctx, clientID, models.AuditActionTokenExchangeRejected, req, map[string]any{"reason": err.Error()})
+		}
+		logger.FromContext(ctx, s.log).Infow("Token exchange failed", zap.Error(err), "clientId", clientID)
+		return nil, err
+	}
+	if result.IssuedTokenType == "" {
+		result.IssuedTokenType = models.TokenTypeAccessToken
+	}
+
+	// The token came straight from the issuer's token endpoint, so its
+	// claims name the user without verifying its signature.
+	userID := tokenUser(result.AccessToken)
+	s.record(ctx, clientID, models.AuditActionTokenExchangeIssued, req, map[string]any{
+		"userId":    userID,
+		"scope":     result.Scope,
+		"expiresIn": result.ExpiresIn,
+	})
+
+	logger.FromContext(ctx, s.log).Infow("Token exchanged",
+		"clientId", clientID, "audience", req.Audience, "userId", userID, "scope", result.Scope,
+	)
+	return &result, nil
+}
+
+// check returns the scopes to request, which are all those of the client's
+// policy when the request names none.
+func (s *Service) check(clientID string, req *models.TokenExchangeRequest) ([]string, error) {
+	policy, ok := s.cfg.Policies[clientID]
+	if !ok || clientID == "" {
+		return nil, ErrClientNotAllowed
+	}
+	if req.SubjectToken == "" {
+		return nil, ErrSubjectTokenRequired
+	}
+	if req.SubjectTokenType != models.TokenTypeAccessToken ||
+		(req.RequestedTokenType != "" && req.RequestedTokenType != models.TokenTypeAccessToken) {
+		return nil, ErrUnsupportedTokenType
+	}
+	if req.Audience == "" {
+		return nil, ErrAudienceRequired
+	}
+	if !slices.Contains(policy.Audiences, req.Audience) {
+		return nil, ErrAudienceNotAllowed
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return policy.Scopes, nil
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(policy.Scopes, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// record audits an exchange. The subject token is a credential, so only its
+// hash is kept, as the resource ID, to correlate with Okta's own logs.
+func (s *Service) record(
+	ctx context.Context, clientID, action string, req *models.TokenExchangeRequest, details map[string]any,
+) {
+	details["audience"] = req.Audience
+	details["requestedScope"] = req.Scope
+
+	var tokenHash string
+	if req.SubjectToken != "" {
+		sum := sha256.Sum256([]byte(req.SubjectToken))
+		tokenHash = hex.EncodeToString(sum[:])
+	}
+
+	actor := clientID
+	if actor == "" {
+		actor = "client:unknown"
+	}
+	s.auditSvc.Record(ctx, &models.AuditEntry{
+		Actor:        actor,
+		Action:       action,
+		ResourceType: models.ResourceTypeTokenExchange,
+		ResourceID:   tokenHash,
+		Details:      details,
+	})
+}
+
+// post sends the exchange to the token endpoint, authenticated as the
+// exchanging app. OAuth error responses are returned as ErrExchangeRejected.
+func (s *Service) post(ctx context.Context, target string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(url.QueryEscape(s.cfg.ClientID), url.QueryEscape(s.clientSecret.Value()))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach the authorization server: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read the authorization server response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		var oauthErr struct {
+			Code        string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if err := json.Unmarshal(body, &oauthErr); err != nil || oauthErr.Code == "" {
+			return fmt.Errorf("authorization server returned status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+		}
+		if oauthErr.Description == "" {
+			return fmt.Errorf("%w: %s", ErrExchangeRejected, oauthErr.Code)
+		}
+		return fmt.Errorf("%w: %s: %s", ErrExchangeRejected, oauthErr.Code, oauthErr.Description)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode the authorization server response: %w", err)
+	}
+	return nil
+}
+
+// endpoint discovers the token endpoint of the issuer once.
+func (s *Service) endpoint(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	tokenURL := s.tokenURL
+	s.mu.Unlock()
+	if tokenURL != "" {
+		return tokenURL, nil
+	}
+
+	discoveryURL := strings.TrimRight(s.issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build discovery request: %w", err)
+	}
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch issuer discovery document: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("issuer discovery returned unexpected status code: %d", res.StatusCode)
+	}
+
+	var document struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&document); err != nil {
+		return "", fmt.Errorf("failed to decode issuer discovery document: %w", err)
+	}
+	if document.TokenEndpoint == "" {
+		return "", errors.New("issuer discovery document has no token endpoint")
+	}
+
+	s.mu.Lock()
+	s.tokenURL = document.TokenEndpoint
+	s.mu.Unlock()
+	return document.TokenEndpoint, nil
+}
+
+// tokenUser returns the Okta user ID of an access token, or its subject when
+// it has none.
+func tokenUser(accessToken string) string {
+	token, err := jwt.ParseString(accessToken)
+	if err != nil {
+		return "unknown"
+	}
+	if uid, ok := token.Get("uid"); ok {
+		if userID, ok := uid.(string); ok && userID != "" {
+			return userID
+		}
+	}
+	return token.Subject()
+}
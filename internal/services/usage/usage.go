@@ -0,0 +1,296 @@
+package usage_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	app_service "github.com/iamBelugaa/iam/internal/services/app"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	job_service "github.com/iamBelugaa/iam/internal/services/job"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
+)
+
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrNoUsers       = errors.New("at least one user ID is required")
+	ErrWindowTooLong = errors.New("the System Log only covers the last 90 days")
+)
+
+// logRetentionDays is how far back Okta keeps System Log events, and so the
+// longest window in which a missing sign-in means the access is unused.
+const logRetentionDays = 90
+
+// signInFilter matches single sign-on into an app.
+const signInFilter = `eventType eq "user.authentication.sso" and outcome.result eq "SUCCESS"`
+
+// Service correlates app assignments with sign-in activity from the System
+// Log to suggest access that can be revoked.
+type Service struct {
+	client   *okta.APIClient
+	log      *zap.SugaredLogger
+	cfg      *config.ReportsConfig
+	appsSvc  *app_service.Service
+	auditSvc *audit_service.Service
+	jobsSvc  *job_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, client *okta.APIClient, cfg *config.ReportsConfig,
+	appsSvc *app_service.Service, auditSvc *audit_service.Service, jobsSvc *job_service.Service,
+) *Service {
+	return &Service{log: log, client: client, cfg: cfg, appsSvc: appsSvc, auditSvc: auditSvc, jobsSvc: jobsSvc}
+}
+
+// GetAppUnusedAccess lists the users assigned to the app before the usage
+// window who have not signed in to it during the window.
+func (s *Service) GetAppUnusedAccess(
+	ctx context.Context, appID string, filter *models.UnusedAccessFilter,
+) ([]*models.UnusedAccess, error) {
+	cutoff, err := s.cutoff(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	app, err := s.appsSvc.GetApp(ctx, appID)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Building unused access suggestions for app", "appId", appID, "days", filter.Days)
+
+	appUsers, response, err := s.client.ApplicationUsersAPI.ListApplicationUsers(ctx, appID).Execute()
+	if err == nil {
+		appUsers, err = pagination.All(appUsers, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get app users from Okta", zap.Error(err), "appId", appID)
+		return nil, fmt.Errorf("failed to get users of app %s from Okta: %w", appID, err)
+	}
+
+	events, err := s.signIns(ctx, fmt.Sprintf(`%s and target.id eq %q`, signInFilter, appID), cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool, len(events))
+	for _, event := range events {
+		used[event.Actor.GetId()] = true
+	}
+
+	result := make([]*models.UnusedAccess, 0)
+	for i := range appUsers {
+		appUser := &appUsers[i]
+		if used[appUser.GetId()] || appUser.Created == nil || appUser.Created.After(cutoff) {
+			continue
+		}
+
+		suggestion := newUnusedAccess(app, appUser, filter.Now)
+		if appUser.Credentials != nil {
+			suggestion.UserName = appUser.Credentials.GetUserName()
+		}
+		result = append(result, suggestion)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Assigned.Before(result[j].Assigned) })
+
+	logger.FromContext(ctx, s.log).Infow("Unused access suggestions built for app", "appId", appID, "count", len(result))
+	return result, nil
+}
+
+// GetUserUnusedAccess lists the apps the user was assigned to before the
+// usage window and has not signed in to during the window.
+func (s *Service) GetUserUnusedAccess(
+	ctx context.Context, userID string, filter *models.UnusedAccessFilter,
+) ([]*models.UnusedAccess, error) {
+	cutoff, err := s.cutoff(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, response, err := s.client.UserAPI.GetUser(ctx, userID).Execute(); err != nil {
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Building unused access suggestions for user", "userId", userID, "days", filter.Days)
+
+	apps, err := s.appsSvc.GetUserApps(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := s.signIns(ctx, fmt.Sprintf(`%s and actor.id eq %q`, signInFilter, userID), cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+	for _, event := range events {
+		for _, target := range event.Target {
+			used[target.GetId()] = true
+		}
+	}
+
+	result := make([]*models.UnusedAccess, 0)
+	for _, app := range apps {
+		if used[app.ID] {
+			continue
+		}
+
+		appUser, response, err := s.client.ApplicationUsersAPI.GetApplicationUser(ctx, app.ID, userID).Execute()
+		if err != nil {
+			logger.FromContext(ctx, s.log).Infow("Failed to get app user from Okta", zap.Error(err), "appId", app.ID, "userId", userID)
+			if okta_client.StatusCode(response) == http.StatusNotFound {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get assignment of app %s from Okta: %w", app.ID, err)
+		}
+		if appUser.Created == nil || appUser.Created.After(cutoff) {
+			continue
+		}
+
+		result = append(result, newUnusedAccess(app, appUser, filter.Now))
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Assigned.Before(result[j].Assigned) })
+
+	logger.FromContext(ctx, s.log).Infow("Unused access suggestions built for user", "userId", userID, "count", len(result))
+	return result, nil
+}
+
+// RevokeUnusedAccess starts a job that unassigns the users from the app. The
+// job first checks the suggestions again, so a user who signed in since they
+// were listed, or whose access comes from a group, keeps the app.
+func (s *Service) RevokeUnusedAccess(
+	ctx context.Context, actor string, req *models.RevokeUnusedAccessRequest,
+) (*models.Job, error) {
+	if len(req.UserIDs) == 0 {
+		return nil, ErrNoUsers
+	}
+
+	filter := &models.UnusedAccessFilter{Days: req.Days}
+	if _, err := s.cutoff(filter); err != nil {
+		return nil, err
+	}
+	if _, err := s.appsSvc.GetApp(ctx, req.AppID); err != nil {
+		return nil, err
+	}
+
+	tracker := s.jobsSvc.Create(
+		models.JobTypeUnusedAccessRevocation, models.ResourceTypeApp, req.AppID,
+		[]string{models.RevocationStepVerify, models.RevocationStepUnassign},
+	)
+
+	logger.FromContext(ctx, s.log).Infow("Revoking unused app access",
+		"appId", req.AppID, "userCount", len(req.UserIDs), "jobId", tracker.JobID(),
+	)
+
+	s.jobsSvc.Go(tracker, func(ctx context.Context) error {
+		return s.revoke(ctx, tracker, actor, req, filter)
+	})
+
+	return tracker.Job(), nil
+}
+
+func (s *Service) revoke(
+	ctx context.Context, tracker *job_service.Tracker, actor string,
+	req *models.RevokeUnusedAccessRequest, filter *models.UnusedAccessFilter,
+) error {
+	var revocable []string
+
+	err := tracker.Step(models.RevocationStepVerify, func() (string, error) {
+		unused, err := s.GetAppUnusedAccess(ctx, req.AppID, filter)
+		if err != nil {
+			return "", err
+		}
+
+		suggested := make(map[string]bool, len(unused))
+		for _, access := range unused {
+			suggested[access.UserID] = access.Revocable
+		}
+
+		for _, userID := range req.UserIDs {
+			if suggested[userID] {
+				revocable = append(revocable, userID)
+			}
+		}
+		return fmt.Sprintf("%d of %d assignments are still unused and directly assigned",
+			len(revocable), len(req.UserIDs)), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tracker.Step(models.RevocationStepUnassign, func() (string, error) {
+		for _, userID := range revocable {
+			response, err := s.client.ApplicationUsersAPI.UnassignUserFromApplication(ctx, req.AppID, userID).Execute()
+			if err != nil && okta_client.StatusCode(response) != http.StatusNotFound {
+				logger.FromContext(ctx, s.log).Infow("Failed to unassign user from app in Okta", zap.Error(err),
+					"appId", req.AppID, "userId", userID,
+				)
+				return "", fmt.Errorf("failed to unassign user %s from app in Okta: %w", userID, err)
+			}
+
+			s.auditSvc.Record(ctx, &models.AuditEntry{
+				Actor:        actor,
+				Action:       models.AuditActionAppAccessRevoked,
+				ResourceType: models.ResourceTypeApp,
+				ResourceID:   req.AppID,
+				Details:      map[string]any{"userId": userID, "jobId": tracker.JobID()},
+			})
+		}
+		return fmt.Sprintf("Unassigned %d users", len(revocable)), nil
+	})
+}
+
+// cutoff fills in the filter defaults and returns the start of the window.
+func (s *Service) cutoff(filter *models.UnusedAccessFilter) (time.Time, error) {
+	if filter.Days <= 0 {
+		filter.Days = s.cfg.UnusedAccessDays
+	}
+	if filter.Days > logRetentionDays {
+		return time.Time{}, ErrWindowTooLong
+	}
+	if filter.Now.IsZero() {
+		filter.Now = time.Now()
+	}
+	return filter.Now.AddDate(0, 0, -filter.Days), nil
+}
+
+func (s *Service) signIns(ctx context.Context, filter string, since time.Time) ([]okta.LogEvent, error) {
+	events, response, err := s.client.SystemLogAPI.ListLogEvents(ctx).Since(since).Filter(filter).Execute()
+	if err == nil {
+		events, err = pagination.All(events, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to list log events from Okta", zap.Error(err), "filter", filter)
+		return nil, fmt.Errorf("failed to list sign-in events from Okta: %w", err)
+	}
+	return events, nil
+}
+
+func newUnusedAccess(app *models.App, appUser *okta.AppUser, now time.Time) *models.UnusedAccess {
+	scope := appUser.GetScope()
+	return &models.UnusedAccess{
+		AppID:        app.ID,
+		AppLabel:     app.Label,
+		UserID:       appUser.GetId(),
+		Scope:        scope,
+		Assigned:     *appUser.Created,
+		DaysAssigned: int(now.Sub(*appUser.Created) / (24 * time.Hour)),
+		Revocable:    scope == models.AppAssignmentScopeUser,
+	}
+}
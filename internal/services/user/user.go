@@ -2,25 +2,49 @@ package user_service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 
 	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	okta_client "github.com/iamBelugaa/iam/pkg/okta"
+	"github.com/iamBelugaa/iam/pkg/pagination"
 	"github.com/okta/okta-sdk-golang/v5/okta"
 	"go.uber.org/zap"
 )
 
+var (
+	ErrUserNotFound  = errors.New("user not found")
+	ErrInvalidSearch = errors.New("invalid user search expression")
+	// ErrStatusNotAllowed is returned when Okta refuses a lifecycle operation
+	// because of the user's status, such as unlocking a user who is not
+	// locked out.
+	ErrStatusNotAllowed = errors.New("the operation is not allowed in the user's current status")
+)
+
 type Service struct {
 	client *okta.APIClient
 	log    *zap.SugaredLogger
+	// hooks run around creates, updates and deletes. It is nil when the org
+	// runs none.
+	hooks *hooks.Registry
 }
 
-func New(log *zap.SugaredLogger, client *okta.APIClient) *Service {
-	return &Service{log: log, client: client}
+func New(log *zap.SugaredLogger, client *okta.APIClient, hooks *hooks.Registry) *Service {
+	return &Service{log: log, client: client, hooks: hooks}
 }
 
 func (s *Service) CreateUser(ctx context.Context, req *models.CreateUserRequest) (*models.User, error) {
 	var profile okta.UserProfile
-	s.log.Infow("Creating user in Okta", "email", req.Email, "login", req.Login)
+	logger.FromContext(ctx, s.log).Infow("Creating user in Okta", "email", req.Email, "login", req.Login)
+
+	event := &hooks.Event{Operation: hooks.CreateUser, Input: req}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return nil, err
+	}
 
 	profile.SetEmail(req.Email)
 	profile.SetLogin(req.Login)
@@ -42,34 +66,39 @@ func (s *Service) CreateUser(ctx context.Context, req *models.CreateUserRequest)
 
 	user, response, err := s.client.UserAPI.CreateUser(ctx).Body(createUserRequest).Activate(req.Activate).Execute()
 	if err != nil {
-		s.log.Infow("Failed to create user in Okta", zap.Error(err),
-			"email", req.Email, "statusCode", response.StatusCode,
+		logger.FromContext(ctx, s.log).Infow("Failed to create user in Okta", zap.Error(err),
+			"email", req.Email, "statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to create user in Okta: %w", err)
 	}
 
-	s.log.Infow("User created successfully in Okta",
+	logger.FromContext(ctx, s.log).Infow("User created successfully in Okta",
 		"userId", *user.Id,
 		"email", req.Email,
-		"statusCode", response.StatusCode,
+		"statusCode", okta_client.StatusCode(response),
 	)
 
-	return models.ConvertOktaUserToModel(user), nil
+	created := models.ConvertOktaUserToModel(user)
+	s.runAfter(ctx, event, created.ID, created)
+	return created, nil
 }
 
 func (s *Service) GetUser(ctx context.Context, userID string) (*models.User, error) {
-	s.log.Infow("Getting user from Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Getting user from Okta", "userId", userID)
 
 	user, response, err := s.client.UserAPI.GetUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get user from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get user from Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrUserNotFound
+		}
 		return nil, fmt.Errorf("failed to get user from Okta: %w", err)
 	}
 
-	s.log.Infow("User retrieved successfully from Okta",
+	logger.FromContext(ctx, s.log).Infow("User retrieved successfully from Okta",
 		zap.String("userId", userID))
 
 	return models.ConvertOktaUserToModel(&okta.User{
@@ -91,12 +120,44 @@ func (s *Service) GetUser(ctx context.Context, userID string) (*models.User, err
 	}), nil
 }
 
+// GetUserProfile returns every attribute of the user's Okta profile, base
+// and custom alike, under the names Okta gives them.
+func (s *Service) GetUserProfile(ctx context.Context, userID string) (map[string]any, error) {
+	logger.FromContext(ctx, s.log).Infow("Getting user profile from Okta", "userId", userID)
+
+	user, response, err := s.client.UserAPI.GetUser(ctx, userID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to get user profile from Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user profile from Okta: %w", err)
+	}
+
+	profile := make(map[string]any)
+	if user.Profile == nil {
+		return profile, nil
+	}
+
+	data, err := json.Marshal(user.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode user profile: %w", err)
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return nil, fmt.Errorf("failed to decode user profile: %w", err)
+	}
+	return profile, nil
+}
+
 func (s *Service) GetUsers(ctx context.Context) ([]*models.User, error) {
-	s.log.Infow("Getting users from Okta")
+	logger.FromContext(ctx, s.log).Infow("Getting users from Okta")
 
 	users, _, err := s.client.UserAPI.ListUsers(ctx).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get users from Okta", zap.Error(err))
+		logger.FromContext(ctx, s.log).Infow("Failed to get users from Okta", zap.Error(err))
 		return nil, fmt.Errorf("failed to get users from Okta: %w", err)
 	}
 
@@ -105,12 +166,78 @@ func (s *Service) GetUsers(ctx context.Context) ([]*models.User, error) {
 		result[i] = models.ConvertOktaUserToModel(&users[i])
 	}
 
-	s.log.Infow("Users retrieved successfully from Okta", "count", len(result))
+	logger.FromContext(ctx, s.log).Infow("Users retrieved successfully from Okta", "count", len(result))
 	return result, nil
 }
 
+// FindUsersByEmail lists the users whose primary email is email.
+func (s *Service) FindUsersByEmail(ctx context.Context, email string) ([]*models.User, error) {
+	return s.SearchUsers(ctx, fmt.Sprintf("profile.email eq %q", email))
+}
+
+// SearchUsers lists every user matching an Okta search expression, such as
+// profile.department eq "Sales".
+func (s *Service) SearchUsers(ctx context.Context, expression string) ([]*models.User, error) {
+	logger.FromContext(ctx, s.log).Infow("Searching Okta users", "search", expression)
+
+	users, response, err := s.client.UserAPI.ListUsers(ctx).Search(expression).Execute()
+	if err == nil {
+		users, err = pagination.All(users, response)
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to search Okta users", zap.Error(err),
+			"search", expression,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		if okta_client.StatusCode(response) == http.StatusBadRequest {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSearch, expression)
+		}
+		return nil, fmt.Errorf("failed to search users in Okta: %w", err)
+	}
+
+	result := make([]*models.User, len(users))
+	for i := range users {
+		result[i] = models.ConvertOktaUserToModel(&users[i])
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Users searched successfully in Okta", "search", expression, "count", len(result))
+	return result, nil
+}
+
+// StreamUsers passes every user to emit, fetching pages from Okta only as
+// the previous page has been emitted.
+func (s *Service) StreamUsers(ctx context.Context, emit func(*models.User) error) error {
+	logger.FromContext(ctx, s.log).Infow("Streaming users from Okta")
+
+	var count int
+	users, response, err := s.client.UserAPI.ListUsers(ctx).Execute()
+	if err == nil {
+		err = pagination.Each(users, response, func(page []okta.User) error {
+			for i := range page {
+				if err := emit(models.ConvertOktaUserToModel(&page[i])); err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+	}
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to stream users from Okta", zap.Error(err), "emittedCount", count)
+		return fmt.Errorf("failed to stream users from Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Users streamed successfully from Okta", "count", count)
+	return nil
+}
+
 func (s *Service) UpdateUser(ctx context.Context, userID string, req *models.UpdateUserRequest) (*models.User, error) {
-	s.log.Info("Updating user in Okta", zap.String("userId", userID))
+	logger.FromContext(ctx, s.log).Info("Updating user in Okta", zap.String("userId", userID))
+
+	event := &hooks.Event{Operation: hooks.UpdateUser, ResourceID: userID, Input: req}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return nil, err
+	}
 
 	var profile okta.UserProfile
 	updateNeeded := false
@@ -138,77 +265,99 @@ func (s *Service) UpdateUser(ctx context.Context, userID string, req *models.Upd
 		UpdateUser(ctx, userID).User(okta.UpdateUserRequest{Profile: &profile}).Execute()
 
 	if err != nil {
-		s.log.Infow("Failed to update user in Okta",
+		logger.FromContext(ctx, s.log).Infow("Failed to update user in Okta",
 			zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to update user in Okta: %w", err)
 	}
 
-	s.log.Info("User updated successfully in Okta", "userId", userID)
-	return models.ConvertOktaUserToModel(user), nil
+	logger.FromContext(ctx, s.log).Info("User updated successfully in Okta", "userId", userID)
+
+	updated := models.ConvertOktaUserToModel(user)
+	s.runAfter(ctx, event, userID, updated)
+	return updated, nil
 }
 
 func (s *Service) DeleteUser(ctx context.Context, userID string) error {
-	s.log.Info("Deleting user in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Info("Deleting user in Okta", "userId", userID)
+
+	event := &hooks.Event{Operation: hooks.DeleteUser, ResourceID: userID}
+	if err := s.hooks.RunBefore(ctx, event); err != nil {
+		return err
+	}
 
 	response, err := s.client.UserAPI.DeactivateUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to deactivate user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to deactivate user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to deactivate user in Okta: %w", err)
 	}
 
 	response, err = s.client.UserAPI.DeleteUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to delete user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to delete user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to delete user in Okta: %w", err)
 	}
 
-	s.log.Info("User deleted successfully in Okta", zap.String("userId", userID))
+	logger.FromContext(ctx, s.log).Info("User deleted successfully in Okta", zap.String("userId", userID))
+	s.runAfter(ctx, event, userID, nil)
 	return nil
 }
 
+// runAfter runs the after hooks of event once its operation succeeded. Their
+// errors are only logged, as the change has been made.
+func (s *Service) runAfter(ctx context.Context, event *hooks.Event, resourceID string, result any) {
+	event.ResourceID = resourceID
+	event.Result = result
+	if err := s.hooks.RunAfter(ctx, event); err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to run after hooks", zap.Error(err),
+			"operation", event.Operation,
+			"resourceId", resourceID,
+		)
+	}
+}
+
 func (s *Service) ActivateUser(ctx context.Context, userID string) error {
-	s.log.Info("Activating user in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Info("Activating user in Okta", "userId", userID)
 
 	_, response, err := s.client.UserAPI.ActivateUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to activate user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to activate user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to activate user in Okta: %w", err)
 	}
 
-	s.log.Info("User activated successfully in Okta", zap.String("userId", userID))
+	logger.FromContext(ctx, s.log).Info("User activated successfully in Okta", zap.String("userId", userID))
 	return nil
 }
 
 func (s *Service) DeactivateUser(ctx context.Context, userID string) error {
-	s.log.Info("Deactivating user in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Info("Deactivating user in Okta", "userId", userID)
 
 	response, err := s.client.UserAPI.DeactivateUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to deactivate user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to deactivate user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to deactivate user in Okta: %w", err)
 	}
 
-	s.log.Info("User deactivated successfully in Okta", zap.String("userId", userID))
+	logger.FromContext(ctx, s.log).Info("User deactivated successfully in Okta", zap.String("userId", userID))
 	return nil
 }
 
 func (s *Service) SetUserPassword(ctx context.Context, userID, newPassword string) error {
-	s.log.Infow("Setting user password in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Setting user password in Okta", "userId", userID)
 
 	changePasswordRequest := okta.ChangePasswordRequest{
 		NewPassword: &okta.PasswordCredential{
@@ -219,41 +368,132 @@ func (s *Service) SetUserPassword(ctx context.Context, userID, newPassword strin
 	_, response, err := s.client.UserAPI.
 		ChangePassword(ctx, userID).ChangePasswordRequest(changePasswordRequest).Execute()
 	if err != nil {
-		s.log.Infow("Failed to set user password in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to set user password in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to set user password in Okta: %w", err)
 	}
 
-	s.log.Infow("User password set successfully in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("User password set successfully in Okta", "userId", userID)
 	return nil
 }
 
 func (s *Service) ExpireUserPassword(ctx context.Context, userID string) error {
-	s.log.Infow("Expiring user password in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Expiring user password in Okta", "userId", userID)
 
 	_, response, err := s.client.UserAPI.ExpirePassword(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to expire user password in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to expire user password in Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		return lifecycleError(response, fmt.Errorf("failed to expire user password in Okta: %w", err))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User password expired successfully in Okta", "userId", userID)
+	return nil
+}
+
+// ExpireUserPasswordWithTempPassword expires the user's password and returns
+// the one-time password Okta generates, which the user must change at their
+// next sign-in.
+func (s *Service) ExpireUserPasswordWithTempPassword(ctx context.Context, userID string) (string, error) {
+	logger.FromContext(ctx, s.log).Infow("Expiring user password with a temporary password in Okta", "userId", userID)
+
+	tempPassword, response, err := s.client.UserAPI.ExpirePasswordAndGetTemporaryPassword(ctx, userID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to issue temporary password in Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		return "", lifecycleError(response, fmt.Errorf("failed to issue temporary password in Okta: %w", err))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Temporary password issued successfully in Okta", "userId", userID)
+	return tempPassword.GetTempPassword(), nil
+}
+
+// ResetUserPassword has Okta email the user a link to choose a new password.
+func (s *Service) ResetUserPassword(ctx context.Context, userID string) error {
+	logger.FromContext(ctx, s.log).Infow("Resetting user password in Okta", "userId", userID)
+
+	_, response, err := s.client.UserAPI.GenerateResetPasswordToken(ctx, userID).SendEmail(true).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to reset user password in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
-		return fmt.Errorf("failed to expire user password in Okta: %w", err)
+		return lifecycleError(response, fmt.Errorf("failed to reset user password in Okta: %w", err))
 	}
 
-	s.log.Infow("User password expired successfully in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("User password reset email sent successfully by Okta", "userId", userID)
+	return nil
+}
+
+// RevokeUserSessions signs the user out of every Okta session. With
+// oauthTokens, the access and refresh tokens Okta issued them are revoked too.
+func (s *Service) RevokeUserSessions(ctx context.Context, userID string, oauthTokens bool) error {
+	logger.FromContext(ctx, s.log).Infow("Revoking user sessions in Okta", "userId", userID, "oauthTokens", oauthTokens)
+
+	response, err := s.client.UserAPI.RevokeUserSessions(ctx, userID).OauthTokens(oauthTokens).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to revoke user sessions in Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		if okta_client.StatusCode(response) == http.StatusNotFound {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to revoke user sessions in Okta: %w", err)
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User sessions revoked successfully in Okta", "userId", userID)
+	return nil
+}
+
+// ResetUserFactors unenrolls every factor of the user, who enrolls again at
+// their next sign-in.
+func (s *Service) ResetUserFactors(ctx context.Context, userID string) error {
+	logger.FromContext(ctx, s.log).Infow("Resetting user factors in Okta", "userId", userID)
+
+	response, err := s.client.UserAPI.ResetFactors(ctx, userID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to reset user factors in Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		return lifecycleError(response, fmt.Errorf("failed to reset user factors in Okta: %w", err))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User factors reset successfully in Okta", "userId", userID)
+	return nil
+}
+
+func (s *Service) UnlockUser(ctx context.Context, userID string) error {
+	logger.FromContext(ctx, s.log).Infow("Unlocking user in Okta", "userId", userID)
+
+	response, err := s.client.UserAPI.UnlockUser(ctx, userID).Execute()
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Failed to unlock user in Okta", zap.Error(err),
+			"userId", userID,
+			"statusCode", okta_client.StatusCode(response),
+		)
+		return lifecycleError(response, fmt.Errorf("failed to unlock user in Okta: %w", err))
+	}
+
+	logger.FromContext(ctx, s.log).Infow("User unlocked successfully in Okta", "userId", userID)
 	return nil
 }
 
 func (s *Service) GetUserGroups(ctx context.Context, userID string) ([]*models.Group, error) {
-	s.log.Infow("Getting user groups from Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Getting user groups from Okta", "userId", userID)
 
 	groups, response, err := s.client.UserAPI.ListUserGroups(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to get user groups from Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to get user groups from Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return nil, fmt.Errorf("failed to get user groups from Okta: %w", err)
 	}
@@ -263,38 +503,60 @@ func (s *Service) GetUserGroups(ctx context.Context, userID string) ([]*models.G
 		result[i] = models.ConvertOktaGroupToModel(&groups[i])
 	}
 
-	s.log.Infow("User groups retrieved successfully from Okta", "userId", userID, "groupCount", len(result))
+	logger.FromContext(ctx, s.log).Infow("User groups retrieved successfully from Okta", "userId", userID, "groupCount", len(result))
 	return result, nil
 }
 
 func (s *Service) SuspendUser(ctx context.Context, userID string) error {
-	s.log.Infow("Suspending user in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Suspending user in Okta", "userId", userID)
 
 	response, err := s.client.UserAPI.SuspendUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to suspend user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to suspend user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to suspend user in Okta: %w", err)
 	}
 
-	s.log.Infow("User suspended successfully in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("User suspended successfully in Okta", "userId", userID)
 	return nil
 }
 
 func (s *Service) UnsuspendUser(ctx context.Context, userID string) error {
-	s.log.Infow("Unsuspending user in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("Unsuspending user in Okta", "userId", userID)
 
 	response, err := s.client.UserAPI.UnsuspendUser(ctx, userID).Execute()
 	if err != nil {
-		s.log.Infow("Failed to unsuspend user in Okta", zap.Error(err),
+		logger.FromContext(ctx, s.log).Infow("Failed to unsuspend user in Okta", zap.Error(err),
 			"userId", userID,
-			"statusCode", response.StatusCode,
+			"statusCode", okta_client.StatusCode(response),
 		)
 		return fmt.Errorf("failed to unsuspend user in Okta: %w", err)
 	}
 
-	s.log.Infow("User unsuspended successfully in Okta", "userId", userID)
+	logger.FromContext(ctx, s.log).Infow("User unsuspended successfully in Okta", "userId", userID)
 	return nil
 }
+
+// statusNotAllowedCode is the Okta error code of a lifecycle operation the
+// user's status does not allow. Okta answers it with a 403, which otherwise
+// means the API token lacks a permission.
+const statusNotAllowedCode = "E0000038"
+
+// lifecycleError returns ErrUserNotFound or ErrStatusNotAllowed when Okta's
+// response says so, and err otherwise.
+func lifecycleError(response *okta.APIResponse, err error) error {
+	switch okta_client.StatusCode(response) {
+	case http.StatusNotFound:
+		return ErrUserNotFound
+	case http.StatusForbidden:
+		var apiErr *okta.GenericOpenAPIError
+		if errors.As(err, &apiErr) {
+			if model, ok := apiErr.Model().(okta.Error); ok && model.GetErrorCode() == statusNotAllowedCode {
+				return ErrStatusNotAllowed
+			}
+		}
+	}
+	return err
+}
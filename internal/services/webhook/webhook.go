@@ -0,0 +1,359 @@
+package webhook_service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/pkg/logger"
+	"github.com/iamBelugaa/iam/pkg/objectstore"
+)
+
+const (
+	SignatureHeader = "X-IAM-Signature"
+	TimestampHeader = "X-IAM-Timestamp"
+	EventTypeHeader = "X-IAM-Event"
+
+	// deliveryTimeout bounds a whole delivery, including connecting.
+	deliveryTimeout = 10 * time.Second
+
+	// stateKey is the object holding the subscribers.
+	stateKey = "state.json"
+)
+
+var (
+	ErrSubscriberNotFound = errors.New("webhook subscriber not found")
+	errPrivateAddress     = errors.New("webhook receivers on loopback, private or link-local addresses are not allowed")
+)
+
+type Service struct {
+	log    *zap.SugaredLogger
+	client *http.Client
+	// store keeps the subscribers and their signing secrets across
+	// restarts, stored as one object on every change. It is nil when they
+	// are only kept in memory.
+	store       objectstore.Store
+	mu          sync.RWMutex
+	subscribers map[string]*models.WebhookSubscriber
+	// inFlight counts the published deliveries still running.
+	inFlight atomic.Int64
+}
+
+// state is the subscribers, keyed by ID.
+type state struct {
+	Subscribers map[string]*models.WebhookSubscriber `json:"subscribers"`
+}
+
+// New returns the service with the subscribers read from store, which may be
+// nil.
+func New(log *zap.SugaredLogger, store objectstore.Store) (*Service, error) {
+	s := &Service{
+		log:         log,
+		client:      newClient(),
+		store:       store,
+		subscribers: make(map[string]*models.WebhookSubscriber),
+	}
+
+	if store == nil {
+		return s, nil
+	}
+	object, err := store.Get(context.Background(), stateKey)
+	if errors.Is(err, objectstore.ErrNotFound) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook subscribers: %w", err)
+	}
+
+	var stored state
+	if err := json.Unmarshal(object.Data, &stored); err != nil {
+		return nil, fmt.Errorf("failed to decode webhook subscribers: %w", err)
+	}
+	for id, subscriber := range stored.Subscribers {
+		s.subscribers[id] = subscriber
+	}
+	return s, nil
+}
+
+// save stores the subscribers. Callers hold mu.
+func (s *Service) save(ctx context.Context) error {
+	if s.store == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(state{Subscribers: s.subscribers})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook subscribers: %w", err)
+	}
+	if err := s.store.Put(ctx, stateKey, "application/json", data); err != nil {
+		return fmt.Errorf("failed to store webhook subscribers: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) CreateSubscriber(ctx context.Context, req *models.CreateWebhookSubscriberRequest) (*models.WebhookSubscriber, error) {
+	logger.FromContext(ctx, s.log).Infow("Registering webhook subscriber", "url", req.URL)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	subscriber := &models.WebhookSubscriber{
+		ID:          uuid.NewString(),
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      req.Events,
+		Secret:      hex.EncodeToString(secret),
+		Created:     time.Now().UTC(),
+	}
+
+	s.mu.Lock()
+	s.subscribers[subscriber.ID] = subscriber
+	if err := s.save(ctx); err != nil {
+		delete(s.subscribers, subscriber.ID)
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.mu.Unlock()
+
+	logger.FromContext(ctx, s.log).Infow("Webhook subscriber registered successfully", "subscriberId", subscriber.ID, "url", req.URL)
+
+	created := *subscriber
+	return &created, nil
+}
+
+func (s *Service) GetSubscribers(ctx context.Context) []*models.WebhookSubscriber {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*models.WebhookSubscriber, 0, len(s.subscribers))
+	for _, subscriber := range s.subscribers {
+		result = append(result, redact(subscriber))
+	}
+
+	return result
+}
+
+func (s *Service) GetSubscriber(ctx context.Context, subscriberID string) (*models.WebhookSubscriber, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	subscriber, ok := s.subscribers[subscriberID]
+	if !ok {
+		return nil, ErrSubscriberNotFound
+	}
+
+	return redact(subscriber), nil
+}
+
+func (s *Service) DeleteSubscriber(ctx context.Context, subscriberID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subscriber, ok := s.subscribers[subscriberID]
+	if !ok {
+		return ErrSubscriberNotFound
+	}
+
+	delete(s.subscribers, subscriberID)
+	if err := s.save(ctx); err != nil {
+		s.subscribers[subscriberID] = subscriber
+		return err
+	}
+	logger.FromContext(ctx, s.log).Infow("Webhook subscriber deleted successfully", "subscriberId", subscriberID)
+	return nil
+}
+
+// TestSubscriber delivers a signed sample event to the subscriber and reports
+// how it responded. A non-2xx response is reported, not returned as an error.
+func (s *Service) TestSubscriber(ctx context.Context, subscriberID string) (*models.WebhookDeliveryResult, error) {
+	s.mu.RLock()
+	subscriber, ok := s.subscribers[subscriberID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrSubscriberNotFound
+	}
+
+	event := &models.WebhookEvent{
+		ID:         uuid.NewString(),
+		Type:       models.WebhookEventTest,
+		OccurredAt: time.Now().UTC(),
+		Data: map[string]any{
+			"message":      "This is a test event from the IAM platform",
+			"subscriberId": subscriber.ID,
+		},
+	}
+
+	logger.FromContext(ctx, s.log).Infow("Sending test event to webhook subscriber", "subscriberId", subscriber.ID, "eventId", event.ID)
+	return s.deliver(ctx, subscriber, event)
+}
+
+// Publish delivers an event of eventType to every subscriber registered for
+// it. Deliveries run in the background so callers are not held up by slow
+// receivers; failures are only logged.
+func (s *Service) Publish(ctx context.Context, eventType string, data any) {
+	event := &models.WebhookEvent{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	s.mu.RLock()
+	var subscribers []*models.WebhookSubscriber
+	for _, subscriber := range s.subscribers {
+		if slices.Contains(subscriber.Events, eventType) {
+			subscribers = append(subscribers, subscriber)
+		}
+	}
+	s.mu.RUnlock()
+
+	logger.FromContext(ctx, s.log).Infow("Publishing webhook event", "eventId", event.ID, "type", eventType, "subscriberCount", len(subscribers))
+
+	ctx = context.WithoutCancel(ctx)
+	for _, subscriber := range subscribers {
+		s.inFlight.Add(1)
+		go func() {
+			defer s.inFlight.Add(-1)
+			if _, err := s.deliver(ctx, subscriber, event); err != nil {
+				logger.FromContext(ctx, s.log).Infow("Failed to publish webhook event", zap.Error(err), "subscriberId", subscriber.ID, "eventId", event.ID)
+			}
+		}()
+	}
+}
+
+// Backlog counts the published deliveries still running.
+func (s *Service) Backlog() int {
+	return int(s.inFlight.Load())
+}
+
+func (s *Service) deliver(ctx context.Context, subscriber *models.WebhookSubscriber, event *models.WebhookEvent) (*models.WebhookDeliveryResult, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscriber.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, event.Type)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(subscriber.Secret, timestamp, body))
+
+	result := &models.WebhookDeliveryResult{EventID: event.ID}
+	start := time.Now()
+
+	resp, err := s.client.Do(req)
+	result.DurationMs = time.Since(start).Milliseconds()
+
+	if err != nil {
+		logger.FromContext(ctx, s.log).Infow("Webhook delivery failed", zap.Error(err), "subscriberId", subscriber.ID, "eventId", event.ID)
+		result.Error = err.Error()
+		return result, nil
+	}
+	defer resp.Body.Close()
+	// The body is not reported; a little is read so the connection can be
+	// reused.
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	result.StatusCode = resp.StatusCode
+	result.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+
+	logger.FromContext(ctx, s.log).Infow("Webhook delivered",
+		"subscriberId", subscriber.ID,
+		"eventId", event.ID,
+		"statusCode", resp.StatusCode,
+		"durationMs", result.DurationMs,
+	)
+	return result, nil
+}
+
+// newClient returns the client deliveries are made with. Subscriber URLs are
+// chosen by callers, so the client only connects to public addresses,
+// checked once the host name is resolved so DNS cannot point it elsewhere, and
+// does not follow redirects: a redirect is reported as the subscriber's
+// response. Proxies are not used, as the address checked would be the proxy's.
+func newClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("invalid webhook address %q: %w", address, err)
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: deliveryTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: deliveryTimeout,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which is
+// not routable on the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether addr may receive webhooks: it is not loopback,
+// private, link-local (which holds cloud metadata services such as
+// 169.254.169.254), multicast or unspecified.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !addr.IsLoopback() &&
+		!addr.IsLinkLocalUnicast() && !sharedAddressSpace.Contains(addr)
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of "<timestamp>.<body>" so that
+// receivers can verify both the payload and its freshness.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func redact(subscriber *models.WebhookSubscriber) *models.WebhookSubscriber {
+	copied := *subscriber
+	copied.Secret = ""
+	return &copied
+}
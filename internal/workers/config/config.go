@@ -0,0 +1,93 @@
+package config_worker
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker reloads the configuration when its file changes and hands it to
+// apply, which updates the settings that can change without a restart. A
+// file that fails to load or validate is ignored until it is fixed.
+type Worker struct {
+	log   *zap.SugaredLogger
+	apply func(cfg *config.Config)
+	// started is the configuration the server started with, which settings
+	// that need a restart are compared against; applied is the last one
+	// handed to apply.
+	started *config.Config
+	applied *config.Config
+
+	modTime time.Time
+	size    int64
+}
+
+func New(log *zap.SugaredLogger, cfg *config.Config, apply func(cfg *config.Config)) *Worker {
+	return &Worker{log: log, apply: apply, started: cfg, applied: cfg}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.changed()
+
+	w.log.Infow("Config reload worker started", "file", w.started.File.Path, "interval", w.started.File.ReloadInterval)
+	scheduler.Every(ctx, w.started.File.ReloadInterval, w.reload)
+	w.log.Infow("Config reload worker stopped")
+}
+
+func (w *Worker) reload(context.Context) {
+	if !w.changed() {
+		return
+	}
+
+	next, err := config.Load()
+	if err != nil {
+		w.log.Infow("Failed to reload config; keeping the previous settings", zap.Error(err))
+		return
+	}
+
+	var applied, ignored []string
+	for _, key := range w.applied.Changed(next) {
+		if config.Reloadable(key) {
+			applied = append(applied, key)
+		}
+	}
+	for _, key := range w.started.Changed(next) {
+		if !config.Reloadable(key) {
+			ignored = append(ignored, key)
+		}
+	}
+
+	if len(ignored) > 0 {
+		w.log.Infow("Config settings changed that need a restart to take effect", "settings", ignored)
+	}
+	if len(applied) == 0 {
+		return
+	}
+
+	w.apply(next)
+	w.applied = next
+	w.log.Infow("Config reloaded", "settings", applied)
+}
+
+// changed reports whether the file was modified since the last call. Size is
+// compared too, as some filesystems only record modification times to the
+// second.
+func (w *Worker) changed() bool {
+	info, err := os.Stat(w.started.File.Path)
+	if err != nil {
+		w.log.Infow("Failed to stat config file", zap.Error(err), "file", w.started.File.Path)
+		return false
+	}
+
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+	return true
+}
@@ -0,0 +1,35 @@
+package dashboard_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	dashboard_service "github.com/iamBelugaa/iam/internal/services/dashboard"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker keeps the dashboard's figures up to date.
+type Worker struct {
+	log          *zap.SugaredLogger
+	interval     time.Duration
+	dashboardSvc *dashboard_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, dashboardSvc *dashboard_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, dashboardSvc: dashboardSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Dashboard worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.update)
+	w.log.Infow("Dashboard worker stopped")
+}
+
+func (w *Worker) update(ctx context.Context) {
+	if err := w.dashboardSvc.Update(ctx); err != nil {
+		w.log.Infow("Failed to update the dashboard", zap.Error(err))
+	}
+}
@@ -0,0 +1,36 @@
+package directory_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	directory_service "github.com/iamBelugaa/iam/internal/services/directory"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker builds the directory index at startup and rebuilds it periodically.
+type Worker struct {
+	log          *zap.SugaredLogger
+	interval     time.Duration
+	directorySvc *directory_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, directorySvc *directory_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, directorySvc: directorySvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Directory index worker started", "interval", w.interval)
+	w.refresh(ctx)
+	scheduler.Every(ctx, w.interval, w.refresh)
+	w.log.Infow("Directory index worker stopped")
+}
+
+func (w *Worker) refresh(ctx context.Context) {
+	if err := w.directorySvc.Refresh(ctx); err != nil {
+		w.log.Infow("Failed to refresh directory index; serving the previous index", zap.Error(err))
+	}
+}
@@ -0,0 +1,35 @@
+package drift_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	drift_service "github.com/iamBelugaa/iam/internal/services/drift"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker checks the System Log for changes made around this service.
+type Worker struct {
+	log      *zap.SugaredLogger
+	interval time.Duration
+	driftSvc *drift_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, driftSvc *drift_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, driftSvc: driftSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Drift detection worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.detect)
+	w.log.Infow("Drift detection worker stopped")
+}
+
+func (w *Worker) detect(ctx context.Context) {
+	if err := w.driftSvc.Detect(ctx); err != nil {
+		w.log.Infow("Failed to check Okta for out-of-band changes", zap.Error(err))
+	}
+}
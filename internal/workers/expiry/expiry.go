@@ -0,0 +1,68 @@
+package expiry_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+const actor = "system:membership-expiry"
+
+// Worker periodically removes time-bound group memberships that have expired.
+type Worker struct {
+	log       *zap.SugaredLogger
+	interval  time.Duration
+	groupsSvc *group_service.Service
+	auditSvc  *audit_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, interval time.Duration,
+	groupsSvc *group_service.Service, auditSvc *audit_service.Service,
+) *Worker {
+	return &Worker{log: log, interval: interval, groupsSvc: groupsSvc, auditSvc: auditSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Membership expiry worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.removeExpired)
+	w.log.Infow("Membership expiry worker stopped")
+}
+
+func (w *Worker) removeExpired(ctx context.Context) {
+	expired := w.groupsSvc.ExpiredMemberships(time.Now())
+	if len(expired) == 0 {
+		return
+	}
+
+	w.log.Infow("Removing expired group memberships", "count", len(expired))
+
+	for _, membership := range expired {
+		if err := w.groupsSvc.RemoveUserFromGroup(ctx, membership.GroupID, membership.UserID); err != nil {
+			// Leave the expiry in place so the removal is retried on the next run.
+			w.log.Infow("Failed to remove expired group membership", zap.Error(err),
+				"groupId", membership.GroupID,
+				"userId", membership.UserID,
+			)
+			continue
+		}
+
+		w.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionMembershipExpired,
+			ResourceType: models.ResourceTypeGroup,
+			ResourceID:   membership.GroupID,
+			Details: map[string]any{
+				"userId":    membership.UserID,
+				"expiresAt": membership.ExpiresAt,
+			},
+		})
+	}
+}
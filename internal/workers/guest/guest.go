@@ -0,0 +1,58 @@
+package guest_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+const actor = "system:guests"
+
+// Worker periodically deactivates expired guests and suspends guests whose
+// sponsor did not re-attest in time.
+type Worker struct {
+	log       *zap.SugaredLogger
+	interval  time.Duration
+	guestsSvc *guest_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, guestsSvc *guest_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, guestsSvc: guestsSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Guest lifecycle worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.enforce)
+	w.log.Infow("Guest lifecycle worker stopped")
+}
+
+func (w *Worker) enforce(ctx context.Context) {
+	now := time.Now()
+
+	var deactivated, suspended int
+	for _, guest := range w.guestsSvc.ExpiredGuests(now) {
+		if err := w.guestsSvc.DeactivateGuest(ctx, actor, guest.ID, models.AuditActionGuestExpired); err != nil {
+			w.log.Infow("Failed to deactivate expired guest", zap.Error(err), "guestId", guest.ID)
+			continue
+		}
+		deactivated++
+	}
+
+	for _, guest := range w.guestsSvc.LapsedAttestations(now) {
+		if err := w.guestsSvc.SuspendGuest(ctx, actor, guest.ID); err != nil {
+			w.log.Infow("Failed to suspend guest with lapsed attestation", zap.Error(err), "guestId", guest.ID)
+			continue
+		}
+		suspended++
+	}
+
+	if deactivated > 0 || suspended > 0 {
+		w.log.Infow("Guest lifecycle enforced", "deactivatedCount", deactivated, "suspendedCount", suspended)
+	}
+}
@@ -0,0 +1,74 @@
+package inactivity_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	report_service "github.com/iamBelugaa/iam/internal/services/report"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+const actor = "system:inactivity"
+
+// Worker periodically suspends active users reported as inactive.
+type Worker struct {
+	log        *zap.SugaredLogger
+	interval   time.Duration
+	reportsSvc *report_service.Service
+	usersSvc   *user_service.Service
+	auditSvc   *audit_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, interval time.Duration, reportsSvc *report_service.Service,
+	usersSvc *user_service.Service, auditSvc *audit_service.Service,
+) *Worker {
+	return &Worker{log: log, interval: interval, reportsSvc: reportsSvc, usersSvc: usersSvc, auditSvc: auditSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Inactive user suspension worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.suspendInactive)
+	w.log.Infow("Inactive user suspension worker stopped")
+}
+
+func (w *Worker) suspendInactive(ctx context.Context) {
+	users, err := w.reportsSvc.GetInactiveUsers(ctx, &models.InactiveUsersFilter{})
+	if err != nil {
+		w.log.Infow("Failed to build inactive users report", zap.Error(err))
+		return
+	}
+
+	var suspended int
+	for _, user := range users {
+		// Okta only suspends active users; other statuses are left for review.
+		if user.Status != models.UserStatusActive {
+			continue
+		}
+
+		if err := w.usersSvc.SuspendUser(ctx, user.ID); err != nil {
+			w.log.Infow("Failed to suspend inactive user", zap.Error(err), "userId", user.ID)
+			continue
+		}
+
+		suspended++
+		w.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionUserAutoSuspended,
+			ResourceType: models.ResourceTypeUser,
+			ResourceID:   user.ID,
+			Details: map[string]any{
+				"daysInactive": user.DaysInactive,
+				"reason":       user.Reason,
+			},
+		})
+	}
+
+	w.log.Infow("Inactive users suspended", "reportedCount", len(users), "suspendedCount", suspended)
+}
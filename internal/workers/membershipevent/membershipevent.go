@@ -0,0 +1,36 @@
+package membershipevent_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	membershipevent_service "github.com/iamBelugaa/iam/internal/services/membershipevent"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker copies membership changes from the System Log into the local event
+// store.
+type Worker struct {
+	log       *zap.SugaredLogger
+	interval  time.Duration
+	eventsSvc *membershipevent_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, eventsSvc *membershipevent_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, eventsSvc: eventsSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Membership event ingestion worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.ingest)
+	w.log.Infow("Membership event ingestion worker stopped")
+}
+
+func (w *Worker) ingest(ctx context.Context) {
+	if err := w.eventsSvc.Ingest(ctx); err != nil {
+		w.log.Infow("Failed to ingest membership changes from Okta", zap.Error(err))
+	}
+}
@@ -0,0 +1,42 @@
+package pendingchange_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	pendingchange_service "github.com/iamBelugaa/iam/internal/services/pendingchange"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker periodically expires the pending membership changes of protected
+// groups that no admin decided on in time.
+type Worker struct {
+	log        *zap.SugaredLogger
+	interval   time.Duration
+	changesSvc *pendingchange_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, changesSvc *pendingchange_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, changesSvc: changesSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Pending change expiry worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.expire)
+	w.log.Infow("Pending change expiry worker stopped")
+}
+
+func (w *Worker) expire(ctx context.Context) {
+	expired, err := w.changesSvc.ExpireStale(ctx, time.Now())
+	if err != nil {
+		w.log.Infow("Failed to expire stale pending changes", zap.Error(err))
+		return
+	}
+
+	if expired > 0 {
+		w.log.Infow("Stale pending changes expired", "expiredCount", expired)
+	}
+}
@@ -0,0 +1,30 @@
+package reconciliation_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker retries the remaining steps of sagas left short of their intended
+// state.
+type Worker struct {
+	log      *zap.SugaredLogger
+	interval time.Duration
+	sagasSvc *saga_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, sagasSvc *saga_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, sagasSvc: sagasSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Reconciliation worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.sagasSvc.Reconcile)
+	w.log.Infow("Reconciliation worker stopped")
+}
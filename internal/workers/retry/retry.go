@@ -0,0 +1,35 @@
+package retry_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	retry_service "github.com/iamBelugaa/iam/internal/services/retry"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker replays the queued mutations whose next attempt is due.
+type Worker struct {
+	log      *zap.SugaredLogger
+	interval time.Duration
+	retrySvc *retry_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, retrySvc *retry_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, retrySvc: retrySvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Retry queue worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.process)
+	w.log.Infow("Retry queue worker stopped")
+}
+
+func (w *Worker) process(ctx context.Context) {
+	if err := w.retrySvc.Process(ctx); err != nil && ctx.Err() == nil {
+		w.log.Infow("Failed to replay queued mutations", zap.Error(err))
+	}
+}
@@ -0,0 +1,35 @@
+package scheduledchange_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	scheduledchange_service "github.com/iamBelugaa/iam/internal/services/scheduledchange"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker applies the scheduled membership changes whose next attempt is due.
+type Worker struct {
+	log          *zap.SugaredLogger
+	interval     time.Duration
+	scheduledSvc *scheduledchange_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, scheduledSvc *scheduledchange_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, scheduledSvc: scheduledSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Scheduled change worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.process)
+	w.log.Infow("Scheduled change worker stopped")
+}
+
+func (w *Worker) process(ctx context.Context) {
+	if err := w.scheduledSvc.Process(ctx); err != nil && ctx.Err() == nil {
+		w.log.Infow("Failed to apply scheduled membership changes", zap.Error(err))
+	}
+}
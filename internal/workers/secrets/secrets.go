@@ -0,0 +1,36 @@
+package secrets_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/secrets"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker reloads secrets periodically so rotated credentials take effect
+// without a restart.
+type Worker struct {
+	log      *zap.SugaredLogger
+	interval time.Duration
+	store    *secrets.Store
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, store *secrets.Store) *Worker {
+	return &Worker{log: log, interval: interval, store: store}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Secrets refresh worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.refresh)
+	w.log.Infow("Secrets refresh worker stopped")
+}
+
+func (w *Worker) refresh(ctx context.Context) {
+	if err := w.store.Refresh(ctx); err != nil {
+		w.log.Infow("Failed to refresh secrets; keeping the previous values", zap.Error(err))
+	}
+}
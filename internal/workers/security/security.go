@@ -0,0 +1,36 @@
+package security_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	security_service "github.com/iamBelugaa/iam/internal/services/security"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker checks the System Log for brute-force and impossible-travel
+// sign-ins.
+type Worker struct {
+	log         *zap.SugaredLogger
+	interval    time.Duration
+	securitySvc *security_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, securitySvc *security_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, securitySvc: securitySvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Security detection worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.detect)
+	w.log.Infow("Security detection worker stopped")
+}
+
+func (w *Worker) detect(ctx context.Context) {
+	if err := w.securitySvc.Detect(ctx); err != nil {
+		w.log.Infow("Failed to check Okta sign-ins for attacks", zap.Error(err))
+	}
+}
@@ -0,0 +1,78 @@
+package serviceaccount_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	serviceaccount_service "github.com/iamBelugaa/iam/internal/services/serviceaccount"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+const actor = "system:service-accounts"
+
+// Worker periodically raises reminders for service accounts whose
+// credentials are due for rotation or whose review date has passed.
+type Worker struct {
+	log                *zap.SugaredLogger
+	interval           time.Duration
+	serviceAccountsSvc *serviceaccount_service.Service
+	auditSvc           *audit_service.Service
+}
+
+func New(
+	log *zap.SugaredLogger, interval time.Duration,
+	serviceAccountsSvc *serviceaccount_service.Service, auditSvc *audit_service.Service,
+) *Worker {
+	return &Worker{log: log, interval: interval, serviceAccountsSvc: serviceAccountsSvc, auditSvc: auditSvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Service account reminder worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.remind)
+	w.log.Infow("Service account reminder worker stopped")
+}
+
+func (w *Worker) remind(ctx context.Context) {
+	now := time.Now().UTC()
+
+	rotationDue := w.serviceAccountsSvc.GetRotationDue(ctx, now)
+	for _, account := range rotationDue {
+		w.log.Infow("Service account credential rotation due",
+			"serviceAccountId", account.ID, "name", account.Name, "ownerId", account.OwnerID,
+		)
+
+		w.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionServiceAccountRotationDue,
+			ResourceType: models.ResourceTypeServiceAccount,
+			ResourceID:   account.ID,
+			Details: map[string]any{
+				"ownerId":           account.OwnerID,
+				"credentialRotated": account.CredentialRotated,
+				"nextRotation":      account.NextRotation(),
+			},
+		})
+	}
+
+	pastReview := w.serviceAccountsSvc.GetPastReview(ctx, now)
+	for _, account := range pastReview {
+		w.auditSvc.Record(ctx, &models.AuditEntry{
+			Actor:        actor,
+			Action:       models.AuditActionServiceAccountReviewOverdue,
+			ResourceType: models.ResourceTypeServiceAccount,
+			ResourceID:   account.ID,
+			Details: map[string]any{
+				"ownerId":     account.OwnerID,
+				"reviewBy":    account.ReviewBy,
+				"daysOverdue": account.DaysOverdue,
+			},
+		})
+	}
+
+	w.log.Infow("Service account reminders raised", "rotationDueCount", len(rotationDue), "pastReviewCount", len(pastReview))
+}
@@ -0,0 +1,37 @@
+package snapshot_worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	history_service "github.com/iamBelugaa/iam/internal/services/history"
+	"github.com/iamBelugaa/iam/pkg/scheduler"
+)
+
+// Worker stores a membership snapshot whenever one is due. It checks every
+// interval, which is shorter than the snapshot interval, so a restart does
+// not delay the next snapshot by a whole snapshot interval.
+type Worker struct {
+	log        *zap.SugaredLogger
+	interval   time.Duration
+	historySvc *history_service.Service
+}
+
+func New(log *zap.SugaredLogger, interval time.Duration, historySvc *history_service.Service) *Worker {
+	return &Worker{log: log, interval: interval, historySvc: historySvc}
+}
+
+// Run blocks until ctx is cancelled.
+func (w *Worker) Run(ctx context.Context) {
+	w.log.Infow("Membership snapshot worker started", "interval", w.interval)
+	scheduler.Every(ctx, w.interval, w.snapshot)
+	w.log.Infow("Membership snapshot worker stopped")
+}
+
+func (w *Worker) snapshot(ctx context.Context) {
+	if err := w.historySvc.SnapshotIfDue(ctx); err != nil {
+		w.log.Infow("Failed to store membership snapshot", zap.Error(err))
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+// AccessRequestsClient wraps the /access-requests endpoints. They act for
+// the owner of the client's access token.
+type AccessRequestsClient struct {
+	c *Client
+}
+
+func (a *AccessRequestsClient) List(ctx context.Context, filter AccessRequestFilter) ([]*AccessRequest, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"status":      filter.Status,
+		"groupId":     filter.GroupID,
+		"requesterId": filter.RequesterID,
+		"approverId":  filter.ApproverID,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	var requests []*AccessRequest
+	return requests, a.c.do(ctx, http.MethodGet, "/access-requests", query, nil, &requests)
+}
+
+func (a *AccessRequestsClient) Get(ctx context.Context, requestID string) (*AccessRequest, error) {
+	var request AccessRequest
+	if err := a.c.do(ctx, http.MethodGet, a.path(requestID), nil, nil, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (a *AccessRequestsClient) Create(ctx context.Context, req *CreateAccessRequestRequest) (*AccessRequest, error) {
+	var request AccessRequest
+	if err := a.c.do(ctx, http.MethodPost, "/access-requests", nil, req, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (a *AccessRequestsClient) Approve(ctx context.Context, requestID, comment string) (*AccessRequest, error) {
+	return a.decide(ctx, requestID, "approve", comment)
+}
+
+func (a *AccessRequestsClient) Deny(ctx context.Context, requestID, comment string) (*AccessRequest, error) {
+	return a.decide(ctx, requestID, "deny", comment)
+}
+
+func (a *AccessRequestsClient) ProtectedGroups(ctx context.Context) ([]*ProtectedGroup, error) {
+	var groups []*ProtectedGroup
+	return groups, a.c.do(ctx, http.MethodGet, "/access-requests/protected-groups", nil, nil, &groups)
+}
+
+func (a *AccessRequestsClient) ProtectGroup(
+	ctx context.Context, groupID string, req *UpdateProtectedGroupRequest,
+) (*ProtectedGroup, error) {
+	var group ProtectedGroup
+	path := "/access-requests/protected-groups/" + url.PathEscape(groupID)
+	if err := a.c.do(ctx, http.MethodPut, path, nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (a *AccessRequestsClient) UnprotectGroup(ctx context.Context, groupID string) error {
+	path := "/access-requests/protected-groups/" + url.PathEscape(groupID)
+	return a.c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+func (a *AccessRequestsClient) decide(ctx context.Context, requestID, action, comment string) (*AccessRequest, error) {
+	var request AccessRequest
+	body := &models.AccessRequestDecision{Comment: comment}
+	if err := a.c.do(ctx, http.MethodPost, a.path(requestID)+"/"+action, nil, body, &request); err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (a *AccessRequestsClient) path(requestID string) string {
+	return "/access-requests/" + url.PathEscape(requestID)
+}
@@ -0,0 +1,252 @@
+// Package client is a typed Go client for the IAM REST API. It injects the
+// access token, retries transient failures of idempotent calls and iterates
+// streamed collections without buffering them.
+//
+//	c, err := client.New("https://iam.example.com", client.WithToken(token))
+//	for user, err := range c.Users.All(ctx) {
+//		...
+//	}
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const apiPrefix = "/api/v1"
+
+// TokenSource returns the access token sent as a bearer token on every
+// request. It is called once per attempt, so it can refresh expiring tokens.
+type TokenSource func(ctx context.Context) (string, error)
+
+// Client talks to one IAM deployment. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      TokenSource
+	maxRetries int
+	backoff    time.Duration
+
+	Users          *UsersClient
+	Groups         *GroupsClient
+	Roles          *RolesClient
+	AccessRequests *AccessRequestsClient
+}
+
+type Option func(*Client)
+
+// WithHTTPClient replaces http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithToken authenticates every request with a fixed access token.
+func WithToken(token string) Option {
+	return WithTokenSource(func(context.Context) (string, error) { return token, nil })
+}
+
+// WithTokenSource authenticates every request with a token from source.
+func WithTokenSource(source TokenSource) Option {
+	return func(c *Client) { c.token = source }
+}
+
+// WithRetries sets how often an idempotent request is retried after a
+// network error, 429 or 5xx response, and the delay before the first retry.
+// The delay doubles on each further retry unless the server sends Retry-After.
+func WithRetries(maxRetries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxRetries = maxRetries
+		c.backoff = backoff
+	}
+}
+
+// New creates a client for the API served at baseURL.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme and host are required", baseURL)
+	}
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+		maxRetries: 3,
+		backoff:    500 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	c.Users = &UsersClient{c: c}
+	c.Groups = &GroupsClient{c: c}
+	c.Roles = &RolesClient{c: c}
+	c.AccessRequests = &AccessRequestsClient{c: c}
+	return c, nil
+}
+
+// Error is returned for every non-2xx response.
+type Error struct {
+	StatusCode int
+	ErrorCode  string
+	Message    string
+	Details    any
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("iam: %d %s: %s", e.StatusCode, e.ErrorCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 response.
+func IsNotFound(err error) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends a JSON request and decodes the data of the success envelope into
+// out, which may be nil.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	resp, err := c.send(ctx, method, path, query, payload, "application/json")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	envelope := struct {
+		Data json.RawMessage `json:"data"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(envelope.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(envelope.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// send performs the request, retrying transient failures of idempotent
+// methods, and returns the successful response with its body unread.
+func (c *Client) send(
+	ctx context.Context, method, path string, query url.Values, payload []byte, accept string,
+) (*http.Response, error) {
+	target := c.baseURL.JoinPath(apiPrefix, path)
+	target.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+		req.Header.Set("Accept", accept)
+		if payload != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		if c.token != nil {
+			token, err := c.token(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get access token: %w", err)
+			}
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		if attempt < c.maxRetries && idempotent(method) && retryable(resp, err) {
+			delay := c.backoff << attempt
+			if resp != nil {
+				if after := retryAfter(resp); after > 0 {
+					delay = after
+				}
+				drain(resp)
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		defer drain(resp)
+		return nil, decodeError(resp)
+	}
+}
+
+func decodeError(resp *http.Response) error {
+	apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+	var body response.ErrorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Message != "" {
+		apiErr.ErrorCode = body.ErrorCode
+		apiErr.Message = body.Message
+		apiErr.Details = body.Details
+	}
+	return apiErr
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+// GroupsClient wraps the /groups endpoints.
+type GroupsClient struct {
+	c *Client
+}
+
+// List returns every group in one response.
+func (g *GroupsClient) List(ctx context.Context) ([]*Group, error) {
+	var groups []*Group
+	return groups, g.c.do(ctx, http.MethodGet, "/groups", nil, nil, &groups)
+}
+
+// All iterates every group, streaming them from the server page by page.
+func (g *GroupsClient) All(ctx context.Context) iter.Seq2[*Group, error] {
+	return stream[Group](ctx, g.c, "/groups", nil)
+}
+
+func (g *GroupsClient) Get(ctx context.Context, groupID string) (*Group, error) {
+	var group Group
+	if err := g.c.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(groupID), nil, nil, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (g *GroupsClient) Create(ctx context.Context, req *CreateGroupRequest) (*Group, error) {
+	var group Group
+	if err := g.c.do(ctx, http.MethodPost, "/groups", nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (g *GroupsClient) Update(ctx context.Context, groupID string, req *UpdateGroupRequest) (*Group, error) {
+	var group Group
+	if err := g.c.do(ctx, http.MethodPut, "/groups/"+url.PathEscape(groupID), nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (g *GroupsClient) Delete(ctx context.Context, groupID string) error {
+	return g.c.do(ctx, http.MethodDelete, "/groups/"+url.PathEscape(groupID), nil, nil, nil)
+}
+
+// Members returns the members of a group together with the expiry of their
+// time-bound memberships.
+func (g *GroupsClient) Members(ctx context.Context, groupID string) ([]*GroupMember, error) {
+	var members []*GroupMember
+	query := url.Values{"includeExpiry": {"true"}}
+	return members, g.c.do(ctx, http.MethodGet, g.membersPath(groupID), query, nil, &members)
+}
+
+// AllMembers iterates the members of a group, streaming them from the server
+// page by page.
+func (g *GroupsClient) AllMembers(ctx context.Context, groupID string) iter.Seq2[*User, error] {
+	return stream[User](ctx, g.c, g.membersPath(groupID), nil)
+}
+
+// AddMember adds the user to the group. A non-nil expiresAt makes the
+// membership time-bound.
+func (g *GroupsClient) AddMember(ctx context.Context, groupID, userID string, expiresAt *time.Time) error {
+	var body any
+	if expiresAt != nil {
+		body = &models.AddGroupMemberRequest{ExpiresAt: expiresAt}
+	}
+	return g.c.do(ctx, http.MethodPut, g.membersPath(groupID)+"/"+url.PathEscape(userID), nil, body, nil)
+}
+
+func (g *GroupsClient) RemoveMember(ctx context.Context, groupID, userID string) error {
+	return g.c.do(ctx, http.MethodDelete, g.membersPath(groupID)+"/"+url.PathEscape(userID), nil, nil, nil)
+}
+
+func (g *GroupsClient) Roles(ctx context.Context, groupID string) ([]*Role, error) {
+	var roles []*Role
+	return roles, g.c.do(ctx, http.MethodGet, "/groups/"+url.PathEscape(groupID)+"/roles", nil, nil, &roles)
+}
+
+func (g *GroupsClient) AssignRole(ctx context.Context, groupID, roleID string) error {
+	path := "/groups/" + url.PathEscape(groupID) + "/roles/" + url.PathEscape(roleID)
+	return g.c.do(ctx, http.MethodPut, path, nil, nil, nil)
+}
+
+func (g *GroupsClient) UnassignRole(ctx context.Context, groupID, roleID string) error {
+	path := "/groups/" + url.PathEscape(groupID) + "/roles/" + url.PathEscape(roleID)
+	return g.c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
+
+func (g *GroupsClient) membersPath(groupID string) string {
+	return "/groups/" + url.PathEscape(groupID) + "/members"
+}
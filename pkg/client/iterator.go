@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// stream iterates a collection served as newline-delimited JSON. Records are
+// decoded as they arrive, and the iteration stops at the first error, which
+// is yielded last. Breaking out of the loop closes the connection.
+func stream[T any](ctx context.Context, c *Client, path string, query url.Values) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("stream", "true")
+
+		resp, err := c.send(ctx, http.MethodGet, path, query, nil, "application/x-ndjson")
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		decoder := json.NewDecoder(resp.Body)
+		for {
+			item := new(T)
+			if err := decoder.Decode(item); err != nil {
+				if !errors.Is(err, io.EOF) {
+					yield(nil, fmt.Errorf("failed to decode %s stream: %w", path, err))
+				}
+				return
+			}
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package client
+
+import "github.com/iamBelugaa/iam/internal/models"
+
+// The API models are re-exported so callers outside this module can name them.
+type (
+	User                        = models.User
+	CreateUserRequest           = models.CreateUserRequest
+	UpdateUserRequest           = models.UpdateUserRequest
+	Group                       = models.Group
+	CreateGroupRequest          = models.CreateGroupRequest
+	UpdateGroupRequest          = models.UpdateGroupRequest
+	GroupMember                 = models.GroupMember
+	Role                        = models.Role
+	CreateRoleRequest           = models.CreateRoleRequest
+	UpdateRoleRequest           = models.UpdateRoleRequest
+	AccessRequest               = models.AccessRequest
+	CreateAccessRequestRequest  = models.CreateAccessRequestRequest
+	AccessRequestFilter         = models.AccessRequestFilter
+	ProtectedGroup              = models.ProtectedGroup
+	UpdateProtectedGroupRequest = models.UpdateProtectedGroupRequest
+)
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// RolesClient wraps the /roles endpoints.
+type RolesClient struct {
+	c *Client
+}
+
+func (r *RolesClient) List(ctx context.Context) ([]*Role, error) {
+	var roles []*Role
+	return roles, r.c.do(ctx, http.MethodGet, "/roles", nil, nil, &roles)
+}
+
+func (r *RolesClient) Get(ctx context.Context, roleID string) (*Role, error) {
+	var role Role
+	if err := r.c.do(ctx, http.MethodGet, "/roles/"+url.PathEscape(roleID), nil, nil, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RolesClient) Create(ctx context.Context, req *CreateRoleRequest) (*Role, error) {
+	var role Role
+	if err := r.c.do(ctx, http.MethodPost, "/roles", nil, req, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RolesClient) Update(ctx context.Context, roleID string, req *UpdateRoleRequest) (*Role, error) {
+	var role Role
+	if err := r.c.do(ctx, http.MethodPut, "/roles/"+url.PathEscape(roleID), nil, req, &role); err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RolesClient) Delete(ctx context.Context, roleID string) error {
+	return r.c.do(ctx, http.MethodDelete, "/roles/"+url.PathEscape(roleID), nil, nil, nil)
+}
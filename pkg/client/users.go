@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"iter"
+	"net/http"
+	"net/url"
+)
+
+// UsersClient wraps the /users endpoints.
+type UsersClient struct {
+	c *Client
+}
+
+// List returns every user in one response.
+func (u *UsersClient) List(ctx context.Context) ([]*User, error) {
+	var users []*User
+	return users, u.c.do(ctx, http.MethodGet, "/users", nil, nil, &users)
+}
+
+// All iterates every user, streaming them from the server page by page.
+func (u *UsersClient) All(ctx context.Context) iter.Seq2[*User, error] {
+	return stream[User](ctx, u.c, "/users", nil)
+}
+
+func (u *UsersClient) Get(ctx context.Context, userID string) (*User, error) {
+	var user User
+	if err := u.c.do(ctx, http.MethodGet, "/users/"+url.PathEscape(userID), nil, nil, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (u *UsersClient) Create(ctx context.Context, req *CreateUserRequest) (*User, error) {
+	var user User
+	if err := u.c.do(ctx, http.MethodPost, "/users", nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (u *UsersClient) Update(ctx context.Context, userID string, req *UpdateUserRequest) (*User, error) {
+	var user User
+	if err := u.c.do(ctx, http.MethodPut, "/users/"+url.PathEscape(userID), nil, req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Delete deactivates and deletes the user.
+func (u *UsersClient) Delete(ctx context.Context, userID string) error {
+	return u.c.do(ctx, http.MethodDelete, "/users/"+url.PathEscape(userID), nil, nil, nil)
+}
+
+func (u *UsersClient) Activate(ctx context.Context, userID string) error {
+	return u.lifecycle(ctx, userID, "activate")
+}
+
+func (u *UsersClient) Deactivate(ctx context.Context, userID string) error {
+	return u.lifecycle(ctx, userID, "deactivate")
+}
+
+func (u *UsersClient) Suspend(ctx context.Context, userID string) error {
+	return u.lifecycle(ctx, userID, "suspend")
+}
+
+func (u *UsersClient) Unsuspend(ctx context.Context, userID string) error {
+	return u.lifecycle(ctx, userID, "unsuspend")
+}
+
+func (u *UsersClient) lifecycle(ctx context.Context, userID, action string) error {
+	return u.c.do(ctx, http.MethodPost, "/users/"+url.PathEscape(userID)+"/"+action, nil, nil, nil)
+}
+
+func (u *UsersClient) Roles(ctx context.Context, userID string) ([]*Role, error) {
+	var roles []*Role
+	return roles, u.c.do(ctx, http.MethodGet, "/users/"+url.PathEscape(userID)+"/roles", nil, nil, &roles)
+}
+
+func (u *UsersClient) AssignRole(ctx context.Context, userID, roleID string) error {
+	path := "/users/" + url.PathEscape(userID) + "/roles/" + url.PathEscape(roleID)
+	return u.c.do(ctx, http.MethodPut, path, nil, nil, nil)
+}
+
+func (u *UsersClient) UnassignRole(ctx context.Context, userID, roleID string) error {
+	path := "/users/" + url.PathEscape(userID) + "/roles/" + url.PathEscape(roleID)
+	return u.c.do(ctx, http.MethodDelete, path, nil, nil, nil)
+}
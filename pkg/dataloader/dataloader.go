@@ -0,0 +1,91 @@
+package dataloader
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchFunc fetches the values for keys in one go. It returns one value and
+// one error per key, in the same order as keys.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) ([]V, []error)
+
+// Loader coalesces the loads issued within a short window into a single call
+// to its BatchFunc and caches every result, so resolving the same key twice
+// in one request only fetches it once. Loaders are meant to live for the
+// duration of a single request.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	wait  time.Duration
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch []K
+}
+
+type result[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+func New[K comparable, V any](fetch BatchFunc[K, V], wait time.Duration) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, wait: wait, cache: make(map[K]*result[V])}
+}
+
+// Load returns the value for key, waiting for the batch it joined.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	res, ok := l.cache[key]
+	if !ok {
+		res = &result[V]{done: make(chan struct{})}
+		l.cache[key] = res
+		l.batch = append(l.batch, key)
+
+		// The first key of a batch schedules its dispatch.
+		if len(l.batch) == 1 {
+			time.AfterFunc(l.wait, func() { l.dispatch(ctx) })
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-res.done:
+		return res.value, res.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Prime stores a value fetched elsewhere so later loads of key reuse it.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+
+	res := &result[V]{done: make(chan struct{}), value: value}
+	close(res.done)
+	l.cache[key] = res
+}
+
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	l.mu.Lock()
+	keys := l.batch
+	l.batch = nil
+
+	results := make([]*result[V], len(keys))
+	for i, key := range keys {
+		results[i] = l.cache[key]
+	}
+	l.mu.Unlock()
+
+	values, errs := l.fetch(ctx, keys)
+	for i, res := range results {
+		res.value, res.err = values[i], errs[i]
+		close(res.done)
+	}
+}
@@ -0,0 +1,362 @@
+package expression
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Env is what an expression is evaluated against.
+type Env struct {
+	// Vars are the objects expressions refer to by name, such as "user" for
+	// the user's profile. Attributes the object does not have are null.
+	Vars map[string]map[string]any
+	// Groups are the groups of the user, for the group membership functions.
+	Groups []Group
+}
+
+// Group is a group the user is a member of.
+type Group struct {
+	ID   string
+	Name string
+}
+
+// Evaluate parses and evaluates src against env.
+func Evaluate(src string, env *Env) (any, error) {
+	expr, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Eval(env)
+}
+
+// Eval evaluates the expression against env. The result is nil, a bool, a
+// float64, a string or a []any; an expression that cannot be evaluated, such
+// as one calling an unknown function, returns an *Error.
+func (e *Expr) Eval(env *Env) (any, error) {
+	if env == nil {
+		env = &Env{}
+	}
+	return eval(e.root, env)
+}
+
+// TypeOf names the type of an evaluated value as JSON does.
+func TypeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func eval(n node, env *Env) (any, error) {
+	switch n := n.(type) {
+	case *literal:
+		return n.value, nil
+
+	case *list:
+		items := make([]any, len(n.items))
+		for i, item := range n.items {
+			value, err := eval(item, env)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = value
+		}
+		return items, nil
+
+	case *variable:
+		object, ok := env.Vars[n.name]
+		if !ok {
+			return nil, errorAt(n, "unknown variable %s", n.name)
+		}
+		return normalize(object), nil
+
+	case *member:
+		x, err := eval(n.x, env)
+		if err != nil {
+			return nil, err
+		}
+		object, ok := x.(map[string]any)
+		if !ok {
+			return nil, errorAt(n, "cannot read %s of %s", n.name, TypeOf(x))
+		}
+		return normalize(object[n.name]), nil
+
+	case *index:
+		return evalIndex(n, env)
+
+	case *call:
+		fn, ok := functions[n.name]
+		if !ok {
+			return nil, errorAt(n, "unknown function %s", n.name)
+		}
+		args := make([]any, len(n.args))
+		for i, arg := range n.args {
+			value, err := eval(arg, env)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		result, err := fn(env, args)
+		if err != nil {
+			return nil, errorAt(n, "%s: %v", n.name, err)
+		}
+		return limitString(n, result)
+
+	case *unary:
+		x, err := eval(n.x, env)
+		if err != nil {
+			return nil, err
+		}
+		if n.op == "!" {
+			b, ok := x.(bool)
+			if !ok {
+				return nil, errorAt(n, "! needs a boolean, got %s", TypeOf(x))
+			}
+			return !b, nil
+		}
+		f, ok := x.(float64)
+		if !ok {
+			return nil, errorAt(n, "- needs a number, got %s", TypeOf(x))
+		}
+		return -f, nil
+
+	case *binary:
+		result, err := evalBinary(n, env)
+		if err != nil {
+			return nil, err
+		}
+		return limitString(n, result)
+
+	case *conditional:
+		cond, err := eval(n.cond, env)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := cond.(bool)
+		if !ok {
+			return nil, errorAt(n, "the condition of ?: must be a boolean, got %s", TypeOf(cond))
+		}
+		if b {
+			return eval(n.then, env)
+		}
+		return eval(n.other, env)
+	}
+
+	return nil, errorAt(n, "unsupported expression")
+}
+
+func evalIndex(n *index, env *Env) (any, error) {
+	x, err := eval(n.x, env)
+	if err != nil {
+		return nil, err
+	}
+	key, err := eval(n.key, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch x := x.(type) {
+	case []any:
+		i, ok := key.(float64)
+		if !ok || i != math.Trunc(i) {
+			return nil, errorAt(n, "an array index must be a whole number, got %s", TypeOf(key))
+		}
+		if i < 0 || int(i) >= len(x) {
+			return nil, errorAt(n, "index %d is outside the array of %d items", int(i), len(x))
+		}
+		return x[int(i)], nil
+	case map[string]any:
+		name, ok := key.(string)
+		if !ok {
+			return nil, errorAt(n, "an attribute name must be a string, got %s", TypeOf(key))
+		}
+		return normalize(x[name]), nil
+	}
+	return nil, errorAt(n, "cannot index %s", TypeOf(x))
+}
+
+func evalBinary(n *binary, env *Env) (any, error) {
+	x, err := eval(n.x, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || only evaluate their right side when it decides the result.
+	if n.op == "&&" || n.op == "||" {
+		left, ok := x.(bool)
+		if !ok {
+			return nil, errorAt(n, "%s needs booleans, got %s", n.op, TypeOf(x))
+		}
+		if left == (n.op == "||") {
+			return left, nil
+		}
+		y, err := eval(n.y, env)
+		if err != nil {
+			return nil, err
+		}
+		right, ok := y.(bool)
+		if !ok {
+			return nil, errorAt(n, "%s needs booleans, got %s", n.op, TypeOf(y))
+		}
+		return right, nil
+	}
+
+	y, err := eval(n.y, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(x, y), nil
+	case "!=":
+		return !equal(x, y), nil
+	case "<", "<=", ">", ">=":
+		cmp, ok := compare(x, y)
+		if !ok {
+			return nil, errorAt(n, "cannot compare %s with %s", TypeOf(x), TypeOf(y))
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		default:
+			return cmp >= 0, nil
+		}
+	case "+":
+		_, xs := x.(string)
+		_, ys := y.(string)
+		if xs || ys {
+			return toString(x) + toString(y), nil
+		}
+	}
+
+	a, xok := x.(float64)
+	b, yok := y.(float64)
+	if !xok || !yok {
+		return nil, errorAt(n, "%s needs numbers, got %s and %s", n.op, TypeOf(x), TypeOf(y))
+	}
+	switch n.op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	}
+	if b == 0 {
+		return nil, errorAt(n, "division by zero")
+	}
+	return a / b, nil
+}
+
+func equal(x, y any) bool {
+	return reflect.DeepEqual(x, y)
+}
+
+// compare orders two numbers or two strings.
+func compare(x, y any) (int, bool) {
+	switch x := x.(type) {
+	case float64:
+		if y, ok := y.(float64); ok {
+			switch {
+			case x < y:
+				return -1, true
+			case x > y:
+				return 1, true
+			}
+			return 0, true
+		}
+	case string:
+		if y, ok := y.(string); ok {
+			return strings.Compare(x, y), true
+		}
+	}
+	return 0, false
+}
+
+// normalize turns attribute values into the types expressions work with:
+// every number becomes a float64 and every array a []any.
+func normalize(value any) any {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return f
+	case []string:
+		items := make([]any, len(v))
+		for i, s := range v {
+			items[i] = s
+		}
+		return items
+	case []any:
+		items := make([]any, len(v))
+		for i, item := range v {
+			items[i] = normalize(item)
+		}
+		return items
+	}
+	return value
+}
+
+// toString formats a value as concatenation does.
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = toString(item)
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// limitString fails when value is a string longer than MaxStringLength, so
+// an expression concatenating large attributes cannot build huge strings.
+func limitString(n node, value any) (any, error) {
+	if s, ok := value.(string); ok && len(s) > MaxStringLength {
+		return nil, errorAt(n, "result is longer than %d bytes", MaxStringLength)
+	}
+	return value, nil
+}
+
+func errorAt(n node, format string, args ...any) *Error {
+	return &Error{Position: n.position(), Message: fmt.Sprintf(format, args...)}
+}
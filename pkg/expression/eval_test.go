@@ -0,0 +1,101 @@
+package expression
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate(t *testing.T) {
+	env := &Env{
+		Vars: map[string]map[string]any{
+			"user": {
+				"firstName":  "Ada",
+				"department": "Engineering",
+				"level":      4,
+				"email":      "ada@example.com",
+				"roles":      []string{"dev", "ops"},
+				"manager":    map[string]any{"login": "grace"},
+				"big":        strings.Repeat("x", MaxStringLength/2+1),
+			},
+		},
+		Groups: []Group{{ID: "00g1", Name: "Engineering"}, {ID: "00g2", Name: "eng-oncall"}},
+	}
+
+	tests := []struct {
+		name    string
+		src     string
+		want    any
+		wantErr string
+	}{
+		{name: "attribute", src: "user.department", want: "Engineering"},
+		{name: "missing attribute is null", src: "user.costCenter", want: nil},
+		{name: "integer attribute is a number", src: "user.level", want: float64(4)},
+		{name: "equality", src: `user.department == "Engineering"`, want: true},
+		{name: "case sensitive equality", src: `user.department == "engineering"`, want: false},
+		{name: "comparison", src: "user.level >= 4 and user.level < 5", want: true},
+		{name: "string comparison", src: `"a" < "b"`, want: true},
+		{name: "concatenation", src: `user.firstName + " " + user.level`, want: "Ada 4"},
+		{name: "arithmetic precedence", src: "1 + 2 * 3", want: float64(7)},
+		{name: "unary minus", src: "-user.level", want: float64(-4)},
+		{name: "ternary", src: `user.level > 3 ? "senior" : "junior"`, want: "senior"},
+		{name: "short circuit", src: "false && user.nope.deeper", want: false},
+		{name: "array attribute", src: "user.roles[1]", want: "ops"},
+		{name: "nested attribute", src: "user.manager.login", want: "grace"},
+		{name: "list literal", src: `{"a", 1}`, want: []any{"a", float64(1)}},
+		{name: "upper case", src: "String.toUpperCase(user.firstName)", want: "ADA"},
+		{name: "substring after", src: `String.substringAfter(user.email, "@")`, want: "example.com"},
+		{name: "replace", src: `String.replace(user.email, "[aeiou]", "")`, want: "d@xmpl.cm"},
+		{name: "arrays contains", src: `Arrays.contains(user.roles, "ops")`, want: true},
+		{name: "arrays size", src: "Arrays.size(user.roles)", want: float64(2)},
+		{name: "convert", src: `Convert.toInt(2.6)`, want: float64(3)},
+		{name: "member of group", src: `isMemberOfGroup("00g2")`, want: true},
+		{name: "member of group name", src: `isMemberOfGroupNameStartsWith("eng-")`, want: true},
+		{name: "member of group regex", src: `isMemberOfGroupNameRegex("Eng.*")`, want: true},
+
+		{name: "unknown variable", src: "app.name", wantErr: "unknown variable app"},
+		{name: "unknown function", src: "Time.now()", wantErr: "unknown function Time.now"},
+		{name: "member of string", src: "user.firstName.x", wantErr: "cannot read x of string"},
+		{name: "division by zero", src: "1 / 0", wantErr: "division by zero"},
+		{name: "logic on strings", src: `"a" && true`, wantErr: "needs booleans"},
+		{name: "index out of range", src: "user.roles[5]", wantErr: "outside the array"},
+		{name: "fractional index", src: "user.roles[0.5]", wantErr: "whole number"},
+		{name: "wrong argument count", src: `String.len("a", "b")`, wantErr: "takes 1 arguments"},
+		{name: "null argument", src: "String.len(user.nope)", wantErr: "argument 1 is null"},
+		{name: "invalid pattern", src: `isMemberOfGroupNameRegex("(")`, wantErr: "invalid pattern"},
+		{name: "non-boolean condition", src: `"x" ? 1 : 2`, wantErr: "must be a boolean"},
+		{name: "concatenation past the limit", src: "user.big + user.big", wantErr: "longer than"},
+		{name: "append past the limit", src: "String.append(user.big, user.big)", wantErr: "longer than"},
+		{name: "parse error", src: "user.", wantErr: "expected a name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.src, env)
+			if tt.wantErr != "" {
+				var exprErr *Error
+				if !errors.As(err, &exprErr) {
+					t.Fatalf("Evaluate() error = %v, want an *Error", err)
+				}
+				if !strings.Contains(exprErr.Message, tt.wantErr) {
+					t.Errorf("Evaluate() error = %q, want one containing %q", exprErr.Message, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Evaluate() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateWithoutEnv(t *testing.T) {
+	got, err := Evaluate(`"a" + 1`, nil)
+	if err != nil || got != "a1" {
+		t.Errorf("Evaluate() = %v, %v, want a1", got, err)
+	}
+}
@@ -0,0 +1,257 @@
+package expression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type function func(env *Env, args []any) (any, error)
+
+// functions are the functions of the Okta Expression Language that can be
+// evaluated without Okta, by the name expressions call them with.
+var functions = map[string]function{
+	"String.append": stringsOf(2, func(s []string) (any, error) { return s[0] + s[1], nil }),
+	"String.join": func(env *Env, args []any) (any, error) {
+		if len(args) < 1 {
+			return nil, errors.New("takes a separator and the strings to join")
+		}
+		parts := make([]string, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			parts = append(parts, toString(arg))
+		}
+		return strings.Join(parts, toString(args[0])), nil
+	},
+	"String.len": stringsOf(1, func(s []string) (any, error) { return float64(len([]rune(s[0]))), nil }),
+	"String.removeSpaces": stringsOf(1, func(s []string) (any, error) {
+		return strings.ReplaceAll(s[0], " ", ""), nil
+	}),
+	"String.replace": stringsOf(3, func(s []string) (any, error) {
+		pattern, err := regexp.Compile(s[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return pattern.ReplaceAllString(s[0], s[2]), nil
+	}),
+	"String.replaceFirst": stringsOf(3, func(s []string) (any, error) {
+		pattern, err := regexp.Compile(s[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		if loc := pattern.FindStringSubmatchIndex(s[0]); loc != nil {
+			replaced := pattern.ExpandString(nil, s[2], s[0], loc)
+			return s[0][:loc[0]] + string(replaced) + s[0][loc[1]:], nil
+		}
+		return s[0], nil
+	}),
+	"String.stringContains": stringsOf(2, func(s []string) (any, error) { return strings.Contains(s[0], s[1]), nil }),
+	"String.stringSwitch": func(env *Env, args []any) (any, error) {
+		if len(args) < 2 || len(args)%2 != 0 {
+			return nil, errors.New("takes the input, a default and pairs of keys and values")
+		}
+		input := toString(args[0])
+		for i := 2; i < len(args); i += 2 {
+			if strings.Contains(input, toString(args[i])) {
+				return args[i+1], nil
+			}
+		}
+		return args[1], nil
+	},
+	"String.substring": func(env *Env, args []any) (any, error) {
+		if len(args) != 3 {
+			return nil, errors.New("takes a string, a start index and an end index")
+		}
+		runes := []rune(toString(args[0]))
+		start, startOK := args[1].(float64)
+		end, endOK := args[2].(float64)
+		if !startOK || !endOK || start < 0 || end > float64(len(runes)) || start > end {
+			return nil, fmt.Errorf("indexes must be numbers within the string of %d characters", len(runes))
+		}
+		return string(runes[int(start):int(end)]), nil
+	},
+	"String.substringAfter": stringsOf(2, func(s []string) (any, error) {
+		if _, after, ok := strings.Cut(s[0], s[1]); ok {
+			return after, nil
+		}
+		return "", nil
+	}),
+	"String.substringBefore": stringsOf(2, func(s []string) (any, error) {
+		if before, _, ok := strings.Cut(s[0], s[1]); ok {
+			return before, nil
+		}
+		return "", nil
+	}),
+	"String.toLowerCase": stringsOf(1, func(s []string) (any, error) { return strings.ToLower(s[0]), nil }),
+	"String.toUpperCase": stringsOf(1, func(s []string) (any, error) { return strings.ToUpper(s[0]), nil }),
+
+	"Arrays.add": arrayAnd(func(items []any, value any) (any, error) { return append(slices.Clone(items), value), nil }),
+	"Arrays.remove": arrayAnd(func(items []any, value any) (any, error) {
+		return slices.DeleteFunc(slices.Clone(items), func(item any) bool { return equal(item, value) }), nil
+	}),
+	"Arrays.contains": arrayAnd(func(items []any, value any) (any, error) {
+		return slices.ContainsFunc(items, func(item any) bool { return equal(item, value) }), nil
+	}),
+	"Arrays.clear":       array(func(items []any) (any, error) { return []any{}, nil }),
+	"Arrays.isEmpty":     array(func(items []any) (any, error) { return len(items) == 0, nil }),
+	"Arrays.size":        array(func(items []any) (any, error) { return float64(len(items)), nil }),
+	"Arrays.toCsvString": array(func(items []any) (any, error) { return toString(items), nil }),
+	"Arrays.flatten": func(env *Env, args []any) (any, error) {
+		items := make([]any, 0, len(args))
+		var flatten func(values []any)
+		flatten = func(values []any) {
+			for _, value := range values {
+				if nested, ok := value.([]any); ok {
+					flatten(nested)
+				} else {
+					items = append(items, value)
+				}
+			}
+		}
+		flatten(args)
+		return items, nil
+	},
+
+	"Convert.toInt": number(func(f float64) any { return math.Round(f) }),
+	"Convert.toNum": number(func(f float64) any { return f }),
+
+	"isMemberOfGroup": func(env *Env, args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, errors.New("takes one group ID")
+		}
+		return memberOf(env, func(g Group) bool { return g.ID == toString(args[0]) }), nil
+	},
+	"isMemberOfAnyGroup": func(env *Env, args []any) (any, error) {
+		var ids []string
+		for _, arg := range args {
+			ids = append(ids, strings.Split(toString(arg), ",")...)
+		}
+		return memberOf(env, func(g Group) bool { return slices.Contains(ids, g.ID) }), nil
+	},
+	"isMemberOfGroupName": groupName(func(name, arg string) (bool, error) { return name == arg, nil }),
+	"isMemberOfGroupNameStartsWith": groupName(func(name, arg string) (bool, error) {
+		return strings.HasPrefix(name, arg), nil
+	}),
+	"isMemberOfGroupNameContains": groupName(func(name, arg string) (bool, error) {
+		return strings.Contains(name, arg), nil
+	}),
+	"isMemberOfGroupNameRegex": groupName(func(name, arg string) (bool, error) {
+		pattern, err := regexp.Compile("^(?:" + arg + ")$")
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern: %w", err)
+		}
+		return pattern.MatchString(name), nil
+	}),
+}
+
+// Functions lists the names of the functions expressions can call.
+func Functions() []string {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stringsOf wraps a function of n arguments, each turned into a string.
+// A null argument is an error rather than the string "null".
+func stringsOf(n int, fn func([]string) (any, error)) function {
+	return func(env *Env, args []any) (any, error) {
+		if len(args) != n {
+			return nil, fmt.Errorf("takes %d arguments, got %d", n, len(args))
+		}
+		s := make([]string, n)
+		for i, arg := range args {
+			if arg == nil {
+				return nil, fmt.Errorf("argument %d is null", i+1)
+			}
+			s[i] = toString(arg)
+		}
+		return fn(s)
+	}
+}
+
+func array(fn func([]any) (any, error)) function {
+	return func(env *Env, args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes one array, got %d arguments", len(args))
+		}
+		items, err := toArray(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(items)
+	}
+}
+
+func arrayAnd(fn func([]any, any) (any, error)) function {
+	return func(env *Env, args []any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("takes an array and a value, got %d arguments", len(args))
+		}
+		items, err := toArray(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return fn(items, args[1])
+	}
+}
+
+// toArray takes null for an empty array, as Okta does for unset array
+// attributes.
+func toArray(value any) ([]any, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []any:
+		return v, nil
+	}
+	return nil, fmt.Errorf("needs an array, got %s", TypeOf(value))
+}
+
+func number(fn func(float64) any) function {
+	return func(env *Env, args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("takes one value, got %d arguments", len(args))
+		}
+		switch v := args[0].(type) {
+		case float64:
+			return fn(v), nil
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a number", v)
+			}
+			return fn(f), nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to a number", TypeOf(args[0]))
+	}
+}
+
+func groupName(match func(name, arg string) (bool, error)) function {
+	return func(env *Env, args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, errors.New("takes one group name")
+		}
+		arg := toString(args[0])
+		for _, group := range env.Groups {
+			ok, err := match(group.Name, arg)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+}
+
+func memberOf(env *Env, match func(Group) bool) bool {
+	return slices.ContainsFunc(env.Groups, match)
+}
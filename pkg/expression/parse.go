@@ -0,0 +1,491 @@
+// Package expression parses and evaluates the subset of the Okta Expression
+// Language used by group rules and profile mappings: attribute references
+// such as user.department, string, number, boolean and null literals, the
+// relational, logical and ternary operators, + for concatenation and
+// arithmetic, and the String, Arrays, Convert and group membership functions.
+package expression
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the longest expression Parse accepts, in characters.
+	MaxLength = 4096
+	// MaxDepth is how deeply Parse lets parentheses, lists, calls, indexes,
+	// conditionals and unary operators nest.
+	MaxDepth = 100
+	// MaxStringLength is the longest string, in bytes, an expression may
+	// build by concatenating or calling functions.
+	MaxStringLength = 64 << 10
+)
+
+// Error is a syntax error, or an error evaluating an expression, at a
+// position counted in characters from 1.
+type Error struct {
+	Position int
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Position)
+}
+
+// Expr is a parsed expression.
+type Expr struct {
+	root node
+}
+
+// Parse parses src, returning an *Error for an invalid expression, or one
+// longer than MaxLength or nested deeper than MaxDepth.
+func Parse(src string) (*Expr, error) {
+	if length := utf8.RuneCountInString(src); length > MaxLength {
+		return nil, &Error{
+			Position: MaxLength + 1,
+			Message:  fmt.Sprintf("expression is longer than %d characters", MaxLength),
+		}
+	}
+
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, &Error{Position: tok.pos, Message: fmt.Sprintf("unexpected %s", tok)}
+	}
+	return &Expr{root: root}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return "string " + strconv.Quote(t.text)
+	default:
+		return strconv.Quote(t.text)
+	}
+}
+
+// wordOperators are the operators that can also be written as words, in any
+// case.
+var wordOperators = map[string]string{
+	"and": "&&", "or": "||", "not": "!",
+	"eq": "==", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">=",
+}
+
+func lex(src string) ([]token, error) {
+	runes := []rune(src)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '"' || r == '\'':
+			var text strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, &Error{Position: pos, Message: "unterminated string"}
+				}
+				if runes[i] == r {
+					// A doubled quote stands for the quote itself.
+					if i+1 < len(runes) && runes[i+1] == r {
+						text.WriteRune(r)
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text.String(), pos: pos})
+
+		case unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: string(runes[start:i]), pos: pos})
+
+		case unicode.IsLetter(r) || r == '_' || r == '$':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_' || runes[i] == '$') {
+				i++
+			}
+			word := string(runes[start:i])
+			if op, ok := wordOperators[strings.ToLower(word)]; ok {
+				tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			} else {
+				tokens = append(tokens, token{kind: tokenIdent, text: word, pos: pos})
+			}
+
+		default:
+			op := ""
+			if i+1 < len(runes) {
+				switch pair := string(runes[i : i+2]); pair {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = pair
+				}
+			}
+			if op == "" {
+				if !strings.ContainsRune("()[]{},.?:!<>+-*/", r) {
+					return nil, &Error{Position: pos, Message: fmt.Sprintf("unexpected character %q", r)}
+				}
+				op = string(r)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: pos})
+			i += len([]rune(op))
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// The expression tree.
+type (
+	node interface{ position() int }
+
+	literal struct {
+		pos   int
+		value any
+	}
+	list struct {
+		pos   int
+		items []node
+	}
+	variable struct {
+		pos  int
+		name string
+	}
+	member struct {
+		pos  int
+		x    node
+		name string
+	}
+	index struct {
+		pos int
+		x   node
+		key node
+	}
+	call struct {
+		pos  int
+		name string
+		args []node
+	}
+	unary struct {
+		pos int
+		op  string
+		x   node
+	}
+	binary struct {
+		pos  int
+		op   string
+		x, y node
+	}
+	conditional struct {
+		pos               int
+		cond, then, other node
+	}
+)
+
+func (n *literal) position() int     { return n.pos }
+func (n *list) position() int        { return n.pos }
+func (n *variable) position() int    { return n.pos }
+func (n *member) position() int      { return n.pos }
+func (n *index) position() int       { return n.pos }
+func (n *call) position() int        { return n.pos }
+func (n *unary) position() int       { return n.pos }
+func (n *binary) position() int      { return n.pos }
+func (n *conditional) position() int { return n.pos }
+
+type parser struct {
+	tokens []token
+	next   int
+	depth  int
+}
+
+// nest enters one more level of nesting at tok, failing past MaxDepth. The
+// caller leaves it again with p.depth--.
+func (p *parser) nest(tok token) error {
+	if p.depth++; p.depth > MaxDepth {
+		return &Error{Position: tok.pos, Message: fmt.Sprintf("expression is nested more than %d levels deep", MaxDepth)}
+	}
+	return nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators.
+func (p *parser) accept(ops ...string) (token, bool) {
+	tok := p.peek()
+	if tok.kind == tokenOperator {
+		for _, op := range ops {
+			if tok.text == op {
+				return p.advance(), true
+			}
+		}
+	}
+	return tok, false
+}
+
+func (p *parser) expect(op string) error {
+	if tok, ok := p.accept(op); !ok {
+		return &Error{Position: tok.pos, Message: fmt.Sprintf("expected %q, found %s", op, tok)}
+	}
+	return nil
+}
+
+func (p *parser) ternary() (node, error) {
+	if err := p.nest(p.peek()); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+
+	cond, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	tok, ok := p.accept("?")
+	if !ok {
+		return cond, nil
+	}
+
+	then, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	other, err := p.ternary()
+	if err != nil {
+		return nil, err
+	}
+	return &conditional{pos: tok.pos, cond: cond, then: then, other: other}, nil
+}
+
+// precedence lists the binary operators from the loosest binding.
+var precedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">="},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *parser) binary(level int) (node, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok, ok := p.accept(precedence[level]...)
+		if !ok {
+			return x, nil
+		}
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &binary{pos: tok.pos, op: tok.text, x: x, y: y}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if tok, ok := p.accept("!", "-"); ok {
+		if err := p.nest(tok); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{pos: tok.pos, op: tok.text, x: x}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		switch tok := p.peek(); {
+		case tok.kind == tokenOperator && tok.text == ".":
+			p.advance()
+			name := p.advance()
+			if name.kind != tokenIdent {
+				return nil, &Error{Position: name.pos, Message: fmt.Sprintf("expected a name after \".\", found %s", name)}
+			}
+			x = &member{pos: name.pos, x: x, name: name.text}
+
+		case tok.kind == tokenOperator && tok.text == "[":
+			p.advance()
+			key, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			x = &index{pos: tok.pos, x: x, key: key}
+
+		case tok.kind == tokenOperator && tok.text == "(":
+			name, ok := functionName(x)
+			if !ok {
+				return nil, &Error{
+					Position: tok.pos,
+					Message:  "only functions such as String.toUpperCase can be called, not methods of values",
+				}
+			}
+			p.advance()
+			args, err := p.items(")")
+			if err != nil {
+				return nil, err
+			}
+			pos := x.position()
+			if qualified, ok := x.(*member); ok {
+				pos = qualified.x.position()
+			}
+			x = &call{pos: pos, name: name, args: args}
+
+		default:
+			return x, nil
+		}
+	}
+}
+
+// functionName is the name of the function x refers to when called: a bare
+// name such as isMemberOfGroup, or one qualified by its class such as
+// String.len.
+func functionName(x node) (string, bool) {
+	switch n := x.(type) {
+	case *variable:
+		return n.name, true
+	case *member:
+		if class, ok := n.x.(*variable); ok {
+			return class.name + "." + n.name, true
+		}
+	}
+	return "", false
+}
+
+// items parses a comma separated list of expressions up to the closing
+// operator.
+func (p *parser) items(closing string) ([]node, error) {
+	var items []node
+	if _, ok := p.accept(closing); ok {
+		return items, nil
+	}
+	for {
+		item, err := p.ternary()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if _, ok := p.accept(","); !ok {
+			return items, p.expect(closing)
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.advance()
+
+	switch tok.kind {
+	case tokenString:
+		return &literal{pos: tok.pos, value: tok.text}, nil
+
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, &Error{Position: tok.pos, Message: fmt.Sprintf("invalid number %s", tok.text)}
+		}
+		return &literal{pos: tok.pos, value: value}, nil
+
+	case tokenIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return &literal{pos: tok.pos, value: true}, nil
+		case "false":
+			return &literal{pos: tok.pos, value: false}, nil
+		case "null":
+			return &literal{pos: tok.pos, value: nil}, nil
+		}
+		return &variable{pos: tok.pos, name: tok.text}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			x, err := p.ternary()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		case "{":
+			items, err := p.items("}")
+			if err != nil {
+				return nil, err
+			}
+			return &list{pos: tok.pos, items: items}, nil
+		}
+	}
+
+	return nil, &Error{Position: tok.pos, Message: fmt.Sprintf("unexpected %s", tok)}
+}
@@ -0,0 +1,94 @@
+package expression
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		wantErr     string
+		wantPos     int
+		maxDuration time.Duration
+	}{
+		{name: "attribute", src: "user.department"},
+		{name: "comparison", src: `user.department == "Engineering"`},
+		{name: "word operators", src: `user.a eq "x" AND NOT (user.b lt 3)`},
+		{name: "ternary", src: `user.level > 3 ? "senior" : "junior"`},
+		{name: "nested ternary", src: `a.x ? a.y ? 1 : 2 : 3`},
+		{name: "function call", src: `String.toUpperCase(user.firstName)`},
+		{name: "list and index", src: `{"a", "b"}[1]`},
+		{name: "attribute index", src: `user["department"]`},
+		{name: "escaped quote", src: `"it''s" + 'a \"b\"'`},
+		{name: "decimal", src: `1.5 * 2`},
+		{name: "empty", src: "", wantErr: "unexpected end of expression", wantPos: 1},
+		{name: "unterminated string", src: `user.a == "x`, wantErr: "unterminated string", wantPos: 11},
+		{name: "unexpected character", src: "user.a # 1", wantErr: "unexpected character '#'", wantPos: 8},
+		{name: "missing closing parenthesis", src: "(user.a", wantErr: `expected ")"`, wantPos: 8},
+		{name: "trailing token", src: "user.a user.b", wantErr: `unexpected "user"`, wantPos: 8},
+		{name: "member without name", src: "user.", wantErr: `expected a name after "."`, wantPos: 6},
+		{name: "method of value", src: `user.name.trim()`, wantErr: "only functions", wantPos: 15},
+		{name: "ternary without else", src: "a ? b", wantErr: `expected ":"`, wantPos: 6},
+		{name: "dangling operator", src: "user.a ==", wantErr: "unexpected end of expression", wantPos: 10},
+
+		{name: "nesting at the limit", src: nested("(", "x", ")", MaxDepth-1)},
+		{name: "nested parentheses", src: nested("(", "x", ")", MaxDepth+1), wantErr: "nested more than"},
+		{name: "nested lists", src: nested("{", "x", "}", MaxDepth+1), wantErr: "nested more than"},
+		{name: "nested calls", src: nested("String.len(", "x", ")", MaxDepth+1), wantErr: "nested more than"},
+		{name: "nested indexes", src: "x" + strings.Repeat("[x", MaxDepth+1) + strings.Repeat("]", MaxDepth+1), wantErr: "nested more than"},
+		{name: "chained unary operators", src: strings.Repeat("!", MaxDepth+1) + "x", wantErr: "nested more than"},
+		{name: "chained ternaries", src: strings.Repeat("x ? x : ", MaxDepth+1) + "x", wantErr: "nested more than"},
+		{
+			name: "too long", src: strings.Repeat("x", MaxLength+1),
+			wantErr: "longer than", wantPos: MaxLength + 1,
+		},
+		{
+			name: "many operators are lexed in linear time", src: strings.Repeat("((", MaxLength/2),
+			wantErr: "nested more than", maxDuration: time.Second,
+		},
+		{
+			name: "long flat expression", src: "x" + strings.Repeat(" || x", (MaxLength-1)/5),
+			maxDuration: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			expr, err := Parse(tt.src)
+			if tt.maxDuration > 0 && time.Since(start) > tt.maxDuration {
+				t.Errorf("Parse() took %s, want at most %s", time.Since(start), tt.maxDuration)
+			}
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse() error = %v", err)
+				}
+				if expr == nil {
+					t.Fatal("Parse() = nil")
+				}
+				return
+			}
+
+			var exprErr *Error
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("Parse() error = %v, want an *Error", err)
+			}
+			if !strings.Contains(exprErr.Message, tt.wantErr) {
+				t.Errorf("Parse() error = %q, want one containing %q", exprErr.Message, tt.wantErr)
+			}
+			if tt.wantPos != 0 && exprErr.Position != tt.wantPos {
+				t.Errorf("Parse() error position = %d, want %d", exprErr.Position, tt.wantPos)
+			}
+		})
+	}
+}
+
+// nested wraps inner in depth pairs of open and close.
+func nested(open, inner, close string, depth int) string {
+	return strings.Repeat(open, depth) + inner + strings.Repeat(close, depth)
+}
@@ -0,0 +1,165 @@
+// Package hooks lets a deployment run its own code before and after user and
+// group operations, such as pushing every new group to an internal CMDB,
+// without changing the services or handlers. Hooks are registered at compile
+// time, from an init function in a file the deployment adds to the server's
+// main package:
+//
+//	func init() {
+//		hooks.After(hooks.CreateGroup, "cmdb", func(ctx context.Context, event *hooks.Event) error {
+//			group := event.Result.(*models.Group)
+//			return cmdb.Push(ctx, group.ID, group.Name)
+//		})
+//	}
+//
+// A before hook that returns an error rejects the operation before Okta is
+// called. An after hook runs once the operation succeeded; its error is
+// logged and does not change the outcome, as the change has been made.
+package hooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Operation names an operation hooks attach to.
+type Operation string
+
+// Operations hooks can attach to. Input and Result of the Event for each are
+// the service's request and result types.
+const (
+	// CreateUser: Input *models.CreateUserRequest, Result *models.User.
+	CreateUser Operation = "CreateUser"
+	// UpdateUser: Input *models.UpdateUserRequest, Result *models.User.
+	UpdateUser Operation = "UpdateUser"
+	// DeleteUser: no Input or Result.
+	DeleteUser Operation = "DeleteUser"
+	// CreateGroup: Input *models.CreateGroupRequest, Result *models.Group.
+	CreateGroup Operation = "CreateGroup"
+	// UpdateGroup: Input *models.UpdateGroupRequest, Result *models.Group.
+	UpdateGroup Operation = "UpdateGroup"
+	// DeleteGroup: no Input or Result.
+	DeleteGroup Operation = "DeleteGroup"
+	// AddGroupMember: MemberID is the user added; no Input or Result.
+	AddGroupMember Operation = "AddGroupMember"
+	// RemoveGroupMember: MemberID is the user removed; no Input or Result.
+	RemoveGroupMember Operation = "RemoveGroupMember"
+)
+
+// Event describes an operation to its hooks.
+type Event struct {
+	Operation Operation
+	// ResourceID is the user or group acted on. It is empty before a create.
+	ResourceID string
+	// MemberID is the user added to or removed from a group.
+	MemberID string
+	// Input is the operation's request. Before hooks may change it.
+	Input any
+	// Result is what the operation returned. It is only set for after hooks.
+	Result any
+}
+
+// Hook runs for an operation.
+type Hook func(ctx context.Context, event *Event) error
+
+// RejectedError is returned when a before hook rejects an operation. Its
+// message is the hook's error, which is shown to the client, so hooks should
+// say what to change.
+type RejectedError struct {
+	Hook      string
+	Operation Operation
+	Err       error
+}
+
+func (e *RejectedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Registry holds the hooks of each operation. A nil Registry has no hooks.
+type Registry struct {
+	mu     sync.RWMutex
+	before map[Operation][]namedHook
+	after  map[Operation][]namedHook
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		before: make(map[Operation][]namedHook),
+		after:  make(map[Operation][]namedHook),
+	}
+}
+
+// Default is the registry the server runs hooks from.
+var Default = NewRegistry()
+
+// Before registers hook with Default to run before op.
+func Before(op Operation, name string, hook Hook) {
+	Default.Before(op, name, hook)
+}
+
+// After registers hook with Default to run after op succeeded.
+func After(op Operation, name string, hook Hook) {
+	Default.After(op, name, hook)
+}
+
+// Before registers hook to run before op. Hooks run in the order they were
+// registered; name identifies the hook in logs and errors.
+func (r *Registry) Before(op Operation, name string, hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.before[op] = append(r.before[op], namedHook{name: name, hook: hook})
+}
+
+// After registers hook to run after op succeeded. Hooks run in the order
+// they were registered; name identifies the hook in logs and errors.
+func (r *Registry) After(op Operation, name string, hook Hook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.after[op] = append(r.after[op], namedHook{name: name, hook: hook})
+}
+
+// RunBefore runs the before hooks of the event's operation and stops at the
+// first that fails, returning a *RejectedError.
+func (r *Registry) RunBefore(ctx context.Context, event *Event) error {
+	for _, h := range r.hooks(event.Operation, false) {
+		if err := h.hook(ctx, event); err != nil {
+			return &RejectedError{Hook: h.name, Operation: event.Operation, Err: err}
+		}
+	}
+	return nil
+}
+
+// RunAfter runs every after hook of the event's operation and returns their
+// errors joined, each prefixed with its hook's name.
+func (r *Registry) RunAfter(ctx context.Context, event *Event) error {
+	var errs []error
+	for _, h := range r.hooks(event.Operation, true) {
+		if err := h.hook(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", h.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) hooks(op Operation, after bool) []namedHook {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if after {
+		return r.after[op]
+	}
+	return r.before[op]
+}
@@ -0,0 +1,165 @@
+// Package iam embeds the platform's Okta orchestration in other Go programs,
+// without running the HTTP server. It exposes the same services the API is
+// built on, behind interfaces, with their own rate limit handling, circuit
+// breakers, separation of duties checks, hooks and sagas:
+//
+//	client, err := iam.New(ctx, &iam.Config{Domain: "example.okta.com", APIToken: token})
+//	if err != nil {
+//		return err
+//	}
+//	saga, err := client.Provisioning.OnboardUser(ctx, &iam.OnboardUserRequest{...})
+//
+// The package does not link chi. The services log with zap, but callers need
+// not use it: logs go to an optional slog.Logger.
+package iam
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/secrets"
+	audit_service "github.com/iamBelugaa/iam/internal/services/audit"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	guest_service "github.com/iamBelugaa/iam/internal/services/guest"
+	provisioning_service "github.com/iamBelugaa/iam/internal/services/provisioning"
+	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	sod_service "github.com/iamBelugaa/iam/internal/services/sod"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+	"github.com/iamBelugaa/iam/pkg/hooks"
+	"github.com/iamBelugaa/iam/pkg/okta"
+)
+
+// The secret names the credentials in Config are stored under.
+const (
+	apiTokenSecret   = "api-token"
+	privateKeySecret = "private-key"
+)
+
+// Config selects the org and how to authenticate with it.
+type Config struct {
+	// Domain is the org's domain, such as example.okta.com.
+	Domain string
+	// APIToken is an SSWS API token. It is used unless ClientID is set.
+	APIToken string
+	// ClientID, when set, authenticates as an OAuth service app with the PEM
+	// PrivateKey, whose key ID is PrivateKeyID, asking for Scopes.
+	ClientID     string
+	PrivateKey   string
+	PrivateKeyID string
+	Scopes       []string
+	// CacheTTL enables the response cache when non-zero.
+	CacheTTL time.Duration
+	// RateLimitMaxRetries is how often a rate limited request is retried once
+	// the limit resets. Zero does not retry.
+	RateLimitMaxRetries int
+	// MemoryOrg, when set, serves the org in process instead of Okta, such as
+	// the org of memory.NewOrg from pkg/okta/memory, seeded with sample users
+	// and groups. Domain and the credentials are not needed.
+	MemoryOrg http.Handler
+	// Logger receives the services' logs. Nil discards them.
+	Logger *slog.Logger
+	// Hooks run around user and group operations. Nil runs none.
+	Hooks *hooks.Registry
+}
+
+// Client is an embedded IAM platform for one org.
+type Client struct {
+	Users        Users
+	Groups       Groups
+	Roles        Roles
+	Provisioning Provisioning
+	Sagas        Sagas
+
+	backend okta.Backend
+}
+
+// New creates the services for the org in cfg. It does not call Okta; use
+// TestConnection to check the org answers.
+func New(ctx context.Context, cfg *Config) (*Client, error) {
+	log := newLogger(cfg.Logger)
+
+	oktaCfg := &config.OktaConfig{
+		Name:                "primary",
+		Backend:             config.OktaBackendOkta,
+		Domain:              cfg.Domain,
+		APITokenSecret:      apiTokenSecret,
+		ClientID:            cfg.ClientID,
+		PrivateKeySecret:    privateKeySecret,
+		PrivateKeyID:        cfg.PrivateKeyID,
+		Scopes:              cfg.Scopes,
+		CacheTTL:            cfg.CacheTTL,
+		RateLimitMaxRetries: cfg.RateLimitMaxRetries,
+		BreakerFailures:     5,
+		BreakerOpenTimeout:  30 * time.Second,
+		BudgetSyncShare:     25,
+		BudgetBatchShare:    15,
+		BudgetMaxWait:       30 * time.Second,
+	}
+	switch {
+	case cfg.MemoryOrg != nil:
+		oktaCfg.Backend = config.OktaBackendMemory
+	case cfg.Domain == "":
+		return nil, fmt.Errorf("iam: domain is required")
+	case cfg.ClientID == "" && cfg.APIToken == "":
+		return nil, fmt.Errorf("iam: an API token, or a client ID and private key, are required")
+	}
+
+	store := secrets.NewStore(log, staticSecrets{
+		apiTokenSecret:   cfg.APIToken,
+		privateKeySecret: cfg.PrivateKey,
+	})
+	backend, err := okta.New(ctx, oktaCfg, store, func() http.Handler { return cfg.MemoryOrg })
+	if err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+
+	sdk := backend.SDK()
+	auditSvc := audit_service.New(log, nil, nil)
+	usersSvc := user_service.New(log, sdk, cfg.Hooks)
+	sodSvc, err := sod_service.New(log, sdk, nil, auditSvc)
+	if err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+	guestsSvc, err := guest_service.New(log, &config.GuestsConfig{}, nil, usersSvc, auditSvc)
+	if err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+	groupsSvc, err := group_service.New(log, sdk, nil, sodSvc, guestsSvc, nil, cfg.Hooks)
+	if err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+	sagasSvc, err := saga_service.New(log, &config.ReconciliationConfig{MaxAttempts: 10}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("iam: %w", err)
+	}
+
+	return &Client{
+		Users:        usersSvc,
+		Groups:       groupsSvc,
+		Roles:        role_service.New(log, sdk),
+		Provisioning: provisioning_service.New(log, sagasSvc, usersSvc, groupsSvc),
+		Sagas:        sagasSvc,
+		backend:      backend,
+	}, nil
+}
+
+// TestConnection checks that the org answers.
+func (c *Client) TestConnection(ctx context.Context) error {
+	return c.backend.TestConnection(ctx)
+}
+
+// staticSecrets serves the credentials given in Config.
+type staticSecrets map[string]string
+
+func (s staticSecrets) Get(_ context.Context, name string) (string, error) {
+	value, ok := s[name]
+	if !ok || value == "" {
+		return "", secrets.ErrSecretNotFound
+	}
+	return value, nil
+}
@@ -0,0 +1,114 @@
+package iam
+
+import (
+	"context"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/models"
+	group_service "github.com/iamBelugaa/iam/internal/services/group"
+	provisioning_service "github.com/iamBelugaa/iam/internal/services/provisioning"
+	role_service "github.com/iamBelugaa/iam/internal/services/role"
+	saga_service "github.com/iamBelugaa/iam/internal/services/saga"
+	user_service "github.com/iamBelugaa/iam/internal/services/user"
+)
+
+// The types the services take and return. They are the ones the HTTP API
+// serves, so they marshal to the same JSON.
+type (
+	User               = models.User
+	CreateUserRequest  = models.CreateUserRequest
+	UpdateUserRequest  = models.UpdateUserRequest
+	Group              = models.Group
+	CreateGroupRequest = models.CreateGroupRequest
+	UpdateGroupRequest = models.UpdateGroupRequest
+	Role               = models.Role
+	CreateRoleRequest  = models.CreateRoleRequest
+	UpdateRoleRequest  = models.UpdateRoleRequest
+	Saga               = models.Saga
+	SagaStep           = models.SagaStep
+	SagaFilter         = models.SagaFilter
+	OnboardUserRequest = models.OnboardUserRequest
+	CreateTeamRequest  = models.CreateTeamRequest
+	Reconciliation     = models.Reconciliation
+)
+
+// Errors the services return, for matching with errors.Is.
+var (
+	ErrInvalidJoinPolicy = group_service.ErrInvalidJoinPolicy
+	ErrSagaNotFound      = saga_service.ErrSagaNotFound
+	ErrReconciling       = saga_service.ErrReconciling
+	ErrNotReconciling    = saga_service.ErrNotReconciling
+	ErrNameRequired      = provisioning_service.ErrNameRequired
+	ErrNoGroups          = provisioning_service.ErrNoGroups
+)
+
+// Users manages the org's users.
+type Users interface {
+	CreateUser(ctx context.Context, req *CreateUserRequest) (*User, error)
+	GetUser(ctx context.Context, userID string) (*User, error)
+	GetUsers(ctx context.Context) ([]*User, error)
+	UpdateUser(ctx context.Context, userID string, req *UpdateUserRequest) (*User, error)
+	// DeleteUser deactivates the user, then deletes them.
+	DeleteUser(ctx context.Context, userID string) error
+	ActivateUser(ctx context.Context, userID string) error
+	DeactivateUser(ctx context.Context, userID string) error
+	SuspendUser(ctx context.Context, userID string) error
+	UnsuspendUser(ctx context.Context, userID string) error
+	ExpireUserPassword(ctx context.Context, userID string) error
+	GetUserGroups(ctx context.Context, userID string) ([]*Group, error)
+}
+
+// Groups manages the org's groups and their members.
+type Groups interface {
+	CreateGroup(ctx context.Context, req *CreateGroupRequest) (*Group, error)
+	GetGroup(ctx context.Context, groupID string) (*Group, error)
+	GetGroups(ctx context.Context) ([]*Group, error)
+	UpdateGroup(ctx context.Context, groupID string, req *UpdateGroupRequest) (*Group, error)
+	DeleteGroup(ctx context.Context, groupID string) error
+	// AddUserToGroup adds a member, until expiresAt when it is set.
+	AddUserToGroup(ctx context.Context, groupID, userID string, expiresAt *time.Time) error
+	RemoveUserFromGroup(ctx context.Context, groupID, userID string) error
+	GetGroupMembers(ctx context.Context, groupID string) ([]*User, error)
+}
+
+// Roles manages the org's custom admin roles and their assignments.
+type Roles interface {
+	CreateRole(ctx context.Context, req *CreateRoleRequest) (*Role, error)
+	GetRole(ctx context.Context, roleID string) (*Role, error)
+	GetRoles(ctx context.Context) ([]*Role, error)
+	UpdateRole(ctx context.Context, roleID string, req *UpdateRoleRequest) (*Role, error)
+	DeleteRole(ctx context.Context, roleID string) error
+	AssignRoleToUser(ctx context.Context, userID, roleID string) error
+	UnassignRoleFromUser(ctx context.Context, userID, roleID string) error
+	AssignRoleToGroup(ctx context.Context, groupID, roleID string) error
+	UnassignRoleFromGroup(ctx context.Context, groupID, roleID string) error
+	GetUserRoles(ctx context.Context, userID string) ([]*Role, error)
+	GetGroupRoles(ctx context.Context, groupID string) ([]*Role, error)
+}
+
+// Provisioning runs operations that make several changes as sagas, undoing
+// the changes made when a later one fails. The saga is returned whether or
+// not it succeeded.
+type Provisioning interface {
+	OnboardUser(ctx context.Context, req *OnboardUserRequest) (*Saga, error)
+	CreateTeam(ctx context.Context, req *CreateTeamRequest) (*Saga, error)
+}
+
+// Sagas reports the sagas Provisioning ran.
+type Sagas interface {
+	GetSaga(ctx context.Context, sagaID string) (*Saga, error)
+	GetSagas(ctx context.Context, filter *SagaFilter) []*Saga
+	// Pending lists the sagas left short of their intended state. Call
+	// Reconcile periodically to retry their remaining steps.
+	Pending(ctx context.Context) []*Reconciliation
+	Reconcile(ctx context.Context)
+	Retry(ctx context.Context, sagaID string) (*Reconciliation, error)
+}
+
+var (
+	_ Users        = (*user_service.Service)(nil)
+	_ Groups       = (*group_service.Service)(nil)
+	_ Roles        = (*role_service.Service)(nil)
+	_ Provisioning = (*provisioning_service.Service)(nil)
+	_ Sagas        = (*saga_service.Service)(nil)
+)
@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader carries the correlation ID of a request, both from and to
+// clients and on the calls made to serve it.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds the IDs accepted from clients, which end up in
+// every log line of the request.
+const maxRequestIDLength = 128
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithRequestID returns a context carrying id and a logger that adds it to
+// every line as "requestId".
+func WithRequestID(ctx context.Context, log *zap.SugaredLogger, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return context.WithValue(ctx, loggerKey, log.With("requestId", id))
+}
+
+// FromContext returns the request-scoped logger in ctx, or fallback outside
+// of a request.
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if log, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return log
+	}
+	return fallback
+}
+
+// RequestID returns the correlation ID in ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// NewRequestID keeps a client's ID when it is reasonable to log and echo
+// back, and otherwise generates one.
+func NewRequestID(clientID string) string {
+	if clientID == "" || len(clientID) > maxRequestIDLength {
+		return uuid.NewString()
+	}
+	for _, c := range clientID {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.' || c == ':') {
+			return uuid.NewString()
+		}
+	}
+	return clientID
+}
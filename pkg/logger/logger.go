@@ -9,8 +9,10 @@ import (
 
 // Creates and configures a new Zap SugaredLogger.
 // It sets up a production-ready logger with JSON encoding, ISO8601 timestamps,
-// and includes service name and process ID as initial fields.
-func New(service string, outputPaths ...string) *zap.SugaredLogger {
+// and includes service name and process ID as initial fields. Changing level
+// changes the minimum level the logger writes while it is in use, and every
+// line is passed through redactor before it is written.
+func New(service string, level zap.AtomicLevel, redactor *Redactor, outputPaths ...string) *zap.SugaredLogger {
 	encoderCfg := zap.NewProductionEncoderConfig()
 
 	encoderCfg.TimeKey = "timestamp"
@@ -19,7 +21,7 @@ func New(service string, outputPaths ...string) *zap.SugaredLogger {
 	// Initialize the Zap configuration. This struct holds all the settings
 	// for building the logger.
 	config := zap.Config{
-		Level:             zap.NewAtomicLevelAt(zap.InfoLevel),
+		Level:             level,
 		Development:       false,
 		DisableCaller:     false,
 		DisableStacktrace: false,
@@ -36,5 +38,5 @@ func New(service string, outputPaths ...string) *zap.SugaredLogger {
 		config.OutputPaths = outputPaths
 	}
 
-	return zap.Must(config.Build()).Sugar()
+	return zap.Must(config.Build(zap.WrapCore(redactor.core))).Sugar()
 }
@@ -0,0 +1,210 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Mask replaces the values redacted from logs and audit entries.
+const Mask = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// phonePattern matches international numbers, which start with +, and
+	// North American ones such as (555) 010-4477, but not dates or IDs.
+	phonePattern = regexp.MustCompile(`\+\d[\d\s().-]{6,}\d|\(?\b\d{3}\)?[\s.-]\d{3}[\s.-]\d{4}\b`)
+)
+
+// Redactor masks personal data in log fields and audit details. Fields whose
+// key is one of the masked names, in any case, have their value replaced,
+// including the fields of maps and structs logged as a whole, by their JSON
+// names. In strict mode keys that merely contain a masked name are masked
+// too, so "managerEmail" goes with "email", and email addresses and phone
+// numbers are scrubbed from every message and string value. Like
+// zap.AtomicLevel, its rules may change while loggers built with it are in
+// use; they apply to the lines and loggers that follow.
+type Redactor struct {
+	rules atomic.Pointer[redactRules]
+}
+
+type redactRules struct {
+	fields []string
+	strict bool
+}
+
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// Configure sets the field names to mask and whether strict mode is on.
+func (r *Redactor) Configure(fields []string, strict bool) {
+	rules := &redactRules{strict: strict}
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			rules.fields = append(rules.fields, strings.ToLower(field))
+		}
+	}
+	r.rules.Store(rules)
+}
+
+// Map returns a copy of m with its personal data masked, at any depth. A nil
+// Redactor returns m as it is.
+func (r *Redactor) Map(m map[string]any) map[string]any {
+	rules := r.load()
+	if rules == nil || m == nil {
+		return m
+	}
+
+	masked, ok := rules.value(m).(map[string]any)
+	if !ok {
+		return m
+	}
+	return masked
+}
+
+func (r *Redactor) load() *redactRules {
+	if r == nil {
+		return nil
+	}
+	rules := r.rules.Load()
+	if rules == nil || len(rules.fields) == 0 && !rules.strict {
+		return nil
+	}
+	return rules
+}
+
+// core wraps a zap core so every line it writes is redacted.
+func (r *Redactor) core(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core, redactor: r}
+}
+
+type redactingCore struct {
+	zapcore.Core
+	redactor *Redactor
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(c.redactor.load().fieldsOf(fields)), redactor: c.redactor}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	rules := c.redactor.load()
+	if rules != nil && rules.strict {
+		entry.Message = scrub(entry.Message)
+	}
+	return c.Core.Write(entry, rules.fieldsOf(fields))
+}
+
+func (rules *redactRules) fieldsOf(fields []zapcore.Field) []zapcore.Field {
+	if rules == nil || len(fields) == 0 {
+		return fields
+	}
+
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = rules.field(field)
+	}
+	return redacted
+}
+
+func (rules *redactRules) field(field zapcore.Field) zapcore.Field {
+	if rules.masked(field.Key) {
+		return zap.String(field.Key, Mask)
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		if rules.strict {
+			field.String = scrub(field.String)
+		}
+	case zapcore.ErrorType, zapcore.StringerType:
+		if rules.strict {
+			return zap.String(field.Key, scrub(fmt.Sprint(field.Interface)))
+		}
+	case zapcore.ReflectType:
+		return zap.Any(field.Key, rules.value(field.Interface))
+	}
+	return field
+}
+
+// value returns v with its personal data masked. Maps, slices and structs
+// are read through their JSON encoding, so struct fields are matched by
+// their JSON names.
+func (rules *redactRules) value(v any) any {
+	switch v := v.(type) {
+	case nil, bool, int, int64, float64:
+		return v
+	case string:
+		if rules.strict {
+			return scrub(v)
+		}
+		return v
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		if rules.strict {
+			return Mask
+		}
+		return v
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded any
+	if err := decoder.Decode(&decoded); err != nil {
+		return v
+	}
+	return rules.walk(decoded)
+}
+
+func (rules *redactRules) walk(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, child := range v {
+			if rules.masked(key) {
+				v[key] = Mask
+				continue
+			}
+			v[key] = rules.walk(child)
+		}
+	case []any:
+		for i, child := range v {
+			v[i] = rules.walk(child)
+		}
+	case string:
+		if rules.strict {
+			return scrub(v)
+		}
+	}
+	return v
+}
+
+func (rules *redactRules) masked(key string) bool {
+	key = strings.ToLower(key)
+	for _, field := range rules.fields {
+		if key == field || rules.strict && strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrub masks the email addresses and phone numbers in s.
+func scrub(s string) string {
+	s = emailPattern.ReplaceAllString(s, Mask)
+	return phonePattern.ReplaceAllString(s, Mask)
+}
@@ -0,0 +1,51 @@
+package objectstore
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps objects in memory until the process exits, for tests and
+// for running the server without durable storage.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]*Object
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]*Object)}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = &Object{Key: key, ContentType: contentType, Data: slices.Clone(data), Modified: time.Now()}
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Object, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	object, ok := s.objects[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	copied := *object
+	copied.Data = slices.Clone(object.Data)
+	return &copied, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.objects[key]; !ok {
+		return ErrNotFound
+	}
+	delete(s.objects, key)
+	return nil
+}
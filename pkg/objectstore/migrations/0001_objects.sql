@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS iam_objects (
+    namespace    TEXT        NOT NULL,
+    key          TEXT        NOT NULL,
+    content_type TEXT        NOT NULL DEFAULT '',
+    data         BYTEA       NOT NULL,
+    modified     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (namespace, key)
+);
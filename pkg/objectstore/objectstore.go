@@ -0,0 +1,125 @@
+package objectstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+// Object is a stored blob together with its metadata.
+type Object struct {
+	Key         string
+	ContentType string
+	Data        []byte
+	Modified    time.Time
+}
+
+// Store is a minimal object storage abstraction. Implementations backed by
+// S3-compatible services can be swapped in without touching callers.
+type Store interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FileStore keeps objects on the local filesystem under a root directory,
+// with the content type in a ".meta" file next to each object.
+type FileStore struct {
+	root string
+}
+
+type fileMeta struct {
+	ContentType string `json:"contentType"`
+}
+
+func NewFileStore(root string) (*FileStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create object store directory: %w", err)
+	}
+	return &FileStore{root: root}, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	meta, err := json.Marshal(fileMeta{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to encode object metadata: %w", err)
+	}
+
+	if err := os.WriteFile(path+".meta", meta, 0o640); err != nil {
+		return fmt.Errorf("failed to write object metadata: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0o640); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileStore) Get(ctx context.Context, key string) (*Object, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat object: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	var meta fileMeta
+	if raw, err := os.ReadFile(path + ".meta"); err == nil {
+		_ = json.Unmarshal(raw, &meta)
+	}
+
+	return &Object{Key: key, ContentType: meta.ContentType, Data: data, Modified: info.ModTime()}, nil
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); errors.Is(err, os.ErrNotExist) {
+		return ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	_ = os.Remove(path + ".meta")
+	return nil
+}
+
+// path resolves key inside the root, rejecting keys that would escape it.
+func (s *FileStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.HasSuffix(cleaned, ".meta") {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.root, cleaned), nil
+}
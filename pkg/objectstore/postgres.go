@@ -0,0 +1,183 @@
+package objectstore
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// migrationLock is the advisory lock held while migrating, so replicas
+// starting together do not apply the same migration twice.
+const migrationLock = 7_215_340_118
+
+// PostgresOptions locate the database and size its connection pool. Zero
+// values keep pgx's defaults.
+type PostgresOptions struct {
+	URL             string
+	MaxConns        int
+	MinConns        int
+	MaxConnIdleTime time.Duration
+	MaxConnLifetime time.Duration
+}
+
+// OpenPostgres connects a pool to the database in opts and brings its schema
+// up to date. Every PostgresStore of the server shares the pool.
+func OpenPostgres(ctx context.Context, opts PostgresOptions) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(opts.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Postgres URL: %w", err)
+	}
+	if opts.MaxConns > 0 {
+		poolConfig.MaxConns = int32(opts.MaxConns)
+	}
+	if opts.MinConns > 0 {
+		poolConfig.MinConns = int32(opts.MinConns)
+	}
+	if opts.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = opts.MaxConnLifetime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Postgres pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to Postgres: %w", err)
+	}
+	if err := migrate(ctx, pool); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// migrate applies the migrations not applied yet, in order, each in its own
+// transaction. A migration's version is the number its file name starts
+// with.
+func migrate(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire a Postgres connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLock); err != nil {
+		return fmt.Errorf("failed to lock the schema for migration: %w", err)
+	}
+	defer conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLock)
+
+	if _, err := conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS iam_schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied TIMESTAMPTZ NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create the migrations table: %w", err)
+	}
+
+	var current int
+	if err := conn.QueryRow(ctx, "SELECT COALESCE(MAX(version), 0) FROM iam_schema_migrations").Scan(&current); err != nil {
+		return fmt.Errorf("failed to read the schema version: %w", err)
+	}
+
+	names, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(strings.TrimPrefix(name, "migrations/"), "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return fmt.Errorf("migration %s has no version", name)
+		}
+		if version <= current {
+			continue
+		}
+
+		script, err := migrations.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		err = pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, string(script)); err != nil {
+				return err
+			}
+			_, err := tx.Exec(ctx, "INSERT INTO iam_schema_migrations (version) VALUES ($1)", version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// PostgresStore keeps objects in a Postgres table under a namespace, so the
+// stores of every feature can share one database.
+type PostgresStore struct {
+	pool      *pgxpool.Pool
+	namespace string
+}
+
+func NewPostgresStore(pool *pgxpool.Pool, namespace string) *PostgresStore {
+	return &PostgresStore{pool: pool, namespace: namespace}
+}
+
+func (s *PostgresStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	if key == "" {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+
+	_, err := s.pool.Exec(ctx, `INSERT INTO iam_objects (namespace, key, content_type, data, modified)
+		VALUES ($1, $2, $3, $4, now())
+		ON CONFLICT (namespace, key) DO UPDATE
+		SET content_type = EXCLUDED.content_type, data = EXCLUDED.data, modified = EXCLUDED.modified`,
+		s.namespace, key, contentType, data,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (*Object, error) {
+	object := &Object{Key: key}
+	err := s.pool.QueryRow(ctx,
+		"SELECT content_type, data, modified FROM iam_objects WHERE namespace = $1 AND key = $2",
+		s.namespace, key,
+	).Scan(&object.ContentType, &object.Data, &object.Modified)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return object, nil
+}
+
+func (s *PostgresStore) Delete(ctx context.Context, key string) error {
+	tag, err := s.pool.Exec(ctx, "DELETE FROM iam_objects WHERE namespace = $1 AND key = $2", s.namespace, key)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
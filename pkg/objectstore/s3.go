@@ -0,0 +1,181 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	aws_config "github.com/aws/aws-sdk-go-v2/config"
+)
+
+// S3Store keeps objects in an S3 bucket, or a bucket of an S3-compatible
+// service such as MinIO when an endpoint is given, under a key prefix.
+// Credentials and, unless set, the region come from the standard AWS
+// environment, shared config or instance role.
+type S3Store struct {
+	client      *http.Client
+	credentials aws.CredentialsProvider
+	signer      *v4.Signer
+	region      string
+	base        string
+}
+
+// S3Options locate the bucket. Endpoint is only needed for services other
+// than AWS, which are addressed path-style.
+type S3Options struct {
+	Bucket   string
+	Prefix   string
+	Region   string
+	Endpoint string
+}
+
+func NewS3Store(ctx context.Context, opts S3Options) (*S3Store, error) {
+	awsConfig, err := aws_config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS configuration: %w", err)
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = awsConfig.Region
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no AWS region configured for bucket %s", opts.Bucket)
+	}
+
+	base := fmt.Sprintf("https://%s.s3.%s.amazonaws.com", opts.Bucket, region)
+	if opts.Endpoint != "" {
+		base = strings.TrimSuffix(opts.Endpoint, "/") + "/" + opts.Bucket
+	}
+	if prefix := strings.Trim(opts.Prefix, "/"); prefix != "" {
+		base += "/" + prefix
+	}
+
+	return &S3Store{
+		client:      &http.Client{Timeout: 30 * time.Second},
+		credentials: awsConfig.Credentials,
+		signer:      v4.NewSigner(),
+		region:      region,
+		base:        base,
+	}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key, contentType string, data []byte) error {
+	response, err := s.do(ctx, http.MethodPut, key, data, func(r *http.Request) {
+		r.Header.Set("Content-Type", contentType)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to write object: %w", s3Error(response))
+	}
+	return nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (*Object, error) {
+	response, err := s.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("failed to read object: %w", s3Error(response))
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	modified, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+	return &Object{Key: key, ContentType: response.Header.Get("Content-Type"), Data: data, Modified: modified}, nil
+}
+
+// Delete removes the object. S3 does not say whether the object existed, so
+// a missing object is looked up first to report ErrNotFound.
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	head, err := s.do(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	head.Body.Close()
+	if head.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+
+	response, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent && response.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete object: %w", s3Error(response))
+	}
+	return nil
+}
+
+// do sends a request for the object, signed with Signature Version 4.
+func (s *S3Store) do(
+	ctx context.Context, method, key string, body []byte, prepare func(*http.Request),
+) (*http.Response, error) {
+	cleaned := strings.Trim(key, "/")
+	if cleaned == "" {
+		return nil, fmt.Errorf("invalid object key %q", key)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, s.base+"/"+escapeKey(cleaned), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if prepare != nil {
+		prepare(request)
+	}
+
+	credentials, err := s.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AWS credentials: %w", err)
+	}
+
+	hash := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(hash[:])
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(ctx, credentials, request, payloadHash, "s3", s.region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
+	}
+
+	return s.client.Do(request)
+}
+
+// escapeKey escapes each segment of key, keeping the slashes.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3Error describes a failed response by its status and the start of the
+// error document S3 sends.
+func s3Error(response *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+	return fmt.Errorf("S3 answered %s: %s", response.Status, bytes.TrimSpace(body))
+}
@@ -0,0 +1,234 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// errUnavailable is returned, without calling Okta, while the circuit
+// breaker of the endpoint class a request belongs to is open. The SDK
+// flattens transport errors into strings, so callers learn of it through
+// Unavailable instead.
+var errUnavailable = errors.New("okta is unavailable")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+var (
+	breakerStateGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "iam",
+		Subsystem: "okta",
+		Name:      "circuit_breaker_state",
+		Help:      "State of the circuit breaker of each Okta endpoint class: 0 closed, 1 half-open, 2 open.",
+	}, []string{"org", "class"})
+	breakerRejections = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "iam",
+		Subsystem: "okta",
+		Name:      "circuit_breaker_rejections_total",
+		Help:      "Requests failed fast because the circuit breaker of their Okta endpoint class was open.",
+	}, []string{"org", "class"})
+)
+
+// breakerTransport fails requests fast once Okta keeps failing, instead of
+// letting every caller wait for its own timeout. Each endpoint class, the
+// first path segment after /api/v1 such as users or groups, has its own
+// breaker, so one failing API does not cut off the others. A breaker opens
+// after a number of consecutive failures, network errors or 5xx responses,
+// and lets a single probe through once the open timeout has passed: it
+// closes again if the probe succeeds and reopens if it fails. It wraps the
+// retries, so a request that fails after retrying counts once.
+type breakerTransport struct {
+	base        http.RoundTripper
+	org         string
+	failures    int
+	openTimeout time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+type breaker struct {
+	state    breakerState
+	failures int
+	openedAt time.Time
+	// probing is set while the half-open probe is in flight.
+	probing bool
+}
+
+func newBreakerTransport(base http.RoundTripper, org string, failures int, openTimeout time.Duration) *breakerTransport {
+	return &breakerTransport{
+		base:        base,
+		org:         org,
+		failures:    failures,
+		openTimeout: openTimeout,
+		breakers:    make(map[string]*breaker),
+	}
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	class := endpointClass(req.URL.Path)
+
+	if retryAfter, ok := t.allow(class); !ok {
+		breakerRejections.WithLabelValues(t.org, class).Inc()
+		markUnavailable(req.Context(), retryAfter)
+		markRetryable(req.Context())
+		return nil, fmt.Errorf("%w: circuit breaker for %s is open, retry after %s",
+			errUnavailable, class, retryAfter.Round(time.Millisecond))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err != nil && req.Context().Err() != nil:
+		// The caller gave up; that says nothing about Okta.
+		t.release(class)
+	case err != nil || resp.StatusCode >= http.StatusInternalServerError:
+		t.record(class, false)
+		markRetryable(req.Context())
+	default:
+		t.record(class, true)
+		if resp.StatusCode == http.StatusTooManyRequests {
+			// Still rate limited once the retries ran out.
+			markRetryable(req.Context())
+		}
+	}
+	return resp, err
+}
+
+// allow reports whether a request of class may go to Okta, and otherwise how
+// long until the breaker lets a probe through.
+func (t *breakerTransport) allow(class string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.breaker(class)
+	switch b.state {
+	case breakerOpen:
+		if elapsed := time.Since(b.openedAt); elapsed < t.openTimeout {
+			return t.openTimeout - elapsed, false
+		}
+		t.setState(class, b, breakerHalfOpen)
+		b.probing = true
+		return 0, true
+	case breakerHalfOpen:
+		if b.probing {
+			return time.Second, false
+		}
+		b.probing = true
+		return 0, true
+	default:
+		return 0, true
+	}
+}
+
+func (t *breakerTransport) record(class string, success bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.breaker(class)
+	b.probing = false
+	if success {
+		b.failures = 0
+		t.setState(class, b, breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= t.failures {
+		b.openedAt = time.Now()
+		t.setState(class, b, breakerOpen)
+	}
+}
+
+// release lets another request probe when the probe was cancelled.
+func (t *breakerTransport) release(class string) {
+	t.mu.Lock()
+	t.breaker(class).probing = false
+	t.mu.Unlock()
+}
+
+// breaker returns the breaker of class. Callers hold mu.
+func (t *breakerTransport) breaker(class string) *breaker {
+	b, ok := t.breakers[class]
+	if !ok {
+		b = &breaker{}
+		t.breakers[class] = b
+		breakerStateGauge.WithLabelValues(t.org, class).Set(float64(breakerClosed))
+	}
+	return b
+}
+
+func (t *breakerTransport) setState(class string, b *breaker, state breakerState) {
+	b.state = state
+	breakerStateGauge.WithLabelValues(t.org, class).Set(float64(state))
+}
+
+// endpointClass is the resource a request path addresses, such as users in
+// /api/v1/users/{id}/groups.
+func endpointClass(path string) string {
+	path = strings.TrimPrefix(path, "/api/v1/")
+	class, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if class == "" {
+		return "other"
+	}
+	return class
+}
+
+type unavailableKey struct{}
+
+// TrackUnavailable returns a context in which requests rejected by an open
+// circuit breaker are noted, for Unavailable to report.
+func TrackUnavailable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unavailableKey{}, new(atomic.Int64))
+}
+
+// Unavailable reports whether a request made with ctx was rejected by an
+// open circuit breaker, and how long until the breaker lets a probe through.
+func Unavailable(ctx context.Context) (time.Duration, bool) {
+	retryAfter, ok := ctx.Value(unavailableKey{}).(*atomic.Int64)
+	if !ok || retryAfter.Load() == 0 {
+		return 0, false
+	}
+	return time.Duration(retryAfter.Load()), true
+}
+
+func markUnavailable(ctx context.Context, retryAfter time.Duration) {
+	if tracked, ok := ctx.Value(unavailableKey{}).(*atomic.Int64); ok {
+		tracked.Store(int64(max(retryAfter, time.Nanosecond)))
+	}
+}
+
+type retryableKey struct{}
+
+// TrackRetryable returns a context in which requests that failed in a way
+// worth retrying later, an open circuit breaker, a network error, a 5xx or a
+// 429 left after retrying, are noted for Retryable to report.
+func TrackRetryable(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryableKey{}, new(atomic.Bool))
+}
+
+// Retryable reports whether a request made with ctx failed in a way worth
+// retrying later.
+func Retryable(ctx context.Context) bool {
+	retryable, ok := ctx.Value(retryableKey{}).(*atomic.Bool)
+	return ok && retryable.Load()
+}
+
+func markRetryable(ctx context.Context) {
+	if tracked, ok := ctx.Value(retryableKey{}).(*atomic.Bool); ok {
+		tracked.Store(true)
+	}
+}
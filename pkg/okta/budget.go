@@ -0,0 +1,269 @@
+package okta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The subsystems sharing an org's rate limits. Requests are interactive
+// unless their context says otherwise.
+const (
+	SubsystemInteractive = "interactive"
+	SubsystemSync        = "sync"
+	SubsystemBatch       = "batch"
+)
+
+// errBudgetExhausted is returned, without calling Okta, when a background
+// request has used up its share of a rate limit window and the window does
+// not reset in time. Like errUnavailable, callers learn of it through
+// Unavailable.
+var errBudgetExhausted = errors.New("okta rate limit budget exhausted")
+
+var (
+	budgetRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "iam",
+		Subsystem: "okta",
+		Name:      "budget_requests_total",
+		Help:      "Okta requests by subsystem and what the budget did with them: admitted, queued until the window reset, or shed.",
+	}, []string{"org", "subsystem", "outcome"})
+	budgetWaitSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "iam",
+		Subsystem: "okta",
+		Name:      "budget_wait_seconds_total",
+		Help:      "Time Okta requests spent queued for their budget, by subsystem.",
+	}, []string{"org", "subsystem"})
+	budgetWaiting = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "iam",
+		Subsystem: "okta",
+		Name:      "budget_waiting",
+		Help:      "Okta requests queued for their budget, by subsystem.",
+	}, []string{"org", "subsystem"})
+)
+
+type subsystemKey struct{}
+
+// WithSubsystem returns a context whose Okta requests are counted against
+// the budget of subsystem: SubsystemSync for background workers and
+// SubsystemBatch for jobs.
+func WithSubsystem(ctx context.Context, subsystem string) context.Context {
+	return context.WithValue(ctx, subsystemKey{}, subsystem)
+}
+
+func subsystemOf(ctx context.Context) string {
+	switch subsystem, _ := ctx.Value(subsystemKey{}).(string); subsystem {
+	case SubsystemSync, SubsystemBatch:
+		return subsystem
+	default:
+		return SubsystemInteractive
+	}
+}
+
+// budgetTransport shares each rate limit window of an org between the
+// subsystems calling it, so background work cannot starve the requests
+// callers are waiting for. Sync and batch requests may each use their share
+// of a window, and none of the part kept for interactive requests, which are
+// never held back and may use whatever the others leave. A background
+// request over its budget waits for the window to reset, and fails at once
+// if that is further away than the maximum wait. It sits above the breaker,
+// so held-back requests say nothing about Okta's health.
+type budgetTransport struct {
+	base    http.RoundTripper
+	org     string
+	limits  *rateLimitTransport
+	maxWait atomic.Int64
+
+	mu     sync.Mutex
+	shares map[string]int
+	usage  map[budgetKey]*budgetUsage
+}
+
+type budgetKey struct {
+	class     string
+	subsystem string
+}
+
+type budgetUsage struct {
+	reset   time.Time
+	used    int
+	waiting int
+}
+
+func newBudgetTransport(base http.RoundTripper, limits *rateLimitTransport, cfg *config.OktaConfig) *budgetTransport {
+	t := &budgetTransport{base: base, org: cfg.Name, limits: limits, usage: make(map[budgetKey]*budgetUsage)}
+	t.tune(cfg)
+	return t
+}
+
+func (t *budgetTransport) tune(cfg *config.OktaConfig) {
+	t.maxWait.Store(int64(cfg.BudgetMaxWait))
+	t.mu.Lock()
+	t.shares = map[string]int{SubsystemSync: cfg.BudgetSyncShare, SubsystemBatch: cfg.BudgetBatchShare}
+	t.mu.Unlock()
+}
+
+func (t *budgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	subsystem := subsystemOf(ctx)
+	class := endpointClass(req.URL.Path)
+
+	for queued := false; ; queued = true {
+		wait, ok := t.take(class, subsystem)
+		if ok {
+			budgetRequests.WithLabelValues(t.org, subsystem, "admitted").Inc()
+			break
+		}
+
+		if wait > time.Duration(t.maxWait.Load()) {
+			budgetRequests.WithLabelValues(t.org, subsystem, "shed").Inc()
+			markUnavailable(ctx, wait)
+			markRetryable(ctx)
+			return nil, fmt.Errorf("%w: %s requests to %s must wait for the window to reset, retry after %s",
+				errBudgetExhausted, subsystem, class, wait.Round(time.Millisecond))
+		}
+		if !queued {
+			budgetRequests.WithLabelValues(t.org, subsystem, "queued").Inc()
+		}
+
+		if err := t.wait(ctx, class, subsystem, wait); err != nil {
+			return nil, err
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// take counts a request of subsystem against its budget for class, or
+// reports how long until the window resets when it is used up.
+func (t *budgetTransport) take(class, subsystem string) (time.Duration, bool) {
+	window, known := t.limits.window(class)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	usage := t.usageOf(class, subsystem)
+	if !known {
+		// Nothing is known of the limit until Okta first reports it.
+		return 0, true
+	}
+	if !usage.reset.After(time.Now()) {
+		usage.reset, usage.used = window.Reset, 0
+	}
+
+	if subsystem != SubsystemInteractive {
+		allowances := t.allowances(window.Limit)
+		reserved := window.Limit - allowances[SubsystemSync] - allowances[SubsystemBatch]
+		if usage.used >= allowances[subsystem] || window.Remaining <= reserved {
+			return time.Until(usage.reset), false
+		}
+	}
+
+	usage.used++
+	return 0, true
+}
+
+// wait holds a request back for d, or until ctx is done.
+func (t *budgetTransport) wait(ctx context.Context, class, subsystem string, d time.Duration) error {
+	t.setWaiting(class, subsystem, 1)
+	defer t.setWaiting(class, subsystem, -1)
+
+	started := time.Now()
+	defer func() { budgetWaitSeconds.WithLabelValues(t.org, subsystem).Add(time.Since(started).Seconds()) }()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (t *budgetTransport) setWaiting(class, subsystem string, delta int) {
+	t.mu.Lock()
+	t.usageOf(class, subsystem).waiting += delta
+	t.mu.Unlock()
+	budgetWaiting.WithLabelValues(t.org, subsystem).Add(float64(delta))
+}
+
+// allowances returns how many requests each background subsystem may make
+// in a window of limit requests. Callers hold mu.
+func (t *budgetTransport) allowances(limit int) map[string]int {
+	allowances := make(map[string]int, len(t.shares))
+	for subsystem, share := range t.shares {
+		allowances[subsystem] = max(limit*share/100, 1)
+	}
+	return allowances
+}
+
+// usageOf returns the usage of subsystem for class. Callers hold mu.
+func (t *budgetTransport) usageOf(class, subsystem string) *budgetUsage {
+	key := budgetKey{class: class, subsystem: subsystem}
+	usage, ok := t.usage[key]
+	if !ok {
+		usage = &budgetUsage{}
+		t.usage[key] = usage
+	}
+	return usage
+}
+
+// budgets returns the budget of each subsystem in each endpoint class Okta
+// has reported a window for, by class and subsystem.
+func (t *budgetTransport) budgets() []*models.OktaBudget {
+	windows := make(map[string]models.OktaRateLimit)
+	for _, reported := range t.limits.rateLimits() {
+		if window, ok := t.limits.window(reported.Class); ok {
+			windows[reported.Class] = window
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	result := make([]*models.OktaBudget, 0, len(windows)*3)
+	for class, window := range windows {
+		allowances := t.allowances(window.Limit)
+		allowances[SubsystemInteractive] = window.Limit
+
+		for _, subsystem := range []string{SubsystemInteractive, SubsystemSync, SubsystemBatch} {
+			budget := &models.OktaBudget{
+				Org:       t.org,
+				Class:     class,
+				Subsystem: subsystem,
+				Share:     t.shares[subsystem],
+				Allowance: allowances[subsystem],
+				Reset:     window.Reset,
+			}
+			if subsystem == SubsystemInteractive {
+				budget.Share = 100 - t.shares[SubsystemSync] - t.shares[SubsystemBatch]
+			}
+			if usage, ok := t.usage[budgetKey{class: class, subsystem: subsystem}]; ok {
+				if usage.reset.After(now) {
+					budget.Used = usage.used
+				}
+				budget.Waiting = usage.waiting
+			}
+			result = append(result, budget)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Class != result[j].Class {
+			return result[i].Class < result[j].Class
+		}
+		return result[i].Subsystem < result[j].Subsystem
+	})
+	return result
+}
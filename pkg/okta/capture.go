@@ -0,0 +1,139 @@
+package okta
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+const (
+	// maxInteractions bounds the interactions recorded for one context.
+	maxInteractions = 500
+	// maxInteractionBody is the largest body an interaction keeps; larger
+	// ones are left out.
+	maxInteractionBody = 1 << 20
+)
+
+type interactionsKey struct{}
+
+type interactionLog struct {
+	mu           sync.Mutex
+	interactions []*models.OktaInteraction
+	truncated    bool
+}
+
+// TrackInteractions returns a context in which every request sent to Okta
+// and the response it got are recorded, for Interactions to return.
+func TrackInteractions(ctx context.Context) context.Context {
+	return context.WithValue(ctx, interactionsKey{}, &interactionLog{})
+}
+
+// Interactions returns the interactions recorded in ctx, oldest first, and
+// whether some were left out because there were too many.
+func Interactions(ctx context.Context) ([]*models.OktaInteraction, bool) {
+	log, ok := ctx.Value(interactionsKey{}).(*interactionLog)
+	if !ok {
+		return nil, false
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]*models.OktaInteraction(nil), log.interactions...), log.truncated
+}
+
+type replayKey struct{}
+
+// ReplayInteractions returns a context in which requests are served by
+// replay, such as an oktamock.Replay of recorded interactions, instead of
+// being sent to Okta.
+func ReplayInteractions(ctx context.Context, replay http.Handler) context.Context {
+	return context.WithValue(ctx, replayKey{}, replay)
+}
+
+// replaying reports whether req is served by a replay.
+func replaying(req *http.Request) bool {
+	return req != nil && req.Context().Value(replayKey{}) != nil
+}
+
+// captureTransport records requests made with a context from
+// TrackInteractions and serves those made with one from ReplayInteractions.
+// It wraps every other transport, so replayed requests never reach Okta,
+// its rate limits or the circuit breakers.
+type captureTransport struct {
+	base http.RoundTripper
+}
+
+func (t captureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	log, tracked := req.Context().Value(interactionsKey{}).(*interactionLog)
+	replay, replayed := req.Context().Value(replayKey{}).(http.Handler)
+	if !tracked && !replayed {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	var resp *http.Response
+	var err error
+	if replayed {
+		recorder := httptest.NewRecorder()
+		replay.ServeHTTP(recorder, req)
+		resp = recorder.Result()
+		resp.Request = req
+	} else {
+		resp, err = t.base.RoundTrip(req)
+	}
+	if !tracked {
+		return resp, err
+	}
+
+	interaction := &models.OktaInteraction{
+		OktaRequest: models.OktaRequest{Method: req.Method, Path: req.URL.Path, Query: req.URL.RawQuery},
+		RequestBody: jsonBody(body),
+	}
+	if err != nil {
+		interaction.Error = err.Error()
+	} else {
+		data, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(data))
+
+		interaction.Status = resp.StatusCode
+		interaction.Link = resp.Header.Get("Link")
+		interaction.ResponseBody = jsonBody(data)
+	}
+
+	log.mu.Lock()
+	if len(log.interactions) < maxInteractions {
+		log.interactions = append(log.interactions, interaction)
+	} else {
+		log.truncated = true
+	}
+	log.mu.Unlock()
+	return resp, err
+}
+
+// jsonBody returns data when it is a JSON document small enough to keep.
+func jsonBody(data []byte) json.RawMessage {
+	if len(data) == 0 || len(data) > maxInteractionBody || !json.Valid(data) {
+		return nil
+	}
+	return json.RawMessage(data)
+}
@@ -0,0 +1,169 @@
+package okta
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/iamBelugaa/iam/internal/secrets"
+	"github.com/iamBelugaa/iam/pkg/logger"
+)
+
+// authTransport sets the Authorization header of every Okta request from the
+// current value of a secret, replacing the one set by the SDK. The SDK reads
+// its credentials once at construction, so this is what lets a rotated token
+// or key take effect without rebuilding the clients the services hold. It
+// also forwards the ID of the request being served, so Okta calls can be
+// correlated with it.
+type authTransport struct {
+	base      http.RoundTripper
+	authorize func(req *http.Request) (string, error)
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header, err := t.authorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// RoundTrippers must not modify the caller's request.
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", header)
+	if id := logger.RequestID(req.Context()); id != "" {
+		req.Header.Set(logger.RequestIDHeader, id)
+	}
+	return t.base.RoundTrip(req)
+}
+
+func apiTokenAuth(secret *secrets.Secret) func(*http.Request) (string, error) {
+	return func(*http.Request) (string, error) {
+		return "SSWS " + secret.Value(), nil
+	}
+}
+
+// privateKeyAuth exchanges a client assertion signed with a service app's
+// private key for an access token, and reuses the token until shortly before
+// it expires or the key is rotated. Service apps that require DPoP are not
+// supported.
+type privateKeyAuth struct {
+	base     http.RoundTripper
+	tokenURL string
+	clientID string
+	keyID    string
+	scopes   []string
+	secret   *secrets.Secret
+
+	mu        sync.Mutex
+	token     string
+	tokenKey  string
+	expiresAt time.Time
+}
+
+func (a *privateKeyAuth) authorize(req *http.Request) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	privateKey := a.secret.Value()
+	if a.token != "" && a.tokenKey == privateKey && time.Now().Before(a.expiresAt) {
+		return "Bearer " + a.token, nil
+	}
+
+	token, expiresIn, err := a.requestToken(req, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	a.token = token
+	a.tokenKey = privateKey
+	// Renew a minute early so a request never carries an expiring token.
+	a.expiresAt = time.Now().Add(time.Duration(expiresIn)*time.Second - time.Minute)
+	return "Bearer " + token, nil
+}
+
+func (a *privateKeyAuth) requestToken(req *http.Request, privateKey string) (string, int, error) {
+	assertion, err := a.clientAssertion(privateKey)
+	if err != nil {
+		return "", 0, err
+	}
+
+	form := url.Values{
+		"grant_type":            {"client_credentials"},
+		"scope":                 {strings.Join(a.scopes, " ")},
+		"client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"},
+		"client_assertion":      {assertion},
+	}
+
+	tokenReq, err := http.NewRequestWithContext(req.Context(), http.MethodPost, a.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to build token request: %w", err)
+	}
+	tokenReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	tokenReq.Header.Set("Accept", "application/json")
+
+	resp, err := a.base.RoundTrip(tokenReq)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to request Okta access token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		TokenType        string `json:"token_type"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", 0, fmt.Errorf("failed to decode Okta token response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("okta token request failed: %s: %s", body.Error, body.ErrorDescription)
+	}
+	if !strings.EqualFold(body.TokenType, "Bearer") {
+		return "", 0, fmt.Errorf("okta issued a %s token; disable DPoP on the service app", body.TokenType)
+	}
+	return body.AccessToken, body.ExpiresIn, nil
+}
+
+func (a *privateKeyAuth) clientAssertion(privateKey string) (string, error) {
+	key, err := jwk.ParseKey([]byte(privateKey), jwk.WithPEM(true))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse Okta private key: %w", err)
+	}
+	if a.keyID != "" {
+		if err := key.Set(jwk.KeyIDKey, a.keyID); err != nil {
+			return "", fmt.Errorf("failed to set key ID: %w", err)
+		}
+	}
+
+	now := time.Now()
+	token := jwt.New()
+	for name, value := range map[string]any{
+		jwt.IssuerKey:     a.clientID,
+		jwt.SubjectKey:    a.clientID,
+		jwt.AudienceKey:   a.tokenURL,
+		jwt.IssuedAtKey:   now,
+		jwt.ExpirationKey: now.Add(5 * time.Minute),
+		jwt.JwtIDKey:      uuid.NewString(),
+	} {
+		if err := token.Set(name, value); err != nil {
+			return "", fmt.Errorf("failed to set %s claim: %w", name, err)
+		}
+	}
+
+	signed, err := jwt.Sign(token, jwa.RS256, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign client assertion: %w", err)
+	}
+	return string(signed), nil
+}
@@ -0,0 +1,86 @@
+package okta
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/okta/okta-sdk-golang/v5/okta"
+)
+
+// MemoryClient serves an org from memory instead of Okta. Its SDK client
+// sends every request to an in-process fake org, such as memory.NewOrg, so
+// the services work unchanged and nothing leaves the process. Changes last
+// until the process exits.
+type MemoryClient struct {
+	sdk *okta.APIClient
+}
+
+// NewMemoryClient creates a client of org named after cfg. It needs no
+// domain or credentials.
+func NewMemoryClient(cfg *config.OktaConfig, org http.Handler) (*MemoryClient, error) {
+	domain := cfg.Domain
+	if domain == "" {
+		domain = cfg.Name + ".okta.local"
+	}
+
+	oktaConfig, err := okta.NewConfiguration(
+		okta.WithToken("memory"),
+		okta.WithOrgUrl(fmt.Sprintf("https://%s", domain)),
+		okta.WithRateLimitMaxRetries(0),
+		okta.WithCache(false),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create okta config : %w", err)
+	}
+
+	oktaConfig.HTTPClient = &http.Client{
+		Transport: captureTransport{base: handlerTransport{handler: org}},
+	}
+	return &MemoryClient{sdk: okta.NewAPIClient(oktaConfig)}, nil
+}
+
+// Tune does nothing: the in-memory org has no cache or rate limits.
+func (c *MemoryClient) Tune(cfg *config.OktaConfig) {}
+
+// RateLimits reports nothing: the in-memory org has no rate limits.
+func (c *MemoryClient) RateLimits() []*models.OktaRateLimit {
+	return nil
+}
+
+// Budgets reports nothing: the in-memory org has no rate limits to share.
+func (c *MemoryClient) Budgets() []*models.OktaBudget {
+	return nil
+}
+
+func (c *MemoryClient) SDK() *okta.APIClient {
+	return c.sdk
+}
+
+func (c *MemoryClient) TestConnection(ctx context.Context) error {
+	if _, _, err := c.sdk.OrgSettingAPI.GetOrgSettings(ctx).Execute(); err != nil {
+		return fmt.Errorf("failed to validate in-memory Okta org: %w", err)
+	}
+	return nil
+}
+
+// handlerTransport serves requests with a handler in process. Like a network
+// transport, it refuses requests whose context is done.
+type handlerTransport struct {
+	handler http.Handler
+}
+
+func (t handlerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	recorder := httptest.NewRecorder()
+	t.handler.ServeHTTP(recorder, req)
+	resp := recorder.Result()
+	resp.Request = req
+	return resp, nil
+}
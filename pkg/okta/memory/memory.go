@@ -0,0 +1,16 @@
+// Package memory provides the fake org the in-memory Okta backend serves. It
+// is apart from package okta so programs that only talk to Okta do not link
+// the fake org, or the router it is built on.
+package memory
+
+import (
+	"net/http"
+
+	"github.com/iamBelugaa/iam/internal/oktamock"
+)
+
+// NewOrg returns an org seeded with sample users and groups, served in
+// process. Changes last until the process exits.
+func NewOrg() http.Handler {
+	return oktamock.NewOrg(oktamock.DefaultFixtures())
+}
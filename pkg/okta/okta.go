@@ -7,42 +7,151 @@ import (
 	"time"
 
 	"github.com/iamBelugaa/iam/internal/config"
+	"github.com/iamBelugaa/iam/internal/models"
+	"github.com/iamBelugaa/iam/internal/secrets"
 	"github.com/okta/okta-sdk-golang/v5/okta"
 )
 
+// Backend is an Okta org the services talk to through its SDK client. Client
+// talks to a real org and MemoryClient serves one from memory, for running
+// the API without Okta.
+type Backend interface {
+	// SDK is the client the services call the org with.
+	SDK() *okta.APIClient
+	// Tune applies the settings of cfg that can change while the backend is
+	// in use.
+	Tune(cfg *config.OktaConfig)
+	// TestConnection checks that the org answers.
+	TestConnection(ctx context.Context) error
+	// RateLimits reports the org's rate limit windows, as its responses
+	// last reported them.
+	RateLimits() []*models.OktaRateLimit
+	// Budgets reports the part of each rate limit window each subsystem may
+	// use, and how much of it it has.
+	Budgets() []*models.OktaBudget
+}
+
+// New creates the backend cfg selects. The in-memory backend serves an org
+// made by newOrg, such as memory.NewOrg; it is passed in so programs that
+// only talk to Okta do not link the fake org.
+func New(
+	ctx context.Context, cfg *config.OktaConfig, store *secrets.Store, newOrg func() http.Handler,
+) (Backend, error) {
+	if cfg.Backend == config.OktaBackendMemory {
+		if newOrg == nil {
+			return nil, fmt.Errorf("okta org %s: the in-memory backend is not available", cfg.Name)
+		}
+		return NewMemoryClient(cfg, newOrg())
+	}
+	return NewClient(ctx, cfg, store)
+}
+
 type Client struct {
-	sdk *okta.APIClient
+	sdk     *okta.APIClient
+	cache   *responseCache
+	retries *retryTransport
+	limits  *rateLimitTransport
+	budget  *budgetTransport
 }
 
-func NewClient(cfg *config.OktaConfig) (*Client, error) {
+// NewClient creates a client for the org in cfg. Its credential, the API
+// token or, when cfg.ClientID is set, the service app's private key, is
+// loaded from store, and every request uses its latest value.
+func NewClient(ctx context.Context, cfg *config.OktaConfig, store *secrets.Store) (*Client, error) {
+	base := &http.Transport{
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		ExpectContinueTimeout: 1 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+	}
+	transport := &authTransport{base: base}
+
+	if cfg.ClientID == "" {
+		secret, err := store.Load(ctx, cfg.APITokenSecret)
+		if err != nil {
+			return nil, err
+		}
+		transport.authorize = apiTokenAuth(secret)
+	} else {
+		if len(cfg.Scopes) == 0 {
+			return nil, fmt.Errorf("okta org %s: scopes are required to authenticate as a service app", cfg.Name)
+		}
+
+		secret, err := store.Load(ctx, cfg.PrivateKeySecret)
+		if err != nil {
+			return nil, err
+		}
+
+		auth := &privateKeyAuth{
+			base:     base,
+			tokenURL: fmt.Sprintf("https://%s/oauth2/v1/token", cfg.Domain),
+			clientID: cfg.ClientID,
+			keyID:    cfg.PrivateKeyID,
+			scopes:   cfg.Scopes,
+			secret:   secret,
+		}
+		transport.authorize = auth.authorize
+	}
+
+	cache := newResponseCache(cfg.CacheTTL)
+	limits := newRateLimitTransport(transport, cfg.Name)
+	retries := &retryTransport{base: limits, timeout: 30 * time.Second}
+	retries.maxRetries.Store(int32(cfg.RateLimitMaxRetries))
+	breakers := newBreakerTransport(retries, cfg.Name, cfg.BreakerFailures, cfg.BreakerOpenTimeout)
+	budget := newBudgetTransport(breakers, limits, cfg)
+
+	// Each client tracks its own org's rate limit headers and waits for the
+	// window to reset once it is exhausted, so orgs never throttle each other.
+	// Within an org, background work is held to its share of each window.
+	// The cache and 429 retries are ours rather than the SDK's so Tune can
+	// change them on a running client.
 	oktaConfig, err := okta.NewConfiguration(
-		okta.WithToken(cfg.APIToken),
+		// The SDK sends an SSWS header built from this token; authTransport
+		// replaces it on every request with the current credential.
+		okta.WithToken("managed-by-transport"),
 		okta.WithOrgUrl(fmt.Sprintf("https://%s", cfg.Domain)),
+		okta.WithRateLimitPrevent(true),
+		okta.WithRateLimitMaxRetries(0),
+		okta.WithCache(true),
+		okta.WithCacheManager(cache),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create okta config : %w", err)
 	}
 
-	httpClient := &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:          100,
-			MaxIdleConnsPerHost:   10,
-			ExpectContinueTimeout: 1 * time.Second,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ResponseHeaderTimeout: 20 * time.Second,
-		},
-	}
+	oktaConfig.HTTPClient = &http.Client{Transport: captureTransport{base: budget}}
+	return &Client{
+		sdk:     okta.NewAPIClient(oktaConfig),
+		cache:   cache,
+		retries: retries,
+		limits:  limits,
+		budget:  budget,
+	}, nil
+}
 
-	oktaConfig.HTTPClient = httpClient
-	return &Client{sdk: okta.NewAPIClient(oktaConfig)}, nil
+// Tune applies the settings of cfg that can change while the client is in
+// use: the response cache TTL, the rate limit retries and the rate limit
+// budget. Changing the TTL empties the cache.
+func (c *Client) Tune(cfg *config.OktaConfig) {
+	c.cache.setTTL(cfg.CacheTTL)
+	c.retries.maxRetries.Store(int32(cfg.RateLimitMaxRetries))
+	c.budget.tune(cfg)
 }
 
 func (c *Client) SDK() *okta.APIClient {
 	return c.sdk
 }
 
+func (c *Client) RateLimits() []*models.OktaRateLimit {
+	return c.limits.rateLimits()
+}
+
+func (c *Client) Budgets() []*models.OktaBudget {
+	return c.budget.budgets()
+}
+
 func (c *Client) TestConnection(ctx context.Context) error {
 	_, resp, err := c.sdk.OrgSettingAPI.GetOrgSettings(ctx).Execute()
 	if err != nil {
@@ -55,3 +164,12 @@ func (c *Client) TestConnection(ctx context.Context) error {
 
 	return nil
 }
+
+// StatusCode is the status of Okta's response, or zero when the request
+// failed before one was received.
+func StatusCode(response *okta.APIResponse) int {
+	if response == nil || response.Response == nil {
+		return 0
+	}
+	return response.StatusCode
+}
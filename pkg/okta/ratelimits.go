@@ -0,0 +1,106 @@
+package okta
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iamBelugaa/iam/internal/models"
+)
+
+const (
+	rateLimitLimitHeader     = "X-Rate-Limit-Limit"
+	rateLimitRemainingHeader = "X-Rate-Limit-Remaining"
+	rateLimitResetHeader     = "X-Rate-Limit-Reset"
+)
+
+// rateLimitWindow is how long an Okta rate limit window lasts.
+const rateLimitWindow = time.Minute
+
+// rateLimitTransport notes the rate limit window Okta reports on each
+// response, per endpoint class, so the pressure the service puts on its org
+// can be reported. It sits below the retries, so every attempt counts.
+type rateLimitTransport struct {
+	base http.RoundTripper
+	org  string
+
+	mu      sync.Mutex
+	windows map[string]*models.OktaRateLimit
+}
+
+func newRateLimitTransport(base http.RoundTripper, org string) *rateLimitTransport {
+	return &rateLimitTransport{base: base, org: org, windows: make(map[string]*models.OktaRateLimit)}
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	limit, _ := strconv.Atoi(resp.Header.Get(rateLimitLimitHeader))
+	remaining, _ := strconv.Atoi(resp.Header.Get(rateLimitRemainingHeader))
+	reset, _ := strconv.ParseInt(resp.Header.Get(rateLimitResetHeader), 10, 64)
+	if limit <= 0 {
+		return resp, nil
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		remaining = 0
+	}
+
+	class := endpointClass(req.URL.Path)
+	t.mu.Lock()
+	t.windows[class] = &models.OktaRateLimit{
+		Org:       t.org,
+		Class:     class,
+		Limit:     limit,
+		Remaining: min(max(remaining, 0), limit),
+		Reset:     time.Unix(reset, 0).UTC(),
+	}
+	t.mu.Unlock()
+	return resp, nil
+}
+
+// rateLimits returns the window of each endpoint class, by class. Windows
+// that have reset since are reported with their whole limit remaining.
+func (t *rateLimitTransport) rateLimits() []*models.OktaRateLimit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	result := make([]*models.OktaRateLimit, 0, len(t.windows))
+	for _, window := range t.windows {
+		copied := *window
+		if !copied.Reset.After(now) {
+			copied.Remaining = copied.Limit
+		}
+		copied.Utilization = float64(copied.Limit-copied.Remaining) / float64(copied.Limit)
+		result = append(result, &copied)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Class < result[j].Class })
+	return result
+}
+
+// window returns the current window of class. Once the reported window has
+// reset, the next one is assumed to last rateLimitWindow with the whole
+// limit remaining, until a response reports it.
+func (t *rateLimitTransport) window(class string) (models.OktaRateLimit, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	window, ok := t.windows[class]
+	if !ok {
+		return models.OktaRateLimit{}, false
+	}
+
+	current := *window
+	now := time.Now()
+	if !current.Reset.After(now) {
+		current.Remaining = current.Limit
+		current.Reset = current.Reset.Add((now.Sub(current.Reset)/rateLimitWindow + 1) * rateLimitWindow)
+	}
+	return current, true
+}
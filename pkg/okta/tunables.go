@@ -0,0 +1,179 @@
+package okta
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/okta/okta-sdk-golang/v5/okta"
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// maxRetryBackoff caps how long a request waits for a rate limit window to
+// reset, matching the SDK's default.
+const maxRetryBackoff = 30 * time.Second
+
+// responseCache is the SDK's response cache with a TTL that can change while
+// the client is in use. A zero TTL disables it.
+type responseCache struct {
+	ttl   atomic.Int64
+	items *gocache.Cache
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	cache := &responseCache{items: gocache.New(gocache.NoExpiration, time.Minute)}
+	cache.setTTL(ttl)
+	return cache
+}
+
+func (c *responseCache) setTTL(ttl time.Duration) {
+	if time.Duration(c.ttl.Swap(int64(ttl))) != ttl {
+		// Entries keep the TTL they were stored with, so start over.
+		c.items.Flush()
+	}
+}
+
+func (c *responseCache) enabled() bool {
+	return c.ttl.Load() > 0
+}
+
+func (c *responseCache) Get(key string) *http.Response {
+	item, found := c.items.Get(key)
+	if !found || !c.enabled() {
+		return nil
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(item.([]byte))), nil)
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+func (c *responseCache) Set(key string, value *http.Response) {
+	// Replayed responses are not Okta's, so they must not be served later.
+	if !c.enabled() || replaying(value.Request) {
+		return
+	}
+	dump, err := httputil.DumpResponse(value, true)
+	if err != nil {
+		return
+	}
+	c.items.Set(key, dump, time.Duration(c.ttl.Load()))
+}
+
+func (c *responseCache) GetString(key string) string {
+	if item, found := c.items.Get(key); found && c.enabled() {
+		if value, ok := item.(string); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func (c *responseCache) SetString(key string, value string) {
+	if c.enabled() {
+		c.items.Set(key, value, time.Duration(c.ttl.Load()))
+	}
+}
+
+func (c *responseCache) Delete(key string) {
+	c.items.Delete(key)
+}
+
+func (c *responseCache) Clear() {
+	c.items.Flush()
+}
+
+func (c *responseCache) Has(key string) bool {
+	_, found := c.items.Get(key)
+	return found && c.enabled()
+}
+
+// retryTransport retries requests Okta rejects with 429 once the rate limit
+// window resets, up to a limit that can change while the client is in use.
+// It replaces the SDK's own retries, whose limit is fixed at construction.
+// Each attempt gets its own timeout, so waiting for a window does not eat
+// into the time the retried request has to complete.
+type retryTransport struct {
+	base       http.RoundTripper
+	timeout    time.Duration
+	maxRetries atomic.Int32
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := t.attempt(req, body)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempt >= int(t.maxRetries.Load()) {
+			return resp, err
+		}
+
+		wait, err := okta.Get429BackoffTime(resp)
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		// A request whose deadline passes before the window resets would only
+		// be cancelled while waiting, so it gives up now.
+		backoff := min(time.Duration(wait)*time.Second, maxRetryBackoff)
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < backoff {
+			return nil, context.DeadlineExceeded
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+
+		req = req.Clone(req.Context())
+		req.Header.Set("X-Okta-Retry-For", resp.Header.Get("X-Okta-Request-Id"))
+		req.Header.Set("X-Okta-Retry-Count", strconv.Itoa(attempt+1))
+	}
+}
+
+func (t *retryTransport) attempt(req *http.Request, body []byte) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	attempt := req.Clone(ctx)
+	if body != nil {
+		attempt.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := t.base.RoundTrip(attempt)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases an attempt's timeout once its body has been read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
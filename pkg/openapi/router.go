@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Router wraps a chi router so that every route is registered together with
+// its documentation. Routes can only be added through the typed methods, so
+// the spec cannot drift from what is actually served.
+type Router struct {
+	router  chi.Router
+	spec    *Spec
+	prefix  string
+	secured bool
+}
+
+func NewRouter(router chi.Router, spec *Spec) *Router {
+	return &Router{router: router, spec: spec}
+}
+
+// Use appends middlewares to the router's stack.
+func (r *Router) Use(middlewares ...func(http.Handler) http.Handler) {
+	r.router.Use(middlewares...)
+}
+
+// Secure appends middlewares that authenticate the caller, and documents
+// every route registered on the router afterwards as requiring a bearer token.
+func (r *Router) Secure(middlewares ...func(http.Handler) http.Handler) {
+	r.router.Use(middlewares...)
+	r.secured = true
+}
+
+// Route mounts a sub-router along pattern.
+func (r *Router) Route(pattern string, fn func(r *Router)) {
+	r.router.Route(pattern, func(sub chi.Router) {
+		fn(&Router{router: sub, spec: r.spec, prefix: r.prefix + pattern, secured: r.secured})
+	})
+}
+
+// Group adds a router sharing r's path, whose middlewares apply only to the
+// routes registered on it.
+func (r *Router) Group(fn func(r *Router)) {
+	r.router.Group(func(sub chi.Router) {
+		fn(&Router{router: sub, spec: r.spec, prefix: r.prefix, secured: r.secured})
+	})
+}
+
+func (r *Router) Get(pattern string, handler http.HandlerFunc, doc Doc) {
+	r.Method(http.MethodGet, pattern, handler, doc)
+}
+
+func (r *Router) Post(pattern string, handler http.HandlerFunc, doc Doc) {
+	r.Method(http.MethodPost, pattern, handler, doc)
+}
+
+func (r *Router) Put(pattern string, handler http.HandlerFunc, doc Doc) {
+	r.Method(http.MethodPut, pattern, handler, doc)
+}
+
+func (r *Router) Patch(pattern string, handler http.HandlerFunc, doc Doc) {
+	r.Method(http.MethodPatch, pattern, handler, doc)
+}
+
+func (r *Router) Delete(pattern string, handler http.HandlerFunc, doc Doc) {
+	r.Method(http.MethodDelete, pattern, handler, doc)
+}
+
+// Method registers handler for method and pattern and adds it to the spec.
+func (r *Router) Method(method, pattern string, handler http.HandlerFunc, doc Doc) {
+	r.router.Method(method, pattern, handler)
+
+	path := r.prefix + pattern
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	r.spec.add(method, path, doc, r.secured)
+}
@@ -0,0 +1,119 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	durationType   = reflect.TypeFor[time.Duration]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+)
+
+// schemaFor returns the schema of t as encoding/json would marshal it. Named
+// struct types are added to the components and referenced, which also keeps
+// recursive types such as a user's groups and a group's members finite.
+func (s *Spec) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	case durationType:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Nullable: nullable}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64", Nullable: nullable}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	case reflect.String:
+		return &Schema{Type: "string", Nullable: nullable}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte", Nullable: nullable}
+		}
+		return &Schema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.objectSchema(t)
+		}
+		return &Schema{Ref: s.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component registers the named struct t and returns its reference.
+func (s *Spec) component(t reflect.Type) string {
+	name, ok := s.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := s.doc.Components.Schemas[name]; taken {
+			name = strings.ReplaceAll(t.PkgPath(), "/", ".") + "." + name
+		}
+
+		s.names[t] = name
+		// Reserve the name before building the schema so that recursive
+		// fields resolve to the reference.
+		s.doc.Components.Schemas[name] = &Schema{}
+		*s.doc.Components.Schemas[name] = *s.objectSchema(t)
+	}
+
+	return "#/components/schemas/" + name
+}
+
+func (s *Spec) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addFields(schema, t)
+	return schema
+}
+
+// addFields adds the exported fields of t, flattening embedded structs the
+// way encoding/json does.
+func (s *Spec) addFields(schema *Schema, t reflect.Type) {
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(schema, embedded)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema.Properties[name] = s.schemaFor(field.Type)
+	}
+}
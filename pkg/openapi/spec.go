@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const bearerAuth = "bearerAuth"
+
+// pathParam matches chi URL parameters, with or without a regexp.
+var pathParam = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+
+// Doc describes a route. Request and Response are sample values of the JSON
+// request body and of the data field of the success envelope; their Go types
+// are turned into schemas.
+type Doc struct {
+	Summary     string
+	Description string
+	Query       []Param
+	Request     any
+	Response    any
+	// Status is the success status code; it defaults to 200.
+	Status int
+	// Consumes lists request content types accepted instead of JSON, such
+	// as raw uploads.
+	Consumes []string
+	// Produces lists response content types served instead of, or as well
+	// as, the JSON envelope, such as CSV exports.
+	Produces []string
+}
+
+// Param is an optional query string parameter.
+type Param struct {
+	Name        string
+	Description string
+}
+
+// Spec collects operations as routes are registered and serves them as an
+// OpenAPI document.
+type Spec struct {
+	mu    sync.Mutex
+	doc   *Document
+	names map[reflect.Type]string
+}
+
+func New(info Info) *Spec {
+	s := &Spec{
+		names: make(map[reflect.Type]string),
+		doc: &Document{
+			OpenAPI: "3.0.3",
+			Info:    info,
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+	}
+
+	s.component(reflect.TypeFor[response.ErrorResponse]())
+	return s
+}
+
+// JSON encodes the document.
+func (s *Spec) JSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.MarshalIndent(s.doc, "", "  ")
+}
+
+// ServeHTTP serves the document as JSON.
+func (s *Spec) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	data, err := s.JSON()
+	if err != nil {
+		response.RespondError(w, http.StatusInternalServerError, "API_ERROR", "Failed to encode OpenAPI spec", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(data)
+}
+
+func (s *Spec) add(method, path string, doc Doc, secured bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op := &Operation{
+		Summary:     doc.Summary,
+		Description: doc.Description,
+		Responses:   make(map[string]*Response),
+	}
+
+	if tag := tagFor(path); tag != "" {
+		op.Tags = []string{tag}
+		if !slices.ContainsFunc(s.doc.Tags, func(t Tag) bool { return t.Name == tag }) {
+			s.doc.Tags = append(s.doc.Tags, Tag{Name: tag})
+		}
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+		})
+	}
+	for _, param := range doc.Query {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name: param.Name, In: "query", Description: param.Description, Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if doc.Request != nil || len(doc.Consumes) > 0 {
+		op.RequestBody = &RequestBody{Required: true, Content: make(map[string]MediaType)}
+		if doc.Request != nil {
+			schema := s.schemaFor(reflect.TypeOf(doc.Request))
+			op.RequestBody.Content["application/json"] = MediaType{Schema: schema}
+			op.RequestBody.Content[response.YAML] = MediaType{Schema: schema}
+		}
+		for _, contentType := range doc.Consumes {
+			op.RequestBody.Content[contentType] = MediaType{Schema: rawSchema(contentType)}
+		}
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	success := &Response{Description: http.StatusText(status), Content: make(map[string]MediaType)}
+	if doc.Response != nil || len(doc.Produces) == 0 {
+		envelope := s.envelope(doc.Response)
+		success.Content["application/json"] = MediaType{Schema: envelope}
+		success.Content[response.YAML] = MediaType{Schema: envelope}
+	}
+	for _, contentType := range doc.Produces {
+		success.Content[contentType] = MediaType{Schema: rawSchema(contentType)}
+	}
+
+	op.Responses[strconv.Itoa(status)] = success
+	errorSchema := s.schemaFor(reflect.TypeFor[response.ErrorResponse]())
+	op.Responses["default"] = &Response{
+		Description: "Error",
+		Content: map[string]MediaType{
+			"application/json": {Schema: errorSchema},
+			response.YAML:      {Schema: errorSchema},
+		},
+	}
+
+	if secured {
+		op.Security = []map[string][]string{{bearerAuth: {}}}
+	}
+
+	path = pathParam.ReplaceAllString(path, "{$1}")
+	if s.doc.Paths[path] == nil {
+		s.doc.Paths[path] = make(PathItem)
+	}
+	s.doc.Paths[path][strings.ToLower(method)] = op
+}
+
+// Secured reports whether the operation registered for method and path, a
+// route pattern such as /api/v1/users/{userID}, requires a caller.
+func (s *Spec) Secured(method, path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.doc.Paths[pathParam.ReplaceAllString(path, "{$1}")][strings.ToLower(method)]
+	return ok && len(op.Security) > 0
+}
+
+// envelope is the schema of response.SuccessResponse carrying data.
+func (s *Spec) envelope(data any) *Schema {
+	schema := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"success": {Type: "boolean"},
+			"message": {Type: "string"},
+		},
+	}
+
+	if data != nil {
+		schema.Properties["data"] = s.schemaFor(reflect.TypeOf(data))
+	}
+	return schema
+}
+
+func rawSchema(contentType string) *Schema {
+	switch {
+	case strings.Contains(contentType, "json"):
+		return &Schema{}
+	case strings.HasPrefix(contentType, "text/"):
+		return &Schema{Type: "string"}
+	default:
+		return &Schema{Type: "string", Format: "binary"}
+	}
+}
+
+// tagFor groups operations by the first path segment after any API prefix,
+// e.g. "/api/v1/users/{userID}" is tagged "users".
+func tagFor(path string) string {
+	for segment := range strings.SplitSeq(path, "/") {
+		if segment == "" || segment == "api" || isVersion(segment) {
+			continue
+		}
+		name, _, _ := strings.Cut(segment, ":")
+		return name
+	}
+	return ""
+}
+
+func isVersion(segment string) bool {
+	if len(segment) < 2 || segment[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(segment[1:])
+	return err == nil
+}
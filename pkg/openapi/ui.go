@@ -0,0 +1,31 @@
+package openapi
+
+import (
+	_ "embed"
+	"html/template"
+	"net/http"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files"
+)
+
+//go:embed ui.html
+var uiHTML string
+
+var uiTemplate = template.Must(template.New("ui").Parse(uiHTML))
+
+// UI serves Swagger UI for the spec at specURL. The Swagger UI assets are
+// embedded in the binary. Mount it under a prefix with http.StripPrefix.
+func UI(title, specURL string) http.Handler {
+	assets := http.FileServer(swaggerFiles.HTTP)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "" && r.URL.Path != "/" && !strings.HasSuffix(r.URL.Path, "/index.html") {
+			assets.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = uiTemplate.Execute(w, map[string]string{"Title": title, "SpecURL": specURL})
+	})
+}
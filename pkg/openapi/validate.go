@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+func init() {
+	// kin-openapi decodes JSON Patch bodies but not JSON Merge Patch ones.
+	openapi3filter.RegisterBodyDecoder("application/merge-patch+json", openapi3filter.JSONBodyDecoder)
+}
+
+// Violation is one way a request does not match the spec.
+type Violation struct {
+	// In is where the problem is: path, query, header or body.
+	In string `json:"in"`
+	// Name is the parameter name, for path, query and header violations.
+	Name string `json:"name,omitempty"`
+	// Field is the JSON pointer of the offending body field, if any.
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// ValidateRequests returns middleware that checks every request for a
+// documented operation against the spec: path and query parameters, and JSON
+// bodies against the schema of the request type. Mismatches are rejected with
+// a 400 listing every Violation. Requests for undocumented routes, and raw
+// uploads documented through Doc.Consumes, are passed through unchecked.
+// Authentication is left to the routes themselves.
+//
+// The spec is loaded on the first request, once every route is registered.
+func (s *Spec) ValidateRequests(next http.Handler) http.Handler {
+	load := sync.OnceValues(s.compile)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router, err := load()
+		if err != nil {
+			response.RespondError(w, http.StatusInternalServerError, "API_ERROR", "Failed to load OpenAPI spec", nil)
+			return
+		}
+
+		route, pathParams, err := router.FindRoute(r)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		input := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				MultiError:         true,
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+				ExcludeRequestBody: rawBody(route, r),
+			},
+		}
+
+		if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+			response.RespondError(
+				w, http.StatusBadRequest, "VALIDATION_ERROR", "Request does not match the API schema", violations(err),
+			)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Spec) compile() (routers.Router, error) {
+	data, err := s.JSON()
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := openapi3.NewLoader().LoadFromData(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(openapi3.NewLoader().Context); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return gorillamux.NewRouter(doc)
+}
+
+// rawBody reports whether the request carries one of the non-JSON bodies the
+// operation documents, such as an image upload, which is not validated.
+func rawBody(route *routers.Route, r *http.Request) bool {
+	body := route.Operation.RequestBody
+	if body == nil || body.Value == nil || r.Header.Get("Content-Type") == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || strings.Contains(mediaType, "json") {
+		return false
+	}
+	return body.Value.Content.Get(mediaType) != nil
+}
+
+// violations flattens the errors returned by openapi3filter.
+func violations(err error) []Violation {
+	var result []Violation
+
+	var walk func(err error, at Violation)
+	walk = func(err error, at Violation) {
+		switch e := err.(type) {
+		case openapi3.MultiError:
+			for _, err := range e {
+				walk(err, at)
+			}
+		case *openapi3filter.RequestError:
+			if e.Parameter != nil {
+				at.In, at.Name = e.Parameter.In, e.Parameter.Name
+			} else if e.RequestBody != nil {
+				at.In = "body"
+			}
+			if e.Err == nil {
+				at.Message = e.Reason
+				result = append(result, at)
+				return
+			}
+			walk(e.Err, at)
+		case *openapi3.SchemaError:
+			if pointer := e.JSONPointer(); len(pointer) > 0 {
+				at.Field = "/" + strings.Join(pointer, "/")
+			}
+			at.Message = e.Reason
+			result = append(result, at)
+		default:
+			at.Message = err.Error()
+			result = append(result, at)
+		}
+	}
+
+	walk(err, Violation{})
+	return result
+}
@@ -0,0 +1,46 @@
+package pagination
+
+import "github.com/okta/okta-sdk-golang/v5/okta"
+
+// All follows the Okta Link header of a list response and appends every
+// remaining page to the items already returned by the first call.
+func All[T any](items []T, resp *okta.APIResponse) ([]T, error) {
+	for resp != nil && resp.HasNextPage() {
+		var page []T
+
+		next, err := resp.Next(&page)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, page...)
+		resp = next
+	}
+
+	return items, nil
+}
+
+// Each calls fn with the first page and then with every following page as it
+// is fetched, so callers can process large collections without holding them
+// in memory. Iteration stops at the first error returned by fn.
+func Each[T any](items []T, resp *okta.APIResponse, fn func(page []T) error) error {
+	if err := fn(items); err != nil {
+		return err
+	}
+
+	for resp != nil && resp.HasNextPage() {
+		var page []T
+
+		next, err := resp.Next(&page)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(page); err != nil {
+			return err
+		}
+		resp = next
+	}
+
+	return nil
+}
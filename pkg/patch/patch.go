@@ -0,0 +1,425 @@
+// Package patch applies JSON Merge Patch (RFC 7386) and JSON Patch
+// (RFC 6902) documents to the JSON form of a resource.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Media types of the patch formats.
+const (
+	MergePatch = "application/merge-patch+json"
+	JSONPatch  = "application/json-patch+json"
+)
+
+// Limits on patches, so a small patch cannot build a huge resource.
+const (
+	// MaxBodySize is the largest patch, in bytes, handlers read.
+	MaxBodySize = 1 << 20
+	// MaxOperations is how many operations a JSON Patch may have.
+	MaxOperations = 1000
+	// MaxNodes is how many values, counting every member and item, the
+	// operations of a JSON Patch may add or copy. Without it, copying the
+	// resource into itself would double it with every operation.
+	MaxNodes = 100_000
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("patch must be sent as " + MergePatch + " or " + JSONPatch)
+	// ErrInvalidPatch is returned for patches that are not well formed, such
+	// as an operation without a path.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrConflict is returned for patches that cannot be applied to the
+	// resource as it is, such as a test that fails or a path that does not
+	// exist.
+	ErrConflict = errors.New("patch does not apply to the resource")
+	// ErrInvalidResult is returned when the patched resource is not valid,
+	// such as a field of the wrong type or one the resource does not have.
+	ErrInvalidResult = errors.New("patched resource is invalid")
+)
+
+// Apply applies body, a patch of the media type contentType names, to the
+// JSON form of doc and decodes the result into v. Fields of the result that v
+// does not have are rejected rather than dropped.
+func Apply(contentType string, doc any, body []byte, v any) error {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != MergePatch && mediaType != JSONPatch) {
+		return ErrUnsupportedMediaType
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode resource: %w", err)
+	}
+	var target any
+	if err := json.Unmarshal(data, &target); err != nil {
+		return fmt.Errorf("failed to decode resource: %w", err)
+	}
+
+	if mediaType == MergePatch {
+		var merge any
+		if err := json.Unmarshal(body, &merge); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidPatch, strings.TrimPrefix(err.Error(), "json: "))
+		}
+		target = mergePatch(target, merge)
+	} else {
+		var operations []operation
+		if err := json.Unmarshal(body, &operations); err != nil {
+			return fmt.Errorf("%w: must be an array of operations: %s", ErrInvalidPatch,
+				strings.TrimPrefix(err.Error(), "json: "),
+			)
+		}
+		if len(operations) > MaxOperations {
+			return fmt.Errorf("%w: more than %d operations", ErrInvalidPatch, MaxOperations)
+		}
+		budget := MaxNodes
+		for i, op := range operations {
+			if target, err = op.apply(target, &budget); err != nil {
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+	}
+
+	if data, err = json.Marshal(target); err != nil {
+		return fmt.Errorf("failed to encode patched resource: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return fmt.Errorf("%w: field %q cannot be a %s", ErrInvalidResult, typeErr.Field, typeErr.Value)
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidResult, strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// mergePatch applies patch to target as RFC 7386 describes: objects are
+// merged member by member, null removes a member, and anything else replaces
+// the target.
+func mergePatch(target, patch any) any {
+	patchObject, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]any)
+	if !ok {
+		targetObject = make(map[string]any)
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// operation is one step of a JSON Patch. Value is nil when the operation has
+// none, and "null" when its value is null.
+type operation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// apply returns doc with the operation made. The values it adds or copies
+// are taken from budget, the number of values the patch may still add.
+func (op operation) apply(doc any, budget *int) (any, error) {
+	if op.Path == nil {
+		return nil, fmt.Errorf("%w: path is required", ErrInvalidPatch)
+	}
+	path, err := parsePointer(*op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var from []string
+	switch op.Op {
+	case "add", "replace", "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: %s requires a value", ErrInvalidPatch, op.Op)
+		}
+	case "move", "copy":
+		if op.From == nil {
+			return nil, fmt.Errorf("%w: %s requires from", ErrInvalidPatch, op.Op)
+		}
+		if from, err = parsePointer(*op.From); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+
+	var value any
+	if op.Value != nil {
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPatch, strings.TrimPrefix(err.Error(), "json: "))
+		}
+	}
+
+	if op.Op == "add" || op.Op == "replace" {
+		if err := spend(budget, value); err != nil {
+			return nil, err
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		return add(doc, path, value)
+	case "remove":
+		return remove(doc, path)
+	case "replace":
+		if len(path) == 0 {
+			return value, nil
+		}
+		if doc, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "move":
+		if len(path) > len(from) && slices.Equal(path[:len(from)], from) {
+			return nil, fmt.Errorf("%w: cannot move %q into itself", ErrInvalidPatch, *op.From)
+		}
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if doc, err = remove(doc, from); err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		if value, err = get(doc, from); err != nil {
+			return nil, err
+		}
+		if err := spend(budget, value); err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	default:
+		current, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, fmt.Errorf("%w: test of %q failed", ErrConflict, *op.Path)
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens.
+// The empty pointer is the whole document.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", ErrInvalidPatch, pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for i, token := range path {
+		child, err := child(doc, token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, pointer(path[:i+1]))
+		}
+		doc = child
+	}
+	return doc, nil
+}
+
+// add sets the value at path, inserting it into an array or adding an
+// object member. The parent of path must exist.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	return update(doc, path, func(parent any, key string) (any, error) {
+		switch parent := parent.(type) {
+		case map[string]any:
+			parent[key] = value
+			return parent, nil
+		case []any:
+			if key == "-" {
+				return append(parent, value), nil
+			}
+			index, err := arrayIndex(key, len(parent)+1)
+			if err != nil {
+				return nil, err
+			}
+			return append(parent[:index], append([]any{value}, parent[index:]...)...), nil
+		default:
+			return nil, fmt.Errorf("%w: parent is not an object or array", ErrConflict)
+		}
+	})
+}
+
+// remove deletes the value at path, which must exist.
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("%w: the whole resource cannot be removed", ErrInvalidPatch)
+	}
+
+	return update(doc, path, func(parent any, key string) (any, error) {
+		switch parent := parent.(type) {
+		case map[string]any:
+			if _, ok := parent[key]; !ok {
+				return nil, fmt.Errorf("%w: path does not exist", ErrConflict)
+			}
+			delete(parent, key)
+			return parent, nil
+		case []any:
+			index, err := arrayIndex(key, len(parent))
+			if err != nil {
+				return nil, err
+			}
+			return append(parent[:index], parent[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("%w: parent is not an object or array", ErrConflict)
+		}
+	})
+}
+
+// update calls fn with the container that holds the last token of path and
+// that token, and returns doc with the container fn returns in its place.
+func update(doc any, path []string, fn func(parent any, key string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		updated, err := fn(doc, path[0])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", err, pointer(path))
+		}
+		return updated, nil
+	}
+
+	next, err := child(doc, path[0])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, pointer(path[:1]))
+	}
+	updated, err := update(next, path[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch doc := doc.(type) {
+	case map[string]any:
+		doc[path[0]] = updated
+	case []any:
+		index, _ := arrayIndex(path[0], len(doc))
+		doc[index] = updated
+	}
+	return doc, nil
+}
+
+func child(doc any, key string) (any, error) {
+	switch doc := doc.(type) {
+	case map[string]any:
+		value, ok := doc[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: path does not exist", ErrConflict)
+		}
+		return value, nil
+	case []any:
+		index, err := arrayIndex(key, len(doc))
+		if err != nil {
+			return nil, err
+		}
+		return doc[index], nil
+	default:
+		return nil, fmt.Errorf("%w: path does not exist", ErrConflict)
+	}
+}
+
+// arrayIndex parses key as an index below limit.
+func arrayIndex(key string, limit int) (int, error) {
+	index, err := strconv.Atoi(key)
+	if err != nil || index < 0 || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("%w: %q is not an array index", ErrInvalidPatch, key)
+	}
+	if index >= limit {
+		return 0, fmt.Errorf("%w: array index %d is out of range", ErrConflict, index)
+	}
+	return index, nil
+}
+
+// pointer joins tokens back into a JSON Pointer for error messages.
+func pointer(tokens []string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// spend takes the values of value from budget, and fails once it runs out.
+func spend(budget *int, value any) error {
+	*budget -= countNodes(value, *budget+1)
+	if *budget < 0 {
+		return fmt.Errorf("%w: the patch adds more than %d values", ErrInvalidPatch, MaxNodes)
+	}
+	return nil
+}
+
+// countNodes counts value and the members and items within it, stopping once
+// the count reaches limit.
+func countNodes(value any, limit int) int {
+	count := 1
+	switch value := value.(type) {
+	case map[string]any:
+		for _, child := range value {
+			if count >= limit {
+				break
+			}
+			count += countNodes(child, limit-count)
+		}
+	case []any:
+		for _, child := range value {
+			if count >= limit {
+				break
+			}
+			count += countNodes(child, limit-count)
+		}
+	}
+	return count
+}
+
+func deepCopy(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		copied := make(map[string]any, len(value))
+		for key, child := range value {
+			copied[key] = deepCopy(child)
+		}
+		return copied
+	case []any:
+		copied := make([]any, len(value))
+		for i, child := range value {
+			copied[i] = deepCopy(child)
+		}
+		return copied
+	default:
+		return value
+	}
+}
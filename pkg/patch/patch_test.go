@@ -0,0 +1,152 @@
+package patch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+type resource struct {
+	Name    string         `json:"name"`
+	Tags    []string       `json:"tags"`
+	Profile map[string]any `json:"profile"`
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        string
+		wantErr     error
+		wantMessage string
+		maxDuration time.Duration
+	}{
+		{name: "merge replaces a field", contentType: MergePatch, body: `{"name":"ops"}`, want: `{"name":"ops","tags":["a","b"],"profile":{"x":1}}`},
+		{name: "merge null removes a field", contentType: MergePatch, body: `{"tags":null}`, want: `{"name":"eng","tags":null,"profile":{"x":1}}`},
+		{name: "merge nested object", contentType: MergePatch, body: `{"profile":{"x":null,"y":2}}`, want: `{"name":"eng","tags":["a","b"],"profile":{"y":2}}`},
+		{name: "media type parameters", contentType: MergePatch + "; charset=utf-8", body: `{}`, want: `{"name":"eng","tags":["a","b"],"profile":{"x":1}}`},
+		{
+			name: "deeply nested merge", contentType: MergePatch,
+			body: `{"profile":` + strings.Repeat(`{"a":`, 5000) + "1" + strings.Repeat("}", 5001),
+		},
+		{name: "merge of another type", contentType: MergePatch, body: `[1]`, wantErr: ErrInvalidResult},
+		{name: "merge malformed", contentType: MergePatch, body: `{`, wantErr: ErrInvalidPatch},
+		{name: "merge unknown field", contentType: MergePatch, body: `{"owner":"x"}`, wantErr: ErrInvalidResult},
+		{name: "merge wrong type", contentType: MergePatch, body: `{"name":1}`, wantErr: ErrInvalidResult, wantMessage: `field "name"`},
+		{name: "unsupported media type", contentType: "application/json", body: `{}`, wantErr: ErrUnsupportedMediaType},
+		{name: "malformed media type", contentType: ";;", body: `{}`, wantErr: ErrUnsupportedMediaType},
+
+		{name: "append to array", body: `[{"op":"add","path":"/tags/-","value":"c"}]`, want: `{"name":"eng","tags":["a","b","c"],"profile":{"x":1}}`},
+		{name: "insert into array", body: `[{"op":"add","path":"/tags/0","value":"z"}]`, want: `{"name":"eng","tags":["z","a","b"],"profile":{"x":1}}`},
+		{name: "insert at end of array", body: `[{"op":"add","path":"/tags/2","value":"c"}]`, want: `{"name":"eng","tags":["a","b","c"],"profile":{"x":1}}`},
+		{name: "remove from array", body: `[{"op":"remove","path":"/tags/0"}]`, want: `{"name":"eng","tags":["b"],"profile":{"x":1}}`},
+		{name: "replace", body: `[{"op":"replace","path":"/name","value":"ops"}]`, want: `{"name":"ops","tags":["a","b"],"profile":{"x":1}}`},
+		{name: "move", body: `[{"op":"move","from":"/profile/x","path":"/profile/y"}]`, want: `{"name":"eng","tags":["a","b"],"profile":{"y":1}}`},
+		{name: "copy", body: `[{"op":"copy","from":"/name","path":"/profile/n"}]`, want: `{"name":"eng","tags":["a","b"],"profile":{"n":"eng","x":1}}`},
+		{name: "test passes", body: `[{"op":"test","path":"/tags","value":["a","b"]}]`, want: `{"name":"eng","tags":["a","b"],"profile":{"x":1}}`},
+		{name: "escaped pointer", body: `[{"op":"add","path":"/profile/a~1b~0c","value":true}]`, want: `{"name":"eng","tags":["a","b"],"profile":{"a/b~c":true,"x":1}}`},
+		{name: "null value", body: `[{"op":"add","path":"/profile/x","value":null}]`, want: `{"name":"eng","tags":["a","b"],"profile":{"x":null}}`},
+		{name: "no operations", body: `[]`, want: `{"name":"eng","tags":["a","b"],"profile":{"x":1}}`},
+
+		{name: "test fails", body: `[{"op":"test","path":"/name","value":"ops"}]`, wantErr: ErrConflict},
+		{name: "not an array", body: `{"op":"add"}`, wantErr: ErrInvalidPatch},
+		{name: "missing path", body: `[{"op":"remove"}]`, wantErr: ErrInvalidPatch, wantMessage: "path is required"},
+		{name: "relative path", body: `[{"op":"remove","path":"name"}]`, wantErr: ErrInvalidPatch},
+		{name: "unknown op", body: `[{"op":"merge","path":"/name"}]`, wantErr: ErrInvalidPatch},
+		{name: "add without value", body: `[{"op":"add","path":"/name"}]`, wantErr: ErrInvalidPatch},
+		{name: "copy without from", body: `[{"op":"copy","path":"/name"}]`, wantErr: ErrInvalidPatch},
+		{name: "remove missing member", body: `[{"op":"remove","path":"/profile/y"}]`, wantErr: ErrConflict},
+		{name: "missing parent", body: `[{"op":"add","path":"/profile/a/b","value":1}]`, wantErr: ErrConflict},
+		{name: "through a string", body: `[{"op":"add","path":"/name/x","value":1}]`, wantErr: ErrConflict},
+		{name: "index out of range", body: `[{"op":"remove","path":"/tags/2"}]`, wantErr: ErrConflict},
+		{name: "index with leading zero", body: `[{"op":"remove","path":"/tags/01"}]`, wantErr: ErrInvalidPatch},
+		{name: "negative index", body: `[{"op":"remove","path":"/tags/-1"}]`, wantErr: ErrInvalidPatch},
+		{name: "huge index", body: `[{"op":"remove","path":"/tags/99999999999999999999"}]`, wantErr: ErrInvalidPatch},
+		{name: "remove end of array", body: `[{"op":"remove","path":"/tags/-"}]`, wantErr: ErrInvalidPatch},
+		{name: "remove whole resource", body: `[{"op":"remove","path":""}]`, wantErr: ErrInvalidPatch},
+		{name: "move into itself", body: `[{"op":"move","from":"/profile","path":"/profile/x"}]`, wantErr: ErrInvalidPatch},
+		{name: "replace whole resource", body: `[{"op":"replace","path":"","value":{"name":"x","extra":1}}]`, wantErr: ErrInvalidResult},
+		{name: "too many operations", body: repeatOps(`{"op":"test","path":"/name","value":"eng"}`, MaxOperations+1), wantErr: ErrInvalidPatch, wantMessage: "more than"},
+		{
+			name: "copies doubling the resource", body: copyBomb(60),
+			wantErr: ErrInvalidPatch, wantMessage: "adds more than", maxDuration: time.Second,
+		},
+		{
+			name: "many long removals", body: longArray(MaxNodes/2) + "," + repeatOps(`{"op":"remove","path":"/profile/list/0"}`, MaxOperations-1)[1:],
+			maxDuration: time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = JSONPatch
+			}
+			doc := &resource{Name: "eng", Tags: []string{"a", "b"}, Profile: map[string]any{"x": 1}}
+
+			start := time.Now()
+			var got resource
+			err := Apply(contentType, doc, []byte(tt.body), &got)
+			if tt.maxDuration > 0 && time.Since(start) > tt.maxDuration {
+				t.Errorf("Apply() took %s, want at most %s", time.Since(start), tt.maxDuration)
+			}
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Apply() error = %v, want %v", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), tt.wantMessage) {
+					t.Errorf("Apply() error = %q, want one containing %q", err, tt.wantMessage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+			if tt.want == "" {
+				return
+			}
+			data, _ := json.Marshal(got)
+			if string(data) != tt.want {
+				t.Errorf("Apply() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyLeavesDocUnchanged(t *testing.T) {
+	doc := &resource{Name: "eng", Tags: []string{"a", "b"}, Profile: map[string]any{"x": 1}}
+	var got resource
+	if err := Apply(JSONPatch, doc, []byte(`[{"op":"remove","path":"/tags/0"},{"op":"add","path":"/profile/y","value":2}]`), &got); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(doc.Tags) != 2 || len(doc.Profile) != 1 {
+		t.Errorf("Apply() changed doc to %+v", doc)
+	}
+}
+
+// repeatOps is a JSON Patch of op n times.
+func repeatOps(op string, n int) string {
+	return "[" + strings.TrimSuffix(strings.Repeat(op+",", n), ",") + "]"
+}
+
+// copyBomb is a JSON Patch copying the profile into itself n times, which
+// doubles its size every time.
+func copyBomb(n int) string {
+	ops := make([]string, n)
+	for i := range ops {
+		ops[i] = fmt.Sprintf(`{"op":"copy","from":"/profile","path":"/profile/c%d"}`, i)
+	}
+	return "[" + strings.Join(ops, ",") + "]"
+}
+
+// longArray is the start of a JSON Patch adding an array of n numbers to the
+// profile, to be followed by more operations.
+func longArray(n int) string {
+	return `[{"op":"add","path":"/profile/list","value":[` + strings.TrimSuffix(strings.Repeat("1,", n), ",") + `]}`
+}
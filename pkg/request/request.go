@@ -0,0 +1,134 @@
+// Package request decodes JSON request bodies in the mode chosen for the
+// API version a request was made to.
+package request
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Mode says how forgiving decoding is.
+type Mode string
+
+const (
+	// Lenient ignores fields the request type does not have, and anything
+	// after the first JSON value.
+	Lenient Mode = "lenient"
+	// Strict rejects unknown fields, such as misspelled field names, and
+	// bodies holding more than one JSON value.
+	Strict Mode = "strict"
+)
+
+// IsMode reports whether mode is one of the modes.
+func IsMode(mode string) bool {
+	return mode == string(Lenient) || mode == string(Strict)
+}
+
+type modeKey struct{}
+
+// WithMode returns middleware that decodes the bodies of the requests it
+// serves in mode.
+func WithMode(mode Mode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), modeKey{}, mode)))
+		})
+	}
+}
+
+// ModeFromContext returns the decoding mode of ctx. Requests no middleware
+// set a mode for are decoded leniently.
+func ModeFromContext(ctx context.Context) Mode {
+	if mode, ok := ctx.Value(modeKey{}).(Mode); ok {
+		return mode
+	}
+	return Lenient
+}
+
+// IfMatch reports whether the If-Match header of r allows changing a
+// resource whose current entity tag is etag. Requests without the header are
+// allowed, and "*" matches any tag. Weak tags never match, as If-Match
+// compares tags strongly.
+func IfMatch(r *http.Request, etag string) bool {
+	header := r.Header.Values("If-Match")
+	if len(header) == 0 {
+		return true
+	}
+
+	for _, value := range header {
+		for tag := range strings.SplitSeq(value, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || tag == etag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Decode decodes the JSON body of r into v in the request's mode. An empty
+// body returns io.EOF. Other errors say what is wrong with the body in terms
+// a client can act on, such as the name of an unknown field.
+func Decode(r *http.Request, v any) error {
+	strict := ModeFromContext(r.Context()) == Strict
+
+	decoder := json.NewDecoder(r.Body)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if err := decoder.Decode(v); err != nil {
+		return describe(err)
+	}
+	if strict {
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return errors.New("body must hold a single JSON value")
+		}
+	}
+	return nil
+}
+
+// describe rewords the errors of encoding/json, which name Go types, for
+// clients.
+func describe(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return err
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("body ends before the JSON value is complete")
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("malformed JSON at offset %d", syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return fmt.Errorf("body must be a JSON %s, not %s", kind(typeErr.Type.Kind().String()), typeErr.Value)
+		}
+		return fmt.Errorf("field %q must be a %s, not %s", typeErr.Field, kind(typeErr.Type.Kind().String()), typeErr.Value)
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	default:
+		return err
+	}
+}
+
+// kind names a Go kind as the JSON type it decodes from.
+func kind(goKind string) string {
+	switch {
+	case strings.HasPrefix(goKind, "int"), strings.HasPrefix(goKind, "uint"), strings.HasPrefix(goKind, "float"):
+		return "number"
+	case goKind == "bool":
+		return "boolean"
+	case goKind == "slice", goKind == "array":
+		return "array"
+	case goKind == "struct", goKind == "map":
+		return "object"
+	default:
+		return goKind
+	}
+}
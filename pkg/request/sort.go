@@ -0,0 +1,46 @@
+package request
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Query parameters that sort a listing.
+const (
+	SortByParam    = "sortBy"
+	SortOrderParam = "sortOrder"
+)
+
+// Sort reads the sortBy and sortOrder parameters of r and returns the
+// comparison to sort a listing with, or nil when sortBy is not set. fields
+// maps each field that can be sorted by to its comparison in ascending
+// order; sortOrder=desc reverses it.
+func Sort[T any](r *http.Request, fields map[string]func(a, b T) int) (func(a, b T) int, error) {
+	query := r.URL.Query()
+	sortBy, order := query.Get(SortByParam), query.Get(SortOrderParam)
+	if sortBy == "" {
+		if order != "" {
+			return nil, fmt.Errorf("%s requires %s", SortOrderParam, SortByParam)
+		}
+		return nil, nil
+	}
+
+	compare, ok := fields[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("%s must be one of %s", SortByParam,
+			strings.Join(slices.Sorted(maps.Keys(fields)), ", "),
+		)
+	}
+
+	switch order {
+	case "", "asc":
+		return compare, nil
+	case "desc":
+		return func(a, b T) int { return compare(b, a) }, nil
+	default:
+		return nil, fmt.Errorf("%s must be asc or desc", SortOrderParam)
+	}
+}
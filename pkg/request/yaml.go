@@ -0,0 +1,177 @@
+package request
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/iamBelugaa/iam/pkg/patch"
+	"github.com/iamBelugaa/iam/pkg/response"
+)
+
+const (
+	// MaxYAMLBodySize is the largest YAML body accepted, in bytes.
+	MaxYAMLBodySize = 1 << 20
+	// maxYAMLNodes caps the nodes a YAML body may expand to once its aliases
+	// are followed, so a few bytes of nested aliases cannot produce an
+	// exponentially large value.
+	maxYAMLNodes = 100_000
+)
+
+// DecodeYAML is middleware that turns YAML request bodies into JSON before
+// anything reads them, so they are validated against the spec and decoded
+// into the same request types as JSON bodies. A PATCH body becomes a JSON
+// Merge Patch when it is a mapping and a JSON Patch when it is a sequence.
+func DecodeYAML(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !response.IsYAML(r.Header.Get("Content-Type")) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxYAMLBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				response.RespondError(w, http.StatusRequestEntityTooLarge, "API_ERROR",
+					fmt.Sprintf("Request body is larger than %d bytes", MaxYAMLBodySize), nil)
+				return
+			}
+			response.RespondError(w, http.StatusBadRequest, "API_ERROR", "Failed to read request body", nil)
+			return
+		}
+
+		value, err := yamlToJSON(data)
+		if err != nil {
+			response.RespondError(w, http.StatusBadRequest, "API_ERROR", "Invalid request body: "+err.Error(), nil)
+			return
+		}
+
+		contentType := "application/json"
+		if r.Method == http.MethodPatch {
+			contentType = patch.MergePatch
+			if bytes.HasPrefix(value, []byte("[")) {
+				contentType = patch.JSONPatch
+			}
+		}
+
+		r.Body = io.NopCloser(bytes.NewReader(value))
+		r.ContentLength = int64(len(value))
+		r.Header.Set("Content-Type", contentType)
+		r.Header.Set("Content-Length", strconv.Itoa(len(value)))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// yamlToJSON converts a single YAML document to JSON. An empty body stays
+// empty.
+func yamlToJSON(data []byte) ([]byte, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var document yaml.Node
+	if err := decoder.Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("malformed YAML: %w", err)
+	}
+	var next yaml.Node
+	if err := decoder.Decode(&next); !errors.Is(err, io.EOF) {
+		return nil, errors.New("body must hold a single YAML document")
+	}
+
+	c := &converter{following: make(map[*yaml.Node]bool)}
+	value, err := c.jsonValue(&document)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
+
+// converter converts the nodes of one YAML document, counting the nodes
+// it expands and the anchors whose aliases it is following.
+type converter struct {
+	nodes     int
+	following map[*yaml.Node]bool
+}
+
+// jsonValue returns the JSON value of a YAML node. Scalars keep the type
+// YAML resolves them to; timestamps and other tags JSON has no type for are
+// kept as the strings they were written as. An alias is expanded in place,
+// unless it refers to a node that contains it.
+func (c *converter) jsonValue(node *yaml.Node) (any, error) {
+	if c.nodes++; c.nodes > maxYAMLNodes {
+		return nil, fmt.Errorf("body expands to more than %d values", maxYAMLNodes)
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return c.jsonValue(node.Content[0])
+	case yaml.AliasNode:
+		if node.Alias == nil || c.following[node.Alias] {
+			return nil, fmt.Errorf("line %d: alias *%s refers to a node that contains it", node.Line, node.Value)
+		}
+		c.following[node.Alias] = true
+		defer delete(c.following, node.Alias)
+		return c.jsonValue(node.Alias)
+
+	case yaml.MappingNode:
+		object := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("line %d: mapping keys must be scalars", key.Line)
+			}
+			value, err := c.jsonValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			object[key.Value] = value
+		}
+		return object, nil
+
+	case yaml.SequenceNode:
+		array := make([]any, 0, len(node.Content))
+		for _, child := range node.Content {
+			value, err := c.jsonValue(child)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		return array, nil
+	}
+
+	switch node.ShortTag() {
+	case "!!null":
+		return nil, nil
+	case "!!bool":
+		var value bool
+		err := node.Decode(&value)
+		return value, err
+	case "!!int":
+		var value int64
+		if err := node.Decode(&value); err != nil {
+			return nil, fmt.Errorf("line %d: integer %s is out of range", node.Line, node.Value)
+		}
+		return value, nil
+	case "!!float":
+		var value float64
+		if err := node.Decode(&value); err != nil || math.IsInf(value, 0) || math.IsNaN(value) {
+			return nil, fmt.Errorf("line %d: %s is not a number JSON can hold", node.Line, node.Value)
+		}
+		return value, nil
+	default:
+		return node.Value, nil
+	}
+}
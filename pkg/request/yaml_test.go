@@ -0,0 +1,110 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestYAMLToJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    string
+		wantErr string
+	}{
+		{name: "empty body", yaml: "", want: ""},
+		{name: "mapping", yaml: "name: Engineering\ncount: 3\nactive: true\n", want: `{"active":true,"count":3,"name":"Engineering"}`},
+		{name: "sequence", yaml: "- a\n- 1.5\n- null\n", want: `["a",1.5,null]`},
+		{name: "flow style", yaml: `{tags: [x, y]}`, want: `{"tags":["x","y"]}`},
+		{name: "timestamp stays a string", yaml: "at: 2024-01-02T03:04:05Z", want: `{"at":"2024-01-02T03:04:05Z"}`},
+		{name: "quoted number stays a string", yaml: `id: "123"`, want: `{"id":"123"}`},
+		{name: "alias expanded", yaml: "base: &b {x: 1}\ncopy: *b\n", want: `{"base":{"x":1},"copy":{"x":1}}`},
+		{name: "two documents", yaml: "a: 1\n---\nb: 2\n", wantErr: "single YAML document"},
+		{name: "malformed", yaml: "a: [1, 2", wantErr: "malformed YAML"},
+		{name: "non-scalar key", yaml: "? [a, b]\n: 1\n", wantErr: "mapping keys must be scalars"},
+		{name: "infinity", yaml: "n: .inf", wantErr: "not a number JSON can hold"},
+		{name: "not a number", yaml: "n: .nan", wantErr: "not a number JSON can hold"},
+		{name: "alias cycle", yaml: "&a [*a]", wantErr: "refers to a node that contains it"},
+		{name: "alias cycle through a mapping", yaml: "&a {self: *a}", wantErr: "refers to a node that contains it"},
+		{name: "alias fan-out", yaml: aliasBomb(10), wantErr: "expands to more than"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yamlToJSON([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("yamlToJSON() error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("yamlToJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("yamlToJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// aliasBomb is a YAML document whose every level refers to the one below it
+// ten times, so it expands to 10^levels values.
+func aliasBomb(levels int) string {
+	var b strings.Builder
+	b.WriteString("l0: &l0 [x, x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= levels; i++ {
+		prev := "*l" + strconv.Itoa(i-1)
+		fmt.Fprintf(&b, "l%d: &l%d [%s]\n", i, i, strings.TrimSuffix(strings.Repeat(prev+", ", 10), ", "))
+	}
+	return b.String()
+}
+
+func TestDecodeYAML(t *testing.T) {
+	tests := []struct {
+		name            string
+		method          string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{name: "converted", method: http.MethodPost, body: "name: x", wantStatus: http.StatusOK, wantContentType: "application/json", wantBody: `{"name":"x"}`},
+		{name: "merge patch", method: http.MethodPatch, body: "name: x", wantStatus: http.StatusOK, wantContentType: "application/merge-patch+json"},
+		{name: "json patch", method: http.MethodPatch, body: "- {op: remove, path: /a}", wantStatus: http.StatusOK, wantContentType: "application/json-patch+json"},
+		{name: "invalid", method: http.MethodPost, body: "a: [", wantStatus: http.StatusBadRequest},
+		{name: "alias cycle", method: http.MethodPost, body: "&a [*a]", wantStatus: http.StatusBadRequest},
+		{name: "oversized body", method: http.MethodPost, body: "a: " + strings.Repeat("x", MaxYAMLBodySize), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotContentType, gotBody string
+			handler := DecodeYAML(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotContentType = r.Header.Get("Content-Type")
+				body, _ := io.ReadAll(r.Body)
+				gotBody = string(body)
+			}))
+
+			r := httptest.NewRequest(tt.method, "/", strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "application/yaml")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantContentType != "" && gotContentType != tt.wantContentType {
+				t.Errorf("Content-Type = %q, want %q", gotContentType, tt.wantContentType)
+			}
+			if tt.wantBody != "" && gotBody != tt.wantBody {
+				t.Errorf("body = %s, want %s", gotBody, tt.wantBody)
+			}
+		})
+	}
+}
@@ -1,8 +1,16 @@
 package response
 
 import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iamBelugaa/iam/pkg/logger"
 )
 
 type SuccessResponse struct {
@@ -17,6 +25,8 @@ type ErrorResponse struct {
 	Message   string `json:"message"`
 	ErrorCode string `json:"errorCode"`
 	Details   any    `json:"details,omitempty"`
+	// RequestID correlates the error with the server's logs.
+	RequestID string `json:"requestId,omitempty"`
 }
 
 func RespondSuccess(w http.ResponseWriter, code int, msg string, data any) {
@@ -24,11 +34,96 @@ func RespondSuccess(w http.ResponseWriter, code int, msg string, data any) {
 	respond(w, code, response)
 }
 
+// RespondError writes an error response, including the request ID the
+// request logging middleware set on w.
 func RespondError(w http.ResponseWriter, status int, code, msg string, details any) {
-	response := ErrorResponse{Success: false, Code: status, Message: msg, ErrorCode: code, Details: details}
+	response := ErrorResponse{
+		Success:   false,
+		Code:      status,
+		Message:   msg,
+		ErrorCode: code,
+		Details:   details,
+		RequestID: w.Header().Get(logger.RequestIDHeader),
+	}
 	respond(w, status, response)
 }
 
+// ETag returns a strong entity tag for the state of a resource that the
+// given values identify, such as its ID and Okta lastUpdated time.
+func ETag(values ...string) string {
+	hash := sha256.Sum256([]byte(strings.Join(values, "\x00")))
+	return `"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+func RespondCSV(w http.ResponseWriter, filename string, header []string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return
+	}
+
+	_ = writer.WriteAll(rows)
+}
+
+// WantsStream reports whether the client asked for newline-delimited JSON,
+// either with ?stream=true or an Accept header of application/x-ndjson.
+func WantsStream(r *http.Request) bool {
+	if r.URL.Query().Get("stream") == "true" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/x-ndjson")
+}
+
+// Stream writes newline-delimited JSON with chunked transfer, flushing after
+// every value so clients receive records while later ones are still fetched.
+// The status line is sent with the first value, so an error that happens
+// before anything was written can still be answered with RespondError.
+type Stream struct {
+	w          http.ResponseWriter
+	encoder    *json.Encoder
+	controller *http.ResponseController
+	started    bool
+}
+
+func NewStream(w http.ResponseWriter) *Stream {
+	return &Stream{w: w, encoder: json.NewEncoder(w), controller: http.NewResponseController(w)}
+}
+
+// Started reports whether the status line has been sent.
+func (s *Stream) Started() bool {
+	return s.started
+}
+
+func (s *Stream) Write(value any) error {
+	s.start()
+
+	if err := s.encoder.Encode(value); err != nil {
+		return err
+	}
+	return s.controller.Flush()
+}
+
+// Close sends an empty stream if nothing was written.
+func (s *Stream) Close() {
+	s.start()
+}
+
+func (s *Stream) start() {
+	if s.started {
+		return
+	}
+
+	s.started = true
+	// Large collections can take longer than the server's write timeout.
+	_ = s.controller.SetWriteDeadline(time.Time{})
+
+	s.w.Header().Set("Content-Type", "application/x-ndjson")
+	s.w.WriteHeader(http.StatusOK)
+}
+
 func respond[T any](w http.ResponseWriter, statusCode int, data T) {
 	if statusCode == http.StatusNoContent {
 		w.WriteHeader(statusCode)
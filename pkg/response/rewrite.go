@@ -0,0 +1,128 @@
+package response
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+)
+
+type rewriteMode int
+
+const (
+	rewritePassthrough rewriteMode = iota
+	rewriteBuffered
+	rewriteLines
+)
+
+// Rewriter changes JSON bodies as they are written, passing every document
+// to a function that modifies it in place. JSON bodies are buffered and
+// rewritten by Finish once the handler returns; newline-delimited JSON is
+// rewritten line by line so streams keep flowing. Anything else, including
+// 204s, is passed through untouched.
+type Rewriter struct {
+	http.ResponseWriter
+	// rewrite is told whether value is a line of a stream rather than a
+	// whole body in the response envelope.
+	rewrite func(value any, line bool)
+
+	mode        rewriteMode
+	wroteHeader bool
+	status      int
+	buf         bytes.Buffer
+}
+
+func NewRewriter(w http.ResponseWriter, rewrite func(value any, line bool)) *Rewriter {
+	return &Rewriter{ResponseWriter: w, rewrite: rewrite}
+}
+
+func (rw *Rewriter) WriteHeader(status int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	rw.status = status
+
+	mediaType, _, _ := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		rw.mode = rewriteBuffered
+		// The length changes once the body is rewritten.
+		rw.Header().Del("Content-Length")
+		return
+	case "application/x-ndjson":
+		rw.mode = rewriteLines
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *Rewriter) Write(data []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	switch rw.mode {
+	case rewriteBuffered:
+		return rw.buf.Write(data)
+	case rewriteLines:
+		rw.buf.Write(data)
+		for {
+			line, err := rw.buf.ReadBytes('\n')
+			if err != nil {
+				// Keep the partial line for the next write.
+				rest := append([]byte(nil), line...)
+				rw.buf.Reset()
+				rw.buf.Write(rest)
+				return len(data), nil
+			}
+			if _, err := rw.ResponseWriter.Write(rw.apply(line, true)); err != nil {
+				return 0, err
+			}
+		}
+	default:
+		return rw.ResponseWriter.Write(data)
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, so
+// streams can still be flushed and lift their write deadline.
+func (rw *Rewriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Finish writes the buffered body, or the last line of a stream that did not
+// end with a newline.
+func (rw *Rewriter) Finish() {
+	switch rw.mode {
+	case rewriteBuffered:
+		rw.ResponseWriter.WriteHeader(rw.status)
+		_, _ = rw.ResponseWriter.Write(rw.apply(rw.buf.Bytes(), false))
+	case rewriteLines:
+		if rw.buf.Len() > 0 {
+			_, _ = rw.ResponseWriter.Write(rw.apply(rw.buf.Bytes(), true))
+		}
+	}
+}
+
+// apply rewrites one JSON document. Documents that cannot be decoded are
+// dropped rather than passed on unchanged.
+func (rw *Rewriter) apply(data []byte, line bool) []byte {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return data
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil
+	}
+	rw.rewrite(value, line)
+
+	var out bytes.Buffer
+	if err := json.NewEncoder(&out).Encode(value); err != nil {
+		return nil
+	}
+	return out.Bytes()
+}
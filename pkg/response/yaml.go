@@ -0,0 +1,145 @@
+package response
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// YAML is the media type of YAML bodies. The unregistered types clients
+// still send for YAML are accepted as well.
+const YAML = "application/yaml"
+
+var yamlMediaTypes = []string{YAML, "application/x-yaml", "text/yaml", "text/x-yaml"}
+
+// IsYAML reports whether a Content-Type or Accept value names YAML.
+func IsYAML(value string) bool {
+	mediaType, _, _ := mime.ParseMediaType(value)
+	for _, yamlType := range yamlMediaTypes {
+		if mediaType == yamlType {
+			return true
+		}
+	}
+	return false
+}
+
+// WantsYAML reports whether the client asked for YAML rather than JSON: its
+// Accept header lists a YAML media type, and lists it before any JSON one.
+func WantsYAML(r *http.Request) bool {
+	for _, values := range r.Header.Values("Accept") {
+		for value := range strings.SplitSeq(values, ",") {
+			if IsYAML(value) {
+				return true
+			}
+			if mediaType, _, _ := mime.ParseMediaType(value); mediaType == "application/json" {
+				return false
+			}
+		}
+	}
+	return false
+}
+
+// EncodeYAML is middleware that sends JSON responses as YAML to clients that
+// ask for it, keeping the fields in the order JSON has them. Anything else,
+// such as CSV exports and newline-delimited JSON streams, is sent as it is.
+func EncodeYAML(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		if !WantsYAML(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		yw := &yamlWriter{ResponseWriter: w}
+		next.ServeHTTP(yw, r)
+		yw.finish()
+	})
+}
+
+// yamlWriter buffers a JSON body to send it as YAML once the handler
+// returns.
+type yamlWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+	buffered    bool
+	status      int
+	buf         bytes.Buffer
+}
+
+func (yw *yamlWriter) WriteHeader(status int) {
+	if yw.wroteHeader {
+		return
+	}
+	yw.wroteHeader = true
+	yw.status = status
+
+	if mediaType, _, _ := mime.ParseMediaType(yw.Header().Get("Content-Type")); mediaType == "application/json" {
+		yw.buffered = true
+		return
+	}
+	yw.ResponseWriter.WriteHeader(status)
+}
+
+func (yw *yamlWriter) Write(data []byte) (int, error) {
+	if !yw.wroteHeader {
+		yw.WriteHeader(http.StatusOK)
+	}
+	if yw.buffered {
+		return yw.buf.Write(data)
+	}
+	return yw.ResponseWriter.Write(data)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (yw *yamlWriter) Unwrap() http.ResponseWriter {
+	return yw.ResponseWriter
+}
+
+// finish sends the buffered body as YAML. A body that is not valid JSON is
+// sent as it is.
+func (yw *yamlWriter) finish() {
+	if !yw.buffered {
+		return
+	}
+
+	body := yw.buf.Bytes()
+	if converted, err := jsonToYAML(body); err == nil {
+		body = converted
+		yw.Header().Set("Content-Type", YAML)
+	}
+	yw.Header().Del("Content-Length")
+	yw.ResponseWriter.WriteHeader(yw.status)
+	_, _ = yw.ResponseWriter.Write(body)
+}
+
+// jsonToYAML converts a JSON document to block-style YAML. JSON is YAML
+// already, so it is parsed as YAML, which keeps the order of fields and the
+// type of every value, and then written out without the JSON styling.
+func jsonToYAML(data []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	unstyle(&node)
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func unstyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		unstyle(child)
+	}
+}
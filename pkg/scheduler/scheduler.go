@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// Every runs task once per interval until ctx is cancelled. The first run
+// happens after one interval has elapsed, not immediately.
+func Every(ctx context.Context, interval time.Duration, task func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			task(ctx)
+		}
+	}
+}